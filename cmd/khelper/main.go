@@ -1,17 +1,31 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"khelper/internal/tuidriver"
 	"khelper/pkg/config"
 	"khelper/pkg/k8s"
+	"khelper/pkg/k8s/fake"
 	"khelper/pkg/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 var (
@@ -19,6 +33,18 @@ var (
 	deployment string
 	pod        string
 	container  string
+	inCluster  bool
+	configFile string
+	kubeconfig string
+	theme      string
+	timeout    time.Duration
+	readOnly   bool
+	tailLines  int64
+	quiet      bool
+	accessible bool
+
+	// cfg is the config file with the environment and flag overrides applied
+	cfg *config.Config
 )
 
 func main() {
@@ -27,6 +53,8 @@ func main() {
 		Short: "Interactive Kubernetes deployment helper",
 		Long:  `khelper is an interactive CLI tool that simplifies Kubernetes deployment management with a modern terminal UI.`,
 		RunE:  runInteractive,
+
+		PersistentPreRunE: applyOptions,
 	}
 
 	// Global flags
@@ -34,13 +62,42 @@ func main() {
 	rootCmd.PersistentFlags().StringVarP(&deployment, "deployment", "d", "", "Deployment name")
 	rootCmd.PersistentFlags().StringVarP(&pod, "pod", "p", "", "Pod name")
 	rootCmd.PersistentFlags().StringVarP(&container, "container", "c", "", "Container name")
+	rootCmd.PersistentFlags().BoolVar(&inCluster, "in-cluster", false, "Use the pod's service account instead of a kubeconfig (auto-detected when no kubeconfig is configured)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file to use instead of the default one ($"+config.EnvConfig+")")
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig to use for this run ($"+config.EnvKubeConfig+")")
+	rootCmd.PersistentFlags().StringVar(&theme, "theme", "", "Color theme: dark, light, mono or high-contrast ($"+config.EnvTheme+")")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "API server connect timeout ($"+config.EnvTimeout+")")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "Disable commands that change the cluster ($"+config.EnvReadOnly+")")
+	rootCmd.PersistentFlags().BoolVar(&accessible, "accessible", false, "ASCII-only, high-contrast TUI for screen readers ($"+config.EnvAccessible+")")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only data and errors, no progress or confirmations")
+	rootCmd.PersistentFlags().Int64VarP(&tailLines, "tail", "t", 0, "Log lines to show before following (default 100, $"+config.EnvTailLines+")")
 
 	// Subcommands
 	rootCmd.AddCommand(logsCmd())
 	rootCmd.AddCommand(shellCmd())
+	rootCmd.AddCommand(attachCmd())
 	rootCmd.AddCommand(scaleCmd())
 	rootCmd.AddCommand(portForwardCmd())
+	rootCmd.AddCommand(pfCmd())
+	rootCmd.AddCommand(reverseCmd())
+	rootCmd.AddCommand(driveCmd())
+	rootCmd.AddCommand(tokenCmd())
 	rootCmd.AddCommand(updateImageCmd())
+	rootCmd.AddCommand(patchCmd())
+	rootCmd.AddCommand(overlayCmd())
+	rootCmd.AddCommand(pullSecretsCmd())
+	rootCmd.AddCommand(drainImpactCmd())
+	rootCmd.AddCommand(resumeCmd())
+	rootCmd.AddCommand(logsArchiveCmd())
+	rootCmd.AddCommand(resourcesCmd())
+	rootCmd.AddCommand(recentsCmd())
+	rootCmd.AddCommand(credentialsCmd())
+	rootCmd.AddCommand(undoCmd())
+	rootCmd.AddCommand(waitCmd())
+	rootCmd.AddCommand(showCmd())
+	rootCmd.AddCommand(scheduleCmd())
+	rootCmd.AddCommand(whoamiCmd())
+	rootCmd.AddCommand(getCmd())
 
 	// Silence Cobra's default error printing - we handle it ourselves
 	rootCmd.SilenceErrors = true
@@ -48,205 +105,1648 @@ func main() {
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		if explained, ok := k8s.ExplainError(err); ok {
+			fmt.Fprintf(os.Stderr, "%s. %s\n", explained.Summary, explained.Hint)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
-func runInteractive(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+// Exit codes of failed commands, for scripts to tell failures apart
+const (
+	exitError         = 1 // any other failure
+	exitNotFound      = 3 // a namespace, workload, pod or credential does not exist
+	exitForbidden     = 4 // denied by RBAC or not authenticated
+	exitTimeout       = 5 // the cluster or a wait did not answer in time
+	exitRolloutFailed = 6 // a rollout exceeded its progress deadline
+)
+
+// exitCode maps an error to the exit code scripts can check
+func exitCode(err error) int {
+	var netErr net.Error
+	var sigErr *k8s.SignalError
+	switch {
+	case errors.As(err, &sigErr):
+		// Like a shell, report the signal that ended the session
+		if sig, ok := sigErr.Signal.(syscall.Signal); ok {
+			return 128 + int(sig)
+		}
+		return exitError
+	case errors.Is(err, k8s.ErrRolloutFailed):
+		return exitRolloutFailed
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return exitTimeout
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return exitForbidden
+	case apierrors.IsNotFound(err), errors.Is(err, config.ErrCredentialNotFound):
+		return exitNotFound
+	}
+	return exitError
+}
+
+// info prints human-readable progress and confirmations to stderr, keeping
+// stdout for data; --quiet drops them
+func info(format string, args ...interface{}) {
+	fmt.Fprintf(ui.Messages, format+"\n", args...)
+}
+
+// applyOptions loads the config file and applies the KHELPER_* environment
+// variables and the global flags on top of it, flags taking precedence
+func applyOptions(cmd *cobra.Command, args []string) error {
+	env, err := config.EnvOptions()
+	if err != nil {
+		return err
+	}
+	opts := env.Merge(config.Options{
+		KubeConfig: kubeconfig,
+		Namespace:  namespace,
+		Theme:      theme,
+		Timeout:    timeout,
+		ReadOnly:   readOnly,
+		TailLines:  tailLines,
+		Accessible: accessible,
+	})
+	if opts.TailLines < 0 {
+		return fmt.Errorf("--tail must not be negative")
+	}
+
+	if configFile == "" {
+		configFile = os.Getenv(config.EnvConfig)
+	}
+	if configFile != "" {
+		config.SetConfigPath(config.ExpandPath(configFile))
+	}
+	cfg, err = config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	for _, warning := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	cfg.Override(opts)
+	namespace = opts.Namespace
+	applyRetry(cfg.Retry)
+	if err := ui.SetChangeCauseTemplate(cfg.ChangeCause); err != nil {
+		return fmt.Errorf("invalid change_cause template: %w", err)
+	}
+	if quiet {
+		ui.Messages = io.Discard
+	}
+	ui.SetAccessible(cfg.IsAccessible())
+	themeName := cfg.GetTheme()
+	if themeName == "" && cfg.IsAccessible() {
+		themeName = "high-contrast"
+	}
+	return ui.ApplyTheme(themeName)
+}
 
-	// Override namespace from flag if provided
-	if namespace != "" {
-		cfg.LastNamespace = namespace
+// applyRetry sets the retry policy of the clients from the config, keeping
+// the defaults of unset fields
+func applyRetry(r config.Retry) {
+	k8s.Retry = k8s.DefaultRetryPolicy
+	if r.Attempts != nil {
+		k8s.Retry.Attempts = *r.Attempts
+	}
+	if r.InitialDelay > 0 {
+		k8s.Retry.InitialDelay = r.InitialDelay
+	}
+	if r.MaxDelay > 0 {
+		k8s.Retry.MaxDelay = r.MaxDelay
 	}
+}
 
-	// Try to create k8s client, but don't fail if no kubeconfig exists
-	// The UI will prompt user to select/enter a kubeconfig path
-	var k8sClient *k8s.Client
-	var clientErr error
-	if cfg.KubeConfig != "" {
-		k8sClient, clientErr = k8s.NewClientWithConfig(cfg.KubeConfig)
-	} else {
-		k8sClient, clientErr = k8s.NewClient()
+// newClient creates the client of a subcommand, using the kubeconfig given
+// with --kubeconfig or KHELPER_KUBECONFIG if any
+func newClient() (*k8s.Client, error) {
+	return k8s.NewClientWithOptions(clientOptions(cfg.GetOverrides().KubeConfig))
+}
+
+// clientOptions are the options of a client of kubeconfig, with the
+// configured connect timeout
+func clientOptions(kubeconfig string) k8s.ClientOptions {
+	return k8s.ClientOptions{KubeConfig: kubeconfig, ConnectTimeout: cfg.GetTimeout()}
+}
+
+// checkWritable fails commands that change the cluster in read-only mode
+func checkWritable(command string) error {
+	if cfg.IsReadOnly() {
+		return fmt.Errorf("%s changes the cluster and is disabled in read-only mode", command)
 	}
+	return nil
+}
 
-	// Create model - it will handle nil client by showing kubeconfig selection
-	model := ui.NewModel(cfg, k8sClient, clientErr)
+func runInteractive(cmd *cobra.Command, args []string) error {
+	return runTUI(false)
+}
 
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	finalModel, err := p.Run()
-	if err != nil {
-		return fmt.Errorf("failed to run TUI: %w", err)
+func resumeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Reopen the TUI at the last selected deployment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTUI(true)
+		},
 	}
+}
 
-	// Handle post-TUI actions
-	m := finalModel.(ui.Model)
-	return handlePostTUIAction(m, k8sClient)
+func logsArchiveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logs-archive",
+		Short: "Browse and search log files saved from the log viewer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p := tea.NewProgram(ui.NewArchiveModel(), tea.WithAltScreen())
+			if _, err := p.Run(); err != nil {
+				return fmt.Errorf("failed to run TUI: %w", err)
+			}
+			return nil
+		},
+	}
 }
 
-func handlePostTUIAction(m ui.Model, k8sClient *k8s.Client) error {
-	if m.GetCommand() == nil {
-		return nil
+func resourcesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resources",
+		Short: "Browse any resource kind in a namespace: list, view YAML, delete",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" {
+				return fmt.Errorf("namespace is required")
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			model := ui.NewResourcesModel(k8sClient, namespace)
+			model.SetReadOnly(cfg.IsReadOnly())
+			p := tea.NewProgram(model, tea.WithAltScreen())
+			if _, err := p.Run(); err != nil {
+				return fmt.Errorf("failed to run TUI: %w", err)
+			}
+			return nil
+		},
 	}
+}
 
-	switch m.GetCommand().Name {
-	case "shell":
-		// Empty string lets the Shell function auto-detect the best shell
-		return ui.RunShell(k8sClient, m.GetNamespace(), m.GetPod(), m.GetContainer(), "")
-	case "logs-follow":
-		return ui.RunLogs(k8sClient, m.GetNamespace(), m.GetPod(), m.GetContainer(), true)
-	case "port-forward":
-		parts := strings.Split(m.GetInputValue(), ":")
-		if len(parts) == 2 {
-			local, _ := strconv.Atoi(parts[0])
-			remote, _ := strconv.Atoi(parts[1])
-			return ui.RunPortForward(k8sClient, m.GetNamespace(), m.GetPod(), local, remote)
-		}
+func recentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recents",
+		Short: "Maintain the recent items shown at the top of lists",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:       "clear [category]",
+		Short:     "Forget recent items of one category or all of them (favorites are kept)",
+		Long:      "Forget recent items. Categories: " + strings.Join(config.Categories, ", "),
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: config.Categories,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			category := ""
+			if len(args) == 1 {
+				category = args[0]
+			}
+			if err := cfg.ClearRecents(category); err != nil {
+				return err
+			}
+			if category == "" {
+				category = "all categories"
+			}
+			info("Cleared recents of %s", category)
+			return nil
+		},
+	})
+	return cmd
+}
+
+func credentialsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "credentials",
+		Short: "Manage secrets such as registry passwords, kept in the OS keychain or encrypted",
+		Long: "Manage secrets used by khelper. They are kept in the OS keychain when one is available " +
+			"(set " + config.EnvKeychain + "=off to disable) and in a file next to the config otherwise, encrypted " +
+			"with a key in the keychain or, without one, only obfuscated with a key file beside it (both mode 0600). " +
+			"Names are <kind>/<id>, e.g. registry/ghcr.io, webhook/slack or proxy/corp.",
 	}
 
-	return nil
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the stored credentials",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := config.OpenCredentials()
+			if err != nil {
+				return err
+			}
+			names, err := store.List()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <name>",
+		Short: "Store a secret, read from the terminal without echo or from stdin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := config.OpenCredentials()
+			if err != nil {
+				return err
+			}
+			secret, err := readSecret(args[0])
+			if err != nil {
+				return err
+			}
+			if err := store.Set(args[0], secret); err != nil {
+				return err
+			}
+			info("Stored %s", args[0])
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <name>",
+		Short: "Print a stored secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := config.OpenCredentials()
+			if err != nil {
+				return err
+			}
+			secret, err := store.Get(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(secret)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "Remove a stored secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := config.OpenCredentials()
+			if err != nil {
+				return err
+			}
+			if err := store.Delete(args[0]); err != nil {
+				return err
+			}
+			info("Deleted %s", args[0])
+			return nil
+		},
+	})
+
+	return cmd
 }
 
-func logsCmd() *cobra.Command {
-	var follow bool
-	var tailLines int64
+func waitCmd() *cobra.Command {
+	var waitTimeout time.Duration
 
 	cmd := &cobra.Command{
-		Use:   "logs",
-		Short: "View container logs",
+		Use:   "wait",
+		Short: "Wait until a deployment is rolled out and ready",
+		Long: "Block until the deployment's rollout is complete and all replicas are ready, printing progress as it changes. " +
+			"Exits non-zero when the rollout fails or --timeout passes, for use in CI scripts. " +
+			"Here --timeout is the wait limit; the API server connect timeout comes from $" + config.EnvTimeout + ".",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if namespace == "" || deployment == "" || pod == "" || container == "" {
-				return fmt.Errorf("namespace, deployment, pod, and container are required")
+			if namespace == "" || deployment == "" {
+				return fmt.Errorf("namespace and deployment are required")
 			}
 
-			k8sClient, err := k8s.NewClient()
+			k8sClient, err := newClient()
 			if err != nil {
 				return err
 			}
 
-			return ui.RunLogs(k8sClient, namespace, pod, container, follow)
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+			defer cancel()
+
+			started := time.Now()
+			err = k8sClient.WaitForRollout(ctx, namespace, deployment, 2*time.Second, func(status k8s.RolloutStatus) {
+				info("[%s] %s: %s", time.Since(started).Round(time.Second), deployment, status)
+			})
+			if err != nil {
+				return err
+			}
+			info("%s is rolled out and ready after %s", deployment, time.Since(started).Round(time.Second))
+			return nil
 		},
 	}
 
-	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
-	cmd.Flags().Int64VarP(&tailLines, "tail", "t", 100, "Number of lines to show")
+	cmd.Flags().DurationVar(&waitTimeout, "timeout", ui.DefaultWaitTimeout, "How long to wait for the rollout")
 
 	return cmd
 }
 
-func shellCmd() *cobra.Command {
-	var shell string
+func showCmd() *cobra.Command {
+	var output string
 
 	cmd := &cobra.Command{
-		Use:   "shell",
-		Short: "Open shell in container",
+		Use:   "show <command> [input]",
+		Short: "Print the result of a TUI command such as describe, list-env or list-revisions",
+		Long: "Run a command that shows a result in the TUI and print the result to stdout, or write it to a file with --output, " +
+			"e.g. khelper show describe -n shop -d web | grep Image. The input is the answer to the command's prompt. " +
+			"Commands that change the cluster or need the TUI are refused.",
+		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if namespace == "" || pod == "" || container == "" {
-				return fmt.Errorf("namespace, pod, and container are required")
+			if namespace == "" {
+				return fmt.Errorf("namespace is required")
+			}
+			if container == "" {
+				container = cfg.GetPrefs(namespace, deployment).Container
 			}
 
-			k8sClient, err := k8s.NewClient()
+			k8sClient, err := newClient()
 			if err != nil {
 				return err
 			}
 
-			return ui.RunShell(k8sClient, namespace, pod, container, shell)
+			target := ui.ResultTarget{Namespace: namespace, Deployment: deployment, Pod: pod, Container: container}
+			if len(args) == 2 {
+				target.Input = args[1]
+			}
+			result, err := ui.RunResultCommand(cmd.Context(), cfg, k8sClient, args[0], target)
+			if err != nil {
+				return err
+			}
+			if !strings.HasSuffix(result, "\n") {
+				result += "\n"
+			}
+			if output == "" {
+				fmt.Print(result)
+				return nil
+			}
+			if err := os.WriteFile(output, []byte(result), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", output, err)
+			}
+			info("Wrote the %s result to %s", args[0], output)
+			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&shell, "shell", "s", "/bin/sh", "Shell to use")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the result to this file instead of stdout")
 
 	return cmd
 }
 
-func scaleCmd() *cobra.Command {
-	var replicas int32
+func overlayCmd() *cobra.Command {
+	var base, repo, output string
 
 	cmd := &cobra.Command{
-		Use:   "scale",
-		Short: "Scale deployment",
+		Use:   "overlay",
+		Short: "Write the live deployment as a kustomize overlay on its base",
+		Long: "Write the deployment as a kustomize overlay: a kustomization listing its base and a strategic merge patch " +
+			"with the fields the live deployment changes, e.g. to move a fix made in the cluster into the GitOps repository. " +
+			"The base file is found from the config.kubernetes.io/origin annotation in --repo or kustomize.repo_dir, " +
+			"or given with --base. With --output the files are written to that directory, else printed.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if namespace == "" || deployment == "" {
 				return fmt.Errorf("namespace and deployment are required")
 			}
+			if repo == "" {
+				repo = cfg.GetKustomizeRepo()
+			}
 
-			k8sClient, err := k8s.NewClient()
+			k8sClient, err := newClient()
 			if err != nil {
 				return err
 			}
-
 			ctx := cmd.Context()
-			if err := k8sClient.ScaleDeployment(ctx, namespace, deployment, replicas); err != nil {
+			live, err := k8sClient.GetDeployment(ctx, namespace, deployment)
+			if err != nil {
+				return err
+			}
+			if base == "" {
+				if base, err = ui.KustomizeBase(repo, live); err != nil {
+					return err
+				}
+			}
+
+			resources := filepath.Dir(base)
+			if repo != "" {
+				if rel, err := filepath.Rel(repo, resources); err == nil && !strings.HasPrefix(rel, "..") {
+					resources = rel
+				}
+			}
+			if output != "" {
+				dir, err := filepath.Abs(filepath.Dir(base))
+				if err != nil {
+					return err
+				}
+				out, err := filepath.Abs(output)
+				if err != nil {
+					return err
+				}
+				if resources, err = filepath.Rel(out, dir); err != nil {
+					return fmt.Errorf("failed to find the base from %s: %w", output, err)
+				}
+			}
+			overlay, err := ui.DeploymentOverlay(ctx, k8sClient, base, live, filepath.ToSlash(resources))
+			if err != nil {
 				return err
 			}
+			if output == "" {
+				fmt.Print(overlay.String())
+				return nil
+			}
 
-			fmt.Printf("Scaled %s to %d replicas\n", deployment, replicas)
+			kustomization := filepath.Join(output, "kustomization.yaml")
+			if _, err := os.Stat(kustomization); err == nil {
+				return fmt.Errorf("%s already exists, remove it or pick another --output", kustomization)
+			}
+			if err := os.MkdirAll(output, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			if err := os.WriteFile(kustomization, []byte(overlay.Kustomization), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", kustomization, err)
+			}
+			if overlay.Patch == "" {
+				info("Wrote %s, %s does not differ from its base", kustomization, deployment)
+				return nil
+			}
+			patchFile := filepath.Join(output, overlay.PatchFile)
+			if err := os.WriteFile(patchFile, []byte(overlay.Patch), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", patchFile, err)
+			}
+			info("Wrote %s and %s", kustomization, patchFile)
 			return nil
 		},
 	}
 
-	cmd.Flags().Int32VarP(&replicas, "replicas", "r", 1, "Number of replicas")
-	cmd.MarkFlagRequired("replicas")
+	cmd.Flags().StringVar(&base, "base", "", "The base file holding the deployment, instead of the one its origin annotation names")
+	cmd.Flags().StringVar(&repo, "repo", "", "Checkout of the GitOps repository the origin paths are relative to (default kustomize.repo_dir)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write kustomization.yaml and the patch to this directory instead of stdout")
 
 	return cmd
 }
 
-func portForwardCmd() *cobra.Command {
-	var localPort, remotePort int
+func undoCmd() *cobra.Command {
+	var yes bool
 
 	cmd := &cobra.Command{
-		Use:   "port-forward",
-		Short: "Forward port to pod",
+		Use:   "undo",
+		Short: "Undo the last scale, image, env or rollback change",
+		Long: "Revert the last change recorded in the audit history, limited to a namespace and deployment when given. " +
+			"Shows what will be restored and asks for confirmation unless --yes is set.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if namespace == "" || pod == "" {
-				return fmt.Errorf("namespace and pod are required")
+			if err := checkWritable("undo"); err != nil {
+				return err
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
 			}
 
-			k8sClient, err := k8s.NewClient()
+			ctx := cmd.Context()
+			change, err := ui.LastUndoableChange(k8sClient, namespace, deployment)
+			if err != nil {
+				return err
+			}
+			if change == nil {
+				return fmt.Errorf("no change recorded that can be undone")
+			}
+			preview, err := ui.UndoPreview(ctx, k8sClient, *change)
 			if err != nil {
 				return err
 			}
+			if yes {
+				info("%s", preview)
+			} else {
+				fmt.Fprintln(os.Stderr, preview)
+				if !confirm("\nRestore?") {
+					return fmt.Errorf("undo cancelled")
+				}
+			}
 
-			return ui.RunPortForward(k8sClient, namespace, pod, localPort, remotePort)
+			result, err := ui.UndoChange(ctx, k8sClient, *change)
+			if err != nil {
+				return err
+			}
+			info("%s", result)
+			return nil
 		},
 	}
 
-	cmd.Flags().IntVarP(&localPort, "local", "l", 8080, "Local port")
-	cmd.Flags().IntVarP(&remotePort, "remote", "r", 80, "Remote port")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Restore without asking")
 
 	return cmd
 }
 
-func updateImageCmd() *cobra.Command {
-	var image string
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(question string) bool {
+	fmt.Fprint(os.Stderr, question+" [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(answer), "y")
+}
 
-	cmd := &cobra.Command{
-		Use:   "update-image",
-		Short: "Update container image",
+// readSecret prompts for a secret without echo, or reads it from stdin when
+// that is not a terminal
+func readSecret(name string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, "Secret for %s: ", name)
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return string(secret), nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// runTUI starts the interactive UI, optionally jumping straight back to the
+// last session's deployment
+func runTUI(resume bool) error {
+	if resume {
+		session := cfg.GetLastSession()
+		if session == nil {
+			return fmt.Errorf("no previous session to resume")
+		}
+		if session.KubeConfig != "" && session.KubeConfig != k8s.InClusterKubeConfig {
+			cfg.KubeConfig = session.KubeConfig
+		}
+		cfg.LastNamespace = session.Namespace
+	}
+
+	// Override namespace from flag if provided
+	if namespace != "" {
+		cfg.LastNamespace = namespace
+	}
+
+	// Try to create k8s client, but don't fail if no kubeconfig exists
+	// The UI will prompt user to select/enter a kubeconfig path
+	var k8sClient k8s.ClientInterface
+	client, useInCluster, clientErr := tuiClient()
+	if useInCluster && clientErr != nil {
+		return fmt.Errorf("failed to use in-cluster config: %w", clientErr)
+	}
+	if clientErr == nil {
+		k8sClient = client
+	}
+
+	// Create model - it will handle nil client by showing kubeconfig selection
+	model := ui.NewModel(cfg, k8sClient, clientErr)
+	model.SetInCluster(useInCluster)
+	if resume {
+		if clientErr != nil {
+			return fmt.Errorf("failed to resume session: %w", clientErr)
+		}
+		if err := model.ResumeSession(); err != nil {
+			return err
+		}
+	}
+	return runModel(model)
+}
+
+// tuiClient creates the client of the TUI from the configured kubeconfig, or
+// the pod's service account in-cluster. In-cluster mode defaults to the
+// pod's own namespace.
+func tuiClient() (*k8s.Client, bool, error) {
+	useInCluster := inCluster || (cfg.GetKubeConfig() == "" && k8s.IsInCluster())
+	if useInCluster && cfg.LastNamespace == "" {
+		cfg.LastNamespace = k8s.InClusterNamespace()
+	}
+	opts := clientOptions(cfg.GetKubeConfig())
+	opts.InCluster = useInCluster
+	client, err := k8s.NewClientWithOptions(opts)
+	return client, useInCluster, err
+}
+
+// runModel runs the TUI and then what it quit to run, like a shell
+func runModel(model ui.Model) error {
+	p := tea.NewProgram(model, tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("failed to run TUI: %w", err)
+	}
+
+	// Handle post-TUI actions
+	m := finalModel.(ui.Model)
+	return handlePostTUIAction(m)
+}
+
+func getCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <kind>/<name>",
+		Short: "Open the TUI on an object, e.g. deploy/api or po/api-7d9f",
+		Long: `Open the TUI on an object given kubectl-style as kind/name or kind name.
+The kind is a plural, singular or short name or the kind itself, optionally
+with its group (deployments.apps), resolved through the cluster's discovery.
+Without --namespace the kubeconfig context's namespace is used.
+Deployments and Deployment-like custom workloads open at their command list,
+any other object opens in the resources explorer at its YAML.`,
+		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if namespace == "" || deployment == "" || container == "" || image == "" {
-				return fmt.Errorf("namespace, deployment, container, and image are required")
+			kind, name, ok := strings.Cut(args[0], "/")
+			if len(args) == 2 {
+				if ok {
+					return fmt.Errorf("give the object as kind/name or kind name, not both")
+				}
+				name = args[1]
+			}
+			if kind == "" || name == "" {
+				return fmt.Errorf("expected kind/name, e.g. deploy/api")
 			}
 
-			k8sClient, err := k8s.NewClient()
+			client, useInCluster, err := tuiClient()
 			if err != nil {
 				return err
 			}
-
-			ctx := cmd.Context()
-			if err := k8sClient.UpdateImage(ctx, namespace, deployment, container, image); err != nil {
+			ctx := context.Background()
+			resources, err := client.ListAPIResources(ctx)
+			if err != nil {
 				return err
 			}
-
-			fmt.Printf("Updated %s image to %s\n", container, image)
+			res, err := k8s.FindAPIResource(resources, kind)
+			if err != nil {
+				return err
+			}
+			// Like kubectl, without --namespace the context's namespace is used
+			ns := namespace
+			if !res.Namespaced {
+				ns = ""
+			} else if ns == "" {
+				if id, _ := client.WhoAmI(ctx); id != nil {
+					ns = id.Namespace
+				}
+				if ns == "" {
+					ns = "default"
+				}
+			}
+			if _, err := client.GetResourceManifest(ctx, ns, res, name, false); err != nil {
+				return err
+			}
+
+			if ref, ok := k8s.WorkloadRef(res, name); ok {
+				model := ui.NewModel(cfg, client, nil)
+				model.SetInCluster(useInCluster)
+				model.Focus(ns, ref)
+				return runModel(model)
+			}
+			model := ui.NewResourcesModel(client, ns)
+			model.SetReadOnly(cfg.IsReadOnly())
+			model.Focus(res, name)
+			p := tea.NewProgram(model, tea.WithAltScreen())
+			if _, err := p.Run(); err != nil {
+				return fmt.Errorf("failed to run TUI: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+func handlePostTUIAction(m ui.Model) error {
+	if m.GetCommand() == nil {
+		return nil
+	}
+
+	// Use the model's client, the kubeconfig may have changed inside the TUI
+	k8sClient := m.GetClient()
+
+	// Only when the TUI quit to run the command, not when quit after one.
+	// Logs are followed inside the TUI and never run here.
+	if !m.RunsAfterExit() {
+		return nil
+	}
+
+	switch m.GetCommand().Name {
+	case "shell":
+		// The preferred shell is tried first, without one the Shell function
+		// auto-detects the best shell
+		shell := cfg.GetPrefs(m.GetNamespace(), m.GetDeployment()).Shell
+		return ui.RunShell(k8sClient, m.GetNamespace(), m.GetPod(), m.GetContainer(), shell)
+	case "attach":
+		return ui.RunAttach(k8sClient, m.GetNamespace(), m.GetPod(), m.GetContainer())
+	case "port-forward":
+		ports, err := k8s.ParsePortPairs(m.GetInputValue())
+		if err != nil {
+			return err
+		}
+		return ui.RunPortForward(k8sClient, m.GetNamespace(), m.GetPod(), ports)
+	}
+
+	return nil
+}
+
+func logsCmd() *cobra.Command {
+	var follow bool
+	var pipe string
+
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "View container logs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if container == "" {
+				container = cfg.GetPrefs(namespace, deployment).Container
+			}
+			if namespace == "" || deployment == "" || pod == "" || container == "" {
+				return fmt.Errorf("namespace, deployment, pod, and container are required")
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			return ui.RunLogs(k8sClient, namespace, pod, container, follow, cfg.GetDeploymentTailLines(namespace, deployment), pipe)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	cmd.Flags().StringVar(&pipe, "pipe", "", "Also write the lines to the input of a shell command, e.g. 'jq .', printing its output on stderr")
+
+	return cmd
+}
+
+func shellCmd() *cobra.Command {
+	var shell string
+
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Open shell in container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prefs := cfg.GetPrefs(namespace, deployment)
+			if container == "" {
+				container = prefs.Container
+			}
+			if namespace == "" || pod == "" || container == "" {
+				return fmt.Errorf("namespace, pod, and container are required")
+			}
+			if err := checkWritable("shell"); err != nil {
+				return err
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			if !cmd.Flags().Changed("shell") && prefs.Shell != "" {
+				shell = prefs.Shell
+			}
+			return ui.RunShell(k8sClient, namespace, pod, container, shell)
+		},
+	}
+
+	cmd.Flags().StringVarP(&shell, "shell", "s", "/bin/sh", "Shell to use, overriding the deployment's preferred shell")
+
+	return cmd
+}
+
+func attachCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "attach",
+		Short: "Attach to the main process of a container",
+		Long: "Attach to the stdin and output of a container's main process instead of starting a shell, " +
+			"like kubectl attach. Its stdin is shared with every other attached session. " +
+			"Press " + k8s.DetachKeys + " to detach.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if container == "" {
+				container = cfg.GetPrefs(namespace, deployment).Container
+			}
+			if namespace == "" || pod == "" || container == "" {
+				return fmt.Errorf("namespace, pod, and container are required")
+			}
+			if err := checkWritable("attach"); err != nil {
+				return err
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			return ui.RunAttach(k8sClient, namespace, pod, container)
+		},
+	}
+}
+
+func scaleCmd() *cobra.Command {
+	var replicas, at string
+
+	cmd := &cobra.Command{
+		Use:   "scale",
+		Short: "Scale deployment, now or at a given time",
+		Long: "Scale a deployment to a replica count or a preset: prev (the count before the last scale), " +
+			"min or max (the bounds of its HorizontalPodAutoscaler). With --at the command waits until then.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || deployment == "" {
+				return fmt.Errorf("namespace and deployment are required")
+			}
+			if err := checkWritable("scale"); err != nil {
+				return err
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if at != "" {
+				when, err := ui.ParseScaleTime(at, time.Now())
+				if err != nil {
+					return err
+				}
+				info("Scaling %s to %s at %s, press Ctrl+C to cancel...", deployment, replicas, when.Format("15:04:05"))
+				select {
+				case <-time.After(time.Until(when)):
+				case <-ctx.Done():
+					return fmt.Errorf("scale cancelled")
+				}
+			}
+
+			scaleInfo, err := k8sClient.GetScaleInfo(ctx, namespace, deployment)
+			if err != nil {
+				return err
+			}
+			scaleInfo.Previous = cfg.GetPreviousReplicas(namespace, deployment)
+			target, err := scaleInfo.ResolveReplicas(replicas)
+			if err != nil {
+				return err
+			}
+			change := config.Change{Namespace: namespace, Deployment: deployment, Operation: config.OpScale, After: fmt.Sprint(target)}
+			note, err := ui.ApplyChange(ctx, k8sClient, change, func(ctx context.Context) error {
+				return k8sClient.ScaleDeployment(ctx, namespace, deployment, target)
+			})
+			if err != nil {
+				return err
+			}
+			if err := cfg.SetPreviousReplicas(namespace, deployment, scaleInfo.Replicas); err != nil {
+				return fmt.Errorf("failed to record previous replicas: %w", err)
+			}
+
+			info("Scaled %s from %d to %d replicas", deployment, scaleInfo.Replicas, target)
+			if note != "" {
+				info("Note: %s", note)
+			}
+			if note := scaleInfo.HPANote(); note != "" {
+				info("Note: %s", note)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&replicas, "replicas", "r", "", "Number of replicas, or prev, min or max")
+	cmd.Flags().StringVar(&at, "at", "", "Scale at a clock time (HH:MM) or after a delay (e.g. 30m)")
+	cmd.MarkFlagRequired("replicas")
+
+	return cmd
+}
+
+func whoamiCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "whoami",
+		Short: "Print the user and groups the cluster sees, the cluster and the namespace in use",
+		Long: "Ask the API server who it authenticates you as (SelfSubjectReview, Kubernetes 1.27+) and print it " +
+			"with the kubeconfig context, API server and the namespace commands use.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+			identity, err := k8sClient.WhoAmI(cmd.Context())
+			caps, _ := k8sClient.Capabilities(cmd.Context())
+			fmt.Print(ui.FormatIdentity(identity, caps, nil, namespace))
+			return err
+		},
+	}
+}
+
+func scheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "List or run the scheduled actions of the config",
+		Long: "Scheduled actions are defined under schedules in the config file, e.g. scaling a dev namespace down " +
+			"at 20:00 and back up at 08:00. khelper schedule run performs them while it runs.",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the scheduled actions with their last change and next run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			history, err := config.LoadHistory()
+			if err != nil {
+				return err
+			}
+			if len(cfg.Schedules) == 0 {
+				info("No schedules configured")
+				return nil
+			}
+			fmt.Print(ui.ScheduleList(cfg, history, time.Now()))
+			return cfg.ValidateSchedules()
+		},
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Perform the scheduled actions at their times until stopped",
+		Long: "Run in the foreground and perform the scheduled actions at their times, logging each run to stdout. " +
+			"Changes are recorded in the audit history with the schedule's name and can be undone. " +
+			"Edits to the schedules apply within a minute.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkWritable("schedule run"); err != nil {
+				return err
+			}
+			if err := cfg.ValidateSchedules(); err != nil {
+				return err
+			}
+			if len(cfg.Schedules) == 0 {
+				return fmt.Errorf("no schedules configured, add them under schedules in the config file")
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			logger := log.New(os.Stdout, "", log.LstdFlags)
+			info("Running %d scheduled actions, press Ctrl+C to stop...", len(cfg.Schedules))
+			return ui.RunSchedules(ctx, cfg, func(kubeconfig string) (ui.ScalingClient, error) {
+				if kubeconfig == "" {
+					return newClient()
+				}
+				return k8s.NewClientWithOptions(clientOptions(kubeconfig))
+			}, logger.Printf)
+		},
+	}
+
+	cmd.AddCommand(listCmd, runCmd)
+	return cmd
+}
+
+func tokenCmd() *cobra.Command {
+	var serviceAccount, kubeconfigOut string
+	var duration time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Mint a short-lived service account token",
+		Long:  "Mint a token with the TokenRequest API and print it, or write a kubeconfig using it with --kubeconfig-out. Without --service-account the deployment's service account is used.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || (serviceAccount == "" && deployment == "") {
+				return fmt.Errorf("namespace and deployment or service account are required")
+			}
+			if err := checkWritable("token"); err != nil {
+				return err
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			if serviceAccount == "" {
+				if serviceAccount, err = k8sClient.DeploymentServiceAccount(ctx, namespace, deployment); err != nil {
+					return err
+				}
+			}
+
+			token, err := k8sClient.CreateServiceAccountToken(ctx, namespace, serviceAccount, duration)
+			if err != nil {
+				return err
+			}
+			if kubeconfigOut == "" {
+				fmt.Println(token.Token)
+				return nil
+			}
+
+			data, err := token.KubeConfig()
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(kubeconfigOut, data, 0600); err != nil {
+				return fmt.Errorf("failed to write kubeconfig: %w", err)
+			}
+			info("Wrote kubeconfig for %s/%s to %s (expires %s)",
+				namespace, serviceAccount, kubeconfigOut, token.Expires.Local().Format(time.RFC3339))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&serviceAccount, "service-account", "s", "", "Service account name (default: the deployment's)")
+	cmd.Flags().DurationVar(&duration, "duration", k8s.DefaultTokenDuration, "Token lifetime")
+	cmd.Flags().StringVarP(&kubeconfigOut, "kubeconfig-out", "o", "", "Write a kubeconfig using the token to this file")
+
+	return cmd
+}
+
+func portForwardCmd() *cobra.Command {
+	var localPort, remotePort int
+	var portPairs string
+
+	cmd := &cobra.Command{
+		Use:   "port-forward",
+		Short: "Forward port to pod",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || pod == "" {
+				return fmt.Errorf("namespace and pod are required")
+			}
+
+			ports := []k8s.PortPair{{Local: localPort, Remote: remotePort}}
+			if portPairs != "" {
+				var err error
+				if ports, err = k8s.ParsePortPairs(portPairs); err != nil {
+					return err
+				}
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			return ui.RunPortForward(k8sClient, namespace, pod, ports)
+		},
+	}
+
+	cmd.Flags().IntVarP(&localPort, "local", "l", 8080, "Local port")
+	cmd.Flags().IntVarP(&remotePort, "remote", "r", 80, "Remote port")
+	cmd.Flags().StringVar(&portPairs, "ports", "", "Several ports at once as local:remote pairs or single ports, e.g. 8080:80,9090 (instead of --local and --remote)")
+
+	return cmd
+}
+
+func pfCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pf",
+		Short: "Manage and start saved port-forwards",
+		Long: "Save port-forwards to a deployment or service under a name and start them with pf up. " +
+			"Saved forwards follow rollouts by reconnecting to a new pod; those saved with --autostart " +
+			"also run in the background while the TUI is open for their kubeconfig.",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up <name>...",
+		Short: "Start saved port-forwards until interrupted",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clients := make(map[string]k8s.ClientInterface)
+			var forwards []ui.SavedForward
+			for _, name := range args {
+				pf, err := cfg.GetPortForward(name)
+				if err != nil {
+					return err
+				}
+				kubeConfig := pf.KubeConfig
+				if override := cfg.GetOverrides().KubeConfig; override != "" || kubeConfig == "" {
+					kubeConfig = override
+				} else {
+					kubeConfig = config.ExpandPath(kubeConfig)
+				}
+				client, ok := clients[kubeConfig]
+				if !ok {
+					if client, err = k8s.NewClientWithOptions(clientOptions(kubeConfig)); err != nil {
+						return err
+					}
+					clients[kubeConfig] = client
+				}
+				forwards = append(forwards, ui.SavedForward{Name: name, PortForward: pf, Client: client})
+			}
+			return ui.RunSavedForwards(forwards)
+		},
+	})
+
+	var ports, service string
+	var autostart bool
+	save := &cobra.Command{
+		Use:   "save <name>",
+		Short: "Save a port-forward to the deployment (-d) or --service in the namespace (-n)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" {
+				return fmt.Errorf("namespace is required")
+			}
+			target := deployment
+			if service != "" {
+				target = "service/" + service
+			}
+			if target == "" {
+				return fmt.Errorf("a deployment (-d) or --service is required")
+			}
+			pairs, err := k8s.ParsePortPairs(ports)
+			if err != nil {
+				return err
+			}
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+			pf := config.PortForward{
+				KubeConfig: k8sClient.GetKubeConfigPath(),
+				Namespace:  namespace,
+				Target:     target,
+				Ports:      k8s.FormatPortPairs(pairs),
+				Autostart:  autostart,
+			}
+			if err := cfg.SavePortForward(args[0], pf); err != nil {
+				return err
+			}
+			info("Saved %s: %s", args[0], pf)
+			return nil
+		},
+	}
+	save.Flags().StringVar(&ports, "ports", "", "Ports as local:remote pairs or single ports, e.g. 8080:80,9090; remote ones are the service's with --service")
+	save.Flags().StringVar(&service, "service", "", "Forward to a pod of this service instead of the deployment")
+	save.Flags().BoolVar(&autostart, "autostart", false, "Also start it in the background when the TUI opens for this kubeconfig")
+	cmd.AddCommand(save)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the saved port-forwards",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, name := range cfg.PortForwardNames() {
+				pf := cfg.PortForwards[name]
+				line := name + "\t" + pf.String()
+				if pf.Autostart {
+					line += " (autostart)"
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "Remove a saved port-forward",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cfg.DeletePortForward(args[0]); err != nil {
+				return err
+			}
+			info("Deleted %s", args[0])
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+func reverseCmd() *cobra.Command {
+	var service, local string
+	var port int
+	var restore bool
+
+	cmd := &cobra.Command{
+		Use:   "reverse",
+		Short: "Send a service's traffic to a local port (dev clusters only)",
+		Long: "Send the traffic of a service in the namespace (-n) to a local port, so in-cluster callers reach " +
+			"the code running on this machine. An agent pod takes the service over from its pods until " +
+			"interrupted. Allowed only on the kubeconfigs listed in reverse_tunnel.dev_clusters.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || service == "" {
+				return fmt.Errorf("namespace and --service are required")
+			}
+			if err := checkWritable("reverse"); err != nil {
+				return err
+			}
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+			if err := cfg.CheckReverseTunnel(k8sClient.GetKubeConfigPath()); err != nil {
+				return err
+			}
+			if restore {
+				if err := k8sClient.RestoreService(context.Background(), namespace, service); err != nil {
+					return err
+				}
+				info("Restored service %s", service)
+				return nil
+			}
+			if local == "" {
+				return fmt.Errorf("--local is required")
+			}
+			if _, err := strconv.Atoi(local); err == nil {
+				local = "localhost:" + local
+			}
+			return ui.RunReverseTunnel(k8sClient, k8s.ReverseTunnelOptions{
+				Namespace: namespace,
+				Service:   service,
+				Port:      port,
+				Image:     cfg.ReverseTunnel.Image,
+				LocalAddr: local,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&service, "service", "", "Service whose traffic is sent here")
+	cmd.Flags().IntVar(&port, "port", 0, "Service port to take over (default: its only TCP port)")
+	cmd.Flags().StringVarP(&local, "local", "l", "", "Local port or host:port to send the traffic to")
+	cmd.Flags().BoolVar(&restore, "restore", false, "Give the service its selector back after khelper was killed during a reverse tunnel")
+
+	return cmd
+}
+
+func driveCmd() *cobra.Command {
+	var golden, size string
+	var update, live bool
+
+	cmd := &cobra.Command{
+		Use:   "drive <script>...",
+		Short: "Run the TUI headless through scripts of key presses and print or check its views",
+		Long: "Run the TUI without a terminal through scripts of key presses, printing the views they snapshot, " +
+			"or with --golden comparing them against golden files. Scripts run against a small demo cluster " +
+			"with an empty config, so their views are the same on every machine, unless --live uses the " +
+			"configured cluster and config. See internal/tuidriver for the script steps.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var width, height int
+			if _, err := fmt.Sscanf(size, "%dx%d", &width, &height); err != nil || width < 1 || height < 1 {
+				return fmt.Errorf("invalid --size %q, use e.g. 100x30", size)
+			}
+			if update && golden == "" {
+				return fmt.Errorf("--update needs --golden")
+			}
+
+			root, err := os.MkdirTemp("", "khelper-drive-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(root)
+
+			var failed []error
+			for i, script := range args {
+				home := filepath.Join(root, strconv.Itoa(i))
+				model, err := driveModel(live, home)
+				if err != nil {
+					return err
+				}
+				d := tuidriver.New(model, width, height)
+				d.Settle(tuidriver.DefaultSettleTimeout)
+				err = tuidriver.RunScript(d, script, tuidriver.ScriptOptions{
+					GoldenDir: golden,
+					Update:    update,
+					Out:       os.Stdout,
+					Home:      home,
+					Restart: func() (tea.Model, error) {
+						return driveModel(live, home)
+					},
+				})
+				if err != nil {
+					failed = append(failed, err)
+				}
+			}
+			if len(failed) > 0 {
+				return errors.Join(failed...)
+			}
+			if golden != "" && !update {
+				info("%d script(s) match their golden files", len(args))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&golden, "golden", "", "Directory of golden files the snapshots must match")
+	cmd.Flags().BoolVar(&update, "update", false, "Write the snapshots to the golden files")
+	cmd.Flags().BoolVar(&live, "live", false, "Use the configured cluster and config instead of the demo cluster")
+	cmd.Flags().StringVar(&size, "size", fmt.Sprintf("%dx%d", tuidriver.DefaultWidth, tuidriver.DefaultHeight), "Terminal size")
+
+	return cmd
+}
+
+// driveModel creates the TUI for a drive script: on the demo cluster with a
+// fresh config in the given home, or live
+func driveModel(live bool, home string) (ui.Model, error) {
+	if live {
+		client, err := newClient()
+		if err != nil {
+			return ui.Model{}, err
+		}
+		return ui.NewModel(cfg, client, nil), nil
+	}
+
+	if err := os.MkdirAll(home, 0755); err != nil {
+		return ui.Model{}, err
+	}
+	for _, env := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME"} {
+		os.Setenv(env, home)
+	}
+	os.Setenv(config.EnvKeychain, "off")
+	config.SetConfigPath(filepath.Join(home, "config.yml"))
+	demoCfg, err := config.Load()
+	if err != nil {
+		return ui.Model{}, fmt.Errorf("failed to load config: %w", err)
+	}
+	demoCfg.Override(config.Options{ReadOnly: cfg.IsReadOnly()})
+	return ui.NewModel(demoCfg, fake.NewClient(fake.DemoObjects()...), nil), nil
+}
+
+func patchCmd() *cobra.Command {
+	var patchType, file, inline string
+	var dryRun, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "patch",
+		Short: "Patch the deployment, previewing the diff from a server-side dry run",
+		Long: "Patch the deployment with a strategic merge, JSON merge or JSON patch written in YAML or JSON; " +
+			"without --type a list is a JSON patch and a mapping a strategic merge patch. The change is checked " +
+			"with a server-side dry run and its diff printed, then applied once confirmed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || deployment == "" {
+				return fmt.Errorf("namespace and deployment are required")
+			}
+			if (file == "") == (inline == "") {
+				return fmt.Errorf("give the patch with either --patch or --file")
+			}
+			if file == "-" && !yes && !dryRun {
+				return fmt.Errorf("a patch read from stdin needs --yes or --dry-run, there is no stdin left to confirm it")
+			}
+			if !dryRun {
+				if err := checkWritable("patch"); err != nil {
+					return err
+				}
+			}
+
+			text := inline
+			if file != "" {
+				var data []byte
+				var err error
+				if file == "-" {
+					data, err = io.ReadAll(os.Stdin)
+				} else {
+					data, err = os.ReadFile(file)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to read the patch: %w", err)
+				}
+				text = string(data)
+			}
+			patch, err := k8s.ParsePatch(patchType, text)
+			if err != nil {
+				return err
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			before, after, err := k8sClient.PatchDeployment(ctx, namespace, deployment, patch, true)
+			if err != nil {
+				return err
+			}
+			diff, err := ui.PatchDiff(before, after)
+			if err != nil {
+				return err
+			}
+			if diff == "" {
+				info("The patch changes nothing in %s", deployment)
+				return nil
+			}
+			fmt.Print(diff)
+			if dryRun {
+				return nil
+			}
+
+			shown, blocked, err := ui.LintGate(k8s.LintPodTemplate(&after.Spec.Template), cfg.GetLintThresholds(k8sClient.GetKubeConfigPath()))
+			if err != nil {
+				return err
+			}
+			if len(shown) > 0 {
+				fmt.Fprintln(os.Stderr, "Lint findings in the template:\n"+ui.FormatLintFindings(shown))
+				if blocked {
+					return fmt.Errorf("patch refused by lint")
+				}
+			}
+			if !yes && !confirm("Apply the patch?") {
+				return fmt.Errorf("patch cancelled")
+			}
+
+			patch.ResourceVersion = before.ResourceVersion
+			change := config.Change{Namespace: namespace, Deployment: deployment, Operation: config.OpPatch, After: patch.Summary()}
+			note, err := ui.ApplyChange(ctx, k8sClient, change, func(ctx context.Context) error {
+				_, _, err := k8sClient.PatchDeployment(ctx, namespace, deployment, patch, false)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			info("Patched %s: %s", deployment, change.After)
+			if note != "" {
+				info("Note: %s", note)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&patchType, "type", "", "Patch type: strategic, merge or json (default: json for a list, strategic otherwise)")
+	cmd.Flags().StringVar(&inline, "patch", "", "The patch, in YAML or JSON")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Read the patch from a file, - for stdin")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the diff of the server-side dry run")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply without asking, unless lint blocks it")
+
+	return cmd
+}
+
+func pullSecretsCmd() *cobra.Command {
+	var create, yes bool
+	var name, server, username string
+
+	cmd := &cobra.Command{
+		Use:   "pull-secrets",
+		Short: "Check the image pull secrets against the registries, and create a missing one",
+		Long: "Check that the imagePullSecrets of the deployment's pod template and service account exist and that " +
+			"their logins pull each image of the template from its registry, and list the pods waiting on a failed pull. " +
+			"With --create, create a docker-registry secret for the registry that denied a pull, asking for the username " +
+			"and password. The login is checked against the images first, and the secret added to the pod template " +
+			"unless it is referenced already. Without a terminal, give --username and --yes and pipe the password.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || deployment == "" {
+				return fmt.Errorf("namespace and deployment are required")
+			}
+			interactive := term.IsTerminal(int(os.Stdin.Fd()))
+			if create {
+				if err := checkWritable("pull-secrets --create"); err != nil {
+					return err
+				}
+				if !interactive && (username == "" || !yes) {
+					return fmt.Errorf("without a terminal, --create needs --username and --yes, the password is read from stdin")
+				}
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			report, err := k8sClient.CheckPullSecrets(ctx, namespace, deployment)
+			if err != nil {
+				return err
+			}
+			fmt.Println(ui.FormatPullSecrets(report))
+			failed := 0
+			for _, image := range report.Images {
+				if image.Err != nil {
+					failed++
+				}
+			}
+			if !create {
+				if failed > 0 {
+					return fmt.Errorf("%d of %d images cannot be pulled", failed, len(report.Images))
+				}
+				return nil
+			}
+
+			if server == "" {
+				denied := report.DeniedRegistries()
+				if len(denied) == 0 {
+					return fmt.Errorf("no registry denied a pull, give the registry with --server")
+				}
+				server = denied[0]
+			}
+			if name == "" {
+				name = ui.PullSecretName(report)
+			}
+			if username == "" {
+				fmt.Fprintf(os.Stderr, "\nUsername for %s: ", server)
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				if username = strings.TrimSpace(answer); username == "" {
+					return fmt.Errorf("a username is required")
+				}
+			}
+			password, err := readSecret("registry " + server)
+			if err != nil {
+				return err
+			}
+
+			secret := k8s.PullSecret{Name: name, Logins: []k8s.RegistryLogin{{Server: k8s.LoginServer(server), Username: username, Password: password}}}
+			reference := !report.References(name)
+			question := fmt.Sprintf("Create secret %s with the login to %s as %s", name, server, username)
+			if reference {
+				question += " and add it to the imagePullSecrets of " + deployment
+			}
+			if !yes && !confirm(question+"?") {
+				return fmt.Errorf("pull-secrets cancelled")
+			}
+			images := make([]string, 0, len(report.Images))
+			for _, image := range report.Images {
+				images = append(images, image.Image)
+			}
+			result, err := ui.CreatePullSecret(ctx, k8sClient, namespace, deployment, secret, images, reference)
+			if err != nil {
+				return err
+			}
+			info("%s", result)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&create, "create", false, "Create a docker-registry secret for a registry that denied a pull")
+	cmd.Flags().StringVar(&name, "name", "", "Name of the secret to create (default: the missing one referenced, else pull-<registry>)")
+	cmd.Flags().StringVar(&server, "server", "", "Registry of the login, as in image names (default: the first that denied a pull)")
+	cmd.Flags().StringVar(&username, "username", "", "Username of the login, asked for if not given")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Create without asking")
+
+	return cmd
+}
+
+func drainImpactCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "drain-impact [node]",
+		Short: "Preview what draining a node does to the deployment",
+		Long: "Show which of the deployment's pods draining the node evicts, whether the PodDisruptionBudgets allow it " +
+			"and on which nodes the replacements fit by their requests, node selector, affinity and tolerations. " +
+			"Without a node, each node running the pods is checked. Exits non-zero if the deployment would not keep serving.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || deployment == "" {
+				return fmt.Errorf("namespace and deployment are required")
+			}
+			node := ""
+			if len(args) > 0 {
+				node = args[0]
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+			result, survives, err := ui.DrainImpact(cmd.Context(), k8sClient, namespace, deployment, node)
+			if err != nil {
+				return err
+			}
+			fmt.Println(result)
+			if !survives {
+				return fmt.Errorf("%s would not keep serving through the drain", deployment)
+			}
+			return nil
+		},
+	}
+}
+
+func updateImageCmd() *cobra.Command {
+	var image string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "update-image",
+		Short: "Update container image",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" || deployment == "" || container == "" || image == "" {
+				return fmt.Errorf("namespace, deployment, container, and image are required")
+			}
+			if err := checkWritable("update-image"); err != nil {
+				return err
+			}
+
+			k8sClient, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			findings, err := k8sClient.LintWorkload(ctx, namespace, deployment, k8s.EditImage(container, image))
+			if err != nil {
+				return err
+			}
+			shown, blocked, err := ui.LintGate(findings, cfg.GetLintThresholds(k8sClient.GetKubeConfigPath()))
+			if err != nil {
+				return err
+			}
+			if len(shown) > 0 {
+				fmt.Fprintln(os.Stderr, "Lint findings in the template:\n"+ui.FormatLintFindings(shown))
+				if blocked {
+					return fmt.Errorf("update-image refused by lint")
+				}
+				if !yes && !confirm("Apply anyway?") {
+					return fmt.Errorf("update-image cancelled")
+				}
+			}
+
+			change := config.Change{Namespace: namespace, Deployment: deployment, Operation: config.OpUpdateImage, Container: container, After: image}
+			note, err := ui.ApplyChange(ctx, k8sClient, change, func(ctx context.Context) error {
+				return k8sClient.UpdateImage(ctx, namespace, deployment, container, image)
+			})
+			if err != nil {
+				return err
+			}
+
+			info("Updated %s image to %s", container, image)
+			if note != "" {
+				info("Note: %s", note)
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&image, "image", "i", "", "New image")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply despite lint findings below the block threshold")
 	cmd.MarkFlagRequired("image")
 
 	return cmd
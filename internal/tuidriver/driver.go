@@ -0,0 +1,212 @@
+// Package tuidriver drives a bubbletea model without a terminal: it feeds
+// key events, runs the commands the model returns and snapshots the
+// rendered view, so UI flows can be scripted, replayed for demos and
+// compared against golden files.
+package tuidriver
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// DefaultWidth and DefaultHeight are the terminal size of a new driver
+	DefaultWidth  = 100
+	DefaultHeight = 30
+
+	// quietPeriod is how long no message may arrive before the model is
+	// considered settled
+	quietPeriod = 150 * time.Millisecond
+	// DefaultSettleTimeout bounds waiting for a model that keeps receiving
+	// messages, e.g. from a ticking spinner
+	DefaultSettleTimeout = 5 * time.Second
+)
+
+// Driver runs a model the way a tea.Program would, minus the terminal.
+// Commands run in goroutines and their messages are delivered by Settle,
+// so the model is only ever updated from the caller's goroutine.
+type Driver struct {
+	model         tea.Model
+	msgs          chan tea.Msg
+	quit          bool
+	width, height int
+}
+
+// New starts a model in a width x height terminal: it runs Init and sends
+// the window size, as tea.Program does on start
+func New(model tea.Model, width, height int) *Driver {
+	d := &Driver{msgs: make(chan tea.Msg, 256)}
+	d.Restart(model, width, height)
+	return d
+}
+
+// Restart replaces the model with a new one started in the terminal, as a
+// new run of the program. Messages of the old model's commands still
+// running are delivered to the new one.
+func (d *Driver) Restart(model tea.Model, width, height int) {
+	d.model, d.quit = model, false
+	d.run(model.Init())
+	d.Resize(width, height)
+}
+
+// Model returns the current model, to inspect it
+func (d *Driver) Model() tea.Model {
+	return d.model
+}
+
+// Quit reports whether the model asked to quit
+func (d *Driver) Quit() bool {
+	return d.quit
+}
+
+// Send updates the model with a message and starts the command it returns
+func (d *Driver) Send(msg tea.Msg) {
+	if _, ok := msg.(tea.QuitMsg); ok {
+		d.quit = true
+		return
+	}
+	var cmd tea.Cmd
+	d.model, cmd = d.model.Update(msg)
+	d.run(cmd)
+}
+
+// Resize sends a new terminal size
+func (d *Driver) Resize(width, height int) {
+	d.width, d.height = width, height
+	d.Send(tea.WindowSizeMsg{Width: width, Height: height})
+}
+
+// Type sends text as typed, one key per rune
+func (d *Driver) Type(text string) {
+	for _, r := range text {
+		d.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+// Press sends named keys such as enter, esc, down, ctrl+c or alt+x
+func (d *Driver) Press(keys ...string) error {
+	for _, name := range keys {
+		key, err := ParseKey(name)
+		if err != nil {
+			return err
+		}
+		d.Send(key)
+	}
+	return nil
+}
+
+// Settle delivers the messages of running commands until none arrived for
+// a moment, or timeout passed. It reports whether the model settled.
+func (d *Driver) Settle(timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	quiet := time.NewTimer(quietPeriod)
+	defer quiet.Stop()
+	for !d.quit {
+		select {
+		case msg := <-d.msgs:
+			d.Send(msg)
+			if !quiet.Stop() {
+				<-quiet.C
+			}
+			quiet.Reset(quietPeriod)
+		case <-quiet.C:
+			return true
+		case <-deadline:
+			return false
+		}
+	}
+	return true
+}
+
+// View renders the model as plain text, without colors or trailing spaces
+func (d *Driver) View() string {
+	lines := strings.Split(ansi.Strip(d.model.View()), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// run starts a command in the background
+func (d *Driver) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		d.deliver(cmd())
+	}()
+}
+
+// deliver passes a command's message on to Settle. Batches and sequences
+// are unpacked as tea.Program does; a sequence's commands run one after
+// another.
+func (d *Driver) deliver(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case nil:
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			d.run(cmd)
+		}
+	default:
+		// tea.Sequence's message type is unexported
+		if cmds, ok := sequence(msg); ok {
+			for _, cmd := range cmds {
+				if cmd != nil {
+					d.deliver(cmd())
+				}
+			}
+			return
+		}
+		d.msgs <- msg
+	}
+}
+
+// sequence returns the commands of a tea.Sequence message
+func sequence(msg tea.Msg) ([]tea.Cmd, bool) {
+	v := reflect.ValueOf(msg)
+	cmdType := reflect.TypeOf(tea.Cmd(nil))
+	if v.Kind() != reflect.Slice || v.Type().Elem() != cmdType {
+		return nil, false
+	}
+	cmds := make([]tea.Cmd, v.Len())
+	for i := range cmds {
+		cmds[i], _ = v.Index(i).Interface().(tea.Cmd)
+	}
+	return cmds, true
+}
+
+// keyTypes maps key names such as enter or ctrl+c to their key type
+var keyTypes = func() map[string]tea.KeyType {
+	names := make(map[string]tea.KeyType)
+	for k := tea.KeyType(-100); k < 128; k++ {
+		if name := k.String(); name != "" && k != tea.KeyRunes {
+			names[name] = k
+		}
+	}
+	names["space"] = tea.KeySpace
+	return names
+}()
+
+// ParseKey turns a key name as bubbletea prints it, e.g. enter, shift+tab,
+// ctrl+c or alt+x, into a key event. A single character is that key.
+func ParseKey(name string) (tea.KeyMsg, error) {
+	if k, ok := keyTypes[name]; ok {
+		return tea.KeyMsg{Type: k}, nil
+	}
+	alt := false
+	if rest, ok := strings.CutPrefix(name, "alt+"); ok && rest != "" {
+		alt, name = true, rest
+		if k, ok := keyTypes[name]; ok {
+			return tea.KeyMsg{Type: k, Alt: true}, nil
+		}
+	}
+	if runes := []rune(name); len(runes) == 1 {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: runes, Alt: alt}, nil
+	}
+	return tea.KeyMsg{}, fmt.Errorf("unknown key %q", name)
+}
@@ -0,0 +1,61 @@
+package tuidriver_test
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"khelper/internal/tuidriver"
+	"khelper/pkg/config"
+	"khelper/pkg/k8s/fake"
+	"khelper/pkg/ui"
+)
+
+var update = flag.Bool("update", false, "write the views to the golden files")
+
+// TestGolden replays each flow against the demo cluster with a fresh config
+// and compares its views with the golden files, as make golden does. Run
+// with -update after intended UI changes.
+func TestGolden(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "flows", "*.tui"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, script := range scripts {
+		name := strings.TrimSuffix(filepath.Base(script), filepath.Ext(script))
+		t.Run(name, func(t *testing.T) {
+			home := t.TempDir()
+			for _, env := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME"} {
+				t.Setenv(env, home)
+			}
+			t.Setenv(config.EnvKeychain, "off")
+			config.SetConfigPath(filepath.Join(home, "config.yml"))
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+
+			model := ui.NewModel(cfg, fake.NewClient(fake.DemoObjects()...), nil)
+			d := tuidriver.New(model, tuidriver.DefaultWidth, tuidriver.DefaultHeight)
+			d.Settle(tuidriver.DefaultSettleTimeout)
+			err = tuidriver.RunScript(d, script, tuidriver.ScriptOptions{
+				GoldenDir: filepath.Join("testdata", "golden"),
+				Update:    *update,
+				Home:      home,
+				Restart: func() (tea.Model, error) {
+					cfg, err := config.Load()
+					if err != nil {
+						return nil, err
+					}
+					return ui.NewModel(cfg, fake.NewClient(fake.DemoObjects()...), nil), nil
+				},
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
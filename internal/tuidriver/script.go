@@ -0,0 +1,187 @@
+package tuidriver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// GoldenExt is the extension of golden files
+const GoldenExt = ".golden"
+
+// ScriptOptions say what a script does with its snapshots
+type ScriptOptions struct {
+	// GoldenDir, if set, holds a golden file per snapshot that the view must
+	// match; otherwise snapshots are printed to Out
+	GoldenDir string
+	// Update writes the views to the golden files instead of comparing them
+	Update bool
+	// Out receives the printed snapshots
+	Out io.Writer
+	// Home, if set, is shown as ~ in golden files, as it differs per run
+	Home string
+	// Restart creates the model the restart step starts, as the next run of
+	// the program would
+	Restart func() (tea.Model, error)
+}
+
+// RunScript drives a model through a script, one step per line:
+//
+//	# a comment
+//	type shop          types the rest of the line
+//	press down enter   presses named keys, see ParseKey
+//	tap enter          presses keys without settling, to snapshot the view
+//	                   shown while the command they start runs
+//	size 120 40        resizes the terminal
+//	settle 10s         waits longer than usual for the model to settle
+//	sleep 500ms        waits, e.g. for a tick
+//	restart            starts the program again, see ScriptOptions.Restart
+//	snapshot deps      prints the view, or compares it with deps.golden
+//
+// The model is settled after each type, press, size and restart step, but
+// not after tap. The golden files of a script are named after it, e.g.
+// flows/open.tui's deps snapshot is open-deps.golden. All mismatches are
+// reported together.
+func RunScript(d *Driver, path string, opts ScriptOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var mismatches []error
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		step, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		if d.Quit() {
+			return fmt.Errorf("%s:%d: the model quit before %s", path, lineNo, step)
+		}
+
+		switch step {
+		case "type":
+			d.Type(arg)
+			d.Settle(DefaultSettleTimeout)
+		case "press":
+			if err := d.Press(strings.Fields(arg)...); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			d.Settle(DefaultSettleTimeout)
+		case "tap":
+			if err := d.Press(strings.Fields(arg)...); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+		case "size":
+			var width, height int
+			if _, err := fmt.Sscanf(arg, "%d %d", &width, &height); err != nil {
+				return fmt.Errorf("%s:%d: size needs a width and height", path, lineNo)
+			}
+			d.Resize(width, height)
+			d.Settle(DefaultSettleTimeout)
+		case "restart":
+			if opts.Restart == nil {
+				return fmt.Errorf("%s:%d: restart is not supported here", path, lineNo)
+			}
+			model, err := opts.Restart()
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			d.Restart(model, d.width, d.height)
+			d.Settle(DefaultSettleTimeout)
+		case "settle", "sleep":
+			wait, err := time.ParseDuration(arg)
+			if err != nil {
+				return fmt.Errorf("%s:%d: invalid duration %q", path, lineNo, arg)
+			}
+			if step == "sleep" {
+				time.Sleep(wait)
+			}
+			d.Settle(wait)
+		case "snapshot":
+			if arg == "" {
+				arg = strconv.Itoa(lineNo)
+			}
+			if err := snapshot(d.View(), name+"-"+arg, opts); err != nil {
+				if !errors.Is(err, errMismatch) {
+					return err
+				}
+				mismatches = append(mismatches, fmt.Errorf("%s:%d: %w", path, lineNo, err))
+			}
+		default:
+			return fmt.Errorf("%s:%d: unknown step %q", path, lineNo, step)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.Join(mismatches...)
+}
+
+// errMismatch marks a view that differs from its golden file
+var errMismatch = errors.New("view differs from")
+
+// timestamp matches the times views show, such as when a change was made
+var timestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+
+// snapshot prints a view or checks it against its golden file. Timestamps
+// and the home directory are masked in golden files, they differ on every
+// run.
+func snapshot(view, name string, opts ScriptOptions) error {
+	if opts.GoldenDir == "" {
+		_, err := fmt.Fprintf(opts.Out, "--- %s\n%s", name, view)
+		return err
+	}
+	view = timestamp.ReplaceAllString(view, "YYYY-MM-DD hh:mm:ss")
+	if opts.Home != "" {
+		view = strings.ReplaceAll(view, opts.Home, "~")
+	}
+	path := filepath.Join(opts.GoldenDir, name+GoldenExt)
+	if opts.Update {
+		if err := os.MkdirAll(opts.GoldenDir, 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(view), 0644)
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read golden file, create it with --update: %w", err)
+	}
+	if string(golden) == view {
+		return nil
+	}
+	return fmt.Errorf("%w %s\n%s", errMismatch, path, diffLines(string(golden), view))
+}
+
+// diffLines shows the lines where a view differs from the golden one
+func diffLines(golden, view string) string {
+	want := strings.Split(golden, "\n")
+	got := strings.Split(view, "\n")
+	var b strings.Builder
+	for i := range max(len(want), len(got)) {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			fmt.Fprintf(&b, "  line %d\n    - %s\n    + %s\n", i+1, w, g)
+		}
+	}
+	return b.String()
+}
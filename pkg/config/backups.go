@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigBackup is the data of a ConfigMap or Secret saved before khelper
+// changed it
+type ConfigBackup struct {
+	Time      time.Time         `json:"time"`
+	Cluster   string            `json:"cluster"`
+	Namespace string            `json:"namespace"`
+	Kind      string            `json:"kind"` // ConfigMap or Secret
+	Name      string            `json:"name"`
+	User      string            `json:"user,omitempty"`
+	Data      map[string]string `json:"data"`
+	Path      string            `json:"-"` // the file it is stored in
+}
+
+// GetConfigBackupDir returns where the backups of a ConfigMap or Secret are
+// kept: <state dir>/backups/<cluster>/<namespace>/<kind>-<name>
+func GetConfigBackupDir(cluster, namespace, kind, name string) (string, error) {
+	dir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "backups", cluster, namespace, strings.ToLower(kind)+"-"+name), nil
+}
+
+// SaveConfigBackup writes a backup to a file named after its time. The file
+// is only readable by the user, backups of Secrets hold their values.
+func SaveConfigBackup(backup ConfigBackup) (string, error) {
+	if backup.Time.IsZero() {
+		backup.Time = time.Now()
+	}
+	dir, err := GetConfigBackupDir(backup.Cluster, backup.Namespace, backup.Kind, backup.Name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, backup.Time.Format("20060102-150405.000000000")+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return path, nil
+}
+
+// ListConfigBackups returns the backups of a ConfigMap or Secret, newest
+// first. Files that cannot be read are skipped.
+func ListConfigBackups(cluster, namespace, kind, name string) ([]ConfigBackup, error) {
+	dir, err := GetConfigBackupDir(cluster, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []ConfigBackup
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var backup ConfigBackup
+		if err := json.Unmarshal(data, &backup); err != nil {
+			continue
+		}
+		backup.Path = path
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
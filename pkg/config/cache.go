@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of lists kept in the offline cache
+const (
+	CacheNamespaces  = "namespaces"
+	CacheDeployments = "deployments"
+	CachePods        = "pods"
+	CacheContainers  = "containers" // scoped by deployment, not by pod
+)
+
+const (
+	// cacheMaxAge is how long a list is kept without being loaded again
+	cacheMaxAge = 30 * 24 * time.Hour
+	// cacheMaxLists bounds the lists kept per kubeconfig; the ones loaded
+	// longest ago are dropped first
+	cacheMaxLists = 200
+	// cacheTouchAge is how old the time of an unchanged list gets before it
+	// is written again
+	cacheTouchAge = time.Hour
+)
+
+// CachedList is the last successfully loaded version of a list
+type CachedList struct {
+	Items   []string          `json:"items"`
+	Details map[string]string `json:"details,omitempty"` // shown after the items, e.g. a pod's phase
+	Time    time.Time         `json:"time"`
+}
+
+// cacheMu serializes updates of the cache file by concurrent loads
+var cacheMu sync.Mutex
+
+// GetCachePath returns the offline cache of resource lists
+func GetCachePath() (string, error) {
+	dir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache.json"), nil
+}
+
+// cacheKey identifies a list, e.g. the pods of a deployment in a cluster
+func cacheKey(kubeConfig, kind, scope string) string {
+	return strings.Join([]string{ExpandPath(kubeConfig), kind, scope}, "|")
+}
+
+func readCache(path string) map[string]CachedList {
+	lists := map[string]CachedList{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lists
+	}
+	// A corrupt cache is replaced by the next save
+	_ = json.Unmarshal(data, &lists)
+	return lists
+}
+
+// pruneCache drops the lists older than cacheMaxAge and, per kubeconfig,
+// the oldest beyond cacheMaxLists
+func pruneCache(lists map[string]CachedList, now time.Time) {
+	clusters := make(map[string][]string)
+	for key, list := range lists {
+		if now.Sub(list.Time) > cacheMaxAge {
+			delete(lists, key)
+			continue
+		}
+		cluster, _, _ := strings.Cut(key, "|")
+		clusters[cluster] = append(clusters[cluster], key)
+	}
+	for _, keys := range clusters {
+		if len(keys) <= cacheMaxLists {
+			continue
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lists[keys[i]].Time.After(lists[keys[j]].Time)
+		})
+		for _, key := range keys[cacheMaxLists:] {
+			delete(lists, key)
+		}
+	}
+}
+
+// SaveCachedList remembers a list loaded from the cluster so that it can be
+// shown while the cluster is unreachable. The file is only written when the
+// list changed or its time got older than cacheTouchAge.
+func SaveCachedList(kubeConfig, kind, scope string, list CachedList) error {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	path, err := GetCachePath()
+	if err != nil {
+		return err
+	}
+	lists := readCache(path)
+	key, now := cacheKey(kubeConfig, kind, scope), time.Now()
+	if old, ok := lists[key]; ok && now.Sub(old.Time) < cacheTouchAge &&
+		slices.Equal(old.Items, list.Items) && maps.Equal(old.Details, list.Details) {
+		return nil
+	}
+	list.Time = now
+	lists[key] = list
+	pruneCache(lists, now)
+	data, err := json.Marshal(lists)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Written aside and renamed, so that a concurrent reader never sees half
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadCachedList returns the last saved version of a list
+func LoadCachedList(kubeConfig, kind, scope string) (CachedList, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	path, err := GetCachePath()
+	if err != nil {
+		return CachedList{}, false
+	}
+	list, ok := readCache(path)[cacheKey(kubeConfig, kind, scope)]
+	if !ok || time.Since(list.Time) > cacheMaxAge {
+		return CachedList{}, false
+	}
+	return list, true
+}
@@ -1,12 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// MaxRecentItems is the default length of recent lists
 const MaxRecentItems = 5
 
 type Config struct {
@@ -19,17 +24,272 @@ type Config struct {
 	RecentLogSearches  []string            `yaml:"recent_log_searches,omitempty"`
 	RecentAssetFolders []string            `yaml:"recent_asset_folders,omitempty"`
 	RecentLocalPaths   []string            `yaml:"recent_local_paths,omitempty"`
+	LastSession        *Session            `yaml:"last_session,omitempty"`
+	LogPrefix          LogPrefix           `yaml:"log_prefix,omitempty"`
+	Recents            Recents             `yaml:"recents,omitempty"`
+	Favorites          map[string][]string `yaml:"favorites,omitempty"` // category[/scope] -> items
+	Theme              string              `yaml:"theme,omitempty"`     // dark (default), light, mono or high-contrast
+	Timeout            time.Duration       `yaml:"timeout,omitempty"`   // API server connect timeout
+	ReadOnly           bool                `yaml:"read_only,omitempty"`
+	TailLines          int64               `yaml:"tail_lines,omitempty"`
+	Accessible         bool                `yaml:"accessible,omitempty"`        // ASCII-only, high-contrast rendering with a status line
+	Prefs              map[string]Prefs    `yaml:"prefs,omitempty"`             // namespace[/deployment] -> defaults
+	PreviousReplicas   map[string]int32    `yaml:"previous_replicas,omitempty"` // namespace/deployment -> replicas before the last scale
+	Lint               Lint                `yaml:"lint,omitempty"`
+	NamespaceOverview  bool                `yaml:"namespace_overview,omitempty"` // show what needs attention before the deployment list
+	KeepDeployment     bool                `yaml:"keep_deployment,omitempty"`    // reselect the deployment after switching namespaces if it exists there
+	ExternalShell      bool                `yaml:"external_shell,omitempty"`     // leave the TUI for shells instead of the shell pane
+	Retry              Retry               `yaml:"retry,omitempty"`
+	ChangeCause        string              `yaml:"change_cause,omitempty"`   // template of the change-cause annotation, off to not set it
+	DebugImage         string              `yaml:"debug_image,omitempty"`    // image of the debug sidecar
+	NoAutoSelect       bool                `yaml:"no_auto_select,omitempty"` // list a single pod or container instead of picking it
+	LogSplit           LogSplit            `yaml:"log_split,omitempty"`
+	LogColors          bool                `yaml:"log_colors,omitempty"` // show the ANSI colors of log lines instead of stripping them
+	Schedules          []Schedule          `yaml:"schedules,omitempty"`  // actions khelper schedule run performs at their times
+	Prometheus         Prometheus          `yaml:"prometheus,omitempty"`
+	Tracing            Tracing             `yaml:"tracing,omitempty"`
+	LogBackend         LogBackend          `yaml:"log_backend,omitempty"`
+	PortForwards       PortForwards        `yaml:"port_forwards,omitempty"`
+	ReverseTunnel      ReverseTunnel       `yaml:"reverse_tunnel,omitempty"`
+	Kustomize          Kustomize           `yaml:"kustomize,omitempty"`
+
+	overrides Options // set per run, never saved
+
+	// Warnings are problems Load worked around, for the caller to show
+	Warnings []string `yaml:"-"`
 }
 
-func GetConfigPath() (string, error) {
+// LogPrefix configures the line prefix of multi-pod log streams
+type LogPrefix struct {
+	// Template is a Go text/template with .Pod, .Container, .ShortPod,
+	// .Timestamp and .Time
+	Template string `yaml:"template,omitempty"`
+	// Colors is the palette pods are assigned colors from
+	Colors  []string `yaml:"colors,omitempty"`
+	NoColor bool     `yaml:"no_color,omitempty"`
+}
+
+// DefaultLogSplit is the percentage of the log viewer's height the line
+// list takes, the full entry below gets the rest
+const DefaultLogSplit = 60
+
+// LogSplit is the log viewer's layout as last chosen
+type LogSplit struct {
+	List       int  `yaml:"list,omitempty"` // percentage of the height for the line list
+	HideDetail bool `yaml:"hide_detail,omitempty"`
+}
+
+// Session is the last full selection made in the TUI, used to resume. Pods
+// come and go, so the pod is picked again after resuming.
+type Session struct {
+	KubeConfig string `yaml:"kubeconfig,omitempty"`
+	Context    string `yaml:"context,omitempty"` // kubeconfig context, switched to on resume
+	Namespace  string `yaml:"namespace"`
+	Deployment string `yaml:"deployment"`
+	Command    string `yaml:"command,omitempty"` // selected in the command list on resume
+}
+
+// configPathOverride is the config file given with --config or KHELPER_CONFIG
+var configPathOverride string
+
+// SetConfigPath makes khelper use a config file other than the default one
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// legacyDir is where khelper kept its config and exports before it followed
+// the XDG base directories
+func legacyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".khelper"), nil
+}
+
+// xdgDir returns $<env>/khelper, or ~/<fallback>/khelper if the variable is
+// not set
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "khelper"), nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, ".khelper", "config.yml"), nil
+	return filepath.Join(home, fallback, "khelper"), nil
+}
+
+// GetConfigPath returns the config file location: the one set with
+// SetConfigPath, else $XDG_CONFIG_HOME/khelper/config.yml (on Windows
+// %AppData%\khelper\config.yml). A legacy ~/.khelper config that could not
+// be migrated is used while no new config exists.
+func GetConfigPath() (string, error) {
+	if configPathOverride != "" {
+		return configPathOverride, nil
+	}
+	legacy, err := legacyDir()
+	if err != nil {
+		return "", err
+	}
+	legacyPath := filepath.Join(legacy, "config.yml")
+
+	var dir string
+	if runtime.GOOS == "windows" {
+		if dir, err = os.UserConfigDir(); err != nil {
+			return legacyPath, nil
+		}
+		dir = filepath.Join(dir, "khelper")
+	} else if dir, err = xdgDir("XDG_CONFIG_HOME", ".config"); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "config.yml")
+	if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(legacyPath); err == nil {
+			return legacyPath, nil
+		}
+	}
+	return path, nil
+}
+
+// GetStateDir returns where the files khelper produces are kept:
+// $XDG_STATE_HOME/khelper, or the config's directory on Windows and while a
+// legacy ~/.khelper config is in use
+func GetStateDir() (string, error) {
+	path, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	legacy, err := legacyDir()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" || filepath.Dir(path) == legacy {
+		return filepath.Dir(path), nil
+	}
+	return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// GetExportDir returns the directory files exported from the TUI are written to
+func GetExportDir() (string, error) {
+	dir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "exports"), nil
+}
+
+// GetImageCacheDir returns where image layers pulled for browsing the
+// files of distroless containers are kept: $XDG_CACHE_HOME/khelper/images,
+// or %LocalAppData%\khelper\images on Windows. They can be downloaded
+// again, so they are not kept with the state.
+func GetImageCacheDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "khelper", "images"), nil
+	}
+	dir, err := xdgDir("XDG_CACHE_HOME", ".cache")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "images"), nil
+}
+
+// migrateLegacy moves the config and exports of a legacy ~/.khelper
+// directory to the XDG directories. On failure the legacy files stay where
+// they are and keep being used.
+func migrateLegacy() error {
+	if configPathOverride != "" || runtime.GOOS == "windows" {
+		return nil
+	}
+	legacy, err := legacyDir()
+	if err != nil {
+		return err
+	}
+	legacyPath := filepath.Join(legacy, "config.yml")
+	if _, err := os.Stat(legacyPath); err != nil {
+		return nil
+	}
+	configDir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(configDir, "config.yml")
+	if _, err := os.Stat(path); err == nil {
+		// Already migrated, or a new config was written meanwhile
+		return nil
+	}
+	stateDir, err := xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+	if err != nil {
+		return err
+	}
+
+	// Move the exports first: once the config is moved, exports are looked
+	// up in the state directory
+	legacyExports := filepath.Join(legacy, "exports")
+	if _, err := os.Stat(legacyExports); err == nil {
+		exports := filepath.Join(stateDir, "exports")
+		if _, err := os.Stat(exports); err == nil {
+			return fmt.Errorf("failed to migrate %s: %s already exists", legacyExports, exports)
+		}
+		if err := os.MkdirAll(stateDir, 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(legacyExports, exports); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", legacyExports, err)
+		}
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(legacyPath, path); err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", legacyPath, err)
+	}
+	// Only removed if nothing else was kept there
+	os.Remove(legacy)
+	return nil
+}
+
+// GetLogArchiveDir returns the directory logs saved for a deployment are
+// kept in: <export dir>/logs/<cluster>/<namespace>/<deployment>
+func GetLogArchiveDir(cluster, namespace, deployment string) (string, error) {
+	dir, err := GetExportDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "logs", cluster, namespace, deployment), nil
+}
+
+// GetTokenKubeConfigPath returns where the kubeconfig minted for a service
+// account is written: <export dir>/kubeconfigs/<namespace>-<service account>.yaml
+func GetTokenKubeConfigPath(namespace, serviceAccount string) (string, error) {
+	dir, err := GetExportDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kubeconfigs", namespace+"-"+serviceAccount+".yaml"), nil
+}
+
+// ExpandPath expands a leading ~ to the user's home directory and converts
+// the path to the local OS separator
+func ExpandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return filepath.Clean(filepath.FromSlash(path))
 }
 
 func Load() (*Config, error) {
+	var warnings []string
+	if err := migrateLegacy(); err != nil {
+		warnings = append(warnings, fmt.Sprintf("%v, keeping ~/.khelper", err))
+	}
+
 	configPath, err := GetConfigPath()
 	if err != nil {
 		return nil, err
@@ -38,6 +298,7 @@ func Load() (*Config, error) {
 	cfg := &Config{
 		RecentDeployments: make(map[string][]string),
 		RecentPods:        make(map[string][]string),
+		Warnings:          warnings,
 	}
 
 	data, err := os.ReadFile(configPath)
@@ -59,6 +320,7 @@ func Load() (*Config, error) {
 	if cfg.RecentPods == nil {
 		cfg.RecentPods = make(map[string][]string)
 	}
+	normalizeRecentPods(cfg.RecentPods)
 
 	return cfg, nil
 }
@@ -88,9 +350,10 @@ func (c *Config) SetNamespace(ns string) error {
 }
 
 // addToRecent adds an item to the front of a recent list, removing duplicates
-func addToRecent(list []string, item string) []string {
+// and keeping at most max items
+func addToRecent(list []string, item string, max int) []string {
 	// Remove existing occurrence
-	newList := make([]string, 0, MaxRecentItems)
+	newList := make([]string, 0, max)
 	for _, existing := range list {
 		if existing != item {
 			newList = append(newList, existing)
@@ -99,43 +362,106 @@ func addToRecent(list []string, item string) []string {
 	// Add to front
 	newList = append([]string{item}, newList...)
 	// Limit size
-	if len(newList) > MaxRecentItems {
-		newList = newList[:MaxRecentItems]
+	if len(newList) > max {
+		newList = newList[:max]
 	}
 	return newList
 }
 
+// removeFromRecent returns the list without the given items
+func removeFromRecent(list []string, items []string) []string {
+	remove := make(map[string]bool, len(items))
+	for _, item := range items {
+		remove[item] = true
+	}
+	kept := make([]string, 0, len(list))
+	for _, existing := range list {
+		if !remove[existing] {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
+
 // AddRecentDeployment adds a deployment to recent list for a namespace
 func (c *Config) AddRecentDeployment(namespace, deployment string) error {
-	c.RecentDeployments[namespace] = addToRecent(c.RecentDeployments[namespace], deployment)
+	c.RecentDeployments[namespace] = c.addToRecent(CategoryDeployments, c.RecentDeployments[namespace], deployment)
 	return c.Save()
 }
 
 // GetRecentDeployments returns recent deployments for a namespace
 func (c *Config) GetRecentDeployments(namespace string) []string {
-	return c.RecentDeployments[namespace]
+	return c.recent(CategoryDeployments, c.RecentDeployments[namespace])
+}
+
+// RemoveRecentDeployments drops deployments that no longer exist from a
+// namespace's recent list, and the last session if it pointed to one of them
+func (c *Config) RemoveRecentDeployments(namespace string, deployments []string) error {
+	c.RecentDeployments[namespace] = removeFromRecent(c.RecentDeployments[namespace], deployments)
+	if len(c.RecentDeployments[namespace]) == 0 {
+		delete(c.RecentDeployments, namespace)
+	}
+	key := favoritesKey(CategoryDeployments, namespace)
+	if favorites := removeFromRecent(c.Favorites[key], deployments); len(favorites) > 0 {
+		c.Favorites[key] = favorites
+	} else {
+		delete(c.Favorites, key)
+	}
+	if s := c.LastSession; s != nil && s.Namespace == namespace {
+		for _, d := range deployments {
+			if s.Deployment == d {
+				c.LastSession = nil
+				break
+			}
+		}
+	}
+	return c.Save()
+}
+
+// PruneNamespaces drops recent deployments of namespaces that are not in
+// existing, and the last namespace and session if they pointed to one. It
+// returns the number of namespaces removed.
+func (c *Config) PruneNamespaces(existing []string) (int, error) {
+	keep := make(map[string]bool, len(existing))
+	for _, ns := range existing {
+		keep[ns] = true
+	}
+	removed := 0
+	for ns := range c.RecentDeployments {
+		if !keep[ns] {
+			delete(c.RecentDeployments, ns)
+			removed++
+		}
+	}
+	if c.LastNamespace != "" && !keep[c.LastNamespace] {
+		c.LastNamespace = ""
+	}
+	if c.LastSession != nil && !keep[c.LastSession.Namespace] {
+		c.LastSession = nil
+	}
+	return removed, c.Save()
 }
 
 // AddRecentCommand adds a command to recent list
 func (c *Config) AddRecentCommand(command string) error {
-	c.RecentCommands = addToRecent(c.RecentCommands, command)
+	c.RecentCommands = c.addToRecent(CategoryCommands, c.RecentCommands, command)
 	return c.Save()
 }
 
 // GetRecentCommands returns recent commands
 func (c *Config) GetRecentCommands() []string {
-	return c.RecentCommands
+	return c.recent(CategoryCommands, c.RecentCommands)
 }
 
-// AddRecentPod adds a pod to recent list for a deployment
+// AddRecentPod adds a pod to recent list for a deployment, by name
 func (c *Config) AddRecentPod(deployment, pod string) error {
-	c.RecentPods[deployment] = addToRecent(c.RecentPods[deployment], pod)
+	c.RecentPods[deployment] = c.addToRecent(CategoryPods, c.RecentPods[deployment], pod)
 	return c.Save()
 }
 
 // GetRecentPods returns recent pods for a deployment
 func (c *Config) GetRecentPods(deployment string) []string {
-	return c.RecentPods[deployment]
+	return c.recent(CategoryPods, c.RecentPods[deployment])
 }
 
 // AddRecentLogSearch adds a log search term to recent list
@@ -143,47 +469,51 @@ func (c *Config) AddRecentLogSearch(search string) error {
 	if search == "" {
 		return nil
 	}
-	c.RecentLogSearches = addToRecent(c.RecentLogSearches, search)
+	c.RecentLogSearches = c.addToRecent(CategoryLogSearches, c.RecentLogSearches, search)
 	return c.Save()
 }
 
 // GetRecentLogSearches returns recent log searches
 func (c *Config) GetRecentLogSearches() []string {
-	return c.RecentLogSearches
+	return c.recent(CategoryLogSearches, c.RecentLogSearches)
 }
 
 // SetKubeConfig sets the kubeconfig path
 func (c *Config) SetKubeConfig(path string) error {
 	c.KubeConfig = path
-	c.RecentKubeConfigs = addToRecent(c.RecentKubeConfigs, path)
+	c.RecentKubeConfigs = c.addToRecent(CategoryKubeConfigs, c.RecentKubeConfigs, path)
 	return c.Save()
 }
 
-// GetKubeConfig returns the kubeconfig path
+// GetKubeConfig returns the kubeconfig path, overridden for this run or
+// from the config file
 func (c *Config) GetKubeConfig() string {
+	if c.overrides.KubeConfig != "" {
+		return c.overrides.KubeConfig
+	}
 	return c.KubeConfig
 }
 
 // GetRecentKubeConfigs returns recent kubeconfig paths
 func (c *Config) GetRecentKubeConfigs() []string {
-	return c.RecentKubeConfigs
+	return c.recent(CategoryKubeConfigs, c.RecentKubeConfigs)
 }
 
 // AddRecentKubeConfig adds a kubeconfig to recent list
 func (c *Config) AddRecentKubeConfig(path string) error {
-	c.RecentKubeConfigs = addToRecent(c.RecentKubeConfigs, path)
+	c.RecentKubeConfigs = c.addToRecent(CategoryKubeConfigs, c.RecentKubeConfigs, path)
 	return c.Save()
 }
 
 // AddRecentAssetFolder adds an asset folder to recent list
 func (c *Config) AddRecentAssetFolder(folder string) error {
-	c.RecentAssetFolders = addToRecent(c.RecentAssetFolders, folder)
+	c.RecentAssetFolders = c.addToRecent(CategoryAssetFolders, c.RecentAssetFolders, folder)
 	return c.Save()
 }
 
 // GetRecentAssetFolders returns recent asset folders
 func (c *Config) GetRecentAssetFolders() []string {
-	return c.RecentAssetFolders
+	return c.recent(CategoryAssetFolders, c.RecentAssetFolders)
 }
 
 // AddRecentLocalPath adds a local path to recent list
@@ -191,11 +521,65 @@ func (c *Config) AddRecentLocalPath(path string) error {
 	if path == "" {
 		return nil
 	}
-	c.RecentLocalPaths = addToRecent(c.RecentLocalPaths, path)
+	c.RecentLocalPaths = c.addToRecent(CategoryLocalPaths, c.RecentLocalPaths, path)
 	return c.Save()
 }
 
 // GetRecentLocalPaths returns recent local paths
 func (c *Config) GetRecentLocalPaths() []string {
-	return c.RecentLocalPaths
+	return c.recent(CategoryLocalPaths, c.RecentLocalPaths)
+}
+
+// SetPreviousReplicas records a deployment's replica count before scaling it
+func (c *Config) SetPreviousReplicas(namespace, deployment string, replicas int32) error {
+	if c.PreviousReplicas == nil {
+		c.PreviousReplicas = make(map[string]int32)
+	}
+	c.PreviousReplicas[namespace+"/"+deployment] = replicas
+	return c.Save()
+}
+
+// GetPreviousReplicas returns a deployment's replica count before it was
+// last scaled, or nil if it was never scaled with khelper
+func (c *Config) GetPreviousReplicas(namespace, deployment string) *int32 {
+	replicas, ok := c.PreviousReplicas[namespace+"/"+deployment]
+	if !ok {
+		return nil
+	}
+	return &replicas
+}
+
+// SaveSession stores the current selection as the last session
+func (c *Config) SaveSession(session Session) error {
+	c.LastSession = &session
+	return c.Save()
+}
+
+// GetLastSession returns the last session, or nil if there is nothing to resume
+func (c *Config) GetLastSession() *Session {
+	if c.LastSession == nil || c.LastSession.Namespace == "" || c.LastSession.Deployment == "" {
+		return nil
+	}
+	return c.LastSession
+}
+
+// GetLogSplit returns the log viewer's layout, the default split if none
+// was chosen
+func (c *Config) GetLogSplit() LogSplit {
+	split := c.LogSplit
+	if split.List == 0 {
+		split.List = DefaultLogSplit
+	}
+	return split
+}
+
+// SetLogSplit remembers the log viewer's layout
+func (c *Config) SetLogSplit(split LogSplit) error {
+	c.LogSplit = split
+	return c.Save()
+}
+
+// GetLogPrefix returns the multi-pod log prefix settings
+func (c *Config) GetLogPrefix() LogPrefix {
+	return c.LogPrefix
 }
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// testHome points HOME at a temporary directory and sets the XDG base
+// directories below it, or clears them without xdg. It returns the home.
+func testHome(t *testing.T, xdg bool) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows uses the known folders, not the XDG base directories")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for env, dir := range map[string]string{
+		"XDG_CONFIG_HOME": "xdg-config",
+		"XDG_STATE_HOME":  "xdg-state",
+		"XDG_CACHE_HOME":  "xdg-cache",
+	} {
+		if xdg {
+			t.Setenv(env, filepath.Join(home, dir))
+		} else {
+			t.Setenv(env, "")
+		}
+	}
+	SetConfigPath("")
+	t.Cleanup(func() { SetConfigPath("") })
+	return home
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile returns the content of a file, or "" if it does not exist
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDirs(t *testing.T) {
+	tests := []struct {
+		name     string
+		xdg      bool
+		relative string // XDG_CONFIG_HOME set to a relative path, which is ignored
+		config   string // relative to the home
+		state    string
+		cache    string
+	}{
+		{
+			name:   "XDG set",
+			xdg:    true,
+			config: "xdg-config/khelper/config.yml",
+			state:  "xdg-state/khelper",
+			cache:  "xdg-cache/khelper/images",
+		},
+		{
+			name:   "XDG unset",
+			config: ".config/khelper/config.yml",
+			state:  ".local/state/khelper",
+			cache:  ".cache/khelper/images",
+		},
+		{
+			name:     "XDG relative",
+			relative: "relative/config",
+			config:   ".config/khelper/config.yml",
+			state:    ".local/state/khelper",
+			cache:    ".cache/khelper/images",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := testHome(t, tt.xdg)
+			if tt.relative != "" {
+				t.Setenv("XDG_CONFIG_HOME", tt.relative)
+			}
+			checkPath(t, "GetConfigPath", GetConfigPath, filepath.Join(home, tt.config))
+			checkPath(t, "GetStateDir", GetStateDir, filepath.Join(home, tt.state))
+			checkPath(t, "GetExportDir", GetExportDir, filepath.Join(home, tt.state, "exports"))
+			checkPath(t, "GetImageCacheDir", GetImageCacheDir, filepath.Join(home, tt.cache))
+		})
+	}
+}
+
+func TestDirsOverride(t *testing.T) {
+	home := testHome(t, true)
+	path := filepath.Join(home, "elsewhere", "khelper.yml")
+	SetConfigPath(path)
+	checkPath(t, "GetConfigPath", GetConfigPath, path)
+	checkPath(t, "GetStateDir", GetStateDir, filepath.Join(home, "xdg-state", "khelper"))
+}
+
+func checkPath(t *testing.T, name string, get func() (string, error), want string) {
+	t.Helper()
+	got, err := get()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if got != want {
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	tests := []struct {
+		name     string
+		xdg      bool
+		files    map[string]string // relative to the home, written before
+		override bool              // a config path is set with SetConfigPath
+		wantErr  bool
+		want     map[string]string // relative to the home, "" for missing
+		config   string            // the config path used afterwards
+		state    string            // the state directory used afterwards
+	}{
+		{
+			name: "legacy only",
+			xdg:  true,
+			files: map[string]string{
+				".khelper/config.yml":        "legacy",
+				".khelper/exports/pods.json": "export",
+			},
+			want: map[string]string{
+				".khelper/config.yml":                 "",
+				".khelper/exports/pods.json":          "",
+				"xdg-config/khelper/config.yml":       "legacy",
+				"xdg-state/khelper/exports/pods.json": "export",
+			},
+			config: "xdg-config/khelper/config.yml",
+			state:  "xdg-state/khelper",
+		},
+		{
+			name: "legacy only, XDG unset",
+			files: map[string]string{
+				".khelper/config.yml":        "legacy",
+				".khelper/exports/pods.json": "export",
+			},
+			want: map[string]string{
+				".khelper/config.yml":                    "",
+				".config/khelper/config.yml":             "legacy",
+				".local/state/khelper/exports/pods.json": "export",
+			},
+			config: ".config/khelper/config.yml",
+			state:  ".local/state/khelper",
+		},
+		{
+			name: "legacy and new",
+			xdg:  true,
+			files: map[string]string{
+				".khelper/config.yml":                 "legacy",
+				".khelper/exports/pods.json":          "legacy export",
+				"xdg-config/khelper/config.yml":       "new",
+				"xdg-state/khelper/exports/pods.json": "new export",
+			},
+			want: map[string]string{
+				".khelper/config.yml":                 "legacy",
+				".khelper/exports/pods.json":          "legacy export",
+				"xdg-config/khelper/config.yml":       "new",
+				"xdg-state/khelper/exports/pods.json": "new export",
+			},
+			config: "xdg-config/khelper/config.yml",
+			state:  "xdg-state/khelper",
+		},
+		{
+			name: "new exports exist",
+			xdg:  true,
+			files: map[string]string{
+				".khelper/config.yml":                 "legacy",
+				".khelper/exports/pods.json":          "legacy export",
+				"xdg-state/khelper/exports/pods.json": "new export",
+			},
+			wantErr: true,
+			want: map[string]string{
+				".khelper/config.yml":                 "legacy",
+				".khelper/exports/pods.json":          "legacy export",
+				"xdg-config/khelper/config.yml":       "",
+				"xdg-state/khelper/exports/pods.json": "new export",
+			},
+			config: ".khelper/config.yml",
+			state:  ".khelper",
+		},
+		{
+			name: "no legacy",
+			xdg:  true,
+			want: map[string]string{
+				"xdg-config/khelper/config.yml": "",
+			},
+			config: "xdg-config/khelper/config.yml",
+			state:  "xdg-state/khelper",
+		},
+		{
+			name: "config path set",
+			xdg:  true,
+			files: map[string]string{
+				".khelper/config.yml": "legacy",
+			},
+			override: true,
+			want: map[string]string{
+				".khelper/config.yml":           "legacy",
+				"xdg-config/khelper/config.yml": "",
+			},
+			config: "custom.yml",
+			state:  "xdg-state/khelper",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := testHome(t, tt.xdg)
+			for path, content := range tt.files {
+				writeTestFile(t, filepath.Join(home, path), content)
+			}
+			if tt.override {
+				SetConfigPath(filepath.Join(home, "custom.yml"))
+			}
+
+			err := migrateLegacy()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("migrateLegacy() error = %v, want error %v", err, tt.wantErr)
+			}
+			for path, want := range tt.want {
+				if got := readTestFile(t, filepath.Join(home, path)); got != want {
+					t.Errorf("%s = %q, want %q", path, got, want)
+				}
+			}
+			checkPath(t, "GetConfigPath", GetConfigPath, filepath.Join(home, tt.config))
+			checkPath(t, "GetStateDir", GetStateDir, filepath.Join(home, tt.state))
+
+			// A second start ends the same way
+			if err := migrateLegacy(); (err != nil) != tt.wantErr {
+				t.Errorf("second migrateLegacy() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrateLegacyRemovesEmptyDir(t *testing.T) {
+	home := testHome(t, true)
+	writeTestFile(t, filepath.Join(home, ".khelper", "config.yml"), "legacy")
+	if err := migrateLegacy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".khelper")); !os.IsNotExist(err) {
+		t.Errorf("empty ~/.khelper left behind: %v", err)
+	}
+
+	// Anything else kept there is not touched
+	home = testHome(t, true)
+	writeTestFile(t, filepath.Join(home, ".khelper", "config.yml"), "legacy")
+	writeTestFile(t, filepath.Join(home, ".khelper", "notes.txt"), "mine")
+	if err := migrateLegacy(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(home, ".khelper", "notes.txt")); got != "mine" {
+		t.Errorf("~/.khelper/notes.txt = %q, want mine", got)
+	}
+}
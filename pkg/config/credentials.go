@@ -0,0 +1,332 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Credential kinds, used as the first part of credential names
+const (
+	CredentialRegistry   = "registry"
+	CredentialWebhook    = "webhook"
+	CredentialProxy      = "proxy"
+	CredentialPrometheus = "prometheus"
+	CredentialTracing    = "tracing"
+	CredentialLogs       = "logs"
+)
+
+// ErrCredentialNotFound is returned for credentials that were never stored
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// CredentialName returns the name a credential is stored under, e.g.
+// "registry/ghcr.io"
+func CredentialName(kind, id string) string {
+	return kind + "/" + id
+}
+
+// Credentials stores secrets such as registry passwords and webhook tokens
+// outside the config file: in the OS keychain when one is available, else
+// AES-GCM encrypted in credentials.yml next to the config. The key of the
+// file is kept in the keychain too if it works. Without one it is in
+// credentials.key next to the file, so the file is only obfuscated: both
+// must be mode 0600 and are refused otherwise. credentials.yml also lists
+// the names of the secrets kept in the keychain.
+type Credentials struct {
+	path     string
+	keyPath  string
+	keychain keychain // nil without an OS keychain
+}
+
+// credentialsFile is the content of credentials.yml
+type credentialsFile struct {
+	Entries map[string]credentialEntry `yaml:"entries"`
+}
+
+// credentialEntry is a stored credential: either in the keychain or
+// encrypted here
+type credentialEntry struct {
+	Keychain  bool   `yaml:"keychain,omitempty"`
+	Encrypted string `yaml:"encrypted,omitempty"` // base64 of nonce and ciphertext
+}
+
+// OpenCredentials opens the credentials store of the current config
+func OpenCredentials() (*Credentials, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(configPath)
+	return &Credentials{
+		path:     filepath.Join(dir, "credentials.yml"),
+		keyPath:  filepath.Join(dir, "credentials.key"),
+		keychain: systemKeychain(),
+	}, nil
+}
+
+// Backend describes where new secrets are stored
+func (c *Credentials) Backend() string {
+	if c.keychain != nil {
+		return c.keychain.name()
+	}
+	return "file " + c.path + ", obfuscated with the key in " + c.keyPath
+}
+
+// List returns the names of the stored credentials
+func (c *Credentials) List() ([]string, error) {
+	f, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(f.Entries))
+	for name := range f.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get returns a stored secret
+func (c *Credentials) Get(name string) (string, error) {
+	f, err := c.load()
+	if err != nil {
+		return "", err
+	}
+	entry, ok := f.Entries[name]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", name, ErrCredentialNotFound)
+	}
+	if entry.Keychain {
+		if c.keychain == nil {
+			return "", fmt.Errorf("%s is stored in a keychain that is not available", name)
+		}
+		return c.keychain.get(name)
+	}
+	return c.decrypt(entry.Encrypted)
+}
+
+// Set stores a secret, in the keychain if possible and encrypted in the
+// credentials file otherwise
+func (c *Credentials) Set(name, secret string) error {
+	if name == "" || strings.ContainsAny(name, " \t\n\"'\\") {
+		return fmt.Errorf("invalid credential name %q, use e.g. %s", name, CredentialName(CredentialRegistry, "ghcr.io"))
+	}
+	f, err := c.load()
+	if err != nil {
+		return err
+	}
+	if c.keychain != nil && c.storeInKeychain(name, secret) {
+		f.Entries[name] = credentialEntry{Keychain: true}
+		return c.save(f)
+	}
+	encrypted, err := c.encrypt(secret, !f.hasEncrypted())
+	if err != nil {
+		return err
+	}
+	f.Entries[name] = credentialEntry{Encrypted: encrypted}
+	return c.save(f)
+}
+
+// storeInKeychain stores a secret in the keychain and reports whether it can
+// be read back; a locked or broken keychain falls back to the file
+func (c *Credentials) storeInKeychain(name, secret string) bool {
+	if err := c.keychain.set(name, secret); err != nil {
+		return false
+	}
+	stored, err := c.keychain.get(name)
+	return err == nil && stored == secret
+}
+
+// RegistryLogin returns the login stored as registry/<registry>: a
+// "username:password" secret, or a token used as the password
+func (c *Credentials) RegistryLogin(registry string) (string, string, bool) {
+	username, password, ok := c.Login(CredentialRegistry, registry)
+	if ok && username == "" {
+		username = "token"
+	}
+	return username, password, ok
+}
+
+// Login returns the login stored as <kind>/<id>, such as
+// prometheus/<host>: a "username:password" secret for basic auth, or a
+// bearer token with an empty username
+func (c *Credentials) Login(kind, id string) (string, string, bool) {
+	secret, err := c.Get(CredentialName(kind, id))
+	if err != nil || secret == "" {
+		return "", "", false
+	}
+	if username, password, ok := strings.Cut(secret, ":"); ok {
+		return username, password, true
+	}
+	return "", secret, true
+}
+
+// Delete removes a stored secret
+func (c *Credentials) Delete(name string) error {
+	f, err := c.load()
+	if err != nil {
+		return err
+	}
+	entry, ok := f.Entries[name]
+	if !ok {
+		return fmt.Errorf("%s: %w", name, ErrCredentialNotFound)
+	}
+	if entry.Keychain {
+		// Dropping only the entry would leave the secret orphaned in the
+		// keychain, so it is kept until the keychain is back
+		if c.keychain == nil {
+			return fmt.Errorf("%s is stored in a keychain that is not available, it was not deleted", name)
+		}
+		if err := c.keychain.delete(name); err != nil {
+			return err
+		}
+	}
+	delete(f.Entries, name)
+	return c.save(f)
+}
+
+// hasEncrypted reports whether secrets are encrypted in the file, so its
+// key must exist
+func (f *credentialsFile) hasEncrypted() bool {
+	for _, entry := range f.Entries {
+		if !entry.Keychain {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Credentials) load() (*credentialsFile, error) {
+	f := &credentialsFile{}
+	data, err := os.ReadFile(c.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if err == nil {
+		if err := checkPrivate(c.path); err != nil {
+			return nil, err
+		}
+	}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", c.path, err)
+	}
+	if f.Entries == nil {
+		f.Entries = make(map[string]credentialEntry)
+	}
+	return f, nil
+}
+
+func (c *Credentials) save(f *credentialsFile) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0600)
+}
+
+// credentialsKeyName is the keychain entry holding the file encryption key
+const credentialsKeyName = "credentials.key"
+
+// key returns the file encryption key. With create, while nothing is
+// encrypted yet, a missing key is created: in the keychain if it works,
+// else in the key file. A key file made before the keychain was available
+// keeps being used.
+func (c *Credentials) key(create bool) ([]byte, error) {
+	key, err := os.ReadFile(c.keyPath)
+	if err == nil {
+		if err := checkPrivate(c.keyPath); err != nil {
+			return nil, err
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%s is not a valid key", c.keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read credentials key: %w", err)
+	}
+
+	if c.keychain != nil {
+		if stored, err := c.keychain.get(credentialsKeyName); err == nil {
+			key, err := hex.DecodeString(strings.TrimSpace(stored))
+			if err != nil || len(key) != 32 {
+				return nil, fmt.Errorf("the %s entry %s is not a valid key", c.keychain.name(), credentialsKeyName)
+			}
+			return key, nil
+		}
+	}
+	if !create {
+		return nil, fmt.Errorf("the credentials key is neither at %s nor in the keychain", c.keyPath)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if c.keychain != nil && c.storeInKeychain(credentialsKeyName, hex.EncodeToString(key)) {
+		return key, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.keyPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(c.keyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write credentials key: %w", err)
+	}
+	return key, nil
+}
+
+func (c *Credentials) gcm(create bool) (cipher.AEAD, error) {
+	key, err := c.key(create)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (c *Credentials) encrypt(secret string, create bool) (string, error) {
+	gcm, err := c.gcm(create)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Credentials) decrypt(encrypted string) (string, error) {
+	gcm, err := c.gcm(false)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("corrupt credential in %s", c.path)
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	secret, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt credential, was %s replaced?", c.keyPath)
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memoryKeychain is a keychain kept in memory; with broken set it fails
+// like a locked keychain
+type memoryKeychain struct {
+	secrets map[string]string
+	broken  bool
+}
+
+func newMemoryKeychain() *memoryKeychain {
+	return &memoryKeychain{secrets: make(map[string]string)}
+}
+
+func (k *memoryKeychain) name() string {
+	return "memory keychain"
+}
+
+func (k *memoryKeychain) get(name string) (string, error) {
+	secret, ok := k.secrets[name]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return secret, nil
+}
+
+func (k *memoryKeychain) set(name, secret string) error {
+	if k.broken {
+		return errors.New("keychain is locked")
+	}
+	k.secrets[name] = secret
+	return nil
+}
+
+func (k *memoryKeychain) delete(name string) error {
+	delete(k.secrets, name)
+	return nil
+}
+
+// testCredentials returns a store in a temporary directory using kc, which
+// may be nil for no keychain
+func testCredentials(t *testing.T, kc keychain) *Credentials {
+	t.Helper()
+	dir := t.TempDir()
+	return &Credentials{
+		path:     filepath.Join(dir, "credentials.yml"),
+		keyPath:  filepath.Join(dir, "credentials.key"),
+		keychain: kc,
+	}
+}
+
+func TestCredentialsEncryptedRoundTrip(t *testing.T) {
+	c := testCredentials(t, nil)
+	secrets := map[string]string{
+		CredentialName(CredentialRegistry, "ghcr.io"):     "user:s3cret",
+		CredentialName(CredentialWebhook, "deploys"):      "token-with-ümlauts",
+		CredentialName(CredentialPrometheus, "prom:9090"): "",
+	}
+	for name, secret := range secrets {
+		if err := c.Set(name, secret); err != nil {
+			t.Fatalf("Set(%s): %v", name, err)
+		}
+	}
+	for name, want := range secrets {
+		got, err := c.Get(name)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", name, err)
+		}
+		if got != want {
+			t.Errorf("Get(%s) = %q, want %q", name, got, want)
+		}
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("credentials file contains a secret in plain text:\n%s", data)
+	}
+	for _, path := range []string{c.path, c.keyPath} {
+		if err := checkPrivate(path); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCredentialsWrongKey(t *testing.T) {
+	c := testCredentials(t, nil)
+	name := CredentialName(CredentialRegistry, "ghcr.io")
+	if err := c.Set(name, "user:s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.keyPath, []byte(strings.Repeat("k", 32)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(name); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Errorf("Get with a replaced key: err = %v, want a decrypt error", err)
+	}
+
+	if err := os.WriteFile(c.keyPath, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(name); err == nil || !strings.Contains(err.Error(), "not a valid key") {
+		t.Errorf("Get with a truncated key: err = %v, want an invalid key error", err)
+	}
+
+	if err := os.Remove(c.keyPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(name); err == nil {
+		t.Error("Get without a key succeeded")
+	}
+}
+
+func TestCredentialsKeychain(t *testing.T) {
+	tests := []struct {
+		name         string
+		keychain     *memoryKeychain
+		wantKeychain bool // the secret is kept in the keychain
+		wantKeyFile  bool // the file key is written next to the file
+	}{
+		{name: "no keychain", wantKeyFile: true},
+		{name: "keychain", keychain: newMemoryKeychain(), wantKeychain: true},
+		{name: "locked keychain", keychain: &memoryKeychain{secrets: map[string]string{}, broken: true}, wantKeyFile: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kc keychain
+			if tt.keychain != nil {
+				kc = tt.keychain
+			}
+			c := testCredentials(t, kc)
+			name := CredentialName(CredentialRegistry, "ghcr.io")
+			if err := c.Set(name, "user:s3cret"); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := c.load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Entries[name].Keychain; got != tt.wantKeychain {
+				t.Errorf("entry in keychain = %v, want %v", got, tt.wantKeychain)
+			}
+			_, err = os.Stat(c.keyPath)
+			if got := err == nil; got != tt.wantKeyFile {
+				t.Errorf("key file written = %v, want %v", got, tt.wantKeyFile)
+			}
+
+			username, password, ok := c.RegistryLogin("ghcr.io")
+			if !ok || username != "user" || password != "s3cret" {
+				t.Errorf("RegistryLogin = %q, %q, %v, want user, s3cret, true", username, password, ok)
+			}
+
+			if err := c.Delete(name); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Get(name); !errors.Is(err, ErrCredentialNotFound) {
+				t.Errorf("Get after Delete: err = %v, want ErrCredentialNotFound", err)
+			}
+			if tt.keychain != nil {
+				if _, ok := tt.keychain.secrets[name]; ok {
+					t.Error("secret left in the keychain after Delete")
+				}
+			}
+		})
+	}
+}
+
+func TestCredentialsKeychainUnavailable(t *testing.T) {
+	kc := newMemoryKeychain()
+	c := testCredentials(t, kc)
+	name := CredentialName(CredentialWebhook, "deploys")
+	if err := c.Set(name, "token"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same store opened without the keychain, e.g. with it turned off
+	c.keychain = nil
+	if _, err := c.Get(name); err == nil {
+		t.Error("Get without the keychain succeeded")
+	}
+	if err := c.Delete(name); err == nil {
+		t.Error("Delete without the keychain succeeded, orphaning the secret")
+	}
+	names, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != name {
+		t.Errorf("List after a failed Delete = %v, want [%s]", names, name)
+	}
+	if _, ok := kc.secrets[name]; !ok {
+		t.Error("secret removed from the keychain")
+	}
+}
+
+func TestSystemKeychainOff(t *testing.T) {
+	t.Setenv(EnvKeychain, "off")
+	if kc := systemKeychain(); kc != nil {
+		t.Errorf("systemKeychain with %s=off = %s, want none", EnvKeychain, kc.name())
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+// maxFastDeployPresets is how many fast-deploy presets a deployment keeps
+const maxFastDeployPresets = 10
+
+// FastDeployPreset is a fast-deploy to repeat: which local directory goes to
+// which asset folder of which container, without the excluded files
+type FastDeployPreset struct {
+	// Name tells presets apart, the asset folder unless renamed
+	Name        string `yaml:"name"`
+	Container   string `yaml:"container,omitempty"`
+	LocalPath   string `yaml:"local_path"`
+	AssetFolder string `yaml:"asset_folder"`
+	// Excludes are glob patterns of files and directories not uploaded,
+	// matched against their path relative to LocalPath and their name
+	Excludes []string `yaml:"excludes,omitempty"`
+}
+
+// GetFastDeployPresets returns the fast-deploy presets of a deployment, the
+// last used first
+func (c *Config) GetFastDeployPresets(namespace, deployment string) []FastDeployPreset {
+	return c.GetPrefs(namespace, deployment).FastDeploy
+}
+
+// SaveFastDeployPreset remembers a fast-deploy of a deployment as its last
+// one. A preset of the same name is replaced; its excludes are kept if the
+// new one has none, so patterns added to the config survive.
+func (c *Config) SaveFastDeployPreset(namespace, deployment string, preset FastDeployPreset) error {
+	if c.Prefs == nil {
+		c.Prefs = make(map[string]Prefs)
+	}
+	key := prefsKey(namespace, deployment)
+	prefs := c.Prefs[key]
+	presets := []FastDeployPreset{preset}
+	for _, p := range prefs.FastDeploy {
+		if p.Name != preset.Name {
+			presets = append(presets, p)
+		} else if len(preset.Excludes) == 0 {
+			presets[0].Excludes = p.Excludes
+		}
+	}
+	if len(presets) > maxFastDeployPresets {
+		presets = presets[:maxFastDeployPresets]
+	}
+	prefs.FastDeploy = presets
+	c.Prefs[key] = prefs
+	return c.Save()
+}
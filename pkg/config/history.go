@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Operations recorded in the audit history, named like the commands
+const (
+	OpScale       = "scale"
+	OpUpdateImage = "update-image"
+	OpSetEnv      = "set-env"
+	OpRollback    = "rollback"
+	OpPatch       = "patch"
+	OpRename      = "rename"
+)
+
+// Change is a mutating operation recorded in the audit history together with
+// the state it replaced, so that it can be undone
+type Change struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	KubeConfig string    `json:"kubeconfig,omitempty"`
+	Namespace  string    `json:"namespace"`
+	Deployment string    `json:"deployment"`
+	Operation  string    `json:"operation"`
+	Container  string    `json:"container,omitempty"` // update-image and set-env
+	Key        string    `json:"key,omitempty"`       // set-env
+	// Before is the replica count, image, env value or revision the change
+	// replaced; nil for an env var that was not set
+	Before *string `json:"before"`
+	// After is the state set, or the fields a patch changed
+	After string `json:"after"`
+	// Reverts is the ID of the change this one undid
+	Reverts string `json:"reverts,omitempty"`
+	// NoUndo explains why the change cannot be undone
+	NoUndo string `json:"no_undo,omitempty"`
+	// User is the local user who made the change
+	User string `json:"user,omitempty"`
+	// Schedule is the scheduled action that made the change, if any
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// Target describes what a change modified, e.g. "replicas of web"
+func (c Change) Target() string {
+	switch c.Operation {
+	case OpScale:
+		return "replicas of " + c.Deployment
+	case OpUpdateImage:
+		return fmt.Sprintf("image of %s/%s", c.Deployment, c.Container)
+	case OpSetEnv:
+		return fmt.Sprintf("%s on %s/%s", c.Key, c.Deployment, c.Container)
+	case OpRollback:
+		return "revision of " + c.Deployment
+	case OpPatch, OpRename:
+		return c.Deployment
+	}
+	return c.Operation + " of " + c.Deployment
+}
+
+// Summary describes a change in one line, e.g. "scale web: 3 → 5"
+func (c Change) Summary() string {
+	if c.Operation == OpPatch || c.Operation == OpRename {
+		return fmt.Sprintf("%s %s: %s", c.Operation, c.Deployment, c.After)
+	}
+	return fmt.Sprintf("%s %s: %s → %s", c.Operation, c.Target(), FormatState(c.Before), c.After)
+}
+
+// FormatState renders a recorded state, "(unset)" for nil
+func FormatState(state *string) string {
+	if state == nil {
+		return "(unset)"
+	}
+	return *state
+}
+
+// GetHistoryPath returns the audit history file, one JSON change per line
+func GetHistoryPath() (string, error) {
+	dir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// RecordChange appends a change to the audit history, filling in its ID and
+// time
+func RecordChange(change Change) (Change, error) {
+	if change.Time.IsZero() {
+		change.Time = time.Now()
+	}
+	if change.ID == "" {
+		change.ID = strconv.FormatInt(change.Time.UnixNano(), 36)
+	}
+	path, err := GetHistoryPath()
+	if err != nil {
+		return change, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return change, err
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return change, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return change, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return change, fmt.Errorf("failed to write history: %w", err)
+	}
+	return change, nil
+}
+
+// LoadHistory returns the recorded changes, oldest first. Lines that cannot
+// be parsed are skipped.
+func LoadHistory() ([]Change, error) {
+	path, err := GetHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer f.Close()
+
+	var changes []Change
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var change Change
+		if json.Unmarshal(scanner.Bytes(), &change) == nil {
+			changes = append(changes, change)
+		}
+	}
+	return changes, scanner.Err()
+}
+
+// LastUndoable returns the latest change matching the filter that was
+// neither an undo itself nor undone since, or nil. Empty filter fields match
+// everything.
+func LastUndoable(changes []Change, kubeConfig, namespace, deployment string) *Change {
+	undone := make(map[string]bool)
+	for _, change := range changes {
+		if change.Reverts != "" {
+			undone[change.Reverts] = true
+		}
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if (kubeConfig != "" && change.KubeConfig != kubeConfig) ||
+			(namespace != "" && change.Namespace != namespace) ||
+			(deployment != "" && change.Deployment != deployment) {
+			continue
+		}
+		if change.Reverts != "" || undone[change.ID] {
+			continue
+		}
+		return &change
+	}
+	return nil
+}
+
+// DeployedImage is an image khelper set on a container
+type DeployedImage struct {
+	Image string
+	Time  time.Time
+	User  string
+}
+
+// ImageHistory returns the images update-image and its undo set on a
+// deployment's container, newest first, each image once with the last time
+// it was deployed
+func ImageHistory(changes []Change, kubeConfig, namespace, deployment, container string) []DeployedImage {
+	var images []DeployedImage
+	seen := make(map[string]bool)
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.Operation != OpUpdateImage || (kubeConfig != "" && change.KubeConfig != kubeConfig) ||
+			change.Namespace != namespace || change.Deployment != deployment || change.Container != container {
+			continue
+		}
+		if seen[change.After] {
+			continue
+		}
+		seen[change.After] = true
+		images = append(images, DeployedImage{Image: change.After, Time: change.Time, User: change.User})
+	}
+	return images
+}
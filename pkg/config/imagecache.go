@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// imageCacheMaxAge is how long an image layer is kept without being used
+	imageCacheMaxAge = 30 * 24 * time.Hour
+	// imageCacheMaxSize bounds the size of all cached layers; the ones used
+	// longest ago are dropped first
+	imageCacheMaxSize = 2 << 30
+)
+
+// PruneImageCache drops the cached image layers not used for
+// imageCacheMaxAge and, beyond imageCacheMaxSize in total, the ones used
+// longest ago. A layer's modification time is when it was last used. The
+// layers once kept in the state directory are removed as well.
+func PruneImageCache() error {
+	dir, err := GetImageCacheDir()
+	if err != nil {
+		return err
+	}
+	if stateDir, err := GetStateDir(); err == nil {
+		if legacy := filepath.Join(stateDir, "images"); legacy != dir {
+			_ = os.RemoveAll(legacy)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	type layer struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var layers []layer
+	now := time.Now()
+	for _, entry := range entries {
+		// Leftovers of downloads that failed are named .download-*
+		if !strings.HasPrefix(entry.Name(), "sha256-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if now.Sub(info.ModTime()) > imageCacheMaxAge {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+		layers = append(layers, layer{path: path, size: info.Size(), used: info.ModTime()})
+	}
+
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].used.After(layers[j].used)
+	})
+	var total int64
+	for _, l := range layers {
+		total += l.size
+		if total <= imageCacheMaxSize {
+			continue
+		}
+		if err := os.Remove(l.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keychainService is the service credentials are stored under in the OS
+// keychain
+const keychainService = "khelper"
+
+// keychain is an OS secret store
+type keychain interface {
+	name() string
+	get(name string) (string, error)
+	set(name, secret string) error
+	delete(name string) error
+}
+
+// systemKeychain returns the OS keychain, or nil if there is none or it was
+// turned off with KHELPER_KEYCHAIN=off
+func systemKeychain() keychain {
+	if os.Getenv(EnvKeychain) == "off" {
+		return nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			return macKeychain{}
+		}
+	case "linux", "freebsd", "openbsd":
+		// The Secret Service (GNOME Keyring, KWallet) needs a session bus
+		if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return nil
+		}
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{}
+		}
+	case "windows":
+		return platformKeychain()
+	}
+	return nil
+}
+
+// runKeychainTool runs a keychain command line tool and returns its output
+func runKeychainTool(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// macKeychain uses the macOS login keychain through the security tool
+type macKeychain struct{}
+
+func (macKeychain) name() string {
+	return "macOS keychain"
+}
+
+func (macKeychain) get(name string) (string, error) {
+	out, err := runKeychainTool("", "security", "find-generic-password", "-s", keychainService, "-a", name, "-w")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func (macKeychain) set(name, secret string) error {
+	// Passed on stdin in interactive mode and hex encoded, so the secret
+	// neither shows up in the process list nor needs quoting
+	command := fmt.Sprintf("add-generic-password -U -s %q -a %q -X %s\n", keychainService, name, hex.EncodeToString([]byte(secret)))
+	_, err := runKeychainTool(command, "security", "-i")
+	return err
+}
+
+func (macKeychain) delete(name string) error {
+	_, err := runKeychainTool("", "security", "delete-generic-password", "-s", keychainService, "-a", name)
+	return err
+}
+
+// secretService uses the freedesktop Secret Service through secret-tool
+type secretService struct{}
+
+func (secretService) name() string {
+	return "Secret Service keyring"
+}
+
+func (secretService) get(name string) (string, error) {
+	out, err := runKeychainTool("", "secret-tool", "lookup", "service", keychainService, "account", name)
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+func (secretService) set(name, secret string) error {
+	_, err := runKeychainTool(secret, "secret-tool", "store", "--label", keychainService+" "+name, "service", keychainService, "account", name)
+	return err
+}
+
+func (secretService) delete(name string) error {
+	_, err := runKeychainTool("", "secret-tool", "clear", "service", keychainService, "account", name)
+	return err
+}
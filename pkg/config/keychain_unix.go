@@ -0,0 +1,25 @@
+//go:build !windows
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// platformKeychain is nil: the Unix keychains are found by their tools
+func platformKeychain() keychain {
+	return nil
+}
+
+// checkPrivate refuses a secrets file that others may read or write
+func checkPrivate(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		return fmt.Errorf("%s is accessible by others (mode %04o), run chmod 600 %s", path, mode, path)
+	}
+	return nil
+}
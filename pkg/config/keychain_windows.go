@@ -0,0 +1,115 @@
+//go:build windows
+
+package config
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1 // CRED_TYPE_GENERIC
+	credPersistLocalMachine = 2 // CRED_PERSIST_LOCAL_MACHINE
+)
+
+// winCredential is the Win32 CREDENTIALW structure
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// platformKeychain returns the Windows Credential Manager
+func platformKeychain() keychain {
+	if err := procCredReadW.Find(); err != nil {
+		return nil
+	}
+	return credentialManager{}
+}
+
+// credentialManager keeps secrets as generic credentials of the Windows
+// Credential Manager, named khelper/<name>
+type credentialManager struct{}
+
+func (credentialManager) name() string {
+	return "Windows Credential Manager"
+}
+
+func credentialTarget(name string) (*uint16, error) {
+	return windows.UTF16PtrFromString(keychainService + "/" + name)
+}
+
+func (credentialManager) get(name string) (string, error) {
+	target, err := credentialTarget(name)
+	if err != nil {
+		return "", err
+	}
+	var cred *winCredential
+	if r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); r == 0 {
+		return "", fmt.Errorf("CredRead: %w", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (credentialManager) set(name, secret string) error {
+	target, err := credentialTarget(name)
+	if err != nil {
+		return err
+	}
+	user, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	blob := []byte(secret)
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return fmt.Errorf("CredWrite: %w", err)
+	}
+	return nil
+}
+
+func (credentialManager) delete(name string) error {
+	target, err := credentialTarget(name)
+	if err != nil {
+		return err
+	}
+	if r, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		return fmt.Errorf("CredDelete: %w", err)
+	}
+	return nil
+}
+
+// checkPrivate is a no-op: files under the user's profile are private to
+// them by the profile's ACL, not by mode bits
+func checkPrivate(path string) error {
+	return nil
+}
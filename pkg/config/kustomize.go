@@ -0,0 +1,17 @@
+package config
+
+// Kustomize configures exporting deployments as kustomize overlays
+type Kustomize struct {
+	// RepoDir is the local checkout of the GitOps repository that the
+	// config.kubernetes.io/origin annotations of deployments point into
+	RepoDir string `yaml:"repo_dir,omitempty"`
+}
+
+// GetKustomizeRepo returns the checkout the base manifests are read from,
+// empty if none is configured
+func (c *Config) GetKustomizeRepo() string {
+	if c.Kustomize.RepoDir == "" {
+		return ""
+	}
+	return ExpandPath(c.Kustomize.RepoDir)
+}
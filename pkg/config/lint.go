@@ -0,0 +1,38 @@
+package config
+
+// LintThresholds decide what happens to template lint findings before
+// update-image and set-env. Severities are info, warning, error or off.
+type LintThresholds struct {
+	// Confirm is the severity from which findings are shown and the change
+	// has to be confirmed, warning if empty
+	Confirm string `yaml:"confirm,omitempty"`
+	// Block is the severity from which the change is refused, off if empty
+	Block string `yaml:"block,omitempty"`
+}
+
+// Lint configures template linting, with thresholds overridable per profile
+type Lint struct {
+	LintThresholds `yaml:",inline"`
+	// Profiles are keyed by kubeconfig path, so that e.g. production
+	// clusters can block changes that only warn elsewhere
+	Profiles map[string]LintThresholds `yaml:"profiles,omitempty"`
+}
+
+// GetLintThresholds returns the lint thresholds for a kubeconfig: its
+// profile's settings over the global ones
+func (c *Config) GetLintThresholds(kubeConfig string) LintThresholds {
+	t := c.Lint.LintThresholds
+	for path, profile := range c.Lint.Profiles {
+		if kubeConfig == "" || ExpandPath(path) != ExpandPath(kubeConfig) {
+			continue
+		}
+		if profile.Confirm != "" {
+			t.Confirm = profile.Confirm
+		}
+		if profile.Block != "" {
+			t.Block = profile.Block
+		}
+		break
+	}
+	return t
+}
@@ -0,0 +1,103 @@
+package config
+
+import "time"
+
+// Log backend types
+const (
+	LogBackendLoki          = "loki"
+	LogBackendElasticsearch = "elasticsearch"
+)
+
+// DefaultLogHistoryRange is how far back logs-history looks without a range
+const DefaultLogHistoryRange = time.Hour
+
+// DefaultLogHistoryLimit is how many lines logs-history loads per page
+const DefaultLogHistoryLimit = 1000
+
+// Default queries of the log backends, Go text/templates with .Namespace,
+// .Deployment, .Container and .PodRegex, a regular expression matching the
+// names of the deployment's pods including deleted ones. They use the
+// labels and fields of the usual Kubernetes log shippers.
+const (
+	DefaultLokiQuery          = `{namespace="{{.Namespace}}",pod=~"{{.PodRegex}}",container="{{.Container}}"}`
+	DefaultElasticsearchQuery = `kubernetes.namespace_name:"{{.Namespace}}" AND kubernetes.pod_name:/{{.PodRegex}}/ AND kubernetes.container_name:"{{.Container}}"`
+)
+
+// LogBackendSettings locate the log store of a cluster
+type LogBackendSettings struct {
+	Type string `yaml:"type,omitempty"` // loki or elasticsearch
+	// URL is an http(s) URL, or service/<namespace>/<name>[:<port>] to go
+	// through the API server's service proxy
+	URL   string `yaml:"url,omitempty"`
+	Query string `yaml:"query,omitempty"` // LogQL stream selector or Lucene query, the type's default if empty
+	// Elasticsearch only: the index pattern and the fields of the line and
+	// the pod name
+	Index        string        `yaml:"index,omitempty"`         // * if empty
+	MessageField string        `yaml:"message_field,omitempty"` // message or log if empty
+	PodField     string        `yaml:"pod_field,omitempty"`     // kubernetes.pod_name if empty
+	Range        time.Duration `yaml:"range,omitempty"`         // how far back to look without a range, 1h if unset
+	Limit        int           `yaml:"limit,omitempty"`         // lines per page, 1000 if unset
+}
+
+// LogBackend configures where logs-history reads older logs from, with the
+// backend overridable per profile. Without one, it reads what the API
+// server still has.
+type LogBackend struct {
+	LogBackendSettings `yaml:",inline"`
+	// Profiles are keyed by kubeconfig path, as each cluster usually ships
+	// its logs somewhere else
+	Profiles map[string]LogBackendSettings `yaml:"profiles,omitempty"`
+}
+
+// GetLogBackend returns the log backend settings for a kubeconfig: its
+// profile's settings over the global ones, with the defaults filled in
+func (c *Config) GetLogBackend(kubeConfig string) LogBackendSettings {
+	s := c.LogBackend.LogBackendSettings
+	for path, profile := range c.LogBackend.Profiles {
+		if kubeConfig == "" || ExpandPath(path) != ExpandPath(kubeConfig) {
+			continue
+		}
+		if profile.Type != "" {
+			// Another kind of backend shares none of the global settings
+			s = profile
+		} else {
+			if profile.URL != "" {
+				s.URL = profile.URL
+			}
+			if profile.Query != "" {
+				s.Query = profile.Query
+			}
+			if profile.Index != "" {
+				s.Index = profile.Index
+			}
+			if profile.MessageField != "" {
+				s.MessageField = profile.MessageField
+			}
+			if profile.PodField != "" {
+				s.PodField = profile.PodField
+			}
+			if profile.Range > 0 {
+				s.Range = profile.Range
+			}
+			if profile.Limit > 0 {
+				s.Limit = profile.Limit
+			}
+		}
+		break
+	}
+	if s.Query == "" {
+		switch s.Type {
+		case LogBackendLoki:
+			s.Query = DefaultLokiQuery
+		case LogBackendElasticsearch:
+			s.Query = DefaultElasticsearchQuery
+		}
+	}
+	if s.Range <= 0 {
+		s.Range = DefaultLogHistoryRange
+	}
+	if s.Limit <= 0 {
+		s.Limit = DefaultLogHistoryLimit
+	}
+	return s
+}
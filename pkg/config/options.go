@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultTailLines is how many log lines are shown before following
+const DefaultTailLines = 100
+
+// Environment variables overriding config file settings
+const (
+	EnvConfig     = "KHELPER_CONFIG"
+	EnvKubeConfig = "KHELPER_KUBECONFIG"
+	EnvNamespace  = "KHELPER_NAMESPACE"
+	EnvTheme      = "KHELPER_THEME"
+	EnvTimeout    = "KHELPER_TIMEOUT"
+	EnvReadOnly   = "KHELPER_READ_ONLY"
+	EnvTailLines  = "KHELPER_TAIL_LINES"
+	EnvAccessible = "KHELPER_ACCESSIBLE"
+	// EnvKeychain set to "off" keeps credentials out of the OS keychain
+	EnvKeychain = "KHELPER_KEYCHAIN"
+)
+
+// Options are settings overridden for a single run by KHELPER_* environment
+// variables and command line flags. Zero values are not set. Overrides are
+// never written to the config file.
+//
+// Precedence, highest first: flags, environment, config file, defaults.
+type Options struct {
+	KubeConfig string
+	Namespace  string
+	Theme      string
+	Timeout    time.Duration
+	// ReadOnly can only switch read-only mode on, never off
+	ReadOnly  bool
+	TailLines int64
+	// Accessible can only switch accessible mode on, never off
+	Accessible bool
+}
+
+// EnvOptions reads the options set in KHELPER_* environment variables
+func EnvOptions() (Options, error) {
+	o := Options{
+		KubeConfig: os.Getenv(EnvKubeConfig),
+		Namespace:  os.Getenv(EnvNamespace),
+		Theme:      os.Getenv(EnvTheme),
+	}
+	if v := os.Getenv(EnvTimeout); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid %s: %w", EnvTimeout, err)
+		}
+		o.Timeout = timeout
+	}
+	if v := os.Getenv(EnvReadOnly); v != "" {
+		readOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid %s: %w", EnvReadOnly, err)
+		}
+		o.ReadOnly = readOnly
+	}
+	if v := os.Getenv(EnvAccessible); v != "" {
+		accessible, err := strconv.ParseBool(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid %s: %w", EnvAccessible, err)
+		}
+		o.Accessible = accessible
+	}
+	if v := os.Getenv(EnvTailLines); v != "" {
+		lines, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || lines < 0 {
+			return o, fmt.Errorf("invalid %s: %q is not a line count", EnvTailLines, v)
+		}
+		o.TailLines = lines
+	}
+	return o, nil
+}
+
+// Merge returns o with the options set in other taking precedence
+func (o Options) Merge(other Options) Options {
+	if other.KubeConfig != "" {
+		o.KubeConfig = other.KubeConfig
+	}
+	if other.Namespace != "" {
+		o.Namespace = other.Namespace
+	}
+	if other.Theme != "" {
+		o.Theme = other.Theme
+	}
+	if other.Timeout != 0 {
+		o.Timeout = other.Timeout
+	}
+	o.ReadOnly = o.ReadOnly || other.ReadOnly
+	if other.TailLines != 0 {
+		o.TailLines = other.TailLines
+	}
+	o.Accessible = o.Accessible || other.Accessible
+	return o
+}
+
+// Override applies options for this run on top of the config file
+func (c *Config) Override(o Options) {
+	c.overrides = o
+}
+
+// GetOverrides returns the options overridden for this run
+func (c *Config) GetOverrides() Options {
+	return c.overrides
+}
+
+// GetTheme returns the color theme name, empty for the default
+func (c *Config) GetTheme() string {
+	if c.overrides.Theme != "" {
+		return c.overrides.Theme
+	}
+	return c.Theme
+}
+
+// GetTimeout returns how long connecting to the API server may take, 0 for
+// no limit
+func (c *Config) GetTimeout() time.Duration {
+	if c.overrides.Timeout != 0 {
+		return c.overrides.Timeout
+	}
+	return c.Timeout
+}
+
+// IsReadOnly reports whether commands that change the cluster are disabled
+func (c *Config) IsReadOnly() bool {
+	return c.ReadOnly || c.overrides.ReadOnly
+}
+
+// IsAccessible reports whether the TUI renders for screen readers: ASCII
+// only, high contrast and with a status line
+func (c *Config) IsAccessible() bool {
+	return c.Accessible || c.overrides.Accessible
+}
+
+// GetTailLines returns how many log lines are shown before following
+func (c *Config) GetTailLines() int64 {
+	if c.overrides.TailLines != 0 {
+		return c.overrides.TailLines
+	}
+	if c.TailLines > 0 {
+		return c.TailLines
+	}
+	return DefaultTailLines
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PortForward is a saved port-forward, started by name with khelper pf up
+type PortForward struct {
+	// KubeConfig is the profile the forward belongs to, the current
+	// kubeconfig if empty
+	KubeConfig string `yaml:"kubeconfig,omitempty"`
+	Namespace  string `yaml:"namespace"`
+	// Target is a deployment or custom workload, or service/<name>
+	Target string `yaml:"target"`
+	// Ports are comma-separated local:remote pairs or single ports, e.g.
+	// 8080:80,9090, the remote ones of the service for a service
+	Ports string `yaml:"ports"`
+	// Autostart starts the forward in the background when the TUI opens for
+	// its profile
+	Autostart bool `yaml:"autostart,omitempty"`
+}
+
+// PortForwards are the saved port-forwards by name
+type PortForwards map[string]PortForward
+
+// String describes the forward, e.g. "8080:80 -> service/api in shop"
+func (pf PortForward) String() string {
+	return fmt.Sprintf("%s -> %s in %s", pf.Ports, pf.Target, pf.Namespace)
+}
+
+// PortForwardNames returns the names of the saved port-forwards, sorted
+func (c *Config) PortForwardNames() []string {
+	names := make([]string, 0, len(c.PortForwards))
+	for name := range c.PortForwards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPortForward returns a saved port-forward
+func (c *Config) GetPortForward(name string) (PortForward, error) {
+	pf, ok := c.PortForwards[name]
+	if !ok {
+		return pf, fmt.Errorf("no port-forward named %q, saved are: %s", name, strings.Join(c.PortForwardNames(), ", "))
+	}
+	return pf, nil
+}
+
+// SavePortForward saves a port-forward under a name, replacing one saved
+// under it before
+func (c *Config) SavePortForward(name string, pf PortForward) error {
+	if c.PortForwards == nil {
+		c.PortForwards = make(map[string]PortForward)
+	}
+	c.PortForwards[name] = pf
+	return c.Save()
+}
+
+// DeletePortForward removes a saved port-forward
+func (c *Config) DeletePortForward(name string) error {
+	if _, err := c.GetPortForward(name); err != nil {
+		return err
+	}
+	delete(c.PortForwards, name)
+	return c.Save()
+}
+
+// AutostartPortForwards returns the names of the port-forwards started when
+// the TUI opens for a kubeconfig: those of its profile and those without one
+func (c *Config) AutostartPortForwards(kubeConfig string) []string {
+	var names []string
+	for _, name := range c.PortForwardNames() {
+		pf := c.PortForwards[name]
+		if pf.Autostart && (pf.KubeConfig == "" || ExpandPath(pf.KubeConfig) == ExpandPath(kubeConfig)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,76 @@
+package config
+
+// Prefs are defaults for the deployments of a namespace or for a single
+// deployment. Empty fields are not set.
+type Prefs struct {
+	// Container is pre-selected in the container list
+	Container string `yaml:"container,omitempty"`
+	// Shell is tried first when opening a shell
+	Shell string `yaml:"shell,omitempty"`
+	// TailLines is how many log lines are shown before following
+	TailLines int64 `yaml:"tail_lines,omitempty"`
+	// FastDeploy are the deployment's fast-deploy presets, the last used
+	// first
+	FastDeploy []FastDeployPreset `yaml:"fast_deploy,omitempty"`
+}
+
+// merge returns p with the fields set in other taking precedence
+func (p Prefs) merge(other Prefs) Prefs {
+	if other.Container != "" {
+		p.Container = other.Container
+	}
+	if other.Shell != "" {
+		p.Shell = other.Shell
+	}
+	if other.TailLines != 0 {
+		p.TailLines = other.TailLines
+	}
+	if len(other.FastDeploy) > 0 {
+		p.FastDeploy = other.FastDeploy
+	}
+	return p
+}
+
+// prefsKey is where the prefs of a deployment are stored, or of a whole
+// namespace if deployment is empty
+func prefsKey(namespace, deployment string) string {
+	if deployment == "" {
+		return namespace
+	}
+	return namespace + "/" + deployment
+}
+
+// GetPrefs returns the defaults for a deployment: its own prefs on top of
+// its namespace's
+func (c *Config) GetPrefs(namespace, deployment string) Prefs {
+	prefs := c.Prefs[prefsKey(namespace, "")]
+	if deployment != "" {
+		prefs = prefs.merge(c.Prefs[prefsKey(namespace, deployment)])
+	}
+	return prefs
+}
+
+// SetDefaultContainer remembers the container pre-selected for a deployment
+func (c *Config) SetDefaultContainer(namespace, deployment, container string) error {
+	if c.Prefs == nil {
+		c.Prefs = make(map[string]Prefs)
+	}
+	key := prefsKey(namespace, deployment)
+	prefs := c.Prefs[key]
+	prefs.Container = container
+	c.Prefs[key] = prefs
+	return c.Save()
+}
+
+// GetDeploymentTailLines returns how many log lines are shown before
+// following a deployment's logs. An override for this run takes precedence
+// over the deployment's prefs, which take precedence over tail_lines.
+func (c *Config) GetDeploymentTailLines(namespace, deployment string) int64 {
+	if c.overrides.TailLines != 0 {
+		return c.overrides.TailLines
+	}
+	if lines := c.GetPrefs(namespace, deployment).TailLines; lines > 0 {
+		return lines
+	}
+	return c.GetTailLines()
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultPrometheusRange is how far back the metrics command looks
+const DefaultPrometheusRange = time.Hour
+
+// Units of Prometheus query results, for formatting them
+const (
+	UnitBytes   = "bytes"
+	UnitSeconds = "seconds"
+	UnitRatio   = "ratio" // shown as a percentage
+	UnitCores   = "cores"
+)
+
+// PrometheusQuery is a named PromQL query of the metrics command. The
+// query is a Go text/template with .Namespace, .Deployment and .PodRegex,
+// a regular expression matching the deployment's pod names.
+type PrometheusQuery struct {
+	Name  string `yaml:"name"`
+	Query string `yaml:"query"`
+	Unit  string `yaml:"unit,omitempty"` // bytes, seconds, ratio or cores; plain numbers if empty
+}
+
+// DefaultPrometheusQueries are the queries the metrics command runs unless
+// configured queries replace them by name. The request metrics follow the
+// common Prometheus client names.
+var DefaultPrometheusQueries = []PrometheusQuery{
+	{
+		Name:  "error rate",
+		Query: `sum(rate(http_requests_total{namespace="{{.Namespace}}",pod=~"{{.PodRegex}}",code=~"5.."}[5m])) / sum(rate(http_requests_total{namespace="{{.Namespace}}",pod=~"{{.PodRegex}}"}[5m]))`,
+		Unit:  UnitRatio,
+	},
+	{
+		Name:  "p95 latency",
+		Query: `histogram_quantile(0.95, sum by (le) (rate(http_request_duration_seconds_bucket{namespace="{{.Namespace}}",pod=~"{{.PodRegex}}"}[5m])))`,
+		Unit:  UnitSeconds,
+	},
+	{
+		Name:  "restarts",
+		Query: `sum by (pod) (increase(kube_pod_container_status_restarts_total{namespace="{{.Namespace}}",pod=~"{{.PodRegex}}"}[5m]))`,
+	},
+	{
+		Name:  "cpu",
+		Query: `sum by (pod) (rate(container_cpu_usage_seconds_total{namespace="{{.Namespace}}",pod=~"{{.PodRegex}}",container!=""}[5m]))`,
+		Unit:  UnitCores,
+	},
+	{
+		Name:  "memory",
+		Query: `sum by (pod) (container_memory_working_set_bytes{namespace="{{.Namespace}}",pod=~"{{.PodRegex}}",container!=""})`,
+		Unit:  UnitBytes,
+	},
+}
+
+// PrometheusSettings locate the Prometheus of a cluster
+type PrometheusSettings struct {
+	// URL is an http(s) URL, or service/<namespace>/<name>[:<port>] to go
+	// through the API server's service proxy
+	URL   string        `yaml:"url,omitempty"`
+	Range time.Duration `yaml:"range,omitempty"` // how far back to query, 1h if unset
+}
+
+// Prometheus configures the metrics command, with the Prometheus to ask
+// overridable per profile
+type Prometheus struct {
+	PrometheusSettings `yaml:",inline"`
+	// Profiles are keyed by kubeconfig path, as each cluster usually has
+	// its own Prometheus
+	Profiles map[string]PrometheusSettings `yaml:"profiles,omitempty"`
+	Queries  []PrometheusQuery             `yaml:"queries,omitempty"`
+}
+
+// GetPrometheus returns the Prometheus settings for a kubeconfig: its
+// profile's settings over the global ones
+func (c *Config) GetPrometheus(kubeConfig string) PrometheusSettings {
+	s := c.Prometheus.PrometheusSettings
+	for path, profile := range c.Prometheus.Profiles {
+		if kubeConfig == "" || ExpandPath(path) != ExpandPath(kubeConfig) {
+			continue
+		}
+		if profile.URL != "" {
+			s.URL = profile.URL
+		}
+		if profile.Range > 0 {
+			s.Range = profile.Range
+		}
+		break
+	}
+	if s.Range <= 0 {
+		s.Range = DefaultPrometheusRange
+	}
+	return s
+}
+
+// GetPrometheusQueries returns the default queries, replaced by configured
+// ones of the same name, followed by the other configured ones
+func (c *Config) GetPrometheusQueries() []PrometheusQuery {
+	configured := make(map[string]PrometheusQuery)
+	for _, q := range c.Prometheus.Queries {
+		configured[strings.ToLower(q.Name)] = q
+	}
+	var queries []PrometheusQuery
+	for _, q := range DefaultPrometheusQueries {
+		if own, ok := configured[strings.ToLower(q.Name)]; ok {
+			q = own
+			delete(configured, strings.ToLower(q.Name))
+		}
+		queries = append(queries, q)
+	}
+	for _, q := range c.Prometheus.Queries {
+		if _, ok := configured[strings.ToLower(q.Name)]; ok {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Recent and favorite item categories, as used in the recents settings
+const (
+	CategoryKubeConfigs  = "kubeconfigs"
+	CategoryNamespaces   = "namespaces"
+	CategoryDeployments  = "deployments"
+	CategoryCommands     = "commands"
+	CategoryPods         = "pods"
+	CategoryLogSearches  = "log_searches"
+	CategoryAssetFolders = "asset_folders"
+	CategoryLocalPaths   = "local_paths"
+)
+
+// Categories lists every recents category
+var Categories = []string{
+	CategoryKubeConfigs, CategoryNamespaces, CategoryDeployments, CategoryCommands,
+	CategoryPods, CategoryLogSearches, CategoryAssetFolders, CategoryLocalPaths,
+}
+
+// Recents configures the recent and favorite items shown at the top of lists
+type Recents struct {
+	// Max is how many recent items are kept per list, MaxRecentItems if 0
+	Max int `yaml:"max,omitempty"`
+	// Disabled lists categories whose recents are neither recorded nor shown
+	Disabled []string `yaml:"disabled,omitempty"`
+	// RecentsFirst shows recents above favorites instead of below them
+	RecentsFirst bool `yaml:"recents_first,omitempty"`
+}
+
+// maxRecent returns how many recent items are kept per list
+func (c *Config) maxRecent() int {
+	if c.Recents.Max > 0 {
+		return c.Recents.Max
+	}
+	return MaxRecentItems
+}
+
+// RecentsEnabled reports whether recents are recorded and shown for a category
+func (c *Config) RecentsEnabled(category string) bool {
+	return !slices.Contains(c.Recents.Disabled, category)
+}
+
+// recent returns a recent list, or nothing if the category is disabled
+func (c *Config) recent(category string, list []string) []string {
+	if !c.RecentsEnabled(category) {
+		return nil
+	}
+	return list
+}
+
+// normalizeRecentPods drops the phase that recent pods were once saved with,
+// as in "api-5d8f9c7b6-x7k2p (Running)", so they are remembered by name
+func normalizeRecentPods(recent map[string][]string) {
+	for deployment, pods := range recent {
+		names := make([]string, 0, len(pods))
+		for _, pod := range pods {
+			if i := strings.Index(pod, " ("); i != -1 {
+				pod = pod[:i]
+			}
+			if !slices.Contains(names, pod) {
+				names = append(names, pod)
+			}
+		}
+		recent[deployment] = names
+	}
+}
+
+// addToRecent adds an item to the front of a recent list, removing
+// duplicates. Disabled categories are left unchanged.
+func (c *Config) addToRecent(category string, list []string, item string) []string {
+	if !c.RecentsEnabled(category) {
+		return list
+	}
+	return addToRecent(list, item, c.maxRecent())
+}
+
+// favoritesKey is where favorites of a category are stored. Scoped
+// categories (deployments per namespace) include the scope.
+func favoritesKey(category, scope string) string {
+	if scope == "" {
+		return category
+	}
+	return category + "/" + scope
+}
+
+// GetFavorites returns the favorite items of a category
+func (c *Config) GetFavorites(category, scope string) []string {
+	return c.Favorites[favoritesKey(category, scope)]
+}
+
+// IsFavorite reports whether an item is a favorite
+func (c *Config) IsFavorite(category, scope, item string) bool {
+	return slices.Contains(c.GetFavorites(category, scope), item)
+}
+
+// ToggleFavorite adds an item to the favorites or removes it, and returns
+// whether it is now a favorite
+func (c *Config) ToggleFavorite(category, scope, item string) (bool, error) {
+	key := favoritesKey(category, scope)
+	if c.Favorites == nil {
+		c.Favorites = make(map[string][]string)
+	}
+	favorite := !slices.Contains(c.Favorites[key], item)
+	if favorite {
+		c.Favorites[key] = append(c.Favorites[key], item)
+	} else {
+		c.Favorites[key] = removeFromRecent(c.Favorites[key], []string{item})
+		if len(c.Favorites[key]) == 0 {
+			delete(c.Favorites, key)
+		}
+	}
+	return favorite, c.Save()
+}
+
+// Pinned returns the items shown at the top of a list: the favorites and the
+// recents, in the configured order and without duplicates
+func (c *Config) Pinned(category, scope string, recents []string) []string {
+	first, second := c.GetFavorites(category, scope), recents
+	if c.Recents.RecentsFirst {
+		first, second = second, first
+	}
+	pinned := make([]string, 0, len(first)+len(second))
+	for _, item := range append(append([]string{}, first...), second...) {
+		if !slices.Contains(pinned, item) {
+			pinned = append(pinned, item)
+		}
+	}
+	return pinned
+}
+
+// ClearRecents forgets the recent items of a category, or of all categories
+// if category is empty. Favorites are kept.
+func (c *Config) ClearRecents(category string) error {
+	switch category {
+	case "":
+		for _, cat := range Categories {
+			if err := c.clearRecents(cat); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := c.clearRecents(category); err != nil {
+			return err
+		}
+	}
+	return c.Save()
+}
+
+func (c *Config) clearRecents(category string) error {
+	switch category {
+	case CategoryKubeConfigs:
+		c.RecentKubeConfigs = nil
+	case CategoryNamespaces:
+		// Only the last namespace is remembered
+	case CategoryDeployments:
+		c.RecentDeployments = make(map[string][]string)
+	case CategoryCommands:
+		c.RecentCommands = nil
+	case CategoryPods:
+		c.RecentPods = make(map[string][]string)
+	case CategoryLogSearches:
+		c.RecentLogSearches = nil
+	case CategoryAssetFolders:
+		c.RecentAssetFolders = nil
+	case CategoryLocalPaths:
+		c.RecentLocalPaths = nil
+	default:
+		return fmt.Errorf("unknown category %q, use one of: %s", category, strings.Join(Categories, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// Retry configures retrying reads from the API server after transient
+// failures. Unset fields keep khelper's defaults.
+type Retry struct {
+	// Attempts is how many times a failed read is retried, 0 disables
+	// retrying
+	Attempts *int `yaml:"attempts,omitempty"`
+	// InitialDelay is the wait before the first retry, doubled for each
+	// next one
+	InitialDelay time.Duration `yaml:"initial_delay,omitempty"`
+	// MaxDelay caps the wait between retries
+	MaxDelay time.Duration `yaml:"max_delay,omitempty"`
+}
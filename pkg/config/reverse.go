@@ -0,0 +1,24 @@
+package config
+
+import "fmt"
+
+// ReverseTunnel configures khelper reverse, which sends a service's traffic
+// to this machine. It takes the service over from its pods, so it is only
+// allowed on the clusters listed as dev clusters.
+type ReverseTunnel struct {
+	// DevClusters are the kubeconfigs of the clusters it may be used on
+	DevClusters []string `yaml:"dev_clusters,omitempty"`
+	// Image runs the agent pod, it needs socat and sh
+	Image string `yaml:"image,omitempty"`
+}
+
+// CheckReverseTunnel fails unless reverse tunnels are allowed on the cluster
+// of a kubeconfig
+func (c *Config) CheckReverseTunnel(kubeConfig string) error {
+	for _, path := range c.ReverseTunnel.DevClusters {
+		if kubeConfig != "" && ExpandPath(path) == ExpandPath(kubeConfig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("reverse tunnels take services over from their pods and are only allowed on dev clusters: add %s to reverse_tunnel.dev_clusters in config.yml", kubeConfig)
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is an action khelper schedule run performs at the times of a cron
+// spec, such as scaling a dev namespace down in the evening
+type Schedule struct {
+	Name string `yaml:"name"`
+	// Cron is "minute hour day-of-month month day-of-week" in local time,
+	// e.g. "0 20 * * mon-fri"
+	Cron   string `yaml:"cron"`
+	Action string `yaml:"action"` // scale
+	// KubeConfig defaults to the one khelper schedule run is started with
+	KubeConfig string `yaml:"kubeconfig,omitempty"`
+	Namespace  string `yaml:"namespace"`
+	// Deployments defaults to all deployments of the namespace
+	Deployments []string `yaml:"deployments,omitempty"`
+	Replicas    string   `yaml:"replicas,omitempty"` // scale: count, prev, min or max
+}
+
+// Validate checks that a schedule can run
+func (s Schedule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("schedule without a name")
+	}
+	if _, err := ParseCron(s.Cron); err != nil {
+		return fmt.Errorf("schedule %s: %w", s.Name, err)
+	}
+	if s.Namespace == "" {
+		return fmt.Errorf("schedule %s: namespace is required", s.Name)
+	}
+	switch s.Action {
+	case OpScale:
+		if s.Replicas == "" {
+			return fmt.Errorf("schedule %s: replicas is required to scale", s.Name)
+		}
+	default:
+		return fmt.Errorf("schedule %s: unknown action %q, expected scale", s.Name, s.Action)
+	}
+	return nil
+}
+
+// ValidateSchedules checks the configured schedules and that their names
+// are unique
+func (c *Config) ValidateSchedules() error {
+	names := make(map[string]bool)
+	for _, s := range c.Schedules {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+		if names[s.Name] {
+			return fmt.Errorf("schedule %s is defined twice", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return nil
+}
+
+// Cron is a parsed cron spec, a set of allowed values per field
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny tell whether the day fields are "*": when both are
+	// restricted a day matching either runs, as in cron
+	domAny, dowAny bool
+}
+
+// cronField describes the values of a cron field
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names of the values from min on
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is Sunday too
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseCron reads a five field cron spec. Fields take *, values, ranges
+// (1-5), steps (*/15, 0-30/10), lists (1,15) and month and day names.
+func ParseCron(spec string) (Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return Cron{}, fmt.Errorf("invalid cron spec %q, expected minute hour day-of-month month day-of-week", spec)
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return Cron{}, fmt.Errorf("invalid cron spec %q: %w", spec, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return Cron{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField reads a comma separated list of a field's values
+func parseCronField(field string, f cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if r, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", s, f.name)
+			}
+			rng, step = r, n
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = cronValue(from, f); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(to, f); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 on
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q in %s", rng, f.name)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// cronValue reads a number or name of a field
+func cronValue(s string, f cronField) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q, expected %d-%d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Matches reports whether the spec runs in the minute of t
+func (c Cron) Matches(t time.Time) bool {
+	return c.minute&(1<<t.Minute()) != 0 && c.hour&(1<<t.Hour()) != 0 &&
+		c.month&(1<<int(t.Month())) != 0 && c.dayMatches(t)
+}
+
+// Next returns the first minute after t the spec runs in, or a zero time if
+// it runs in none within five years (e.g. on February 30th)
+func (c Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the spec runs on the day of t
+func (c Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
@@ -0,0 +1,39 @@
+package config
+
+// TracingSettings locate the tracing backend of a cluster
+type TracingSettings struct {
+	// URL opens a trace in a tracing UI, a Go text/template with .TraceID,
+	// e.g. https://jaeger.example.com/trace/{{.TraceID}}
+	URL string `yaml:"url,omitempty"`
+	// API is the Jaeger or Tempo query API to fetch traces from, an http(s)
+	// URL or service/<namespace>/<name>[:<port>] through the API server
+	API string `yaml:"api,omitempty"`
+}
+
+// Tracing configures looking up the traces of log lines, overridable per
+// profile
+type Tracing struct {
+	TracingSettings `yaml:",inline"`
+	// Profiles are keyed by kubeconfig path, as each cluster usually has
+	// its own tracing backend
+	Profiles map[string]TracingSettings `yaml:"profiles,omitempty"`
+}
+
+// GetTracing returns the tracing settings for a kubeconfig: its profile's
+// settings over the global ones
+func (c *Config) GetTracing(kubeConfig string) TracingSettings {
+	s := c.Tracing.TracingSettings
+	for path, profile := range c.Tracing.Profiles {
+		if kubeConfig == "" || ExpandPath(path) != ExpandPath(kubeConfig) {
+			continue
+		}
+		if profile.URL != "" {
+			s.URL = profile.URL
+		}
+		if profile.API != "" {
+			s.API = profile.API
+		}
+		break
+	}
+	return s
+}
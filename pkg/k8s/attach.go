@@ -0,0 +1,180 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// DetachKeys leave an attached session without touching the process
+const DetachKeys = "Ctrl+P Ctrl+Q"
+
+// AttachOptions holds options for attaching to a container's main process
+type AttachOptions struct {
+	Namespace     string
+	PodName       string
+	ContainerName string
+	Stdin         io.Reader
+	Stdout        io.Writer
+	Stderr        io.Writer
+}
+
+// AttachMode describes how Attach connects to a container
+type AttachMode struct {
+	Stdin bool // the container keeps stdin open, input reaches the process
+	TTY   bool // the container runs with a TTY
+}
+
+// GetAttachMode reads from the pod spec whether the container accepts input
+// and runs with a TTY. The container must be running.
+func (c *Client) GetAttachMode(ctx context.Context, namespace, podName, containerName string) (AttachMode, error) {
+	pod, err := c.GetPod(ctx, namespace, podName)
+	if err != nil {
+		return AttachMode{}, err
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == containerName && cs.State.Running == nil {
+			return AttachMode{}, fmt.Errorf("container %s is not running", containerName)
+		}
+	}
+	for _, ct := range pod.Spec.Containers {
+		if ct.Name == containerName {
+			return AttachMode{Stdin: ct.Stdin, TTY: ct.Stdin && ct.TTY}, nil
+		}
+	}
+	return AttachMode{}, fmt.Errorf("container %s not found in pod %s", containerName, podName)
+}
+
+// Attach connects to the stdio of a container's main process, like kubectl
+// attach. Input is only sent if the container keeps stdin open; with a TTY
+// the local terminal is put into raw mode. DetachKeys end the session
+// without closing the process's stdin.
+func (c *Client) Attach(ctx context.Context, opts AttachOptions) error {
+	if c.config == nil {
+		return fmt.Errorf("attach is not supported without a REST config")
+	}
+	mode, err := c.GetAttachMode(ctx, opts.Namespace, opts.PodName, opts.ContainerName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streamOpts := remotecommand.StreamOptions{Stdout: opts.Stdout, Stderr: opts.Stderr}
+	var tty *ttySession
+	if mode.TTY {
+		// A TTY merges stderr into stdout
+		streamOpts.Stderr = nil
+		streamOpts.Tty = true
+		tty, err = setupTTY(opts.Stdin, opts.Stdout, cancel)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer tty.Restore()
+		streamOpts.TerminalSizeQueue = tty.SizeQueue()
+	}
+	var stdin *detachReader
+	if mode.Stdin && opts.Stdin != nil {
+		stdin = newDetachReader(opts.Stdin, cancel)
+		defer stdin.finish()
+		streamOpts.Stdin = stdin
+	}
+
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(opts.PodName).
+		Namespace(opts.Namespace).
+		SubResource("attach").
+		VersionedParams(&corev1.PodAttachOptions{
+			Container: opts.ContainerName,
+			Stdin:     streamOpts.Stdin != nil,
+			Stdout:    streamOpts.Stdout != nil,
+			Stderr:    streamOpts.Stderr != nil,
+			TTY:       streamOpts.Tty,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	err = executor.StreamWithContext(ctx, streamOpts)
+	if tty != nil {
+		if sigErr := tty.Err(); sigErr != nil {
+			return sigErr
+		}
+	}
+	if stdin != nil && stdin.detached() {
+		return nil
+	}
+	return err
+}
+
+// detachReader forwards stdin until the detach keys are typed. It never
+// reports EOF while the session runs: the stream would close the shared stdin
+// of the main process, which ends many programs. A Ctrl+P is forwarded before
+// it is known to start the detach keys.
+type detachReader struct {
+	in     io.Reader
+	detach func()
+	prev   byte
+
+	once        sync.Once
+	done        chan struct{}
+	hasDetached atomic.Bool
+}
+
+// ctrlP and ctrlQ are the bytes of the detach keys
+const (
+	ctrlP = 0x10
+	ctrlQ = 0x11
+)
+
+func newDetachReader(in io.Reader, detach func()) *detachReader {
+	return &detachReader{in: in, detach: detach, done: make(chan struct{})}
+}
+
+func (r *detachReader) Read(p []byte) (int, error) {
+	if r.detached() {
+		return 0, r.wait()
+	}
+	n, err := r.in.Read(p)
+	for i := 0; i < n; i++ {
+		if r.prev == ctrlP && p[i] == ctrlQ {
+			r.hasDetached.Store(true)
+			r.detach()
+			// Pass on what was typed before the keys
+			if i > 0 {
+				i--
+			}
+			return i, nil
+		}
+		r.prev = p[i]
+	}
+	if err != nil {
+		return n, r.wait()
+	}
+	return n, nil
+}
+
+// wait blocks until the session is over, then reports EOF
+func (r *detachReader) wait() error {
+	<-r.done
+	return io.EOF
+}
+
+// finish releases a blocked Read once the session is over
+func (r *detachReader) finish() {
+	r.once.Do(func() { close(r.done) })
+}
+
+func (r *detachReader) detached() bool {
+	return r.hasDetached.Load()
+}
@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Capabilities is the version of the API server and the API group versions
+// it serves, read from discovery
+type Capabilities struct {
+	Version       string // e.g. v1.29.3
+	groupVersions map[string]bool
+}
+
+// FeatureAPI is an API a khelper feature relies on beyond the core ones
+type FeatureAPI struct {
+	Feature string
+	// GroupVersions are the versions of the API khelper uses, any of them
+	// will do
+	GroupVersions []string
+	Since         int // first Kubernetes 1.x minor version serving one
+	// Older is the deprecated version that servers before Since offer
+	// instead, which khelper does not use
+	Older string
+}
+
+// FeatureAPIs are the APIs khelper's features rely on beyond the core ones
+var FeatureAPIs = []FeatureAPI{
+	{Feature: "ingress", GroupVersions: []string{"networking.k8s.io/v1"}, Since: 19, Older: "networking.k8s.io/v1beta1"},
+	{Feature: "scale to min or max (HPA bounds)", GroupVersions: []string{"autoscaling/v2"}, Since: 23, Older: "autoscaling/v2beta2"},
+	{Feature: "the user in the header and whoami", GroupVersions: []string{"authentication.k8s.io/v1", "authentication.k8s.io/v1beta1"}, Since: 27},
+}
+
+// Capabilities returns what the API server supports. It is read once per
+// client; a failed read is retried the next time.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	c.capabilitiesMu.Lock()
+	defer c.capabilitiesMu.Unlock()
+	if c.capabilities != nil {
+		return c.capabilities, nil
+	}
+
+	discovery := c.clientset.Discovery()
+	info, err := discovery.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the server version: %w", err)
+	}
+	groups, err := discovery.ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API groups: %w", err)
+	}
+	caps := &Capabilities{Version: info.GitVersion, groupVersions: make(map[string]bool)}
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			caps.groupVersions[version.GroupVersion] = true
+		}
+	}
+	c.capabilities = caps
+	return caps, nil
+}
+
+// Serves reports whether the server serves an API group version. Without
+// discovery data everything is assumed to be served.
+func (caps *Capabilities) Serves(groupVersion string) bool {
+	return len(caps.groupVersions) == 0 || caps.groupVersions[groupVersion]
+}
+
+// Warnings lists the features that do not work with this server because it
+// lacks the API version they rely on
+func (caps *Capabilities) Warnings() []string {
+	var warnings []string
+	for _, api := range FeatureAPIs {
+		if slices.ContainsFunc(api.GroupVersions, caps.Serves) {
+			continue
+		}
+		warning := fmt.Sprintf("%s needs %s (Kubernetes 1.%d+)", api.Feature, strings.Join(api.GroupVersions, " or "), api.Since)
+		if api.Older != "" && caps.groupVersions[api.Older] {
+			warning += fmt.Sprintf(", this cluster only serves the deprecated %s", api.Older)
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// explainMissingAPI turns the not found error of a request to an API group
+// version the server does not serve into one saying so
+func (c *Client) explainMissingAPI(ctx context.Context, groupVersion string, err error) error {
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	caps, capsErr := c.Capabilities(ctx)
+	if capsErr != nil || caps.Serves(groupVersion) {
+		return err
+	}
+	return fmt.Errorf("this cluster (Kubernetes %s) does not serve %s: %w", caps.Version, groupVersion, err)
+}
@@ -0,0 +1,115 @@
+package k8s
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CertWarnDays is how many days before expiry certificates are flagged
+const CertWarnDays = 30
+
+// Certificate is the leaf certificate of a TLS secret
+type Certificate struct {
+	Subject   string
+	Issuer    string
+	SANs      []string // DNS names and IP addresses
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// SecretCertificate is the certificate of a TLS secret, or why it could not
+// be read
+type SecretCertificate struct {
+	Secret string
+	Cert   *Certificate
+	Err    error
+}
+
+// ListCertificates returns the certificates of the namespace's TLS secrets,
+// the ones expiring first first; unreadable ones come last
+func (c *Client) ListCertificates(ctx context.Context, namespace string) ([]SecretCertificate, error) {
+	secrets, err := c.clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeTLS),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	var certs []SecretCertificate
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Type != corev1.SecretTypeTLS {
+			continue
+		}
+		cert, err := secretCertificate(secret)
+		certs = append(certs, SecretCertificate{Secret: secret.Name, Cert: cert, Err: err})
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		a, b := certs[i].Cert, certs[j].Cert
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.NotAfter.Before(b.NotAfter)
+	})
+	return certs, nil
+}
+
+// DaysLeft returns the whole days until the certificate expires, negative
+// for days since it expired
+func (c *Certificate) DaysLeft(now time.Time) int {
+	return int(c.NotAfter.Sub(now).Hours() / 24)
+}
+
+// secretCertificate parses the leaf certificate of a TLS secret's tls.crt,
+// the first of the chain
+func secretCertificate(secret *corev1.Secret) (*Certificate, error) {
+	data := secret.Data[corev1.TLSCertKey]
+	if len(data) == 0 {
+		return nil, fmt.Errorf("secret %s has no %s", secret.Name, corev1.TLSCertKey)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("secret %s has no PEM certificate in %s", secret.Name, corev1.TLSCertKey)
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the certificate of secret %s: %w", secret.Name, err)
+		}
+		sans := append([]string{}, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			sans = append(sans, ip.String())
+		}
+		return &Certificate{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			SANs:      sans,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		}, nil
+	}
+}
+
+// Expiry renders when the certificate expires, e.g. "2026-12-01 (46 days)",
+// flagging expired ones with ✗ and those expiring within CertWarnDays with ⚠
+func (c *Certificate) Expiry(now time.Time) string {
+	days := c.DaysLeft(now)
+	date := c.NotAfter.Local().Format("2006-01-02")
+	switch {
+	case !now.Before(c.NotAfter):
+		return fmt.Sprintf("✗ expired %s (%d days ago)", date, -days)
+	case days < CertWarnDays:
+		return fmt.Sprintf("⚠ %s (%d days left)", date, days)
+	}
+	return fmt.Sprintf("%s (%d days)", date, days)
+}
@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type changeCauseKey struct{}
+
+// WithChangeCause makes the workload updates made with ctx record cause in
+// the kubernetes.io/change-cause annotation, which the rollout history of
+// the new revision shows. An empty cause leaves the annotation alone.
+func WithChangeCause(ctx context.Context, cause string) context.Context {
+	return context.WithValue(ctx, changeCauseKey{}, cause)
+}
+
+// setChangeCause annotates obj with the change cause carried by ctx
+func setChangeCause(ctx context.Context, obj metav1.Object) {
+	cause, _ := ctx.Value(changeCauseKey{}).(string)
+	if cause == "" {
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ChangeCauseAnnotation] = cause
+	obj.SetAnnotations(annotations)
+}
@@ -0,0 +1,115 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultCleanupRestarts is the restart count from which crash looping pods
+// are offered for cleanup
+const DefaultCleanupRestarts = 5
+
+// CleanupPod is a pod that is done or broken and only clutters the namespace
+type CleanupPod struct {
+	Name   string
+	Reason string // Evicted, Completed, Failed or CrashLoopBackOff
+	Detail string // e.g. the eviction message or the restart count
+	Since  time.Time
+	Owner  string // e.g. ReplicaSet/web-7d4b9, empty for bare pods
+}
+
+// ListCleanupPods finds the namespace's evicted, completed and failed pods
+// and those crash looping with at least restarts restarts, oldest first
+func (c *Client) ListCleanupPods(ctx context.Context, namespace string, restarts int32) ([]CleanupPod, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	var found []CleanupPod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		p := CleanupPod{Name: pod.Name, Since: pod.CreationTimestamp.Time}
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			p.Owner = owner.Kind + "/" + owner.Name
+		}
+		switch pod.Status.Phase {
+		case corev1.PodFailed:
+			p.Reason, p.Detail = "Failed", pod.Status.Message
+			if pod.Status.Reason == "Evicted" {
+				p.Reason = "Evicted"
+			} else if pod.Status.Reason != "" {
+				p.Detail = pod.Status.Reason + ": " + p.Detail
+			}
+			p.Since = podFinishedAt(pod)
+		case corev1.PodSucceeded:
+			p.Reason = "Completed"
+			p.Since = podFinishedAt(pod)
+		default:
+			status := crashLooping(pod, restarts)
+			if status == nil {
+				continue
+			}
+			p.Reason = "CrashLoopBackOff"
+			p.Detail = fmt.Sprintf("%s restarted %d times", status.Name, status.RestartCount)
+			if p.Owner != "" {
+				p.Detail += ", deleting recreates it"
+			}
+		}
+		found = append(found, p)
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Since.Before(found[j].Since) })
+	return found, nil
+}
+
+// crashLooping returns the status of a container crash looping with at least
+// restarts restarts, nil if there is none
+func crashLooping(pod *corev1.Pod, restarts int32) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		status := &pod.Status.ContainerStatuses[i]
+		if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" && status.RestartCount >= restarts {
+			return status
+		}
+	}
+	return nil
+}
+
+// podFinishedAt is when the pod's last container terminated, else when it
+// was created
+func podFinishedAt(pod *corev1.Pod) time.Time {
+	finished := pod.CreationTimestamp.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if t := status.State.Terminated; t != nil && t.FinishedAt.After(finished) {
+			finished = t.FinishedAt.Time
+		}
+	}
+	return finished
+}
+
+// DeletePods deletes the pods one after another and returns those deleted.
+// Pods already gone count as deleted; other failures are joined in the error.
+func (c *Client) DeletePods(ctx context.Context, namespace string, names []string) ([]string, error) {
+	var deleted []string
+	var errs []error
+	for _, name := range names {
+		err := c.clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete pod %s: %w", name, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		deleted = append(deleted, name)
+	}
+	return deleted, errors.Join(errs...)
+}
@@ -3,68 +3,182 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// InClusterKubeConfig is reported as the kubeconfig path when running with
+// the pod's mounted service account
+const InClusterKubeConfig = "(in-cluster)"
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
 type Client struct {
-	clientset  *kubernetes.Clientset
+	clientset  kubernetes.Interface
+	dynamic    dynamic.Interface // for Deployment-like custom resources, may be nil
 	config     *rest.Config
 	kubeconfig string
+	context    string // kubeconfig context in use, empty in-cluster
+	retries    chan RetryEvent
+	exec       ExecFunc // runs commands in containers instead of the API server, may be nil
+
+	capabilitiesMu sync.Mutex
+	capabilities   *Capabilities // read from discovery on first use
+}
+
+// ClientOptions configures a new Client
+type ClientOptions struct {
+	// KubeConfig is the kubeconfig file to use. If empty, the in-cluster
+	// config is tried first, then $KUBECONFIG and ~/.kube/config.
+	KubeConfig string
+	// Context is the kubeconfig context to use, empty for its current
+	// context. A context the kubeconfig does not have is an error.
+	Context string
+	// InCluster uses the pod's mounted service account instead of a
+	// kubeconfig
+	InCluster bool
+	// ConnectTimeout limits how long connecting to the API server may take,
+	// 0 for the system default. Established streams such as followed logs
+	// are not affected.
+	ConnectTimeout time.Duration
 }
 
 // NewClient creates a new Kubernetes client with default kubeconfig
 func NewClient() (*Client, error) {
-	return NewClientWithConfig("")
+	return NewClientWithOptions(ClientOptions{})
 }
 
 // NewClientWithConfig creates a new Kubernetes client with specified kubeconfig
 func NewClientWithConfig(kubeconfigPath string) (*Client, error) {
-	config, kubeconfig, err := getKubeConfig(kubeconfigPath)
+	return NewClientWithOptions(ClientOptions{KubeConfig: kubeconfigPath})
+}
+
+// NewInClusterClient creates a client from the mounted service account
+func NewInClusterClient() (*Client, error) {
+	return NewClientWithOptions(ClientOptions{InCluster: true})
+}
+
+// NewClientWithOptions creates a new Kubernetes client configured by opts
+func NewClientWithOptions(opts ClientOptions) (*Client, error) {
+	var config *rest.Config
+	var kubeconfig, kubeContext string
+	var err error
+	if opts.InCluster {
+		config, err = rest.InClusterConfig()
+		kubeconfig = InClusterKubeConfig
+	} else {
+		config, kubeconfig, kubeContext, err = getKubeConfig(opts.KubeConfig, opts.Context)
+	}
 	if err != nil {
 		return nil, err
 	}
+	applyConnectTimeout(config, opts.ConnectTimeout)
+	retries := applyRetry(config)
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
 		clientset:  clientset,
+		dynamic:    dynamicClient,
 		config:     config,
 		kubeconfig: kubeconfig,
+		context:    kubeContext,
+		retries:    retries,
 	}, nil
 }
 
+// IsInCluster reports whether khelper is running inside a pod with a
+// mounted service account
+func IsInCluster() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(serviceAccountDir, "token"))
+	return err == nil
+}
+
+// InClusterNamespace returns the namespace khelper's own pod runs in, taken
+// from POD_NAMESPACE or the service account mount
+func InClusterNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// NewClientFromClientset wraps an existing clientset. config may be nil, in
+// which case streaming operations such as exec and port-forward fail.
+func NewClientFromClientset(clientset kubernetes.Interface, config *rest.Config, kubeconfig string) *Client {
+	return &Client{
+		clientset:  clientset,
+		config:     config,
+		kubeconfig: kubeconfig,
+	}
+}
+
 // GetKubeConfigPath returns the path of the kubeconfig being used
 func (c *Client) GetKubeConfigPath() string {
 	return c.kubeconfig
 }
 
-func getKubeConfig(kubeconfigPath string) (*rest.Config, string, error) {
+// KubeContext returns the kubeconfig context being used, empty in-cluster
+func (c *Client) KubeContext() string {
+	return c.context
+}
+
+// applyConnectTimeout makes the client give up connecting after timeout
+func applyConnectTimeout(config *rest.Config, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	config.Dial = dialer.DialContext
+}
+
+// getKubeConfig returns the config of a kubeconfig's context, the path and
+// the name of the context used
+func getKubeConfig(kubeconfigPath, kubeContext string) (*rest.Config, string, string, error) {
 	// If a specific path is provided, use it
 	if kubeconfigPath != "" {
-		config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+		config, name, err := contextConfig(kubeconfigPath, kubeContext)
 		if err != nil {
-			return nil, "", err
+			return nil, "", "", err
 		}
-		return config, kubeconfigPath, nil
+		return config, kubeconfigPath, name, nil
 	}
 
-	// Try in-cluster config first
-	config, err := rest.InClusterConfig()
-	if err == nil {
-		return config, "(in-cluster)", nil
+	// Try in-cluster config first, unless a context is asked for
+	if kubeContext == "" {
+		if config, err := rest.InClusterConfig(); err == nil {
+			return config, InClusterKubeConfig, "", nil
+		}
 	}
 
 	// Fall back to kubeconfig file
@@ -72,23 +186,47 @@ func getKubeConfig(kubeconfigPath string) (*rest.Config, string, error) {
 	if kubeconfig == "" {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, "", err
+			return nil, "", "", err
 		}
 		kubeconfig = filepath.Join(home, ".kube", "config")
 	}
 
-	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	config, name, err := contextConfig(kubeconfig, kubeContext)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return config, kubeconfig, name, nil
+}
+
+// contextConfig returns the config of a context of a kubeconfig, its
+// current one if kubeContext is empty, and the context's name
+func contextConfig(kubeconfigPath, kubeContext string) (*rest.Config, string, error) {
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	kubeconfig, err := loader.RawConfig()
 	if err != nil {
 		return nil, "", err
 	}
-	return config, kubeconfig, nil
+	if kubeContext == "" {
+		kubeContext = kubeconfig.CurrentContext
+	} else if _, ok := kubeconfig.Contexts[kubeContext]; !ok {
+		return nil, "", fmt.Errorf("context %s not found in %s", kubeContext, kubeconfigPath)
+	}
+	config, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	return config, kubeContext, nil
 }
 
+// GetConfig returns the REST config the client was created with
 func (c *Client) GetConfig() *rest.Config {
 	return c.config
 }
 
-func (c *Client) GetClientset() *kubernetes.Clientset {
+// GetClientset returns the clientset behind the client, a fake one in tests
+func (c *Client) GetClientset() kubernetes.Interface {
 	return c.clientset
 }
 
@@ -122,8 +260,54 @@ func (c *Client) ListDeployments(ctx context.Context, namespace string) ([]strin
 	return names, nil
 }
 
-// GetDeployment returns a specific deployment
+// ListAllDeployments returns "namespace/name" of the deployments in all
+// namespaces. Without the right to list them cluster-wide, the namespaces
+// are listed one by one, skipping those that may not be read.
+func (c *Client) ListAllDeployments(ctx context.Context) ([]string, error) {
+	deployments, err := c.clientset.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		names := make([]string, 0, len(deployments.Items))
+		for _, dep := range deployments.Items {
+			names = append(names, dep.Namespace+"/"+dep.Name)
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+	if !apierrors.IsForbidden(err) {
+		return nil, err
+	}
+
+	namespaces, nsErr := c.ListNamespaces(ctx)
+	if nsErr != nil {
+		return nil, err
+	}
+	var names []string
+	for _, namespace := range namespaces {
+		deployments, err := c.ListDeployments(ctx, namespace)
+		if apierrors.IsForbidden(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range deployments {
+			names = append(names, namespace+"/"+name)
+		}
+	}
+	return names, nil
+}
+
+// GetDeployment returns a specific deployment. Custom workload references
+// ("rollout/name") are returned converted to a Deployment, see
+// workloadAsDeployment.
 func (c *Client) GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error) {
+	if kind, workloadName := parseWorkloadRef(name); kind != nil {
+		obj, err := c.getWorkload(ctx, namespace, kind, workloadName)
+		if err != nil {
+			return nil, err
+		}
+		return workloadAsDeployment(obj)
+	}
 	return c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
@@ -145,19 +329,24 @@ func (c *Client) ListPods(ctx context.Context, namespace, deploymentName string)
 	return pods.Items, nil
 }
 
-// ListPodNames returns pod names for a deployment
-func (c *Client) ListPodNames(ctx context.Context, namespace, deploymentName string) ([]string, error) {
+// PodEntry is a pod as listed to pick one: its name and phase
+type PodEntry struct {
+	Name  string
+	Phase corev1.PodPhase
+}
+
+// ListPodEntries returns the pods of a deployment with their phase
+func (c *Client) ListPodEntries(ctx context.Context, namespace, deploymentName string) ([]PodEntry, error) {
 	pods, err := c.ListPods(ctx, namespace, deploymentName)
 	if err != nil {
 		return nil, err
 	}
 
-	names := make([]string, 0, len(pods))
+	entries := make([]PodEntry, 0, len(pods))
 	for _, pod := range pods {
-		status := string(pod.Status.Phase)
-		names = append(names, fmt.Sprintf("%s (%s)", pod.Name, status))
+		entries = append(entries, PodEntry{Name: pod.Name, Phase: pod.Status.Phase})
 	}
-	return names, nil
+	return entries, nil
 }
 
 // GetPod returns a specific pod
@@ -179,8 +368,53 @@ func (c *Client) ListContainers(ctx context.Context, namespace, podName string)
 	return names, nil
 }
 
+// ListWorkloadContainers returns the container names found in any of a
+// deployment's pods, including sidecars injected into only some of them
+func (c *Client) ListWorkloadContainers(ctx context.Context, namespace, deploymentName string) ([]string, error) {
+	pods, err := c.ListPods(ctx, namespace, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if !seen[container.Name] {
+				seen[container.Name] = true
+				names = append(names, container.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListPodEntriesWithContainer returns the pods of a deployment that run a
+// container, with their phase
+func (c *Client) ListPodEntriesWithContainer(ctx context.Context, namespace, deploymentName, containerName string) ([]PodEntry, error) {
+	pods, err := c.ListPods(ctx, namespace, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []PodEntry
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == containerName {
+				entries = append(entries, PodEntry{Name: pod.Name, Phase: pod.Status.Phase})
+				break
+			}
+		}
+	}
+	return entries, nil
+}
+
 // ScaleDeployment scales a deployment to the specified replicas
 func (c *Client) ScaleDeployment(ctx context.Context, namespace, name string, replicas int32) error {
+	if kind, workloadName := parseWorkloadRef(name); kind != nil {
+		return c.scaleWorkload(ctx, namespace, kind, workloadName, replicas)
+	}
 	scale, err := c.clientset.AppsV1().Deployments(namespace).GetScale(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return err
@@ -192,18 +426,25 @@ func (c *Client) ScaleDeployment(ctx context.Context, namespace, name string, re
 
 // UpdateImage updates the image of a container in a deployment
 func (c *Client) UpdateImage(ctx context.Context, namespace, deploymentName, containerName, image string) error {
+	return c.UpdateImages(ctx, namespace, deploymentName, map[string]string{containerName: image})
+}
+
+// UpdateImages sets the images of several containers, keyed by container
+// name, in a single update so that they roll out together
+func (c *Client) UpdateImages(ctx context.Context, namespace, deploymentName string, images map[string]string) error {
+	if kind, workloadName := parseWorkloadRef(deploymentName); kind != nil {
+		return c.updateWorkloadImages(ctx, namespace, kind, workloadName, images)
+	}
 	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
 	if err != nil {
 		return err
 	}
 
-	for i, container := range deployment.Spec.Template.Spec.Containers {
-		if container.Name == containerName {
-			deployment.Spec.Template.Spec.Containers[i].Image = image
-			break
-		}
+	if err := EditImages(images)(&deployment.Spec.Template); err != nil {
+		return fmt.Errorf("%w in deployment %s", err, deploymentName)
 	}
 
+	setChangeCause(ctx, deployment)
 	_, err = c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
 	return err
 }
@@ -230,13 +471,16 @@ func (c *Client) GetReplicaSets(ctx context.Context, namespace, deploymentName s
 func (c *Client) GetIngresses(ctx context.Context, namespace string) ([]networkingv1.Ingress, error) {
 	ingresses, err := c.clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return nil, err
+		return nil, c.explainMissingAPI(ctx, "networking.k8s.io/v1", err)
 	}
 	return ingresses.Items, nil
 }
 
 // SetEnvVar sets an environment variable on a container in a deployment
 func (c *Client) SetEnvVar(ctx context.Context, namespace, deploymentName, containerName, key, value string) error {
+	if kind, workloadName := parseWorkloadRef(deploymentName); kind != nil {
+		return errDeploymentOnly("set-env", kind, workloadName)
+	}
 	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
 	if err != nil {
 		return err
@@ -262,6 +506,35 @@ func (c *Client) SetEnvVar(ctx context.Context, namespace, deploymentName, conta
 		}
 	}
 
+	setChangeCause(ctx, deployment)
+	_, err = c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	return err
+}
+
+// UnsetEnvVar removes an environment variable from a container in a deployment
+func (c *Client) UnsetEnvVar(ctx context.Context, namespace, deploymentName, containerName, key string) error {
+	if kind, workloadName := parseWorkloadRef(deploymentName); kind != nil {
+		return errDeploymentOnly("set-env", kind, workloadName)
+	}
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return err
+	}
+
+	for i, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == containerName {
+			env := container.Env[:0]
+			for _, e := range container.Env {
+				if e.Name != key {
+					env = append(env, e)
+				}
+			}
+			deployment.Spec.Template.Spec.Containers[i].Env = env
+			break
+		}
+	}
+
+	setChangeCause(ctx, deployment)
 	_, err = c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
 	return err
 }
@@ -284,6 +557,9 @@ func (c *Client) GetEnvVars(ctx context.Context, namespace, deploymentName, cont
 
 // RollbackDeployment rolls back a deployment to a previous revision
 func (c *Client) RollbackDeployment(ctx context.Context, namespace, name string, revision int64) error {
+	if kind, workloadName := parseWorkloadRef(name); kind != nil {
+		return errDeploymentOnly("rollback", kind, workloadName)
+	}
 	// Get the deployment
 	deployment, err := c.GetDeployment(ctx, namespace, name)
 	if err != nil {
@@ -312,6 +588,7 @@ func (c *Client) RollbackDeployment(ctx context.Context, namespace, name string,
 
 	// Update deployment with the pod template from the target replica set
 	deployment.Spec.Template = targetRS.Spec.Template
+	setChangeCause(ctx, deployment)
 	_, err = c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
 	return err
 }
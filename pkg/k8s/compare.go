@@ -0,0 +1,307 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// zoneLabel is the well-known node label holding the node's zone
+const zoneLabel = "topology.kubernetes.io/zone"
+
+// compareRow is one compared field of two pods
+type compareRow struct {
+	field string
+	a, b  string
+	// info rows always differ between pods and are not marked
+	info bool
+}
+
+// ComparePods returns a side-by-side comparison of two pods: node placement,
+// labels, and for each container its image, state, resources and env.
+// Differing rows are marked, so it is quick to see why only one replica
+// misbehaves.
+func (c *Client) ComparePods(ctx context.Context, namespace, podA, podB string) (string, error) {
+	a, err := c.GetPod(ctx, namespace, podA)
+	if err != nil {
+		return "", err
+	}
+	b, err := c.GetPod(ctx, namespace, podB)
+	if err != nil {
+		return "", err
+	}
+
+	var sections []compareSection
+	add := func(title string, rows []compareRow) {
+		sections = append(sections, compareSection{title: title, rows: rows})
+	}
+
+	add("Placement", []compareRow{
+		{field: "Node", a: a.Spec.NodeName, b: b.Spec.NodeName},
+		{field: "Zone", a: c.nodeZone(ctx, a.Spec.NodeName), b: c.nodeZone(ctx, b.Spec.NodeName)},
+		{field: "Phase", a: string(a.Status.Phase), b: string(b.Status.Phase)},
+		{field: "QoS class", a: string(a.Status.QOSClass), b: string(b.Status.QOSClass)},
+		{"Pod IP", a.Status.PodIP, b.Status.PodIP, true},
+		{"Started", formatStartTime(a.Status.StartTime), formatStartTime(b.Status.StartTime), true},
+	})
+	add("Labels", compareMaps(a.Labels, b.Labels))
+
+	for _, name := range containerNames(a, b) {
+		ca, cb := findContainer(a, name), findContainer(b, name)
+		sa, sb := containerStatus(a, name), containerStatus(b, name)
+
+		rows := []compareRow{
+			{field: "Image", a: containerImage(ca), b: containerImage(cb)},
+			{field: "Image ID", a: statusImageID(sa), b: statusImageID(sb)},
+			{field: "State", a: statusState(sa), b: statusState(sb)},
+			{field: "Restarts", a: statusRestarts(sa), b: statusRestarts(sb)},
+		}
+		rows = append(rows, compareResources(ca, cb)...)
+		rows = append(rows, compareMaps(containerEnv(ca), containerEnv(cb))...)
+		add("Container "+name, rows)
+	}
+	return renderComparison(a.Name, b.Name, sections), nil
+}
+
+// CompareDeployments returns a side-by-side comparison of two deployments,
+// typically the same one in two clusters: replicas, strategy, and for each
+// container its image, resources and env. Differing rows are marked.
+func CompareDeployments(a, b *appsv1.Deployment, nameA, nameB string) string {
+	var sections []compareSection
+	sections = append(sections, compareSection{title: "Deployment", rows: []compareRow{
+		{field: "Replicas", a: deploymentReplicas(a), b: deploymentReplicas(b)},
+		{field: "Strategy", a: string(a.Spec.Strategy.Type), b: string(b.Spec.Strategy.Type)},
+		{field: "Service account", a: a.Spec.Template.Spec.ServiceAccountName, b: b.Spec.Template.Spec.ServiceAccountName},
+		{"Ready", fmt.Sprint(a.Status.ReadyReplicas), fmt.Sprint(b.Status.ReadyReplicas), true},
+		{"Revision", a.Annotations[revisionAnnotation], b.Annotations[revisionAnnotation], true},
+	}})
+
+	podA := &corev1.Pod{Spec: a.Spec.Template.Spec}
+	podB := &corev1.Pod{Spec: b.Spec.Template.Spec}
+	for _, name := range containerNames(podA, podB) {
+		ca, cb := findContainer(podA, name), findContainer(podB, name)
+		rows := []compareRow{{field: "Image", a: containerImage(ca), b: containerImage(cb)}}
+		rows = append(rows, compareResources(ca, cb)...)
+		rows = append(rows, compareMaps(containerEnv(ca), containerEnv(cb))...)
+		sections = append(sections, compareSection{title: "Container " + name, rows: rows})
+	}
+	return renderComparison(nameA, nameB, sections)
+}
+
+func deploymentReplicas(d *appsv1.Deployment) string {
+	if d.Spec.Replicas == nil {
+		return "1"
+	}
+	return fmt.Sprint(*d.Spec.Replicas)
+}
+
+// compareSection is a titled group of compared fields
+type compareSection struct {
+	title string
+	rows  []compareRow
+}
+
+// renderComparison lays out the sections in two columns and counts the
+// differing rows
+func renderComparison(nameA, nameB string, sections []compareSection) string {
+	w := newDescribeWriter()
+	w.line(0, "  \t%s\t%s", nameA, nameB)
+
+	differences := 0
+	for _, s := range sections {
+		w.line(0, "%s:\t\t", s.title)
+		for _, row := range s.rows {
+			marker := " "
+			if row.a != row.b && !row.info {
+				marker = "≠"
+				differences++
+			}
+			w.line(0, "%s %s\t%s\t%s", marker, row.field, orNone(row.a), orNone(row.b))
+		}
+	}
+	w.line(0, "")
+	w.line(0, "%d difference(s), marked with ≠", differences)
+	return w.String()
+}
+
+// nodeZone returns the zone label of a node, or "" if it cannot be read
+func (c *Client) nodeZone(ctx context.Context, nodeName string) string {
+	if nodeName == "" {
+		return ""
+	}
+	node, err := c.clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	return node.Labels[zoneLabel]
+}
+
+// compareMaps returns a row for every key in either map, sorted by key
+func compareMaps(a, b map[string]string) []compareRow {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	rows := make([]compareRow, 0, len(sorted))
+	for _, k := range sorted {
+		rows = append(rows, compareRow{field: k, a: a[k], b: b[k]})
+	}
+	return rows
+}
+
+func compareResources(a, b *corev1.Container) []compareRow {
+	var rows []compareRow
+	for _, kind := range []string{"requests", "limits"} {
+		list := func(c *corev1.Container) corev1.ResourceList {
+			if c == nil {
+				return nil
+			}
+			if kind == "requests" {
+				return c.Resources.Requests
+			}
+			return c.Resources.Limits
+		}
+		la, lb := list(a), list(b)
+		names := map[corev1.ResourceName]bool{}
+		for n := range la {
+			names[n] = true
+		}
+		for n := range lb {
+			names[n] = true
+		}
+		sorted := make([]string, 0, len(names))
+		for n := range names {
+			sorted = append(sorted, string(n))
+		}
+		sort.Strings(sorted)
+		for _, n := range sorted {
+			rows = append(rows, compareRow{
+				field: kind + "." + n,
+				a:     quantityString(la, corev1.ResourceName(n)),
+				b:     quantityString(lb, corev1.ResourceName(n)),
+			})
+		}
+	}
+	return rows
+}
+
+func quantityString(list corev1.ResourceList, name corev1.ResourceName) string {
+	if q, ok := list[name]; ok {
+		return q.String()
+	}
+	return ""
+}
+
+// containerNames returns the container names of both pods, in spec order
+func containerNames(a, b *corev1.Pod) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, pod := range []*corev1.Pod{a, b} {
+		for _, c := range pod.Spec.Containers {
+			if !seen[c.Name] {
+				seen[c.Name] = true
+				names = append(names, c.Name)
+			}
+		}
+	}
+	return names
+}
+
+func findContainer(pod *corev1.Pod, name string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+func containerImage(c *corev1.Container) string {
+	if c == nil {
+		return ""
+	}
+	return c.Image
+}
+
+// containerEnv returns a container's env as "env.NAME" -> value or source
+func containerEnv(c *corev1.Container) map[string]string {
+	env := map[string]string{}
+	if c == nil {
+		return env
+	}
+	for _, e := range c.Env {
+		env["env."+e.Name] = describeEnvValue(e)
+	}
+	for _, from := range c.EnvFrom {
+		switch {
+		case from.ConfigMapRef != nil:
+			env["envFrom.configMap."+from.ConfigMapRef.Name] = from.Prefix + "*"
+		case from.SecretRef != nil:
+			env["envFrom.secret."+from.SecretRef.Name] = from.Prefix + "*"
+		}
+	}
+	return env
+}
+
+// statusImageID returns the digest of the image that is actually running
+func statusImageID(s *corev1.ContainerStatus) string {
+	if s == nil {
+		return ""
+	}
+	if i := strings.LastIndex(s.ImageID, "@"); i >= 0 {
+		return s.ImageID[i+1:]
+	}
+	return s.ImageID
+}
+
+func statusState(s *corev1.ContainerStatus) string {
+	switch {
+	case s == nil:
+		return ""
+	case s.State.Running != nil:
+		if s.Ready {
+			return "Running, ready"
+		}
+		return "Running, not ready"
+	case s.State.Waiting != nil:
+		return "Waiting: " + s.State.Waiting.Reason
+	case s.State.Terminated != nil:
+		return fmt.Sprintf("Terminated: %s (exit %d)", s.State.Terminated.Reason, s.State.Terminated.ExitCode)
+	}
+	return ""
+}
+
+func statusRestarts(s *corev1.ContainerStatus) string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", s.RestartCount)
+}
+
+func formatStartTime(t *metav1.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ListConfigSources returns the ConfigMaps and Secrets the deployment's pod
+// template uses, as sorted "ConfigMap/name" and "Secret/name"
+func (c *Client) ListConfigSources(ctx context.Context, namespace, deploymentName string) ([]string, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+	return configSources(&deployment.Spec.Template.Spec), nil
+}
+
+// GetConfigData returns the text data of a ConfigMap or Secret. Binary
+// values are left out; UpdateConfigData keeps them.
+func (c *Client) GetConfigData(ctx context.Context, namespace, kind, name string) (map[string]string, error) {
+	values, err := c.sourceData(ctx, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, fmt.Errorf("%s %s not found", kind, name)
+	}
+	data := make(map[string]string, len(values))
+	for key, value := range values {
+		if utf8.Valid(value) {
+			data[key] = string(value)
+		}
+	}
+	return data, nil
+}
+
+// UpdateConfigData replaces the text data of a ConfigMap or Secret; binary
+// values GetConfigData left out stay as they are
+func (c *Client) UpdateConfigData(ctx context.Context, namespace, kind, name string, data map[string]string) error {
+	if kind == "Secret" {
+		secrets := c.clientset.CoreV1().Secrets(namespace)
+		secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get secret %s: %w", name, err)
+		}
+		if secret.Immutable != nil && *secret.Immutable {
+			return fmt.Errorf("secret %s is immutable", name)
+		}
+		values := map[string][]byte{}
+		for key, value := range secret.Data {
+			if _, ok := data[key]; !ok && !utf8.Valid(value) {
+				values[key] = value
+			}
+		}
+		for key, value := range data {
+			values[key] = []byte(value)
+		}
+		secret.Data = values
+		secret.StringData = nil
+		if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update secret %s: %w", name, err)
+		}
+		return nil
+	}
+
+	configMaps := c.clientset.CoreV1().ConfigMaps(namespace)
+	configMap, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get configmap %s: %w", name, err)
+	}
+	if configMap.Immutable != nil && *configMap.Immutable {
+		return fmt.Errorf("configmap %s is immutable", name)
+	}
+	binary := map[string][]byte{}
+	for key, value := range configMap.BinaryData {
+		if _, ok := data[key]; !ok && !utf8.Valid(value) {
+			binary[key] = value
+		}
+	}
+	configMap.Data = data
+	configMap.BinaryData = binary
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update configmap %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxMountedFiles bounds how many mounted files are read, one exec each
+const maxMountedFiles = 50
+
+// MountedFile is a file a ConfigMap or Secret volume puts into a container,
+// with the key's current value and what the container sees
+type MountedFile struct {
+	Path    string // in the container
+	Kind    string // ConfigMap or Secret
+	Source  string // the ConfigMap's or Secret's name
+	Key     string
+	SubPath bool // mounted with subPath, running pods never get updates
+	Missing bool // the ConfigMap or Secret does not exist
+
+	Data      []byte // the key's current value, nil if the source or key is gone
+	SourceErr error  // why the source could not be read, e.g. forbidden
+	Mounted   []byte // read from the container
+	ReadErr   error  // why the file could not be read
+}
+
+// Secret reports whether the file comes from a Secret
+func (f MountedFile) Secret() bool {
+	return f.Kind == "Secret"
+}
+
+// Stale reports whether the container sees other data than the source holds
+func (f MountedFile) Stale() bool {
+	return f.ReadErr == nil && f.Data != nil && !bytes.Equal(f.Data, f.Mounted)
+}
+
+// GetMountedFiles maps the container's ConfigMap and Secret volume mounts,
+// including projected ones, to the files they hold, and reads each file in
+// the container to compare it with the current data
+func (c *Client) GetMountedFiles(ctx context.Context, namespace, podName, containerName string) ([]MountedFile, error) {
+	pod, err := c.GetPod(ctx, namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	files, err := c.mountedFiles(ctx, pod, containerName)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > maxMountedFiles {
+		files = files[:maxMountedFiles]
+	}
+	for i := range files {
+		if files[i].Key == "" {
+			// The source's keys are unknown, so are its files
+			continue
+		}
+		files[i].Mounted, files[i].ReadErr = c.readFile(ctx, namespace, podName, containerName, files[i].Path)
+	}
+	return files, nil
+}
+
+// volumeSource is a ConfigMap or Secret a volume projects, with the keys it
+// selects and where they go; all keys by name if items is empty
+type volumeSource struct {
+	kind  string
+	name  string
+	items []corev1.KeyToPath
+}
+
+// mountedFiles lists the files of the container's ConfigMap and Secret
+// mounts with the current data of their keys
+func (c *Client) mountedFiles(ctx context.Context, pod *corev1.Pod, containerName string) ([]MountedFile, error) {
+	container := findContainer(pod, containerName)
+	if container == nil {
+		return nil, fmt.Errorf("container %s not found in pod %s", containerName, pod.Name)
+	}
+	volumes := make(map[string]corev1.Volume, len(pod.Spec.Volumes))
+	for _, volume := range pod.Spec.Volumes {
+		volumes[volume.Name] = volume
+	}
+
+	type sourceResult struct {
+		values map[string][]byte // nil if missing
+		err    error
+	}
+	sources := map[string]sourceResult{} // by kind/name
+	var files []MountedFile
+	for _, mount := range container.VolumeMounts {
+		volume, ok := volumes[mount.Name]
+		if !ok {
+			continue
+		}
+		for _, source := range volumeSources(volume) {
+			id := source.kind + "/" + source.name
+			result, loaded := sources[id]
+			if !loaded {
+				result.values, result.err = c.sourceData(ctx, pod.Namespace, source.kind, source.name)
+				if result.err != nil && !apierrors.IsForbidden(result.err) {
+					return nil, result.err
+				}
+				sources[id] = result
+			}
+			values, missing := result.values, result.values == nil && result.err == nil
+			if values == nil && len(source.items) == 0 {
+				files = append(files, MountedFile{Path: mount.MountPath, Kind: source.kind, Source: source.name,
+					Missing: missing, SourceErr: result.err})
+				continue
+			}
+			for _, item := range sourceItems(source, values) {
+				file := MountedFile{Kind: source.kind, Source: source.name, Key: item.Key, SubPath: mount.SubPath != "",
+					Missing: missing, SourceErr: result.err}
+				switch {
+				case mount.SubPath == "":
+					file.Path = path.Join(mount.MountPath, item.Path)
+				case item.Path == mount.SubPath:
+					file.Path = mount.MountPath
+				case strings.HasPrefix(item.Path, mount.SubPath+"/"):
+					file.Path = path.Join(mount.MountPath, strings.TrimPrefix(item.Path, mount.SubPath+"/"))
+				default:
+					continue
+				}
+				if value, ok := values[item.Key]; ok {
+					file.Data = value
+				}
+				files = append(files, file)
+			}
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// volumeSources returns the ConfigMaps and Secrets a volume puts files from
+func volumeSources(volume corev1.Volume) []volumeSource {
+	switch {
+	case volume.ConfigMap != nil:
+		return []volumeSource{{kind: "ConfigMap", name: volume.ConfigMap.Name, items: volume.ConfigMap.Items}}
+	case volume.Secret != nil:
+		return []volumeSource{{kind: "Secret", name: volume.Secret.SecretName, items: volume.Secret.Items}}
+	case volume.Projected != nil:
+		var sources []volumeSource
+		for _, projection := range volume.Projected.Sources {
+			if projection.ConfigMap != nil {
+				sources = append(sources, volumeSource{kind: "ConfigMap", name: projection.ConfigMap.Name, items: projection.ConfigMap.Items})
+			}
+			if projection.Secret != nil {
+				sources = append(sources, volumeSource{kind: "Secret", name: projection.Secret.Name, items: projection.Secret.Items})
+			}
+		}
+		return sources
+	}
+	return nil
+}
+
+// sourceItems returns the keys a volume source selects, all keys of the
+// data by name if it lists none
+func sourceItems(source volumeSource, values map[string][]byte) []corev1.KeyToPath {
+	if len(source.items) > 0 {
+		return source.items
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make([]corev1.KeyToPath, len(keys))
+	for i, key := range keys {
+		items[i] = corev1.KeyToPath{Key: key, Path: key}
+	}
+	return items
+}
+
+// sourceData returns the data of a ConfigMap or Secret by key, nil if it
+// does not exist
+func (c *Client) sourceData(ctx context.Context, namespace, kind, name string) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	if kind == "Secret" {
+		secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+		}
+		for key, value := range secret.Data {
+			values[key] = value
+		}
+		return values, nil
+	}
+	configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s: %w", name, err)
+	}
+	for key, value := range configMap.Data {
+		values[key] = []byte(value)
+	}
+	for key, value := range configMap.BinaryData {
+		values[key] = value
+	}
+	return values, nil
+}
+
+// readFile returns a file's content from inside a container
+func (c *Client) readFile(ctx context.Context, namespace, podName, containerName, file string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := c.Exec(ctx, ExecOptions{
+		Namespace:     namespace,
+		PodName:       podName,
+		ContainerName: containerName,
+		Command:       []string{"cat", file},
+		Stdout:        &stdout,
+		Stderr:        &stderr,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
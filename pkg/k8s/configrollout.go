@@ -0,0 +1,182 @@
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConfigChecksumAnnotation holds, on the pod template, the checksum of the
+// ConfigMaps and Secrets the pods use as of the last config rollout
+const ConfigChecksumAnnotation = "khelper.io/config-checksum"
+
+// ErrConfigUnchanged is returned when the pods already run with the current
+// ConfigMaps and Secrets
+var ErrConfigUnchanged = errors.New("the ConfigMaps and Secrets did not change since the last config rollout")
+
+// ConfigRollout is a rollout started for changed ConfigMaps and Secrets
+type ConfigRollout struct {
+	Sources  []string // "ConfigMap/name" and "Secret/name" the pods use
+	Checksum string
+}
+
+// PodConfig is whether a pod runs with the config of a config rollout
+type PodConfig struct {
+	Pod        string
+	Current    bool     // created from the template with the rollout's checksum
+	StaleFiles []string // "container:path" of mounted files that differ from their source
+	Unreadable int      // mounted files that could not be read
+}
+
+// RollConfig sets the checksum of the ConfigMaps and Secrets the deployment
+// uses as a pod template annotation, so that a changed config rolls the
+// pods like a changed template. It returns ErrConfigUnchanged if the
+// checksum is already set.
+func (c *Client) RollConfig(ctx context.Context, namespace, deploymentName string) (*ConfigRollout, error) {
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	sources := configSources(&deployment.Spec.Template.Spec)
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%s uses no ConfigMaps or Secrets", deploymentName)
+	}
+	checksum, err := c.configChecksum(ctx, namespace, sources)
+	if err != nil {
+		return nil, err
+	}
+	rollout := &ConfigRollout{Sources: sources, Checksum: checksum}
+	template := &deployment.Spec.Template
+	if template.Annotations[ConfigChecksumAnnotation] == checksum {
+		return rollout, ErrConfigUnchanged
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[ConfigChecksumAnnotation] = checksum
+	setChangeCause(ctx, deployment)
+	if _, err := c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to update deployment: %w", err)
+	}
+	return rollout, nil
+}
+
+// GetPodConfigs reports for each running pod of the deployment whether it
+// was created with the checksum and which of its mounted ConfigMap and
+// Secret files still differ from their source. Terminating pods are left out.
+func (c *Client) GetPodConfigs(ctx context.Context, namespace, deploymentName, checksum string) ([]PodConfig, error) {
+	pods, err := c.ListPods(ctx, namespace, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	var configs []PodConfig
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		config := PodConfig{Pod: pod.Name, Current: pod.Annotations[ConfigChecksumAnnotation] == checksum}
+		if pod.Status.Phase == corev1.PodRunning {
+			for _, container := range pod.Spec.Containers {
+				files, err := c.mountedFiles(ctx, pod, container.Name)
+				if err != nil {
+					return nil, err
+				}
+				for _, file := range files {
+					if file.Key == "" || file.Data == nil {
+						continue
+					}
+					file.Mounted, file.ReadErr = c.readFile(ctx, namespace, pod.Name, container.Name, file.Path)
+					switch {
+					case file.ReadErr != nil:
+						config.Unreadable++
+					case file.Stale():
+						config.StaleFiles = append(config.StaleFiles, container.Name+":"+file.Path)
+					}
+				}
+			}
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// configSources returns the ConfigMaps and Secrets a pod spec uses through
+// volumes, envFrom and env, as sorted "Kind/name"
+func configSources(spec *corev1.PodSpec) []string {
+	seen := map[string]bool{}
+	add := func(kind, name string) {
+		if name != "" {
+			seen[kind+"/"+name] = true
+		}
+	}
+	for _, volume := range spec.Volumes {
+		for _, source := range volumeSources(volume) {
+			add(source.kind, source.name)
+		}
+	}
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, from := range container.EnvFrom {
+			if from.ConfigMapRef != nil {
+				add("ConfigMap", from.ConfigMapRef.Name)
+			}
+			if from.SecretRef != nil {
+				add("Secret", from.SecretRef.Name)
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+				add("ConfigMap", ref.Name)
+			}
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+				add("Secret", ref.Name)
+			}
+		}
+	}
+	sources := make([]string, 0, len(seen))
+	for source := range seen {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// configChecksum hashes the data of the sources, a missing source hashes
+// as missing
+func (c *Client) configChecksum(ctx context.Context, namespace string, sources []string) (string, error) {
+	h := sha256.New()
+	for _, source := range sources {
+		kind, name, _ := strings.Cut(source, "/")
+		values, err := c.sourceData(ctx, namespace, kind, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00", source)
+		if values == nil {
+			fmt.Fprint(h, "missing\x00")
+			continue
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(h, "%s\x00%d\x00", key, len(values[key]))
+			h.Write(values[key])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
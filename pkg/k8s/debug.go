@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DebugContainerName is the name of the sidecar added for debugging
+const DebugContainerName = "khelper-debug"
+
+// DefaultDebugImage has tcpdump, curl, dig and the like
+const DefaultDebugImage = "nicolaka/netshoot"
+
+// debugCopyLabel marks pods copied for debugging with the pod they copy
+const debugCopyLabel = "khelper.io/debug-copy-of"
+
+// ErrDebugSidecarExists is returned when the debug sidecar was added before
+var ErrDebugSidecarExists = errors.New("the debug sidecar is already added")
+
+// debugContainer is the sidecar: it keeps the image's default shell open on
+// a TTY like kubectl debug does, and may capture packets. All containers of
+// a pod share its network namespace.
+func debugContainer(image string) corev1.Container {
+	return corev1.Container{
+		Name:                     DebugContainerName,
+		Image:                    image,
+		Stdin:                    true,
+		TTY:                      true,
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		SecurityContext: &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "NET_RAW"}},
+		},
+	}
+}
+
+// AddDebugSidecar adds the debug sidecar to a deployment's pod template,
+// which rolls its pods
+func (c *Client) AddDebugSidecar(ctx context.Context, namespace, deploymentName, image string) error {
+	if kind, workloadName := parseWorkloadRef(deploymentName); kind != nil {
+		return errDeploymentOnly("debug-sidecar", kind, workloadName)
+	}
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return err
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == DebugContainerName {
+			return ErrDebugSidecarExists
+		}
+	}
+	deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, debugContainer(image))
+
+	setChangeCause(ctx, deployment)
+	_, err = c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	return err
+}
+
+// RemoveDebugSidecar removes the debug sidecar from a deployment's pod
+// template again
+func (c *Client) RemoveDebugSidecar(ctx context.Context, namespace, deploymentName string) error {
+	if kind, workloadName := parseWorkloadRef(deploymentName); kind != nil {
+		return errDeploymentOnly("debug-sidecar", kind, workloadName)
+	}
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return err
+	}
+	containers := deployment.Spec.Template.Spec.Containers
+	kept := containers[:0]
+	for _, container := range containers {
+		if container.Name != DebugContainerName {
+			kept = append(kept, container)
+		}
+	}
+	if len(kept) == len(containers) {
+		return fmt.Errorf("deployment %s has no debug sidecar", deploymentName)
+	}
+	deployment.Spec.Template.Spec.Containers = kept
+
+	setChangeCause(ctx, deployment)
+	_, err = c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	return err
+}
+
+// CreateDebugCopy creates a copy of a pod with the debug sidecar and waits
+// until it runs. The copy has none of the pod's labels, so services do not
+// send it traffic and its replica set does not adopt it.
+func (c *Client) CreateDebugCopy(ctx context.Context, namespace, podName, image string) (string, error) {
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod: %w", err)
+	}
+	name := podName
+	if len(name) > 57 {
+		name = name[:57]
+	}
+	name += "-debug"
+
+	spec := pod.Spec.DeepCopy()
+	spec.NodeName = ""
+	spec.EphemeralContainers = nil
+	spec.Containers = append(spec.Containers, debugContainer(image))
+	spec.RestartPolicy = corev1.RestartPolicyNever
+	copied := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{debugCopyLabel: podName},
+			Annotations: pod.Annotations,
+		},
+		Spec: *spec,
+	}
+	if _, err := c.clientset.CoreV1().Pods(namespace).Create(ctx, copied, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to create debug copy: %w", err)
+	}
+	return name, c.waitPodRunning(ctx, namespace, name)
+}
+
+// DeleteDebugCopy deletes a pod created by CreateDebugCopy
+func (c *Client) DeleteDebugCopy(ctx context.Context, namespace, name string) error {
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pod: %w", err)
+	}
+	if _, ok := pod.Labels[debugCopyLabel]; !ok {
+		return fmt.Errorf("pod %s is not a debug copy", name)
+	}
+	return c.clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// waitPodRunning polls a pod until it runs, failing early when an image
+// cannot be pulled or the pod ended
+func (c *Client) waitPodRunning(ctx context.Context, namespace, name string) error {
+	phase, err := c.waitPodStarted(ctx, namespace, name)
+	if err == nil && phase != corev1.PodRunning {
+		return fmt.Errorf("pod %s ended (%s) before it could be debugged", name, phase)
+	}
+	return err
+}
+
+// waitPodStarted polls a pod until it runs or ended and returns its phase,
+// failing early when an image cannot be pulled
+func (c *Client) waitPodStarted(ctx context.Context, namespace, name string) (corev1.PodPhase, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get pod: %w", err)
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed:
+			return pod.Status.Phase, nil
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if w := status.State.Waiting; w != nil && (w.Reason == "ErrImagePull" || w.Reason == "ImagePullBackOff" || w.Reason == "InvalidImageName") {
+				return "", fmt.Errorf("pod %s cannot start container %s: %s: %s", name, status.Name, w.Reason, w.Message)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var (
+	// serviceEnvRef matches references to the variables Kubernetes injects
+	// for services, e.g. $(REDIS_SERVICE_HOST)
+	serviceEnvRef = regexp.MustCompile(`\$\(([A-Z0-9_]+)_SERVICE_(?:HOST|PORT)\)`)
+	// hostToken matches the host names in an env value
+	hostToken = regexp.MustCompile(`[a-z0-9]([-a-z0-9.]*[a-z0-9])?`)
+	// hostEnvKey matches env keys whose value is likely an address
+	hostEnvKey = regexp.MustCompile(`HOST|ADDR|URL|URI|ENDPOINT|SERVER|DSN|BROKER`)
+)
+
+// graphWorkload is a workload of the dependency graph
+type graphWorkload struct {
+	label  string // e.g. "Deployment web"
+	labels map[string]string
+	spec   corev1.PodSpec
+	deps   map[string][]string // target -> why it is one
+}
+
+// depend records that the workload calls a target, and why
+func (w *graphWorkload) depend(target, evidence string) {
+	if target == w.label {
+		return
+	}
+	for _, e := range w.deps[target] {
+		if e == evidence {
+			return
+		}
+	}
+	w.deps[target] = append(w.deps[target], evidence)
+}
+
+// DescribeDependencies draws an approximate dependency graph of the
+// namespace's Deployments, StatefulSets and DaemonSets: a workload calls a
+// service whose name its env or ConfigMaps mention as a host or as
+// $(NAME_SERVICE_HOST), and a workload NetworkPolicies let it reach.
+// Services are resolved to the workloads they select. The deployment is
+// drawn first; kinds that may not be listed are skipped.
+func (c *Client) DescribeDependencies(ctx context.Context, namespace, deploymentName string) (string, error) {
+	workloads, skipped, err := c.graphWorkloads(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list services: %w", err)
+	}
+	configMaps := make(map[string]map[string]string)
+	if list, err := c.clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{}); err == nil {
+		for _, cm := range list.Items {
+			configMaps[cm.Name] = cm.Data
+		}
+	} else if apierrors.IsForbidden(err) {
+		skipped = append(skipped, "ConfigMaps")
+	} else {
+		return "", fmt.Errorf("failed to list config maps: %w", err)
+	}
+
+	// The workloads behind each service, or the service itself without any
+	targets := make(map[string][]string)
+	for _, svc := range services.Items {
+		for _, w := range workloads {
+			if len(svc.Spec.Selector) > 0 && labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(w.labels)) {
+				targets[svc.Name] = append(targets[svc.Name], w.label)
+			}
+		}
+		switch {
+		case len(targets[svc.Name]) > 0:
+		case svc.Spec.Type == corev1.ServiceTypeExternalName:
+			targets[svc.Name] = []string{"Service " + svc.Name + " (ExternalName " + svc.Spec.ExternalName + ")"}
+		default:
+			targets[svc.Name] = []string{"Service " + svc.Name + " (selects no workload)"}
+		}
+	}
+
+	for _, w := range workloads {
+		for key, value := range workloadEnv(&w.spec, configMaps) {
+			for _, svc := range referencedServices(key, value, namespace, targets) {
+				if strings.Contains(svc, ".") {
+					w.depend("Service "+svc+" (other namespace)", key)
+					continue
+				}
+				for _, target := range targets[svc] {
+					evidence := key
+					if strings.HasPrefix(target, "Service ") {
+						w.depend(target, evidence)
+					} else {
+						w.depend(target, evidence+" via Service "+svc)
+					}
+				}
+			}
+		}
+	}
+	if err := c.addPolicyDependencies(ctx, namespace, workloads); err != nil {
+		if !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		skipped = append(skipped, "NetworkPolicies")
+	}
+	callers := make(map[string][]string)
+	for _, w := range workloads {
+		for target := range w.deps {
+			callers[target] = append(callers[target], w.label)
+		}
+	}
+
+	_, name := parseWorkloadRef(deploymentName)
+	sort.SliceStable(workloads, func(i, j int) bool {
+		return workloads[i].label == "Deployment "+name && workloads[j].label != "Deployment "+name
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Workloads of %s and what they call, guessed from env, ConfigMaps and NetworkPolicies\n", namespace)
+	if len(skipped) > 0 {
+		fmt.Fprintf(&b, "Not readable, left out: %s\n", strings.Join(skipped, ", "))
+	}
+	if len(workloads) == 0 {
+		b.WriteString("\nNo workloads\n")
+		return b.String(), nil
+	}
+	for _, w := range workloads {
+		root := &topologyNode{label: w.label}
+		if w.label == "Deployment "+name {
+			root.label += "  (selected)"
+		}
+		if from := callers[w.label]; len(from) > 0 {
+			sort.Strings(from)
+			root.label += "  ← " + strings.Join(from, ", ")
+		}
+		var deps []string
+		for target := range w.deps {
+			deps = append(deps, target)
+		}
+		sort.Strings(deps)
+		for _, target := range deps {
+			sort.Strings(w.deps[target])
+			root.add("→ %s  (%s)", target, strings.Join(w.deps[target], ", "))
+		}
+		b.WriteString("\n" + root.label + "\n")
+		root.render(&b, "")
+	}
+	return b.String(), nil
+}
+
+// graphWorkloads lists the namespace's Deployments, StatefulSets and
+// DaemonSets, and the kinds that may not be listed
+func (c *Client) graphWorkloads(ctx context.Context, namespace string) ([]*graphWorkload, []string, error) {
+	var workloads []*graphWorkload
+	var skipped []string
+	add := func(kind string, meta metav1.ObjectMeta, template corev1.PodTemplateSpec) {
+		workloads = append(workloads, &graphWorkload{
+			label:  kind + " " + meta.Name,
+			labels: template.Labels,
+			spec:   template.Spec,
+			deps:   make(map[string][]string),
+		})
+	}
+	skip := func(kind string, err error) error {
+		if apierrors.IsForbidden(err) {
+			skipped = append(skipped, kind)
+			return nil
+		}
+		return fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+
+	apps := c.clientset.AppsV1()
+	if list, err := apps.Deployments(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		if err := skip("Deployments", err); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for _, d := range list.Items {
+			add("Deployment", d.ObjectMeta, d.Spec.Template)
+		}
+	}
+	if list, err := apps.StatefulSets(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		if err := skip("StatefulSets", err); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for _, s := range list.Items {
+			add("StatefulSet", s.ObjectMeta, s.Spec.Template)
+		}
+	}
+	if list, err := apps.DaemonSets(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		if err := skip("DaemonSets", err); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for _, d := range list.Items {
+			add("DaemonSet", d.ObjectMeta, d.Spec.Template)
+		}
+	}
+	return workloads, skipped, nil
+}
+
+// workloadEnv returns the env of a pod spec's containers, by where it is
+// declared: "env KEY" or "ConfigMap name/KEY" for values taken from one
+func workloadEnv(spec *corev1.PodSpec, configMaps map[string]map[string]string) map[string]string {
+	env := make(map[string]string)
+	for _, container := range append(spec.InitContainers, spec.Containers...) {
+		for _, from := range container.EnvFrom {
+			if from.ConfigMapRef == nil {
+				continue
+			}
+			for key, value := range configMaps[from.ConfigMapRef.Name] {
+				env["ConfigMap "+from.ConfigMapRef.Name+"/"+key] = value
+			}
+		}
+		for _, e := range container.Env {
+			switch {
+			case e.ValueFrom == nil:
+				env["env "+e.Name] = e.Value
+			case e.ValueFrom.ConfigMapKeyRef != nil:
+				ref := e.ValueFrom.ConfigMapKeyRef
+				if value, ok := configMaps[ref.Name][ref.Key]; ok {
+					env["ConfigMap "+ref.Name+"/"+ref.Key] = value
+				}
+			}
+		}
+	}
+	return env
+}
+
+// referencedServices returns the services an env value points to: those in
+// $(NAME_SERVICE_HOST) references, qualified service host names, as
+// "name.namespace" when in another namespace, and bare service names if
+// the key or value says it is an address
+func referencedServices(key, value, namespace string, services map[string][]string) []string {
+	var names []string
+	for _, match := range serviceEnvRef.FindAllStringSubmatch(value, -1) {
+		if name := strings.ReplaceAll(strings.ToLower(match[1]), "_", "-"); services[name] != nil {
+			names = append(names, name)
+		}
+	}
+
+	lower := strings.ToLower(value)
+	// Keys are "env KEY" or "ConfigMap name/KEY"
+	name := key[strings.LastIndexAny(key, " /")+1:]
+	address := hostEnvKey.MatchString(strings.ToUpper(name)) || strings.Contains(lower, "://")
+	for _, loc := range hostToken.FindAllStringIndex(lower, -1) {
+		host := lower[loc[0]:loc[1]]
+		// A port after the host marks an address too, e.g. redis:6379
+		port := loc[1] < len(lower) && lower[loc[1]] == ':' && loc[1]+1 < len(lower) && lower[loc[1]+1] >= '0' && lower[loc[1]+1] <= '9'
+		qualified := strings.TrimSuffix(strings.TrimSuffix(host, ".cluster.local"), ".svc")
+		svc, ns, ok := strings.Cut(qualified, ".")
+		switch {
+		case ok && !strings.Contains(ns, ".") && ns == namespace:
+			if services[svc] != nil {
+				names = append(names, svc)
+			}
+		case ok && !strings.Contains(ns, ".") && qualified != host:
+			names = append(names, svc+"."+ns)
+		case !ok && (address || port):
+			if services[host] != nil {
+				names = append(names, host)
+			}
+		}
+	}
+	return names
+}
+
+// addPolicyDependencies adds the dependencies NetworkPolicies declare
+// between the namespace's workloads, by egress rules of the caller and
+// ingress rules of the callee that select the other by pod labels
+func (c *Client) addPolicyDependencies(ctx context.Context, namespace string, workloads []*graphWorkload) error {
+	policies, err := c.clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	matches := func(selector *metav1.LabelSelector, w *graphWorkload) bool {
+		s, err := metav1.LabelSelectorAsSelector(selector)
+		return err == nil && selector != nil && !s.Empty() && s.Matches(labels.Set(w.labels))
+	}
+	// Peers in other namespaces or selecting every pod say nothing about a
+	// dependency
+	peerMatches := func(peer networkingv1.NetworkPolicyPeer, w *graphWorkload) bool {
+		return peer.NamespaceSelector == nil && peer.IPBlock == nil && matches(peer.PodSelector, w)
+	}
+	for _, policy := range policies.Items {
+		evidence := "NetworkPolicy " + policy.Name
+		for _, subject := range workloads {
+			if !matches(&policy.Spec.PodSelector, subject) {
+				continue
+			}
+			for _, peer := range workloads {
+				for _, rule := range policy.Spec.Egress {
+					for _, to := range rule.To {
+						if peerMatches(to, peer) {
+							subject.depend(peer.label, evidence)
+						}
+					}
+				}
+				for _, rule := range policy.Spec.Ingress {
+					for _, from := range rule.From {
+						if peerMatches(from, peer) {
+							peer.depend(subject.label, evidence)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
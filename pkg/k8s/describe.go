@@ -0,0 +1,606 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/yaml"
+)
+
+// describeWriter writes indented, tab-aligned describe output like kubectl
+type describeWriter struct {
+	b  strings.Builder
+	tw *tabwriter.Writer
+}
+
+func newDescribeWriter() *describeWriter {
+	w := &describeWriter{}
+	w.tw = tabwriter.NewWriter(&w.b, 0, 8, 2, ' ', 0)
+	return w
+}
+
+// line writes a line at the given indentation level; tabs align columns
+func (w *describeWriter) line(level int, format string, args ...interface{}) {
+	fmt.Fprintf(w.tw, strings.Repeat("  ", level)+format+"\n", args...)
+}
+
+func (w *describeWriter) String() string {
+	w.tw.Flush()
+	return w.b.String()
+}
+
+// DescribeDeployment returns a kubectl describe style report of a deployment:
+// its pod template (probes, resources, mounts, volumes, tolerations and
+// affinity), conditions, replica sets and recent events
+func (c *Client) DescribeDeployment(ctx context.Context, namespace, name string) (string, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	w := newDescribeWriter()
+	describeObjectMeta(w, &deployment.ObjectMeta)
+	w.line(0, "Selector:\t%s", metav1.FormatLabelSelector(deployment.Spec.Selector))
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	w.line(0, "Replicas:\t%d desired | %d updated | %d total | %d available | %d unavailable",
+		desired, deployment.Status.UpdatedReplicas, deployment.Status.Replicas,
+		deployment.Status.AvailableReplicas, deployment.Status.UnavailableReplicas)
+	w.line(0, "StrategyType:\t%s", deployment.Spec.Strategy.Type)
+	w.line(0, "MinReadySeconds:\t%d", deployment.Spec.MinReadySeconds)
+	if ru := deployment.Spec.Strategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil && ru.MaxSurge != nil {
+		w.line(0, "RollingUpdateStrategy:\t%s max unavailable, %s max surge", ru.MaxUnavailable.String(), ru.MaxSurge.String())
+	}
+
+	w.line(0, "Pod Template:")
+	describeLabels(w, 1, "Labels", deployment.Spec.Template.Labels)
+	describeLabels(w, 1, "Annotations", deployment.Spec.Template.Annotations)
+	describePodSpec(w, 1, &deployment.Spec.Template.Spec)
+
+	if len(deployment.Status.Conditions) > 0 {
+		w.line(0, "Conditions:")
+		w.line(1, "Type\tStatus\tReason\tMessage")
+		w.line(1, "----\t------\t------\t-------")
+		for _, cond := range deployment.Status.Conditions {
+			w.line(1, "%s\t%s\t%s\t%s", cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+	}
+
+	if replicaSets, err := c.GetReplicaSets(ctx, namespace, name); err == nil {
+		describeReplicaSets(w, deployment, replicaSets)
+	}
+
+	eventKind, eventName := "Deployment", name
+	if workload, workloadName := parseWorkloadRef(name); workload != nil {
+		eventKind, eventName = workload.kind, workloadName
+	}
+	events, err := c.listEvents(ctx, namespace, eventKind, eventName)
+	if err != nil {
+		w.line(0, "Events:\t<unable to list: %v>", err)
+	} else {
+		describeEvents(w, events)
+	}
+
+	return w.String(), nil
+}
+
+// DescribePod returns a kubectl describe style report of a pod: its node,
+// IPs, containers with their state, restarts and last termination, conditions,
+// volumes, QoS class, tolerations and recent events
+func (c *Client) DescribePod(ctx context.Context, namespace, name string) (string, error) {
+	pod, err := c.GetPod(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	w := newDescribeWriter()
+	w.line(0, "Name:\t%s", pod.Name)
+	w.line(0, "Namespace:\t%s", pod.Namespace)
+	if pod.Spec.Priority != nil {
+		w.line(0, "Priority:\t%d", *pod.Spec.Priority)
+	}
+	if pod.Spec.PriorityClassName != "" {
+		w.line(0, "Priority Class Name:\t%s", pod.Spec.PriorityClassName)
+	}
+	if pod.Spec.ServiceAccountName != "" {
+		w.line(0, "Service Account:\t%s", pod.Spec.ServiceAccountName)
+	}
+	node := pod.Spec.NodeName
+	if node == "" {
+		node = "<none>"
+	} else if pod.Status.HostIP != "" {
+		node += "/" + pod.Status.HostIP
+	}
+	w.line(0, "Node:\t%s", node)
+	if pod.Status.StartTime != nil {
+		w.line(0, "Start Time:\t%s", pod.Status.StartTime.Format(time.RFC1123Z))
+	}
+	describeLabels(w, 0, "Labels", pod.Labels)
+	describeLabels(w, 0, "Annotations", pod.Annotations)
+
+	status := string(pod.Status.Phase)
+	if pod.DeletionTimestamp != nil {
+		status = fmt.Sprintf("Terminating (lasts %s)", shortDuration(time.Since(pod.DeletionTimestamp.Time)))
+	}
+	w.line(0, "Status:\t%s", status)
+	if pod.Status.Reason != "" {
+		w.line(0, "Reason:\t%s", pod.Status.Reason)
+	}
+	if pod.Status.Message != "" {
+		w.line(0, "Message:\t%s", pod.Status.Message)
+	}
+	w.line(0, "IP:\t%s", pod.Status.PodIP)
+	if len(pod.Status.PodIPs) > 0 {
+		w.line(0, "IPs:")
+		for _, ip := range pod.Status.PodIPs {
+			w.line(1, "IP:\t%s", ip.IP)
+		}
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller {
+			w.line(0, "Controlled By:\t%s/%s", ref.Kind, ref.Name)
+		}
+	}
+
+	if len(pod.Spec.InitContainers) > 0 {
+		w.line(0, "Init Containers:")
+		for i := range pod.Spec.InitContainers {
+			container := &pod.Spec.InitContainers[i]
+			describeContainer(w, 1, container, findContainerStatus(pod.Status.InitContainerStatuses, container.Name))
+		}
+	}
+	w.line(0, "Containers:")
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		describeContainer(w, 1, container, findContainerStatus(pod.Status.ContainerStatuses, container.Name))
+	}
+
+	if len(pod.Status.Conditions) > 0 {
+		w.line(0, "Conditions:")
+		w.line(1, "Type\tStatus")
+		for _, cond := range pod.Status.Conditions {
+			w.line(1, "%s\t%s", cond.Type, cond.Status)
+		}
+	}
+	describeVolumes(w, 0, pod.Spec.Volumes)
+	w.line(0, "QoS Class:\t%s", pod.Status.QOSClass)
+	describeLabels(w, 0, "Node-Selectors", pod.Spec.NodeSelector)
+	describeTolerations(w, 0, pod.Spec.Tolerations)
+
+	events, err := c.listEvents(ctx, namespace, "Pod", name)
+	if err != nil {
+		w.line(0, "Events:\t<unable to list: %v>", err)
+	} else {
+		describeEvents(w, events)
+	}
+
+	return w.String(), nil
+}
+
+// findContainerStatus returns the status of the named container, or nil
+func findContainerStatus(statuses []corev1.ContainerStatus, name string) *corev1.ContainerStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// listEvents returns the events about an object, oldest first
+func (c *Client) listEvents(ctx context.Context, namespace, kind, name string) ([]corev1.Event, error) {
+	selector := fields.Set{
+		"involvedObject.kind": kind,
+		"involvedObject.name": name,
+	}.AsSelector().String()
+	events, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(events.Items, func(i, j int) bool {
+		return eventTime(events.Items[i]).Before(eventTime(events.Items[j]))
+	})
+	return events.Items, nil
+}
+
+// eventTime returns when an event was last seen
+func eventTime(e corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+func describeObjectMeta(w *describeWriter, meta *metav1.ObjectMeta) {
+	w.line(0, "Name:\t%s", meta.Name)
+	w.line(0, "Namespace:\t%s", meta.Namespace)
+	w.line(0, "CreationTimestamp:\t%s", meta.CreationTimestamp.Format(time.RFC1123Z))
+	describeLabels(w, 0, "Labels", meta.Labels)
+	describeLabels(w, 0, "Annotations", meta.Annotations)
+}
+
+// describeLabels writes a sorted key=value map, one entry per line
+func describeLabels(w *describeWriter, level int, title string, m map[string]string) {
+	if len(m) == 0 {
+		w.line(level, "%s:\t<none>", title)
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		label := ""
+		if i == 0 {
+			label = title + ":"
+		}
+		w.line(level, "%s\t%s=%s", label, k, m[k])
+	}
+}
+
+func describePodSpec(w *describeWriter, level int, spec *corev1.PodSpec) {
+	if spec.ServiceAccountName != "" {
+		w.line(level, "Service Account:\t%s", spec.ServiceAccountName)
+	}
+	if len(spec.InitContainers) > 0 {
+		w.line(level, "Init Containers:")
+		for i := range spec.InitContainers {
+			describeContainer(w, level+1, &spec.InitContainers[i], nil)
+		}
+	}
+	w.line(level, "Containers:")
+	for i := range spec.Containers {
+		describeContainer(w, level+1, &spec.Containers[i], nil)
+	}
+	describeVolumes(w, level, spec.Volumes)
+	describeLabels(w, level, "Node-Selectors", spec.NodeSelector)
+	describeTolerations(w, level, spec.Tolerations)
+	describeAffinity(w, level, spec.Affinity)
+}
+
+// describeContainer writes a container of a pod template, or of a pod with
+// its status when status is not nil
+func describeContainer(w *describeWriter, level int, container *corev1.Container, status *corev1.ContainerStatus) {
+	w.line(level, "%s:", container.Name)
+	level++
+	if status != nil && status.ContainerID != "" {
+		w.line(level, "Container ID:\t%s", status.ContainerID)
+	}
+	w.line(level, "Image:\t%s", container.Image)
+	if status != nil && status.ImageID != "" {
+		w.line(level, "Image ID:\t%s", status.ImageID)
+	}
+
+	if len(container.Ports) == 0 {
+		w.line(level, "Ports:\t<none>")
+	} else {
+		ports := make([]string, 0, len(container.Ports))
+		for _, p := range container.Ports {
+			ports = append(ports, fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol))
+		}
+		w.line(level, "Ports:\t%s", strings.Join(ports, ", "))
+	}
+	if len(container.Command) > 0 {
+		w.line(level, "Command:\t%s", strings.Join(container.Command, " "))
+	}
+	if len(container.Args) > 0 {
+		w.line(level, "Args:\t%s", strings.Join(container.Args, " "))
+	}
+	if status != nil {
+		describeContainerState(w, level, "State", status.State)
+		if status.LastTerminationState != (corev1.ContainerState{}) {
+			describeContainerState(w, level, "Last State", status.LastTerminationState)
+		}
+		w.line(level, "Ready:\t%v", status.Ready)
+		w.line(level, "Restart Count:\t%d", status.RestartCount)
+	}
+
+	describeResources(w, level, "Limits", container.Resources.Limits)
+	describeResources(w, level, "Requests", container.Resources.Requests)
+
+	if container.LivenessProbe != nil {
+		w.line(level, "Liveness:\t%s", describeProbe(container.LivenessProbe))
+	}
+	if container.ReadinessProbe != nil {
+		w.line(level, "Readiness:\t%s", describeProbe(container.ReadinessProbe))
+	}
+	if container.StartupProbe != nil {
+		w.line(level, "Startup:\t%s", describeProbe(container.StartupProbe))
+	}
+
+	if len(container.Env) == 0 && len(container.EnvFrom) == 0 {
+		w.line(level, "Environment:\t<none>")
+	} else {
+		w.line(level, "Environment:")
+		for _, from := range container.EnvFrom {
+			switch {
+			case from.ConfigMapRef != nil:
+				w.line(level+1, "ConfigMap\t%s\t(prefix %q)", from.ConfigMapRef.Name, from.Prefix)
+			case from.SecretRef != nil:
+				w.line(level+1, "Secret\t%s\t(prefix %q)", from.SecretRef.Name, from.Prefix)
+			}
+		}
+		for _, env := range container.Env {
+			w.line(level+1, "%s:\t%s", env.Name, describeEnvValue(env))
+		}
+	}
+
+	if len(container.VolumeMounts) == 0 {
+		w.line(level, "Mounts:\t<none>")
+	} else {
+		w.line(level, "Mounts:")
+		for _, mount := range container.VolumeMounts {
+			mode := "rw"
+			if mount.ReadOnly {
+				mode = "ro"
+			}
+			subPath := ""
+			if mount.SubPath != "" {
+				subPath = ", path = " + mount.SubPath
+			}
+			w.line(level+1, "%s from %s (%s%s)", mount.MountPath, mount.Name, mode, subPath)
+		}
+	}
+}
+
+// describeContainerState writes whether a container is running, waiting or
+// terminated, with the reason, exit code and times
+func describeContainerState(w *describeWriter, level int, title string, state corev1.ContainerState) {
+	switch {
+	case state.Running != nil:
+		w.line(level, "%s:\tRunning", title)
+		w.line(level+1, "Started:\t%s", state.Running.StartedAt.Format(time.RFC1123Z))
+	case state.Waiting != nil:
+		w.line(level, "%s:\tWaiting", title)
+		if state.Waiting.Reason != "" {
+			w.line(level+1, "Reason:\t%s", state.Waiting.Reason)
+		}
+		if state.Waiting.Message != "" {
+			w.line(level+1, "Message:\t%s", state.Waiting.Message)
+		}
+	case state.Terminated != nil:
+		w.line(level, "%s:\tTerminated", title)
+		if state.Terminated.Reason != "" {
+			w.line(level+1, "Reason:\t%s", state.Terminated.Reason)
+		}
+		if state.Terminated.Message != "" {
+			w.line(level+1, "Message:\t%s", strings.TrimSpace(state.Terminated.Message))
+		}
+		w.line(level+1, "Exit Code:\t%d", state.Terminated.ExitCode)
+		if state.Terminated.Signal != 0 {
+			w.line(level+1, "Signal:\t%d", state.Terminated.Signal)
+		}
+		w.line(level+1, "Started:\t%s", state.Terminated.StartedAt.Format(time.RFC1123Z))
+		w.line(level+1, "Finished:\t%s", state.Terminated.FinishedAt.Format(time.RFC1123Z))
+	default:
+		w.line(level, "%s:\tWaiting", title)
+	}
+}
+
+func describeResources(w *describeWriter, level int, title string, resources corev1.ResourceList) {
+	if len(resources) == 0 {
+		return
+	}
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	w.line(level, "%s:", title)
+	for _, name := range names {
+		q := resources[corev1.ResourceName(name)]
+		w.line(level+1, "%s:\t%s", name, q.String())
+	}
+}
+
+// describeProbe summarizes a probe like kubectl,
+// e.g. "http-get http://:8080/healthz delay=0s timeout=1s period=10s #success=1 #failure=3"
+func describeProbe(probe *corev1.Probe) string {
+	attrs := fmt.Sprintf("delay=%ds timeout=%ds period=%ds #success=%d #failure=%d",
+		probe.InitialDelaySeconds, probe.TimeoutSeconds, probe.PeriodSeconds, probe.SuccessThreshold, probe.FailureThreshold)
+
+	switch {
+	case probe.Exec != nil:
+		return fmt.Sprintf("exec %v %s", probe.Exec.Command, attrs)
+	case probe.HTTPGet != nil:
+		scheme := strings.ToLower(string(probe.HTTPGet.Scheme))
+		if scheme == "" {
+			scheme = "http"
+		}
+		return fmt.Sprintf("http-get %s://%s:%s%s %s", scheme, probe.HTTPGet.Host, probe.HTTPGet.Port.String(), probe.HTTPGet.Path, attrs)
+	case probe.TCPSocket != nil:
+		return fmt.Sprintf("tcp-socket %s:%s %s", probe.TCPSocket.Host, probe.TCPSocket.Port.String(), attrs)
+	case probe.GRPC != nil:
+		return fmt.Sprintf("grpc <pod>:%d %s", probe.GRPC.Port, attrs)
+	}
+	return "unknown " + attrs
+}
+
+// describeEnvValue shows an env var's value or where it comes from
+func describeEnvValue(env corev1.EnvVar) string {
+	from := env.ValueFrom
+	switch {
+	case from == nil:
+		return env.Value
+	case from.FieldRef != nil:
+		return fmt.Sprintf("(%s:%s)", from.FieldRef.APIVersion, from.FieldRef.FieldPath)
+	case from.ResourceFieldRef != nil:
+		return fmt.Sprintf("%s (%s)", from.ResourceFieldRef.Resource, from.ResourceFieldRef.ContainerName)
+	case from.SecretKeyRef != nil:
+		return fmt.Sprintf("<set to the key '%s' in secret '%s'>", from.SecretKeyRef.Key, from.SecretKeyRef.Name)
+	case from.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("<set to the key '%s' of config map '%s'>", from.ConfigMapKeyRef.Key, from.ConfigMapKeyRef.Name)
+	}
+	return ""
+}
+
+func describeVolumes(w *describeWriter, level int, volumes []corev1.Volume) {
+	if len(volumes) == 0 {
+		w.line(level, "Volumes:\t<none>")
+		return
+	}
+	w.line(level, "Volumes:")
+	for _, v := range volumes {
+		w.line(level+1, "%s:", v.Name)
+		src := v.VolumeSource
+		switch {
+		case src.ConfigMap != nil:
+			w.line(level+2, "Type:\tConfigMap (a volume populated by a ConfigMap)")
+			w.line(level+2, "Name:\t%s", src.ConfigMap.Name)
+		case src.Secret != nil:
+			w.line(level+2, "Type:\tSecret (a volume populated by a Secret)")
+			w.line(level+2, "SecretName:\t%s", src.Secret.SecretName)
+		case src.EmptyDir != nil:
+			w.line(level+2, "Type:\tEmptyDir (a temporary directory that shares a pod's lifetime)")
+			w.line(level+2, "Medium:\t%s", src.EmptyDir.Medium)
+		case src.PersistentVolumeClaim != nil:
+			w.line(level+2, "Type:\tPersistentVolumeClaim")
+			w.line(level+2, "ClaimName:\t%s", src.PersistentVolumeClaim.ClaimName)
+			w.line(level+2, "ReadOnly:\t%v", src.PersistentVolumeClaim.ReadOnly)
+		case src.HostPath != nil:
+			w.line(level+2, "Type:\tHostPath (bare host directory volume)")
+			w.line(level+2, "Path:\t%s", src.HostPath.Path)
+		case src.Projected != nil:
+			w.line(level+2, "Type:\tProjected (a volume that contains injected data from multiple sources)")
+		case src.DownwardAPI != nil:
+			w.line(level+2, "Type:\tDownwardAPI (a volume populated by information about the pod)")
+		case src.CSI != nil:
+			w.line(level+2, "Type:\tCSI (a Container Storage Interface (CSI) volume source)")
+			w.line(level+2, "Driver:\t%s", src.CSI.Driver)
+		default:
+			w.line(level+2, "Type:\t<unknown>")
+		}
+	}
+}
+
+func describeTolerations(w *describeWriter, level int, tolerations []corev1.Toleration) {
+	if len(tolerations) == 0 {
+		w.line(level, "Tolerations:\t<none>")
+		return
+	}
+	for i, t := range tolerations {
+		label := ""
+		if i == 0 {
+			label = "Tolerations:"
+		}
+		s := t.Key
+		if t.Value != "" {
+			s += "=" + t.Value
+		}
+		if t.Operator == corev1.TolerationOpExists && t.Key == "" {
+			s = "op=Exists"
+		}
+		if t.Effect != "" {
+			s += ":" + string(t.Effect)
+		}
+		if t.TolerationSeconds != nil {
+			s += fmt.Sprintf(" for %ds", *t.TolerationSeconds)
+		}
+		w.line(level, "%s\t%s", label, s)
+	}
+}
+
+// describeAffinity writes the affinity rules as YAML, they are too varied to
+// summarize on one line
+func describeAffinity(w *describeWriter, level int, affinity *corev1.Affinity) {
+	if affinity == nil {
+		w.line(level, "Affinity:\t<none>")
+		return
+	}
+	data, err := yaml.Marshal(affinity)
+	if err != nil {
+		w.line(level, "Affinity:\t<%v>", err)
+		return
+	}
+	w.line(level, "Affinity:")
+	for _, l := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		w.line(level+1, "%s", l)
+	}
+}
+
+func describeReplicaSets(w *describeWriter, deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) {
+	var newRS []string
+	var oldRS []string
+	for _, rs := range replicaSets {
+		owned := false
+		for _, ref := range rs.OwnerReferences {
+			if ref.UID == deployment.UID {
+				owned = true
+			}
+		}
+		if !owned {
+			continue
+		}
+		summary := fmt.Sprintf("%s (%d/%d replicas created)", rs.Name, rs.Status.Replicas, derefInt32(rs.Spec.Replicas))
+		if rs.Annotations["deployment.kubernetes.io/revision"] == deployment.Annotations["deployment.kubernetes.io/revision"] {
+			newRS = append(newRS, summary)
+		} else if rs.Status.Replicas > 0 {
+			oldRS = append(oldRS, summary)
+		}
+	}
+	w.line(0, "OldReplicaSets:\t%s", joinOrNone(oldRS))
+	w.line(0, "NewReplicaSet:\t%s", joinOrNone(newRS))
+}
+
+func describeEvents(w *describeWriter, events []corev1.Event) {
+	if len(events) == 0 {
+		w.line(0, "Events:\t<none>")
+		return
+	}
+	w.line(0, "Events:")
+	w.line(1, "Type\tReason\tAge\tFrom\tMessage")
+	w.line(1, "----\t------\t---\t----\t-------")
+	for _, e := range events {
+		age := shortDuration(time.Since(eventTime(e)))
+		if e.Count > 1 {
+			age = fmt.Sprintf("%s (x%d)", age, e.Count)
+		}
+		from := e.Source.Component
+		if from == "" {
+			from = e.ReportingController
+		}
+		w.line(1, "%s\t%s\t%s\t%s\t%s", e.Type, e.Reason, age, from, strings.TrimSpace(e.Message))
+	}
+}
+
+// shortDuration formats a duration like kubectl ages: 45s, 12m, 3h, 5d
+func shortDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func derefInt32(p *int32) int32 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "<none>"
+	}
+	return strings.Join(items, ", ")
+}
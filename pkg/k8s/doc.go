@@ -0,0 +1,9 @@
+// Package k8s is the Kubernetes client layer used by khelper.
+//
+// It wraps client-go with the higher level operations the TUI needs (listing
+// workloads, exec, logs, port forwarding, file uploads, rollbacks) and can be
+// embedded by other tools. Every operation takes a context for cancellation,
+// multi-argument operations take an options struct, and nothing in this
+// package writes to os.Stdout or os.Stderr on its own: callers decide where
+// output goes by supplying writers in the options.
+package k8s
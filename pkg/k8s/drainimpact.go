@@ -0,0 +1,484 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// hostnameLabel is the topology key of anti-affinity spreading pods over
+// nodes
+const hostnameLabel = "kubernetes.io/hostname"
+
+// DrainImpact is what draining a node does to a workload: the pods evicted,
+// the PDBs pacing the evictions and where the replacements could start
+type DrainImpact struct {
+	Node      string
+	Namespace string
+	Workload  string
+	Replicas  int32
+	Evicted   []DrainPod
+	// Remaining is the number of ready pods on other nodes
+	Remaining int
+	PDBs      []DrainBudget
+	// Placements are the nodes the replacements of the evicted pods fit
+	// on, in the order of Evicted
+	Placements []DrainPlacement
+	// Others is the number of other pods the drain evicts from the node,
+	// DaemonSet and static pods aside. They are placed before the
+	// replacements, as they compete for the same room.
+	Others int
+	// CapacityErr tells why the placements were not checked, e.g. no
+	// permission to list the pods of all namespaces
+	CapacityErr error
+}
+
+// DrainPod is a pod of the workload running on the drained node
+type DrainPod struct {
+	Name  string
+	Ready bool
+}
+
+// DrainBudget is a PodDisruptionBudget covering the workload's pods
+type DrainBudget struct {
+	Name string
+	// Budget is what it keeps, e.g. "minAvailable 2"
+	Budget  string
+	Allowed int32
+}
+
+// DrainPlacement is where the replacement of an evicted pod could start
+type DrainPlacement struct {
+	Pod string
+	// Node is empty if no node fits, Reason then tells why in the way of
+	// the scheduler, e.g. "0/4 nodes fit: 2 insufficient memory, 1 cordoned"
+	Node   string
+	Reason string
+}
+
+// Problems lists what endangers the workload during the drain; none means
+// it keeps serving throughout
+func (d *DrainImpact) Problems() []string {
+	if len(d.Evicted) == 0 {
+		return nil
+	}
+	var problems []string
+	var blocking []string
+	// The drain evicts past the smallest budget only as replacements
+	// become ready
+	allowed := int32(len(d.Evicted))
+	for _, pdb := range d.PDBs {
+		allowed = min(allowed, pdb.Allowed)
+		if pdb.Allowed == 0 {
+			blocking = append(blocking, pdb.Name)
+		}
+	}
+	if len(blocking) > 0 {
+		problems = append(problems, fmt.Sprintf("PDB %s allows no eviction now: the drain waits until it does, forever if all pods are ready already",
+			strings.Join(blocking, ", ")))
+	}
+	if len(d.PDBs) == 0 && d.Remaining == 0 {
+		problems = append(problems, fmt.Sprintf("no ready pod of %s runs elsewhere and no PDB paces the evictions: it is down until the replacements are ready", d.Workload))
+	}
+	for _, p := range d.Placements {
+		if p.Node != "" {
+			continue
+		}
+		if allowed < int32(len(d.Evicted)) {
+			problems = append(problems, fmt.Sprintf("the replacement of %s cannot be scheduled (%s): the drain blocks once the PDB budget is used", p.Pod, p.Reason))
+		} else {
+			problems = append(problems, fmt.Sprintf("the replacement of %s cannot be scheduled (%s): %s runs with fewer pods after the drain", p.Pod, p.Reason, d.Workload))
+		}
+	}
+	return problems
+}
+
+// CheckDrainImpact works out what draining a node does to a workload: its
+// pods there are evicted at the pace of the PDBs covering them, and their
+// replacements are placed on the other nodes by the template's node
+// selector, affinity, tolerations and resource requests, against what the
+// pods of all namespaces already request.
+func (c *Client) CheckDrainImpact(ctx context.Context, namespace, ref, node string) (*DrainImpact, error) {
+	if _, err := c.clientset.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("node %s not found", node)
+		}
+		return nil, fmt.Errorf("failed to get node %s: %w", node, err)
+	}
+	deployment, err := c.GetDeployment(ctx, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+	impact := &DrainImpact{Node: node, Namespace: namespace, Workload: ref, Replicas: derefInt32(deployment.Spec.Replicas)}
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(deployment.Spec.Selector)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		ready := isPodReady(&pod)
+		switch {
+		case pod.Spec.NodeName == node:
+			impact.Evicted = append(impact.Evicted, DrainPod{Name: pod.Name, Ready: ready})
+		case ready:
+			impact.Remaining++
+		}
+	}
+	sort.Slice(impact.Evicted, func(i, j int) bool { return impact.Evicted[i].Name < impact.Evicted[j].Name })
+
+	pdbs, err := c.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to list PDBs: %w", err)
+	}
+	if err == nil {
+		for _, pdb := range pdbs.Items {
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || selector.Empty() || !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+				continue
+			}
+			impact.PDBs = append(impact.PDBs, DrainBudget{Name: pdb.Name, Budget: pdbBudget(&pdb), Allowed: pdb.Status.DisruptionsAllowed})
+		}
+	}
+
+	if len(impact.Evicted) > 0 {
+		impact.Placements, impact.Others, impact.CapacityErr = c.placeReplacements(ctx, node, deployment.Spec.Template, impact.Evicted, pods.Items)
+	}
+	return impact, nil
+}
+
+// isPodReady reports whether a pod has the Ready condition
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeRoom is what a node has left for new pods
+type nodeRoom struct {
+	node        *corev1.Node
+	cpu, memory int64 // millicores and bytes
+	pods        int64
+	// own counts the workload's pods on the node, for anti-affinity
+	own int
+}
+
+// placeReplacements places the replacement of each evicted pod on the node
+// with the most CPU left that fits it, as a rough stand-in for the scheduler.
+// The other pods evicted from the drained node are placed first the same
+// way, their number is returned with the placements.
+func (c *Client) placeReplacements(ctx context.Context, drained string, template corev1.PodTemplateSpec, evicted []DrainPod, own []corev1.Pod) ([]DrainPlacement, int, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "status.phase!=Succeeded,status.phase!=Failed"})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list the pods of all namespaces: %w", err)
+	}
+
+	rooms := make(map[string]*nodeRoom, len(nodes.Items))
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		allocatable := node.Status.Allocatable
+		rooms[node.Name] = &nodeRoom{
+			node:   node,
+			cpu:    allocatable.Cpu().MilliValue(),
+			memory: allocatable.Memory().Value(),
+			pods:   allocatable.Pods().Value(),
+		}
+	}
+	for _, pod := range pods.Items {
+		room := rooms[pod.Spec.NodeName]
+		if room == nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		cpu, memory := podRequests(&pod.Spec)
+		room.cpu -= cpu
+		room.memory -= memory
+		room.pods--
+	}
+	isOwn := make(map[string]bool, len(own))
+	for _, pod := range own {
+		isOwn[pod.Namespace+"/"+pod.Name] = true
+		if room := rooms[pod.Spec.NodeName]; room != nil && pod.DeletionTimestamp == nil {
+			room.own++
+		}
+	}
+
+	names := make([]string, 0, len(rooms))
+	for name := range rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// place finds the node with the most CPU left that fits a pod, or why
+	// none does
+	place := func(spec *corev1.PodSpec, cpu, memory int64, antiAffinity bool) (*nodeRoom, map[string]int) {
+		var best *nodeRoom
+		reasons := map[string]int{}
+		for _, name := range names {
+			room := rooms[name]
+			reason := ""
+			switch {
+			case name == drained:
+				reason = "being drained"
+			case room.node.Spec.Unschedulable:
+				reason = "cordoned"
+			case !isNodeReady(room.node):
+				reason = "not ready"
+			default:
+				reason = nodeMismatch(spec, room.node)
+			}
+			switch {
+			case reason != "":
+			case antiAffinity && room.own > 0:
+				reason = "pod anti-affinity"
+			case room.pods < 1:
+				reason = "too many pods"
+			case room.cpu < cpu:
+				reason = "insufficient cpu"
+			case room.memory < memory:
+				reason = "insufficient memory"
+			}
+			if reason != "" {
+				reasons[reason]++
+				continue
+			}
+			if best == nil || room.cpu > best.cpu {
+				best = room
+			}
+		}
+		return best, reasons
+	}
+	take := func(room *nodeRoom, cpu, memory int64) {
+		room.cpu -= cpu
+		room.memory -= memory
+		room.pods--
+	}
+
+	// Others that fit nowhere stay pending and take no room
+	others := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName != drained || pod.DeletionTimestamp != nil || isOwn[pod.Namespace+"/"+pod.Name] || isDaemonSetPod(pod) || isStaticPod(pod) {
+			continue
+		}
+		others++
+		cpu, memory := podRequests(&pod.Spec)
+		if room, _ := place(&pod.Spec, cpu, memory, false); room != nil {
+			take(room, cpu, memory)
+		}
+	}
+
+	cpu, memory := podRequests(&template.Spec)
+	antiAffinity := hostnameAntiAffinity(template)
+	placements := make([]DrainPlacement, 0, len(evicted))
+	for _, pod := range evicted {
+		best, reasons := place(&template.Spec, cpu, memory, antiAffinity)
+		if best == nil {
+			placements = append(placements, DrainPlacement{Pod: pod.Name, Reason: fitReason(len(names), reasons)})
+			continue
+		}
+		take(best, cpu, memory)
+		best.own++
+		placements = append(placements, DrainPlacement{Pod: pod.Name, Node: best.node.Name})
+	}
+	return placements, others, nil
+}
+
+// isDaemonSetPod reports whether a DaemonSet owns a pod, a drain leaves
+// those in place
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// isStaticPod reports whether a pod is the mirror of a static pod the
+// kubelet runs from a file, which a drain cannot evict
+func isStaticPod(pod *corev1.Pod) bool {
+	_, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]
+	return ok
+}
+
+// fitReason sums up why no node fits, e.g. "0/4 nodes fit: 2 insufficient
+// memory, 1 being drained, 1 cordoned"
+func fitReason(nodes int, reasons map[string]int) string {
+	parts := make([]string, 0, len(reasons))
+	for reason, count := range reasons {
+		parts = append(parts, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.Fields(parts[i])[0])
+		b, _ := strconv.Atoi(strings.Fields(parts[j])[0])
+		if a != b {
+			return a > b
+		}
+		return parts[i] < parts[j]
+	})
+	return fmt.Sprintf("0/%d nodes fit: %s", nodes, strings.Join(parts, ", "))
+}
+
+// podRequests sums the CPU and memory requests of a pod as the scheduler
+// does: its containers, or its largest init container if more, and its
+// overhead
+func podRequests(spec *corev1.PodSpec) (int64, int64) {
+	var cpu, memory int64
+	for _, c := range spec.Containers {
+		cpu += c.Resources.Requests.Cpu().MilliValue()
+		memory += c.Resources.Requests.Memory().Value()
+	}
+	for _, c := range spec.InitContainers {
+		cpu = max(cpu, c.Resources.Requests.Cpu().MilliValue())
+		memory = max(memory, c.Resources.Requests.Memory().Value())
+	}
+	if spec.Overhead != nil {
+		cpu += spec.Overhead.Cpu().MilliValue()
+		memory += spec.Overhead.Memory().Value()
+	}
+	return cpu, memory
+}
+
+// isNodeReady reports whether a node has the Ready condition
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeMismatch tells why a pod cannot be scheduled on a node by its taints,
+// node selector and required node affinity, empty if it can
+func nodeMismatch(spec *corev1.PodSpec, node *corev1.Node) string {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range spec.Tolerations {
+			if spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return "untolerated taint " + taint.Key
+		}
+	}
+	for key, value := range spec.NodeSelector {
+		if node.Labels[key] != value {
+			return "node selector"
+		}
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil || spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	// The terms are ORed, the requirements of a term ANDed
+	for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		matches := true
+		for _, requirement := range term.MatchExpressions {
+			value, ok := node.Labels[requirement.Key]
+			matches = matches && requirementMatches(requirement, value, ok)
+		}
+		for _, requirement := range term.MatchFields {
+			matches = matches && requirement.Key == "metadata.name" && requirementMatches(requirement, node.Name, true)
+		}
+		if matches && (len(term.MatchExpressions) > 0 || len(term.MatchFields) > 0) {
+			return ""
+		}
+	}
+	return "node affinity"
+}
+
+// requirementMatches checks a node selector requirement against a label
+// value, ok telling whether the node has the label
+func requirementMatches(requirement corev1.NodeSelectorRequirement, value string, ok bool) bool {
+	in := func() bool {
+		for _, v := range requirement.Values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	compare := func(greater bool) bool {
+		if !ok || len(requirement.Values) != 1 {
+			return false
+		}
+		a, errA := resource.ParseQuantity(value)
+		b, errB := resource.ParseQuantity(requirement.Values[0])
+		if errA != nil || errB != nil {
+			return false
+		}
+		if greater {
+			return a.Cmp(b) > 0
+		}
+		return a.Cmp(b) < 0
+	}
+	switch requirement.Operator {
+	case corev1.NodeSelectorOpIn:
+		return ok && in()
+	case corev1.NodeSelectorOpNotIn:
+		return !ok || !in()
+	case corev1.NodeSelectorOpExists:
+		return ok
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !ok
+	case corev1.NodeSelectorOpGt:
+		return compare(true)
+	case corev1.NodeSelectorOpLt:
+		return compare(false)
+	}
+	return false
+}
+
+// hostnameAntiAffinity reports whether the template's pods must not share
+// a node with each other, by a required anti-affinity on the hostname
+// selecting their own labels
+func hostnameAntiAffinity(template corev1.PodTemplateSpec) bool {
+	affinity := template.Spec.Affinity
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey != hostnameLabel || term.LabelSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err == nil && !selector.Empty() && selector.Matches(labels.Set(template.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListNodeNames returns the names of the cluster's nodes
+func (c *Client) ListNodeNames(ctx context.Context) ([]string, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	names := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,139 @@
+package k8s
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrorExplanation is a short description of a failure with a next step
+type ErrorExplanation struct {
+	Summary string
+	Hint    string
+}
+
+// forbiddenPattern picks the verb and resource out of a Forbidden message
+var forbiddenPattern = regexp.MustCompile(`cannot (\S+) resource "([^"]+)"`)
+
+// ExplainError classifies common API and connection failures into a short
+// summary and a suggestion. It returns false for errors it does not know.
+func ExplainError(err error) (ErrorExplanation, bool) {
+	if err == nil {
+		return ErrorExplanation{}, false
+	}
+	text := err.Error()
+	var certErr *x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var dnsErr *net.DNSError
+
+	switch {
+	case apierrors.IsForbidden(err):
+		summary := "Access denied by RBAC"
+		if m := forbiddenPattern.FindStringSubmatch(text); m != nil {
+			summary = fmt.Sprintf("Not allowed to %s %s", m[1], m[2])
+		}
+		return ErrorExplanation{
+			Summary: summary,
+			Hint:    "Your user or service account lacks this permission. Check it with 'kubectl auth can-i', or ask a cluster admin for a role that grants it.",
+		}, true
+
+	case apierrors.IsUnauthorized(err):
+		return ErrorExplanation{
+			Summary: "Not authenticated",
+			Hint:    "The kubeconfig's credentials are missing, invalid or expired. Log in again or refresh the token, e.g. with your cloud provider's get-credentials command.",
+		}, true
+
+	case apierrors.IsNotFound(err):
+		summary := "Not found"
+		var status apierrors.APIStatus
+		if errors.As(err, &status) {
+			if d := status.Status().Details; d != nil && d.Name != "" {
+				summary = fmt.Sprintf("%s %q not found", singular(d.Kind), d.Name)
+			}
+		}
+		return ErrorExplanation{
+			Summary: summary,
+			Hint:    "It may have been deleted, renamed or replaced by a rollout. Check that the right namespace and kubeconfig are selected.",
+		}, true
+
+	case apierrors.IsConflict(err):
+		return ErrorExplanation{
+			Summary: "The object was changed by someone else",
+			Hint:    "Another client updated it in the meantime. Run the command again to apply it to the latest version.",
+		}, true
+
+	case apierrors.IsTooManyRequests(err), apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err):
+		return ErrorExplanation{
+			Summary: "The API server is overloaded or unavailable",
+			Hint:    "This is usually temporary. Wait a moment and try again.",
+		}, true
+
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err),
+		isTimeout(err):
+		return ErrorExplanation{
+			Summary: "The request timed out",
+			Hint:    "The cluster is slow or unreachable. Check the VPN, try again, or allow more time with --timeout.",
+		}, true
+
+	case errors.As(err, &certErr), errors.As(err, &hostErr), errors.As(err, &invalidErr),
+		strings.Contains(text, "x509:"):
+		return ErrorExplanation{
+			Summary: "The API server's TLS certificate is not trusted",
+			Hint:    "The kubeconfig's certificate-authority-data may be outdated, or a proxy intercepts TLS. Fetch a fresh kubeconfig for the cluster.",
+		}, true
+
+	case errors.As(err, &dnsErr), strings.Contains(text, "no such host"):
+		return ErrorExplanation{
+			Summary: "The API server's host name does not resolve",
+			Hint:    "Check the server address in the kubeconfig and whether the VPN that provides its DNS is connected.",
+		}, true
+
+	case errors.Is(err, syscall.ECONNREFUSED), strings.Contains(text, "connection refused"):
+		return ErrorExplanation{
+			Summary: "Cannot connect to the API server",
+			Hint:    "Nothing answers at the kubeconfig's server address. Is the cluster running and the VPN or tunnel up?",
+		}, true
+	}
+	return ErrorExplanation{}, false
+}
+
+// IsUnreachable reports whether err means the API server could not be
+// reached at all, as opposed to an answer such as forbidden or not found
+func IsUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var certErr *x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr) {
+		return false
+	}
+	var netErr net.Error
+	text := err.Error()
+	return errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, context.DeadlineExceeded) ||
+		apierrors.IsServiceUnavailable(err) || strings.Contains(text, "connection refused") ||
+		strings.Contains(text, "no such host") || strings.Contains(text, "i/o timeout")
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// singular turns a resource name such as "ingresses" into its kind's word
+func singular(resource string) string {
+	if strings.HasSuffix(resource, "sses") {
+		return strings.TrimSuffix(resource, "es")
+	}
+	return strings.TrimSuffix(resource, "s")
+}
@@ -0,0 +1,142 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// EventFilter selects events by type, reason and the kind of object they are
+// about. Empty fields match everything; values are compared case-insensitively.
+type EventFilter struct {
+	Types   []string
+	Reasons []string
+	Kinds   []string
+}
+
+// ParseEventFilter reads a filter such as "warning reason=BackOff,Failed
+// kind=Pod". A bare word is an event type.
+func ParseEventFilter(text string) (EventFilter, error) {
+	var f EventFilter
+	for _, field := range strings.Fields(text) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			f.Types = append(f.Types, field)
+			continue
+		}
+		values := strings.Split(value, ",")
+		switch strings.ToLower(key) {
+		case "type":
+			f.Types = append(f.Types, values...)
+		case "reason":
+			f.Reasons = append(f.Reasons, values...)
+		case "kind":
+			f.Kinds = append(f.Kinds, values...)
+		default:
+			return f, fmt.Errorf("unknown event filter %q, use type=, reason= or kind=", key)
+		}
+	}
+	return f, nil
+}
+
+// IsEmpty reports whether the filter matches every event
+func (f EventFilter) IsEmpty() bool {
+	return len(f.Types) == 0 && len(f.Reasons) == 0 && len(f.Kinds) == 0
+}
+
+// Match reports whether an event passes the filter
+func (f EventFilter) Match(e *corev1.Event) bool {
+	return matchAny(f.Types, e.Type) && matchAny(f.Reasons, e.Reason) && matchAny(f.Kinds, e.InvolvedObject.Kind)
+}
+
+func matchAny(values []string, s string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// EventTime returns when an event was last seen
+func EventTime(e *corev1.Event) time.Time {
+	return eventTime(*e)
+}
+
+// WatchEvents calls handle with the namespace's events, oldest first, and
+// then with every new or updated event until ctx is cancelled. Events last
+// seen before since are skipped. The watch is resumed when the server closes
+// it and restarted from a fresh list when its resource version expires.
+func (c *Client) WatchEvents(ctx context.Context, namespace string, since time.Time, handle func(*corev1.Event)) error {
+	events := c.clientset.CoreV1().Events(namespace)
+
+	for relist := false; ; relist = true {
+		list, err := events.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to list events: %w", err)
+		}
+		sort.Slice(list.Items, func(i, j int) bool {
+			return eventTime(list.Items[i]).Before(eventTime(list.Items[j]))
+		})
+		for i := range list.Items {
+			e := &list.Items[i]
+			if t := eventTime(*e); t.After(since) || (!relist && t.Equal(since)) {
+				handle(e)
+				since = t
+			}
+		}
+
+		resourceVersion := list.ResourceVersion
+		for {
+			w, err := events.Watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					break
+				}
+				return fmt.Errorf("failed to watch events: %w", err)
+			}
+
+			expired := false
+			for ev := range w.ResultChan() {
+				if ev.Type == watch.Error {
+					expired = true
+					break
+				}
+				e, ok := ev.Object.(*corev1.Event)
+				if !ok {
+					continue
+				}
+				resourceVersion = e.ResourceVersion
+				if ev.Type == watch.Added || ev.Type == watch.Modified {
+					handle(e)
+					since = eventTime(*e)
+				}
+			}
+			w.Stop()
+
+			if ctx.Err() != nil {
+				return nil
+			}
+			if expired {
+				// Relist, skipping what was already handled
+				break
+			}
+		}
+	}
+}
@@ -5,9 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 
-	"golang.org/x/term"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/remotecommand"
@@ -23,10 +21,28 @@ type ExecOptions struct {
 	Stdout        io.Writer
 	Stderr        io.Writer
 	TTY           bool
+	// SizeQueue propagates terminal resizes when TTY is set. Optional.
+	SizeQueue remotecommand.TerminalSizeQueue
+}
+
+// ExecFunc runs a command in a container, see SetExec
+type ExecFunc func(ctx context.Context, opts ExecOptions) error
+
+// SetExec makes the client run commands in containers with exec rather than
+// through the API server, e.g. to answer them in a fake cluster
+func (c *Client) SetExec(exec ExecFunc) {
+	c.exec = exec
 }
 
 // Exec executes a command in a container
 func (c *Client) Exec(ctx context.Context, opts ExecOptions) error {
+	if c.exec != nil {
+		return c.exec(ctx, opts)
+	}
+	if c.config == nil {
+		return fmt.Errorf("exec is not supported without a REST config")
+	}
+
 	req := c.clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Name(opts.PodName).
@@ -47,23 +63,38 @@ func (c *Client) Exec(ctx context.Context, opts ExecOptions) error {
 	}
 
 	streamOpts := remotecommand.StreamOptions{
-		Stdin:  opts.Stdin,
-		Stdout: opts.Stdout,
-		Stderr: opts.Stderr,
-		Tty:    opts.TTY,
+		Stdin:             opts.Stdin,
+		Stdout:            opts.Stdout,
+		Stderr:            opts.Stderr,
+		Tty:               opts.TTY,
+		TerminalSizeQueue: opts.SizeQueue,
 	}
 
 	return executor.StreamWithContext(ctx, streamOpts)
 }
 
+// ShellOptions holds options for opening an interactive shell
+type ShellOptions struct {
+	Namespace     string
+	PodName       string
+	ContainerName string
+	// Shell is tried first; common shells are tried after it.
+	Shell  string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
 // Shell opens an interactive shell in a container
 // It tries multiple shells in order: the specified shell, then /bin/bash, /bin/sh, /bin/ash, sh
-func (c *Client) Shell(ctx context.Context, namespace, podName, containerName string, shell string) error {
+// If Stdin is a terminal it is put into raw mode for the duration of the session,
+// and terminal resizes are forwarded to the container.
+func (c *Client) Shell(ctx context.Context, opts ShellOptions) error {
 	// List of shells to try in order of preference
 	shells := []string{}
 
-	if shell != "" {
-		shells = append(shells, shell)
+	if opts.Shell != "" {
+		shells = append(shells, opts.Shell)
 	}
 
 	// Add common shells
@@ -82,24 +113,31 @@ func (c *Client) Shell(ctx context.Context, namespace, podName, containerName st
 		}
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Put terminal into raw mode for proper TTY handling
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	tty, err := setupTTY(opts.Stdin, opts.Stdout, cancel)
 	if err != nil {
 		return fmt.Errorf("failed to set terminal to raw mode: %w", err)
 	}
-	defer term.Restore(int(os.Stdin.Fd()), oldState)
+	defer tty.Restore()
 
 	for _, sh := range shells {
 		err := c.Exec(ctx, ExecOptions{
-			Namespace:     namespace,
-			PodName:       podName,
-			ContainerName: containerName,
+			Namespace:     opts.Namespace,
+			PodName:       opts.PodName,
+			ContainerName: opts.ContainerName,
 			Command:       []string{sh},
-			Stdin:         os.Stdin,
-			Stdout:        os.Stdout,
-			Stderr:        os.Stderr,
+			Stdin:         opts.Stdin,
+			Stdout:        opts.Stdout,
+			Stderr:        opts.Stderr,
 			TTY:           true,
+			SizeQueue:     tty.SizeQueue(),
 		})
+		if sigErr := tty.Err(); sigErr != nil {
+			return sigErr
+		}
 
 		if err == nil {
 			return nil
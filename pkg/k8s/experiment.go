@@ -0,0 +1,124 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// experimentLabel marks experiment pods with the workload they are cloned from
+const experimentLabel = "khelper.io/experiment-of"
+
+// Experiment is what an experiment pod changes in one container of the
+// workload's pod template. Empty fields keep the template's values.
+type Experiment struct {
+	Container string
+	Image     string
+	CPU       string // limit, requests above it are lowered to it
+	Memory    string // limit, requests above it are lowered to it
+	Env       []corev1.EnvVar
+}
+
+// CreateExperimentPod creates a single pod from a workload's pod template
+// with the experiment's changes and waits until it started. The pod has no
+// owner and none of the template's labels, so the replica set does not adopt
+// it and services do not send it traffic. It runs once, to completion or
+// until deleted.
+func (c *Client) CreateExperimentPod(ctx context.Context, namespace, deploymentName string, exp Experiment) (string, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return "", err
+	}
+	_, workloadName := parseWorkloadRef(deploymentName)
+
+	spec := deployment.Spec.Template.Spec.DeepCopy()
+	var container *corev1.Container
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == exp.Container {
+			container = &spec.Containers[i]
+		}
+	}
+	if container == nil {
+		return "", fmt.Errorf("%s has no container %s", deploymentName, exp.Container)
+	}
+	if exp.Image != "" {
+		container.Image = exp.Image
+	}
+	if err := setLimit(container, corev1.ResourceCPU, exp.CPU); err != nil {
+		return "", err
+	}
+	if err := setLimit(container, corev1.ResourceMemory, exp.Memory); err != nil {
+		return "", err
+	}
+	for _, env := range exp.Env {
+		setEnv(container, env)
+	}
+	spec.RestartPolicy = corev1.RestartPolicyNever
+
+	name := workloadName
+	if len(name) > 46 {
+		name = name[:46]
+	}
+	name += "-experiment-" + utilrand.String(5)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{experimentLabel: workloadName},
+			Annotations: deployment.Spec.Template.Annotations,
+		},
+		Spec: *spec,
+	}
+	if _, err := c.clientset.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to create experiment pod: %w", err)
+	}
+	_, err = c.waitPodStarted(ctx, namespace, name)
+	return name, err
+}
+
+// DeleteExperimentPod deletes a pod created by CreateExperimentPod
+func (c *Client) DeleteExperimentPod(ctx context.Context, namespace, name string) error {
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pod: %w", err)
+	}
+	if _, ok := pod.Labels[experimentLabel]; !ok {
+		return fmt.Errorf("pod %s is not an experiment pod", name)
+	}
+	return c.clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// setLimit sets a container's limit of a resource and lowers its request to
+// the limit if it is higher. An empty value keeps both.
+func setLimit(container *corev1.Container, name corev1.ResourceName, value string) error {
+	if value == "" {
+		return nil
+	}
+	limit, err := resource.ParseQuantity(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s limit %q: %w", name, value, err)
+	}
+	if container.Resources.Limits == nil {
+		container.Resources.Limits = corev1.ResourceList{}
+	}
+	container.Resources.Limits[name] = limit
+	if request, ok := container.Resources.Requests[name]; ok && request.Cmp(limit) > 0 {
+		container.Resources.Requests[name] = limit
+	}
+	return nil
+}
+
+// setEnv sets an env var of a container, replacing one of the same name
+func setEnv(container *corev1.Container, env corev1.EnvVar) {
+	for i := range container.Env {
+		if container.Env[i].Name == env.Name {
+			container.Env[i] = env
+			return
+		}
+	}
+	container.Env = append(container.Env, env)
+}
@@ -0,0 +1,122 @@
+package fake
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DemoObjects is a small shop: an api and a web deployment with running
+// pods and services, web with a proxy sidecar and a ConfigMap mounted, and a
+// failed migration pod left over, plus the empty default namespace. Objects
+// are created two hours before now, so their ages render the same on every
+// run.
+func DemoObjects() []runtime.Object {
+	created := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", CreationTimestamp: created}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", CreationTimestamp: created}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "shop", CreationTimestamp: created},
+			Data:       map[string]string{"nginx.conf": "server {\n  listen 3000;\n}\n"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate-28461", Namespace: "shop", CreationTimestamp: created},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "migrate", Image: "ghcr.io/shop/api:1.4.2"}}},
+			Status: corev1.PodStatus{
+				Phase:   corev1.PodFailed,
+				Message: "container migrate exited with code 1",
+			},
+		},
+	}
+	for _, app := range []struct {
+		name     string
+		image    string
+		port     int32
+		replicas int32
+		sidecar  bool
+	}{
+		{"api", "ghcr.io/shop/api:1.4.2", 8080, 2, false},
+		{"web", "ghcr.io/shop/web:2.0.1", 3000, 1, true},
+	} {
+		labels := map[string]string{"app": app.name}
+		containers := []corev1.Container{{
+			Name:  app.name,
+			Image: app.image,
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: app.port}},
+			Env:   []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}},
+		}}
+		var volumes []corev1.Volume
+		if app.sidecar {
+			containers = append(containers, corev1.Container{
+				Name:         "proxy",
+				Image:        "nginx:1.27",
+				VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/nginx/conf.d"}},
+			})
+			volumes = []corev1.Volume{{
+				Name: "config",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}},
+				},
+			}}
+		}
+		podSpec := corev1.PodSpec{Containers: containers, Volumes: volumes}
+		var statuses []corev1.ContainerStatus
+		for _, container := range containers {
+			statuses = append(statuses, corev1.ContainerStatus{
+				Name:  container.Name,
+				Image: container.Image,
+				Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: created}},
+			})
+		}
+		meta := metav1.ObjectMeta{Name: app.name, Namespace: "shop", Labels: labels, CreationTimestamp: created}
+		objects = append(objects,
+			&appsv1.Deployment{
+				ObjectMeta: meta,
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &app.replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       podSpec,
+					},
+				},
+				Status: appsv1.DeploymentStatus{
+					Replicas:          app.replicas,
+					UpdatedReplicas:   app.replicas,
+					ReadyReplicas:     app.replicas,
+					AvailableReplicas: app.replicas,
+				},
+			},
+			&corev1.Service{
+				ObjectMeta: meta,
+				Spec: corev1.ServiceSpec{
+					Selector: labels,
+					Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http")}},
+				},
+			},
+		)
+		for _, suffix := range []string{"x2k9p", "m4q7t"}[:app.replicas] {
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              app.name + "-7d9f8b6c5-" + suffix,
+					Namespace:         "shop",
+					Labels:            labels,
+					CreationTimestamp: created,
+				},
+				Spec: podSpec,
+				Status: corev1.PodStatus{
+					Phase:             corev1.PodRunning,
+					Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+					ContainerStatuses: statuses,
+				},
+			})
+		}
+	}
+	return objects
+}
@@ -0,0 +1,239 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// files is the filesystem every container of the fake cluster has
+var files = map[string]string{
+	"/app/package.json":             "{\n  \"name\": \"shop\",\n  \"version\": \"1.4.2\"\n}\n",
+	"/app/server.js":                "require('./src/app').listen(process.env.PORT)\n",
+	"/app/src/app.js":               "module.exports = require('express')()\n",
+	"/app/assets/main/js/app.js":    "console.log('shop')\n",
+	"/app/assets/admin/js/admin.js": "console.log('admin')\n",
+	"/etc/hostname":                 "shop\n",
+}
+
+// shellPrompt is the prompt of an interactive shell
+const shellPrompt = "/app # "
+
+// exec answers the commands khelper runs in containers from files: sh with
+// a few builtins, ls and cat. Other programs are not found, as in a slim
+// image.
+func exec(ctx context.Context, opts k8s.ExecOptions) error {
+	stdout, stderr := writerOrDiscard(opts.Stdout), writerOrDiscard(opts.Stderr)
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("no command given")
+	}
+	switch args := opts.Command[1:]; opts.Command[0] {
+	case "sh", "/bin/sh":
+		if len(args) == 0 {
+			return shell(ctx, opts.Stdin, stdout)
+		}
+		if len(args) == 2 && args[0] == "-c" {
+			return script(args[1], stdout, stderr)
+		}
+	case "ls":
+		if len(args) == 2 && args[0] == "-1ApL" {
+			return list(args[1], stdout, stderr)
+		}
+	case "cat":
+		if len(args) == 1 {
+			return cat(args[0], stdout, stderr)
+		}
+	}
+	return fmt.Errorf("exec: %q: executable file not found in $PATH", opts.Command[0])
+}
+
+// shell echoes the keys typed into a TTY and runs each line as a script,
+// until exit or the end of the input
+func shell(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	out := &ttyWriter{stdout}
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		var line []byte
+		buf := make([]byte, 64)
+		for {
+			n, err := stdin.Read(buf)
+			for _, b := range buf[:n] {
+				switch b {
+				case '\r', '\n':
+					select {
+					case lines <- string(line):
+					case <-ctx.Done():
+						return
+					}
+					line = line[:0]
+				case 0x7f:
+					if len(line) > 0 {
+						line = line[:len(line)-1]
+						io.WriteString(out, "\b \b")
+					}
+				default:
+					line = append(line, b)
+					out.Write([]byte{b})
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	io.WriteString(out, shellPrompt)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			io.WriteString(out, "\n")
+			if strings.TrimSpace(line) == "exit" {
+				return nil
+			}
+			if strings.TrimSpace(line) != "" {
+				script(line, out, out)
+			}
+			io.WriteString(out, shellPrompt)
+		}
+	}
+}
+
+// script runs the builtins sh -c needs for khelper: echo, exit, ls, cat and
+// find listing the directories of a directory
+func script(text string, stdout, stderr io.Writer) error {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil
+	}
+	switch fields[0] {
+	case "exit":
+		code := 0
+		if len(fields) > 1 {
+			code, _ = strconv.Atoi(fields[1])
+		}
+		return exitCode(code)
+	case "echo":
+		fmt.Fprintln(stdout, strings.Join(fields[1:], " "))
+		return nil
+	case "ls":
+		dir := "/app"
+		if len(fields) > 1 {
+			dir = fields[len(fields)-1]
+		}
+		return list(dir, stdout, stderr)
+	case "cat":
+		if len(fields) > 1 {
+			return cat(fields[1], stdout, stderr)
+		}
+	case "find":
+		if len(fields) > 1 {
+			entries, err := dirEntries(fields[1])
+			if err != nil {
+				return exitCode(1)
+			}
+			for _, entry := range entries {
+				if name, ok := strings.CutSuffix(entry, "/"); ok {
+					fmt.Fprintln(stdout, name)
+				}
+			}
+			return nil
+		}
+	}
+	fmt.Fprintf(stderr, "sh: %s: not found\n", fields[0])
+	return exitCode(127)
+}
+
+// list prints a directory like ls -1Ap
+func list(dir string, stdout, stderr io.Writer) error {
+	entries, err := dirEntries(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "ls: %s: No such file or directory\n", dir)
+		return exitCode(1)
+	}
+	for _, entry := range entries {
+		fmt.Fprintln(stdout, entry)
+	}
+	return nil
+}
+
+// cat prints a file
+func cat(file string, stdout, stderr io.Writer) error {
+	data, ok := files[path.Clean(file)]
+	if !ok {
+		fmt.Fprintf(stderr, "cat: can't open '%s': No such file or directory\n", file)
+		return exitCode(1)
+	}
+	io.WriteString(stdout, data)
+	return nil
+}
+
+// dirEntries returns the entries of a directory of files, sorted,
+// directories with a trailing slash
+func dirEntries(dir string) ([]string, error) {
+	dir = path.Clean("/" + dir)
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	seen := make(map[string]bool)
+	for file := range files {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok {
+			continue
+		}
+		if name, _, isDir := strings.Cut(rest, "/"); isDir {
+			seen[name+"/"] = true
+		} else {
+			seen[name] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("%s not found", dir)
+	}
+	entries := make([]string, 0, len(seen))
+	for entry := range seen {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// exitCode is the error of a command exiting with code, as the API server
+// reports it
+func exitCode(code int) error {
+	if code == 0 {
+		return nil
+	}
+	return utilexec.CodeExitError{Err: fmt.Errorf("command terminated with exit code %d", code), Code: code}
+}
+
+func writerOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
+
+// ttyWriter turns line feeds into the carriage return and line feed a TTY
+// sends
+type ttyWriter struct {
+	w io.Writer
+}
+
+func (t *ttyWriter) Write(p []byte) (int, error) {
+	if _, err := t.w.Write([]byte(strings.ReplaceAll(string(p), "\n", "\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,155 @@
+// Package fake provides a khelper client backed by client-go's fake
+// clientset, so UI flows can be exercised without a cluster.
+package fake
+
+import (
+	"khelper/pkg/k8s"
+
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authenticationv1beta1 "k8s.io/api/authentication/v1beta1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// NewClient returns a client whose API calls are served from the given
+// objects. Commands run in containers are answered from a small filesystem
+// the containers share, see exec; port-forwards return an error. Dry runs are stored like any update, the fake clientset
+// does not know them. The deployments scale subresource and SelfSubjectReviews,
+// which the fake clientset does not serve, are answered by reactors.
+func NewClient(objects ...runtime.Object) *k8s.Client {
+	clientset := fake.NewSimpleClientset(objects...)
+	clientset.Resources = resources
+	clientset.PrependReactor("create", "*", generatedName)
+	clientset.PrependReactor("get", "deployments", getScale(clientset))
+	clientset.PrependReactor("update", "deployments", updateScale(clientset))
+	clientset.PrependReactor("create", "selfsubjectreviews", selfSubjectReview)
+	client := k8s.NewClientFromClientset(clientset, nil, "(fake)")
+	client.SetExec(exec)
+	return client
+}
+
+// resources are the kinds discovery finds: those the demo objects are made
+// of, and the APIs khelper's features check for
+var resources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "namespaces", Kind: "Namespace", SingularName: "namespace", ShortNames: []string{"ns"}, Verbs: verbs},
+			{Name: "pods", Kind: "Pod", SingularName: "pod", Namespaced: true, ShortNames: []string{"po"}, Verbs: verbs},
+			{Name: "pods/log", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+			{Name: "services", Kind: "Service", SingularName: "service", Namespaced: true, ShortNames: []string{"svc"}, Verbs: verbs},
+		},
+	},
+	{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", SingularName: "deployment", Namespaced: true, ShortNames: []string{"deploy"}, Verbs: verbs},
+		},
+	},
+	{
+		GroupVersion: "networking.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "ingresses", Kind: "Ingress", SingularName: "ingress", Namespaced: true, ShortNames: []string{"ing"}, Verbs: verbs},
+		},
+	},
+	{
+		GroupVersion: "autoscaling/v2",
+		APIResources: []metav1.APIResource{
+			{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler", SingularName: "horizontalpodautoscaler", Namespaced: true, ShortNames: []string{"hpa"}, Verbs: verbs},
+		},
+	},
+	{
+		GroupVersion: "authentication.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "selfsubjectreviews", Kind: "SelfSubjectReview", SingularName: "selfsubjectreview", Verbs: metav1.Verbs{"create"}},
+		},
+	},
+}
+
+var verbs = metav1.Verbs{"get", "list", "watch", "create", "update", "patch", "delete"}
+
+// generatedName answers a create with generateName, which khelper only
+// makes as a dry run, with the object named but not stored
+func generatedName(action k8stesting.Action) (bool, runtime.Object, error) {
+	create, ok := action.(k8stesting.CreateAction)
+	if !ok {
+		return false, nil, nil
+	}
+	object := create.GetObject().DeepCopyObject()
+	accessor, err := meta.Accessor(object)
+	if err != nil || accessor.GetName() != "" || accessor.GetGenerateName() == "" {
+		return false, nil, nil
+	}
+	accessor.SetName(accessor.GetGenerateName() + "fake0")
+	return true, object, nil
+}
+
+// User is who the fake API server authenticates the client as
+const User = "demo-user"
+
+// getScale answers a get of the deployments scale subresource, which the
+// fake clientset does not serve, from the deployment
+func getScale(clientset *fake.Clientset) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get, ok := action.(k8stesting.GetAction)
+		if !ok || get.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		deployment, err := clientset.Tracker().Get(action.GetResource(), get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		return true, scaleOf(deployment.(*appsv1.Deployment)), nil
+	}
+}
+
+// updateScale answers an update of the deployments scale subresource by
+// setting the deployment's replicas
+func updateScale(clientset *fake.Clientset) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update, ok := action.(k8stesting.UpdateAction)
+		if !ok || update.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := update.GetObject().(*autoscalingv1.Scale)
+		object, err := clientset.Tracker().Get(action.GetResource(), update.GetNamespace(), scale.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		deployment := object.(*appsv1.Deployment).DeepCopy()
+		deployment.Spec.Replicas = &scale.Spec.Replicas
+		if err := clientset.Tracker().Update(action.GetResource(), deployment, update.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, scaleOf(deployment), nil
+	}
+}
+
+// scaleOf returns the scale subresource of a deployment
+func scaleOf(deployment *appsv1.Deployment) *autoscalingv1.Scale {
+	scale := &autoscalingv1.Scale{
+		ObjectMeta: metav1.ObjectMeta{Name: deployment.Name, Namespace: deployment.Namespace, ResourceVersion: deployment.ResourceVersion},
+		Status:     autoscalingv1.ScaleStatus{Replicas: deployment.Status.Replicas},
+	}
+	if deployment.Spec.Replicas != nil {
+		scale.Spec.Replicas = *deployment.Spec.Replicas
+	} else {
+		scale.Spec.Replicas = 1
+	}
+	return scale
+}
+
+// selfSubjectReview answers a SelfSubjectReview with User, without storing
+// it
+func selfSubjectReview(action k8stesting.Action) (bool, runtime.Object, error) {
+	userInfo := authenticationv1.UserInfo{Username: User, Groups: []string{"system:authenticated"}}
+	if action.GetResource().Version == "v1beta1" {
+		return true, &authenticationv1beta1.SelfSubjectReview{Status: authenticationv1beta1.SelfSubjectReviewStatus{UserInfo: userInfo}}, nil
+	}
+	return true, &authenticationv1.SelfSubjectReview{Status: authenticationv1.SelfSubjectReviewStatus{UserInfo: userInfo}}, nil
+}
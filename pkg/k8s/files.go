@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -84,6 +85,21 @@ func (c *Client) ClearDirectory(ctx context.Context, namespace, podName, contain
 	return nil
 }
 
+// UploadOptions holds options for uploading local files to a container
+type UploadOptions struct {
+	Namespace     string
+	PodName       string
+	ContainerName string
+	// LocalPath is a directory for UploadDirectory and a file for UploadFile.
+	LocalPath string
+	// RemotePath is the directory in the container to extract into.
+	RemotePath string
+	// Excludes are glob patterns of files and directories UploadDirectory
+	// leaves out, matched against their slash-separated path relative to
+	// LocalPath and against their name.
+	Excludes []string
+}
+
 // UploadResult contains the result of an upload operation
 type UploadResult struct {
 	FileCount int
@@ -92,7 +108,9 @@ type UploadResult struct {
 
 // UploadDirectory uploads a local directory to a container path
 // This mimics kubectl cp behavior using tar
-func (c *Client) UploadDirectory(ctx context.Context, namespace, podName, container, localPath, remotePath string) (*UploadResult, error) {
+func (c *Client) UploadDirectory(ctx context.Context, opts UploadOptions) (*UploadResult, error) {
+	namespace, podName, container := opts.Namespace, opts.PodName, opts.ContainerName
+	localPath, remotePath := opts.LocalPath, opts.RemotePath
 	result := &UploadResult{
 		Files: make([]string, 0),
 	}
@@ -132,6 +150,16 @@ func (c *Client) UploadDirectory(ctx context.Context, namespace, podName, contai
 			return nil
 		}
 
+		// Tar entries always use forward slashes, whatever the local OS
+		relPath = filepath.ToSlash(relPath)
+
+		if excluded(relPath, opts.Excludes) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Create tar header
 		header, err := tar.FileInfoHeader(info, "")
 		if err != nil {
@@ -192,8 +220,25 @@ func (c *Client) UploadDirectory(ctx context.Context, namespace, podName, contai
 	return result, nil
 }
 
+// excluded reports whether a relative path matches one of the glob patterns,
+// as a whole or by its name
+func excluded(relPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(relPath)); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // UploadFile uploads a single file to a container path (with gzip support like your script)
-func (c *Client) UploadFile(ctx context.Context, namespace, podName, container, localFile, remotePath string) error {
+func (c *Client) UploadFile(ctx context.Context, opts UploadOptions) error {
+	namespace, podName, container := opts.Namespace, opts.PodName, opts.ContainerName
+	localFile, remotePath := opts.LocalPath, opts.RemotePath
+
 	// Read file content
 	content, err := os.ReadFile(localFile)
 	if err != nil {
@@ -201,8 +246,6 @@ func (c *Client) UploadFile(ctx context.Context, namespace, podName, container,
 	}
 
 	fileName := filepath.Base(localFile)
-	remoteFile := filepath.Join(remotePath, fileName)
-	remoteFile = strings.ReplaceAll(remoteFile, "\\", "/")
 
 	// Create tar with single file
 	var tarBuffer bytes.Buffer
@@ -0,0 +1,473 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gatewayGroup is the API group of the Gateway API CRDs
+const gatewayGroup = "gateway.networking.k8s.io"
+
+// gatewayVersions are the Gateway API versions khelper reads, preferred first
+var gatewayVersions = []string{"v1", "v1beta1"}
+
+// The Gateway API types are not part of client-go; these are the fields
+// khelper reads, converted from the dynamic client's unstructured objects.
+
+type httpRoute struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		ParentRefs []gatewayParentRef `json:"parentRefs"`
+		Hostnames  []string           `json:"hostnames"`
+		Rules      []httpRouteRule    `json:"rules"`
+	} `json:"spec"`
+	Status struct {
+		Parents []struct {
+			ParentRef  gatewayParentRef   `json:"parentRef"`
+			Conditions []metav1.Condition `json:"conditions"`
+		} `json:"parents"`
+	} `json:"status"`
+}
+
+type gatewayParentRef struct {
+	Group       string `json:"group"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName"`
+}
+
+type httpRouteRule struct {
+	Matches []struct {
+		Path *struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"path"`
+		Method  string `json:"method"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+	} `json:"matches"`
+	BackendRefs []gatewayBackendRef `json:"backendRefs"`
+}
+
+type gatewayBackendRef struct {
+	Group     string `json:"group"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Port      *int32 `json:"port"`
+	Weight    *int32 `json:"weight"`
+}
+
+type gatewayListenerTLS struct {
+	Mode            string `json:"mode"`
+	CertificateRefs []struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"certificateRefs"`
+}
+
+type gateway struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		GatewayClassName string `json:"gatewayClassName"`
+		Listeners        []struct {
+			Name     string              `json:"name"`
+			Hostname string              `json:"hostname"`
+			Port     int32               `json:"port"`
+			Protocol string              `json:"protocol"`
+			TLS      *gatewayListenerTLS `json:"tls"`
+		} `json:"listeners"`
+	} `json:"spec"`
+	Status struct {
+		Addresses []struct {
+			Value string `json:"value"`
+		} `json:"addresses"`
+		Conditions []metav1.Condition `json:"conditions"`
+		Listeners  []struct {
+			Name           string             `json:"name"`
+			AttachedRoutes int32              `json:"attachedRoutes"`
+			Conditions     []metav1.Condition `json:"conditions"`
+		} `json:"listeners"`
+	} `json:"status"`
+}
+
+// DescribeGatewayRoutes returns a report per HTTPRoute of the namespace:
+// its hostnames, whether its parent Gateways accepted it and each rule's
+// matches and backends, checked like ingress backends. Routes to the
+// deployment's services come first. The Gateways the routes attach to
+// follow with their class, addresses and listeners. The Gateway API is
+// read with the dynamic client, in the newest version the cluster serves.
+func (c *Client) DescribeGatewayRoutes(ctx context.Context, namespace, deploymentName string) (string, error) {
+	version, err := c.gatewayAPIVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	if version == "" {
+		return "The Gateway API is not installed in this cluster (no " + gatewayGroup + " CRDs)\n", nil
+	}
+	list, err := c.dynamic.Resource(gatewayResource(version, "httproutes")).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return "The Gateway API is not installed in this cluster (no " + gatewayGroup + " CRDs)\n", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to list HTTPRoutes: %w", err)
+	}
+	if len(list.Items) == 0 {
+		return fmt.Sprintf("No HTTPRoutes in %s\n", namespace), nil
+	}
+	routes := make([]httpRoute, 0, len(list.Items))
+	for i := range list.Items {
+		var route httpRoute
+		if err := fromUnstructured(&list.Items[i], &route); err != nil {
+			return "", err
+		}
+		routes = append(routes, route)
+	}
+
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list services: %w", err)
+	}
+	ownServices := c.deploymentServices(ctx, namespace, deploymentName, services.Items)
+	check := newBackendCheck(c, namespace, services.Items)
+	related := func(route httpRoute) bool {
+		for _, rule := range route.Spec.Rules {
+			for _, ref := range rule.BackendRefs {
+				if isServiceRef(ref) && ref.Namespace == "" && ownServices[ref.Name] {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return related(routes[i]) && !related(routes[j])
+	})
+
+	_, workloadName := parseWorkloadRef(deploymentName)
+	w := newDescribeWriter()
+	var gateways []gatewayParentRef
+	seen := make(map[string]bool)
+	for i, route := range routes {
+		if i > 0 {
+			w.line(0, "")
+		}
+		name := "HTTPRoute " + route.Name
+		if related(route) {
+			name += "  (routes to " + workloadName + ")"
+		}
+		w.line(0, "Name:\t%s", name)
+		w.line(0, "Hostnames:\t%s", joinOrNone(route.Spec.Hostnames))
+		describeRouteParents(w, route)
+		describeRouteRules(ctx, w, route, check)
+
+		for _, ref := range route.Spec.ParentRefs {
+			if !isGatewayRef(ref) {
+				continue
+			}
+			if ref.Namespace == "" {
+				ref.Namespace = namespace
+			}
+			if key := ref.Namespace + "/" + ref.Name; !seen[key] {
+				seen[key] = true
+				gateways = append(gateways, ref)
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, ref := range gateways {
+		w.line(0, "")
+		c.describeGateway(ctx, w, version, ref.Namespace, ref.Name, now)
+	}
+	return w.String(), nil
+}
+
+// gatewayAPIVersion returns the newest Gateway API version the cluster
+// serves, empty if it serves none. Without discovery data the newest one is
+// assumed.
+func (c *Client) gatewayAPIVersion(ctx context.Context) (string, error) {
+	if c.dynamic == nil {
+		return "", fmt.Errorf("the Gateway API needs a dynamic client")
+	}
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return gatewayVersions[0], nil
+	}
+	for _, version := range gatewayVersions {
+		if caps.Serves(gatewayGroup + "/" + version) {
+			return version, nil
+		}
+	}
+	return "", nil
+}
+
+// gatewayResource returns a Gateway API resource in a version
+func gatewayResource(version, resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: gatewayGroup, Version: version, Resource: resource}
+}
+
+// fromUnstructured converts a dynamic client object into one of the Gateway
+// API types above
+func fromUnstructured(obj *unstructured.Unstructured, into interface{}) error {
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, into); err != nil {
+		return fmt.Errorf("failed to read %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// isGatewayRef reports whether a route's parent is a Gateway, the default
+func isGatewayRef(ref gatewayParentRef) bool {
+	return (ref.Group == "" || ref.Group == gatewayGroup) && (ref.Kind == "" || ref.Kind == "Gateway")
+}
+
+// isServiceRef reports whether a route's backend is a Service, the default
+func isServiceRef(ref gatewayBackendRef) bool {
+	return ref.Group == "" && (ref.Kind == "" || ref.Kind == "Service")
+}
+
+// describeRouteParents writes the parents of a route and whether each
+// accepted it, from the status their controllers write on the route
+func describeRouteParents(w *describeWriter, route httpRoute) {
+	if len(route.Spec.ParentRefs) == 0 {
+		w.line(0, "Parents:\t<none>")
+		return
+	}
+	w.line(0, "Parents:")
+	for _, ref := range route.Spec.ParentRefs {
+		status := "? no status from the gateway's controller"
+		for _, parent := range route.Status.Parents {
+			if describeParentRef(parent.ParentRef, route.Namespace) == describeParentRef(ref, route.Namespace) {
+				status = conditionStatus(parent.Conditions, "Accepted", "ResolvedRefs")
+				if status == "" {
+					status = "accepted"
+				}
+				break
+			}
+		}
+		w.line(1, "%s\t%s", describeParentRef(ref, route.Namespace), status)
+	}
+}
+
+// describeParentRef renders a route's parent, e.g. "Gateway infra/public
+// (listener https)"
+func describeParentRef(ref gatewayParentRef, routeNamespace string) string {
+	kind := ref.Kind
+	if kind == "" {
+		kind = "Gateway"
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = routeNamespace
+	}
+	s := fmt.Sprintf("%s %s/%s", kind, namespace, ref.Name)
+	if ref.SectionName != "" {
+		s += " (listener " + ref.SectionName + ")"
+	}
+	return s
+}
+
+// describeRouteRules writes a route's rules as a table of matches and
+// backends, each backend checked for an existing service and port with
+// ready endpoints
+func describeRouteRules(ctx context.Context, w *describeWriter, route httpRoute, check *backendCheck) {
+	w.line(0, "Rules:")
+	w.line(1, "Matches\tBackend\tStatus")
+	w.line(1, "-------\t-------\t------")
+	for _, rule := range route.Spec.Rules {
+		matches := describeRouteMatches(rule)
+		if len(rule.BackendRefs) == 0 {
+			w.line(1, "%s\t<none>\t", matches)
+			continue
+		}
+		for i, ref := range rule.BackendRefs {
+			if i > 0 {
+				matches = ""
+			}
+			w.line(1, "%s\t%s\t%s", matches, describeGatewayBackend(ref, len(rule.BackendRefs) > 1), gatewayBackendStatus(ctx, ref, check))
+		}
+	}
+}
+
+// describeRouteMatches renders a rule's matches, e.g. "PathPrefix /api GET";
+// a rule without matches matches everything
+func describeRouteMatches(rule httpRouteRule) string {
+	if len(rule.Matches) == 0 {
+		return "PathPrefix /"
+	}
+	var matches []string
+	for _, match := range rule.Matches {
+		var parts []string
+		if match.Path != nil {
+			parts = append(parts, match.Path.Type+" "+match.Path.Value)
+		}
+		if match.Method != "" {
+			parts = append(parts, match.Method)
+		}
+		for _, header := range match.Headers {
+			parts = append(parts, header.Name+"="+header.Value)
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "PathPrefix /")
+		}
+		matches = append(matches, strings.Join(parts, " "))
+	}
+	return strings.Join(matches, ", ")
+}
+
+// describeGatewayBackend renders a route backend, e.g. "web:80", with its
+// weight when the rule splits traffic
+func describeGatewayBackend(ref gatewayBackendRef, weighted bool) string {
+	s := ref.Name
+	if !isServiceRef(ref) {
+		s = ref.Kind + "/" + ref.Name
+	}
+	if ref.Namespace != "" {
+		s = ref.Namespace + "/" + s
+	}
+	if ref.Port != nil {
+		s += fmt.Sprintf(":%d", *ref.Port)
+	}
+	if weighted {
+		weight := int32(1)
+		if ref.Weight != nil {
+			weight = *ref.Weight
+		}
+		s += fmt.Sprintf(" (weight %d)", weight)
+	}
+	return s
+}
+
+// gatewayBackendStatus checks a route backend in the route's namespace;
+// backends in other namespaces or of other kinds are not checked
+func gatewayBackendStatus(ctx context.Context, ref gatewayBackendRef, check *backendCheck) string {
+	switch {
+	case !isServiceRef(ref):
+		return "not a Service, not checked"
+	case ref.Namespace != "" && ref.Namespace != check.namespace:
+		return "other namespace, needs a ReferenceGrant"
+	case ref.Port == nil:
+		return "✗ no port set"
+	}
+	return check.serviceStatus(ctx, ref.Name, networkingv1.ServiceBackendPort{Number: *ref.Port})
+}
+
+// describeGateway writes a Gateway's class, addresses and listeners with
+// their TLS certificates and attached routes
+func (c *Client) describeGateway(ctx context.Context, w *describeWriter, version, namespace, name string, now time.Time) {
+	w.line(0, "Name:\tGateway %s/%s", namespace, name)
+	obj, err := c.dynamic.Resource(gatewayResource(version, "gateways")).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		w.line(0, "Status:\t✗ gateway not found")
+		return
+	case apierrors.IsForbidden(err):
+		w.line(0, "Status:\t? not allowed to read the gateway")
+		return
+	case err != nil:
+		w.line(0, "Status:\t? %v", err)
+		return
+	}
+	var gw gateway
+	if err := fromUnstructured(obj, &gw); err != nil {
+		w.line(0, "Status:\t? %v", err)
+		return
+	}
+
+	var addresses []string
+	for _, address := range gw.Status.Addresses {
+		addresses = append(addresses, address.Value)
+	}
+	address := strings.Join(addresses, ", ")
+	if address == "" {
+		address = "<pending>"
+	}
+	status := conditionStatus(gw.Status.Conditions, "Accepted", "Programmed")
+	if status == "" {
+		status = "programmed"
+	}
+	w.line(0, "Class:\t%s", gw.Spec.GatewayClassName)
+	w.line(0, "Address:\t%s", address)
+	w.line(0, "Status:\t%s", status)
+
+	w.line(0, "Listeners:")
+	w.line(1, "Name\tProtocol\tPort\tHostname\tRoutes\tStatus\tTLS")
+	w.line(1, "----\t--------\t----\t--------\t------\t------\t---")
+	for _, listener := range gw.Spec.Listeners {
+		hostname := listener.Hostname
+		if hostname == "" {
+			hostname = "*"
+		}
+		routes, status := "?", "? no status from the gateway's controller"
+		for _, ls := range gw.Status.Listeners {
+			if ls.Name == listener.Name {
+				routes = fmt.Sprint(ls.AttachedRoutes)
+				status = conditionStatus(ls.Conditions, "Accepted", "Programmed", "ResolvedRefs")
+				if status == "" {
+					status = "ok"
+				}
+				break
+			}
+		}
+		w.line(1, "%s\t%s\t%d\t%s\t%s\t%s\t%s", listener.Name, listener.Protocol, listener.Port, hostname, routes, status, c.listenerTLS(ctx, listener.TLS, namespace, now))
+	}
+}
+
+// listenerTLS describes the certificates of a listener, e.g.
+// "web-tls: 2026-12-01 (46 days)"
+func (c *Client) listenerTLS(ctx context.Context, tls *gatewayListenerTLS, namespace string, now time.Time) string {
+	if tls == nil {
+		return ""
+	}
+	if len(tls.CertificateRefs) == 0 {
+		return tls.Mode
+	}
+	var certs []string
+	for _, ref := range tls.CertificateRefs {
+		if ref.Kind != "" && ref.Kind != "Secret" {
+			certs = append(certs, ref.Kind+"/"+ref.Name)
+			continue
+		}
+		secretNamespace := ref.Namespace
+		if secretNamespace == "" {
+			secretNamespace = namespace
+		}
+		certs = append(certs, ref.Name+": "+c.secretExpiry(ctx, secretNamespace, ref.Name, now))
+	}
+	return strings.Join(certs, ", ")
+}
+
+// conditionStatus returns "✗ Type Reason: message" for the first of the
+// given condition types that is not true, empty if all are true or unset
+func conditionStatus(conditions []metav1.Condition, types ...string) string {
+	for _, t := range types {
+		for _, cond := range conditions {
+			if cond.Type != t || cond.Status == metav1.ConditionTrue {
+				continue
+			}
+			s := "✗ " + cond.Type + " " + strings.ToLower(string(cond.Status))
+			if cond.Reason != "" {
+				s += " (" + cond.Reason + ")"
+			}
+			if cond.Message != "" {
+				s += ": " + cond.Message
+			}
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HealthLevel rates how well a deployment serves
+type HealthLevel int
+
+const (
+	// HealthOK means all desired replicas are ready and up to date
+	HealthOK HealthLevel = iota
+	// HealthDegraded means some replicas are not ready or a rollout is
+	// under way
+	HealthDegraded
+	// HealthDown means no replica is ready or the rollout is stuck
+	HealthDown
+	// HealthIdle means the deployment is scaled to zero
+	HealthIdle
+)
+
+// DeploymentHealth is the readiness of a deployment
+type DeploymentHealth struct {
+	Ready   int32
+	Desired int32
+	Level   HealthLevel
+}
+
+// ListDeploymentHealth returns the readiness of the deployments in a
+// namespace by name, from a single list call
+func (c *Client) ListDeploymentHealth(ctx context.Context, namespace string) (map[string]DeploymentHealth, error) {
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	health := make(map[string]DeploymentHealth, len(deployments.Items))
+	for i := range deployments.Items {
+		health[deployments.Items[i].Name] = deploymentHealth(&deployments.Items[i])
+	}
+	return health, nil
+}
+
+// deploymentHealth rates a deployment from its status
+func deploymentHealth(d *appsv1.Deployment) DeploymentHealth {
+	h := DeploymentHealth{Ready: d.Status.ReadyReplicas, Desired: 1}
+	if d.Spec.Replicas != nil {
+		h.Desired = *d.Spec.Replicas
+	}
+	stuck := false
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			stuck = true
+		}
+	}
+
+	switch {
+	case h.Desired == 0:
+		h.Level = HealthIdle
+	case stuck || h.Ready == 0:
+		h.Level = HealthDown
+	case h.Ready < h.Desired || d.Status.UpdatedReplicas < h.Desired:
+		h.Level = HealthDegraded
+	default:
+		h.Level = HealthOK
+	}
+	return h
+}
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// ChangeCauseAnnotation describes why a revision was rolled out
+const ChangeCauseAnnotation = "kubernetes.io/change-cause"
+
+const (
+	revisionAnnotation        = "deployment.kubernetes.io/revision"
+	revisionHistoryAnnotation = "deployment.kubernetes.io/revision-history"
+)
+
+// Revision is an entry of a deployment's rollout history, taken from the
+// replica set that carries it
+type Revision struct {
+	Number      int64
+	Earlier     []string // revision numbers the same template had before it was rolled back to
+	ReplicaSet  string
+	Created     time.Time
+	ChangeCause string
+	Images      []string // container=image
+	Changes     []string // what differs from the previous revision
+	Replicas    int32
+	Ready       int32
+	Current     bool
+}
+
+// RolloutHistory is a deployment's revisions, oldest first, with its
+// current conditions
+type RolloutHistory struct {
+	Deployment string
+	Revisions  []Revision
+	Conditions []appsv1.DeploymentCondition
+}
+
+// GetRolloutHistory returns the revisions of a deployment that still have a
+// replica set, with the changes between consecutive ones
+func (c *Client) GetRolloutHistory(ctx context.Context, namespace, name string) (*RolloutHistory, error) {
+	if kind, workloadName := parseWorkloadRef(name); kind != nil {
+		return nil, errDeploymentOnly("history", kind, workloadName)
+	}
+	deployment, err := c.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := c.GetReplicaSets(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	// A revision with the pod template it rolled out
+	type entry struct {
+		rev  Revision
+		spec *corev1.PodSpec
+	}
+	var entries []entry
+	current := deployment.Annotations[revisionAnnotation]
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		owned := false
+		for _, ref := range rs.OwnerReferences {
+			if ref.UID == deployment.UID {
+				owned = true
+			}
+		}
+		if !owned {
+			continue
+		}
+		number, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		rev := Revision{
+			Number:      number,
+			ReplicaSet:  rs.Name,
+			Created:     rs.CreationTimestamp.Time,
+			ChangeCause: rs.Annotations[ChangeCauseAnnotation],
+			Replicas:    rs.Status.Replicas,
+			Ready:       rs.Status.ReadyReplicas,
+			Current:     rs.Annotations[revisionAnnotation] == current,
+		}
+		if earlier := rs.Annotations[revisionHistoryAnnotation]; earlier != "" {
+			rev.Earlier = strings.Split(earlier, ",")
+		}
+		for _, container := range rs.Spec.Template.Spec.Containers {
+			rev.Images = append(rev.Images, container.Name+"="+container.Image)
+		}
+		entries = append(entries, entry{rev: rev, spec: &rs.Spec.Template.Spec})
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].rev.Number < entries[b].rev.Number })
+
+	history := &RolloutHistory{Deployment: name, Conditions: deployment.Status.Conditions}
+	for i, e := range entries {
+		if i > 0 {
+			e.rev.Changes = templateChanges(entries[i-1].spec, e.spec)
+		}
+		history.Revisions = append(history.Revisions, e.rev)
+	}
+	return history, nil
+}
+
+// templateChanges describes how a pod template differs from the previous
+// revision's: images, env vars and resources per container, or the template
+// as a whole
+func templateChanges(before, after *corev1.PodSpec) []string {
+	var changes []string
+	previous := map[string]corev1.Container{}
+	for _, c := range before.Containers {
+		previous[c.Name] = c
+	}
+	for _, c := range after.Containers {
+		old, ok := previous[c.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("container %s added (%s)", c.Name, c.Image))
+			continue
+		}
+		delete(previous, c.Name)
+		if old.Image != c.Image {
+			changes = append(changes, fmt.Sprintf("image %s: %s → %s", c.Name, old.Image, c.Image))
+		}
+		if keys := changedEnv(old.Env, c.Env); len(keys) > 0 {
+			changes = append(changes, fmt.Sprintf("env %s: %s", c.Name, strings.Join(keys, ", ")))
+		}
+		if !equality.Semantic.DeepEqual(old.Resources, c.Resources) {
+			changes = append(changes, fmt.Sprintf("resources %s", c.Name))
+		}
+	}
+	for name := range previous {
+		changes = append(changes, fmt.Sprintf("container %s removed", name))
+	}
+	if len(changes) == 0 && !equality.Semantic.DeepEqual(before, after) {
+		changes = append(changes, "pod template changed")
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// changedEnv lists the env vars that were added, removed or changed
+func changedEnv(before, after []corev1.EnvVar) []string {
+	old := map[string]corev1.EnvVar{}
+	for _, e := range before {
+		old[e.Name] = e
+	}
+	var keys []string
+	for _, e := range after {
+		prev, ok := old[e.Name]
+		delete(old, e.Name)
+		switch {
+		case !ok:
+			keys = append(keys, "+"+e.Name)
+		case !equality.Semantic.DeepEqual(prev, e):
+			keys = append(keys, "~"+e.Name)
+		}
+	}
+	for name := range old {
+		keys = append(keys, "-"+name)
+	}
+	sort.Strings(keys)
+	return keys
+}
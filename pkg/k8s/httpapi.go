@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ServicePrefix starts the URLs of APIs next to the cluster, such as
+// Prometheus, that are reached through the API server's service proxy,
+// e.g. service/monitoring/prometheus:9090
+const ServicePrefix = "service/"
+
+// maxAPIResponse bounds the answers read from such APIs
+const maxAPIResponse = 32 << 20
+
+// apiRequest is a GET of an HTTP API such as Prometheus or a tracing
+// backend
+type apiRequest struct {
+	base       string // http(s) URL, or service/<namespace>/<name>[:<port>]
+	path       string // below the base, without a leading slash
+	params     url.Values
+	username   string
+	password   string // sent as a bearer token if there is no username
+	accept     string
+	name       string // of the API, for errors
+	credential string // kind of credential to suggest when denied
+}
+
+// APIHost returns what identifies the URL of an API, such as its stored
+// credentials: the host of an http(s) URL or namespace/name of a service
+func APIHost(rawURL string) string {
+	if service, ok := strings.CutPrefix(rawURL, ServicePrefix); ok {
+		service, _, _ = strings.Cut(service, ":")
+		return service
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// getAPI requests an API directly or through the API server's service
+// proxy. Error answers are returned with their body, which often explains
+// them.
+func (c *Client) getAPI(ctx context.Context, r apiRequest) ([]byte, error) {
+	if service, ok := strings.CutPrefix(r.base, ServicePrefix); ok {
+		return c.proxyAPI(ctx, service, r)
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(r.base, "/") + "/" + r.path)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid %s URL %q, use http(s)://... or %s<namespace>/<name>:<port>", r.name, r.base, ServicePrefix)
+	}
+	u.RawQuery = r.params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.accept != "" {
+		req.Header.Set("Accept", r.accept)
+	}
+	switch {
+	case r.username != "":
+		req.SetBasicAuth(r.username, r.password)
+	case r.password != "":
+		req.Header.Set("Authorization", "Bearer "+r.password)
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", r.name, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAPIResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %s answer: %w", r.name, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("%s denied the request; store a login with 'khelper credentials set %s/%s'", r.name, r.credential, u.Host)
+	}
+	return body, fmt.Errorf("%s answered %s", r.name, resp.Status)
+}
+
+// proxyAPI requests an API of a service through the API server
+func (c *Client) proxyAPI(ctx context.Context, service string, r apiRequest) ([]byte, error) {
+	ref, port, _ := strings.Cut(service, ":")
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid %s service %q, use %s<namespace>/<name>:<port>", r.name, service, ServicePrefix)
+	}
+	params := make(map[string]string, len(r.params))
+	for key := range r.params {
+		params[key] = r.params.Get(key)
+	}
+	body, err := c.clientset.CoreV1().Services(namespace).ProxyGet("", name, port, r.path, params).DoRaw(ctx)
+	if err != nil {
+		return body, fmt.Errorf("failed to reach %s through service %s/%s: %w", r.name, namespace, name, err)
+	}
+	return body, nil
+}
@@ -0,0 +1,85 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authenticationv1beta1 "k8s.io/api/authentication/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Identity is who the client talks to the API server as, and which server
+type Identity struct {
+	Context   string // kubeconfig context, empty in-cluster
+	Cluster   string // kubeconfig cluster of the context
+	Server    string // API server URL
+	AuthInfo  string // kubeconfig user of the context
+	Namespace string // default namespace of the context or the pod
+	// User, UID and Groups are what the API server authenticates the client
+	// as; User is empty when the server could not tell
+	User   string
+	UID    string
+	Groups []string
+}
+
+// WhoAmI returns the kubeconfig context and API server of the client and asks
+// the server who it authenticates the client as with a SelfSubjectReview.
+// The identity is returned even when the review fails, with the error.
+func (c *Client) WhoAmI(ctx context.Context) (*Identity, error) {
+	id := c.localIdentity()
+	user, err := c.selfSubjectReview(ctx)
+	if err != nil {
+		return id, err
+	}
+	id.User, id.UID, id.Groups = user.Username, user.UID, user.Groups
+	return id, nil
+}
+
+// localIdentity reads the current context from the kubeconfig
+func (c *Client) localIdentity() *Identity {
+	id := &Identity{}
+	if c.config != nil {
+		id.Server = c.config.Host
+	}
+	if c.kubeconfig == InClusterKubeConfig {
+		id.Namespace = InClusterNamespace()
+		return id
+	}
+	kubeconfig, err := clientcmd.LoadFromFile(c.kubeconfig)
+	if err != nil {
+		return id
+	}
+	id.Context = c.context
+	if id.Context == "" {
+		id.Context = kubeconfig.CurrentContext
+	}
+	if kubeContext, ok := kubeconfig.Contexts[id.Context]; ok {
+		id.Cluster = kubeContext.Cluster
+		id.AuthInfo = kubeContext.AuthInfo
+		id.Namespace = kubeContext.Namespace
+	}
+	return id
+}
+
+// selfSubjectReview asks the API server who it authenticates the client as,
+// falling back to the beta API of Kubernetes 1.27
+func (c *Client) selfSubjectReview(ctx context.Context) (authenticationv1.UserInfo, error) {
+	review, err := c.clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil {
+		return review.Status.UserInfo, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return authenticationv1.UserInfo{}, fmt.Errorf("failed to review the current user: %w", err)
+	}
+	beta, err := c.clientset.AuthenticationV1beta1().SelfSubjectReviews().Create(ctx, &authenticationv1beta1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if apierrors.IsNotFound(err) {
+		return authenticationv1.UserInfo{}, fmt.Errorf("the API server cannot tell who you are, SelfSubjectReview needs Kubernetes 1.27 or later")
+	}
+	if err != nil {
+		return authenticationv1.UserInfo{}, fmt.Errorf("failed to review the current user: %w", err)
+	}
+	return beta.Status.UserInfo, nil
+}
@@ -0,0 +1,358 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxImageFileSize bounds the files read from an image snapshot
+const maxImageFileSize = 64 << 20
+
+// ErrNoExec means the container cannot run the commands the file browser
+// execs, as in distroless images
+var ErrNoExec = errors.New("the container has no ls or cat to run")
+
+// FileEntry is a file or directory in a container or image
+type FileEntry struct {
+	Name string
+	Dir  bool
+	Size int64  // -1 if unknown
+	Link string // the target of a symlink
+}
+
+// ListFiles lists a directory in a container with ls, directories and
+// symlinks to them with a trailing slash. ErrNoExec is returned if the
+// container has no ls.
+func (c *Client) ListFiles(ctx context.Context, namespace, podName, containerName, dir string) ([]FileEntry, error) {
+	var stdout, stderr bytes.Buffer
+	err := c.Exec(ctx, ExecOptions{
+		Namespace:     namespace,
+		PodName:       podName,
+		ContainerName: containerName,
+		Command:       []string{"ls", "-1ApL", dir},
+		Stdout:        &stdout,
+		Stderr:        &stderr,
+	})
+	// Broken symlinks make ls fail but still list everything else
+	if err != nil && stdout.Len() == 0 {
+		if execUnavailable(err) {
+			return nil, fmt.Errorf("%w: %v", ErrNoExec, err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	var entries []FileEntry
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		name, dir := strings.CutSuffix(line, "/")
+		entries = append(entries, FileEntry{Name: name, Dir: dir, Size: -1})
+	}
+	sortFileEntries(entries)
+	return entries, nil
+}
+
+// ReadContainerFile reads a file in a container with cat. ErrNoExec is
+// returned if the container has no cat.
+func (c *Client) ReadContainerFile(ctx context.Context, namespace, podName, containerName, file string) ([]byte, error) {
+	data, err := c.readFile(ctx, namespace, podName, containerName, file)
+	if err != nil && execUnavailable(err) {
+		return nil, fmt.Errorf("%w: %v", ErrNoExec, err)
+	}
+	return data, err
+}
+
+// execUnavailable reports whether an exec failed because the command does
+// not exist in the container, rather than failing itself
+func execUnavailable(err error) bool {
+	text := strings.ToLower(err.Error())
+	return strings.Contains(text, "executable file not found") ||
+		(strings.Contains(text, "oci runtime exec failed") && strings.Contains(text, "no such file or directory"))
+}
+
+// sortFileEntries sorts directories first, then by name
+func sortFileEntries(entries []FileEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Dir != entries[j].Dir {
+			return entries[i].Dir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
+
+// imageEntry is a file of an image snapshot and the layer it comes from
+type imageEntry struct {
+	FileEntry
+	layer    int    // index into the snapshot's layers
+	hardlink string // the path a hard link shares its data with
+}
+
+// ImageSnapshot is the filesystem of a container's image as pulled from its
+// registry: all layers applied, with their whiteouts. It is what the image
+// ships, not what the running container has changed since.
+type ImageSnapshot struct {
+	Image   string // the image pulled, by digest
+	entries map[string]*imageEntry
+	layers  []string // cached layer files, lowest first
+}
+
+// SnapshotImage pulls the image of a pod's container from its registry, by
+// the digest the pod runs if known, into cacheDir and indexes its files.
+// Files are only extracted from the layers when read.
+func (c *Client) SnapshotImage(ctx context.Context, namespace, podName, containerName, cacheDir string, auth RegistryAuth) (*ImageSnapshot, error) {
+	pod, err := c.GetPod(ctx, namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	container := findContainer(pod, containerName)
+	if container == nil {
+		return nil, fmt.Errorf("container %s not found in pod %s", containerName, podName)
+	}
+	ref := parseImageReference(container.Image)
+	for _, status := range pod.Status.ContainerStatuses {
+		// e.g. docker.io/library/nginx@sha256:..., the digest that runs
+		if _, digest, ok := strings.Cut(status.ImageID, "@"); status.Name == containerName && ok {
+			ref.reference = digest
+		}
+	}
+
+	arch := "amd64"
+	if pod.Spec.NodeName != "" {
+		if node, err := c.clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{}); err == nil {
+			arch = node.Status.NodeInfo.Architecture
+		}
+	}
+
+	registry := newRegistryClient(ref, auth)
+	manifest, err := registry.manifest(ctx, ref.reference, "linux", arch)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &ImageSnapshot{Image: ref.String(), entries: map[string]*imageEntry{"/": {FileEntry: FileEntry{Name: "/", Dir: true}}}}
+	for _, layer := range manifest.Layers {
+		file, err := registry.blob(ctx, cacheDir, layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.layers = append(snapshot.layers, file)
+		if err := snapshot.applyLayer(len(snapshot.layers) - 1); err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", layer.Digest, err)
+		}
+	}
+	return snapshot, nil
+}
+
+// openLayer returns the tar stream of a cached layer, gzipped or not
+func (s *ImageSnapshot) openLayer(layer int) (*tar.Reader, io.Closer, error) {
+	f, err := os.Open(s.layers[layer])
+	if err != nil {
+		return nil, nil, err
+	}
+	r := bufio.NewReader(f)
+	magic, _ := r.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return tar.NewReader(gz), f, nil
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		f.Close()
+		return nil, nil, errors.New("zstd compressed layers are not supported")
+	}
+	return tar.NewReader(r), f, nil
+}
+
+// applyLayer adds a layer's files over the lower layers, removing the
+// files its whiteouts hide
+func (s *ImageSnapshot) applyLayer(layer int) error {
+	tr, closer, err := s.openLayer(layer)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Join("/", header.Name)
+		dir, base := path.Split(name)
+		switch {
+		case base == ".wh..wh..opq":
+			// The directory hides everything the lower layers put in it
+			s.remove(path.Clean(dir), func(e *imageEntry) bool { return e.layer < layer }, false)
+			continue
+		case strings.HasPrefix(base, ".wh."):
+			s.remove(path.Join(dir, strings.TrimPrefix(base, ".wh.")), nil, true)
+			continue
+		}
+
+		entry := &imageEntry{FileEntry: FileEntry{Name: name, Size: header.Size}, layer: layer}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.Dir, entry.Size = true, -1
+		case tar.TypeSymlink:
+			entry.Link, entry.Size = header.Linkname, -1
+		case tar.TypeLink:
+			entry.hardlink = path.Join("/", header.Linkname)
+			if target, ok := s.entries[entry.hardlink]; ok {
+				entry.Size = target.Size
+			}
+		case tar.TypeReg:
+		default:
+			// Devices and pipes have no content to show
+			entry.Size = 0
+		}
+		s.entries[name] = entry
+		s.addParents(name, layer)
+	}
+}
+
+// remove deletes the entries below a path the filter matches, all for a
+// nil filter, and with self the path's own entry
+func (s *ImageSnapshot) remove(p string, filter func(*imageEntry) bool, self bool) {
+	if self {
+		delete(s.entries, p)
+	}
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	for name, entry := range s.entries {
+		if strings.HasPrefix(name, prefix) && (filter == nil || filter(entry)) {
+			delete(s.entries, name)
+		}
+	}
+}
+
+// addParents adds the directories a layer leaves implicit
+func (s *ImageSnapshot) addParents(name string, layer int) {
+	for dir := path.Dir(name); dir != "/"; dir = path.Dir(dir) {
+		if _, ok := s.entries[dir]; ok {
+			return
+		}
+		s.entries[dir] = &imageEntry{FileEntry: FileEntry{Name: dir, Dir: true, Size: -1}, layer: layer}
+	}
+}
+
+// resolve follows the symlinks of a path inside the snapshot, returning
+// the entry it ends at and its path
+func (s *ImageSnapshot) resolve(p string) (*imageEntry, string, error) {
+	pending := strings.Split(path.Join("/", p), "/")
+	resolved, entry := "/", s.entries["/"]
+	for hops := 0; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			entry = s.entries[resolved]
+			continue
+		}
+		next := path.Join(resolved, part)
+		e, ok := s.entries[next]
+		if !ok {
+			return nil, "", fmt.Errorf("%s: no such file or directory in the image", p)
+		}
+		if e.Link == "" {
+			resolved, entry = next, e
+			continue
+		}
+		if hops++; hops > 40 {
+			return nil, "", fmt.Errorf("%s: too many levels of symbolic links in the image", p)
+		}
+		// Relative targets start from the link's directory, where we are
+		if path.IsAbs(e.Link) {
+			resolved, entry = "/", s.entries["/"]
+		}
+		pending = append(strings.Split(e.Link, "/"), pending...)
+	}
+	return entry, resolved, nil
+}
+
+// List lists a directory of the snapshot, directories and symlinks to them
+// flagged as directories
+func (s *ImageSnapshot) List(dir string) ([]FileEntry, error) {
+	entry, resolved, err := s.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.Dir {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	prefix := strings.TrimSuffix(resolved, "/") + "/"
+	var entries []FileEntry
+	for name, e := range s.entries {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || rest == "" || strings.Contains(rest, "/") {
+			continue
+		}
+		file := e.FileEntry
+		file.Name = rest
+		if file.Link != "" {
+			if target, _, err := s.resolve(name); err == nil && target.Dir {
+				file.Dir = true
+			}
+		}
+		entries = append(entries, file)
+	}
+	sortFileEntries(entries)
+	return entries, nil
+}
+
+// ReadFile extracts a file of the snapshot from the layer that put it there
+func (s *ImageSnapshot) ReadFile(file string) ([]byte, error) {
+	entry, resolved, err := s.resolve(file)
+	if err != nil {
+		return nil, err
+	}
+	if entry.hardlink != "" {
+		if entry, resolved, err = s.resolve(entry.hardlink); err != nil {
+			return nil, err
+		}
+	}
+	if entry.Dir {
+		return nil, fmt.Errorf("%s is a directory", file)
+	}
+	if entry.Size > maxImageFileSize {
+		return nil, fmt.Errorf("%s has %d bytes, more than the %d MB read from images", file, entry.Size, maxImageFileSize>>20)
+	}
+
+	tr, closer, err := s.openLayer(entry.layer)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s: not found in its layer", file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path.Join("/", header.Name) == resolved {
+			return io.ReadAll(io.LimitReader(tr, maxImageFileSize))
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DescribeIngresses returns a report per ingress of the namespace: its class,
+// load balancer address, TLS secrets with the expiry of their certificates
+// and each rule's backend, checked for an existing service and port with
+// ready endpoints. Ingresses routing to the deployment's services come first.
+func (c *Client) DescribeIngresses(ctx context.Context, namespace, deploymentName string) (string, error) {
+	ingresses, err := c.GetIngresses(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+	if len(ingresses) == 0 {
+		return fmt.Sprintf("No ingresses in %s\n", namespace), nil
+	}
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list services: %w", err)
+	}
+	ownServices := c.deploymentServices(ctx, namespace, deploymentName, services.Items)
+
+	check := newBackendCheck(c, namespace, services.Items)
+	related := func(ing networkingv1.Ingress) bool {
+		for _, backend := range ingressBackends(ing) {
+			if ownServices[backend.Name] {
+				return true
+			}
+		}
+		return false
+	}
+	sort.SliceStable(ingresses, func(i, j int) bool {
+		return related(ingresses[i]) && !related(ingresses[j])
+	})
+
+	_, workloadName := parseWorkloadRef(deploymentName)
+	w := newDescribeWriter()
+	now := time.Now()
+	for i, ing := range ingresses {
+		if i > 0 {
+			w.line(0, "")
+		}
+		name := ing.Name
+		if related(ing) {
+			name += "  (routes to " + workloadName + ")"
+		}
+		w.line(0, "Name:\t%s", name)
+		w.line(0, "Class:\t%s", ingressClass(ing))
+		w.line(0, "Address:\t%s", ingressAddress(ing))
+		c.describeIngressTLS(ctx, w, ing, now)
+
+		w.line(0, "Rules:")
+		w.line(1, "Host\tPath\tBackend\tStatus")
+		w.line(1, "----\t----\t-------\t------")
+		for _, rule := range ing.Spec.Rules {
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			if rule.HTTP == nil {
+				w.line(1, "%s\t\t<none>\t", host)
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				p := path.Path
+				if p == "" {
+					p = "/"
+				}
+				w.line(1, "%s\t%s\t%s\t%s", host, p, describeBackend(path.Backend), check.status(ctx, path.Backend))
+			}
+		}
+		if backend := ing.Spec.DefaultBackend; backend != nil {
+			w.line(0, "Default Backend:\t%s\t%s", describeBackend(*backend), check.status(ctx, *backend))
+		}
+	}
+	return w.String(), nil
+}
+
+// deploymentServices returns the services selecting the pods of a
+// deployment, none if it cannot be read
+func (c *Client) deploymentServices(ctx context.Context, namespace, deploymentName string, services []corev1.Service) map[string]bool {
+	own := make(map[string]bool)
+	if deploymentName == "" {
+		return own
+	}
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return own
+	}
+	for _, name := range selectingServices(services, deployment.Spec.Template.Labels) {
+		own[name] = true
+	}
+	return own
+}
+
+// selectingServices returns the services whose selector matches pod labels
+func selectingServices(services []corev1.Service, podLabels map[string]string) []string {
+	var names []string
+	for _, svc := range services {
+		if len(svc.Spec.Selector) > 0 && labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(podLabels)) {
+			names = append(names, svc.Name)
+		}
+	}
+	return names
+}
+
+// ingressBackends returns the service backends of an ingress
+func ingressBackends(ing networkingv1.Ingress) []networkingv1.IngressServiceBackend {
+	var backends []networkingv1.IngressServiceBackend
+	if b := ing.Spec.DefaultBackend; b != nil && b.Service != nil {
+		backends = append(backends, *b.Service)
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service != nil {
+				backends = append(backends, *path.Backend.Service)
+			}
+		}
+	}
+	return backends
+}
+
+// ingressClass returns the class of an ingress, from the field or the older
+// annotation
+func ingressClass(ing networkingv1.Ingress) string {
+	if ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName
+	}
+	if class := ing.Annotations["kubernetes.io/ingress.class"]; class != "" {
+		return class + " (annotation)"
+	}
+	return "<none> (the cluster's default class, if any)"
+}
+
+// ingressAddress returns the load balancer addresses of an ingress
+func ingressAddress(ing networkingv1.Ingress) string {
+	var addresses []string
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			addresses = append(addresses, lb.IP)
+		}
+		if lb.Hostname != "" {
+			addresses = append(addresses, lb.Hostname)
+		}
+	}
+	if len(addresses) == 0 {
+		return "<pending>"
+	}
+	return strings.Join(addresses, ", ")
+}
+
+// describeIngressTLS writes an ingress's TLS secrets with the hosts they
+// cover and when their certificates expire
+func (c *Client) describeIngressTLS(ctx context.Context, w *describeWriter, ing networkingv1.Ingress, now time.Time) {
+	if len(ing.Spec.TLS) == 0 {
+		w.line(0, "TLS:\t<none>")
+		return
+	}
+	w.line(0, "TLS:")
+	w.line(1, "Secret\tHosts\tExpires")
+	w.line(1, "------\t-----\t-------")
+	for _, tls := range ing.Spec.TLS {
+		hosts := joinOrNone(tls.Hosts)
+		if tls.SecretName == "" {
+			w.line(1, "<none>\t%s\tthe controller's default certificate", hosts)
+			continue
+		}
+		w.line(1, "%s\t%s\t%s", tls.SecretName, hosts, c.secretExpiry(ctx, ing.Namespace, tls.SecretName, now))
+	}
+}
+
+// secretExpiry describes when the certificate of a TLS secret expires, or
+// why that is unknown
+func (c *Client) secretExpiry(ctx context.Context, namespace, name string, now time.Time) string {
+	secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return "✗ secret not found"
+	case apierrors.IsForbidden(err):
+		return "? not allowed to read the secret"
+	case err != nil:
+		return "? " + err.Error()
+	}
+	cert, err := secretCertificate(secret)
+	if err != nil {
+		return "✗ " + err.Error()
+	}
+	return cert.Expiry(now)
+}
+
+// describeBackend renders an ingress backend, e.g. "web:80"
+func describeBackend(backend networkingv1.IngressBackend) string {
+	if backend.Resource != nil {
+		return fmt.Sprintf("%s/%s", backend.Resource.Kind, backend.Resource.Name)
+	}
+	if backend.Service == nil {
+		return "<none>"
+	}
+	return backend.Service.Name + ":" + describeBackendPort(backend.Service.Port)
+}
+
+// describeBackendPort renders the port of a service backend by name or number
+func describeBackendPort(port networkingv1.ServiceBackendPort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return fmt.Sprint(port.Number)
+}
+
+// backendCheck checks ingress backends against the services of the
+// namespace, counting ready endpoints once per service
+type backendCheck struct {
+	client    *Client
+	namespace string
+	services  map[string]*corev1.Service
+	ready     map[string]int // service -> ready endpoints, -1 if unknown
+}
+
+// newBackendCheck returns a backendCheck against the given services of the
+// namespace
+func newBackendCheck(c *Client, namespace string, services []corev1.Service) *backendCheck {
+	check := &backendCheck{client: c, namespace: namespace, services: make(map[string]*corev1.Service), ready: make(map[string]int)}
+	for i := range services {
+		check.services[services[i].Name] = &services[i]
+	}
+	return check
+}
+
+// status tells whether a backend's service and port exist and how many of
+// its endpoints are ready
+func (b *backendCheck) status(ctx context.Context, backend networkingv1.IngressBackend) string {
+	if backend.Service == nil {
+		return ""
+	}
+	return b.serviceStatus(ctx, backend.Service.Name, backend.Service.Port)
+}
+
+// serviceStatus tells whether a service and its port exist and how many of
+// its endpoints are ready
+func (b *backendCheck) serviceStatus(ctx context.Context, name string, port networkingv1.ServiceBackendPort) string {
+	svc, ok := b.services[name]
+	if !ok {
+		return "✗ service " + name + " not found"
+	}
+	if !servicePortExists(svc, port) {
+		return fmt.Sprintf("✗ service %s has no port %s", name, describeBackendPort(port))
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return "ok (external name " + svc.Spec.ExternalName + ")"
+	}
+
+	ready, ok := b.ready[name]
+	if !ok {
+		ready = b.client.readyEndpoints(ctx, b.namespace, name)
+		b.ready[name] = ready
+	}
+	switch {
+	case ready < 0:
+		return "ok"
+	case ready == 0:
+		return "⚠ no ready endpoints"
+	}
+	return fmt.Sprintf("ok, %d ready", ready)
+}
+
+// servicePortExists reports whether a service has the port an ingress
+// backend names or numbers
+func servicePortExists(svc *corev1.Service, port networkingv1.ServiceBackendPort) bool {
+	for _, p := range svc.Spec.Ports {
+		if (port.Name != "" && p.Name == port.Name) || (port.Name == "" && p.Port == port.Number) {
+			return true
+		}
+	}
+	return false
+}
+
+// readyEndpoints counts the ready endpoints of a service across its
+// EndpointSlices, -1 if they cannot be read
+func (c *Client) readyEndpoints(ctx context.Context, namespace, service string) int {
+	slices, err := c.clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service,
+	})
+	if err != nil {
+		return -1
+	}
+	ready := 0
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				ready++
+			}
+		}
+	}
+	return ready
+}
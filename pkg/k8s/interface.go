@@ -0,0 +1,203 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// Connection tells which cluster a client talks to and reports its retries
+type Connection interface {
+	GetKubeConfigPath() string
+	KubeContext() string
+	RetryEvents() <-chan RetryEvent
+}
+
+// ClusterInfo tells who the cluster sees and what it serves
+type ClusterInfo interface {
+	WhoAmI(ctx context.Context) (*Identity, error)
+	Capabilities(ctx context.Context) (*Capabilities, error)
+	ListNodeNames(ctx context.Context) ([]string, error)
+}
+
+// Browser lists the namespaces and workloads the selectors offer
+type Browser interface {
+	ListNamespaces(ctx context.Context) ([]string, error)
+	ListDeployments(ctx context.Context, namespace string) ([]string, error)
+	ListAllDeployments(ctx context.Context) ([]string, error)
+	ListWorkloads(ctx context.Context, namespace string) ([]string, error)
+	ListDeploymentHealth(ctx context.Context, namespace string) (map[string]DeploymentHealth, error)
+	GetNamespaceOverview(ctx context.Context, namespace string) (*NamespaceOverview, error)
+	WorkloadExists(ctx context.Context, namespace, ref string) (bool, error)
+	WatchDeployments(ctx context.Context, namespace string, changed func()) error
+}
+
+// Resources reads and deletes any resource the cluster serves
+type Resources interface {
+	ListAPIResources(ctx context.Context) ([]APIResource, error)
+	ListResources(ctx context.Context, namespace string, res APIResource) ([]string, error)
+	GetResourceManifest(ctx context.Context, namespace string, res APIResource, name string, keepManagedFields bool) (string, error)
+	DeleteResource(ctx context.Context, namespace string, res APIResource, name string) error
+	GetManifest(ctx context.Context, namespace, kind, name string, keepManagedFields bool) (string, error)
+}
+
+// Pods lists, watches and cleans up the pods of a workload
+type Pods interface {
+	ListPods(ctx context.Context, namespace, deploymentName string) ([]corev1.Pod, error)
+	ListPodEntries(ctx context.Context, namespace, deploymentName string) ([]PodEntry, error)
+	GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error)
+	ListContainers(ctx context.Context, namespace, podName string) ([]string, error)
+	ListWorkloadContainers(ctx context.Context, namespace, deploymentName string) ([]string, error)
+	ListPodEntriesWithContainer(ctx context.Context, namespace, deploymentName, containerName string) ([]PodEntry, error)
+	WatchPods(ctx context.Context, namespace, deploymentName string, changed func()) error
+	ListCleanupPods(ctx context.Context, namespace string, restarts int32) ([]CleanupPod, error)
+	DeletePods(ctx context.Context, namespace string, names []string) ([]string, error)
+}
+
+// Describer explains a workload and its surroundings as text
+type Describer interface {
+	DescribeDeployment(ctx context.Context, namespace, name string) (string, error)
+	DescribePod(ctx context.Context, namespace, name string) (string, error)
+	ComparePods(ctx context.Context, namespace, podA, podB string) (string, error)
+	DescribeRBAC(ctx context.Context, namespace, deploymentName string) (string, error)
+	GetIngresses(ctx context.Context, namespace string) ([]networkingv1.Ingress, error)
+	DescribeIngresses(ctx context.Context, namespace, deploymentName string) (string, error)
+	DescribeGatewayRoutes(ctx context.Context, namespace, deploymentName string) (string, error)
+	DescribeTopology(ctx context.Context, namespace, deploymentName string) (string, error)
+	DescribeDependencies(ctx context.Context, namespace, deploymentName string) (string, error)
+	ListCertificates(ctx context.Context, namespace string) ([]SecretCertificate, error)
+	CheckDrainImpact(ctx context.Context, namespace, ref, node string) (*DrainImpact, error)
+	FindStuckResources(ctx context.Context, namespace string) (*StuckResources, error)
+	GetRolloutHistory(ctx context.Context, namespace, name string) (*RolloutHistory, error)
+	GetResourcePressure(ctx context.Context, namespace, deploymentName string) (*ResourcePressure, error)
+}
+
+// Workloads reads and changes deployments
+type Workloads interface {
+	GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error)
+	GetReplicaSets(ctx context.Context, namespace, deploymentName string) ([]appsv1.ReplicaSet, error)
+	GetEnvVars(ctx context.Context, namespace, deploymentName, containerName string) ([]corev1.EnvVar, error)
+	LintWorkload(ctx context.Context, namespace, ref string, edit func(*corev1.PodTemplateSpec) error) ([]LintFinding, error)
+	GetScaleInfo(ctx context.Context, namespace, ref string) (*ScaleInfo, error)
+	ScaleDeployment(ctx context.Context, namespace, name string, replicas int32) error
+	UpdateImage(ctx context.Context, namespace, deploymentName, containerName, image string) error
+	UpdateImages(ctx context.Context, namespace, deploymentName string, images map[string]string) error
+	SetEnvVar(ctx context.Context, namespace, deploymentName, containerName, key, value string) error
+	UnsetEnvVar(ctx context.Context, namespace, deploymentName, containerName, key string) error
+	RollbackDeployment(ctx context.Context, namespace, name string, revision int64) error
+	PatchDeployment(ctx context.Context, namespace, name string, patch Patch, dryRun bool) (*appsv1.Deployment, *appsv1.Deployment, error)
+	DefaultDeployment(ctx context.Context, namespace string, base *appsv1.Deployment) *appsv1.Deployment
+}
+
+// Renamer moves a deployment to a new name step by step
+type Renamer interface {
+	PlanRename(ctx context.Context, namespace, from, to string) (*Rename, error)
+	RunRenameStep(ctx context.Context, r *Rename) error
+	RollbackRename(ctx context.Context, r *Rename) ([]string, error)
+}
+
+// Configs reads, edits and rolls out the ConfigMaps and Secrets of a deployment
+type Configs interface {
+	GetMountedFiles(ctx context.Context, namespace, podName, containerName string) ([]MountedFile, error)
+	RollConfig(ctx context.Context, namespace, deploymentName string) (*ConfigRollout, error)
+	GetPodConfigs(ctx context.Context, namespace, deploymentName, checksum string) ([]PodConfig, error)
+	ListConfigSources(ctx context.Context, namespace, deploymentName string) ([]string, error)
+	GetConfigData(ctx context.Context, namespace, kind, name string) (map[string]string, error)
+	UpdateConfigData(ctx context.Context, namespace, kind, name string, data map[string]string) error
+}
+
+// Access mints service account tokens and manages image pull secrets
+type Access interface {
+	DeploymentServiceAccount(ctx context.Context, namespace, deploymentName string) (string, error)
+	CreateServiceAccountToken(ctx context.Context, namespace, serviceAccount string, expiration time.Duration) (*ServiceAccountToken, error)
+	CheckPullSecrets(ctx context.Context, namespace, ref string) (*PullSecretReport, error)
+	CreatePullSecret(ctx context.Context, namespace string, s PullSecret) error
+}
+
+// Debugger runs extra containers, pods and jobs next to a deployment
+type Debugger interface {
+	AddDebugSidecar(ctx context.Context, namespace, deploymentName, image string) error
+	RemoveDebugSidecar(ctx context.Context, namespace, deploymentName string) error
+	CreateDebugCopy(ctx context.Context, namespace, podName, image string) (string, error)
+	DeleteDebugCopy(ctx context.Context, namespace, name string) error
+	CreateExperimentPod(ctx context.Context, namespace, deploymentName string, exp Experiment) (string, error)
+	DeleteExperimentPod(ctx context.Context, namespace, name string) error
+	CreateTemplateJob(ctx context.Context, namespace, deploymentName, containerName, command string) (string, string, error)
+	TemplateJobStatus(ctx context.Context, namespace, jobName, podName string) (string, error)
+	DeleteTemplateJob(ctx context.Context, namespace, name string) error
+}
+
+// Execer runs commands, shells and checks in containers
+type Execer interface {
+	Exec(ctx context.Context, opts ExecOptions) error
+	Shell(ctx context.Context, opts ShellOptions) error
+	CheckShellAvailable(ctx context.Context, namespace, podName, containerName string) (string, error)
+	GetAttachMode(ctx context.Context, namespace, podName, containerName string) (AttachMode, error)
+	Attach(ctx context.Context, opts AttachOptions) error
+	RunNetChecks(ctx context.Context, namespace, podName, containerName string, checks []NetCheck) ([]NetResult, error)
+}
+
+// Logs reads container logs and watches events
+type Logs interface {
+	StreamLogs(ctx context.Context, opts LogOptions, output io.Writer) error
+	FollowLogs(ctx context.Context, opts LogOptions, output io.Writer) error
+	FollowPodLogs(ctx context.Context, opts MultiLogOptions, handle func(LogLine)) error
+	GetLogs(ctx context.Context, opts LogOptions) (string, error)
+	GetLogsPage(ctx context.Context, opts LogOptions, before time.Time, newer int64) (*LogPage, error)
+	WatchEvents(ctx context.Context, namespace string, since time.Time, handle func(*corev1.Event)) error
+}
+
+// Telemetry queries the metrics, tracing and log backends
+type Telemetry interface {
+	QueryPrometheus(ctx context.Context, q MetricsQuery) ([]MetricSeries, error)
+	DescribeTrace(ctx context.Context, q TraceQuery) (string, error)
+	QueryLogRange(ctx context.Context, q LogRangeQuery) (*LogRange, error)
+}
+
+// Forwarder forwards ports between the cluster and this machine
+type Forwarder interface {
+	PortForward(ctx context.Context, opts PortForwardOptions) error
+	ResolvePortForward(ctx context.Context, namespace, target string, ports []PortPair) (string, []PortPair, error)
+	ReverseTunnel(ctx context.Context, opts ReverseTunnelOptions) error
+	RestoreService(ctx context.Context, namespace, name string) error
+}
+
+// Files moves files in and out of containers
+type Files interface {
+	ListDirectories(ctx context.Context, namespace, podName, container, path string) ([]string, error)
+	ClearDirectory(ctx context.Context, namespace, podName, container, path string) error
+	UploadDirectory(ctx context.Context, opts UploadOptions) (*UploadResult, error)
+	UploadFile(ctx context.Context, opts UploadOptions) error
+	ListFiles(ctx context.Context, namespace, podName, containerName, dir string) ([]FileEntry, error)
+	ReadContainerFile(ctx context.Context, namespace, podName, containerName, file string) ([]byte, error)
+	SnapshotImage(ctx context.Context, namespace, podName, containerName, cacheDir string, auth RegistryAuth) (*ImageSnapshot, error)
+}
+
+// ClientInterface is every cluster operation used by the khelper UI. *Client
+// implements it; the fake package provides one backed by an in-memory
+// clientset for tests and headless use. Code that needs only part of it
+// takes the feature interfaces above.
+type ClientInterface interface {
+	Connection
+	ClusterInfo
+	Browser
+	Resources
+	Pods
+	Describer
+	Workloads
+	Renamer
+	Configs
+	Access
+	Debugger
+	Execer
+	Logs
+	Telemetry
+	Forwarder
+	Files
+}
+
+var _ ClientInterface = (*Client)(nil)
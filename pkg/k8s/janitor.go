@@ -0,0 +1,207 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StuckPendingAge is how long a pod may be pending before it counts as stuck
+const StuckPendingAge = 10 * time.Minute
+
+// Kinds of stuck resources, in the order they are listed
+const (
+	StuckPod        = "Pod"
+	StuckPVC        = "PersistentVolumeClaim"
+	StuckJob        = "Job"
+	StuckReplicaSet = "ReplicaSet"
+)
+
+// StuckKinds are the kinds FindStuckResources looks at
+var StuckKinds = []string{StuckPod, StuckPVC, StuckJob, StuckReplicaSet}
+
+// StuckResource is a resource stuck in a state it will not leave by itself
+type StuckResource struct {
+	Kind   string
+	Name   string
+	Reason string
+	Since  time.Time // when it got stuck, as far as known
+}
+
+// StuckResources are the stuck resources of a namespace, by kind and oldest
+// first, and the kinds that could not be listed
+type StuckResources struct {
+	Items   []StuckResource
+	Skipped map[string]error // kind -> why it was not listed, e.g. forbidden
+}
+
+// FindStuckResources finds pods pending for longer than StuckPendingAge,
+// unbound PVCs, failed Jobs and ReplicaSets scaled to zero that their
+// deployment's revision history no longer keeps or that lost their owner.
+// Kinds that may not be listed are skipped.
+func (c *Client) FindStuckResources(ctx context.Context, namespace string) (*StuckResources, error) {
+	s := &StuckResources{Skipped: map[string]error{}}
+	now := time.Now()
+	skip := func(kind string, err error) error {
+		if apierrors.IsForbidden(err) {
+			s.Skipped[kind] = err
+			return nil
+		}
+		return fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{FieldSelector: "status.phase=Pending"})
+	if err != nil {
+		if err := skip(StuckPod, err); err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Status.Phase != corev1.PodPending || pod.DeletionTimestamp != nil || now.Sub(pod.CreationTimestamp.Time) < StuckPendingAge {
+				continue
+			}
+			s.add(StuckPod, pod.Name, pendingReason(pod), pod.CreationTimestamp.Time)
+		}
+	}
+
+	claims, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if err := skip(StuckPVC, err); err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range claims.Items {
+			claim := &claims.Items[i]
+			if claim.Status.Phase == corev1.ClaimBound {
+				continue
+			}
+			s.add(StuckPVC, claim.Name, claimProblem(claim), claim.CreationTimestamp.Time)
+		}
+	}
+
+	jobs, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if err := skip(StuckJob, err); err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range jobs.Items {
+			job := &jobs.Items[i]
+			reason := jobFailure(job)
+			if reason == "" {
+				continue
+			}
+			since := job.CreationTimestamp.Time
+			for _, cond := range job.Status.Conditions {
+				if cond.Type == batchv1.JobFailed && !cond.LastTransitionTime.IsZero() {
+					since = cond.LastTransitionTime.Time
+				}
+			}
+			s.add(StuckJob, job.Name, reason, since)
+		}
+	}
+
+	if err := c.findStaleReplicaSets(ctx, namespace, s); err != nil {
+		if err := skip(StuckReplicaSet, err); err != nil {
+			return nil, err
+		}
+	}
+
+	order := map[string]int{}
+	for i, kind := range StuckKinds {
+		order[kind] = i
+	}
+	sort.SliceStable(s.Items, func(i, j int) bool {
+		a, b := s.Items[i], s.Items[j]
+		if a.Kind != b.Kind {
+			return order[a.Kind] < order[b.Kind]
+		}
+		return a.Since.Before(b.Since)
+	})
+	return s, nil
+}
+
+func (s *StuckResources) add(kind, name, reason string, since time.Time) {
+	s.Items = append(s.Items, StuckResource{Kind: kind, Name: name, Reason: reason, Since: since})
+}
+
+// claimProblem explains why a claim is not bound
+func claimProblem(claim *corev1.PersistentVolumeClaim) string {
+	if claim.Status.Phase == corev1.ClaimLost {
+		return "Lost: its volume " + claim.Spec.VolumeName + " is gone"
+	}
+	class := "the default storage class"
+	if claim.Spec.StorageClassName != nil {
+		class = "storage class " + strconv.Quote(*claim.Spec.StorageClassName)
+	}
+	return "Pending: no volume bound from " + class
+}
+
+// findStaleReplicaSets adds the ReplicaSets scaled to zero that are not
+// garbage collected: those without an existing owner, and those beyond
+// their deployment's revisionHistoryLimit
+func (c *Client) findStaleReplicaSets(ctx context.Context, namespace string, s *StuckResources) error {
+	replicaSets, err := c.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	byUID := map[string]*appsv1.Deployment{}
+	for i := range deployments.Items {
+		byUID[string(deployments.Items[i].UID)] = &deployments.Items[i]
+	}
+
+	idle := map[string][]*appsv1.ReplicaSet{} // deployment UID -> its ReplicaSets scaled to zero
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if rs.DeletionTimestamp != nil || rs.Spec.Replicas == nil || *rs.Spec.Replicas != 0 || rs.Status.Replicas != 0 {
+			continue
+		}
+		owner := metav1.GetControllerOf(rs)
+		switch {
+		case owner == nil:
+			s.add(StuckReplicaSet, rs.Name, "0 replicas and no owner", rs.CreationTimestamp.Time)
+		case owner.Kind != "Deployment":
+			continue
+		case byUID[string(owner.UID)] == nil:
+			s.add(StuckReplicaSet, rs.Name, "0 replicas, its Deployment "+owner.Name+" is gone", rs.CreationTimestamp.Time)
+		default:
+			idle[string(owner.UID)] = append(idle[string(owner.UID)], rs)
+		}
+	}
+
+	for uid, sets := range idle {
+		deployment := byUID[uid]
+		limit := int32(10)
+		if deployment.Spec.RevisionHistoryLimit != nil {
+			limit = *deployment.Spec.RevisionHistoryLimit
+		}
+		if int32(len(sets)) <= limit {
+			continue
+		}
+		// The newest revisions are kept, the older ones should be gone
+		sort.Slice(sets, func(i, j int) bool { return replicaSetRevision(sets[i]) > replicaSetRevision(sets[j]) })
+		for _, rs := range sets[limit:] {
+			s.add(StuckReplicaSet, rs.Name, fmt.Sprintf("0 replicas, beyond the revisionHistoryLimit %d of %s", limit, deployment.Name), rs.CreationTimestamp.Time)
+		}
+	}
+	return nil
+}
+
+// replicaSetRevision is the deployment revision a ReplicaSet belongs to
+func replicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	return revision
+}
@@ -0,0 +1,272 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// Traces kustomize and Flux leave on the objects they build
+const (
+	// kustomizeOriginAnnotation is added by kustomize build with the
+	// originAnnotations build metadata: the file the object comes from
+	kustomizeOriginAnnotation = "config.kubernetes.io/origin"
+	// kustomizeManagedByLabel is added by kustomize build
+	// --enable-managedby-label, e.g. kustomize-v5.3.0
+	kustomizeManagedByLabel = "app.kubernetes.io/managed-by"
+	fluxNameLabel           = "kustomize.toolkit.fluxcd.io/name"
+	fluxNamespaceLabel      = "kustomize.toolkit.fluxcd.io/namespace"
+)
+
+// overlayDroppedAnnotations are kept out of overlay patches: set by the
+// server, kubectl, kustomize or khelper rather than written in a repository
+var overlayDroppedAnnotations = []string{
+	"deployment.kubernetes.io/revision",
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"kubectl.kubernetes.io/restartedAt",
+	"kubernetes.io/change-cause",
+	kustomizeOriginAnnotation,
+}
+
+// KustomizeOrigin is where a deployment built by kustomize comes from, as
+// far as its labels and annotations tell
+type KustomizeOrigin struct {
+	// Path is the file of the object, relative to the directory kustomize
+	// build ran in; empty without origin annotations
+	Path string
+	// Repo and Ref name the remote repository of a remote base
+	Repo string
+	Ref  string
+	// Flux is the namespace/name of the Flux Kustomization applying it
+	Flux string
+}
+
+// String describes the origin in one line, e.g. "apps/web/deployment.yaml,
+// applied by Flux Kustomization flux-system/apps"
+func (o KustomizeOrigin) String() string {
+	var parts []string
+	if o.Path != "" {
+		file := o.Path
+		if o.Repo != "" {
+			file = fmt.Sprintf("%s in %s", o.Path, o.Repo)
+			if o.Ref != "" {
+				file += "@" + o.Ref
+			}
+		}
+		parts = append(parts, file)
+	}
+	if o.Flux != "" {
+		parts = append(parts, "applied by Flux Kustomization "+o.Flux)
+	}
+	if len(parts) == 0 {
+		return "built by kustomize"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DetectKustomize reads the kustomize traces of an object: the origin
+// annotation, the Flux labels or the managed-by label of kustomize. It
+// returns nil for objects without any.
+func DetectKustomize(meta metav1.ObjectMeta) *KustomizeOrigin {
+	origin := &KustomizeOrigin{}
+	found := false
+	if value, ok := meta.Annotations[kustomizeOriginAnnotation]; ok {
+		var fields struct {
+			Path string `json:"path"`
+			Repo string `json:"repo"`
+			Ref  string `json:"ref"`
+		}
+		if err := yaml.Unmarshal([]byte(value), &fields); err == nil {
+			origin.Path, origin.Repo, origin.Ref = fields.Path, fields.Repo, fields.Ref
+		}
+		found = true
+	}
+	if name := meta.Labels[fluxNameLabel]; name != "" {
+		origin.Flux = name
+		if namespace := meta.Labels[fluxNamespaceLabel]; namespace != "" {
+			origin.Flux = namespace + "/" + name
+		}
+		found = true
+	}
+	if strings.HasPrefix(meta.Labels[kustomizeManagedByLabel], "kustomize") {
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return origin
+}
+
+// ReadBaseDeployment reads the deployment of a kustomize base file: the one
+// named name, or the only deployment of the file
+func ReadBaseDeployment(file, name string) (*appsv1.Deployment, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the base: %w", err)
+	}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	var found []*appsv1.Deployment
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var meta metav1.TypeMeta
+		if err := yaml.Unmarshal(doc, &meta); err != nil || meta.Kind != "Deployment" {
+			continue
+		}
+		deployment := &appsv1.Deployment{}
+		if err := yaml.UnmarshalStrict(doc, deployment); err != nil {
+			return nil, fmt.Errorf("invalid deployment in %s: %w", file, err)
+		}
+		if deployment.Name == name {
+			return deployment, nil
+		}
+		found = append(found, deployment)
+	}
+	if len(found) == 1 {
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("no deployment %s in %s", name, file)
+}
+
+// DefaultDeployment returns a base deployment with the defaults the API
+// server would set, such as imagePullPolicy, found with a dry-run create of
+// a copy under a generated name. Without them every default would show up
+// in an overlay. The base itself is returned if the dry run fails.
+func (c *Client) DefaultDeployment(ctx context.Context, namespace string, base *appsv1.Deployment) *appsv1.Deployment {
+	probe := base.DeepCopy()
+	probe.Name, probe.GenerateName, probe.Namespace = "", base.Name+"-", namespace
+	defaulted, err := c.clientset.AppsV1().Deployments(namespace).Create(ctx, probe, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	if err != nil || defaulted.Name == "" {
+		return base
+	}
+	defaulted.Name, defaulted.GenerateName = base.Name, ""
+	return defaulted
+}
+
+// Overlay is a kustomize overlay turning a base deployment into another
+type Overlay struct {
+	// PatchFile is the name of the patch file the kustomization lists
+	PatchFile     string
+	Kustomization string
+	// Patch is empty if the deployments do not differ
+	Patch string
+}
+
+// String renders the overlay as one YAML stream, each file headed by its
+// name
+func (o Overlay) String() string {
+	if o.Patch == "" {
+		return "# kustomization.yaml\n" + o.Kustomization
+	}
+	return fmt.Sprintf("# kustomization.yaml\n%s---\n# %s\n%s", o.Kustomization, o.PatchFile, o.Patch)
+}
+
+// KustomizeOverlay writes target as an overlay on base: a kustomization
+// listing the resources and a strategic merge patch with the fields that
+// differ. Server-side fields, the namespace and annotations no repository
+// holds are left out.
+func KustomizeOverlay(base, target *appsv1.Deployment, resources string) (Overlay, error) {
+	overlay := Overlay{PatchFile: target.Name + "-patch.yaml"}
+	original, err := json.Marshal(overlayFields(base))
+	if err != nil {
+		return overlay, fmt.Errorf("failed to marshal the base: %w", err)
+	}
+	modified, err := json.Marshal(overlayFields(target))
+	if err != nil {
+		return overlay, fmt.Errorf("failed to marshal deployment %s: %w", target.Name, err)
+	}
+	data, err := strategicpatch.CreateTwoWayMergePatch(original, modified, appsv1.Deployment{})
+	if err != nil {
+		return overlay, fmt.Errorf("failed to create the patch: %w", err)
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return overlay, fmt.Errorf("failed to read the patch: %w", err)
+	}
+	dropElementOrder(patch)
+
+	var b strings.Builder
+	b.WriteString("apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\n")
+	fmt.Fprintf(&b, "namespace: %s\nresources:\n- %s\n", target.Namespace, resources)
+	if len(patch) > 0 {
+		fmt.Fprintf(&b, "patches:\n- path: %s\n", overlay.PatchFile)
+		patch["apiVersion"], patch["kind"] = "apps/v1", "Deployment"
+		metadata, _ := patch["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		// The name selects the patched object of the base
+		metadata["name"] = base.Name
+		patch["metadata"] = metadata
+		text, err := yaml.Marshal(patch)
+		if err != nil {
+			return overlay, fmt.Errorf("failed to marshal the patch: %w", err)
+		}
+		overlay.Patch = string(text)
+	}
+	overlay.Kustomization = b.String()
+	return overlay, nil
+}
+
+// overlayFields copies a deployment without what an overlay does not set
+func overlayFields(deployment *appsv1.Deployment) *appsv1.Deployment {
+	d := deployment.DeepCopy()
+	d.APIVersion, d.Kind = "apps/v1", "Deployment"
+	d.ObjectMeta = metav1.ObjectMeta{
+		Name:        deployment.Name,
+		Labels:      d.Labels,
+		Annotations: d.Annotations,
+	}
+	d.Status = appsv1.DeploymentStatus{}
+	for _, annotations := range []map[string]string{d.Annotations, d.Spec.Template.Annotations} {
+		for _, name := range overlayDroppedAnnotations {
+			delete(annotations, name)
+		}
+	}
+	delete(d.Labels, fluxNameLabel)
+	delete(d.Labels, fluxNamespaceLabel)
+	if strings.HasPrefix(d.Labels[kustomizeManagedByLabel], "kustomize") {
+		delete(d.Labels, kustomizeManagedByLabel)
+	}
+	return d
+}
+
+// dropElementOrder removes the $setElementOrder directives of a strategic
+// merge patch, and the mappings left empty: kustomize keeps the order of
+// the base anyway, and they make a patch hard to read
+func dropElementOrder(value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if strings.HasPrefix(key, "$setElementOrder/") {
+				delete(value, key)
+				continue
+			}
+			dropElementOrder(child)
+			if fields, ok := child.(map[string]interface{}); ok && len(fields) == 0 {
+				delete(value, key)
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			dropElementOrder(child)
+		}
+	}
+}
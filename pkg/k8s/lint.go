@@ -0,0 +1,187 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Severity ranks lint findings
+type Severity int
+
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityError
+)
+
+// SeverityOff is a threshold no finding reaches
+const SeverityOff Severity = SeverityError + 1
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "off"
+}
+
+// ParseSeverity reads a severity threshold: info, warning, error or off. An
+// empty string returns def.
+func ParseSeverity(s string, def Severity) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return def, nil
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	case "off", "none":
+		return SeverityOff, nil
+	}
+	return 0, fmt.Errorf("invalid severity %q, use info, warning, error or off", s)
+}
+
+// Lint checks
+const (
+	LintLatestTag  = "latest-tag"
+	LintNoProbes   = "no-probes"
+	LintNoLimits   = "no-limits"
+	LintPrivileged = "privileged"
+)
+
+// LintFinding is a problem found in a pod template
+type LintFinding struct {
+	Severity  Severity
+	Check     string
+	Container string
+	Message   string
+}
+
+func (f LintFinding) String() string {
+	return fmt.Sprintf("[%s] %s: %s (%s)", f.Severity, f.Container, f.Message, f.Check)
+}
+
+// LintPodTemplate runs lightweight checks on a pod template: images without
+// a pinned tag, missing probes, missing resource limits and privileged
+// containers. Init containers are only checked for their image and
+// privileges.
+func LintPodTemplate(template *corev1.PodTemplateSpec) []LintFinding {
+	var findings []LintFinding
+	add := func(severity Severity, check, container, format string, args ...interface{}) {
+		findings = append(findings, LintFinding{Severity: severity, Check: check, Container: container, Message: fmt.Sprintf(format, args...)})
+	}
+
+	spec := template.Spec
+	all := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for i, container := range all {
+		if usesLatestTag(container.Image) {
+			add(SeverityWarning, LintLatestTag, container.Name, "image %s is not pinned to a tag or digest", container.Image)
+		}
+		if sc := container.SecurityContext; sc != nil && sc.Privileged != nil && *sc.Privileged {
+			add(SeverityError, LintPrivileged, container.Name, "runs privileged")
+		}
+		if i < len(spec.InitContainers) {
+			continue
+		}
+		if container.ReadinessProbe == nil {
+			add(SeverityWarning, LintNoProbes, container.Name, "has no readiness probe")
+		}
+		if container.LivenessProbe == nil {
+			add(SeverityInfo, LintNoProbes, container.Name, "has no liveness probe")
+		}
+		var missing []string
+		for _, resource := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if _, ok := container.Resources.Limits[resource]; !ok {
+				missing = append(missing, string(resource))
+			}
+		}
+		if len(missing) > 0 {
+			add(SeverityWarning, LintNoLimits, container.Name, "has no %s limit", strings.Join(missing, " or "))
+		}
+	}
+	return findings
+}
+
+// usesLatestTag reports whether an image floats: tagged latest or untagged,
+// and not pinned by digest
+func usesLatestTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	// A colon after the last slash starts the tag; one before it is a port
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, ok := strings.Cut(name, ":")
+	return !ok || tag == "latest"
+}
+
+// LintWorkload lints a workload's pod template as it would be after edit,
+// which is applied to a copy. A nil edit lints the live template.
+func (c *Client) LintWorkload(ctx context.Context, namespace, ref string, edit func(*corev1.PodTemplateSpec) error) ([]LintFinding, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+	template := deployment.Spec.Template.DeepCopy()
+	if edit != nil {
+		if err := edit(template); err != nil {
+			return nil, err
+		}
+	}
+	return LintPodTemplate(template), nil
+}
+
+// EditImage returns a LintWorkload edit that sets a container's image
+func EditImage(containerName, image string) func(*corev1.PodTemplateSpec) error {
+	return func(template *corev1.PodTemplateSpec) error {
+		for i := range template.Spec.Containers {
+			if template.Spec.Containers[i].Name == containerName {
+				template.Spec.Containers[i].Image = image
+				return nil
+			}
+		}
+		return fmt.Errorf("container %s not found", containerName)
+	}
+}
+
+// EditImages returns a LintWorkload edit that sets the images of several
+// containers, keyed by container name
+func EditImages(images map[string]string) func(*corev1.PodTemplateSpec) error {
+	return func(template *corev1.PodTemplateSpec) error {
+		for name, image := range images {
+			if err := EditImage(name, image)(template); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// EditEnv returns a LintWorkload edit that sets a container's env var
+func EditEnv(containerName, key, value string) func(*corev1.PodTemplateSpec) error {
+	return func(template *corev1.PodTemplateSpec) error {
+		for i := range template.Spec.Containers {
+			container := &template.Spec.Containers[i]
+			if container.Name != containerName {
+				continue
+			}
+			for j := range container.Env {
+				if container.Env[j].Name == key {
+					container.Env[j] = corev1.EnvVar{Name: key, Value: value}
+					return nil
+				}
+			}
+			container.Env = append(container.Env, corev1.EnvVar{Name: key, Value: value})
+			return nil
+		}
+		return fmt.Errorf("container %s not found", containerName)
+	}
+}
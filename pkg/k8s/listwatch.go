@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// listWatcher lists and watches one kind of object in a namespace
+type listWatcher struct {
+	list  func(ctx context.Context, opts metav1.ListOptions) (string, error) // returns the resource version
+	watch func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// WatchPods calls changed whenever a pod of a deployment is added, deleted
+// or changes, until ctx is cancelled
+func (c *Client) WatchPods(ctx context.Context, namespace, deploymentName string, changed func()) error {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return err
+	}
+	labelSelector := metav1.FormatLabelSelector(deployment.Spec.Selector)
+	pods := c.clientset.CoreV1().Pods(namespace)
+	return watchChanges(ctx, "pods", listWatcher{
+		list: func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+			opts.LabelSelector = labelSelector
+			list, err := pods.List(ctx, opts)
+			if err != nil {
+				return "", err
+			}
+			return list.ResourceVersion, nil
+		},
+		watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			return pods.Watch(ctx, opts)
+		},
+	}, changed)
+}
+
+// WatchDeployments calls changed whenever a deployment of the namespace is
+// added, deleted or changes, until ctx is cancelled
+func (c *Client) WatchDeployments(ctx context.Context, namespace string, changed func()) error {
+	deployments := c.clientset.AppsV1().Deployments(namespace)
+	return watchChanges(ctx, "deployments", listWatcher{
+		list: func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+			list, err := deployments.List(ctx, opts)
+			if err != nil {
+				return "", err
+			}
+			return list.ResourceVersion, nil
+		},
+		watch: deployments.Watch,
+	}, changed)
+}
+
+// watchChanges watches from the current resource version, resuming when
+// the server closes the watch. When the version expires, changes may have
+// been missed, so changed is called and the watch starts over.
+func watchChanges(ctx context.Context, resource string, lw listWatcher, changed func()) error {
+	for relist := false; ; relist = true {
+		// One item is enough for the list's resource version
+		resourceVersion, err := lw.list(ctx, metav1.ListOptions{Limit: 1})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to list %s: %w", resource, err)
+		}
+		if relist {
+			changed()
+		}
+
+		for expired := false; !expired; {
+			w, err := lw.watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					break
+				}
+				return fmt.Errorf("failed to watch %s: %w", resource, err)
+			}
+
+			for ev := range w.ResultChan() {
+				if ev.Type == watch.Error {
+					expired = true
+					break
+				}
+				if obj, ok := ev.Object.(metav1.Object); ok {
+					resourceVersion = obj.GetResourceVersion()
+				}
+				if ev.Type != watch.Bookmark {
+					changed()
+				}
+			}
+			w.Stop()
+
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+}
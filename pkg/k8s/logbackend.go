@@ -0,0 +1,294 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log backends QueryLogRange reads from besides the API server
+const (
+	logBackendLoki          = "loki"
+	logBackendElasticsearch = "elasticsearch"
+)
+
+// maxPodLogRange bounds what is read of a pod's log from the API server
+const maxPodLogRange = 16 << 20
+
+// LogRangeQuery asks for a deployment's log lines logged in a time range
+type LogRangeQuery struct {
+	// Backend is loki or elasticsearch, or empty to read the logs the API
+	// server still has of PodNames
+	Backend string
+	// URL is an http(s) URL of the backend, or
+	// service/<namespace>/<name>[:<port>] to reach it through the API server
+	URL      string
+	Username string
+	Password string // sent as a bearer token if there is no username
+	// Query is the LogQL stream selector or Lucene query of the lines
+	Query string
+	// Elasticsearch only
+	Index        string
+	MessageField string // message or log if empty
+	PodField     string // kubernetes.pod_name if empty
+
+	Namespace     string
+	PodNames      []string
+	ContainerName string
+	Start         time.Time
+	End           time.Time // excluded
+	Limit         int       // the newest lines kept
+}
+
+// TimedLogLine is a log line with the time it was logged
+type TimedLogLine struct {
+	LogLine
+	Time time.Time
+}
+
+// LogRange is the newest lines of a time range, oldest first
+type LogRange struct {
+	Lines []TimedLogLine
+	// More reports whether the limit left out older lines of the range
+	More bool
+}
+
+// lokiResponse is the JSON answer of Loki's query_range
+type lokiResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"` // nanosecond timestamp and line
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// elasticsearchResponse is the JSON answer of Elasticsearch's _search
+type elasticsearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source map[string]interface{} `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Error *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// QueryLogRange returns the newest lines of a deployment logged in a time
+// range, from Loki, Elasticsearch or the API server
+func (c *Client) QueryLogRange(ctx context.Context, q LogRangeQuery) (*LogRange, error) {
+	var lines []TimedLogLine
+	var err error
+	switch q.Backend {
+	case "":
+		lines, err = c.apiServerLogRange(ctx, q)
+	case logBackendLoki:
+		lines, err = c.lokiLogRange(ctx, q)
+	case logBackendElasticsearch:
+		lines, err = c.elasticsearchLogRange(ctx, q)
+	default:
+		return nil, fmt.Errorf("unknown log backend %q, use %s or %s", q.Backend, logBackendLoki, logBackendElasticsearch)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Backends differ in whether the end is included
+	lines = slices.DeleteFunc(lines, func(line TimedLogLine) bool { return !line.Time.Before(q.End) })
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+	r := &LogRange{Lines: lines}
+	if q.Limit > 0 && len(lines) >= q.Limit {
+		r.Lines = lines[len(lines)-q.Limit:]
+		r.More = true
+	}
+	return r, nil
+}
+
+// lokiLogRange queries Loki backwards from the end of the range
+func (c *Client) lokiLogRange(ctx context.Context, q LogRangeQuery) ([]TimedLogLine, error) {
+	params := url.Values{}
+	params.Set("query", q.Query)
+	params.Set("start", strconv.FormatInt(q.Start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(q.End.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(q.Limit))
+	params.Set("direction", "backward")
+
+	body, err := c.getAPI(ctx, apiRequest{
+		base:       q.URL,
+		path:       "loki/api/v1/query_range",
+		params:     params,
+		username:   q.Username,
+		password:   q.Password,
+		name:       "Loki",
+		credential: "logs",
+	})
+	if err != nil {
+		if len(body) > 0 {
+			// Loki explains bad queries in plain text
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(body)))
+		}
+		return nil, err
+	}
+	var resp lokiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to read the Loki answer: %w", err)
+	}
+	if resp.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("loki answered %s instead of log lines, the query must select streams", resp.Data.ResultType)
+	}
+
+	var lines []TimedLogLine
+	for _, stream := range resp.Data.Result {
+		line := LogLine{PodName: stream.Stream["pod"], ContainerName: stream.Stream["container"]}
+		if line.ContainerName == "" {
+			line.ContainerName = q.ContainerName
+		}
+		for _, value := range stream.Values {
+			nanos, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			line.Text = strings.TrimSuffix(value[1], "\n")
+			lines = append(lines, TimedLogLine{LogLine: line, Time: time.Unix(0, nanos)})
+		}
+	}
+	return lines, nil
+}
+
+// elasticsearchLogRange searches Elasticsearch for the newest lines of the
+// range
+func (c *Client) elasticsearchLogRange(ctx context.Context, q LogRangeQuery) ([]TimedLogLine, error) {
+	index := q.Index
+	if index == "" {
+		index = "*"
+	}
+	// The end is excluded so that a page ends right before the previous one
+	timeRange := fmt.Sprintf(`@timestamp:["%s" TO "%s"}`, q.Start.UTC().Format(time.RFC3339Nano), q.End.UTC().Format(time.RFC3339Nano))
+	params := url.Values{}
+	params.Set("q", "("+q.Query+") AND "+timeRange)
+	params.Set("size", strconv.Itoa(q.Limit))
+	params.Set("sort", "@timestamp:desc")
+
+	body, err := c.getAPI(ctx, apiRequest{
+		base:       q.URL,
+		path:       url.PathEscape(index) + "/_search",
+		params:     params,
+		username:   q.Username,
+		password:   q.Password,
+		accept:     "application/json",
+		name:       "Elasticsearch",
+		credential: "logs",
+	})
+	if err != nil && len(body) == 0 {
+		return nil, err
+	}
+	var resp elasticsearchResponse
+	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read the Elasticsearch answer: %w", jsonErr)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("elasticsearch: %s: %s", resp.Error.Type, resp.Error.Reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	messageFields := []string{"message", "log"}
+	if q.MessageField != "" {
+		messageFields = []string{q.MessageField}
+	}
+	podFields := []string{"kubernetes.pod_name", "kubernetes.pod.name"}
+	if q.PodField != "" {
+		podFields = []string{q.PodField}
+	}
+	var lines []TimedLogLine
+	for _, hit := range resp.Hits.Hits {
+		t, err := time.Parse(time.RFC3339Nano, sourceField(hit.Source, "@timestamp"))
+		if err != nil {
+			continue
+		}
+		line := TimedLogLine{Time: t, LogLine: LogLine{ContainerName: q.ContainerName}}
+		for _, field := range messageFields {
+			if line.Text = strings.TrimSuffix(sourceField(hit.Source, field), "\n"); line.Text != "" {
+				break
+			}
+		}
+		for _, field := range podFields {
+			if line.PodName = sourceField(hit.Source, field); line.PodName != "" {
+				break
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// sourceField returns a string field of a document, written either with
+// dots in the key or as nested objects
+func sourceField(source map[string]interface{}, path string) string {
+	if v, ok := source[path].(string); ok {
+		return v
+	}
+	head, rest, ok := strings.Cut(path, ".")
+	if !ok {
+		return ""
+	}
+	nested, _ := source[head].(map[string]interface{})
+	return sourceField(nested, rest)
+}
+
+// apiServerLogRange reads the lines of the range the API server still has
+// of each pod
+func (c *Client) apiServerLogRange(ctx context.Context, q LogRangeQuery) ([]TimedLogLine, error) {
+	var lines []TimedLogLine
+	for _, podName := range q.PodNames {
+		opts := LogOptions{
+			Namespace:     q.Namespace,
+			PodName:       podName,
+			ContainerName: q.ContainerName,
+			SinceTime:     &q.Start,
+			Timestamps:    true,
+			LimitBytes:    maxPodLogRange,
+		}
+		stream, err := c.clientset.CoreV1().Pods(q.Namespace).GetLogs(podName, opts.podLogOptions(false)).Stream(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get logs of %s: %w", podName, err)
+		}
+		reader := bufio.NewReader(stream)
+		for {
+			text, err := reader.ReadString('\n')
+			if stamp, rest, ok := strings.Cut(strings.TrimSuffix(text, "\n"), " "); ok {
+				if t, perr := time.Parse(time.RFC3339Nano, stamp); perr == nil && t.Before(q.End) {
+					lines = append(lines, TimedLogLine{
+						LogLine: LogLine{PodName: podName, ContainerName: q.ContainerName, Text: rest},
+						Time:    t,
+					})
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				stream.Close()
+				return nil, fmt.Errorf("failed to read logs of %s: %w", podName, err)
+			}
+		}
+		stream.Close()
+	}
+	return lines, nil
+}
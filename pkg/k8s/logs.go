@@ -3,10 +3,16 @@ package k8s
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 // LogOptions holds options for streaming logs
@@ -17,19 +23,39 @@ type LogOptions struct {
 	Follow        bool
 	TailLines     int64
 	Previous      bool
+	// SinceTime, if set, only returns lines logged at or after this time
+	SinceTime *time.Time
+	// Timestamps prefixes every line with its RFC3339 timestamp
+	Timestamps bool
+	// LimitBytes, if set, stops the output after this many bytes
+	LimitBytes int64
 }
 
-// StreamLogs streams logs from a container
-func (c *Client) StreamLogs(ctx context.Context, opts LogOptions, output io.Writer) error {
+// podLogOptions converts LogOptions to the API's PodLogOptions
+func (opts LogOptions) podLogOptions(follow bool) *corev1.PodLogOptions {
 	podLogOpts := &corev1.PodLogOptions{
-		Container: opts.ContainerName,
-		Follow:    opts.Follow,
-		Previous:  opts.Previous,
+		Container:  opts.ContainerName,
+		Follow:     follow,
+		Previous:   opts.Previous,
+		Timestamps: opts.Timestamps,
 	}
 
 	if opts.TailLines > 0 {
 		podLogOpts.TailLines = &opts.TailLines
 	}
+	if opts.SinceTime != nil {
+		since := metav1.NewTime(*opts.SinceTime)
+		podLogOpts.SinceTime = &since
+	}
+	if opts.LimitBytes > 0 {
+		podLogOpts.LimitBytes = &opts.LimitBytes
+	}
+	return podLogOpts
+}
+
+// StreamLogs streams logs from a container
+func (c *Client) StreamLogs(ctx context.Context, opts LogOptions, output io.Writer) error {
+	podLogOpts := opts.podLogOptions(opts.Follow)
 
 	req := c.clientset.CoreV1().Pods(opts.Namespace).GetLogs(opts.PodName, podLogOpts)
 	stream, err := req.Stream(ctx)
@@ -58,17 +84,11 @@ func (c *Client) StreamLogs(ctx context.Context, opts LogOptions, output io.Writ
 	}
 }
 
-// GetLogs returns logs from a container as a string
+// GetLogs returns logs from a container as a string. The whole response is
+// held in memory, so set TailLines or LimitBytes or use GetLogsPage for
+// large histories.
 func (c *Client) GetLogs(ctx context.Context, opts LogOptions) (string, error) {
-	podLogOpts := &corev1.PodLogOptions{
-		Container: opts.ContainerName,
-		Follow:    false,
-		Previous:  opts.Previous,
-	}
-
-	if opts.TailLines > 0 {
-		podLogOpts.TailLines = &opts.TailLines
-	}
+	podLogOpts := opts.podLogOptions(false)
 
 	req := c.clientset.CoreV1().Pods(opts.Namespace).GetLogs(opts.PodName, podLogOpts)
 	result, err := req.Do(ctx).Raw()
@@ -78,3 +98,247 @@ func (c *Client) GetLogs(ctx context.Context, opts LogOptions) (string, error) {
 
 	return string(result), nil
 }
+
+// DefaultLogPageLines is the page size GetLogsPage uses without TailLines
+const DefaultLogPageLines = 500
+
+// LogPage is a bounded chunk of a container's log history
+type LogPage struct {
+	Lines []string
+	// Oldest is the API timestamp of the first line, pass it as before to
+	// get the page preceding this one
+	Oldest time.Time
+	// More reports whether older lines may exist
+	More bool
+}
+
+// GetLogsPage returns up to opts.TailLines lines (DefaultLogPageLines if
+// unset) logged before the given time, or the newest lines when before is
+// zero. The API can only return the newest lines of a log, so newer is the
+// number of lines the caller already has from before onwards; that many
+// extra lines are requested and dropped while reading, keeping memory
+// bounded by the page size.
+func (c *Client) GetLogsPage(ctx context.Context, opts LogOptions, before time.Time, newer int64) (*LogPage, error) {
+	count := opts.TailLines
+	if count <= 0 {
+		count = DefaultLogPageLines
+	}
+	opts.TailLines = count + newer
+	opts.Timestamps = true
+
+	req := c.clientset.CoreV1().Pods(opts.Namespace).GetLogs(opts.PodName, opts.podLogOptions(false))
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", err)
+	}
+	defer stream.Close()
+
+	// Ring buffer of the newest lines before the cutoff
+	type entry struct {
+		time time.Time
+		text string
+	}
+	ring := make([]entry, 0, count)
+	start := 0
+	var total int64
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			total++
+			e := entry{text: strings.TrimSuffix(line, "\n")}
+			if stamp, text, ok := strings.Cut(e.text, " "); ok {
+				if t, perr := time.Parse(time.RFC3339Nano, stamp); perr == nil {
+					e.time, e.text = t, text
+				}
+			}
+			if before.IsZero() || e.time.Before(before) {
+				if int64(len(ring)) < count {
+					ring = append(ring, e)
+				} else {
+					ring[start] = e
+					start = (start + 1) % len(ring)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read logs: %w", err)
+		}
+	}
+
+	page := &LogPage{
+		Lines: make([]string, 0, len(ring)),
+		// A full response means the log goes back further
+		More: total >= opts.TailLines,
+	}
+	for i := range ring {
+		e := ring[(start+i)%len(ring)]
+		if i == 0 {
+			page.Oldest = e.time
+		}
+		page.Lines = append(page.Lines, e.text)
+	}
+	return page, nil
+}
+
+// RestartMarker starts the separator line FollowLogs writes when the
+// followed container restarts
+const RestartMarker = "===== container restarted"
+
+// errPodFinished stops FollowLogs when the pod will not run again
+var errPodFinished = errors.New("pod finished")
+
+// FollowLogs follows a container's logs like StreamLogs with Follow set, but
+// survives container restarts: when the stream ends it watches the pod until
+// the container is running again, writes a separator line and reopens the
+// stream for the new instance. It returns when the context is cancelled, the
+// pod is deleted or the pod has finished.
+func (c *Client) FollowLogs(ctx context.Context, opts LogOptions, output io.Writer) error {
+	opts.Follow = true
+
+	pod, err := c.GetPod(ctx, opts.Namespace, opts.PodName)
+	if err != nil {
+		return err
+	}
+	restarts := containerRestartCount(pod, opts.ContainerName)
+
+	for {
+		streamErr := c.StreamLogs(ctx, opts, output)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		streamEnd := time.Now()
+
+		pod, err := c.GetPod(ctx, opts.Namespace, opts.PodName)
+		if err != nil {
+			return err
+		}
+		if containerRestartCount(pod, opts.ContainerName) == restarts && containerRunning(pod, opts.ContainerName) {
+			// Same instance still running: the server closed the stream
+			if streamErr != nil {
+				return streamErr
+			}
+			opts.TailLines = 0
+			opts.SinceTime = &streamEnd
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		exitCode, count, err := c.waitForRestart(ctx, opts.Namespace, opts.PodName, opts.ContainerName, restarts)
+		if err == errPodFinished {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		restarts = count
+
+		if _, err := fmt.Fprintf(output, "%s (exit code %d) =====\n", RestartMarker, exitCode); err != nil {
+			return err
+		}
+
+		// Stream the new instance from its first line
+		opts.TailLines = 0
+		opts.SinceTime = nil
+	}
+}
+
+// waitForRestart watches a pod until the container's restart count exceeds
+// restarts and the new instance is running. It returns the previous
+// instance's exit code and the new restart count.
+func (c *Client) waitForRestart(ctx context.Context, namespace, podName, containerName string, restarts int32) (int32, int32, error) {
+	for {
+		pod, err := c.GetPod(ctx, namespace, podName)
+		if err != nil {
+			return 0, 0, err
+		}
+		if code, count, ok := restartedContainer(pod, containerName, restarts); ok {
+			return code, count, nil
+		}
+		if podFinished(pod) {
+			return 0, 0, errPodFinished
+		}
+
+		w, err := c.clientset.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", podName).String(),
+			ResourceVersion: pod.ResourceVersion,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for event := range w.ResultChan() {
+			if event.Type == watch.Deleted {
+				w.Stop()
+				return 0, 0, fmt.Errorf("pod %s was deleted", podName)
+			}
+			p, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			if code, count, ok := restartedContainer(p, containerName, restarts); ok {
+				w.Stop()
+				return code, count, nil
+			}
+			if podFinished(p) {
+				w.Stop()
+				return 0, 0, errPodFinished
+			}
+		}
+		w.Stop()
+
+		if ctx.Err() != nil {
+			return 0, 0, ctx.Err()
+		}
+		// The server closed the watch, start over
+	}
+}
+
+// containerStatus finds the status of a container in a pod
+func containerStatus(pod *corev1.Pod, containerName string) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == containerName {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
+
+func containerRestartCount(pod *corev1.Pod, containerName string) int32 {
+	if cs := containerStatus(pod, containerName); cs != nil {
+		return cs.RestartCount
+	}
+	return 0
+}
+
+func containerRunning(pod *corev1.Pod, containerName string) bool {
+	cs := containerStatus(pod, containerName)
+	return cs != nil && cs.State.Running != nil
+}
+
+// restartedContainer reports whether the container restarted since restarts
+// was observed and its new instance is running
+func restartedContainer(pod *corev1.Pod, containerName string, restarts int32) (int32, int32, bool) {
+	cs := containerStatus(pod, containerName)
+	if cs == nil || cs.RestartCount <= restarts || cs.State.Running == nil {
+		return 0, 0, false
+	}
+	exitCode := int32(-1)
+	if cs.LastTerminationState.Terminated != nil {
+		exitCode = cs.LastTerminationState.Terminated.ExitCode
+	}
+	return exitCode, cs.RestartCount, true
+}
+
+// podFinished reports whether the pod has terminated for good
+func podFinished(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
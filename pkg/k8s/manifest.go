@@ -0,0 +1,50 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// GetManifest returns the live YAML manifest of a deployment or pod.
+// managedFields are stripped unless keepManagedFields is set.
+func (c *Client) GetManifest(ctx context.Context, namespace, kind, name string, keepManagedFields bool) (string, error) {
+	var obj interface{}
+	var meta *metav1.ObjectMeta
+
+	switch strings.ToLower(kind) {
+	case "deployment":
+		if workload, workloadName := parseWorkloadRef(name); workload != nil {
+			return c.workloadManifest(ctx, namespace, workload, workloadName, keepManagedFields)
+		}
+		deployment, err := c.GetDeployment(ctx, namespace, name)
+		if err != nil {
+			return "", err
+		}
+		// Typed clients drop the type fields
+		deployment.APIVersion, deployment.Kind = "apps/v1", "Deployment"
+		obj, meta = deployment, &deployment.ObjectMeta
+	case "pod":
+		pod, err := c.GetPod(ctx, namespace, name)
+		if err != nil {
+			return "", err
+		}
+		pod.APIVersion, pod.Kind = "v1", "Pod"
+		obj, meta = pod, &pod.ObjectMeta
+	default:
+		return "", fmt.Errorf("unsupported kind: %s", kind)
+	}
+
+	if !keepManagedFields {
+		meta.ManagedFields = nil
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s: %w", kind, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLine is a single line received while following several pods
+type LogLine struct {
+	PodName       string
+	ContainerName string
+	// Text is the line without its trailing newline
+	Text string
+}
+
+// MultiLogOptions holds options for following a container in several pods
+type MultiLogOptions struct {
+	Namespace     string
+	PodNames      []string
+	ContainerName string
+	TailLines     int64
+	// SinceTime, if set, only returns lines logged at or after this time
+	SinceTime *time.Time
+	// Timestamps prefixes every line's text with its RFC3339 timestamp
+	Timestamps bool
+}
+
+// FollowPodLogs follows a container's logs in several pods at once, calling
+// handle for every line. Calls to handle are serialized. Each pod is
+// followed with FollowLogs, so container restarts are survived. It returns
+// once all streams have ended, with the first error encountered.
+func (c *Client) FollowPodLogs(ctx context.Context, opts MultiLogOptions, handle func(LogLine)) error {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	for _, podName := range opts.PodNames {
+		wg.Add(1)
+		go func(podName string) {
+			defer wg.Done()
+
+			w := &lineWriter{emit: func(text string) {
+				mu.Lock()
+				defer mu.Unlock()
+				handle(LogLine{PodName: podName, ContainerName: opts.ContainerName, Text: text})
+			}}
+			err := c.FollowLogs(ctx, LogOptions{
+				Namespace:     opts.Namespace,
+				PodName:       podName,
+				ContainerName: opts.ContainerName,
+				TailLines:     opts.TailLines,
+				SinceTime:     opts.SinceTime,
+				Timestamps:    opts.Timestamps,
+			}, w)
+			w.Flush()
+
+			if err != nil && ctx.Err() == nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", podName, err)
+				}
+				mu.Unlock()
+			}
+		}(podName)
+	}
+
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// lineWriter splits written bytes into lines
+type lineWriter struct {
+	buf  []byte
+	emit func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits a final line that had no trailing newline
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,268 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NetCheckKind is what a network check tests
+type NetCheckKind string
+
+const (
+	NetCheckDNS  NetCheckKind = "dns"  // resolve a name
+	NetCheckTCP  NetCheckKind = "tcp"  // connect to host:port
+	NetCheckHTTP NetCheckKind = "http" // GET a URL
+)
+
+// NetCheck is a network check run from inside a container
+type NetCheck struct {
+	Kind   NetCheckKind
+	Target string
+}
+
+// NetResult is the outcome of a network check
+type NetResult struct {
+	Check    NetCheck
+	OK       bool
+	Tool     string // the tool found in the container that ran it
+	Detail   string // addresses, HTTP status or why it failed
+	Duration time.Duration
+}
+
+// DefaultNetChecks test the cluster DNS and the API server
+var DefaultNetChecks = []NetCheck{
+	{Kind: NetCheckDNS, Target: "kubernetes.default.svc"},
+	{Kind: NetCheckTCP, Target: "kubernetes.default.svc:443"},
+}
+
+// netCheckTimeout bounds a check, the tools themselves give up after 5s
+const netCheckTimeout = 15 * time.Second
+
+// ParseNetChecks reads space-separated targets: URLs are fetched, host:port
+// is connected to and anything else is resolved
+func ParseNetChecks(input string) ([]NetCheck, error) {
+	var checks []NetCheck
+	for _, target := range strings.Fields(input) {
+		switch {
+		case strings.Contains(target, "://"):
+			u, err := url.Parse(target)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("invalid URL %q, use http:// or https://", target)
+			}
+			checks = append(checks, NetCheck{Kind: NetCheckHTTP, Target: target})
+		case strings.Contains(target, ":"):
+			if _, port, err := net.SplitHostPort(target); err != nil || !validPort(port) {
+				return nil, fmt.Errorf("invalid address %q, use host:port", target)
+			}
+			checks = append(checks, NetCheck{Kind: NetCheckTCP, Target: target})
+		default:
+			checks = append(checks, NetCheck{Kind: NetCheckDNS, Target: target})
+		}
+	}
+	return checks, nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n < 65536
+}
+
+// The check scripts try the tools an image may have in turn. Each prints
+// "@tool <name>", the tool's output and "@rc <exit code>". Targets are
+// passed as arguments, never spliced into the script.
+const (
+	netTimeoutPrefix = `if command -v timeout >/dev/null 2>&1; then t="timeout 5"; else t=""; fi
+`
+	netScriptSuffix = `
+echo "@rc $?"`
+
+	dnsScript = `if command -v getent >/dev/null 2>&1; then echo "@tool getent"; getent hosts "$1" 2>&1
+elif command -v nslookup >/dev/null 2>&1; then echo "@tool nslookup"; nslookup "$1" 2>&1
+elif command -v dig >/dev/null 2>&1; then echo "@tool dig"; dig +short "$1" 2>&1
+elif command -v host >/dev/null 2>&1; then echo "@tool host"; host "$1" 2>&1
+else echo "@tool none"; (exit 127); fi`
+
+	tcpScript = `if command -v nc >/dev/null 2>&1; then echo "@tool nc"; nc -z -w 5 "$1" "$2" 2>&1
+elif command -v bash >/dev/null 2>&1; then echo "@tool bash"; $t bash -c 'exec 3<>"/dev/tcp/$1/$2"' bash "$1" "$2" 2>&1
+elif command -v curl >/dev/null 2>&1; then echo "@tool curl"; out=$(curl -sv -m 3 "telnet://$1:$2" </dev/null 2>&1); echo "$out" | grep -iE 'fail|refused|resolve' | tail -n 1; echo "$out" | grep -q "Connected to"
+else echo "@tool none"; (exit 127); fi`
+
+	httpScript = `if command -v curl >/dev/null 2>&1; then echo "@tool curl"; curl -sS -k -o /dev/null -m 5 -w 'HTTP %{http_code}\n' "$1" 2>&1
+elif command -v wget >/dev/null 2>&1; then echo "@tool wget"; wget -S -O /dev/null -T 5 --no-check-certificate "$1" 2>&1 || wget -S -O /dev/null -T 5 "$1" 2>&1
+elif [ "$5" = http ] && command -v bash >/dev/null 2>&1; then echo "@tool bash"; $t bash -c 'exec 3<>"/dev/tcp/$1/$2" && printf "GET %s HTTP/1.0\r\nHost: %s\r\n\r\n" "$3" "$1" >&3 && head -n 1 <&3' bash "$2" "$3" "$4" 2>&1
+else echo "@tool none"; (exit 127); fi`
+)
+
+// netTools names the tools each kind of check tries
+var netTools = map[NetCheckKind]string{
+	NetCheckDNS:  "getent, nslookup, dig, host",
+	NetCheckTCP:  "nc, bash /dev/tcp, curl",
+	NetCheckHTTP: "curl, wget, bash /dev/tcp",
+}
+
+var (
+	ipPattern         = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\b[0-9a-fA-F]{0,4}(?::[0-9a-fA-F]{0,4}){2,7}\b`)
+	httpStatusPattern = regexp.MustCompile(`HTTP(?:/[\d.]+)? (\d{3})`)
+)
+
+// RunNetChecks runs the checks one after another from inside a container
+// with the tools it has. It fails only if the container has no shell; failed
+// checks are results.
+func (c *Client) RunNetChecks(ctx context.Context, namespace, podName, containerName string, checks []NetCheck) ([]NetResult, error) {
+	shell, err := c.CheckShellAvailable(ctx, namespace, podName, containerName)
+	if err != nil {
+		return nil, err
+	}
+	var results []NetResult
+	for _, check := range checks {
+		script, args := netCheckScript(check)
+		checkCtx, cancel := context.WithTimeout(ctx, netCheckTimeout)
+		start := time.Now()
+		var out bytes.Buffer
+		err := c.Exec(checkCtx, ExecOptions{
+			Namespace:     namespace,
+			PodName:       podName,
+			ContainerName: containerName,
+			Command:       append([]string{shell, "-c", netTimeoutPrefix + script + netScriptSuffix, "khelper"}, args...),
+			Stdout:        &out,
+			Stderr:        &out,
+		})
+		cancel()
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		result := parseNetOutput(check, out.String())
+		result.Duration = time.Since(start)
+		if err != nil && result.Tool == "" {
+			result.Detail = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// netCheckScript returns the script of a check and its arguments
+func netCheckScript(check NetCheck) (string, []string) {
+	switch check.Kind {
+	case NetCheckTCP:
+		host, port, _ := net.SplitHostPort(check.Target)
+		return tcpScript, []string{host, port}
+	case NetCheckHTTP:
+		u, _ := url.Parse(check.Target)
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		return httpScript, []string{check.Target, u.Hostname(), port, u.RequestURI(), u.Scheme}
+	}
+	return dnsScript, []string{check.Target}
+}
+
+// parseNetOutput reads the tool, exit code and output of a check script
+func parseNetOutput(check NetCheck, output string) NetResult {
+	result := NetResult{Check: check}
+	rc := -1
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "@tool "):
+			result.Tool = strings.TrimPrefix(line, "@tool ")
+		case strings.HasPrefix(line, "@rc "):
+			rc, _ = strconv.Atoi(strings.TrimPrefix(line, "@rc "))
+		case strings.TrimSpace(line) != "":
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	if result.Tool == "none" {
+		result.Tool = ""
+		result.Detail = "no tool for it in the container (tried " + netTools[check.Kind] + ")"
+		return result
+	}
+	failure := func(fallback string) string {
+		if len(lines) > 0 {
+			return strings.TrimPrefix(lines[len(lines)-1], "* ")
+		}
+		return fallback
+	}
+
+	switch check.Kind {
+	case NetCheckDNS:
+		if result.Tool == "nslookup" {
+			// Skip the name server nslookup prints before the answer
+			answer := -1
+			for i, line := range lines {
+				if strings.HasPrefix(line, "Name:") {
+					answer = i
+					break
+				}
+			}
+			if answer < 0 {
+				rc = max(rc, 1)
+			} else {
+				lines = lines[answer:]
+			}
+		}
+		var addresses []string
+		seen := map[string]bool{}
+		for _, line := range lines {
+			for _, ip := range ipPattern.FindAllString(line, -1) {
+				if net.ParseIP(ip) != nil && !seen[ip] {
+					seen[ip] = true
+					addresses = append(addresses, ip)
+				}
+			}
+		}
+		if rc == 0 && len(addresses) > 0 {
+			result.OK = true
+			result.Detail = strings.Join(addresses, ", ")
+		} else {
+			result.Detail = failure("does not resolve")
+		}
+
+	case NetCheckTCP:
+		switch rc {
+		case 0:
+			result.OK = true
+			result.Detail = "connected"
+		case 124:
+			result.Detail = "timed out"
+		default:
+			result.Detail = failure("connection failed")
+		}
+
+	case NetCheckHTTP:
+		var status int
+		var messages []string
+		for _, line := range lines {
+			if m := httpStatusPattern.FindStringSubmatch(line); m != nil {
+				status, _ = strconv.Atoi(m[1])
+			} else {
+				messages = append(messages, line)
+			}
+		}
+		lines = messages
+		switch {
+		case status >= 200 && status < 400:
+			result.OK = true
+			result.Detail = "HTTP " + strconv.Itoa(status)
+		case status > 0:
+			result.Detail = "HTTP " + strconv.Itoa(status)
+		case rc == 124:
+			result.Detail = "timed out"
+		default:
+			result.Detail = failure("request failed")
+		}
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// overviewEventWindow is how far back the overview looks for warning events
+const overviewEventWindow = time.Hour
+
+// NamespaceOverview summarizes what needs attention in a namespace
+type NamespaceOverview struct {
+	Deployments int
+	Unhealthy   []string // "name: available/desired ...", sorted
+	PendingPods []string // "name: reason"
+	Warnings    []*corev1.Event
+	FailedJobs  []string // "name: reason"
+}
+
+// GetNamespaceOverview collects deployment health, pending pods, warning
+// events of the last hour (newest first) and failed jobs. Jobs that may not
+// be listed are skipped.
+func (c *Client) GetNamespaceOverview(ctx context.Context, namespace string) (*NamespaceOverview, error) {
+	o := &NamespaceOverview{}
+
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	o.Deployments = len(deployments.Items)
+	for i := range deployments.Items {
+		if problem := deploymentProblem(&deployments.Items[i]); problem != "" {
+			o.Unhealthy = append(o.Unhealthy, deployments.Items[i].Name+": "+problem)
+		}
+	}
+	sort.Strings(o.Unhealthy)
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{FieldSelector: "status.phase=Pending"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodPending {
+			continue
+		}
+		o.PendingPods = append(o.PendingPods, pod.Name+": "+pendingReason(&pod))
+	}
+	sort.Strings(o.PendingPods)
+
+	events, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: "type=Warning"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	since := time.Now().Add(-overviewEventWindow)
+	for i := range events.Items {
+		if events.Items[i].Type == corev1.EventTypeWarning && eventTime(events.Items[i]).After(since) {
+			o.Warnings = append(o.Warnings, &events.Items[i])
+		}
+	}
+	sort.Slice(o.Warnings, func(i, j int) bool {
+		return EventTime(o.Warnings[i]).After(EventTime(o.Warnings[j]))
+	})
+
+	jobs, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for _, job := range jobs.Items {
+			if reason := jobFailure(&job); reason != "" {
+				o.FailedJobs = append(o.FailedJobs, job.Name+": "+reason)
+			}
+		}
+		sort.Strings(o.FailedJobs)
+	}
+	return o, nil
+}
+
+// deploymentProblem describes why a deployment is unhealthy, or returns ""
+func deploymentProblem(d *appsv1.Deployment) string {
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return "rollout stuck (progress deadline exceeded)"
+		}
+	}
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	if d.Status.AvailableReplicas < desired {
+		return fmt.Sprintf("%d/%d available", d.Status.AvailableReplicas, desired)
+	}
+	return ""
+}
+
+// pendingReason explains why a pod is pending: a waiting container's reason
+// or why it is not scheduled
+func pendingReason(pod *corev1.Pod) string {
+	for _, cs := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" && cs.State.Waiting.Reason != "PodInitializing" {
+			return cs.State.Waiting.Reason
+		}
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			if cond.Reason != "" {
+				return cond.Reason
+			}
+			return "not scheduled"
+		}
+	}
+	return "pending since " + pod.CreationTimestamp.Format("15:04")
+}
+
+// jobFailure returns the reason a job failed, or "" if it did not
+func jobFailure(job *batchv1.Job) string {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			if cond.Reason != "" {
+				return cond.Reason
+			}
+			return "failed"
+		}
+	}
+	return ""
+}
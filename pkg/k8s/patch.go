@@ -0,0 +1,264 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// Patch types, named like kubectl patch --type
+const (
+	PatchStrategic = "strategic" // lists such as containers merge by name
+	PatchMerge     = "merge"     // JSON merge patch (RFC 7386), lists are replaced
+	PatchJSON      = "json"      // JSON patch (RFC 6902), a list of operations
+)
+
+// jsonPatchOps are the operations of a JSON patch and the field each needs
+// besides path
+var jsonPatchOps = map[string]string{
+	"add":     "value",
+	"replace": "value",
+	"test":    "value",
+	"remove":  "",
+	"move":    "from",
+	"copy":    "from",
+}
+
+// Patch is a patch checked by ParsePatch, in JSON
+type Patch struct {
+	Type string
+	Data []byte
+	// ResourceVersion, if set, is the version of the deployment the patch
+	// was previewed on; applying it to any other version fails
+	ResourceVersion string
+}
+
+// ErrChangedSincePreview is returned by PatchDeployment when the deployment
+// is no longer the version the patch was previewed on
+var ErrChangedSincePreview = errors.New("changed since preview, re-preview the patch")
+
+// ParsePatch reads a patch written in YAML or JSON and checks its shape. An
+// empty patchType takes a list for a JSON patch and a mapping for a
+// strategic merge patch.
+func ParsePatch(patchType, text string) (Patch, error) {
+	data, err := yaml.YAMLToJSON([]byte(text))
+	if err != nil {
+		return Patch{}, fmt.Errorf("invalid patch: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return Patch{}, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	ops, isList := value.([]interface{})
+	fields, isMap := value.(map[string]interface{})
+	if patchType == "" {
+		patchType = PatchStrategic
+		if isList {
+			patchType = PatchJSON
+		}
+	}
+	switch patchType {
+	case PatchStrategic, PatchMerge:
+		if !isMap {
+			return Patch{}, fmt.Errorf("a %s patch must be a mapping, e.g. spec: {replicas: 3}", patchType)
+		}
+		if len(fields) == 0 {
+			return Patch{}, fmt.Errorf("the patch is empty")
+		}
+	case PatchJSON:
+		if !isList {
+			return Patch{}, fmt.Errorf("a json patch must be a list of operations, e.g. - {op: replace, path: /spec/replicas, value: 3}")
+		}
+		if len(ops) == 0 {
+			return Patch{}, fmt.Errorf("the patch is empty")
+		}
+		for i, op := range ops {
+			if err := checkJSONPatchOp(op); err != nil {
+				return Patch{}, fmt.Errorf("operation %d: %w", i+1, err)
+			}
+		}
+	default:
+		return Patch{}, fmt.Errorf("unknown patch type %q, use %s, %s or %s", patchType, PatchStrategic, PatchMerge, PatchJSON)
+	}
+	return Patch{Type: patchType, Data: data}, nil
+}
+
+// maxPatchFields bounds the fields Summary names
+const maxPatchFields = 4
+
+// Summary names the fields a patch changes, e.g. "spec.replicas,
+// spec.template.spec.containers", for the audit history and change cause
+func (p Patch) Summary() string {
+	var value interface{}
+	if err := json.Unmarshal(p.Data, &value); err != nil {
+		return p.Type + " patch"
+	}
+	var fields []string
+	seen := map[string]bool{}
+	add := func(field string) {
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	if ops, ok := value.([]interface{}); ok {
+		for _, op := range ops {
+			values, _ := op.(map[string]interface{})
+			path, _ := values["path"].(string)
+			add(pointerField(path))
+		}
+	} else {
+		var walk func(prefix string, value interface{})
+		walk = func(prefix string, value interface{}) {
+			children, ok := value.(map[string]interface{})
+			if !ok || len(children) == 0 {
+				add(prefix)
+				return
+			}
+			keys := make([]string, 0, len(children))
+			for key := range children {
+				// Strategic merge directives such as $patch
+				if !strings.HasPrefix(key, "$") {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(strings.TrimPrefix(prefix+"."+key, "."), children[key])
+			}
+		}
+		walk("", value)
+	}
+
+	if len(fields) > maxPatchFields {
+		return fmt.Sprintf("%s and %d more", strings.Join(fields[:maxPatchFields], ", "), len(fields)-maxPatchFields)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// pointerField turns a JSON pointer into a dotted field, e.g.
+// /metadata/annotations/a~1b into metadata.annotations.a/b
+func pointerField(pointer string) string {
+	parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, part := range parts {
+		parts[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// checkJSONPatchOp checks a JSON patch operation has an op, a path and the
+// value or from its op needs
+func checkJSONPatchOp(op interface{}) error {
+	fields, ok := op.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("not a mapping")
+	}
+	name, _ := fields["op"].(string)
+	needs, ok := jsonPatchOps[name]
+	if !ok {
+		return fmt.Errorf("unknown op %q, use add, remove, replace, move, copy or test", name)
+	}
+	if path, _ := fields["path"].(string); !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("%s needs a path starting with /", name)
+	}
+	if _, ok := fields[needs]; needs != "" && !ok {
+		return fmt.Errorf("%s needs a %s", name, needs)
+	}
+	return nil
+}
+
+// PatchDeployment applies a patch to a deployment and sends the result as an
+// update, so a change made meanwhile fails it instead of being merged
+// blindly. With the patch's ResourceVersion set, a change made since the
+// preview fails it with ErrChangedSincePreview, so what is written is what
+// was previewed. The change cause in ctx is recorded. With dryRun the API
+// server validates, admits and defaults the result without storing it. It
+// returns the deployment before and after.
+func (c *Client) PatchDeployment(ctx context.Context, namespace, name string, patch Patch, dryRun bool) (*appsv1.Deployment, *appsv1.Deployment, error) {
+	if kind, workloadName := parseWorkloadRef(name); kind != nil {
+		return nil, nil, errDeploymentOnly("patch", kind, workloadName)
+	}
+	before, err := c.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if patch.ResourceVersion != "" && before.ResourceVersion != patch.ResourceVersion {
+		return nil, nil, fmt.Errorf("deployment %s %w", name, ErrChangedSincePreview)
+	}
+	original, err := json.Marshal(before)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal deployment %s: %w", name, err)
+	}
+
+	var patched []byte
+	switch patch.Type {
+	case PatchStrategic:
+		patched, err = strategicpatch.StrategicMergePatch(original, patch.Data, appsv1.Deployment{})
+	case PatchMerge:
+		patched, err = jsonpatch.MergePatch(original, patch.Data)
+	case PatchJSON:
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(patch.Data); err == nil {
+			patched, err = ops.Apply(original)
+		}
+	default:
+		err = fmt.Errorf("unknown patch type %q", patch.Type)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply the patch: %w", err)
+	}
+
+	// Unknown fields would be dropped silently, typos included
+	after := &appsv1.Deployment{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(after); err != nil {
+		return nil, nil, fmt.Errorf("the patch does not fit a deployment: %w", err)
+	}
+	if after.Name != before.Name || after.Namespace != before.Namespace {
+		return nil, nil, fmt.Errorf("the patch must not change the name or namespace")
+	}
+
+	setChangeCause(ctx, after)
+	after.ResourceVersion = before.ResourceVersion
+	opts := metav1.UpdateOptions{}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	updated, err := c.clientset.AppsV1().Deployments(namespace).Update(ctx, after, opts)
+	if apierrors.IsConflict(err) && patch.ResourceVersion != "" {
+		return nil, nil, fmt.Errorf("deployment %s %w", name, ErrChangedSincePreview)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to patch deployment %s: %w", name, err)
+	}
+	return before, updated, nil
+}
+
+// PatchManifest renders a deployment as YAML to compare it before and after
+// a patch, leaving out the status and the fields the server keeps
+func PatchManifest(deployment *appsv1.Deployment) (string, error) {
+	d := deployment.DeepCopy()
+	d.APIVersion, d.Kind = "apps/v1", "Deployment"
+	d.ManagedFields = nil
+	d.ResourceVersion = ""
+	d.Generation = 0
+	d.Status = appsv1.DeploymentStatus{}
+	data, err := yaml.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal deployment %s: %w", d.Name, err)
+	}
+	return string(data), nil
+}
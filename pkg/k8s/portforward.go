@@ -2,27 +2,105 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"os/signal"
-	"syscall"
+	"strconv"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 )
 
+// errUDPForward explains why UDP ports cannot be forwarded
+var errUDPForward = errors.New("port-forward only carries TCP: the API server's port-forward " +
+	"protocol has no UDP, so UDP services need e.g. a socat relay in the pod")
+
+// PortPair forwards a local port to a port of the pod
+type PortPair struct {
+	Local  int
+	Remote int
+}
+
+// String renders the pair as local:remote, or just the port if they match
+func (p PortPair) String() string {
+	if p.Local == p.Remote {
+		return strconv.Itoa(p.Local)
+	}
+	return fmt.Sprintf("%d:%d", p.Local, p.Remote)
+}
+
+// FormatPortPairs renders pairs the way ParsePortPairs reads them
+func FormatPortPairs(pairs []PortPair) string {
+	parts := make([]string, len(pairs))
+	for i, p := range pairs {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParsePortPairs parses comma-separated port pairs such as 8080:80,9090,
+// where a single port forwards the same port. A /tcp suffix is allowed,
+// /udp is rejected as port-forward cannot carry UDP.
+func ParsePortPairs(s string) ([]PortPair, error) {
+	var pairs []PortPair
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		spec, protocol, _ := strings.Cut(part, "/")
+		switch strings.ToLower(protocol) {
+		case "", "tcp":
+		case "udp":
+			return nil, fmt.Errorf("%s: %w", part, errUDPForward)
+		default:
+			return nil, fmt.Errorf("invalid protocol in %q, only tcp can be forwarded", part)
+		}
+		local, remote, ok := strings.Cut(spec, ":")
+		if !ok {
+			remote = local
+		}
+		l, lerr := strconv.Atoi(local)
+		r, rerr := strconv.Atoi(remote)
+		if lerr != nil || rerr != nil || l < 0 || l > 65535 || r < 1 || r > 65535 {
+			return nil, fmt.Errorf("invalid ports %q, use local:remote or a single port, e.g. 8080:80,9090", part)
+		}
+		pairs = append(pairs, PortPair{Local: l, Remote: r})
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no ports given, use local:remote or a single port, e.g. 8080:80,9090")
+	}
+	return pairs, nil
+}
+
 // PortForwardOptions holds options for port forwarding
 type PortForwardOptions struct {
-	Namespace  string
-	PodName    string
-	LocalPort  int
-	RemotePort int
+	Namespace string
+	PodName   string
+	// Ports are forwarded together, they share the connection to the pod
+	Ports []PortPair
+
+	// Out and ErrOut receive the forwarder's status output. Nil discards it.
+	Out    io.Writer
+	ErrOut io.Writer
+
+	// ReadyChan, if set, is closed once the local port is listening.
+	ReadyChan chan struct{}
 }
 
-// PortForward starts port forwarding to a pod
+// PortForward forwards local ports to a pod. It blocks until the context is
+// cancelled or the connection fails.
 func (c *Client) PortForward(ctx context.Context, opts PortForwardOptions) error {
+	if c.config == nil {
+		return fmt.Errorf("port-forward is not supported without a REST config")
+	}
+
 	url := c.clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Namespace(opts.Namespace).
@@ -41,30 +119,36 @@ func (c *Client) portForward(ctx context.Context, url *url.URL, opts PortForward
 
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
 
-	ports := []string{fmt.Sprintf("%d:%d", opts.LocalPort, opts.RemotePort)}
+	ports := make([]string, len(opts.Ports))
+	for i, p := range opts.Ports {
+		ports[i] = fmt.Sprintf("%d:%d", p.Local, p.Remote)
+	}
 	stopChan := make(chan struct{}, 1)
 	readyChan := make(chan struct{})
 	errChan := make(chan error, 1)
 
-	pf, err := portforward.New(dialer, ports, stopChan, readyChan, os.Stdout, os.Stderr)
+	out, errOut := opts.Out, opts.ErrOut
+	if out == nil {
+		out = io.Discard
+	}
+	if errOut == nil {
+		errOut = io.Discard
+	}
+
+	pf, err := portforward.New(dialer, ports, stopChan, readyChan, out, errOut)
 	if err != nil {
 		return fmt.Errorf("failed to create port forwarder: %w", err)
 	}
 
-	// Handle signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
 	go func() {
-		if err := pf.ForwardPorts(); err != nil {
-			errChan <- err
-		}
+		errChan <- pf.ForwardPorts()
 	}()
 
 	select {
 	case <-readyChan:
-		fmt.Printf("Port forwarding is ready. Forwarding %d -> %d\n", opts.LocalPort, opts.RemotePort)
-		fmt.Println("Press Ctrl+C to stop...")
+		if opts.ReadyChan != nil {
+			close(opts.ReadyChan)
+		}
 	case err := <-errChan:
 		return err
 	case <-ctx.Done():
@@ -73,15 +157,144 @@ func (c *Client) portForward(ctx context.Context, url *url.URL, opts PortForward
 	}
 
 	select {
-	case <-sigChan:
-		fmt.Println("\nStopping port forward...")
-		close(stopChan)
 	case err := <-errChan:
 		return err
 	case <-ctx.Done():
 		close(stopChan)
-		return ctx.Err()
+		return nil
 	}
+}
 
-	return nil
+// ResolvePortForward finds the pod to forward a target's ports to, and
+// returns the pairs with the pod's ports. The target is a deployment or
+// custom workload, whose pods listen on the ports, or service/<name>, whose
+// ports are mapped to its pods' target ports.
+func (c *Client) ResolvePortForward(ctx context.Context, namespace, target string, ports []PortPair) (string, []PortPair, error) {
+	name, isService := strings.CutPrefix(target, "service/")
+	if !isService {
+		pods, err := c.ListPods(ctx, namespace, target)
+		if err != nil {
+			return "", nil, err
+		}
+		pod, err := forwardPod(pods, target)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, p := range ports {
+			if udpOnlyPort(pod, p.Remote) {
+				return "", nil, fmt.Errorf("port %d of %s is UDP: %w", p.Remote, pod.Name, errUDPForward)
+			}
+		}
+		return pod.Name, ports, nil
+	}
+
+	svc, err := c.clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", nil, fmt.Errorf("service %s has no selector, forward to one of its pods instead", name)
+	}
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: svc.Spec.Selector}),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	pod, err := forwardPod(pods.Items, target)
+	if err != nil {
+		return "", nil, err
+	}
+	resolved := make([]PortPair, len(ports))
+	for i, p := range ports {
+		podPort, err := servicePodPort(svc, pod, p.Remote)
+		if err != nil {
+			return "", nil, err
+		}
+		resolved[i] = PortPair{Local: p.Local, Remote: podPort}
+	}
+	return pod.Name, resolved, nil
+}
+
+// servicePodPort maps a service's port to the port of one of its pods
+func servicePodPort(svc *corev1.Service, pod *corev1.Pod, port int) (int, error) {
+	udp := false
+	for _, sp := range svc.Spec.Ports {
+		if int(sp.Port) != port {
+			continue
+		}
+		if sp.Protocol == corev1.ProtocolUDP {
+			// A port may be served over both, e.g. DNS
+			udp = true
+			continue
+		}
+		switch {
+		case sp.TargetPort.Type == intstr.String:
+			if containerPort := namedContainerPort(pod, sp.TargetPort.StrVal); containerPort != 0 {
+				return containerPort, nil
+			}
+			return 0, fmt.Errorf("pod %s has no port named %s", pod.Name, sp.TargetPort.StrVal)
+		case sp.TargetPort.IntVal != 0:
+			return int(sp.TargetPort.IntVal), nil
+		}
+		return port, nil
+	}
+	if udp {
+		return 0, fmt.Errorf("port %d of service %s is UDP: %w", port, svc.Name, errUDPForward)
+	}
+	return 0, fmt.Errorf("service %s has no port %d", svc.Name, port)
+}
+
+// udpOnlyPort reports whether a pod declares a port for UDP only, as DNS
+// servers do
+func udpOnlyPort(pod *corev1.Pod, port int) bool {
+	udp := false
+	for _, container := range pod.Spec.Containers {
+		for _, p := range container.Ports {
+			if int(p.ContainerPort) != port {
+				continue
+			}
+			if p.Protocol != corev1.ProtocolUDP {
+				return false
+			}
+			udp = true
+		}
+	}
+	return udp
+}
+
+// forwardPod picks a running pod to forward to, a ready one if possible
+func forwardPod(pods []corev1.Pod, target string) (*corev1.Pod, error) {
+	var running *corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return pod, nil
+			}
+		}
+		if running == nil {
+			running = pod
+		}
+	}
+	if running == nil {
+		return nil, fmt.Errorf("no running pod of %s", target)
+	}
+	return running, nil
+}
+
+// namedContainerPort returns the number of a pod's port by name, 0 if no
+// container declares it
+func namedContainerPort(pod *corev1.Pod, name string) int {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == name {
+				return int(port.ContainerPort)
+			}
+		}
+	}
+	return 0
 }
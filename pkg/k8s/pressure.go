@@ -0,0 +1,158 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ContainerPressure is a container of one pod with its resources, current
+// usage and how it last terminated
+type ContainerPressure struct {
+	Pod       string
+	Container string
+
+	MemoryRequest, MemoryLimit *resource.Quantity // nil if not set
+	CPURequest, CPULimit       *resource.Quantity
+	MemoryUsage, CPUUsage      *resource.Quantity // nil without metrics
+
+	Restarts       int32
+	State          string // e.g. Running, Waiting: CrashLoopBackOff
+	LastReason     string // reason of the last termination, e.g. OOMKilled
+	LastExitCode   int32
+	LastSignal     int32
+	LastFinishedAt time.Time
+}
+
+// OOMKilled reports whether the container was last killed for exceeding
+// its memory limit
+func (p ContainerPressure) OOMKilled() bool {
+	return p.LastReason == "OOMKilled"
+}
+
+// MemoryPercent is the memory usage in percent of the limit, -1 if either
+// is unknown
+func (p ContainerPressure) MemoryPercent() int {
+	return usagePercent(p.MemoryUsage, p.MemoryLimit)
+}
+
+// CPUPercent is the CPU usage in percent of the limit, -1 if either is
+// unknown
+func (p ContainerPressure) CPUPercent() int {
+	return usagePercent(p.CPUUsage, p.CPULimit)
+}
+
+func usagePercent(usage, limit *resource.Quantity) int {
+	if usage == nil || limit == nil || limit.IsZero() {
+		return -1
+	}
+	return int(usage.MilliValue() * 100 / limit.MilliValue())
+}
+
+// ResourcePressure is the resource situation of a deployment's pods
+type ResourcePressure struct {
+	Containers []ContainerPressure // by pod, then container
+	// MetricsErr says why usage is missing, e.g. no metrics-server
+	MetricsErr error
+}
+
+// GetResourcePressure collects, for every container of the deployment's
+// pods, its requests and limits, the usage reported by metrics-server and
+// the reason and exit code of its last termination
+func (c *Client) GetResourcePressure(ctx context.Context, namespace, deploymentName string) (*ResourcePressure, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+	selector := metav1.FormatLabelSelector(deployment.Spec.Selector)
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	usage, metricsErr := c.podUsage(ctx, namespace, selector)
+
+	pressure := &ResourcePressure{MetricsErr: metricsErr}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		for _, container := range pod.Spec.Containers {
+			p := ContainerPressure{
+				Pod:           pod.Name,
+				Container:     container.Name,
+				MemoryRequest: quantity(container.Resources.Requests, corev1.ResourceMemory),
+				MemoryLimit:   quantity(container.Resources.Limits, corev1.ResourceMemory),
+				CPURequest:    quantity(container.Resources.Requests, corev1.ResourceCPU),
+				CPULimit:      quantity(container.Resources.Limits, corev1.ResourceCPU),
+			}
+			if u, ok := usage[pod.Name+"/"+container.Name]; ok {
+				p.MemoryUsage = quantity(u, corev1.ResourceMemory)
+				p.CPUUsage = quantity(u, corev1.ResourceCPU)
+			}
+			if status := containerStatus(pod, container.Name); status != nil {
+				p.Restarts = status.RestartCount
+				p.State = statusState(status)
+				if last := status.LastTerminationState.Terminated; last != nil {
+					p.LastReason = last.Reason
+					p.LastExitCode = last.ExitCode
+					p.LastSignal = last.Signal
+					p.LastFinishedAt = last.FinishedAt.Time
+				}
+			}
+			pressure.Containers = append(pressure.Containers, p)
+		}
+	}
+	return pressure, nil
+}
+
+func quantity(list corev1.ResourceList, name corev1.ResourceName) *resource.Quantity {
+	q, ok := list[name]
+	if !ok {
+		return nil
+	}
+	return &q
+}
+
+// podMetricsList is the part of metrics.k8s.io's PodMetricsList khelper reads
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Containers []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// podUsage returns the current usage of the selected pods' containers from
+// metrics-server, keyed by "pod/container"
+func (c *Client) podUsage(ctx context.Context, namespace, selector string) (map[string]corev1.ResourceList, error) {
+	rest := c.clientset.Discovery().RESTClient()
+	if rest == nil {
+		return nil, errors.New("metrics are not available without a cluster connection")
+	}
+	raw, err := rest.Get().AbsPath("/apis/metrics.k8s.io/v1beta1", "namespaces", namespace, "pods").
+		Param("labelSelector", selector).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod metrics, is metrics-server installed? %w", err)
+	}
+	var list podMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to read pod metrics: %w", err)
+	}
+	usage := make(map[string]corev1.ResourceList)
+	for _, pod := range list.Items {
+		for _, container := range pod.Containers {
+			usage[pod.Metadata.Name+"/"+container.Name] = container.Usage
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MetricsQuery is a PromQL range query
+type MetricsQuery struct {
+	// URL is an http(s) URL of Prometheus, or
+	// service/<namespace>/<name>[:<port>] to reach it through the API server
+	URL      string
+	Username string
+	Password string // sent as a bearer token if there is no username
+	Query    string
+	Start    time.Time
+	End      time.Time
+	Step     time.Duration
+}
+
+// MetricSeries is a series of a range query result
+type MetricSeries struct {
+	Labels map[string]string
+	Values []float64 // one per step from the start, NaN without a sample
+}
+
+// prometheusResponse is the JSON answer of the Prometheus HTTP API
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string    `json:"metric"`
+			Values [][2]json.RawMessage `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// QueryPrometheus runs a range query, directly against an http(s) URL or
+// through the API server's proxy to a service
+func (c *Client) QueryPrometheus(ctx context.Context, q MetricsQuery) ([]MetricSeries, error) {
+	params := url.Values{}
+	params.Set("query", q.Query)
+	params.Set("start", strconv.FormatInt(q.Start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(q.End.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(q.Step.Seconds(), 'f', -1, 64))
+
+	body, err := c.getAPI(ctx, apiRequest{
+		base:       q.URL,
+		path:       "api/v1/query_range",
+		params:     params,
+		username:   q.Username,
+		password:   q.Password,
+		name:       "Prometheus",
+		credential: "prometheus",
+	})
+	if err != nil && len(body) == 0 {
+		return nil, err
+	}
+
+	var resp prometheusResponse
+	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read the Prometheus answer: %w", jsonErr)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("prometheus: %s: %s", resp.ErrorType, resp.Error)
+	}
+	if resp.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("prometheus answered a %s instead of a range", resp.Data.ResultType)
+	}
+
+	steps := int(q.End.Sub(q.Start)/q.Step) + 1
+	var series []MetricSeries
+	for _, result := range resp.Data.Result {
+		s := MetricSeries{Labels: result.Metric, Values: make([]float64, steps)}
+		for i := range s.Values {
+			s.Values[i] = math.NaN()
+		}
+		for _, sample := range result.Values {
+			var ts float64
+			var value string
+			if json.Unmarshal(sample[0], &ts) != nil || json.Unmarshal(sample[1], &value) != nil {
+				continue
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			i := int(math.Round((ts - float64(q.Start.Unix())) / q.Step.Seconds()))
+			if err == nil && i >= 0 && i < steps {
+				s.Values[i] = v
+			}
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
@@ -0,0 +1,373 @@
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// pullCheckTimeout bounds the registry requests checking one image
+const pullCheckTimeout = 15 * time.Second
+
+// pullErrorReasons are the waiting reasons of containers whose image the
+// kubelet could not pull
+var pullErrorReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+	"InvalidImageName": true,
+}
+
+// RegistryLogin is a login to a registry kept in a pull secret
+type RegistryLogin struct {
+	// Server is the key of the login in the docker config, e.g. ghcr.io
+	// or https://index.docker.io/v1/
+	Server   string
+	Username string
+	Password string
+}
+
+// PullSecretCheck is an image pull secret the pods reference
+type PullSecretCheck struct {
+	Name string
+	// From is where it is referenced: the pod template or the service
+	// account
+	From    string
+	Missing bool
+	// Err tells why an existing secret holds no logins, e.g. a wrong type
+	Err    error
+	Logins []RegistryLogin
+}
+
+// ImagePullCheck is the pull of an image tried against its registry with
+// the logins of the pull secrets, as the kubelet would
+type ImagePullCheck struct {
+	Container string
+	Image     string
+	Registry  string
+	// Secret is the pull secret whose login pulled the image, or was last
+	// tried; empty for an anonymous pull
+	Secret string
+	// Denied is set when the registry refused the login or asked for one
+	Denied bool
+	Err    error
+}
+
+// PullSecretReport is what CheckPullSecrets found for a workload
+type PullSecretReport struct {
+	Namespace      string
+	Workload       string
+	ServiceAccount string
+	Secrets        []PullSecretCheck
+	Images         []ImagePullCheck
+	// PullErrors are the pods' containers waiting on a failed pull, e.g.
+	// "web-7d4b9-x2x7q/app: ImagePullBackOff: ..."
+	PullErrors []string
+}
+
+// Missing returns the names of the referenced pull secrets that do not
+// exist
+func (r *PullSecretReport) Missing() []string {
+	var names []string
+	for _, s := range r.Secrets {
+		if s.Missing {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// References reports whether the pod template or service account already
+// references a pull secret
+func (r *PullSecretReport) References(name string) bool {
+	for _, s := range r.Secrets {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeniedRegistries returns the registries that refused the pull of an image,
+// in the order of the images
+func (r *PullSecretReport) DeniedRegistries() []string {
+	var registries []string
+	seen := map[string]bool{}
+	for _, image := range r.Images {
+		if image.Denied && !seen[image.Registry] {
+			seen[image.Registry] = true
+			registries = append(registries, image.Registry)
+		}
+	}
+	return registries
+}
+
+// CheckPullSecrets checks the image pull secrets of a workload's pods, from
+// its template and service account: that they exist, and that their logins
+// pull each image of the template from its registry. The pods waiting on a
+// failed pull are listed too.
+func (c *Client) CheckPullSecrets(ctx context.Context, namespace, ref string) (*PullSecretReport, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+	spec := deployment.Spec.Template.Spec
+	report := &PullSecretReport{Namespace: namespace, Workload: ref, ServiceAccount: spec.ServiceAccountName}
+	if report.ServiceAccount == "" {
+		report.ServiceAccount = "default"
+	}
+
+	references := make([]PullSecretCheck, 0, len(spec.ImagePullSecrets))
+	for _, s := range spec.ImagePullSecrets {
+		references = append(references, PullSecretCheck{Name: s.Name, From: "pod template"})
+	}
+	account, err := c.clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, report.ServiceAccount, metav1.GetOptions{})
+	if err == nil {
+		for _, s := range account.ImagePullSecrets {
+			references = append(references, PullSecretCheck{Name: s.Name, From: "service account " + report.ServiceAccount})
+		}
+	}
+	seen := map[string]bool{}
+	for _, check := range references {
+		if check.Name == "" || seen[check.Name] {
+			continue
+		}
+		seen[check.Name] = true
+		secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, check.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			check.Missing = true
+		case err != nil:
+			check.Err = err
+		default:
+			check.Logins, check.Err = readDockerConfig(secret)
+		}
+		report.Secrets = append(report.Secrets, check)
+	}
+
+	for _, container := range append(spec.InitContainers, spec.Containers...) {
+		report.Images = append(report.Images, checkImagePull(ctx, container.Name, container.Image, report.Secrets))
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(deployment.Spec.Selector)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if w := status.State.Waiting; w != nil && pullErrorReasons[w.Reason] {
+				report.PullErrors = append(report.PullErrors, fmt.Sprintf("%s/%s: %s: %s", pod.Name, status.Name, w.Reason, w.Message))
+			}
+		}
+	}
+	sort.Strings(report.PullErrors)
+	return report, nil
+}
+
+// checkImagePull pulls the manifest of an image with each login of the pull
+// secrets matching its registry, or anonymously without any
+func checkImagePull(ctx context.Context, container, image string, secrets []PullSecretCheck) ImagePullCheck {
+	ref := parseImageReference(image)
+	check := ImagePullCheck{Container: container, Image: image, Registry: ref.registry}
+	for _, secret := range secrets {
+		for _, login := range secret.Logins {
+			if !loginMatches(login.Server, ref) {
+				continue
+			}
+			check.Secret = secret.Name
+			check.Err = pullManifest(ctx, ref, login.Username, login.Password)
+			if check.Err == nil {
+				check.Denied = false
+				return check
+			}
+			check.Denied = registryDenied(check.Err)
+			check.Err = fmt.Errorf("secret %s: %w", secret.Name, check.Err)
+		}
+	}
+	if check.Secret == "" {
+		check.Err = pullManifest(ctx, ref, "", "")
+		check.Denied = registryDenied(check.Err)
+	}
+	return check
+}
+
+// pullManifest fetches the manifest of an image as a pull starts, with a
+// login unless username and password are empty
+func pullManifest(ctx context.Context, ref imageReference, username, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, pullCheckTimeout)
+	defer cancel()
+	var auth RegistryAuth
+	if username != "" || password != "" {
+		auth = func(string) (string, string, bool) { return username, password, true }
+	}
+	registry := newRegistryClient(ref, auth)
+	registry.deniedHint = "the pods need a pull secret with a login to it"
+	resp, err := registry.get(ctx, "/manifests/"+ref.reference, manifestMediaTypes...)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// readDockerConfig reads the logins of a docker-registry secret, in the
+// .dockerconfigjson or legacy .dockercfg format
+func readDockerConfig(secret *corev1.Secret) ([]RegistryLogin, error) {
+	type entry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	var auths map[string]entry
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var config struct {
+			Auths map[string]entry `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", corev1.DockerConfigJsonKey, err)
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", corev1.DockerConfigKey, err)
+		}
+	default:
+		return nil, fmt.Errorf("type %s is not a docker-registry secret, the kubelet ignores it", secret.Type)
+	}
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("no registry logins")
+	}
+
+	logins := make([]RegistryLogin, 0, len(auths))
+	for server, e := range auths {
+		login := RegistryLogin{Server: server, Username: e.Username, Password: e.Password}
+		if login.Username == "" && login.Password == "" && e.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(e.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth of %s: %w", server, err)
+			}
+			login.Username, login.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		logins = append(logins, login)
+	}
+	sort.Slice(logins, func(i, j int) bool { return logins[i].Server < logins[j].Server })
+	return logins, nil
+}
+
+// loginMatches reports whether the kubelet uses a docker config login for
+// an image: same host, with wildcards such as *.example.com, and the
+// repository under its path if it has one
+func loginMatches(server string, ref imageReference) bool {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, prefix, _ := strings.Cut(server, "/")
+	switch host {
+	case dockerHub, "index.docker.io", dockerHubRegistry:
+		// https://index.docker.io/v1/ is the Docker Hub login of docker login
+		host = dockerHub
+		if prefix == "v1/" || prefix == "v1" || prefix == "v2/" || prefix == "v2" {
+			prefix = ""
+		}
+	}
+	hostParts, registryParts := strings.Split(host, "."), strings.Split(ref.registry, ".")
+	if len(hostParts) != len(registryParts) {
+		return false
+	}
+	for i, part := range hostParts {
+		if ok, _ := path.Match(part, registryParts[i]); !ok {
+			return false
+		}
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || ref.repository == prefix || strings.HasPrefix(ref.repository, prefix+"/")
+}
+
+// PullSecret is a docker-registry secret holding registry logins
+type PullSecret struct {
+	Name   string
+	Logins []RegistryLogin
+}
+
+// CreatePullSecret creates a docker-registry secret, like kubectl create
+// secret docker-registry
+func (c *Client) CreatePullSecret(ctx context.Context, namespace string, s PullSecret) error {
+	type entry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	config := struct {
+		Auths map[string]entry `json:"auths"`
+	}{Auths: map[string]entry{}}
+	for _, login := range s.Logins {
+		config.Auths[login.Server] = entry{
+			Username: login.Username,
+			Password: login.Password,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(login.Username + ":" + login.Password)),
+		}
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the docker config: %w", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: namespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: data},
+	}
+	if _, err := c.clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create secret %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// CheckLogins pulls each image with the login of the secret for its
+// registry, if it has one, so a mistyped password is found before the
+// secret is created
+func (s PullSecret) CheckLogins(ctx context.Context, images []string) error {
+	for _, image := range images {
+		ref := parseImageReference(image)
+		for _, login := range s.Logins {
+			if !loginMatches(login.Server, ref) {
+				continue
+			}
+			if err := pullManifest(ctx, ref, login.Username, login.Password); err != nil {
+				return fmt.Errorf("the login to %s does not pull %s: %w", login.Server, image, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// LoginServer is the docker config key for the login to a registry as
+// named in image references, the one docker login writes for Docker Hub
+func LoginServer(registry string) string {
+	if registry == dockerHub {
+		return "https://index.docker.io/v1/"
+	}
+	return registry
+}
+
+// PullSecretPatch is the strategic merge patch adding a pull secret to the
+// pod template; imagePullSecrets merge by name
+func PullSecretPatch(name string) Patch {
+	data, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"imagePullSecrets": []map[string]string{{"name": name}},
+				},
+			},
+		},
+	})
+	return Patch{Type: PatchStrategic, Data: data}
+}
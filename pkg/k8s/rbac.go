@@ -0,0 +1,203 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// permission is what a set of rules allows on one resource
+type permission struct {
+	verbs map[string]bool
+	from  map[string]bool
+}
+
+// DescribeRBAC summarizes the RBAC of a deployment's pods: their service
+// account, the RoleBindings and ClusterRoleBindings that apply to it, and a
+// condensed list of the verbs allowed per resource
+func (c *Client) DescribeRBAC(ctx context.Context, namespace, deploymentName string) (string, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return "", err
+	}
+	spec := deployment.Spec.Template.Spec
+	saName := spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+
+	w := newDescribeWriter()
+	w.line(0, "Service Account:\t%s/%s", namespace, saName)
+	if sa, err := c.clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, saName, metav1.GetOptions{}); err != nil {
+		w.line(0, "\t<%v>", err)
+	} else {
+		automount := "true"
+		if spec.AutomountServiceAccountToken != nil {
+			automount = fmt.Sprintf("%v (pod)", *spec.AutomountServiceAccountToken)
+		} else if sa.AutomountServiceAccountToken != nil {
+			automount = fmt.Sprintf("%v (service account)", *sa.AutomountServiceAccountToken)
+		}
+		w.line(0, "Token automount:\t%s", automount)
+	}
+
+	namespaced := map[string]*permission{}
+	clusterWide := map[string]*permission{}
+	var bindings []string
+
+	roleBindings, err := c.clientset.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		bindings = append(bindings, fmt.Sprintf("<cannot list RoleBindings: %v>", err))
+	} else {
+		for _, rb := range roleBindings.Items {
+			via, ok := subjectMatches(rb.Subjects, namespace, saName)
+			if !ok {
+				continue
+			}
+			name := fmt.Sprintf("RoleBinding/%s -> %s/%s%s", rb.Name, rb.RoleRef.Kind, rb.RoleRef.Name, via)
+			bindings = append(bindings, name)
+			rules, err := c.roleRules(ctx, namespace, rb.RoleRef)
+			if err != nil {
+				bindings = append(bindings, fmt.Sprintf("  <cannot read %s/%s: %v>", rb.RoleRef.Kind, rb.RoleRef.Name, err))
+				continue
+			}
+			addRules(namespaced, rules, rb.RoleRef.Kind+"/"+rb.RoleRef.Name)
+		}
+	}
+
+	clusterBindings, err := c.clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		bindings = append(bindings, fmt.Sprintf("<cannot list ClusterRoleBindings: %v>", err))
+	} else {
+		for _, crb := range clusterBindings.Items {
+			via, ok := subjectMatches(crb.Subjects, namespace, saName)
+			if !ok {
+				continue
+			}
+			bindings = append(bindings, fmt.Sprintf("ClusterRoleBinding/%s -> ClusterRole/%s%s", crb.Name, crb.RoleRef.Name, via))
+			rules, err := c.roleRules(ctx, "", crb.RoleRef)
+			if err != nil {
+				bindings = append(bindings, fmt.Sprintf("  <cannot read ClusterRole/%s: %v>", crb.RoleRef.Name, err))
+				continue
+			}
+			addRules(clusterWide, rules, "ClusterRole/"+crb.RoleRef.Name)
+		}
+	}
+
+	if len(bindings) == 0 {
+		w.line(0, "Bindings:\t<none>")
+	} else {
+		w.line(0, "Bindings:")
+		for _, b := range bindings {
+			w.line(1, "%s", b)
+		}
+	}
+
+	describePermissions(w, fmt.Sprintf("Allowed in namespace %s", namespace), namespaced)
+	describePermissions(w, "Allowed cluster-wide", clusterWide)
+	return w.String(), nil
+}
+
+// subjectMatches reports whether a binding applies to the service account,
+// directly or through one of its groups, and how
+func subjectMatches(subjects []rbacv1.Subject, namespace, saName string) (string, bool) {
+	for _, s := range subjects {
+		switch {
+		case s.Kind == rbacv1.ServiceAccountKind && s.Name == saName && s.Namespace == namespace:
+			return "", true
+		case s.Kind == rbacv1.GroupKind && (s.Name == "system:serviceaccounts" ||
+			s.Name == "system:serviceaccounts:"+namespace || s.Name == "system:authenticated"):
+			return " (via group " + s.Name + ")", true
+		}
+	}
+	return "", false
+}
+
+// roleRules returns the rules of the Role or ClusterRole a binding refers to
+func (c *Client) roleRules(ctx context.Context, namespace string, ref rbacv1.RoleRef) ([]rbacv1.PolicyRule, error) {
+	if ref.Kind == "Role" {
+		role, err := c.clientset.RbacV1().Roles(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return role.Rules, nil
+	}
+	role, err := c.clientset.RbacV1().ClusterRoles().Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return role.Rules, nil
+}
+
+// addRules merges policy rules into per-resource permissions, keyed by
+// group/resource and any resource names the rule is limited to
+func addRules(perms map[string]*permission, rules []rbacv1.PolicyRule, from string) {
+	for _, rule := range rules {
+		var keys []string
+		for _, group := range orAll(rule.APIGroups) {
+			for _, resource := range rule.Resources {
+				key := resource
+				if group != "" {
+					key = resource + "." + group
+				}
+				if len(rule.ResourceNames) > 0 {
+					key += " [" + strings.Join(rule.ResourceNames, ",") + "]"
+				}
+				keys = append(keys, key)
+			}
+		}
+		for _, url := range rule.NonResourceURLs {
+			keys = append(keys, "url "+url)
+		}
+
+		for _, key := range keys {
+			p := perms[key]
+			if p == nil {
+				p = &permission{verbs: map[string]bool{}, from: map[string]bool{}}
+				perms[key] = p
+			}
+			for _, verb := range rule.Verbs {
+				p.verbs[verb] = true
+			}
+			p.from[from] = true
+		}
+	}
+}
+
+func orAll(groups []string) []string {
+	if len(groups) == 0 {
+		return []string{""}
+	}
+	return groups
+}
+
+func describePermissions(w *describeWriter, title string, perms map[string]*permission) {
+	if len(perms) == 0 {
+		w.line(0, "%s:\t<none>", title)
+		return
+	}
+	w.line(0, "%s:", title)
+	w.line(1, "Resource\tVerbs\tFrom")
+	w.line(1, "--------\t-----\t----")
+
+	keys := make([]string, 0, len(perms))
+	for k := range perms {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		w.line(1, "%s\t%s\t%s", k, strings.Join(sortedKeys(perms[k].verbs), ","), strings.Join(sortedKeys(perms[k].from), ", "))
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,355 @@
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Docker Hub is addressed as docker.io in image references
+const (
+	dockerHub         = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// Manifest media types a pull accepts, image lists and single images
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// RegistryAuth returns the username and password for a registry, as named
+// in image references (e.g. docker.io, ghcr.io); false pulls anonymously
+type RegistryAuth func(registry string) (username, password string, ok bool)
+
+// imageReference is an image reference split into its parts
+type imageReference struct {
+	registry   string // as written, docker.io if none
+	repository string
+	reference  string // digest if known, else the tag
+}
+
+// parseImageReference splits an image such as "nginx:1.25",
+// "ghcr.io/org/app@sha256:..." or "localhost:5000/app"
+func parseImageReference(image string) imageReference {
+	ref := imageReference{registry: dockerHub, reference: "latest"}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.reference = name[i+1:]
+		name = name[:i]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.reference = name[i+1:]
+		name = name[:i]
+	}
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.registry, name = first, rest
+	}
+	if ref.registry == dockerHub && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.repository = name
+	return ref
+}
+
+// String renders the reference, e.g. "docker.io/library/nginx@sha256:..."
+func (r imageReference) String() string {
+	if strings.Contains(r.reference, ":") {
+		return r.registry + "/" + r.repository + "@" + r.reference
+	}
+	return r.registry + "/" + r.repository + ":" + r.reference
+}
+
+// host is the registry's API host, Docker Hub's is not docker.io
+func (r imageReference) host() string {
+	if r.registry == dockerHub {
+		return dockerHubRegistry
+	}
+	return r.registry
+}
+
+// registryDescriptor points to a manifest or blob
+type registryDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+// registryManifest is an image manifest or, with Manifests, an image list
+type registryManifest struct {
+	MediaType string               `json:"mediaType"`
+	Manifests []registryDescriptor `json:"manifests"`
+	Layers    []registryDescriptor `json:"layers"`
+}
+
+// registryClient pulls from one repository of a registry with the
+// distribution API, answering token and basic auth challenges
+type registryClient struct {
+	http  *http.Client
+	ref   imageReference
+	auth  RegistryAuth
+	token string // bearer token, or "basic" once basic auth is asked for
+	// deniedHint follows a pull denied without login, by default how to
+	// store one in the khelper credentials
+	deniedHint string
+}
+
+// registryError is a request a registry refused, with its HTTP status
+type registryError struct {
+	status  int
+	message string
+}
+
+func (e *registryError) Error() string {
+	return e.message
+}
+
+// registryDenied reports whether a registry refused to authenticate
+func registryDenied(err error) bool {
+	var refused *registryError
+	return errors.As(err, &refused) && (refused.status == http.StatusUnauthorized || refused.status == http.StatusForbidden)
+}
+
+// newRegistryClient returns a client for the repository of an image
+func newRegistryClient(ref imageReference, auth RegistryAuth) *registryClient {
+	return &registryClient{http: &http.Client{Timeout: 5 * time.Minute}, ref: ref, auth: auth}
+}
+
+// get requests a path of the repository's API, authenticating once when the
+// registry asks for it
+func (r *registryClient) get(ctx context.Context, apiPath string, accept ...string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+r.ref.host()+"/v2/"+r.ref.repository+apiPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		switch {
+		case r.token == "basic":
+			if user, password, ok := r.login(); ok {
+				req.SetBasicAuth(user, password)
+			}
+		case r.token != "":
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		}
+		resp, err := r.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach registry %s: %w", r.ref.registry, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := r.authenticate(ctx, challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, r.statusError(resp.StatusCode)
+		}
+		return resp, nil
+	}
+}
+
+// statusError explains a failed registry request
+func (r *registryClient) statusError(status int) error {
+	_, _, loggedIn := r.login()
+	var message string
+	switch {
+	case (status == http.StatusUnauthorized || status == http.StatusForbidden) && !loggedIn:
+		hint := r.deniedHint
+		if hint == "" {
+			hint = fmt.Sprintf("store a login with 'khelper credentials set registry/%s'", r.ref.registry)
+		}
+		message = fmt.Sprintf("registry %s denied the pull of %s; %s", r.ref.registry, r.ref.repository, hint)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		message = fmt.Sprintf("registry %s denied the pull of %s with the stored credentials", r.ref.registry, r.ref.repository)
+	case status == http.StatusNotFound:
+		message = fmt.Sprintf("%s not found in registry %s", r.ref, r.ref.registry)
+	default:
+		message = fmt.Sprintf("registry %s answered %d %s", r.ref.registry, status, http.StatusText(status))
+	}
+	return &registryError{status: status, message: message}
+}
+
+// login returns the stored credentials of the registry
+func (r *registryClient) login() (string, string, bool) {
+	if r.auth == nil {
+		return "", "", false
+	}
+	return r.auth(r.ref.registry)
+}
+
+// authenticate answers a WWW-Authenticate challenge: basic auth is sent
+// with the stored credentials, a bearer token is fetched for pulling
+func (r *registryClient) authenticate(ctx context.Context, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "basic") {
+		r.token = "basic"
+		return nil
+	}
+	if !strings.EqualFold(scheme, "bearer") {
+		return fmt.Errorf("registry %s asks for unsupported authentication %q", r.ref.registry, scheme)
+	}
+	values := challengeParams(params)
+	realm, err := url.Parse(values["realm"])
+	if err != nil || values["realm"] == "" {
+		return fmt.Errorf("registry %s sent an invalid token realm %q", r.ref.registry, values["realm"])
+	}
+	query := realm.Query()
+	if service := values["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+r.ref.repository+":pull")
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if user, password, ok := r.login(); ok {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get a token from registry %s: %w", r.ref.registry, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return r.statusError(resp.StatusCode)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to read the token of registry %s: %w", r.ref.registry, err)
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	return nil
+}
+
+// challengeParams parses the key="value" pairs of a challenge
+func challengeParams(params string) map[string]string {
+	values := make(map[string]string)
+	for params != "" {
+		var pair string
+		// Commas inside quotes, e.g. in scopes, do not end a pair
+		inQuotes, end := false, len(params)
+		for i, ch := range params {
+			if ch == '"' {
+				inQuotes = !inQuotes
+			} else if ch == ',' && !inQuotes {
+				end = i
+				break
+			}
+		}
+		pair, params = params[:end], strings.TrimPrefix(params[end:], ",")
+		if key, value, ok := strings.Cut(strings.TrimSpace(pair), "="); ok {
+			values[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return values
+}
+
+// manifest fetches the image manifest of a reference, picking the platform
+// from an image list
+func (r *registryClient) manifest(ctx context.Context, reference, goos, goarch string) (*registryManifest, error) {
+	for {
+		resp, err := r.get(ctx, "/manifests/"+reference, manifestMediaTypes...)
+		if err != nil {
+			return nil, err
+		}
+		var manifest registryManifest
+		err = json.NewDecoder(resp.Body).Decode(&manifest)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the manifest of %s: %w", r.ref, err)
+		}
+		if len(manifest.Manifests) == 0 {
+			return &manifest, nil
+		}
+
+		reference = ""
+		var platforms []string
+		for _, m := range manifest.Manifests {
+			if m.Platform == nil {
+				continue
+			}
+			if m.Platform.OS == goos && m.Platform.Architecture == goarch {
+				reference = m.Digest
+				break
+			}
+			platforms = append(platforms, m.Platform.OS+"/"+m.Platform.Architecture)
+		}
+		if reference == "" {
+			return nil, fmt.Errorf("%s has no %s/%s image, only %s", r.ref, goos, goarch, strings.Join(platforms, ", "))
+		}
+	}
+}
+
+// blob downloads a blob into the cache directory unless it is there,
+// checking its digest, and returns the cached file. A cached blob's
+// modification time is renewed, marking when it was last used.
+func (r *registryClient) blob(ctx context.Context, cacheDir, digest string) (string, error) {
+	algorithm, hash, ok := strings.Cut(digest, ":")
+	if !ok || algorithm != "sha256" {
+		return "", fmt.Errorf("unsupported layer digest %s", digest)
+	}
+	file := filepath.Join(cacheDir, algorithm+"-"+hash)
+	if _, err := os.Stat(file); err == nil {
+		now := time.Now()
+		_ = os.Chtimes(file, now, now)
+		return file, nil
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create the image cache: %w", err)
+	}
+
+	resp, err := r.get(ctx, "/blobs/"+digest)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	tmp, err := os.CreateTemp(cacheDir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create the image cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	sum := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, sum), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download layer %s: %w", digest, err)
+	}
+	if got := hex.EncodeToString(sum.Sum(nil)); got != hash {
+		return "", fmt.Errorf("layer %s arrived with digest sha256:%s", digest, got)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", fmt.Errorf("failed to cache layer %s: %w", digest, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,503 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// RenameWaitTimeout is how long a rename waits for a deployment to be ready
+const RenameWaitTimeout = 5 * time.Minute
+
+// renamePollInterval is how often a rename checks the rollout it waits for
+const renamePollInterval = 2 * time.Second
+
+// RenameStep is a step of renaming a deployment, in the order they run
+type RenameStep int
+
+const (
+	RenameCopy      RenameStep = iota // create the copy under the new name
+	RenameWait                        // wait for the copy to be ready
+	RenameSwitch                      // point the services at the copy's pods
+	RenameRetarget                    // point the HPAs at the copy
+	RenameScaleDown                   // scale the original down to 0
+	RenameDelete                      // delete the original
+	RenameDone
+)
+
+// renameDroppedAnnotations are not copied: they describe the original's
+// rollouts, not the copy's
+var renameDroppedAnnotations = []string{
+	revisionAnnotation,
+	revisionHistoryAnnotation,
+	"kubectl.kubernetes.io/last-applied-configuration",
+	ChangeCauseAnnotation,
+}
+
+// ServiceSwitch is a service a rename points at the copy's pods
+type ServiceSwitch struct {
+	Name   string
+	Before map[string]string
+	After  map[string]string
+	// Switched is set while the service selects the copy
+	Switched bool
+}
+
+// HPARetarget is an HPA a rename points at the copy
+type HPARetarget struct {
+	Name string
+	// Retargeted is set while the HPA scales the copy
+	Retargeted bool
+}
+
+// Rename is a deployment being renamed: copied under the new name, switched
+// to once ready, then scaled down and deleted. It keeps what rolling back
+// the steps done so far needs.
+type Rename struct {
+	Namespace string
+	From, To  string
+	// Labels are the selector labels whose value is the old name, e.g. app,
+	// which take the new name so the copy's pods can be told apart
+	Labels   []string
+	Original *appsv1.Deployment
+	Replicas int32
+	Services []ServiceSwitch
+	// Unswitched are services that select the copy's pods as well, as their
+	// selector has none of Labels
+	Unswitched []string
+	// Ingresses route to the switched services and follow them
+	Ingresses []string
+	// HPAs scale the original and are retargeted to the copy
+	HPAs []HPARetarget
+	// Unmatched are the PodDisruptionBudgets and NetworkPolicies that select
+	// the original's pods by the renamed labels, so they stop matching once
+	// it is gone, e.g. "PodDisruptionBudget web"
+	Unmatched []string
+	// Next is the step to run next, RenameDone when finished
+	Next RenameStep
+	// RollingBack is set once a rollback started, until it is done
+	RollingBack bool
+}
+
+// PlanRename checks a deployment can be renamed and finds the services to
+// switch. The selector must have a label with the deployment's name as its
+// value, e.g. app: web, so the pods of the copy get a selector of their own.
+func (c *Client) PlanRename(ctx context.Context, namespace, from, to string) (*Rename, error) {
+	if kind, workloadName := parseWorkloadRef(from); kind != nil {
+		return nil, errDeploymentOnly("rename", kind, workloadName)
+	}
+	if to == from {
+		return nil, fmt.Errorf("the new name is the current one")
+	}
+	if errs := validation.IsDNS1123Subdomain(to); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid name %q: %s", to, strings.Join(errs, "; "))
+	}
+	original, err := c.GetDeployment(ctx, namespace, from)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.GetDeployment(ctx, namespace, to); err == nil {
+		return nil, fmt.Errorf("deployment %s already exists in %s", to, namespace)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	r := &Rename{Namespace: namespace, From: from, To: to, Original: original, Replicas: 1}
+	if original.Spec.Replicas != nil {
+		r.Replicas = *original.Spec.Replicas
+	}
+	if original.Spec.Selector != nil {
+		for key, value := range original.Spec.Selector.MatchLabels {
+			if value == from {
+				r.Labels = append(r.Labels, key)
+			}
+		}
+	}
+	if len(r.Labels) == 0 {
+		return nil, fmt.Errorf("the selector of %s has no label with the value %s, such as app: %s, so the pods of a copy could not be told apart from its own", from, from, from)
+	}
+	sort.Strings(r.Labels)
+
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	switched := make(map[string]bool)
+	for _, name := range selectingServices(services.Items, original.Spec.Template.Labels) {
+		svc := findService(services.Items, name)
+		after := r.renameLabels(svc.Spec.Selector)
+		if labelsEqual(after, svc.Spec.Selector) {
+			r.Unswitched = append(r.Unswitched, name)
+			continue
+		}
+		r.Services = append(r.Services, ServiceSwitch{Name: name, Before: svc.Spec.Selector, After: after})
+		switched[name] = true
+	}
+
+	ingresses, err := c.GetIngresses(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ingresses {
+		for _, backend := range ingressBackends(ing) {
+			if switched[backend.Name] {
+				r.Ingresses = append(r.Ingresses, ing.Name)
+				break
+			}
+		}
+	}
+
+	if err := c.planRenameHPAs(ctx, r); err != nil {
+		return nil, err
+	}
+	if err := c.planRenameUnmatched(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// planRenameHPAs finds the HPAs scaling the original
+func (c *Client) planRenameHPAs(ctx context.Context, r *Rename) error {
+	hpas, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(r.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list HPAs: %w", err)
+	}
+	for _, hpa := range hpas.Items {
+		if target := hpa.Spec.ScaleTargetRef; target.Kind == "Deployment" && target.Name == r.From {
+			r.HPAs = append(r.HPAs, HPARetarget{Name: hpa.Name})
+		}
+	}
+	return nil
+}
+
+// planRenameUnmatched finds the PodDisruptionBudgets and NetworkPolicies
+// that select the original's pods, as their own or as a peer in the
+// namespace, but not the copy's
+func (c *Client) planRenameUnmatched(ctx context.Context, r *Rename) error {
+	before := labels.Set(r.Original.Spec.Template.Labels)
+	after := labels.Set(r.renameLabels(r.Original.Spec.Template.Labels))
+	stopsMatching := func(selector *metav1.LabelSelector) bool {
+		s, err := metav1.LabelSelectorAsSelector(selector)
+		return err == nil && selector != nil && !s.Empty() && s.Matches(before) && !s.Matches(after)
+	}
+
+	pdbs, err := c.clientset.PolicyV1().PodDisruptionBudgets(r.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to list PDBs: %w", err)
+	}
+	if err == nil {
+		for _, pdb := range pdbs.Items {
+			if stopsMatching(pdb.Spec.Selector) {
+				r.Unmatched = append(r.Unmatched, "PodDisruptionBudget "+pdb.Name)
+			}
+		}
+	}
+
+	policies, err := c.clientset.NetworkingV1().NetworkPolicies(r.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to list NetworkPolicies: %w", err)
+	}
+	if err != nil {
+		return nil
+	}
+	for _, policy := range policies.Items {
+		selects := stopsMatching(&policy.Spec.PodSelector)
+		for _, rule := range policy.Spec.Ingress {
+			for _, from := range rule.From {
+				selects = selects || from.NamespaceSelector == nil && stopsMatching(from.PodSelector)
+			}
+		}
+		for _, rule := range policy.Spec.Egress {
+			for _, to := range rule.To {
+				selects = selects || to.NamespaceSelector == nil && stopsMatching(to.PodSelector)
+			}
+		}
+		if selects {
+			r.Unmatched = append(r.Unmatched, "NetworkPolicy "+policy.Name)
+		}
+	}
+	return nil
+}
+
+// renameLabels copies labels with the value of Labels renamed
+func (r *Rename) renameLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	renamed := make(map[string]string, len(labels))
+	for key, value := range labels {
+		renamed[key] = value
+	}
+	for _, key := range r.Labels {
+		if renamed[key] == r.From {
+			renamed[key] = r.To
+		}
+	}
+	return renamed
+}
+
+// LabelChange describes the renamed labels, e.g. "app: web → web-v2"
+func (r *Rename) LabelChange() string {
+	changes := make([]string, len(r.Labels))
+	for i, key := range r.Labels {
+		changes[i] = fmt.Sprintf("%s: %s → %s", key, r.From, r.To)
+	}
+	return strings.Join(changes, ", ")
+}
+
+// StepTitle describes a step of the rename
+func (r *Rename) StepTitle(step RenameStep) string {
+	switch step {
+	case RenameCopy:
+		return fmt.Sprintf("Copy %s to %s with %s", r.From, r.To, r.LabelChange())
+	case RenameWait:
+		return fmt.Sprintf("Wait for %s to be ready with %d replica(s)", r.To, r.Replicas)
+	case RenameSwitch:
+		if len(r.Services) == 0 {
+			return "Switch services: none selects only the pods of " + r.From
+		}
+		names := make([]string, len(r.Services))
+		for i, svc := range r.Services {
+			names[i] = svc.Name
+		}
+		return fmt.Sprintf("Switch service(s) %s to the pods of %s", strings.Join(names, ", "), r.To)
+	case RenameRetarget:
+		if len(r.HPAs) == 0 {
+			return "Retarget HPAs: none scales " + r.From
+		}
+		names := make([]string, len(r.HPAs))
+		for i, hpa := range r.HPAs {
+			names[i] = hpa.Name
+		}
+		return fmt.Sprintf("Retarget HPA(s) %s to %s", strings.Join(names, ", "), r.To)
+	case RenameScaleDown:
+		return fmt.Sprintf("Scale %s down from %d to 0", r.From, r.Replicas)
+	case RenameDelete:
+		return "Delete " + r.From
+	}
+	return ""
+}
+
+// RunRenameStep runs the next step of a rename and moves on to the one
+// after it. The change cause in ctx is recorded on the deployments changed.
+func (c *Client) RunRenameStep(ctx context.Context, r *Rename) error {
+	if r.RollingBack {
+		return fmt.Errorf("the rename of %s is being rolled back", r.From)
+	}
+	deployments := c.clientset.AppsV1().Deployments(r.Namespace)
+	switch r.Next {
+	case RenameCopy:
+		clone := r.Original.DeepCopy()
+		clone.ObjectMeta = metav1.ObjectMeta{
+			Name:        r.To,
+			Namespace:   r.Namespace,
+			Labels:      r.renameLabels(r.Original.Labels),
+			Annotations: clone.Annotations,
+		}
+		for _, name := range renameDroppedAnnotations {
+			delete(clone.Annotations, name)
+		}
+		clone.Spec.Replicas = &r.Replicas
+		clone.Spec.Selector.MatchLabels = r.renameLabels(clone.Spec.Selector.MatchLabels)
+		clone.Spec.Template.Labels = r.renameLabels(clone.Spec.Template.Labels)
+		clone.Status = appsv1.DeploymentStatus{}
+		setChangeCause(ctx, clone)
+		if _, err := deployments.Create(ctx, clone, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create deployment %s: %w", r.To, err)
+		}
+
+	case RenameWait:
+		wait, cancel := context.WithTimeout(ctx, RenameWaitTimeout)
+		defer cancel()
+		if err := c.WaitForRollout(wait, r.Namespace, r.To, renamePollInterval, nil); err != nil {
+			return err
+		}
+
+	case RenameSwitch:
+		for i := range r.Services {
+			if err := c.setServiceSelector(ctx, r.Namespace, r.Services[i].Name, r.Services[i].Before, r.Services[i].After); err != nil {
+				return err
+			}
+			r.Services[i].Switched = true
+		}
+
+	case RenameRetarget:
+		for i := range r.HPAs {
+			if err := c.setHPATarget(ctx, r.Namespace, r.HPAs[i].Name, r.From, r.To); err != nil {
+				return err
+			}
+			r.HPAs[i].Retargeted = true
+		}
+
+	case RenameScaleDown:
+		if err := c.setReplicas(ctx, r.Namespace, r.From, 0); err != nil {
+			return err
+		}
+
+	case RenameDelete:
+		if err := deployments.Delete(ctx, r.From, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete deployment %s: %w", r.From, err)
+		}
+
+	default:
+		return fmt.Errorf("the rename of %s is finished", r.From)
+	}
+	r.Next++
+	return nil
+}
+
+// RollbackRename undoes the steps of a rename done so far, last first: the
+// original is recreated or scaled back up and waited for before the
+// services select it again, then the copy is deleted. It returns what it
+// did, also when a step of the rollback failed; it can be run again then.
+func (c *Client) RollbackRename(ctx context.Context, r *Rename) ([]string, error) {
+	r.RollingBack = true
+	var done []string
+	deployments := c.clientset.AppsV1().Deployments(r.Namespace)
+	if r.Next > RenameDelete {
+		original := r.Original.DeepCopy()
+		original.ObjectMeta = metav1.ObjectMeta{
+			Name:        r.From,
+			Namespace:   r.Namespace,
+			Labels:      original.Labels,
+			Annotations: original.Annotations,
+		}
+		delete(original.Annotations, revisionAnnotation)
+		delete(original.Annotations, revisionHistoryAnnotation)
+		original.Status = appsv1.DeploymentStatus{}
+		setChangeCause(ctx, original)
+		if _, err := deployments.Create(ctx, original, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return done, fmt.Errorf("failed to recreate deployment %s: %w", r.From, err)
+		}
+		done = append(done, "Recreated "+r.From)
+	} else if r.Next > RenameScaleDown {
+		if err := c.setReplicas(ctx, r.Namespace, r.From, r.Replicas); err != nil {
+			return done, err
+		}
+		done = append(done, fmt.Sprintf("Scaled %s back up to %d", r.From, r.Replicas))
+	}
+	if r.Next > RenameScaleDown {
+		wait, cancel := context.WithTimeout(ctx, RenameWaitTimeout)
+		defer cancel()
+		if err := c.WaitForRollout(wait, r.Namespace, r.From, renamePollInterval, nil); err != nil {
+			return done, fmt.Errorf("the services still select %s: %w", r.To, err)
+		}
+		done = append(done, r.From+" is ready")
+	}
+	for i := range r.HPAs {
+		hpa := &r.HPAs[i]
+		if !hpa.Retargeted {
+			continue
+		}
+		if err := c.setHPATarget(ctx, r.Namespace, hpa.Name, r.To, r.From); err != nil {
+			return done, err
+		}
+		hpa.Retargeted = false
+		done = append(done, fmt.Sprintf("Retargeted HPA %s back to %s", hpa.Name, r.From))
+	}
+	for i := range r.Services {
+		svc := &r.Services[i]
+		if !svc.Switched {
+			continue
+		}
+		if err := c.setServiceSelector(ctx, r.Namespace, svc.Name, svc.After, svc.Before); err != nil {
+			return done, err
+		}
+		svc.Switched = false
+		done = append(done, fmt.Sprintf("Switched service %s back to %s", svc.Name, r.From))
+	}
+	if r.Next > RenameCopy {
+		if err := deployments.Delete(ctx, r.To, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return done, fmt.Errorf("failed to delete deployment %s: %w", r.To, err)
+		}
+		done = append(done, "Deleted "+r.To)
+	}
+	r.Next, r.RollingBack = RenameCopy, false
+	return done, nil
+}
+
+// setServiceSelector replaces a service's selector, unless it changed since
+// it was read as before
+func (c *Client) setServiceSelector(ctx context.Context, namespace, name string, before, after map[string]string) error {
+	services := c.clientset.CoreV1().Services(namespace)
+	svc, err := services.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get service %s: %w", name, err)
+	}
+	if !labelsEqual(svc.Spec.Selector, before) {
+		return fmt.Errorf("the selector of service %s changed meanwhile, it was left as it is", name)
+	}
+	svc.Spec.Selector = after
+	if _, err := services.Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update service %s: %w", name, err)
+	}
+	return nil
+}
+
+// setHPATarget points an HPA scaling the deployment from at the deployment
+// to, unless its target changed since the rename was planned
+func (c *Client) setHPATarget(ctx context.Context, namespace, name, from, to string) error {
+	hpas := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace)
+	hpa, err := hpas.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get HPA %s: %w", name, err)
+	}
+	if target := hpa.Spec.ScaleTargetRef; target.Kind != "Deployment" || target.Name != from {
+		return fmt.Errorf("the target of HPA %s changed meanwhile, it was left as it is", name)
+	}
+	hpa.Spec.ScaleTargetRef.Name = to
+	if _, err := hpas.Update(ctx, hpa, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update HPA %s: %w", name, err)
+	}
+	return nil
+}
+
+// setReplicas scales a deployment with an update, so its change cause is
+// recorded
+func (c *Client) setReplicas(ctx context.Context, namespace, name string, replicas int32) error {
+	deployment, err := c.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	deployment.Spec.Replicas = &replicas
+	setChangeCause(ctx, deployment)
+	if _, err := c.clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to scale deployment %s: %w", name, err)
+	}
+	return nil
+}
+
+// findService returns the service of that name
+func findService(services []corev1.Service, name string) *corev1.Service {
+	for i := range services {
+		if services[i].Name == name {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+// labelsEqual reports whether two label sets are the same
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// APIResource is a resource kind served by the cluster, found through API
+// discovery
+type APIResource struct {
+	Name       string // plural resource name, e.g. "deployments"
+	Kind       string
+	Group      string
+	Version    string
+	Namespaced bool
+	Verbs      []string
+	Singular   string   // e.g. "deployment", empty if the server does not say
+	ShortNames []string // e.g. "deploy"
+}
+
+// String returns the resource in kubectl's resource.group form
+func (r APIResource) String() string {
+	if r.Group == "" {
+		return r.Name
+	}
+	return r.Name + "." + r.Group
+}
+
+// Can reports whether the resource supports a verb
+func (r APIResource) Can(verb string) bool {
+	for _, v := range r.Verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+func (r APIResource) groupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Name}
+}
+
+// FindAPIResource resolves a kind as kubectl does: by plural, singular or
+// short name or by kind, case-insensitively, optionally qualified with the
+// group as in "deployments.apps". When several groups serve the name the core
+// and apps groups win, otherwise the name is ambiguous.
+func FindAPIResource(resources []APIResource, kind string) (APIResource, error) {
+	kind = strings.ToLower(kind)
+	var matches []APIResource
+	for _, r := range resources {
+		if r.named(kind) || (r.Group != "" && strings.HasSuffix(kind, "."+r.Group) && r.named(strings.TrimSuffix(kind, "."+r.Group))) {
+			matches = append(matches, r)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return APIResource{}, fmt.Errorf("the server doesn't have a resource type %q", kind)
+	case 1:
+		return matches[0], nil
+	}
+	for _, group := range []string{"", "apps"} {
+		for _, r := range matches {
+			if r.Group == group {
+				return r, nil
+			}
+		}
+	}
+	names := make([]string, 0, len(matches))
+	for _, r := range matches {
+		names = append(names, r.String())
+	}
+	return APIResource{}, fmt.Errorf("%q is ambiguous, use one of %s", kind, strings.Join(names, ", "))
+}
+
+// named reports whether a lowercase name is the resource's plural, singular
+// or short name or its kind
+func (r APIResource) named(name string) bool {
+	if name == r.Name || name == r.Singular || name == strings.ToLower(r.Kind) {
+		return true
+	}
+	for _, short := range r.ShortNames {
+		if name == short {
+			return true
+		}
+	}
+	return false
+}
+
+// ListAPIResources returns the listable resource kinds of the cluster in
+// their preferred version, sorted by name. Groups whose discovery fails (for
+// example an unavailable aggregated API) are skipped.
+func (c *Client) ListAPIResources(ctx context.Context) ([]APIResource, error) {
+	lists, err := discovery.ServerPreferredResources(c.clientset.Discovery())
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover API resources: %w", err)
+	}
+
+	var resources []APIResource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			// Subresources such as pods/log cannot be listed on their own
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			res := APIResource{
+				Name:       r.Name,
+				Kind:       r.Kind,
+				Group:      gv.Group,
+				Version:    gv.Version,
+				Namespaced: r.Namespaced,
+				Verbs:      r.Verbs,
+				Singular:   r.SingularName,
+				ShortNames: r.ShortNames,
+			}
+			if res.Can("list") && res.Can("get") {
+				resources = append(resources, res)
+			}
+		}
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].String() < resources[j].String()
+	})
+	return resources, nil
+}
+
+// resourceClient returns the dynamic client for a resource, scoped to the
+// namespace if the resource is namespaced
+func (c *Client) resourceClient(namespace string, res APIResource) (dynamic.ResourceInterface, error) {
+	if c.dynamic == nil {
+		return nil, fmt.Errorf("browsing %s needs a dynamic client", res)
+	}
+	client := c.dynamic.Resource(res.groupVersionResource())
+	if res.Namespaced {
+		return client.Namespace(namespace), nil
+	}
+	return client, nil
+}
+
+// ListResources returns the names of a resource's instances in a namespace,
+// or in the whole cluster for cluster-scoped resources
+func (c *Client) ListResources(ctx context.Context, namespace string, res APIResource) ([]string, error) {
+	client, err := c.resourceClient(namespace, res)
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", res, err)
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetResourceManifest returns an instance of any resource as YAML.
+// managedFields are stripped unless keepManagedFields is set.
+func (c *Client) GetResourceManifest(ctx context.Context, namespace string, res APIResource, name string, keepManagedFields bool) (string, error) {
+	client, err := c.resourceClient(namespace, res)
+	if err != nil {
+		return "", err
+	}
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return unstructuredManifest(obj, keepManagedFields)
+}
+
+// DeleteResource deletes an instance of any resource
+func (c *Client) DeleteResource(ctx context.Context, namespace string, res APIResource, name string) error {
+	if !res.Can("delete") {
+		return fmt.Errorf("%s cannot be deleted", res)
+	}
+	client, err := c.resourceClient(namespace, res)
+	if err != nil {
+		return err
+	}
+	if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", res.Kind, name, err)
+	}
+	return nil
+}
+
+// unstructuredManifest renders an object as YAML
+func unstructuredManifest(obj *unstructured.Unstructured, keepManagedFields bool) (string, error) {
+	if !keepManagedFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	}
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s: %w", obj.GetKind(), err)
+	}
+	return string(data), nil
+}
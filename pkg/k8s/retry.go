@@ -0,0 +1,153 @@
+package k8s
+
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// RetryPolicy controls how reads from the API server are retried after
+// transient failures such as dropped connections or 429 and 5xx answers
+type RetryPolicy struct {
+	Attempts     int           // retries after the first try, 0 disables retrying
+	InitialDelay time.Duration // delay before the first retry, doubled for each next one
+	MaxDelay     time.Duration // upper bound of a delay, also for Retry-After
+}
+
+// DefaultRetryPolicy is used unless the config sets another one
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, InitialDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// Retry is the retry policy of clients created afterwards
+var Retry = DefaultRetryPolicy
+
+// RetryEvent reports a retried request. Attempt counts the retries; Done is
+// set once the request succeeded or gave up after retrying.
+type RetryEvent struct {
+	Attempt int
+	Max     int
+	Err     error
+	Done    bool
+}
+
+// retryEventBuffer is how many events are kept for a slow reader, later ones
+// are dropped
+const retryEventBuffer = 16
+
+// RetryEvents returns the client's retry events, nil if it does not retry
+func (c *Client) RetryEvents() <-chan RetryEvent {
+	return c.retries
+}
+
+// applyRetry makes the client retry idempotent reads according to Retry. It
+// returns the channel retries are reported on, nil if retrying is disabled.
+func applyRetry(config *rest.Config) chan RetryEvent {
+	if Retry.Attempts <= 0 {
+		return nil
+	}
+	policy := Retry
+	events := make(chan RetryEvent, retryEventBuffer)
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &retryTransport{next: rt, policy: policy, events: events}
+	})
+	return events
+}
+
+// retryTransport retries GET requests that fail transiently. Streams such as
+// exec and port-forward upgrade the connection and are never retried.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	events chan RetryEvent
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Upgrade") != "" {
+		return t.next.RoundTrip(req)
+	}
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		retryAfter, failure := transientFailure(resp, err)
+		if failure == nil || attempt == t.policy.Attempts || ctx.Err() != nil {
+			if attempt > 0 {
+				t.report(RetryEvent{Attempt: attempt, Max: t.policy.Attempts, Err: failure, Done: true})
+			}
+			return resp, err
+		}
+		if resp != nil {
+			// Drain so that the connection is reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		t.report(RetryEvent{Attempt: attempt + 1, Max: t.policy.Attempts, Err: failure})
+
+		timer := time.NewTimer(t.delay(attempt, retryAfter))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			t.report(RetryEvent{Attempt: attempt + 1, Max: t.policy.Attempts, Err: ctx.Err(), Done: true})
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// delay is the exponential backoff before retry attempt+1, randomized within
+// its upper half, or the server's Retry-After if that is longer
+func (t *retryTransport) delay(attempt int, retryAfter time.Duration) time.Duration {
+	d := t.policy.InitialDelay << attempt
+	if d <= 0 || (t.policy.MaxDelay > 0 && d > t.policy.MaxDelay) {
+		d = t.policy.MaxDelay
+	}
+	if d > 0 {
+		d = d/2 + rand.N(d/2+1)
+	}
+	if retryAfter > d {
+		d = retryAfter
+		if t.policy.MaxDelay > 0 && d > t.policy.MaxDelay {
+			d = t.policy.MaxDelay
+		}
+	}
+	return d
+}
+
+// report sends an event without blocking the request
+func (t *retryTransport) report(event RetryEvent) {
+	if t.events == nil {
+		return
+	}
+	select {
+	case t.events <- event:
+	default:
+	}
+}
+
+// transientFailure returns how long the server asked to wait and why the
+// response is worth retrying, nil if it is not
+func transientFailure(resp *http.Response, err error) (time.Duration, error) {
+	if err != nil {
+		var certErr *x509.UnknownAuthorityError
+		var hostErr x509.HostnameError
+		var invalidErr x509.CertificateInvalidError
+		if errors.As(err, &certErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return retryAfter, errors.New(resp.Status)
+	}
+	return 0, nil
+}
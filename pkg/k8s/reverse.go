@@ -0,0 +1,352 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// DefaultReverseImage runs the reverse tunnel agent, it needs socat and sh
+const DefaultReverseImage = "alpine/socat"
+
+const (
+	// reverseLabel marks reverse tunnel agents with their own name, the
+	// intercepted service selects them by it
+	reverseLabel = "khelper.io/reverse-agent"
+	// reverseSelectorAnnotation keeps the selector of an intercepted
+	// service, to restore it
+	reverseSelectorAnnotation = "khelper.io/reverse-selector"
+	// reverseTunnelPort is where the agent takes the tunnel's connections,
+	// the next port if the service targets this one
+	reverseTunnelPort = 9000
+	// reversePoolSize is how many tunnel connections wait for callers, and
+	// so how many callers are served at once
+	reversePoolSize = 8
+	// reverseCleanupTimeout bounds restoring the service and deleting the
+	// agent after the tunnel stops
+	reverseCleanupTimeout = 30 * time.Second
+)
+
+// ReverseTunnelOptions describe a service whose traffic is sent to a local
+// address
+type ReverseTunnelOptions struct {
+	Namespace string
+	Service   string
+	Port      int    // the service port to intercept, 0 for its only one
+	Image     string // DefaultReverseImage if empty
+	LocalAddr string // where callers are sent, e.g. localhost:3000
+
+	// Out receives progress, nil discards it
+	Out io.Writer
+}
+
+// ReverseTunnel sends a service's traffic to a local address until ctx is
+// cancelled. An agent pod takes over the service's selector and passes each
+// caller on through one of a pool of port-forwarded connections. A caller
+// is connected to the local address once it sends its first bytes, as HTTP
+// and gRPC clients do. The service's selector is restored and the agent
+// deleted when the tunnel stops.
+func (c *Client) ReverseTunnel(ctx context.Context, opts ReverseTunnelOptions) error {
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+	services := c.clientset.CoreV1().Services(opts.Namespace)
+	svc, err := services.Get(ctx, opts.Service, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, ok := svc.Annotations[reverseSelectorAnnotation]; ok {
+		return fmt.Errorf("service %s is already intercepted by a reverse tunnel; if none runs, restore it with khelper reverse --restore", opts.Service)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return fmt.Errorf("service %s has no selector, only services selecting pods can be intercepted", opts.Service)
+	}
+	port, portName, err := reversePort(svc, opts.Port)
+	if err != nil {
+		return err
+	}
+
+	image := opts.Image
+	if image == "" {
+		image = DefaultReverseImage
+	}
+	name := "khelper-reverse-" + opts.Service
+	if len(name) > 57 {
+		name = name[:57]
+	}
+	name += "-" + utilrand.String(5)
+	tunnelPort := reverseTunnelPort
+	if port == tunnelPort {
+		tunnelPort++
+	}
+	agent := reverseAgent(name, opts.Namespace, image, port, portName, tunnelPort)
+	fmt.Fprintf(out, "Starting agent pod %s...\n", name)
+	if _, err := c.clientset.CoreV1().Pods(opts.Namespace).Create(ctx, agent, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create the reverse tunnel agent: %w", err)
+	}
+	defer func() {
+		cleanup, cancel := context.WithTimeout(context.Background(), reverseCleanupTimeout)
+		defer cancel()
+		if err := c.clientset.CoreV1().Pods(opts.Namespace).Delete(cleanup, name, metav1.DeleteOptions{}); err != nil {
+			fmt.Fprintf(out, "Failed to delete agent pod %s: %v\n", name, err)
+		}
+	}()
+	if phase, err := c.waitPodStarted(ctx, opts.Namespace, name); err != nil {
+		return err
+	} else if phase != corev1.PodRunning {
+		return fmt.Errorf("agent pod %s ended (%s), check that image %s has socat and sh", name, phase, image)
+	}
+
+	// Forward the pool's connections to the agent before callers come
+	forwardPort, err := freeLocalPort()
+	if err != nil {
+		return err
+	}
+	forwardCtx, stopForward := context.WithCancel(ctx)
+	defer stopForward()
+	ready := make(chan struct{})
+	forwardErr := make(chan error, 1)
+	go func() {
+		forwardErr <- c.PortForward(forwardCtx, PortForwardOptions{
+			Namespace: opts.Namespace,
+			PodName:   name,
+			Ports:     []PortPair{{Local: forwardPort, Remote: tunnelPort}},
+			ReadyChan: ready,
+		})
+	}()
+	select {
+	case <-ready:
+	case err := <-forwardErr:
+		return fmt.Errorf("failed to connect to the agent: %w", err)
+	case <-ctx.Done():
+		return nil
+	}
+
+	if err := c.interceptService(ctx, opts.Namespace, opts.Service, name); err != nil {
+		return err
+	}
+	defer func() {
+		cleanup, cancel := context.WithTimeout(context.Background(), reverseCleanupTimeout)
+		defer cancel()
+		if err := c.RestoreService(cleanup, opts.Namespace, opts.Service); err != nil {
+			fmt.Fprintf(out, "Failed to restore service %s: %v; run khelper reverse --restore\n", opts.Service, err)
+		} else {
+			fmt.Fprintf(out, "Restored service %s\n", opts.Service)
+		}
+	}()
+	fmt.Fprintf(out, "Sending %s:%d to %s\n", opts.Service, port, opts.LocalAddr)
+
+	var wg sync.WaitGroup
+	for range reversePoolSize {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveReverse(forwardCtx, "localhost:"+strconv.Itoa(forwardPort), opts.LocalAddr, out)
+		}()
+	}
+	select {
+	case err = <-forwardErr:
+		if err == nil && ctx.Err() == nil {
+			err = fmt.Errorf("the connection to agent pod %s was lost", name)
+		}
+	case <-ctx.Done():
+	}
+	stopForward()
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// reversePort finds the service port to intercept, and the port the agent
+// listens on in place of the service's pods, with its name if the service
+// targets it by name
+func reversePort(svc *corev1.Service, port int) (int, string, error) {
+	var ports []corev1.ServicePort
+	for _, sp := range svc.Spec.Ports {
+		if sp.Protocol != corev1.ProtocolUDP && (port == 0 || int(sp.Port) == port) {
+			ports = append(ports, sp)
+		}
+	}
+	switch {
+	case len(ports) == 0 && port != 0:
+		return 0, "", fmt.Errorf("service %s has no TCP port %d", svc.Name, port)
+	case len(ports) == 0:
+		return 0, "", fmt.Errorf("service %s has no TCP port, a reverse tunnel only carries TCP", svc.Name)
+	case len(ports) > 1:
+		numbers := make([]string, len(ports))
+		for i, sp := range ports {
+			numbers[i] = strconv.Itoa(int(sp.Port))
+		}
+		return 0, "", fmt.Errorf("service %s has several ports (%s), choose one", svc.Name, strings.Join(numbers, ", "))
+	}
+	sp := ports[0]
+	switch {
+	case sp.TargetPort.Type == intstr.String:
+		return int(sp.Port), sp.TargetPort.StrVal, nil
+	case sp.TargetPort.IntVal != 0:
+		return int(sp.TargetPort.IntVal), "", nil
+	}
+	return int(sp.Port), "", nil
+}
+
+// reverseAgent is the agent pod. For each connection the tunnel opens on
+// tunnelPort it listens for one caller on the service's target port;
+// the listeners share the port, the kernel spreads callers over them.
+func reverseAgent(name, namespace, image string, port int, portName string, tunnelPort int) *corev1.Pod {
+	script := fmt.Sprintf(`exec socat TCP-LISTEN:%d,fork,reuseaddr SYSTEM:"socat STDIO TCP-LISTEN:%d,reuseaddr,reuseport"`, tunnelPort, port)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{reverseLabel: name},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:    "agent",
+				Image:   image,
+				Command: []string{"sh", "-c", script},
+				Ports: []corev1.ContainerPort{
+					{Name: portName, ContainerPort: int32(port)},
+					{Name: "tunnel", ContainerPort: int32(tunnelPort)},
+				},
+			}},
+		},
+	}
+}
+
+// interceptService points a service's selector at the agent, keeping the
+// selector in an annotation to restore it
+func (c *Client) interceptService(ctx context.Context, namespace, name, agent string) error {
+	services := c.clientset.CoreV1().Services(namespace)
+	svc, err := services.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	selector, err := json.Marshal(svc.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = make(map[string]string)
+	}
+	svc.Annotations[reverseSelectorAnnotation] = string(selector)
+	svc.Spec.Selector = map[string]string{reverseLabel: agent}
+	if _, err := services.Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to point service %s at the agent: %w", name, err)
+	}
+	return nil
+}
+
+// RestoreService gives a service intercepted by a reverse tunnel its
+// selector back
+func (c *Client) RestoreService(ctx context.Context, namespace, name string) error {
+	services := c.clientset.CoreV1().Services(namespace)
+	svc, err := services.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	saved, ok := svc.Annotations[reverseSelectorAnnotation]
+	if !ok {
+		return fmt.Errorf("service %s is not intercepted by a reverse tunnel", name)
+	}
+	var selector map[string]string
+	if err := json.Unmarshal([]byte(saved), &selector); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", reverseSelectorAnnotation, err)
+	}
+	svc.Spec.Selector = selector
+	delete(svc.Annotations, reverseSelectorAnnotation)
+	_, err = services.Update(ctx, svc, metav1.UpdateOptions{})
+	return err
+}
+
+// serveReverse keeps one tunnel connection waiting for a caller, and
+// passes the caller on to the local address, until ctx is cancelled
+func serveReverse(ctx context.Context, tunnelAddr, localAddr string, out io.Writer) {
+	var dialer net.Dialer
+	for ctx.Err() == nil {
+		tunnel, err := dialer.DialContext(ctx, "tcp", tunnelAddr)
+		if err != nil {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		stop := context.AfterFunc(ctx, func() { tunnel.Close() })
+
+		// The caller's first bytes tell that it connected
+		first := make([]byte, 32<<10)
+		n, err := tunnel.Read(first)
+		if err != nil {
+			// The agent is gone or restarting, as idle connections are
+			// otherwise kept
+			stop()
+			tunnel.Close()
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		local, err := dialer.DialContext(ctx, "tcp", localAddr)
+		if err != nil {
+			fmt.Fprintf(out, "Caller dropped: %v\n", err)
+			stop()
+			tunnel.Close()
+			continue
+		}
+		if _, err := local.Write(first[:n]); err == nil {
+			pipeConns(tunnel, local)
+		}
+		stop()
+		tunnel.Close()
+		local.Close()
+	}
+}
+
+// pipeConns copies between two connections until both directions ended
+func pipeConns(a, b net.Conn) {
+	done := make(chan struct{})
+	go func() {
+		io.Copy(b, a)
+		closeWrite(b)
+		close(done)
+	}()
+	io.Copy(a, b)
+	closeWrite(a)
+	<-done
+}
+
+// closeWrite tells the other end that nothing more is sent
+func closeWrite(c net.Conn) {
+	if tcp, ok := c.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	} else {
+		c.Close()
+	}
+}
+
+// freeLocalPort finds a local port nothing listens on
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free local port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,93 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrRolloutFailed is returned when a rollout exceeded its progress deadline
+var ErrRolloutFailed = errors.New("rollout failed")
+
+// RolloutStatus is the progress of a workload's rollout
+type RolloutStatus struct {
+	Desired   int32
+	Updated   int32
+	Ready     int32
+	Available int32
+	Old       int32 // replicas of previous revisions still running
+	Done      bool
+}
+
+func (s RolloutStatus) String() string {
+	text := fmt.Sprintf("%d/%d updated, %d/%d ready, %d/%d available", s.Updated, s.Desired, s.Ready, s.Desired, s.Available, s.Desired)
+	if s.Old > 0 {
+		text += fmt.Sprintf(", %d old replica(s) terminating", s.Old)
+	}
+	return text
+}
+
+// GetRolloutStatus reports how far a deployment's rollout got, like kubectl
+// rollout status. The error wraps ErrRolloutFailed once the progress
+// deadline was exceeded.
+func GetRolloutStatus(deployment *appsv1.Deployment) (RolloutStatus, error) {
+	status := deployment.Status
+	s := RolloutStatus{
+		Desired:   1,
+		Updated:   status.UpdatedReplicas,
+		Ready:     status.ReadyReplicas,
+		Available: status.AvailableReplicas,
+	}
+	if deployment.Spec.Replicas != nil {
+		s.Desired = *deployment.Spec.Replicas
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		s.Old = status.Replicas - status.UpdatedReplicas
+	}
+
+	for _, cond := range status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+			return s, fmt.Errorf("%w: %s", ErrRolloutFailed, cond.Message)
+		}
+	}
+
+	// Custom workloads carry no observedGeneration, their counts are used alone
+	if status.ObservedGeneration != 0 && status.ObservedGeneration < deployment.Generation {
+		return s, nil
+	}
+	s.Done = s.Updated >= s.Desired && s.Old == 0 && s.Available >= s.Desired && s.Ready >= s.Desired
+	return s, nil
+}
+
+// WaitForRollout polls a workload until its rollout is done, calling progress
+// whenever the status changes. It stops early when ctx is done or the
+// rollout fails.
+func (c *Client) WaitForRollout(ctx context.Context, namespace, ref string, interval time.Duration, progress func(RolloutStatus)) error {
+	var last *RolloutStatus
+	for {
+		deployment, err := c.GetDeployment(ctx, namespace, ref)
+		if err != nil {
+			return err
+		}
+		status, err := GetRolloutStatus(deployment)
+		if last == nil || *last != status {
+			last = &status
+			if progress != nil {
+				progress(status)
+			}
+		}
+		if err != nil || status.Done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("rollout of %s not complete: %w", ref, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Scale presets, accepted wherever a replica count is
+const (
+	ScalePrevious = "prev" // the count before the last scale
+	ScaleHPAMin   = "min"  // the HPA's minReplicas
+	ScaleHPAMax   = "max"  // the HPA's maxReplicas
+)
+
+// ScaleInfo is what scale presets are resolved against
+type ScaleInfo struct {
+	Replicas int32
+	Previous *int32 // recorded before the last scale, nil if unknown
+	HPA      string // the HorizontalPodAutoscaler targeting the workload, if any
+	HPAMin   int32
+	HPAMax   int32
+}
+
+// GetScaleInfo returns a workload's replica count and the bounds of the
+// HorizontalPodAutoscaler targeting it. Previous is left for the caller.
+func (c *Client) GetScaleInfo(ctx context.Context, namespace, ref string) (*ScaleInfo, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+	info := &ScaleInfo{Replicas: 1}
+	if deployment.Spec.Replicas != nil {
+		info.Replicas = *deployment.Spec.Replicas
+	}
+
+	kind, name := parseWorkloadRef(ref)
+	kindName := "Deployment"
+	if kind != nil {
+		kindName = kind.kind
+	}
+	hpas, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			return info, nil
+		}
+		return nil, fmt.Errorf("failed to list HPAs: %w", err)
+	}
+	for _, hpa := range hpas.Items {
+		target := hpa.Spec.ScaleTargetRef
+		if target.Kind != kindName || target.Name != name {
+			continue
+		}
+		info.HPA = hpa.Name
+		info.HPAMin = 1
+		if hpa.Spec.MinReplicas != nil {
+			info.HPAMin = *hpa.Spec.MinReplicas
+		}
+		info.HPAMax = hpa.Spec.MaxReplicas
+		break
+	}
+	return info, nil
+}
+
+// ResolveReplicas turns a replica count or preset into a count
+func (i *ScaleInfo) ResolveReplicas(target string) (int32, error) {
+	switch strings.ToLower(target) {
+	case ScalePrevious:
+		if i.Previous == nil {
+			return 0, fmt.Errorf("no previous replica count recorded")
+		}
+		return *i.Previous, nil
+	case ScaleHPAMin, ScaleHPAMax:
+		if i.HPA == "" {
+			return 0, fmt.Errorf("no HorizontalPodAutoscaler targets this workload")
+		}
+		if strings.EqualFold(target, ScaleHPAMin) {
+			return i.HPAMin, nil
+		}
+		return i.HPAMax, nil
+	}
+	replicas, err := strconv.ParseInt(target, 10, 32)
+	if err != nil || replicas < 0 {
+		return 0, fmt.Errorf("invalid replica count %q, use a number, %s, %s or %s", target, ScalePrevious, ScaleHPAMin, ScaleHPAMax)
+	}
+	return int32(replicas), nil
+}
+
+// HPANote warns that an HPA will override a manual scale, or returns ""
+func (i *ScaleInfo) HPANote() string {
+	if i.HPA == "" {
+		return ""
+	}
+	return fmt.Sprintf("HPA %s manages the replicas (%d-%d) and may override this", i.HPA, i.HPAMin, i.HPAMax)
+}
@@ -0,0 +1,150 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// templateJobLabel marks Jobs created from a workload's pod template with
+// the workload
+const templateJobLabel = "khelper.io/job-of"
+
+// CreateTemplateJob creates a Job that runs command with sh -c in a
+// container of a workload's pod template, like a copy of the template pasted
+// into a Job manifest, and waits until its pod started. The other containers
+// are left out so that the Job completes with the command; init containers
+// and native sidecars are kept. The Job runs once and is not retried.
+// It returns the names of the Job and its pod.
+func (c *Client) CreateTemplateJob(ctx context.Context, namespace, deploymentName, containerName, command string) (string, string, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return "", "", err
+	}
+	_, workloadName := parseWorkloadRef(deploymentName)
+
+	spec := deployment.Spec.Template.Spec.DeepCopy()
+	var container *corev1.Container
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == containerName {
+			container = &spec.Containers[i]
+		}
+	}
+	if container == nil {
+		return "", "", fmt.Errorf("%s has no container %s", deploymentName, containerName)
+	}
+	container.Command = []string{"sh", "-c", command}
+	container.Args = nil
+	container.LivenessProbe = nil
+	container.ReadinessProbe = nil
+	container.StartupProbe = nil
+	spec.Containers = []corev1.Container{*container}
+	spec.RestartPolicy = corev1.RestartPolicyNever
+
+	name := workloadName
+	if len(name) > 46 {
+		name = name[:46]
+	}
+	name += "-job-" + utilrand.String(5)
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{templateJobLabel: workloadName},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{templateJobLabel: workloadName},
+					Annotations: deployment.Spec.Template.Annotations,
+				},
+				Spec: *spec,
+			},
+		},
+	}
+	if _, err := c.clientset.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		return "", "", fmt.Errorf("failed to create job: %w", err)
+	}
+
+	pod, err := c.waitJobPod(ctx, namespace, name)
+	if err != nil {
+		return name, "", err
+	}
+	_, err = c.waitPodStarted(ctx, namespace, pod)
+	return name, pod, err
+}
+
+// waitJobPod polls until a Job's pod exists and returns its name
+func (c *Client) waitJobPod(ctx context.Context, namespace, jobName string) (string, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + jobName})
+		if err != nil {
+			return "", fmt.Errorf("failed to list pods: %w", err)
+		}
+		if len(pods.Items) > 0 {
+			return pods.Items[0].Name, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// TemplateJobStatus tells how a Job created by CreateTemplateJob did:
+// running, succeeded or failed with the reason and the exit code of its pod
+func (c *Client) TemplateJobStatus(ctx context.Context, namespace, jobName, podName string) (string, error) {
+	job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get job: %w", err)
+	}
+	exit := ""
+	if pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{}); err == nil {
+		for _, status := range pod.Status.ContainerStatuses {
+			if t := status.State.Terminated; t != nil {
+				exit = fmt.Sprintf(", exit code %d", t.ExitCode)
+				if t.Reason != "" && t.Reason != "Completed" && t.Reason != "Error" {
+					exit += " (" + t.Reason + ")"
+				}
+			}
+		}
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		took := ""
+		if job.Status.StartTime != nil && job.Status.CompletionTime != nil {
+			took = " after " + job.Status.CompletionTime.Sub(job.Status.StartTime.Time).Round(time.Second).String()
+		}
+		return "succeeded" + took + exit, nil
+	case jobFailure(job) != "":
+		return "failed: " + jobFailure(job) + exit, nil
+	case exit != "":
+		// The pod ended before the Job controller noticed
+		return "finished" + exit, nil
+	}
+	return "still running", nil
+}
+
+// DeleteTemplateJob deletes a Job created by CreateTemplateJob and its pod
+func (c *Client) DeleteTemplateJob(ctx context.Context, namespace, name string) error {
+	job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if _, ok := job.Labels[templateJobLabel]; !ok {
+		return fmt.Errorf("job %s was not created from a pod template by khelper", name)
+	}
+	propagation := metav1.DeletePropagationBackground
+	return c.clientset.BatchV1().Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
@@ -0,0 +1,213 @@
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/term"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// rawSessions are the sessions holding the terminal in raw mode. They are
+// restored when a stream goroutine panics, which would otherwise crash
+// khelper without running the deferred Restore.
+var rawSessions = struct {
+	sync.Mutex
+	m map[*ttySession]struct{}
+}{m: map[*ttySession]struct{}{}}
+
+// hookPanics adds restoreOnPanic to the stream panic handlers. It is done
+// once and the handler stays, as the handlers are read without a lock.
+var hookPanics sync.Once
+
+// addRawSession tracks a session in raw mode
+func addRawSession(t *ttySession) {
+	hookPanics.Do(func() {
+		utilruntime.PanicHandlers = append(utilruntime.PanicHandlers, restoreOnPanic)
+	})
+	rawSessions.Lock()
+	defer rawSessions.Unlock()
+	rawSessions.m[t] = struct{}{}
+}
+
+// removeRawSession stops tracking a restored session
+func removeRawSession(t *ttySession) {
+	rawSessions.Lock()
+	defer rawSessions.Unlock()
+	delete(rawSessions.m, t)
+}
+
+// restoreOnPanic restores every terminal still in raw mode
+func restoreOnPanic(interface{}) {
+	rawSessions.Lock()
+	sessions := make([]*ttySession, 0, len(rawSessions.m))
+	for t := range rawSessions.m {
+		sessions = append(sessions, t)
+	}
+	rawSessions.Unlock()
+	for _, t := range sessions {
+		t.Restore()
+	}
+}
+
+// SignalError ends a session when khelper is killed or hung up while it
+// holds the terminal. The caller decides how to exit.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("session ended by signal: %v", e.Signal)
+}
+
+// ttySession prepares the local terminal for an interactive remote TTY:
+// raw input, virtual terminal output and size propagation.
+type ttySession struct {
+	mu         sync.Mutex
+	inFd       int
+	rawState   *term.State
+	restoreOut func()
+	sizeQueue  *terminalSizeQueue
+	stopSigs   chan struct{}
+	stop       func() // ends the session on a signal
+	signal     os.Signal
+}
+
+// setupTTY configures the terminals behind stdin/stdout. Streams that are
+// not terminals are left untouched, so the returned session is always safe
+// to Restore. Until then the terminal is also restored when a stream
+// goroutine panics, and when khelper is killed or hung up, which also calls
+// stop to end the session; Err then reports the signal.
+func setupTTY(stdin io.Reader, stdout io.Writer, stop func()) (*ttySession, error) {
+	t := &ttySession{inFd: -1, stop: stop}
+
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return nil, err
+		}
+		t.inFd = int(f.Fd())
+		t.rawState = state
+		addRawSession(t)
+		t.stopSigs = make(chan struct{})
+		go t.restoreOnSignal()
+	}
+
+	if f, ok := stdout.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		t.restoreOut = enableVirtualTerminal(f)
+		t.sizeQueue = newTerminalSizeQueue(int(f.Fd()))
+	}
+
+	return t, nil
+}
+
+// SizeQueue returns the resize queue for the remote TTY, or nil if stdout is
+// not a terminal
+func (t *ttySession) SizeQueue() remotecommand.TerminalSizeQueue {
+	if t.sizeQueue == nil {
+		return nil
+	}
+	return t.sizeQueue
+}
+
+// restoreOnSignal restores the terminal and ends the session when khelper
+// is terminated. In raw mode Ctrl+C reaches the remote side, so these
+// signals come from elsewhere, e.g. kill or a closed SSH connection.
+func (t *ttySession) restoreOnSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	select {
+	case sig := <-sigs:
+		t.mu.Lock()
+		t.signal = sig
+		t.mu.Unlock()
+		t.Restore()
+		t.stop()
+	case <-t.stopSigs:
+	}
+}
+
+// Err returns a *SignalError if a signal ended the session
+func (t *ttySession) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.signal == nil {
+		return nil
+	}
+	return &SignalError{Signal: t.signal}
+}
+
+// Restore puts the local terminal back into its original state. It is safe
+// to call more than once and from several goroutines.
+func (t *ttySession) Restore() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopSigs != nil {
+		close(t.stopSigs)
+		t.stopSigs = nil
+	}
+	if t.sizeQueue != nil {
+		t.sizeQueue.stop()
+		t.sizeQueue = nil
+	}
+	if t.restoreOut != nil {
+		t.restoreOut()
+		t.restoreOut = nil
+	}
+	if t.rawState != nil {
+		term.Restore(t.inFd, t.rawState)
+		t.rawState = nil
+		removeRawSession(t)
+	}
+}
+
+// terminalSizeQueue reports local terminal size changes to the remote TTY
+type terminalSizeQueue struct {
+	fd    int
+	sizes chan remotecommand.TerminalSize
+	done  chan struct{}
+}
+
+func newTerminalSizeQueue(fd int) *terminalSizeQueue {
+	q := &terminalSizeQueue{
+		fd:    fd,
+		sizes: make(chan remotecommand.TerminalSize, 1),
+		done:  make(chan struct{}),
+	}
+	q.send()
+	go q.watch()
+	return q
+}
+
+// Next implements remotecommand.TerminalSizeQueue
+func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-q.sizes:
+		return &size
+	case <-q.done:
+		return nil
+	}
+}
+
+// send queues the current terminal size, dropping it if one is pending
+func (q *terminalSizeQueue) send() {
+	width, height, err := term.GetSize(q.fd)
+	if err != nil {
+		return
+	}
+	select {
+	case q.sizes <- remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}:
+	default:
+	}
+}
+
+func (q *terminalSizeQueue) stop() {
+	close(q.done)
+}
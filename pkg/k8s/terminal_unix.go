@@ -0,0 +1,31 @@
+//go:build !windows
+
+package k8s
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// enableVirtualTerminal is a no-op: Unix terminals interpret escape
+// sequences natively
+func enableVirtualTerminal(out *os.File) func() {
+	return func() {}
+}
+
+// watch forwards SIGWINCH as terminal size updates
+func (q *terminalSizeQueue) watch() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-sigs:
+			q.send()
+		case <-q.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package k8s
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/term"
+)
+
+// enableVirtualTerminal turns on VT escape sequence processing for the
+// console so the remote shell's output renders correctly, returning a
+// function that restores the previous console mode
+func enableVirtualTerminal(out *os.File) func() {
+	handle := windows.Handle(out.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return func() {}
+	}
+	vtMode := mode | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING | windows.DISABLE_NEWLINE_AUTO_RETURN
+	if err := windows.SetConsoleMode(handle, vtMode); err != nil {
+		return func() {}
+	}
+	return func() {
+		windows.SetConsoleMode(handle, mode)
+	}
+}
+
+// watch polls the console size, Windows has no resize signal
+func (q *terminalSizeQueue) watch() {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	lastWidth, lastHeight, _ := term.GetSize(q.fd)
+	for {
+		select {
+		case <-ticker.C:
+			width, height, err := term.GetSize(q.fd)
+			if err != nil || (width == lastWidth && height == lastHeight) {
+				continue
+			}
+			lastWidth, lastHeight = width, height
+			q.send()
+		case <-q.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ServiceAccountToken is a short-lived token minted with the TokenRequest API
+type ServiceAccountToken struct {
+	Namespace      string
+	ServiceAccount string
+	Token          string
+	Expires        time.Time
+
+	// cluster the token was minted by, nil without connection details
+	cluster *clientcmdapi.Cluster
+}
+
+// DefaultTokenDuration is how long minted tokens are valid unless asked otherwise
+const DefaultTokenDuration = time.Hour
+
+// DeploymentServiceAccount returns the service account a deployment's pods run as
+func (c *Client) DeploymentServiceAccount(ctx context.Context, namespace, deploymentName string) (string, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return "", err
+	}
+	if name := deployment.Spec.Template.Spec.ServiceAccountName; name != "" {
+		return name, nil
+	}
+	return "default", nil
+}
+
+// CreateServiceAccountToken mints a token for a service account that expires
+// after the given duration. The API server may shorten the lifetime.
+func (c *Client) CreateServiceAccountToken(ctx context.Context, namespace, serviceAccount string, expiration time.Duration) (*ServiceAccountToken, error) {
+	seconds := int64(expiration.Seconds())
+	req := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds},
+	}
+	resp, err := c.clientset.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, serviceAccount, req, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token for %s/%s: %w", namespace, serviceAccount, err)
+	}
+	token := &ServiceAccountToken{
+		Namespace:      namespace,
+		ServiceAccount: serviceAccount,
+		Token:          resp.Status.Token,
+		Expires:        resp.Status.ExpirationTimestamp.Time,
+	}
+	if c.config != nil {
+		cluster := clientcmdapi.NewCluster()
+		cluster.Server = c.config.Host
+		cluster.InsecureSkipTLSVerify = c.config.Insecure
+		cluster.CertificateAuthorityData = c.config.CAData
+		if len(cluster.CertificateAuthorityData) == 0 && c.config.CAFile != "" {
+			data, err := os.ReadFile(c.config.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			cluster.CertificateAuthorityData = data
+		}
+		token.cluster = cluster
+	}
+	return token, nil
+}
+
+// KubeConfig returns a kubeconfig that authenticates with the token against
+// the cluster that minted it
+func (t *ServiceAccountToken) KubeConfig() ([]byte, error) {
+	if t.cluster == nil {
+		return nil, fmt.Errorf("no cluster connection details available")
+	}
+	cluster := t.cluster.DeepCopy()
+
+	name := t.Namespace + "-" + t.ServiceAccount
+	user := clientcmdapi.NewAuthInfo()
+	user.Token = t.Token
+
+	kubeContext := clientcmdapi.NewContext()
+	kubeContext.Cluster = name
+	kubeContext.AuthInfo = name
+	kubeContext.Namespace = t.Namespace
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[name] = cluster
+	kubeconfig.AuthInfos[name] = user
+	kubeconfig.Contexts[name] = kubeContext
+	kubeconfig.CurrentContext = name
+
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,358 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// topologyNode is a line of the map and the lines drawn below it
+type topologyNode struct {
+	label    string
+	children []*topologyNode
+}
+
+// add appends a child line and returns it
+func (n *topologyNode) add(format string, args ...interface{}) *topologyNode {
+	child := &topologyNode{label: fmt.Sprintf(format, args...)}
+	n.children = append(n.children, child)
+	return child
+}
+
+// render draws the node and its children as a tree
+func (n *topologyNode) render(b *strings.Builder, prefix string) {
+	for i, child := range n.children {
+		branch, indent := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		b.WriteString(prefix + branch + child.label + "\n")
+		child.render(b, prefix+indent)
+	}
+}
+
+// DescribeTopology draws the wiring of a deployment on one screen: the
+// services selecting its pods and the ingresses routing to them, its HPA
+// and PDBs, its ReplicaSets with their pods and the nodes they run on.
+// Optional APIs that are missing or forbidden are left out.
+func (c *Client) DescribeTopology(ctx context.Context, namespace, deploymentName string) (string, error) {
+	deployment, err := c.GetDeployment(ctx, namespace, deploymentName)
+	if err != nil {
+		return "", err
+	}
+	replicaSets, err := c.GetReplicaSets(ctx, namespace, deploymentName)
+	if err != nil {
+		return "", fmt.Errorf("failed to list replica sets: %w", err)
+	}
+	pods, err := c.ListPods(ctx, namespace, deploymentName)
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	kind, name := parseWorkloadRef(deploymentName)
+	kindName := "Deployment"
+	if kind != nil {
+		kindName = kind.kind
+	}
+	revision := deployment.Annotations["deployment.kubernetes.io/revision"]
+	root := &topologyNode{label: fmt.Sprintf("%s %s  %d/%d ready", kindName, name, deployment.Status.ReadyReplicas, derefInt32(deployment.Spec.Replicas))}
+	if revision != "" {
+		root.label += "  revision " + revision
+	}
+
+	if err := c.addTopologyServices(ctx, root, namespace, deployment); err != nil {
+		return "", err
+	}
+	if err := c.addTopologyHPA(ctx, root, namespace, kindName, name); err != nil {
+		return "", err
+	}
+	if err := c.addTopologyPDBs(ctx, root, namespace, deployment); err != nil {
+		return "", err
+	}
+	addTopologyReplicaSets(root, deployment, replicaSets, pods)
+
+	var b strings.Builder
+	b.WriteString(root.label + "\n")
+	root.render(&b, "")
+	b.WriteString("\n")
+	b.WriteString(c.describeTopologyNodes(ctx, pods))
+	return b.String(), nil
+}
+
+// addTopologyServices adds the services selecting the deployment's pods,
+// each with the ingresses routing to it
+func (c *Client) addTopologyServices(ctx context.Context, root *topologyNode, namespace string, deployment *appsv1.Deployment) error {
+	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list services: %w", err)
+	}
+	selecting := make(map[string]bool)
+	for _, name := range selectingServices(services.Items, deployment.Spec.Template.Labels) {
+		selecting[name] = true
+	}
+	if len(selecting) == 0 {
+		root.add("Service <none selects the pods>")
+		return nil
+	}
+	ingresses, err := c.GetIngresses(ctx, namespace)
+	if err != nil && !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	for _, svc := range services.Items {
+		if !selecting[svc.Name] {
+			continue
+		}
+		var ports []string
+		for _, port := range svc.Spec.Ports {
+			ports = append(ports, fmt.Sprintf("%d→%s/%s", port.Port, port.TargetPort.String(), port.Protocol))
+		}
+		label := fmt.Sprintf("Service %s  %s %s  %s", svc.Name, svc.Spec.Type, svc.Spec.ClusterIP, joinOrNone(ports))
+		if ready := c.readyEndpoints(ctx, namespace, svc.Name); ready >= 0 {
+			label += fmt.Sprintf("  %d ready endpoints", ready)
+		}
+		node := root.add("%s", label)
+
+		for _, ing := range ingresses {
+			var routes []string
+			if b := ing.Spec.DefaultBackend; b != nil && b.Service != nil && b.Service.Name == svc.Name {
+				routes = append(routes, "default backend")
+			}
+			for _, rule := range ing.Spec.Rules {
+				if rule.HTTP == nil {
+					continue
+				}
+				host := rule.Host
+				if host == "" {
+					host = "*"
+				}
+				for _, path := range rule.HTTP.Paths {
+					if path.Backend.Service != nil && path.Backend.Service.Name == svc.Name {
+						routes = append(routes, host+path.Path)
+					}
+				}
+			}
+			if len(routes) > 0 {
+				node.add("Ingress %s  %s", ing.Name, strings.Join(routes, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// addTopologyHPA adds the HPA scaling the workload, if any
+func (c *Client) addTopologyHPA(ctx context.Context, root *topologyNode, namespace, kindName, name string) error {
+	hpas, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list HPAs: %w", err)
+	}
+	for _, hpa := range hpas.Items {
+		target := hpa.Spec.ScaleTargetRef
+		if target.Kind != kindName || target.Name != name {
+			continue
+		}
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+		label := fmt.Sprintf("HPA %s  %d-%d replicas, %d current", hpa.Name, minReplicas, hpa.Spec.MaxReplicas, hpa.Status.CurrentReplicas)
+		if metrics := hpaMetrics(&hpa); len(metrics) > 0 {
+			label += "  " + strings.Join(metrics, ", ")
+		}
+		root.add("%s", label)
+	}
+	return nil
+}
+
+// hpaMetrics renders the resource utilization targets of an HPA with their
+// current value, e.g. "cpu 45%/70%", and names its other metrics
+func hpaMetrics(hpa *autoscalingv2.HorizontalPodAutoscaler) []string {
+	var metrics []string
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource == nil || metric.Resource.Target.AverageUtilization == nil {
+			metrics = append(metrics, strings.ToLower(string(metric.Type)))
+			continue
+		}
+		current := "?"
+		for _, status := range hpa.Status.CurrentMetrics {
+			if status.Resource != nil && status.Resource.Name == metric.Resource.Name && status.Resource.Current.AverageUtilization != nil {
+				current = strconv.Itoa(int(*status.Resource.Current.AverageUtilization))
+			}
+		}
+		metrics = append(metrics, fmt.Sprintf("%s %s%%/%d%%", metric.Resource.Name, current, *metric.Resource.Target.AverageUtilization))
+	}
+	return metrics
+}
+
+// addTopologyPDBs adds the PodDisruptionBudgets covering the deployment's
+// pods
+func (c *Client) addTopologyPDBs(ctx context.Context, root *topologyNode, namespace string, deployment *appsv1.Deployment) error {
+	pdbs, err := c.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list PDBs: %w", err)
+	}
+	for _, pdb := range pdbs.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			continue
+		}
+		root.add("PDB %s  %s  %d disruptions allowed", pdb.Name, pdbBudget(&pdb), pdb.Status.DisruptionsAllowed)
+	}
+	return nil
+}
+
+// pdbBudget renders what a PDB keeps, e.g. "minAvailable 2"
+func pdbBudget(pdb *policyv1.PodDisruptionBudget) string {
+	switch {
+	case pdb.Spec.MinAvailable != nil:
+		return "minAvailable " + pdb.Spec.MinAvailable.String()
+	case pdb.Spec.MaxUnavailable != nil:
+		return "maxUnavailable " + pdb.Spec.MaxUnavailable.String()
+	}
+	return "no budget"
+}
+
+// addTopologyReplicaSets adds the deployment's ReplicaSets, newest first,
+// with their pods. ReplicaSets scaled to zero are summed up in one line and
+// pods owned by none of them, as with CloneSets, hang off the root.
+func addTopologyReplicaSets(root *topologyNode, deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet, pods []corev1.Pod) {
+	var owned []appsv1.ReplicaSet
+	for _, rs := range replicaSets {
+		for _, ref := range rs.OwnerReferences {
+			if ref.UID == deployment.UID {
+				owned = append(owned, rs)
+			}
+		}
+	}
+	revisionOf := func(rs appsv1.ReplicaSet) int {
+		revision, _ := strconv.Atoi(rs.Annotations["deployment.kubernetes.io/revision"])
+		return revision
+	}
+	sort.SliceStable(owned, func(i, j int) bool { return revisionOf(owned[i]) > revisionOf(owned[j]) })
+
+	placed := make(map[string]bool)
+	current := deployment.Annotations["deployment.kubernetes.io/revision"]
+	var scaledDown []string
+	for _, rs := range owned {
+		revision := rs.Annotations["deployment.kubernetes.io/revision"]
+		if rs.Status.Replicas == 0 && derefInt32(rs.Spec.Replicas) == 0 && revision != current {
+			scaledDown = append(scaledDown, revision)
+			continue
+		}
+		label := fmt.Sprintf("ReplicaSet %s  revision %s", rs.Name, revision)
+		if revision == current {
+			label += " (current)"
+		}
+		node := root.add("%s  %d/%d ready", label, rs.Status.ReadyReplicas, derefInt32(rs.Spec.Replicas))
+		for _, pod := range pods {
+			if metav1.IsControlledBy(&pod, &rs) {
+				node.add("%s", topologyPod(&pod))
+				placed[pod.Name] = true
+			}
+		}
+	}
+	if len(scaledDown) > 0 {
+		root.add("%d older ReplicaSets scaled to 0 (revisions %s)", len(scaledDown), strings.Join(scaledDown, ", "))
+	}
+	for _, pod := range pods {
+		if !placed[pod.Name] {
+			root.add("%s", topologyPod(&pod))
+		}
+	}
+}
+
+// topologyPod renders a pod with its status, restarts and node
+func topologyPod(pod *corev1.Pod) string {
+	status := string(pod.Status.Phase)
+	switch {
+	case pod.DeletionTimestamp != nil:
+		status = "Terminating"
+	case pod.Status.Phase == corev1.PodPending:
+		status = "Pending: " + pendingReason(pod)
+	default:
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+				status = cs.State.Waiting.Reason
+			}
+		}
+	}
+	ready, restarts := 0, int32(0)
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Ready {
+			ready++
+		}
+		restarts += cs.RestartCount
+	}
+	label := fmt.Sprintf("Pod %s  %s  %d/%d ready", pod.Name, status, ready, len(pod.Spec.Containers))
+	if restarts > 0 {
+		label += fmt.Sprintf("  %d restarts", restarts)
+	}
+	node := pod.Spec.NodeName
+	if node == "" {
+		node = "<unscheduled>"
+	}
+	return label + "  → " + node
+}
+
+// describeTopologyNodes lists the nodes the pods run on with their zone and
+// pod count, warning when all pods share one node or zone
+func (c *Client) describeTopologyNodes(ctx context.Context, pods []corev1.Pod) string {
+	counts := make(map[string]int)
+	var names []string
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if counts[pod.Spec.NodeName] == 0 {
+			names = append(names, pod.Spec.NodeName)
+		}
+		counts[pod.Spec.NodeName]++
+	}
+	sort.Strings(names)
+
+	w := newDescribeWriter()
+	if len(names) == 0 {
+		w.line(0, "Nodes:\t<none>")
+		return w.String()
+	}
+	w.line(0, "Nodes:")
+	zones, zoned := make(map[string]bool), 0
+	for _, name := range names {
+		zone := "<no zone>"
+		if node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{}); err == nil && node.Labels[zoneLabel] != "" {
+			zone = node.Labels[zoneLabel]
+			zones[zone] = true
+			zoned++
+		}
+		w.line(1, "%s\t%s\t%d pods", name, zone, counts[name])
+	}
+	scheduled := 0
+	for _, count := range counts {
+		scheduled += count
+	}
+	switch {
+	case scheduled > 1 && len(names) == 1:
+		w.line(0, "⚠ All %d pods run on node %s", scheduled, names[0])
+	case scheduled > 1 && len(zones) == 1 && zoned == len(names):
+		for zone := range zones {
+			w.line(0, "⚠ All nodes with pods are in zone %s", zone)
+		}
+	}
+	return w.String()
+}
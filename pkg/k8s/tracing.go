@@ -0,0 +1,284 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTraceSpans bounds the spans drawn of a trace
+const maxTraceSpans = 300
+
+// TraceQuery locates a trace in a Jaeger or Tempo query API
+type TraceQuery struct {
+	// API is an http(s) URL, or service/<namespace>/<name>[:<port>] to reach
+	// it through the API server
+	API      string
+	Username string
+	Password string // sent as a bearer token if there is no username
+	TraceID  string
+}
+
+// traceSpan is a span of either backend's traces
+type traceSpan struct {
+	id       string
+	parent   string
+	service  string
+	name     string
+	start    time.Time
+	duration time.Duration
+	failed   bool
+}
+
+// traceResponse holds the answers of both backends to GET /api/traces/<id>:
+// Jaeger's data and Tempo's OTLP batches
+type traceResponse struct {
+	Data []struct {
+		Spans []struct {
+			SpanID        string `json:"spanID"`
+			OperationName string `json:"operationName"`
+			References    []struct {
+				RefType string `json:"refType"`
+				SpanID  string `json:"spanID"`
+			} `json:"references"`
+			StartTime int64  `json:"startTime"` // µs
+			Duration  int64  `json:"duration"`  // µs
+			ProcessID string `json:"processID"`
+			Tags      []struct {
+				Key   string      `json:"key"`
+				Value interface{} `json:"value"`
+			} `json:"tags"`
+		} `json:"spans"`
+		Processes map[string]struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"processes"`
+	} `json:"data"`
+	Errors []struct {
+		Msg string `json:"msg"`
+	} `json:"errors"`
+	Batches []otlpResourceSpans `json:"batches"`
+}
+
+// otlpResourceSpans are the spans of one service in OTLP JSON
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []struct {
+			Key   string `json:"key"`
+			Value struct {
+				StringValue string `json:"stringValue"`
+			} `json:"value"`
+		} `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans                  []struct{ Spans []otlpSpan } `json:"scopeSpans"`
+	InstrumentationLibrarySpans []struct{ Spans []otlpSpan } `json:"instrumentationLibrarySpans"`
+}
+
+// otlpSpan is a span in OTLP JSON, where 64 bit numbers are strings
+type otlpSpan struct {
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano json.RawMessage `json:"startTimeUnixNano"`
+	EndTimeUnixNano   json.RawMessage `json:"endTimeUnixNano"`
+	Status            struct {
+		Code json.RawMessage `json:"code"`
+	} `json:"status"`
+}
+
+// DescribeTrace fetches a trace from a Jaeger or Tempo query API and sums
+// it up: its spans per service with errors, and the span tree with each
+// span's start offset and duration
+func (c *Client) DescribeTrace(ctx context.Context, q TraceQuery) (string, error) {
+	body, err := c.getAPI(ctx, apiRequest{
+		base:       q.API,
+		path:       "api/traces/" + q.TraceID,
+		username:   q.Username,
+		password:   q.Password,
+		accept:     "application/json",
+		name:       "the tracing API",
+		credential: "tracing",
+	})
+	if err != nil && len(body) == 0 {
+		return "", err
+	}
+	var resp traceResponse
+	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(body)))
+		}
+		return "", fmt.Errorf("failed to read the trace: %w", jsonErr)
+	}
+	if len(resp.Errors) > 0 {
+		return "", fmt.Errorf("trace %s: %s", q.TraceID, resp.Errors[0].Msg)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	spans := resp.jaegerSpans()
+	if len(spans) == 0 {
+		spans = resp.otlpSpans()
+	}
+	if len(spans) == 0 {
+		return "", fmt.Errorf("trace %s not found", q.TraceID)
+	}
+	return describeTraceSpans(q.TraceID, spans), nil
+}
+
+// jaegerSpans returns the spans of a Jaeger answer
+func (r *traceResponse) jaegerSpans() []traceSpan {
+	var spans []traceSpan
+	for _, trace := range r.Data {
+		for _, s := range trace.Spans {
+			span := traceSpan{
+				id:       s.SpanID,
+				service:  trace.Processes[s.ProcessID].ServiceName,
+				name:     s.OperationName,
+				start:    time.UnixMicro(s.StartTime),
+				duration: time.Duration(s.Duration) * time.Microsecond,
+			}
+			for _, ref := range s.References {
+				if ref.RefType == "CHILD_OF" {
+					span.parent = ref.SpanID
+				}
+			}
+			for _, tag := range s.Tags {
+				if (tag.Key == "error" && tag.Value == true) || (tag.Key == "otel.status_code" && tag.Value == "ERROR") {
+					span.failed = true
+				}
+			}
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// otlpSpans returns the spans of a Tempo answer
+func (r *traceResponse) otlpSpans() []traceSpan {
+	var spans []traceSpan
+	for _, batch := range r.Batches {
+		service := ""
+		for _, attr := range batch.Resource.Attributes {
+			if attr.Key == "service.name" {
+				service = attr.Value.StringValue
+			}
+		}
+		scopes := append(batch.ScopeSpans, batch.InstrumentationLibrarySpans...)
+		for _, scope := range scopes {
+			for _, s := range scope.Spans {
+				start, end := otlpNanos(s.StartTimeUnixNano), otlpNanos(s.EndTimeUnixNano)
+				code := strings.Trim(string(s.Status.Code), `"`)
+				spans = append(spans, traceSpan{
+					id:       s.SpanID,
+					parent:   s.ParentSpanID,
+					service:  service,
+					name:     s.Name,
+					start:    time.Unix(0, start),
+					duration: time.Duration(end - start),
+					failed:   code == "2" || code == "STATUS_CODE_ERROR",
+				})
+			}
+		}
+	}
+	return spans
+}
+
+// otlpNanos reads a nanosecond timestamp written as a string or number
+func otlpNanos(raw json.RawMessage) int64 {
+	n, _ := strconv.ParseInt(strings.Trim(string(raw), `"`), 10, 64)
+	return n
+}
+
+// describeTraceSpans renders the summary of a trace's spans
+func describeTraceSpans(traceID string, spans []traceSpan) string {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+	begin, end := spans[0].start, spans[0].start
+	ids := make(map[string]bool, len(spans))
+	type serviceStats struct{ spans, failed int }
+	services := make(map[string]*serviceStats)
+	var names []string
+	failed := 0
+	for _, s := range spans {
+		ids[s.id] = true
+		if e := s.start.Add(s.duration); e.After(end) {
+			end = e
+		}
+		stats, ok := services[s.service]
+		if !ok {
+			stats = &serviceStats{}
+			services[s.service] = stats
+			names = append(names, s.service)
+		}
+		stats.spans++
+		if s.failed {
+			stats.failed++
+			failed++
+		}
+	}
+
+	w := newDescribeWriter()
+	w.line(0, "Trace:\t%s", traceID)
+	w.line(0, "Started:\t%s", begin.Local().Format("2006-01-02 15:04:05.000"))
+	w.line(0, "Duration:\t%s", spanDuration(end.Sub(begin)))
+	w.line(0, "Spans:\t%d across %d services, %d with errors", len(spans), len(services), failed)
+	w.line(0, "Services:")
+	w.line(1, "Service\tSpans\tErrors")
+	w.line(1, "-------\t-----\t------")
+	for _, name := range names {
+		w.line(1, "%s\t%d\t%d", name, services[name].spans, services[name].failed)
+	}
+
+	// Spans whose parent is not in the trace, e.g. not sampled, are roots
+	children := make(map[string][]traceSpan)
+	root := &topologyNode{}
+	for _, s := range spans {
+		parent := s.parent
+		if !ids[parent] || parent == s.id {
+			parent = ""
+		}
+		children[parent] = append(children[parent], s)
+	}
+	drawn := 0
+	var add func(node *topologyNode, parent string)
+	add = func(node *topologyNode, parent string) {
+		for _, s := range children[parent] {
+			if drawn == maxTraceSpans {
+				return
+			}
+			drawn++
+			label := fmt.Sprintf("%s %s  +%s  %s", s.service, s.name, spanDuration(s.start.Sub(begin)), spanDuration(s.duration))
+			if s.failed {
+				label += "  ✗ error"
+			}
+			add(node.add("%s", label), s.id)
+		}
+	}
+	add(root, "")
+
+	var b strings.Builder
+	b.WriteString(w.String())
+	b.WriteString("\n")
+	for _, top := range root.children {
+		b.WriteString(top.label + "\n")
+		top.render(&b, "")
+	}
+	if drawn < len(spans) {
+		fmt.Fprintf(&b, "… %d more spans\n", len(spans)-drawn)
+	}
+	return b.String()
+}
+
+// spanDuration renders a span duration such as 850µs, 12.3ms or 1.25s
+func spanDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	case d < time.Second:
+		return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000)
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
@@ -0,0 +1,233 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// workloadKind is a Deployment-like custom resource: it has spec.replicas,
+// spec.selector and spec.template like a Deployment does
+type workloadKind struct {
+	prefix   string // used in workload references, e.g. "rollout/my-app"
+	kind     string
+	resource schema.GroupVersionResource
+}
+
+var workloadKinds = []workloadKind{
+	{prefix: "rollout", kind: "Rollout", resource: schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}},
+	{prefix: "cloneset", kind: "CloneSet", resource: schema.GroupVersionResource{Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets"}},
+}
+
+// parseWorkloadRef splits a "kind/name" workload reference. Plain names are
+// Deployments and return a nil kind.
+func parseWorkloadRef(ref string) (*workloadKind, string) {
+	prefix, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, ref
+	}
+	for i := range workloadKinds {
+		if workloadKinds[i].prefix == prefix {
+			return &workloadKinds[i], name
+		}
+	}
+	return nil, ref
+}
+
+// IsCustomWorkload reports whether a workload reference names a
+// Deployment-like custom resource such as an Argo Rollout rather than a
+// Deployment
+func IsCustomWorkload(ref string) bool {
+	kind, _ := parseWorkloadRef(ref)
+	return kind != nil
+}
+
+// WorkloadRef returns the workload reference of a resource instance if it is
+// a Deployment or a Deployment-like custom resource, e.g. "rollout/my-app"
+func WorkloadRef(res APIResource, name string) (string, bool) {
+	if res.Group == "apps" && res.Name == "deployments" {
+		return name, true
+	}
+	for _, kind := range workloadKinds {
+		if res.Group == kind.resource.Group && res.Name == kind.resource.Resource {
+			return kind.prefix + "/" + name, true
+		}
+	}
+	return "", false
+}
+
+// ListWorkloads returns the deployments in a namespace followed by the
+// Deployment-like custom resources as "kind/name" references. Custom
+// resources whose CRD is not installed or that may not be listed are skipped.
+func (c *Client) ListWorkloads(ctx context.Context, namespace string) ([]string, error) {
+	names, err := c.ListDeployments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if c.dynamic == nil {
+		return names, nil
+	}
+
+	for _, kind := range workloadKinds {
+		list, err := c.dynamic.Resource(kind.resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %ss: %w", kind.kind, err)
+		}
+		custom := make([]string, 0, len(list.Items))
+		for _, item := range list.Items {
+			custom = append(custom, kind.prefix+"/"+item.GetName())
+		}
+		sort.Strings(custom)
+		names = append(names, custom...)
+	}
+	return names, nil
+}
+
+// WorkloadExists reports whether a deployment or custom workload reference
+// still exists. Errors other than not found are returned.
+func (c *Client) WorkloadExists(ctx context.Context, namespace, ref string) (bool, error) {
+	_, err := c.GetDeployment(ctx, namespace, ref)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// getWorkload fetches a custom workload
+func (c *Client) getWorkload(ctx context.Context, namespace string, kind *workloadKind, name string) (*unstructured.Unstructured, error) {
+	if c.dynamic == nil {
+		return nil, fmt.Errorf("%s %s: custom workloads need a dynamic client", kind.kind, name)
+	}
+	return c.dynamic.Resource(kind.resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// workloadAsDeployment presents a custom workload as a Deployment so that
+// read-only views (pods, env, describe, rbac) work unchanged. Only the
+// metadata and the replicas, selector and template of the spec are kept.
+func workloadAsDeployment(obj *unstructured.Unstructured) (*appsv1.Deployment, error) {
+	deployment := &appsv1.Deployment{}
+	metadata, _, _ := unstructured.NestedMap(obj.Object, "metadata")
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(metadata, &deployment.ObjectMeta); err != nil {
+		return nil, fmt.Errorf("failed to read %s metadata: %w", obj.GetKind(), err)
+	}
+
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if replicas, ok, _ := unstructured.NestedInt64(spec, "replicas"); ok {
+		r := int32(replicas)
+		deployment.Spec.Replicas = &r
+	}
+	if selector, ok, _ := unstructured.NestedMap(spec, "selector"); ok {
+		deployment.Spec.Selector = &metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selector, deployment.Spec.Selector); err != nil {
+			return nil, fmt.Errorf("failed to read %s selector: %w", obj.GetKind(), err)
+		}
+	}
+	if template, ok, _ := unstructured.NestedMap(spec, "template"); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(template, &deployment.Spec.Template); err != nil {
+			return nil, fmt.Errorf("failed to read %s pod template: %w", obj.GetKind(), err)
+		}
+	} else if ref, ok, _ := unstructured.NestedString(spec, "workloadRef", "name"); ok {
+		return nil, fmt.Errorf("%s %s takes its pod template from Deployment %s (workloadRef); select that instead", obj.GetKind(), obj.GetName(), ref)
+	}
+	if deployment.Spec.Selector == nil {
+		return nil, fmt.Errorf("%s %s has no selector", obj.GetKind(), obj.GetName())
+	}
+
+	status := &deployment.Status
+	for field, dst := range map[string]*int32{
+		"replicas":          &status.Replicas,
+		"updatedReplicas":   &status.UpdatedReplicas,
+		"readyReplicas":     &status.ReadyReplicas,
+		"availableReplicas": &status.AvailableReplicas,
+	} {
+		if v, ok, _ := unstructured.NestedInt64(obj.Object, "status", field); ok {
+			*dst = int32(v)
+		}
+	}
+	if status.Replicas > status.AvailableReplicas {
+		status.UnavailableReplicas = status.Replicas - status.AvailableReplicas
+	}
+	return deployment, nil
+}
+
+// scaleWorkload sets spec.replicas of a custom workload
+func (c *Client) scaleWorkload(ctx context.Context, namespace string, kind *workloadKind, name string, replicas int32) error {
+	if c.dynamic == nil {
+		return fmt.Errorf("%s %s: custom workloads need a dynamic client", kind.kind, name)
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"replicas": replicas}})
+	if err != nil {
+		return err
+	}
+	_, err = c.dynamic.Resource(kind.resource).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to scale %s %s: %w", kind.kind, name, err)
+	}
+	return nil
+}
+
+// updateWorkloadImages sets the images of containers, keyed by name, in a
+// custom workload's pod template
+func (c *Client) updateWorkloadImages(ctx context.Context, namespace string, kind *workloadKind, name string, images map[string]string) error {
+	obj, err := c.getWorkload(ctx, namespace, kind, name)
+	if err != nil {
+		return err
+	}
+
+	containers, ok, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil || !ok {
+		return fmt.Errorf("%s %s has no pod template containers", kind.kind, name)
+	}
+	for containerName, image := range images {
+		found := false
+		for i, item := range containers {
+			container, ok := item.(map[string]interface{})
+			if ok && container["name"] == containerName {
+				container["image"] = image
+				containers[i] = container
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("container %s not found in %s %s", containerName, kind.kind, name)
+		}
+	}
+	if err := unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+		return err
+	}
+
+	setChangeCause(ctx, obj)
+	_, err = c.dynamic.Resource(kind.resource).Namespace(namespace).Update(ctx, obj, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update %s %s: %w", kind.kind, name, err)
+	}
+	return nil
+}
+
+// workloadManifest returns a custom workload as YAML
+func (c *Client) workloadManifest(ctx context.Context, namespace string, kind *workloadKind, name string, keepManagedFields bool) (string, error) {
+	obj, err := c.getWorkload(ctx, namespace, kind, name)
+	if err != nil {
+		return "", err
+	}
+	return unstructuredManifest(obj, keepManagedFields)
+}
+
+// errDeploymentOnly is returned by operations that only work on Deployments
+func errDeploymentOnly(operation string, kind *workloadKind, name string) error {
+	return fmt.Errorf("%s is only supported for Deployments, not %s %s", operation, kind.kind, name)
+}
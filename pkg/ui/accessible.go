@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// accessible renders the views for screen readers, see SetAccessible
+var accessible bool
+
+// SetAccessible switches accessible rendering on: ASCII only, words where
+// colors tell a state, and a status line on top announcing what is shown.
+// It must be called before any model is created.
+func SetAccessible(on bool) {
+	accessible = on
+}
+
+// asciiSymbols replaces the symbols of the views with ASCII, or with words
+// where the symbol carries meaning. Longer sequences come first.
+var asciiSymbols = strings.NewReplacer(
+	" • ", ", ",
+	"↑↓", "Up/Down",
+	"←→", "Left/Right",
+	"↑", "Up",
+	"↓", "Down",
+	"→", "->",
+	"←", "<-",
+	"╭", "+", "╮", "+", "╰", "+", "╯", "+",
+	"┌", "+", "┐", "+", "└", "+", "┘", "+",
+	"├", "+", "┤", "+", "┬", "+", "┴", "+", "┼", "+",
+	"─", "-", "━", "-", "│", "|", "┃", "|",
+	"▸", ">", "▶", ">", "◂", "<", "◀", "<",
+	"●", "*", "○", "o", "•", "-",
+	"✓", "[ok]", "✔", "[ok]",
+	"✗", "[error]", "✘", "[error]",
+	"⚠", "[warning]",
+	"★", "[favorite]",
+	"⏳", "[wait]",
+	"…", "...",
+)
+
+// boxEnd matches the right border of a boxed line, with its colors and the
+// spaces after it
+var boxEnd = regexp.MustCompile(`(\x1b\[[0-9;]*m)*[|+](\x1b\[[0-9;]*m)*[ ]*$`)
+
+// accessibleText turns a rendered view into ASCII: mapped symbols become
+// their ASCII or words, other symbols such as emoji are dropped with the
+// space after them. Letters of any script stay, and boxes keep their width.
+func accessibleText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = asciiLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// asciiLine turns one line into ASCII, padding a boxed line back to its
+// width so the right border stays aligned
+func asciiLine(line string) string {
+	var b strings.Builder
+	dropped := false
+	for _, r := range asciiSymbols.Replace(line) {
+		if dropped && r == ' ' {
+			dropped = false
+			continue
+		}
+		dropped = false
+		if unicode.Is(unicode.So, r) || r == '\u200d' || r == '\ufe0f' {
+			dropped = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	ascii := b.String()
+
+	diff := ansi.StringWidth(line) - ansi.StringWidth(ascii)
+	end := boxEnd.FindStringIndex(ascii)
+	if diff == 0 || end == nil {
+		return ascii
+	}
+	before, border := ascii[:end[0]], ascii[end[0]:]
+	if diff > 0 {
+		return before + strings.Repeat(" ", diff) + border
+	}
+	trimmed := strings.TrimRight(before, " ")
+	return trimmed + strings.Repeat(" ", max(0, len(before)-len(trimmed)+diff)) + border
+}
+
+// accessibleView renders a view for screen readers in accessible mode
+func accessibleView(view string) string {
+	if !accessible {
+		return view
+	}
+	return accessibleText(view)
+}
+
+// announcement is the status line of accessible mode: what is shown and
+// what khelper is doing, in one line that changes with the state
+func (m Model) announcement() string {
+	status := "Status: " + m.stateSummary()
+	if parts := m.statusParts(); len(parts) > 0 {
+		status += "; " + strings.Join(parts, ", ")
+	}
+	return status
+}
+
+// stateSummary says what the current screen shows
+func (m Model) stateSummary() string {
+	if m.showHelp {
+		return "Keyboard shortcuts, any key closes them"
+	}
+	if list := m.shownList(); list != nil {
+		return list.Summary()
+	}
+	name := "command"
+	if m.command != nil {
+		name = m.command.Name
+	}
+	switch m.state {
+	case StateInputValue:
+		return fmt.Sprintf("Input for %s: %s", name, m.valueInput.Placeholder)
+	case StateExecuting:
+		if m.exec.isWaiting() {
+			return "Waiting for the cluster to run " + name
+		}
+		return "Running " + name
+	case StateShowResult:
+		if m.err != nil {
+			reason, _, _ := strings.Cut(m.err.Error(), "\n")
+			return fmt.Sprintf("%s failed: %s", name, reason)
+		}
+		return fmt.Sprintf("Result of %s, Enter continues", name)
+	case StateViewLogs:
+		return "Logs of " + m.deployment
+	case StateBrowseResources:
+		return "Resources of namespace " + m.namespace
+	case StateNamespaceOverview:
+		return "Overview of namespace " + m.namespace
+	case StateShellPane:
+		return "Shell in " + m.pod
+	case StateJobOutput:
+		return "Output of a background job"
+	case StateEditImages:
+		return "Images of " + m.deployment
+	}
+	return ""
+}
+
+// shownList returns the list the current screen shows, nil if none
+func (m *Model) shownList() *FuzzyList {
+	switch m.state {
+	case StateResumePrompt:
+		return &m.resumeSelector
+	case StateSelectKubeConfig:
+		return &m.kcSelector
+	case StateSelectNamespace:
+		return &m.nsSelector
+	case StateSelectDeployment:
+		return &m.depSelector
+	case StateSelectCommand:
+		return &m.cmdSelector
+	case StateSelectPod:
+		return &m.podSelector
+	case StateSelectContainer:
+		return &m.contSelector
+	case StateSelectAssetFolder:
+		return &m.assetSelector
+	case StateSelectLocalPath:
+		return &m.localPathSelector.list
+	case StateJobs:
+		return &m.jobSelector
+	case StateQuickOpen:
+		return &m.quickSelector
+	case StateSelectConfig:
+		return &m.configSelector
+	case StateSelectCleanup:
+		return &m.cleanupSelector
+	case StateBrowseFiles:
+		return &m.fileSelector
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"strings"
+)
+
+// Log lines may carry ANSI escape codes, such as the colors of a logger
+// writing to a terminal. The log viewer searches and exports the text
+// without them and shows them only when asked to. Stripping and
+// highlighting share escapeLen, so positions in the stripped text map back
+// to the line as received.
+
+// escapeLen returns the length of the escape sequence at the start of s,
+// which starts with ESC. Unterminated sequences run to the end of s.
+func escapeLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameters up to a final byte in @ to ~
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']', 'P', '_', '^', 'X':
+		// OSC, DCS and the other strings: up to BEL or ST
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	case '(', ')', '*', '+':
+		// Character set selection
+		return min(3, len(s))
+	}
+	return 2
+}
+
+// stripANSI removes the escape sequences from s
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			i += escapeLen(s[i:])
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// highlightANSI highlights the case-insensitive matches of query in a line
+// with escape sequences. Matches are found in the stripped text; a match
+// drops the line's own colors and restores them after it.
+func highlightANSI(line, query string) string {
+	if query == "" {
+		return line
+	}
+	lower := lowerInPlace(stripANSI(line))
+	var ranges [][2]int
+	for start := 0; ; {
+		i := strings.Index(lower[start:], query)
+		if i < 0 {
+			break
+		}
+		ranges = append(ranges, [2]int{start + i, start + i + len(query)})
+		start += i + len(query)
+	}
+	if len(ranges) == 0 {
+		return line
+	}
+
+	matchOn, matchOff, _ := strings.Cut(MatchStyle.Render("x"), "x")
+	var b strings.Builder
+	var active []string // SGR sequences since the last reset
+	pos, r, inMatch := 0, 0, false
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' {
+			n := escapeLen(line[i:])
+			seq := line[i : i+n]
+			if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+				if seq == "\x1b[m" || seq == "\x1b[0m" {
+					active = nil
+				} else {
+					active = append(active, seq)
+				}
+			}
+			if !inMatch {
+				b.WriteString(seq)
+			}
+			i += n
+			continue
+		}
+		if !inMatch && r < len(ranges) && pos == ranges[r][0] {
+			b.WriteString(matchOn)
+			inMatch = true
+		}
+		b.WriteByte(line[i])
+		pos++
+		i++
+		if inMatch && pos == ranges[r][1] {
+			b.WriteString(matchOff)
+			b.WriteString(strings.Join(active, ""))
+			inMatch = false
+			r++
+		}
+	}
+	if inMatch {
+		b.WriteString(matchOff)
+	}
+	return b.String()
+}
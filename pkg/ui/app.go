@@ -6,23 +6,32 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"khelper/pkg/config"
 	"khelper/pkg/k8s"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // AppState represents the current state of the application
 type AppState int
 
 const (
-	StateSelectKubeConfig AppState = iota
+	StateResumePrompt AppState = iota
+	StateSelectKubeConfig
 	StateSelectNamespace
 	StateSelectDeployment
 	StateSelectCommand
@@ -34,6 +43,16 @@ const (
 	StateExecuting
 	StateShowResult
 	StateViewLogs
+	StateBrowseResources
+	StateNamespaceOverview
+	StateShellPane
+	StateJobs
+	StateJobOutput
+	StateEditImages
+	StateQuickOpen
+	StateSelectConfig
+	StateSelectCleanup
+	StateBrowseFiles
 )
 
 // Command represents available commands
@@ -44,58 +63,131 @@ type Command struct {
 	NeedsContainer bool
 	NeedsInput     bool
 	InputPrompt    string
+	OptionalInput  bool // an empty input is accepted
+	NeedsLocalFS   bool
+	ComparesPods   bool // two pods are marked in the pod list
+	DeploymentOnly bool // hidden for custom workloads such as Argo Rollouts
+	Mutating       bool // changes the cluster or runs commands in it, hidden when read-only
+	Offline        bool // usable offline: needs no cluster or ends in a result that can wait for it
 }
 
 var AvailableCommands = []Command{
-	{Name: "logs", Description: "View container logs", NeedsPod: true, NeedsContainer: true},
+	{Name: "logs", Description: "View container logs", NeedsPod: true, NeedsContainer: true, Offline: true},
 	{Name: "logs-follow", Description: "Follow container logs", NeedsPod: true, NeedsContainer: true},
-	{Name: "shell", Description: "Open shell (auto-detects bash/sh/ash)", NeedsPod: true, NeedsContainer: true},
-	{Name: "fast-deploy", Description: "Deploy local dist to /app/assets", NeedsPod: true, NeedsContainer: true},
-	{Name: "scale", Description: "Scale deployment", NeedsInput: true, InputPrompt: "Enter replica count:"},
-	{Name: "update-image", Description: "Update container image", NeedsContainer: true, NeedsInput: true, InputPrompt: "Enter new image:"},
-	{Name: "port-forward", Description: "Forward port to pod", NeedsPod: true, NeedsInput: true, InputPrompt: "Enter ports (local:remote):"},
-	{Name: "rollback", Description: "Rollback deployment", NeedsInput: true, InputPrompt: "Enter revision number:"},
-	{Name: "set-env", Description: "Set environment variable", NeedsContainer: true, NeedsInput: true, InputPrompt: "Enter KEY=VALUE:"},
-	{Name: "list-env", Description: "List environment variables", NeedsContainer: true},
-	{Name: "list-pods", Description: "List all pods"},
-	{Name: "list-revisions", Description: "List deployment revisions"},
-	{Name: "ingress", Description: "Show related ingresses"},
-	{Name: "describe", Description: "Describe deployment"},
+	{Name: "logs-all", Description: "Follow container logs from all pods", NeedsContainer: true},
+	{Name: "logs-history", Description: "Search older logs in Loki, Elasticsearch or the API server", NeedsContainer: true, NeedsInput: true, OptionalInput: true, InputPrompt: "Enter a range: 6h, 2d, 14:00-15:00 or 2024-05-01 14:00-15:00 (default: the last hour):"},
+	{Name: "shell", Description: "Open shell (auto-detects bash/sh/ash)", Mutating: true, NeedsPod: true, NeedsContainer: true},
+	{Name: "run-job", Description: "Run a command in the container as a background job", Mutating: true, NeedsPod: true, NeedsContainer: true, NeedsInput: true, InputPrompt: "Enter command to run with sh -c (e.g. ./migrate.sh up):"},
+	{Name: "template-job", Description: "Run a command in a Job from the template, follow its logs", Mutating: true, NeedsContainer: true, NeedsInput: true, InputPrompt: "Enter command to run with sh -c in a Job (e.g. rake db:migrate):"},
+	{Name: "jobs", Description: "List background jobs, view their output, cancel them", Offline: true},
+	{Name: "attach", Description: "Attach to the container's main process", Mutating: true, NeedsPod: true, NeedsContainer: true},
+	{Name: "fast-deploy", Description: "Deploy local dist to /app/assets", Mutating: true, NeedsPod: true, NeedsContainer: true, NeedsLocalFS: true},
+	{Name: "scale", Description: "Scale deployment, now or at a given time", Mutating: true, NeedsInput: true, InputPrompt: "Enter replicas or prev/min/max (HPA), optionally at HH:MM or in 30m:", Offline: true},
+	{Name: "update-image", Description: "Update container image", Mutating: true, NeedsContainer: true, NeedsInput: true, InputPrompt: "Enter new image:", Offline: true},
+	{Name: "update-images", Description: "Edit all container images, roll out together", Mutating: true},
+	{Name: "debug-sidecar", Description: "Add or remove a debug sidecar in the pods' network", Mutating: true, DeploymentOnly: true, NeedsInput: true, OptionalInput: true, InputPrompt: debugImagePrompt, Offline: true},
+	{Name: "experiment", Description: "Run one pod with another image, limits or env", Mutating: true, NeedsContainer: true, NeedsInput: true, OptionalInput: true, InputPrompt: experimentPrompt, Offline: true},
+	{Name: "debug-copy", Description: "Start a copy of a pod with a debug sidecar", Mutating: true, NeedsPod: true, NeedsInput: true, OptionalInput: true, InputPrompt: debugImagePrompt, Offline: true},
+	{Name: "nettest", Description: "Test DNS, TCP and HTTP from inside the container", Mutating: true, NeedsPod: true, NeedsContainer: true, NeedsInput: true, OptionalInput: true, InputPrompt: "Enter names to resolve, host:port to connect to, URLs to GET (default: cluster DNS and API server):", Offline: true},
+	{Name: "port-forward", Description: "Forward port to pod", NeedsPod: true, NeedsInput: true, InputPrompt: "Enter ports (local:remote, comma-separated):"},
+	{Name: "rollback", Description: "Rollback deployment", Mutating: true, DeploymentOnly: true, NeedsInput: true, InputPrompt: "Enter revision number:", Offline: true},
+	{Name: "set-env", Description: "Set environment variable", Mutating: true, DeploymentOnly: true, NeedsContainer: true, NeedsInput: true, InputPrompt: "Enter KEY=VALUE:", Offline: true},
+	{Name: "config-rollout", Description: "Roll the pods if their ConfigMaps or Secrets changed", Mutating: true, DeploymentOnly: true, Offline: true},
+	{Name: "edit-config", Description: "Edit a ConfigMap or Secret in $EDITOR, with backup", Mutating: true},
+	{Name: "rename", Description: "Rename the deployment, moving its services over", Mutating: true, DeploymentOnly: true, NeedsInput: true, InputPrompt: "Enter the new name of the deployment:", Offline: true},
+	{Name: "patch", Description: "Patch the deployment in $EDITOR, previewing the diff", Mutating: true, DeploymentOnly: true},
+	{Name: "restore-config", Description: "Restore a ConfigMap or Secret from a backup", Mutating: true},
+	{Name: "wait", Description: "Wait until the rollout is complete and ready", NeedsInput: true, OptionalInput: true, InputPrompt: "Enter timeout (default 5m):"},
+	{Name: "undo", Description: "Undo the last scale, image, env or rollback change", Mutating: true},
+	{Name: "list-env", Description: "List environment variables", NeedsContainer: true, Offline: true},
+	{Name: "files", Description: "Browse and download the container's files", Mutating: true, NeedsPod: true, NeedsContainer: true},
+	{Name: "config-files", Description: "Show mounted ConfigMap and Secret files, find stale ones", Mutating: true, NeedsPod: true, NeedsContainer: true},
+	{Name: "list-pods", Description: "List all pods", Offline: true},
+	{Name: "whoami", Description: "Show the user, groups, cluster and namespace in use", Offline: true},
+	{Name: "cleanup", Description: "Delete evicted, completed, failed and crash looping pods", Mutating: true, NeedsInput: true, OptionalInput: true, InputPrompt: "Enter the restart count from which crash looping pods are listed (default 5):"},
+	{Name: "certs", Description: fmt.Sprintf("List TLS secrets, flagging those expiring within %d days", k8s.CertWarnDays), Offline: true},
+	{Name: "pull-secrets", Description: "Check image pull secrets and registry logins", Offline: true},
+	{Name: "janitor", Description: "Find stuck pods, PVCs, Jobs and ReplicaSets", Offline: true},
+	{Name: "list-revisions", Description: "List deployment revisions", DeploymentOnly: true, Offline: true},
+	{Name: "history", Description: "Timeline of revisions: causes, images and changes", DeploymentOnly: true, Offline: true},
+	{Name: "pressure", Description: "OOMKills, restarts and usage against limits", Offline: true},
+	{Name: "ingress", Description: "Show the ingresses with TLS expiry and backend checks", Offline: true},
+	{Name: "gateway", Description: "Show the Gateway API HTTPRoutes with backend checks", Offline: true},
+	{Name: "drain-impact", Description: "Preview draining a node: evictions, PDBs, capacity", NeedsInput: true, OptionalInput: true, InputPrompt: drainImpactPrompt, Offline: true},
+	{Name: "map", Description: "Draw the deployment's services, ingresses, pods and nodes", Offline: true},
+	{Name: "deps", Description: "Guess which workloads of the namespace call which", Offline: true},
+	{Name: "metrics", Description: "Chart Prometheus metrics of the pods", NeedsInput: true, OptionalInput: true, InputPrompt: "Enter a query name or PromQL using {{.Namespace}}, {{.Deployment}}, {{.PodRegex}} (empty: all predefined):", Offline: true},
+	{Name: "describe", Description: "Describe deployment", Offline: true},
+	{Name: "describe-pod", Description: "Describe a pod: states, conditions, volumes, events", NeedsPod: true, Offline: true},
+	{Name: "yaml", Description: "Show the deployment's live YAML manifest", Offline: true},
+	{Name: "pod-yaml", Description: "Show a pod's live YAML manifest", NeedsPod: true, Offline: true},
+	{Name: "rbac", Description: "Show the service account and its allowed verbs", Offline: true},
+	{Name: "compare-clusters", Description: "Compare the deployment with another cluster's", NeedsLocalFS: true},
+	{Name: "compare-pods", Description: "Compare two pods' images, env, resources and placement", NeedsPod: true, ComparesPods: true, Offline: true},
+	{Name: "events", Description: "Tail the namespace's events in real time", NeedsInput: true, OptionalInput: true, InputPrompt: "Filter [warning|normal] [reason=A,B] [kind=Pod,...] (empty: all events):"},
+	{Name: "resources", Description: "Browse, view and delete any resource kind"},
+	{Name: "sa-token", Description: "Mint a service account token and kubeconfig", Mutating: true, NeedsInput: true, OptionalInput: true, InputPrompt: "Enter [service-account] [duration] (default: deployment's account, 1h):", Offline: true},
 }
 
 // Messages
 type (
 	NamespacesLoadedMsg struct {
 		namespaces []string
+		cachedAt   time.Time // when the offline cache shown instead was saved
 		err        error
 	}
 	DeploymentsLoadedMsg struct {
 		deployments []string
+		cachedAt    time.Time
 		err         error
 	}
+	// sessionCheckedMsg reports whether the remembered target still exists
+	sessionCheckedMsg struct {
+		namespaceGone  bool
+		deploymentGone bool
+	}
 	PodsLoadedMsg struct {
-		pods []string
-		err  error
+		pods     []k8s.PodEntry
+		cachedAt time.Time
+		err      error
 	}
 	ContainersLoadedMsg struct {
 		containers []string
+		cachedAt   time.Time
 		err        error
 	}
 	CommandResultMsg struct {
 		result string
 		err    error
+		// kustomize is set for the result of a deployment kustomize built
+		kustomize *kustomizeResult
 	}
 	ExecCompleteMsg struct {
 		err error
 	}
 	LogsLoadedMsg struct {
-		logs string
-		err  error
+		logs      string
+		fetchedAt time.Time
+		oldest    time.Time
+		more      bool
+		err       error
+	}
+	ManifestLoadedMsg struct {
+		manifest  string
+		kustomize *kustomizeResult
+		err       error
+	}
+	olderLogsMsg struct {
+		lines  []string
+		oldest time.Time
+		more   bool
+		err    error
 	}
 	LogLineMsg struct {
 		line string
 	}
 	LogStreamEndMsg struct {
+		gen int
 		err error
 	}
 	KubeConfigsLoadedMsg struct {
@@ -103,7 +195,7 @@ type (
 		err     error
 	}
 	KubeConfigChangedMsg struct {
-		client *k8s.Client
+		client k8s.ClientInterface
 		path   string
 		err    error
 	}
@@ -120,7 +212,7 @@ type (
 // Model is the main application model
 type Model struct {
 	config     *config.Config
-	k8sClient  *k8s.Client
+	k8sClient  k8s.ClientInterface
 	state      AppState
 	prevStates []AppState
 
@@ -133,16 +225,19 @@ type Model struct {
 	inputValue  string
 	assetFolder string
 
-	kcSelector       FuzzyList
-	nsSelector       FuzzyList
-	depSelector      FuzzyList
-	cmdSelector      FuzzyList
-	podSelector      FuzzyList
-	contSelector     FuzzyList
-	assetSelector    FuzzyList
-	localPathSelector FuzzyList
-	valueInput       textinput.Model
-	logViewer        LogViewer
+	resumeSelector    FuzzyList
+	kcSelector        FuzzyList
+	nsSelector        FuzzyList
+	depSelector       FuzzyList
+	cmdSelector       FuzzyList
+	podSelector       FuzzyList
+	contSelector      FuzzyList
+	assetSelector     FuzzyList
+	localPathSelector PathPicker
+	valueInput        textinput.Model
+	logViewer         LogViewer
+	resultViewer      ResultViewer
+	resources         ResourcesModel
 
 	result       string
 	err          error
@@ -151,14 +246,99 @@ type Model struct {
 	streaming    bool
 	streamCtx    context.Context
 	cancelStream context.CancelFunc
+	streamGen    int       // identifies the current stream, stale messages are dropped
+	logsUntil    time.Time // end of what the log viewer already shows
+	logPrefixer  *LogPrefixer
+	logsOldest   time.Time // API timestamp of the oldest loaded line
+	loadingOlder bool
+	eventFilter  k8s.EventFilter
+	logHistory   k8s.LogRangeQuery // the last page logs-history loaded
+	historyMore  bool              // its limit left out older lines of its range
+	listWatch    *listWatch        // refreshes the shown pod or deployment list
+
+	forwards       []*runningForward // saved port-forwards started with the TUI
+	forwardChanges chan struct{}     // signals that one of them connected or failed
+
+	showManagedFields bool
+	comparePods       [2]string
 
 	showNamespaceChange  bool
+	comparingClusters    bool // the kubeconfig list picks the cluster to compare with
 	showKubeConfigChange bool
 	initialClientErr     error
+	session              *config.Session
+	inCluster            bool
+	warning              string // stale remembered selections, shown above the lists
+	scheduledScale       *scheduledScale
+	undoScale            *scaleUndo
+	debugSidecar         *debugSidecar
+	pullSecretFix        *pullSecretFix
+	kustomize            *kustomizeResult
+	oneOff               *oneOffPod
+	pendingUndo          *config.Change       // shown for confirmation before it is reverted
+	pendingLint          *lintedMsg           // a change whose lint findings wait for confirmation
+	pendingPatch         *k8s.Patch           // a patch previewed for confirmation before it is applied
+	pendingRename        *k8s.Rename          // a rename at a checkpoint, y runs its next step
+	pendingConfigRestore *config.ConfigBackup // data put back once confirmed with y
+	pendingOnline        tea.Cmd              // a change prepared offline, run once confirmed with y
+	patchText            string               // the last patch typed, offered again by patch
+	rolloutWait          *rolloutWait
+	overview             *k8s.NamespaceOverview
+	identity             *k8s.Identity // who the cluster sees, shown in the header
+	capabilities         *k8s.Capabilities
+	containerFirst       bool // the container was picked before the pod
+	overviewErr          error
+	shell                *shellSession // the shell pane's session, kept while other screens are shown
+	runAfterExit         bool          // the TUI quit to run the command in the terminal
+	jobs                 []*execJob    // background jobs, oldest first
+	nextJobID            int
+	jobSelector          FuzzyList
+	viewedJob            *execJob
+	jobView              viewport.Model
+	jobTicking           bool
+	exec                 *execution // the command shown with the spinner, Esc cancels it
+	ops                  *operations
+	spinner              spinner.Model
+	showErrorDetails     bool                   // the raw error under its summary and hint
+	retry                *k8s.RetryEvent        // the read being retried, nil if none
+	offline              bool                   // lists come from the offline cache, commands wait for the cluster
+	previousImages       []config.DeployedImage // offered by update-image, newest first
+	completions          []string               // candidates of the last Tab in the value input
+	completionNote       string                 // why the last Tab found none
+	imagePick            int                    // the previous image filled in, -1 for none
+	imageRows            []imageRow             // the update-images table
+	imageCursor          int
+	quickSelector        FuzzyList
+	quickItems           []string // quick-open's "namespace/deployment" list, nil until loaded
+	quickKubeConfig      string   // the kubeconfig quickItems were listed with
+	configSelector       FuzzyList
+	configSource         string                // "Kind/name" whose backups restore-config lists
+	configBackups        []config.ConfigBackup // in the order of configSelector's items
+	configRestore        *config.ConfigBackup  // the data the last edit or restore replaced
+	cleanupSelector      FuzzyList
+	cleanupNames         map[string]string // cleanupSelector's rows to their pods
+	cleanupRestarts      int32
+	pendingCleanup       []string // pods shown for confirmation before they are deleted
+	autoPicked           autoPick // the only pod or container, picked without asking
+	fastDeployExcludes   []string // the patterns fast-deploy leaves out, from its preset
+	showHelp             bool     // the ? overlay is shown over the current state
+	fileSelector         FuzzyList
+	fileDir              string                   // the directory the files command lists
+	fileEntries          map[string]k8s.FileEntry // fileSelector's rows to their files
+	imageSnapshot        *k8s.ImageSnapshot       // the image browsed since the container could not exec
+	viewedFile           string                   // the file shown as the result, Esc goes back to the list
+	fileStatus           string                   // the last download, or why reading failed
+	viewedTrace          string                   // the trace shown as the result, Esc goes back to the logs
 }
 
-// NewModel creates a new application model
-func NewModel(cfg *config.Config, client *k8s.Client, clientErr error) Model {
+const (
+	resumeContinue = "Continue where you left off"
+	resumeFresh    = "Start fresh"
+)
+
+// NewModel creates a new application model. A nil client starts the UI at
+// kubeconfig selection.
+func NewModel(cfg *config.Config, client k8s.ClientInterface, clientErr error) Model {
 	valueInput := textinput.New()
 	valueInput.CharLimit = 200
 	valueInput.Width = 50
@@ -177,9 +357,19 @@ func NewModel(cfg *config.Config, client *k8s.Client, clientErr error) Model {
 		podSelector:       NewFuzzyList("Select Pod"),
 		contSelector:      NewFuzzyList("Select Container"),
 		assetSelector:     NewFuzzyList("Select Asset Folder"),
-		localPathSelector: NewFuzzyList("Select Local Path"),
+		localPathSelector: NewPathPicker("Select Local Path"),
+		resumeSelector:    NewFuzzyList("Continue where you left off?"),
+		jobSelector:       NewFuzzyList("Background Jobs"),
+		quickSelector:     NewFuzzyList("Open Deployment"),
+		configSelector:    NewFuzzyList("Select ConfigMap or Secret"),
+		cleanupSelector:   NewFuzzyList("Select Pods to Delete"),
+		fileSelector:      NewFuzzyList("Files"),
+		exec:              newExecution(),
+		ops:               newOperations(),
+		spinner:           spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(InfoStyle)),
 		valueInput:        valueInput,
 		logViewer:         NewLogViewer(),
+		resultViewer:      NewResultViewer(),
 	}
 
 	// Get kubeconfig path if client exists
@@ -187,45 +377,284 @@ func NewModel(cfg *config.Config, client *k8s.Client, clientErr error) Model {
 		m.kubeconfig = client.GetKubeConfigPath()
 	}
 
-	// Set up command list
-	cmdNames := make([]string, len(AvailableCommands))
-	for i, cmd := range AvailableCommands {
-		cmdNames[i] = fmt.Sprintf("%s - %s", cmd.Name, cmd.Description)
-	}
-	m.cmdSelector.SetItems(cmdNames)
+	m.cmdSelector.SetItems(m.commandItems())
+	m.cmdSelector.SetNameSeparator(" - ")
+	m.depSelector.SetMarkStale(true)
 
 	// Determine initial state - if no client, force kubeconfig selection
 	if client == nil {
 		m.state = StateSelectKubeConfig
 		m.showKubeConfigChange = true
-	} else if m.namespace == "" {
-		m.state = StateSelectNamespace
+	} else if session := cfg.GetLastSession(); session != nil &&
+		session.KubeConfig == m.kubeconfig && session.Namespace == m.namespace {
+		// Offer to jump back to the previous target
+		m.session = session
+		m.state = StateResumePrompt
+		target := session.Namespace + "/" + session.Deployment
+		if session.Context != "" && session.Context != client.KubeContext() {
+			target += " in context " + session.Context
+		}
+		m.resumeSelector.SetItems([]string{
+			fmt.Sprintf("%s (%s)", resumeContinue, target),
+			resumeFresh,
+		})
 	} else {
-		m.state = StateSelectDeployment
+		m.state = m.startState()
 	}
 
 	return m
 }
 
+// commandItems returns the command list entries available in the current mode
+func (m Model) commandItems() []string {
+	cmdNames := make([]string, 0, len(AvailableCommands))
+	for _, cmd := range AvailableCommands {
+		if m.inCluster && cmd.NeedsLocalFS {
+			continue
+		}
+		if cmd.DeploymentOnly && k8s.IsCustomWorkload(m.deployment) {
+			continue
+		}
+		if cmd.Mutating && m.config.IsReadOnly() {
+			continue
+		}
+		cmdNames = append(cmdNames, commandItem(cmd))
+	}
+	return cmdNames
+}
+
+// commandItem returns the command list entry of a command
+func commandItem(cmd Command) string {
+	return fmt.Sprintf("%s - %s", cmd.Name, cmd.Description)
+}
+
+// SetInCluster switches the UI to in-cluster mode: kubeconfig selection is
+// disabled and commands that need the local filesystem are hidden.
+func (m *Model) SetInCluster(inCluster bool) {
+	m.inCluster = inCluster
+	m.cmdSelector.SetItems(m.commandItems())
+}
+
+// clientOptions are the options of a client of kubeconfig, with the
+// configured connect timeout
+func (m Model) clientOptions(kubeconfig string) k8s.ClientOptions {
+	return k8s.ClientOptions{KubeConfig: kubeconfig, ConnectTimeout: m.config.GetTimeout()}
+}
+
+// startState returns the first selection step for a fresh start
+func (m Model) startState() AppState {
+	if m.namespace == "" {
+		return StateSelectNamespace
+	}
+	return StateSelectDeployment
+}
+
+// ResumeSession skips straight to command selection for the last session's
+// deployment, in the session's kube context. It fails if there is no session
+// to resume or its context is gone from the kubeconfig.
+func (m *Model) ResumeSession() error {
+	session := m.config.GetLastSession()
+	if session == nil || m.k8sClient == nil {
+		return fmt.Errorf("no session to resume")
+	}
+	m.session = session
+	client, err := m.sessionClient()
+	if err != nil {
+		m.session = nil
+		return err
+	}
+	if client != nil {
+		m.k8sClient = client
+	}
+	m.applySession()
+	return nil
+}
+
+// sessionClient returns a client for the session's kube context if the
+// current client uses another one, nil if it does not. The session was
+// saved for that context's cluster, so a context no longer in the
+// kubeconfig is an error rather than a reason to use the current one.
+func (m *Model) sessionClient() (k8s.ClientInterface, error) {
+	kubeContext := m.session.Context
+	if kubeContext == "" || kubeContext == m.k8sClient.KubeContext() {
+		return nil, nil
+	}
+	opts := m.clientOptions(m.kubeconfig)
+	opts.Context = kubeContext
+	client, err := k8s.NewClientWithOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resume %s/%s: %w", m.session.Namespace, m.session.Deployment, err)
+	}
+	return client, nil
+}
+
+// Focus skips straight to command selection for a deployment or custom
+// workload reference, as khelper get does
+func (m *Model) Focus(namespace, deployment string) {
+	m.session = nil
+	m.namespace = namespace
+	m.deployment = deployment
+	m.openCommands()
+}
+
+// applySession restores the saved target and opens the command selector on
+// the command run last
+func (m *Model) applySession() {
+	m.namespace = m.session.Namespace
+	m.deployment = m.session.Deployment
+	m.openCommands()
+	for _, cmd := range AvailableCommands {
+		if cmd.Name == m.session.Command {
+			m.cmdSelector.SelectItem(commandItem(cmd))
+		}
+	}
+}
+
+// openCommands opens the command selector for the current target
+func (m *Model) openCommands() {
+	m.state = StateSelectCommand
+	m.cmdSelector.SetItems(m.commandItems())
+	m.cmdSelector.Reset()
+	m.setPinned(&m.cmdSelector, config.CategoryCommands, "", m.config.GetRecentCommands())
+}
+
+// saveSession remembers the current selection so it can be resumed
+func (m *Model) saveSession() {
+	session := config.Session{
+		KubeConfig: m.kubeconfig,
+		Namespace:  m.namespace,
+		Deployment: m.deployment,
+	}
+	if m.k8sClient != nil {
+		session.Context = m.k8sClient.KubeContext()
+	}
+	if m.command != nil {
+		session.Command = m.command.Name
+	}
+	m.config.SaveSession(session)
+}
+
 func (m Model) Init() tea.Cmd {
 	// If no client, load kubeconfig options
 	if m.k8sClient == nil {
 		return m.loadKubeConfigs()
 	}
+	switch m.state {
+	case StateResumePrompt, StateSelectCommand:
+		return tea.Batch(waitRetry(m.k8sClient), m.loadIdentity(), m.loadCapabilities(), autostartForwards, m.checkSession())
+	}
 	if m.namespace == "" {
-		return m.loadNamespaces()
+		return tea.Batch(waitRetry(m.k8sClient), m.loadIdentity(), m.loadCapabilities(), autostartForwards, m.loadNamespaces())
 	}
-	return m.loadDeployments()
+	return tea.Batch(waitRetry(m.k8sClient), m.loadIdentity(), m.loadCapabilities(), autostartForwards, m.loadDeployments())
 }
 
-func (m *Model) loadNamespaces() tea.Cmd {
+// checkSession verifies that the remembered namespace and deployment still
+// exist. Lookup errors other than not found leave the session alone.
+func (m *Model) checkSession() tea.Cmd {
+	if m.session == nil {
+		return nil
+	}
+	namespace, deployment := m.session.Namespace, m.session.Deployment
 	return func() tea.Msg {
 		ctx := context.Background()
-		namespaces, err := m.k8sClient.ListNamespaces(ctx)
-		return NamespacesLoadedMsg{namespaces: namespaces, err: err}
+		if namespaces, err := m.k8sClient.ListNamespaces(ctx); err == nil && !slices.Contains(namespaces, namespace) {
+			return sessionCheckedMsg{namespaceGone: true, deploymentGone: true}
+		}
+		exists, err := m.k8sClient.WorkloadExists(ctx, namespace, deployment)
+		return sessionCheckedMsg{deploymentGone: err == nil && !exists}
+	}
+}
+
+// lists returns the model's fuzzy lists
+func (m *Model) lists() []*FuzzyList {
+	return []*FuzzyList{&m.resumeSelector, &m.kcSelector, &m.nsSelector, &m.depSelector, &m.cmdSelector,
+		&m.podSelector, &m.contSelector, &m.assetSelector, &m.localPathSelector.list, &m.jobSelector,
+		&m.quickSelector, &m.configSelector, &m.cleanupSelector, &m.fileSelector}
+}
+
+// setPinned shows a list's favorites and recents at its top
+func (m *Model) setPinned(list *FuzzyList, category, scope string, recents []string) {
+	list.SetFavorites(m.config.GetFavorites(category, scope))
+	list.SetRecentItems(m.config.Pinned(category, scope, recents))
+}
+
+// toggleFavorite stars or unstars the selected item of the list being shown
+func (m *Model) toggleFavorite() {
+	var list *FuzzyList
+	var category, scope string
+	var recents []string
+	switch m.state {
+	case StateSelectKubeConfig:
+		list, category, recents = &m.kcSelector, config.CategoryKubeConfigs, m.config.GetRecentKubeConfigs()
+	case StateSelectNamespace:
+		list, category = &m.nsSelector, config.CategoryNamespaces
+	case StateSelectDeployment:
+		list, category, scope, recents = &m.depSelector, config.CategoryDeployments, m.namespace, m.config.GetRecentDeployments(m.namespace)
+	case StateSelectCommand:
+		list, category, recents = &m.cmdSelector, config.CategoryCommands, m.config.GetRecentCommands()
+	default:
+		return
+	}
+
+	selected := list.GetSelected()
+	if selected == "" || strings.HasPrefix(selected, "+ ") {
+		return
+	}
+	m.config.ToggleFavorite(category, scope, selected)
+	m.setPinned(list, category, scope, recents)
+}
+
+// setDefaultContainer remembers the selected container as the deployment's
+// default
+func (m *Model) setDefaultContainer() {
+	selected := m.contSelector.GetSelected()
+	if selected == "" {
+		return
+	}
+	if err := m.config.SetDefaultContainer(m.namespace, m.deployment, selected); err != nil {
+		m.warning = "Failed to save default container: " + err.Error()
+		return
+	}
+	m.warning = fmt.Sprintf("%s is now the default container of %s", selected, m.deployment)
+}
+
+// pruneStale removes remembered selections that no longer exist from the
+// config, for the list being shown
+func (m *Model) pruneStale() {
+	switch m.state {
+	case StateSelectNamespace:
+		removed, err := m.config.PruneNamespaces(m.nsSelector.items)
+		if err != nil {
+			m.warning = "Failed to prune: " + err.Error()
+			return
+		}
+		m.warning = fmt.Sprintf("Pruned recents of %d deleted namespace(s)", removed)
+	case StateSelectDeployment:
+		stale := m.depSelector.StaleItems()
+		if len(stale) == 0 {
+			m.warning = ""
+			return
+		}
+		if err := m.config.RemoveRecentDeployments(m.namespace, stale); err != nil {
+			m.warning = "Failed to prune: " + err.Error()
+			return
+		}
+		m.setPinned(&m.depSelector, config.CategoryDeployments, m.namespace, m.config.GetRecentDeployments(m.namespace))
+		m.warning = fmt.Sprintf("Pruned %d stale recent deployment(s)", len(stale))
 	}
 }
 
+func (m *Model) loadNamespaces() tea.Cmd {
+	return m.trackList("namespaces", func() tea.Msg {
+		ctx := context.Background()
+		namespaces, cachedAt, err := m.cachedList(config.CacheNamespaces, "", func() ([]string, error) {
+			return m.k8sClient.ListNamespaces(ctx)
+		})
+		return NamespacesLoadedMsg{namespaces: namespaces, cachedAt: cachedAt, err: err}
+	})
+}
+
 func (m *Model) loadKubeConfigs() tea.Cmd {
 	return func() tea.Msg {
 		configs := m.config.GetRecentKubeConfigs()
@@ -250,38 +679,40 @@ func (m *Model) loadKubeConfigs() tea.Cmd {
 }
 
 func (m *Model) loadDeployments() tea.Cmd {
-	return func() tea.Msg {
+	return m.trackList("deployments", func() tea.Msg {
 		ctx := context.Background()
-		deployments, err := m.k8sClient.ListDeployments(ctx, m.namespace)
-		return DeploymentsLoadedMsg{deployments: deployments, err: err}
-	}
+		deployments, cachedAt, err := m.cachedList(config.CacheDeployments, m.namespace, func() ([]string, error) {
+			return m.k8sClient.ListWorkloads(ctx, m.namespace)
+		})
+		return DeploymentsLoadedMsg{deployments: deployments, cachedAt: cachedAt, err: err}
+	})
 }
 
 func (m *Model) loadPods() tea.Cmd {
-	return func() tea.Msg {
+	return m.trackList("pods", func() tea.Msg {
 		ctx := context.Background()
-		pods, err := m.k8sClient.ListPodNames(ctx, m.namespace, m.deployment)
-		return PodsLoadedMsg{pods: pods, err: err}
-	}
+		pods, cachedAt, err := m.cachedPods(m.namespace+"/"+m.deployment, func() ([]k8s.PodEntry, error) {
+			return m.k8sClient.ListPodEntries(ctx, m.namespace, m.deployment)
+		})
+		return PodsLoadedMsg{pods: pods, cachedAt: cachedAt, err: err}
+	})
 }
 
 func (m *Model) loadContainers() tea.Cmd {
-	return func() tea.Msg {
+	return m.trackList("containers", func() tea.Msg {
 		ctx := context.Background()
-		// Extract pod name without status
 		podName := m.pod
-		if idx := strings.Index(podName, " ("); idx != -1 {
-			podName = podName[:idx]
-		}
-		containers, err := m.k8sClient.ListContainers(ctx, m.namespace, podName)
-		return ContainersLoadedMsg{containers: containers, err: err}
-	}
+		containers, cachedAt, err := m.cachedList(config.CacheContainers, m.namespace+"/"+m.deployment, func() ([]string, error) {
+			return m.k8sClient.ListContainers(ctx, m.namespace, podName)
+		})
+		return ContainersLoadedMsg{containers: containers, cachedAt: cachedAt, err: err}
+	})
 }
 
 func (m *Model) loadAssetFolders() tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
-		podName := extractPodName(m.pod)
+		podName := m.pod
 		folders, err := m.k8sClient.ListDirectories(ctx, m.namespace, podName, m.container, "/app/assets")
 		return AssetFoldersLoadedMsg{folders: folders, err: err}
 	}
@@ -289,18 +720,16 @@ func (m *Model) loadAssetFolders() tea.Cmd {
 
 func (m *Model) executeFastDeploy() tea.Cmd {
 	return func() tea.Msg {
-		ctx := context.Background()
-		podName := extractPodName(m.pod)
+		ctx := m.exec.context()
+		podName := m.pod
 		localPath := m.inputValue
-		var logBuilder strings.Builder
+		// The progress is kept for when the upload is cancelled
+		logBuilder := m.exec
 
 		// Expand ~ to home directory
-		if strings.HasPrefix(localPath, "~/") {
-			home, _ := os.UserHomeDir()
-			localPath = filepath.Join(home, localPath[2:])
-		}
+		localPath = config.ExpandPath(localPath)
 
-		logBuilder.WriteString(fmt.Sprintf("📂 Source: %s\n", localPath))
+		logBuilder.write(fmt.Sprintf("📂 Source: %s\n", localPath))
 
 		// Check if local path exists
 		info, err := os.Stat(localPath)
@@ -313,37 +742,63 @@ func (m *Model) executeFastDeploy() tea.Cmd {
 
 		// Target path is /app/assets/{selected_folder}/js
 		targetPath := fmt.Sprintf("/app/assets/%s/js", m.assetFolder)
-		logBuilder.WriteString(fmt.Sprintf("📁 Target: %s\n", targetPath))
-		logBuilder.WriteString(fmt.Sprintf("🔗 Pod: %s\n", podName))
-		logBuilder.WriteString(fmt.Sprintf("📦 Container: %s\n\n", m.container))
+		logBuilder.write(fmt.Sprintf("📁 Target: %s\n", targetPath))
+		logBuilder.write(fmt.Sprintf("🔗 Pod: %s\n", podName))
+		logBuilder.write(fmt.Sprintf("📦 Container: %s\n", m.container))
+		if len(m.fastDeployExcludes) > 0 {
+			logBuilder.write(fmt.Sprintf("🚫 Excluded: %s\n", strings.Join(m.fastDeployExcludes, ", ")))
+		}
+		logBuilder.write("\n")
 
 		// Step 1: Clear the target directory
-		logBuilder.WriteString("🗑️  Clearing target directory...")
+		logBuilder.write("🗑️  Clearing target directory...")
 		err = m.k8sClient.ClearDirectory(ctx, m.namespace, podName, m.container, targetPath)
 		if err != nil {
 			return FastDeployCompleteMsg{err: fmt.Errorf("failed to clear target directory: %w", err)}
 		}
-		logBuilder.WriteString(" ✓\n\n")
+		logBuilder.write(" ✓\n\n")
 
 		// Step 2: Upload files from local dist to target
-		logBuilder.WriteString("📤 Uploading files:\n")
-		result, err := m.k8sClient.UploadDirectory(ctx, m.namespace, podName, m.container, localPath, targetPath)
+		logBuilder.write("📤 Uploading files:\n")
+		result, err := m.k8sClient.UploadDirectory(ctx, k8s.UploadOptions{
+			Namespace:     m.namespace,
+			PodName:       podName,
+			ContainerName: m.container,
+			LocalPath:     localPath,
+			RemotePath:    targetPath,
+			Excludes:      m.fastDeployExcludes,
+		})
 		if err != nil {
 			return FastDeployCompleteMsg{err: fmt.Errorf("failed to upload files: %w", err)}
 		}
 
 		// List uploaded files
 		for _, file := range result.Files {
-			logBuilder.WriteString(fmt.Sprintf("   ✓ %s\n", file))
+			logBuilder.write(fmt.Sprintf("   ✓ %s\n", file))
 		}
 
-		logBuilder.WriteString(fmt.Sprintf("\n✅ Successfully deployed %d files to %s", result.FileCount, targetPath))
+		logBuilder.write(fmt.Sprintf("\n✅ Successfully deployed %d files to %s", result.FileCount, targetPath))
 
-		return FastDeployCompleteMsg{result: logBuilder.String()}
+		return FastDeployCompleteMsg{result: logBuilder.written()}
 	}
 }
 
-func (m *Model) streamLogs(ctx context.Context, podName string) tea.Cmd {
+// streamLogs follows the selected container's logs. With a zero since it
+// starts from the deployment's tail lines, otherwise from that time onwards.
+func (m *Model) streamLogs(ctx context.Context, podName string, since time.Time) tea.Cmd {
+	gen := m.streamGen
+	opts := k8s.LogOptions{
+		Namespace:     m.namespace,
+		PodName:       podName,
+		ContainerName: m.container,
+		Follow:        true,
+		TailLines:     m.config.GetDeploymentTailLines(m.namespace, m.deployment),
+	}
+	if !since.IsZero() {
+		opts.TailLines = 0
+		opts.SinceTime = &since
+	}
+
 	return func() tea.Msg {
 		// Create a pipe to capture streaming output
 		pr, pw := io.Pipe()
@@ -351,88 +806,427 @@ func (m *Model) streamLogs(ctx context.Context, podName string) tea.Cmd {
 		// Start streaming in a goroutine
 		go func() {
 			defer pw.Close()
-			_ = m.k8sClient.StreamLogs(ctx, k8s.LogOptions{
-				Namespace:     m.namespace,
-				PodName:       podName,
-				ContainerName: m.container,
-				Follow:        true,
-				TailLines:     100,
-			}, pw)
+			_ = m.k8sClient.FollowLogs(ctx, opts, pw)
 		}()
 
 		// Read first line
 		reader := bufio.NewReader(pr)
-		line, err := reader.ReadString('\n')
+		return readLine(gen, reader, pr)
+	}
+}
+
+// isManifestCommand reports whether the current command shows a YAML manifest
+func (m Model) isManifestCommand() bool {
+	return m.command != nil && (m.command.Name == "yaml" || m.command.Name == "pod-yaml")
+}
+
+// resultSource names the files the command's result is saved to, e.g.
+// describe-web.txt or pod-yaml-web-5d8f.yaml
+func (m Model) resultSource() (string, string) {
+	target := m.deployment
+	if m.command.NeedsPod && m.pod != "" {
+		target = m.pod
+	}
+	if m.isManifestCommand() {
+		return m.command.Name + "-" + target, ".yaml"
+	}
+	return m.command.Name + "-" + target, ".txt"
+}
+
+// loadManifest fetches the live manifest of the deployment, or of the
+// selected pod for pod-yaml
+func (m *Model) loadManifest() tea.Cmd {
+	kind, name := "deployment", m.deployment
+	if m.command.Name == "pod-yaml" {
+		kind, name = "pod", m.pod
+	}
+	keep := m.showManagedFields
+	namespace, command := m.namespace, m.command.Name
+
+	return func() tea.Msg {
+		ctx := context.Background()
+		manifest, err := m.k8sClient.GetManifest(ctx, namespace, kind, name, keep)
+		if err != nil || kind != "deployment" {
+			return ManifestLoadedMsg{manifest: manifest, err: err}
+		}
+		// Custom workloads are not deployments and have no overlay
+		var target *kustomizeResult
+		if deployment, err := m.k8sClient.GetDeployment(ctx, namespace, name); err == nil {
+			target = newKustomizeResult(namespace, command, deployment, "")
+		}
+		return ManifestLoadedMsg{manifest: manifest, kustomize: target}
+	}
+}
+
+// newLogViewer creates a log viewer sized to the window that saves into the
+// deployment's log archive
+func (m Model) newLogViewer(source string) LogViewer {
+	lv := NewLogViewer()
+	lv.SetSplit(m.config.GetLogSplit())
+	lv.SetColors(m.config.LogColors)
+	lv.SetSize(m.width, m.height)
+	lv.SetRecentSearches(m.config.GetRecentLogSearches())
+	lv.SetSource(source)
+	if dir, err := config.GetLogArchiveDir(archiveCluster(m.kubeconfig), m.namespace, sanitizeFileName(m.deployment)); err == nil {
+		lv.SetExportDir(dir)
+	}
+	return lv
+}
+
+// loadOlderLogs fetches the page of log lines before the oldest loaded one.
+// Every line the viewer holds is newer, so that many are skipped.
+func (m *Model) loadOlderLogs() tea.Cmd {
+	if m.command != nil && m.command.Name == "logs-history" {
+		return m.loadOlderHistory()
+	}
+	opts := k8s.LogOptions{
+		Namespace:     m.namespace,
+		PodName:       m.pod,
+		ContainerName: m.container,
+		TailLines:     500,
+	}
+	before := m.logsOldest
+	newer := int64(m.logViewer.LineCount())
+
+	return func() tea.Msg {
+		page, err := m.k8sClient.GetLogsPage(context.Background(), opts, before, newer)
 		if err != nil {
-			if err == io.EOF {
-				return LogStreamEndMsg{err: nil}
-			}
-			return LogStreamEndMsg{err: err}
+			return olderLogsMsg{err: err}
 		}
+		return olderLogsMsg{lines: page.Lines, oldest: page.Oldest, more: page.More}
+	}
+}
+
+// streamPodLogs follows the selected container in all running pods of the
+// deployment, like streamLogs
+func (m *Model) streamPodLogs(ctx context.Context, since time.Time) tea.Cmd {
+	gen := m.streamGen
+	opts := k8s.MultiLogOptions{
+		Namespace:     m.namespace,
+		ContainerName: m.container,
+		TailLines:     m.config.GetDeploymentTailLines(m.namespace, m.deployment),
+		Timestamps:    m.logPrefixer.Timestamps(),
+	}
+	if !since.IsZero() {
+		opts.TailLines = 0
+		opts.SinceTime = &since
+	}
 
-		return logStreamMsg{
-			line:   strings.TrimSuffix(line, "\n"),
-			reader: reader,
-			pipe:   pr,
+	return func() tea.Msg {
+		pods, err := m.k8sClient.ListPodEntries(ctx, m.namespace, m.deployment)
+		if err != nil {
+			return LogStreamEndMsg{gen: gen, err: err}
+		}
+		for _, pod := range pods {
+			if pod.Phase == corev1.PodRunning {
+				opts.PodNames = append(opts.PodNames, pod.Name)
+			}
+		}
+		if len(opts.PodNames) == 0 {
+			return LogStreamEndMsg{gen: gen, err: fmt.Errorf("no running pods in deployment %s", m.deployment)}
 		}
+
+		lines := make(chan k8s.LogLine, 100)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- m.k8sClient.FollowPodLogs(ctx, opts, func(line k8s.LogLine) {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+				}
+			})
+			close(lines)
+		}()
+
+		return readPodLine(gen, lines, errs)
+	}
+}
+
+// podLogStreamMsg carries a line and the state of a multi-pod stream
+type podLogStreamMsg struct {
+	gen   int
+	line  k8s.LogLine
+	lines <-chan k8s.LogLine
+	errs  <-chan error
+}
+
+// readNextPodLine returns a command that reads the next multi-pod log line
+func readNextPodLine(gen int, lines <-chan k8s.LogLine, errs <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		return readPodLine(gen, lines, errs)
+	}
+}
+
+func readPodLine(gen int, lines <-chan k8s.LogLine, errs <-chan error) tea.Msg {
+	line, ok := <-lines
+	if !ok {
+		return LogStreamEndMsg{gen: gen, err: <-errs}
 	}
+	return podLogStreamMsg{gen: gen, line: line, lines: lines, errs: errs}
 }
 
 // logStreamMsg carries streaming state
 type logStreamMsg struct {
+	gen    int
 	line   string
 	reader *bufio.Reader
 	pipe   *io.PipeReader
 }
 
 // readNextLine returns a command that reads the next log line
-func readNextLine(reader *bufio.Reader, pipe *io.PipeReader) tea.Cmd {
+func readNextLine(gen int, reader *bufio.Reader, pipe *io.PipeReader) tea.Cmd {
 	return func() tea.Msg {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			pipe.Close()
-			if err == io.EOF {
-				return LogStreamEndMsg{err: nil}
-			}
-			return LogStreamEndMsg{err: err}
-		}
-		return logStreamMsg{
-			line:   strings.TrimSuffix(line, "\n"),
-			reader: reader,
-			pipe:   pipe,
+		return readLine(gen, reader, pipe)
+	}
+}
+
+func readLine(gen int, reader *bufio.Reader, pipe *io.PipeReader) tea.Msg {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		pipe.Close()
+		if err == io.EOF {
+			return LogStreamEndMsg{gen: gen, err: nil}
 		}
+		return LogStreamEndMsg{gen: gen, err: err}
+	}
+	return logStreamMsg{
+		gen:    gen,
+		line:   strings.TrimSuffix(line, "\n"),
+		reader: reader,
+		pipe:   pipe,
 	}
 }
 
+// startFollowing streams new log lines into the existing log viewer
+func (m Model) startFollowing(since time.Time) (Model, tea.Cmd) {
+	m.streamGen++
+	m.streaming = true
+	m.streamCtx, m.cancelStream = context.WithCancel(context.Background())
+	m.logViewer.SetStreaming(true)
+	if m.command != nil && (m.command.Name == "logs-all" || m.command.Name == "logs-history") {
+		return m, m.streamPodLogs(m.streamCtx, since)
+	}
+	if m.command != nil && m.command.Name == "events" {
+		return m, m.streamEvents(m.streamCtx, since)
+	}
+	return m, m.streamLogs(m.streamCtx, m.pod, since)
+}
+
+// stopFollowing cancels the active log stream, keeping what was received
+func (m Model) stopFollowing() Model {
+	if m.streaming && m.cancelStream != nil {
+		m.cancelStream()
+	}
+	m.streaming = false
+	m.streamGen++
+	m.logsUntil = time.Now()
+	m.logViewer.SetStreaming(false)
+	return m
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.stopListWatch()
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		m.logViewer.SetSize(msg.Width, msg.Height)
+		m.resultViewer.SetSize(msg.Width, msg.Height)
+		for _, list := range m.lists() {
+			list.SetWidth(msg.Width - 4)
+		}
+		m.resources, _ = m.resources.update(msg)
+		if m.shell != nil {
+			m.shell.resize(m.shellPaneSize())
+		}
+		m.resizeJobView()
+		return m, nil
+
+	case jobTickMsg:
+		return m.handleJobTick()
+
+	case fuzzyMatchMsg:
+		for _, list := range m.lists() {
+			if cmd := list.Matched(msg); cmd != nil {
+				return m, cmd
+			}
+		}
+		// The embedded explorer's lists
+		var cmd tea.Cmd
+		m.resources, cmd = m.resources.update(msg)
+		return m, cmd
+
+	case execResultMsg:
+		return m.handleExecResult(msg)
+
+	case opDoneMsg:
+		return m.handleOpDone(msg)
+
+	case retryMsg:
+		return m.handleRetry(msg)
+
+	case imagesLoadedMsg:
+		return m.showImageTable(msg)
+
+	case deploymentKeptMsg:
+		return m.handleDeploymentKept(msg)
+
+	case renameMsg:
+		return m.handleRename(msg)
+
+	case overlayLoadedMsg:
+		return m.handleOverlayLoaded(msg)
+
+	case pullSecretsMsg:
+		return m.handlePullSecrets(msg)
+
+	case debugSidecarMsg:
+		m.state = StateShowResult
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.debugSidecar = msg.target
+		m.result = msg.result
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(msg.result)
+		return m, nil
+
+	case oneOffStartedMsg:
+		return m.handleOneOffStarted(msg)
+
+	case oneOffJobStatusMsg:
+		return m.handleOneOffJobStatus(msg)
+
+	case onlineMsg:
+		return m.handleOnline(msg)
+
+	case spinner.TickMsg:
+		return m.updateSpinner(msg)
+
+	case shellOpenedMsg, shellOutputMsg, shellExitedMsg:
+		return m.handleShellMsg(msg)
+
+	case resourcesClosedMsg:
+		m.state = StateSelectCommand
+		m.cmdSelector.Reset()
 		return m, nil
 
+	case apiResourcesLoadedMsg, resourceInstancesMsg, resourceManifestMsg, resourceDeletedMsg:
+		var cmd tea.Cmd
+		m.resources, cmd = m.resources.update(msg)
+		return m, cmd
+
 	case tea.KeyMsg:
+		// Keys pressed while a command or list loads are replayed after it
+		if m.queueKey(msg) {
+			return m, nil
+		}
+
+		// The help closes on any key
+		if m.showHelp {
+			return m.helpKey(msg)
+		}
+		if key.Matches(msg, keys.Help) && m.canShowHelp() {
+			m.showHelp = true
+			return m, nil
+		}
+
+		// Every key but Ctrl+] goes to the shell
+		if m.state == StateShellPane {
+			return m.shellKey(msg)
+		}
+
+		if m.state == StateJobs || m.state == StateJobOutput {
+			return m.jobKey(msg)
+		}
+
+		if m.state == StateEditImages {
+			return m.imageTableKey(msg)
+		}
+
+		if m.state == StateQuickOpen {
+			if model, cmd, ok := m.quickOpenKey(msg); ok {
+				return model, cmd
+			}
+		}
+
+		if m.state == StateBrowseFiles {
+			switch {
+			case key.Matches(msg, keys.BackEmpty) && m.fileSelector.GetInput() == "" && m.fileDir != "/":
+				return m.fileParentDir()
+			case key.Matches(msg, keys.Download):
+				return m.downloadFile()
+			}
+		}
+
+		if m.state == StateSelectLocalPath {
+			switch {
+			case key.Matches(msg, keys.BackEmpty) && m.localPathSelector.InputEmpty():
+				m.localPathSelector.Up()
+				return m, nil
+			case key.Matches(msg, keys.ShowHidden):
+				m.localPathSelector.ToggleHidden()
+				return m, nil
+			}
+		}
+
+		// The resources explorer handles its own keys
+		if m.state == StateBrowseResources {
+			var cmd tea.Cmd
+			m.resources, cmd = m.resources.update(msg)
+			return m, cmd
+		}
+
 		// Handle log viewer state separately
 		if m.state == StateViewLogs {
-			switch msg.String() {
-			case "ctrl+c":
+			switch {
+			case key.Matches(msg, keys.ForceQuit):
 				// Cancel streaming if active
 				if m.streaming && m.cancelStream != nil {
 					m.cancelStream()
 					m.streaming = false
 				}
+				m.logViewer.StopPipe()
 				return m, tea.Quit
-			case "esc", "q":
+			case m.logViewer.IsPromptFocused():
+				// The time range and pipe inputs take all other keys
+				var cmd tea.Cmd
+				m.logViewer, cmd = m.logViewer.Update(msg)
+				return m, cmd
+			case key.Matches(msg, keys.LogFollow):
+				// Toggle follow mode, keeping the lines and search state
+				if !m.logViewer.IsFocused() {
+					if m.streaming {
+						return m.stopFollowing(), nil
+					}
+					return m.startFollowing(m.logsUntil)
+				}
+			case key.Matches(msg, keys.LogTrace):
+				// Open or fetch the trace of the selected line
+				if !m.logViewer.IsFocused() {
+					return m.lookupTrace()
+				}
+			case key.Matches(msg, keys.LogOlder):
+				// Load the page of lines before the oldest one shown
+				if !m.logViewer.IsFocused() && m.logViewer.HasOlder() && !m.loadingOlder {
+					m.loadingOlder = true
+					return m, m.loadOlderLogs()
+				}
+			case key.Matches(msg, keys.LogBack):
 				// Cancel streaming if active
-				if m.streaming && m.cancelStream != nil {
-					m.cancelStream()
-					m.streaming = false
+				if m.streaming {
+					m = m.stopFollowing()
 				}
+				m.logViewer.StopPipe()
 				// Save search if there was one
 				if m.logViewer.GetSearchQuery() != "" {
 					m.config.AddRecentLogSearch(m.logViewer.GetSearchQuery())
 				}
+				if m.canDeleteOneOff() {
+					return m.leaveOneOffLogs()
+				}
 				// Go back to command selection
 				m.state = StateSelectCommand
 				m.cmdSelector.Reset()
@@ -444,23 +1238,243 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
-		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
+		if m.state == StateInputValue {
+			if msg.String() == "tab" {
+				return m.complete()
+			}
+			m.completions, m.completionNote = nil, ""
+		}
 
-		case "ctrl+n":
-			// Switch namespace
-			if m.state != StateSelectNamespace {
-				m.showNamespaceChange = true
-				m.prevStates = append(m.prevStates, m.state)
-				m.state = StateSelectNamespace
-				m.nsSelector.Reset()
-				return m, m.loadNamespaces()
+		if m.state == StateInputValue && m.command != nil && m.command.Name == "update-image" && len(m.previousImages) > 0 {
+			if model, cmd, ok := m.previousImageKey(msg); ok {
+				return model, cmd
 			}
+		}
 
-		case "ctrl+k":
-			// Switch kubeconfig
-			if m.state != StateSelectKubeConfig {
+		// A scheduled scale only waits for its time or to be cancelled
+		if m.state == StateShowResult && m.scheduledScale != nil {
+			switch {
+			case key.Matches(msg, keys.ForceQuit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.StopWaiting):
+				m.scheduledScale = nil
+				m.state = StateSelectCommand
+				m.cmdSelector.Reset()
+			}
+			return m, nil
+		}
+
+		if m.state == StateExecuting && key.Matches(msg, keys.Cancel) {
+			return m.cancelExecution()
+		}
+
+		// The overview is read and left with any key but quit and refresh
+		if m.state == StateNamespaceOverview {
+			switch {
+			case key.Matches(msg, keys.Quit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.Refresh):
+				return m.showOverview()
+			}
+			return m.leaveOverview()
+		}
+
+		// Waiting for a rollout only ends when it is done or stopped
+		if m.state == StateShowResult && m.rolloutWait != nil {
+			switch {
+			case key.Matches(msg, keys.ForceQuit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.StopWaiting):
+				m.rolloutWait = nil
+				m.state = StateSelectCommand
+				m.cmdSelector.Reset()
+			}
+			return m, nil
+		}
+
+		// An undo is only applied once confirmed with y
+		if m.state == StateShowResult && m.pendingUndo != nil {
+			change := *m.pendingUndo
+			m.pendingUndo = nil
+			switch {
+			case key.Matches(msg, keys.ForceQuit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.Confirm):
+				m.startExecution()
+				return m, m.whileExecuting(m.undoNow(change))
+			}
+			m.state = StateSelectCommand
+			m.cmdSelector.Reset()
+			return m, nil
+		}
+
+		// Pods are only cleaned up once confirmed with y
+		if m.state == StateShowResult && m.pendingCleanup != nil {
+			names := m.pendingCleanup
+			m.pendingCleanup = nil
+			switch {
+			case key.Matches(msg, keys.ForceQuit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.Confirm):
+				m.startExecution()
+				return m, m.whileExecuting(m.deleteCleanupPods(names))
+			}
+			// Back to the list, the marks are kept
+			m.state = StateSelectCleanup
+			return m, nil
+		}
+
+		// A patch is only applied once its preview is confirmed with y
+		if m.state == StateShowResult && m.pendingPatch != nil {
+			return m.handlePatchKey(msg)
+		}
+
+		// A rename runs step by step, each confirmed with y
+		if m.state == StateShowResult && m.pendingRename != nil {
+			return m.handleRenameKey(msg)
+		}
+
+		// The data an edit replaced is only put back once confirmed with y
+		if m.state == StateShowResult && m.pendingConfigRestore != nil {
+			return m.handleConfigRestoreKey(msg)
+		}
+
+		// A change prepared offline is only run once confirmed with y
+		if m.state == StateShowResult && m.pendingOnline != nil {
+			return m.handleOnlineKey(msg)
+		}
+
+		// A change with lint findings is only applied once confirmed with y
+		if m.state == StateShowResult && m.pendingLint != nil {
+			apply := m.pendingLint.apply
+			m.pendingLint = nil
+			switch {
+			case key.Matches(msg, keys.ForceQuit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.Confirm):
+				m.startExecution()
+				return m, m.whileExecuting(apply)
+			}
+			m.state = StateSelectCommand
+			m.cmdSelector.Reset()
+			return m, nil
+		}
+
+		if m.state == StateShowResult && m.err != nil && key.Matches(msg, keys.Details) && hasErrorDetails(m.err) {
+			m.showErrorDetails = !m.showErrorDetails
+			return m, nil
+		}
+
+		// The result viewer's search input takes all keys while focused
+		if m.state == StateShowResult && m.err == nil {
+			if m.resultViewer.IsSearching() {
+				var cmd tea.Cmd
+				m.resultViewer, cmd = m.resultViewer.Update(msg)
+				return m, cmd
+			}
+			if key.Matches(msg, keys.ManagedFields) && m.isManifestCommand() {
+				m.showManagedFields = !m.showManagedFields
+				return m, m.loadManifest()
+			}
+			if key.Matches(msg, keys.Overlay) && m.canShowOverlay() {
+				return m.toggleOverlay()
+			}
+			if m.canRemoveDebug() {
+				if model, cmd, ok := m.debugKey(msg); ok {
+					return model, cmd
+				}
+			}
+			if key.Matches(msg, keys.CreatePullSecret) && m.canCreatePullSecret() {
+				fix := *m.pullSecretFix
+				m.pullSecretFix = nil
+				m.startExecution()
+				return m, m.whileExecuting(m.createPullSecret(fix))
+			}
+			if m.canDeleteOneOff() {
+				if model, cmd, ok := m.oneOffKey(msg); ok {
+					return model, cmd
+				}
+			}
+			if key.Matches(msg, keys.UndoResult) && m.canRestoreConfig() {
+				return m.restorePrevious()
+			}
+			if key.Matches(msg, keys.UndoResult) && m.canUndoScale() {
+				// Scale back; undoing again flips between the two counts
+				m.startExecution()
+				return m, m.whileExecuting(m.scaleNow(fmt.Sprint(m.undoScale.replicas)))
+			}
+		}
+
+		switch {
+		case key.Matches(msg, keys.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, keys.Favorite):
+			// Star or unstar the selected item
+			switch m.state {
+			case StateSelectKubeConfig, StateSelectNamespace, StateSelectDeployment, StateSelectCommand:
+				m.toggleFavorite()
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.DefaultContainer):
+			// Pre-select the selected container for this deployment from now on
+			if m.state == StateSelectContainer {
+				m.setDefaultContainer()
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.RepeatDeploy):
+			// Fast-deploy the last preset again, without asking
+			if m.state == StateSelectCommand {
+				return m.repeatFastDeploy()
+			}
+
+		case key.Matches(msg, keys.ShellPane):
+			// Back to the shell left open in the pane
+			if m.state == StateSelectCommand && m.shell != nil {
+				return m.resumeShell()
+			}
+
+		case key.Matches(msg, keys.Overview):
+			// Check what needs attention in the namespace
+			if m.state == StateSelectDeployment {
+				return m.showOverview()
+			}
+
+		case key.Matches(msg, keys.MarkAll):
+			// Mark or unmark all pods to clean up
+			if m.state == StateSelectCleanup {
+				m.cleanupSelector.MarkAll()
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.PruneStale):
+			// Prune remembered selections that no longer exist
+			if m.state == StateSelectNamespace || m.state == StateSelectDeployment {
+				m.pruneStale()
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.Namespace):
+			// Switch namespace
+			if m.state != StateSelectNamespace {
+				m.showNamespaceChange = true
+				m.prevStates = append(m.prevStates, m.state)
+				m.state = StateSelectNamespace
+				m.nsSelector.Reset()
+				return m, m.loadNamespaces()
+			}
+
+		case key.Matches(msg, keys.QuickOpen):
+			// Jump to a deployment in any namespace
+			if m.canQuickOpen() {
+				return m.openQuickOpen()
+			}
+
+		case key.Matches(msg, keys.KubeConfig):
+			// Switch kubeconfig
+			if m.state != StateSelectKubeConfig && !m.inCluster {
 				m.showKubeConfigChange = true
 				m.prevStates = append(m.prevStates, m.state)
 				m.state = StateSelectKubeConfig
@@ -468,9 +1482,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, m.loadKubeConfigs()
 			}
 
-		case "esc":
+		case key.Matches(msg, keys.Back):
 			if m.state == StateSelectKubeConfig && m.showKubeConfigChange {
 				m.showKubeConfigChange = false
+				m.comparingClusters = false
 				if len(m.prevStates) > 0 {
 					m.state = m.prevStates[len(m.prevStates)-1]
 					m.prevStates = m.prevStates[:len(m.prevStates)-1]
@@ -488,31 +1503,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Go back to previous state
 			return m.goBack()
 
-		case "backspace":
+		case key.Matches(msg, keys.BackEmpty):
 			// Only go back if the text input is empty
-			inputEmpty := false
-			switch m.state {
-			case StateSelectKubeConfig:
-				inputEmpty = m.kcSelector.GetInput() == ""
-			case StateSelectNamespace:
-				inputEmpty = m.nsSelector.GetInput() == ""
-			case StateSelectDeployment:
-				inputEmpty = m.depSelector.GetInput() == ""
-			case StateSelectCommand:
-				inputEmpty = m.cmdSelector.GetInput() == ""
-			case StateSelectPod:
-				inputEmpty = m.podSelector.GetInput() == ""
-			case StateSelectContainer:
-				inputEmpty = m.contSelector.GetInput() == ""
-			case StateInputValue:
-				inputEmpty = m.valueInput.Value() == ""
-			default:
-				inputEmpty = true
-			}
-
-			if inputEmpty {
+			if m.inputEmpty() {
 				if m.state == StateSelectKubeConfig && m.showKubeConfigChange {
 					m.showKubeConfigChange = false
+					m.comparingClusters = false
 					if len(m.prevStates) > 0 {
 						m.state = m.prevStates[len(m.prevStates)-1]
 						m.prevStates = m.prevStates[:len(m.prevStates)-1]
@@ -531,18 +1527,41 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			// Otherwise, let backspace pass through to the text input
 
-		case "enter":
+		case key.Matches(msg, keys.Select):
 			return m.handleEnter()
+		}
 
-		case "tab":
-			return m.handleEnter()
+	case sessionCheckedMsg:
+		// Ignore the result if the user already moved on
+		if m.session == nil || (m.state != StateResumePrompt && m.state != StateSelectCommand) {
+			return m, nil
+		}
+		if !msg.namespaceGone && !msg.deploymentGone {
+			return m, nil
+		}
+		session := m.session
+		m.session = nil
+		m.deployment = ""
+		if msg.namespaceGone {
+			m.warning = fmt.Sprintf("Remembered namespace %s no longer exists. Ctrl+X: prune stale recents", session.Namespace)
+			m.namespace = ""
+			m.state = StateSelectNamespace
+			m.nsSelector.Reset()
+			return m, m.loadNamespaces()
 		}
+		m.warning = fmt.Sprintf("Remembered deployment %s/%s no longer exists. Ctrl+X: prune stale recents", session.Namespace, session.Deployment)
+		m.state = StateSelectDeployment
+		m.depSelector.Reset()
+		return m, m.loadDeployments()
 
 	case NamespacesLoadedMsg:
 		if msg.err != nil {
 			m.nsSelector.SetError(msg.err)
 		} else {
+			m.setPinned(&m.nsSelector, config.CategoryNamespaces, "", nil)
 			m.nsSelector.SetItems(msg.namespaces)
+			m.nsSelector.SetCached(msg.cachedAt)
+			m.offline = !msg.cachedAt.IsZero()
 		}
 		return m, nil
 
@@ -550,7 +1569,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.kcSelector.SetError(msg.err)
 		} else {
-			m.kcSelector.SetRecentItems(m.config.GetRecentKubeConfigs())
+			m.setPinned(&m.kcSelector, config.CategoryKubeConfigs, "", m.config.GetRecentKubeConfigs())
 			m.kcSelector.SetItems(msg.configs)
 		}
 		return m, nil
@@ -568,7 +1587,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.namespace = ""
 			m.deployment = ""
 			m.state = StateSelectNamespace
-			return m, m.loadNamespaces()
+			m.retry = nil
+			m.identity = nil
+			m.capabilities = nil
+			return m, tea.Batch(waitRetry(m.k8sClient), m.loadIdentity(), m.loadCapabilities(), m.startForwards(), m.loadNamespaces())
 		}
 		return m, nil
 
@@ -576,17 +1598,56 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.depSelector.SetError(msg.err)
 		} else {
-			m.depSelector.SetRecentItems(m.config.GetRecentDeployments(m.namespace))
+			m.setPinned(&m.depSelector, config.CategoryDeployments, m.namespace, m.config.GetRecentDeployments(m.namespace))
 			m.depSelector.SetItems(msg.deployments)
+			m.depSelector.SetCached(msg.cachedAt)
+			m.depSelector.SetBadges(nil)
+			m.offline = !msg.cachedAt.IsZero()
+			if !m.offline {
+				return m, tea.Batch(m.loadDeploymentHealth(), m.watchList())
+			}
 		}
 		return m, nil
 
+	case listChangedMsg:
+		return m.handleListChanged(msg)
+
+	case forwardChangedMsg:
+		return m.handleForwardChanged(msg)
+
+	case autostartForwardsMsg:
+		return m, m.startForwards()
+
+	case listRefreshedMsg:
+		return m.handleListRefreshed(msg)
+
+	case deploymentHealthMsg:
+		return m.handleDeploymentHealth(msg)
+
+	case completionMsg:
+		return m.handleCompletion(msg)
+
+	case identityMsg:
+		return m.handleIdentity(msg)
+
+	case capabilitiesMsg:
+		return m.handleCapabilities(msg)
+
 	case PodsLoadedMsg:
 		if msg.err != nil {
 			m.podSelector.SetError(msg.err)
 		} else {
 			m.podSelector.SetRecentItems(m.config.GetRecentPods(m.deployment))
-			m.podSelector.SetItems(msg.pods)
+			m.podSelector.SetListItems(m.podListItems(msg.pods))
+			m.podSelector.SetCached(msg.cachedAt)
+			m.podSelector.SetMultiSelect(m.command != nil && m.command.ComparesPods)
+			m.offline = !msg.cachedAt.IsZero()
+			if m.canAutoPick(StateSelectPod, len(msg.pods)) {
+				return m.autoPickPod(msg.pods[0].Name)
+			}
+			if !m.offline {
+				return m, m.watchList()
+			}
 		}
 		return m, nil
 
@@ -595,20 +1656,148 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.contSelector.SetError(msg.err)
 		} else {
 			m.contSelector.SetItems(msg.containers)
+			m.contSelector.SetCached(msg.cachedAt)
+			m.offline = !msg.cachedAt.IsZero()
 			// If only one container, auto-select it
-			if len(msg.containers) == 1 {
-				m.container = msg.containers[0]
-				return m.proceedAfterContainer()
+			if m.canAutoPick(StateSelectContainer, len(msg.containers)) {
+				return m.autoPickContainer(msg.containers[0])
 			}
+			m.contSelector.SelectItem(m.config.GetPrefs(m.namespace, m.deployment).Container)
+		}
+		return m, nil
+
+	case scaleTickMsg:
+		if m.scheduledScale == nil || !m.scheduledScale.at.Equal(msg.at) {
+			// Cancelled or replaced
+			return m, nil
+		}
+		if time.Now().Before(msg.at) {
+			return m, scaleTick(msg.at)
+		}
+		target := m.scheduledScale.target
+		m.scheduledScale = nil
+		m.startExecution()
+		return m, m.whileExecuting(m.scaleNow(target))
+
+	case scaledMsg:
+		m.state = StateShowResult
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.undoScale = &scaleUndo{namespace: msg.namespace, deployment: msg.deployment, replicas: msg.previous}
+		m.result = msg.result
+		if err := m.config.SetPreviousReplicas(msg.namespace, msg.deployment, msg.previous); err != nil {
+			// Kept for this session only, a later prev would restore an older count
+			m.result = withNote(m.result, "failed to save the previous replica count, prev restores an older one after a restart: "+err.Error())
 		}
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(m.result)
 		return m, nil
 
+	case quickOpenLoadedMsg:
+		return m.handleQuickOpenLoaded(msg)
+
+	case overviewLoadedMsg:
+		if m.state != StateNamespaceOverview || msg.namespace != m.namespace {
+			return m, nil
+		}
+		m.overview, m.overviewErr = msg.overview, msg.err
+		return m, nil
+
+	case rolloutStatusMsg:
+		return m.handleRolloutStatus(msg)
+
+	case configSourcesMsg:
+		return m.handleConfigSources(msg)
+
+	case configDataMsg:
+		return m.editConfig(msg)
+
+	case configEditedMsg:
+		return m.handleConfigEdited(msg)
+
+	case patchEditedMsg:
+		return m.handlePatchEdited(msg)
+
+	case patchPreviewMsg:
+		return m.handlePatchPreview(msg)
+
+	case configChangedMsg:
+		return m.handleConfigChanged(msg)
+
+	case cleanupLoadedMsg:
+		return m.handleCleanupLoaded(msg)
+
+	case filesLoadedMsg:
+		return m.handleFilesLoaded(msg)
+
+	case fileReadMsg:
+		return m.handleFileRead(msg)
+
+	case exportedMsg:
+		if m.state == StateBrowseFiles {
+			return m.handleFileExported(msg)
+		}
+
+	case configRolledMsg:
+		return m.handleConfigRolled(msg)
+
+	case podConfigsMsg:
+		return m.handlePodConfigs(msg)
+
+	case lintedMsg:
+		if msg.err == nil && len(msg.findings) == 0 {
+			// Still the same execution, Esc cancels the change too
+			return m, m.whileExecuting(msg.apply)
+		}
+		m.state = StateShowResult
+		switch {
+		case msg.err != nil:
+			m.err = msg.err
+		case msg.blocked:
+			m.err = fmt.Errorf("%s refused, the template has lint findings:\n%s", m.command.Name, FormatLintFindings(msg.findings))
+		default:
+			m.pendingLint = &msg
+		}
+		return m, nil
+
+	case undoPreviewMsg:
+		m.state = StateShowResult
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.pendingUndo = msg.change
+		m.result = msg.preview
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(msg.preview)
+		return m, nil
+
+	case traceLoadedMsg:
+		return m.handleTraceLoaded(msg)
+
 	case CommandResultMsg:
 		m.state = StateShowResult
 		if msg.err != nil {
 			m.err = msg.err
 		} else {
+			m.kustomize = msg.kustomize
 			m.result = msg.result
+			m.resultViewer.SetHighlighter(nil)
+			m.resultViewer.SetContent(msg.result)
+		}
+		return m, nil
+
+	case ManifestLoadedMsg:
+		m.state = StateShowResult
+		if msg.err != nil {
+			m.err = msg.err
+		} else {
+			m.kustomize = msg.kustomize
+			m.result = msg.manifest
+			m.resultViewer.SetHighlighter(highlightYAML)
+			m.resultViewer.SetContent(msg.manifest)
 		}
 		return m, nil
 
@@ -617,27 +1806,81 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = msg.err
 			m.state = StateShowResult
 		} else {
-			m.logViewer = NewLogViewer()
-			m.logViewer.SetSize(m.width, m.height)
-			m.logViewer.SetRecentSearches(m.config.GetRecentLogSearches())
+			m.logViewer = m.newLogViewer(m.pod + "-" + m.container)
 			m.logViewer.SetLogs(msg.logs)
+			m.logViewer.SetHasOlder(msg.more)
 			m.logViewer.Focus()
+			m.logsUntil = msg.fetchedAt
+			m.logsOldest = msg.oldest
 			m.state = StateViewLogs
 		}
 		return m, nil
 
+	case logSplitMsg:
+		m.config.SetLogSplit(msg.split)
+		return m, nil
+
+	case logHistoryMsg:
+		return m.handleLogHistory(msg)
+
+	case olderLogsMsg:
+		m.loadingOlder = false
+		if m.state != StateViewLogs {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.logViewer.SetStatus("Loading older lines failed: " + msg.err.Error())
+			return m, nil
+		}
+		m.logViewer.PrependLogs(msg.lines)
+		m.logViewer.SetHasOlder(msg.more && len(msg.lines) > 0)
+		if len(msg.lines) > 0 {
+			m.logsOldest = msg.oldest
+		}
+		return m, nil
+
 	case logStreamMsg:
+		if msg.gen != m.streamGen {
+			// Stream was stopped, release the reader
+			msg.pipe.Close()
+			return m, nil
+		}
 		// Append the log line and continue reading
 		m.logViewer.AppendLog(msg.line)
-		return m, readNextLine(msg.reader, msg.pipe)
+		return m, readNextLine(msg.gen, msg.reader, msg.pipe)
+
+	case podLogStreamMsg:
+		if msg.gen != m.streamGen {
+			return m, nil
+		}
+		prefix, text := m.logPrefixer.Format(msg.line)
+		m.logViewer.AppendPrefixedLog(prefix, text, m.logPrefixer.Color(msg.line.PodName))
+		return m, readNextPodLine(msg.gen, msg.lines, msg.errs)
+
+	case eventStreamMsg:
+		if msg.gen != m.streamGen {
+			return m, nil
+		}
+		if m.eventFilter.Match(msg.event) {
+			prefix, text := formatEvent(msg.event)
+			m.logViewer.AppendPrefixedLog(prefix, text, m.logPrefixer.Color(msg.event.InvolvedObject.Kind))
+		}
+		return m, readNextEvent(msg.gen, msg.events, msg.errs)
 
 	case LogStreamEndMsg:
+		if msg.gen != m.streamGen {
+			return m, nil
+		}
 		// Stream ended
 		m.streaming = false
+		m.logsUntil = time.Now()
 		m.logViewer.SetStreaming(false)
 		if msg.err != nil {
 			m.err = msg.err
 		}
+		if m.canDeleteOneOff() && m.oneOff.job != "" {
+			return m, m.oneOffJobStatus()
+		}
 		return m, nil
 
 	case ExecCompleteMsg:
@@ -645,6 +1888,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = msg.err
 			m.state = StateShowResult
 		} else {
+			m.runAfterExit = true
 			return m, tea.Quit
 		}
 		return m, nil
@@ -654,7 +1898,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.assetSelector.SetError(msg.err)
 		} else {
 			m.assetSelector.SetRecentItems(m.config.GetRecentAssetFolders())
-			m.assetSelector.SetItems(msg.folders)
+			var items []string
+			for _, preset := range m.fastDeployPresets() {
+				items = append(items, fastDeployPresetItem(preset))
+			}
+			m.assetSelector.SetItems(append(items, msg.folders...))
 		}
 		return m, nil
 
@@ -664,6 +1912,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = msg.err
 		} else {
 			m.result = msg.result
+			m.resultViewer.SetContent(msg.result)
 		}
 		return m, nil
 	}
@@ -671,12 +1920,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Update the active selector
 	var cmd tea.Cmd
 	switch m.state {
+	case StateResumePrompt:
+		m.resumeSelector, cmd = m.resumeSelector.Update(msg)
 	case StateSelectKubeConfig:
 		m.kcSelector, cmd = m.kcSelector.Update(msg)
 	case StateSelectNamespace:
 		m.nsSelector, cmd = m.nsSelector.Update(msg)
 	case StateSelectDeployment:
 		m.depSelector, cmd = m.depSelector.Update(msg)
+	case StateQuickOpen:
+		m.quickSelector, cmd = m.quickSelector.Update(msg)
+	case StateSelectConfig:
+		m.configSelector, cmd = m.configSelector.Update(msg)
+	case StateSelectCleanup:
+		m.cleanupSelector, cmd = m.cleanupSelector.Update(msg)
+	case StateBrowseFiles:
+		m.fileSelector, cmd = m.fileSelector.Update(msg)
 	case StateSelectCommand:
 		m.cmdSelector, cmd = m.cmdSelector.Update(msg)
 	case StateSelectPod:
@@ -689,11 +1948,53 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.localPathSelector, cmd = m.localPathSelector.Update(msg)
 	case StateInputValue:
 		m.valueInput, cmd = m.valueInput.Update(msg)
+	case StateViewLogs:
+		m.logViewer, cmd = m.logViewer.Update(msg)
+	case StateShowResult:
+		m.resultViewer, cmd = m.resultViewer.Update(msg)
 	}
 
 	return m, cmd
 }
 
+// inputEmpty reports whether the filter or input of the current state is
+// empty, so that backspace goes back instead of deleting
+func (m Model) inputEmpty() bool {
+	switch m.state {
+	case StateResumePrompt:
+		return m.resumeSelector.GetInput() == ""
+	case StateSelectKubeConfig:
+		return m.kcSelector.GetInput() == ""
+	case StateSelectNamespace:
+		return m.nsSelector.GetInput() == ""
+	case StateSelectDeployment:
+		return m.depSelector.GetInput() == ""
+	case StateSelectCommand:
+		return m.cmdSelector.GetInput() == ""
+	case StateSelectPod:
+		return m.podSelector.GetInput() == ""
+	case StateSelectContainer:
+		return m.contSelector.GetInput() == ""
+	case StateSelectAssetFolder:
+		return m.assetSelector.GetInput() == ""
+	case StateSelectLocalPath:
+		return m.localPathSelector.InputEmpty()
+	case StateSelectConfig:
+		return m.configSelector.GetInput() == ""
+	case StateSelectCleanup:
+		return m.cleanupSelector.GetInput() == ""
+	case StateBrowseFiles:
+		return m.fileSelector.GetInput() == ""
+	case StateQuickOpen:
+		return m.quickSelector.GetInput() == ""
+	case StateJobs:
+		return m.jobSelector.GetInput() == ""
+	case StateInputValue:
+		return m.valueInput.Value() == ""
+	}
+	return true
+}
+
 func (m Model) goBack() (tea.Model, tea.Cmd) {
 	switch m.state {
 	case StateSelectDeployment:
@@ -704,51 +2005,59 @@ func (m Model) goBack() (tea.Model, tea.Cmd) {
 		m.depSelector.Reset()
 		return m, m.loadDeployments()
 	case StateSelectPod:
+		if m.containerFirst && m.autoPicked.container != "" {
+			// The only container was picked, back to all pods
+			m.containerFirst = false
+			m.container = ""
+			m.autoPicked.container = ""
+			return m.backToPod()
+		}
+		if m.containerFirst {
+			// Back to the containers of all pods
+			m.container = ""
+			m.state = StateSelectContainer
+			m.contSelector.Reset()
+			return m, m.loadWorkloadContainers()
+		}
 		m.state = StateSelectCommand
 		m.cmdSelector.Reset()
 		return m, nil
 	case StateSelectContainer:
-		if m.command.NeedsPod {
-			m.state = StateSelectPod
-			m.podSelector.Reset()
-			return m, m.loadPods()
+		if m.containerFirst && m.pod == "" {
+			m.containerFirst = false
 		}
+		return m.backToPod()
+	case StateSelectConfig:
+		m.warning = ""
+		if m.configSource != "" {
+			// From the backups back to the ConfigMaps and Secrets
+			return m.showConfigSources()
+		}
+		m.state = StateSelectCommand
+		m.cmdSelector.Reset()
+		return m, nil
+	case StateSelectCleanup, StateBrowseFiles:
 		m.state = StateSelectCommand
 		m.cmdSelector.Reset()
 		return m, nil
 	case StateSelectAssetFolder:
-		m.state = StateSelectContainer
-		m.contSelector.Reset()
-		return m, m.loadContainers()
+		return m.backToContainer()
 	case StateSelectLocalPath:
 		m.state = StateSelectAssetFolder
 		m.assetSelector.Reset()
 		return m, m.loadAssetFolders()
 	case StateInputValue:
-		// Handle back from fast-deploy input (entering new path)
-		if m.command != nil && m.command.Name == "fast-deploy" {
-			m.state = StateSelectLocalPath
-			m.localPathSelector.Reset()
-			paths := []string{"+ Enter new path..."}
-			paths = append(paths, m.config.GetRecentLocalPaths()...)
-			m.localPathSelector.SetItems(paths)
-			return m, nil
+		return m.backToContainer()
+	case StateShowResult:
+		if m.viewingFile() {
+			return m.closeFile()
 		}
-		if m.command.NeedsContainer {
-			m.state = StateSelectContainer
-			m.contSelector.Reset()
-			return m, m.loadContainers()
-		} else if m.command.NeedsPod {
-			m.state = StateSelectPod
-			m.podSelector.Reset()
-			return m, m.loadPods()
+		if m.viewedTrace != "" {
+			return m.closeTrace()
 		}
-		m.state = StateSelectCommand
-		m.cmdSelector.Reset()
-		return m, nil
-	case StateShowResult:
 		m.result = ""
 		m.err = nil
+		m.showErrorDetails = false
 		m.state = StateSelectCommand
 		m.cmdSelector.Reset()
 		return m, nil
@@ -758,6 +2067,41 @@ func (m Model) goBack() (tea.Model, tea.Cmd) {
 
 func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	switch m.state {
+	case StateResumePrompt:
+		selected := m.resumeSelector.GetSelected()
+		if selected == "" {
+			return m, nil
+		}
+		if strings.HasPrefix(selected, resumeContinue) {
+			client, err := m.sessionClient()
+			if err != nil {
+				m.warning = err.Error()
+				m.session = nil
+				m.state = m.startState()
+				if m.state == StateSelectNamespace {
+					return m, m.loadNamespaces()
+				}
+				return m, m.loadDeployments()
+			}
+			m.applySession()
+			if client == nil {
+				return m, nil
+			}
+			// The identity and capabilities loaded so far are the other
+			// context's
+			m.k8sClient = client
+			m.retry = nil
+			m.identity = nil
+			m.capabilities = nil
+			return m, tea.Batch(waitRetry(m.k8sClient), m.loadIdentity(), m.loadCapabilities(), m.checkSession())
+		}
+		m.session = nil
+		m.state = m.startState()
+		if m.state == StateSelectNamespace {
+			return m, m.loadNamespaces()
+		}
+		return m, m.loadDeployments()
+
 	case StateSelectKubeConfig:
 		selected := m.kcSelector.GetSelected()
 		if selected == "" {
@@ -774,10 +2118,13 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			m.command = &Command{Name: "set-kubeconfig", InputPrompt: "Enter kubeconfig file path:"}
 			return m, nil
 		}
+		if m.comparingClusters {
+			return m.compareClusters(selected)
+		}
 
 		// Try to create new client with selected config
 		return m, func() tea.Msg {
-			client, err := k8s.NewClientWithConfig(selected)
+			client, err := k8s.NewClientWithOptions(m.clientOptions(selected))
 			if err != nil {
 				return KubeConfigChangedMsg{err: err}
 			}
@@ -789,9 +2136,23 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		if selected == "" {
 			return m, nil
 		}
+		previous := m.deployment
+		switched := selected != m.namespace
 		m.namespace = selected
 		m.config.SetNamespace(selected)
 		m.showNamespaceChange = false
+		m.warning = ""
+		if switched && m.config.KeepDeployment && previous != "" {
+			// Look for the same deployment here; the list is shown meanwhile
+			// and stays if it does not exist
+			m.deployment = ""
+			m.state = StateSelectDeployment
+			m.depSelector.Reset()
+			return m, tea.Batch(m.loadDeployments(), m.keepDeployment(previous))
+		}
+		if m.config.NamespaceOverview {
+			return m.showOverview()
+		}
 		m.state = StateSelectDeployment
 		m.depSelector.Reset()
 		return m, m.loadDeployments()
@@ -801,13 +2162,27 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		if selected == "" {
 			return m, nil
 		}
-		m.deployment = selected
-		m.config.AddRecentDeployment(m.namespace, selected)
-		m.state = StateSelectCommand
-		m.cmdSelector.Reset()
-		// Set recent commands
-		m.cmdSelector.SetRecentItems(m.config.GetRecentCommands())
-		return m, nil
+		if m.depSelector.IsStale(selected) {
+			m.warning = fmt.Sprintf("%s no longer exists in %s. Ctrl+X: prune stale recents", selected, m.namespace)
+			return m, nil
+		}
+		return m.chooseDeployment(selected)
+
+	case StateQuickOpen:
+		return m.chooseQuickOpen(m.quickSelector.GetSelected())
+
+	case StateSelectConfig:
+		selected := m.configSelector.GetSelected()
+		if selected == "" {
+			return m, nil
+		}
+		return m.chooseConfig(selected)
+
+	case StateSelectCleanup:
+		return m.confirmCleanup()
+
+	case StateBrowseFiles:
+		return m.openFile()
 
 	case StateSelectCommand:
 		selected := m.cmdSelector.GetSelected()
@@ -816,6 +2191,7 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		}
 		// Parse command name from selection
 		cmdName := strings.Split(selected, " - ")[0]
+		m.command = nil
 		for i := range AvailableCommands {
 			if AvailableCommands[i].Name == cmdName {
 				m.command = &AvailableCommands[i]
@@ -825,16 +2201,52 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		if m.command == nil {
 			return m, nil
 		}
-		m.config.AddRecentCommand(selected)
-		return m.proceedAfterCommand()
-
+		if m.inCluster && m.command.NeedsLocalFS {
+			m.state = StateShowResult
+			m.err = fmt.Errorf("%s needs local filesystem access and is not available in-cluster", m.command.Name)
+			return m, nil
+		}
+		if m.command.DeploymentOnly && k8s.IsCustomWorkload(m.deployment) {
+			m.state = StateShowResult
+			m.err = fmt.Errorf("%s is only available for Deployments", m.command.Name)
+			return m, nil
+		}
+		if m.command.Mutating && m.config.IsReadOnly() {
+			m.state = StateShowResult
+			m.err = fmt.Errorf("%s changes the cluster and is disabled in read-only mode", m.command.Name)
+			return m, nil
+		}
+		m.config.AddRecentCommand(selected)
+		m.saveSession()
+		m.containerFirst = false
+		m.autoPicked = autoPick{}
+		return m.proceedAfterCommand()
+
 	case StateSelectPod:
 		selected := m.podSelector.GetSelected()
 		if selected == "" {
 			return m, nil
 		}
+		if m.command.ComparesPods {
+			// Enter marks pods like space, comparing once two are marked
+			m.podSelector.ToggleMarked()
+			marked := m.podSelector.GetMarked()
+			if len(marked) < 2 {
+				return m, nil
+			}
+			m.comparePods = [2]string{marked[0], marked[1]}
+			m.podSelector.SetMultiSelect(false)
+			return m.executeCommand()
+		}
+		if selected == podPickContainerFirst {
+			return m.startContainerFirst()
+		}
 		m.pod = selected
 		m.config.AddRecentPod(m.deployment, selected)
+		m.saveSession()
+		if m.containerFirst {
+			return m.proceedAfterContainer()
+		}
 		return m.proceedAfterPod()
 
 	case StateSelectContainer:
@@ -843,58 +2255,61 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.container = selected
-		return m.proceedAfterContainer()
+		m.warning = ""
+		m.saveSession()
+		return m.afterContainerSelected()
 
 	case StateSelectAssetFolder:
 		selected := m.assetSelector.GetSelected()
 		if selected == "" {
 			return m, nil
 		}
+		if preset, ok := m.selectedFastDeployPreset(selected); ok {
+			return m.runFastDeployPreset(preset)
+		}
 		m.assetFolder = selected
 		m.config.AddRecentAssetFolder(selected)
-		// Now show local path selector
+		// Now browse for the local path, starting where this deployment's
+		// last fast-deploy came from
 		m.state = StateSelectLocalPath
-		m.localPathSelector.Reset()
-		// Build list with "add new" option and recent paths
-		paths := []string{"+ Enter new path..."}
-		paths = append(paths, m.config.GetRecentLocalPaths()...)
-		m.localPathSelector.SetItems(paths)
+		recents := m.config.GetRecentLocalPaths()
+		start := ""
+		if presets := m.fastDeployPresets(); len(presets) > 0 {
+			start = presets[0].LocalPath
+		} else if len(recents) > 0 {
+			start = recents[0]
+		}
+		m.localPathSelector.Open(start, recents)
 		return m, nil
 
 	case StateSelectLocalPath:
-		selected := m.localPathSelector.GetSelected()
-		if selected == "" {
+		path, ok := m.localPathSelector.Select()
+		if !ok {
 			return m, nil
 		}
-		// Check if user wants to enter a new path
-		if strings.HasPrefix(selected, "+ ") {
-			m.state = StateInputValue
-			m.valueInput.SetValue("")
-			m.valueInput.Placeholder = "Enter local dist folder path (e.g., ~/project/dist):"
-			m.valueInput.Focus()
-			return m, nil
-		}
-		// Use selected path
-		m.inputValue = selected
-		m.state = StateExecuting
-		return m, m.executeFastDeploy()
+		// Remembered as the preset of the asset folder, to be repeated
+		return m.runFastDeployPreset(config.FastDeployPreset{
+			Name:        m.assetFolder,
+			Container:   m.container,
+			LocalPath:   path,
+			AssetFolder: m.assetFolder,
+		})
 
 	case StateInputValue:
 		m.inputValue = m.valueInput.Value()
-		if m.inputValue == "" {
+		if m.inputValue == "" && (m.command == nil || !m.command.OptionalInput) {
 			return m, nil
 		}
 
 		// Handle kubeconfig path input
 		if m.command != nil && m.command.Name == "set-kubeconfig" {
 			// Expand ~ to home directory
-			path := m.inputValue
-			if strings.HasPrefix(path, "~/") {
-				home, _ := os.UserHomeDir()
-				path = filepath.Join(home, path[2:])
+			path := config.ExpandPath(m.inputValue)
+			if m.comparingClusters {
+				return m.compareClusters(path)
 			}
 			return m, func() tea.Msg {
-				client, err := k8s.NewClientWithConfig(path)
+				client, err := k8s.NewClientWithOptions(m.clientOptions(path))
 				if err != nil {
 					return KubeConfigChangedMsg{err: err}
 				}
@@ -902,18 +2317,18 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			}
 		}
 
-		// Handle fast-deploy local path input
-		if m.command != nil && m.command.Name == "fast-deploy" {
-			m.config.AddRecentLocalPath(m.inputValue)
-			m.state = StateExecuting
-			return m, m.executeFastDeploy()
-		}
-
 		return m.executeCommand()
 
 	case StateShowResult:
+		if m.viewingFile() {
+			return m.closeFile()
+		}
+		if m.viewedTrace != "" {
+			return m.closeTrace()
+		}
 		m.result = ""
 		m.err = nil
+		m.showErrorDetails = false
 		m.state = StateSelectCommand
 		m.cmdSelector.Reset()
 		return m, nil
@@ -922,6 +2337,23 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// chooseDeployment selects a deployment and continues to its commands
+func (m Model) chooseDeployment(selected string) (tea.Model, tea.Cmd) {
+	m.warning = ""
+	m.deployment = selected
+	m.command = nil
+	m.pod = ""
+	m.container = ""
+	m.config.AddRecentDeployment(m.namespace, selected)
+	m.saveSession()
+	m.state = StateSelectCommand
+	m.cmdSelector.SetItems(m.commandItems())
+	m.cmdSelector.Reset()
+	// Set recent commands
+	m.setPinned(&m.cmdSelector, config.CategoryCommands, "", m.config.GetRecentCommands())
+	return m, nil
+}
+
 func (m Model) proceedAfterCommand() (tea.Model, tea.Cmd) {
 	if m.command.NeedsPod {
 		m.state = StateSelectPod
@@ -943,29 +2375,22 @@ func (m Model) proceedAfterCommand() (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) loadPodsAndSelectFirst() tea.Cmd {
-	return func() tea.Msg {
+	return m.trackList("containers", func() tea.Msg {
 		ctx := context.Background()
-		pods, err := m.k8sClient.ListPodNames(ctx, m.namespace, m.deployment)
+		pods, err := m.k8sClient.ListPodEntries(ctx, m.namespace, m.deployment)
 		if err != nil {
 			return PodsLoadedMsg{err: err}
 		}
 		if len(pods) > 0 {
-			m.pod = pods[0]
+			m.pod = pods[0].Name
 		}
-		containers, err := m.k8sClient.ListContainers(ctx, m.namespace, extractPodName(m.pod))
+		containers, err := m.k8sClient.ListContainers(ctx, m.namespace, m.pod)
 		return ContainersLoadedMsg{containers: containers, err: err}
-	}
-}
-
-func extractPodName(podStr string) string {
-	if idx := strings.Index(podStr, " ("); idx != -1 {
-		return podStr[:idx]
-	}
-	return podStr
+	})
 }
 
 // checkShellAvailable checks if a shell is available in the container
-func checkShellAvailable(ctx context.Context, client *k8s.Client, namespace, podName, container string) error {
+func checkShellAvailable(ctx context.Context, client k8s.Execer, namespace, podName, container string) error {
 	_, err := client.CheckShellAvailable(ctx, namespace, podName, container)
 	return err
 }
@@ -985,9 +2410,74 @@ func (m Model) proceedAfterPod() (tea.Model, tea.Cmd) {
 	return m.executeCommand()
 }
 
+// podPickContainerFirst is the pod list entry that picks the container first
+const podPickContainerFirst = "+ Pick container first (e.g. a sidecar)..."
+
+// offerContainerFirst reports whether the pod list offers picking the
+// container first: for commands that need both, when there is a choice
+func (m Model) offerContainerFirst(pods int) bool {
+	return m.command != nil && m.command.NeedsPod && m.command.NeedsContainer &&
+		!m.command.ComparesPods && !m.containerFirst && pods > 1
+}
+
+// podListItems lists pods by name with their phase as the detail, after
+// the entry picking the container first if offered
+func (m Model) podListItems(pods []k8s.PodEntry) []ListItem {
+	items := make([]ListItem, 0, len(pods)+1)
+	if m.offerContainerFirst(len(pods)) {
+		items = append(items, ListItem{ID: podPickContainerFirst})
+	}
+	for _, pod := range pods {
+		items = append(items, ListItem{ID: pod.Name, Detail: string(pod.Phase)})
+	}
+	return items
+}
+
+// startContainerFirst lists the containers of all the deployment's pods, the
+// pods running the chosen one are listed next
+func (m Model) startContainerFirst() (tea.Model, tea.Cmd) {
+	m.containerFirst = true
+	m.pod = ""
+	m.container = ""
+	m.state = StateSelectContainer
+	m.contSelector.Reset()
+	return m, m.loadWorkloadContainers()
+}
+
+func (m *Model) loadWorkloadContainers() tea.Cmd {
+	return m.trackList("containers", func() tea.Msg {
+		containers, cachedAt, err := m.cachedList(config.CacheContainers, m.namespace+"/"+m.deployment, func() ([]string, error) {
+			return m.k8sClient.ListWorkloadContainers(context.Background(), m.namespace, m.deployment)
+		})
+		return ContainersLoadedMsg{containers: containers, cachedAt: cachedAt, err: err}
+	})
+}
+
+func (m *Model) loadPodsWithContainer() tea.Cmd {
+	return m.trackList("pods", func() tea.Msg {
+		pods, err := m.k8sClient.ListPodEntriesWithContainer(context.Background(), m.namespace, m.deployment, m.container)
+		if err == nil && len(pods) == 0 {
+			err = fmt.Errorf("no pod of %s runs a container named %s", m.deployment, m.container)
+		}
+		return PodsLoadedMsg{pods: pods, err: err}
+	})
+}
+
+// afterContainerSelected continues with the pods running the container in
+// the container-first flow, else with the command
+func (m Model) afterContainerSelected() (tea.Model, tea.Cmd) {
+	if m.containerFirst && m.pod == "" {
+		m.state = StateSelectPod
+		m.podSelector.Reset()
+		return m, m.loadPodsWithContainer()
+	}
+	return m.proceedAfterContainer()
+}
+
 func (m Model) proceedAfterContainer() (tea.Model, tea.Cmd) {
 	// Special handling for fast-deploy
 	if m.command.Name == "fast-deploy" {
+		m.fastDeployExcludes = nil
 		m.state = StateSelectAssetFolder
 		m.assetSelector.Reset()
 		return m, m.loadAssetFolders()
@@ -998,18 +2488,44 @@ func (m Model) proceedAfterContainer() (tea.Model, tea.Cmd) {
 		m.valueInput.SetValue("")
 		m.valueInput.Placeholder = m.command.InputPrompt
 		m.valueInput.Focus()
+		if m.command.Name == "update-image" {
+			m.loadPreviousImages()
+		}
 		return m, nil
 	}
 	return m.executeCommand()
 }
 
+// executeCommand runs the selected command. Commands that finish with a
+// result show the spinner meanwhile and are cancelled with Esc.
 func (m Model) executeCommand() (tea.Model, tea.Cmd) {
-	m.state = StateExecuting
-	ctx := context.Background()
-	podName := extractPodName(m.pod)
+	if m.offline && !m.command.Offline {
+		return m.refuseOffline()
+	}
+	m.startExecution()
+	m.resultViewer.SetSource(m.resultSource())
+	model, cmd := m.runCommand()
+	if next, ok := model.(Model); ok && next.state == StateExecuting && cmd != nil {
+		if next.offline {
+			cmd = next.whenOnline(cmd, next.command.Mutating)
+		}
+		return next, next.whileExecuting(cmd)
+	}
+	return model, cmd
+}
+
+func (m Model) runCommand() (tea.Model, tea.Cmd) {
+	ctx := m.exec.context()
+	podName := m.pod
 
 	switch m.command.Name {
 	case "shell":
+		if !m.config.ExternalShell {
+			if m.shell != nil && m.shell.pod == podName && m.shell.container == m.container {
+				return m.resumeShell()
+			}
+			return m, m.openShell(podName, m.container)
+		}
 		// Try to detect if shell is available first
 		return m, func() tea.Msg {
 			// Try a quick command to check if any shell exists
@@ -1021,60 +2537,87 @@ func (m Model) executeCommand() (tea.Model, tea.Cmd) {
 			return ExecCompleteMsg{err: nil}
 		}
 
+	case "attach":
+		return m, func() tea.Msg {
+			// Fail inside the TUI if the container is not running
+			if _, err := m.k8sClient.GetAttachMode(ctx, m.namespace, podName, m.container); err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return ExecCompleteMsg{err: nil}
+		}
+
 	case "logs":
 		return m, func() tea.Msg {
-			logs, err := m.k8sClient.GetLogs(ctx, k8s.LogOptions{
+			fetchedAt := time.Now()
+			page, err := m.k8sClient.GetLogsPage(ctx, k8s.LogOptions{
 				Namespace:     m.namespace,
 				PodName:       podName,
 				ContainerName: m.container,
 				TailLines:     500,
-			})
-			return LogsLoadedMsg{logs: logs, err: err}
+			}, time.Time{}, 0)
+			if err != nil {
+				return LogsLoadedMsg{err: err}
+			}
+			return LogsLoadedMsg{
+				logs:      strings.Join(page.Lines, "\n"),
+				fetchedAt: fetchedAt,
+				oldest:    page.Oldest,
+				more:      page.More,
+			}
 		}
 
 	case "logs-follow":
 		// Start streaming logs
-		m.streaming = true
-		m.streamCtx, m.cancelStream = context.WithCancel(context.Background())
-		m.logViewer = NewLogViewer()
-		m.logViewer.SetSize(m.width, m.height)
-		m.logViewer.SetRecentSearches(m.config.GetRecentLogSearches())
+		m.logViewer = m.newLogViewer(m.pod + "-" + m.container)
 		m.logViewer.SetLogs("") // Start empty
-		m.logViewer.SetStreaming(true)
 		m.state = StateViewLogs
+		return m.startFollowing(time.Time{})
 
-		podName := extractPodName(m.pod)
-		return m, m.streamLogs(m.streamCtx, podName)
-
-	case "scale":
-		replicas, err := strconv.Atoi(m.inputValue)
+	case "logs-all":
+		// Stream all pods with a per-pod prefix
+		prefixer, err := NewLogPrefixer(m.config.GetLogPrefix())
 		if err != nil {
 			return m, func() tea.Msg {
-				return CommandResultMsg{err: fmt.Errorf("invalid replica count: %s", m.inputValue)}
+				return CommandResultMsg{err: fmt.Errorf("invalid log prefix template: %w", err)}
 			}
 		}
-		return m, func() tea.Msg {
-			err := m.k8sClient.ScaleDeployment(ctx, m.namespace, m.deployment, int32(replicas))
-			if err != nil {
+		m.logPrefixer = prefixer
+		m.logViewer = m.newLogViewer(m.deployment + "-" + m.container)
+		m.logViewer.SetLogs("")
+		m.state = StateViewLogs
+		return m.startFollowing(time.Time{})
+
+	case "logs-history":
+		return m.runLogHistory()
+
+	case "scale":
+		target, at, err := ParseScaleInput(m.inputValue, time.Now())
+		if err != nil {
+			return m, func() tea.Msg {
 				return CommandResultMsg{err: err}
 			}
-			return CommandResultMsg{result: fmt.Sprintf("Scaled %s to %d replicas", m.deployment, replicas)}
 		}
+		if !at.IsZero() {
+			return m.scheduleScale(target, at)
+		}
+		return m, m.scaleNow(target)
 
 	case "update-image":
-		return m, func() tea.Msg {
-			err := m.k8sClient.UpdateImage(ctx, m.namespace, m.deployment, m.container, m.inputValue)
+		return m, m.lintBefore(k8s.EditImage(m.container, m.inputValue), func() tea.Msg {
+			change := config.Change{Namespace: m.namespace, Deployment: m.deployment, Operation: config.OpUpdateImage, Container: m.container, After: m.inputValue}
+			note, err := ApplyChange(ctx, m.k8sClient, change, func(ctx context.Context) error {
+				return m.k8sClient.UpdateImage(ctx, m.namespace, m.deployment, m.container, m.inputValue)
+			})
 			if err != nil {
 				return CommandResultMsg{err: err}
 			}
-			return CommandResultMsg{result: fmt.Sprintf("Updated %s image to %s", m.container, m.inputValue)}
-		}
+			return CommandResultMsg{result: withNote(fmt.Sprintf("Updated %s image to %s", m.container, m.inputValue), note)}
+		})
 
 	case "port-forward":
-		parts := strings.Split(m.inputValue, ":")
-		if len(parts) != 2 {
+		if _, err := k8s.ParsePortPairs(m.inputValue); err != nil {
 			return m, func() tea.Msg {
-				return CommandResultMsg{err: fmt.Errorf("invalid port format, use local:remote")}
+				return CommandResultMsg{err: err}
 			}
 		}
 		return m, func() tea.Msg {
@@ -1089,11 +2632,14 @@ func (m Model) executeCommand() (tea.Model, tea.Cmd) {
 			}
 		}
 		return m, func() tea.Msg {
-			err := m.k8sClient.RollbackDeployment(ctx, m.namespace, m.deployment, revision)
+			change := config.Change{Namespace: m.namespace, Deployment: m.deployment, Operation: config.OpRollback, After: m.inputValue}
+			note, err := ApplyChange(ctx, m.k8sClient, change, func(ctx context.Context) error {
+				return m.k8sClient.RollbackDeployment(ctx, m.namespace, m.deployment, revision)
+			})
 			if err != nil {
 				return CommandResultMsg{err: err}
 			}
-			return CommandResultMsg{result: fmt.Sprintf("Rolled back %s to revision %d", m.deployment, revision)}
+			return CommandResultMsg{result: withNote(fmt.Sprintf("Rolled back %s to revision %d", m.deployment, revision), note)}
 		}
 
 	case "set-env":
@@ -1103,14 +2649,104 @@ func (m Model) executeCommand() (tea.Model, tea.Cmd) {
 				return CommandResultMsg{err: fmt.Errorf("invalid format, use KEY=VALUE")}
 			}
 		}
+		return m, m.lintBefore(k8s.EditEnv(m.container, parts[0], parts[1]), func() tea.Msg {
+			change := config.Change{Namespace: m.namespace, Deployment: m.deployment, Operation: config.OpSetEnv, Container: m.container, Key: parts[0], After: parts[1]}
+			note, err := ApplyChange(ctx, m.k8sClient, change, func(ctx context.Context) error {
+				return m.k8sClient.SetEnvVar(ctx, m.namespace, m.deployment, m.container, parts[0], parts[1])
+			})
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: withNote(fmt.Sprintf("Set %s=%s on %s", parts[0], parts[1], m.container), note)}
+		})
+
+	case "undo":
+		return m, m.loadUndo()
+
+	case "update-images":
+		return m, m.loadImageTable()
+
+	case "debug-sidecar":
+		return m, m.addDebugSidecar()
+
+	case "debug-copy":
+		return m, m.createDebugCopy()
+
+	case "experiment":
+		return m, m.startExperiment()
+
+	case "template-job":
+		return m, m.startTemplateJob()
+
+	case "run-job":
+		return m.runJob()
+
+	case "jobs":
+		return m.showJobs()
+
+	case "nettest":
+		return m, m.runNetTest()
+
+	case "compare-clusters":
+		return m.pickCompareKubeConfig()
+
+	case "config-files":
+		return m, m.showMountedFiles()
+
+	case "files":
+		return m.browseFiles("/")
+
+	case "pressure":
+		return m, func() tea.Msg {
+			pressure, err := m.k8sClient.GetResourcePressure(ctx, m.namespace, m.deployment)
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: formatResourcePressure(m.deployment, pressure)}
+		}
+
+	case "certs":
 		return m, func() tea.Msg {
-			err := m.k8sClient.SetEnvVar(ctx, m.namespace, m.deployment, m.container, parts[0], parts[1])
+			certs, err := m.k8sClient.ListCertificates(ctx, m.namespace)
 			if err != nil {
 				return CommandResultMsg{err: err}
 			}
-			return CommandResultMsg{result: fmt.Sprintf("Set %s=%s on %s", parts[0], parts[1], m.container)}
+			return CommandResultMsg{result: formatCertificates(m.namespace, certs, time.Now())}
 		}
 
+	case "pull-secrets":
+		return m, m.checkPullSecrets()
+
+	case "drain-impact":
+		return m, m.checkDrainImpact(strings.TrimSpace(m.inputValue))
+
+	case "janitor":
+		return m, func() tea.Msg {
+			stuck, err := m.k8sClient.FindStuckResources(ctx, m.namespace)
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: formatStuckResources(m.namespace, stuck, time.Now())}
+		}
+
+	case "wait":
+		return m.startWait(strings.TrimSpace(m.inputValue))
+
+	case "config-rollout":
+		return m, m.rollConfig()
+
+	case "edit-config", "restore-config":
+		return m.showConfigSources()
+
+	case "patch":
+		return m.editPatch()
+
+	case "rename":
+		return m, m.planRename(strings.TrimSpace(m.inputValue))
+
+	case "cleanup":
+		return m.showCleanupPods()
+
 	case "list-env":
 		return m, func() tea.Msg {
 			envVars, err := m.k8sClient.GetEnvVars(ctx, m.namespace, m.deployment, m.container)
@@ -1162,88 +2798,209 @@ func (m Model) executeCommand() (tea.Model, tea.Cmd) {
 			for _, rs := range rsList {
 				revision := rs.Annotations["deployment.kubernetes.io/revision"]
 				replicas := *rs.Spec.Replicas
-				result.WriteString(fmt.Sprintf("  Revision %s: %d replicas\n", revision, replicas))
+				result.WriteString(fmt.Sprintf("  Revision %s: %d replicas", revision, replicas))
+				if cause := rs.Annotations[k8s.ChangeCauseAnnotation]; cause != "" {
+					result.WriteString(" - " + cause)
+				}
+				result.WriteString("\n")
 			}
 			return CommandResultMsg{result: result.String()}
 		}
 
+	case "history":
+		return m, func() tea.Msg {
+			history, err := m.k8sClient.GetRolloutHistory(ctx, m.namespace, m.deployment)
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: formatRolloutHistory(history)}
+		}
+
 	case "ingress":
 		return m, func() tea.Msg {
-			ingresses, err := m.k8sClient.GetIngresses(ctx, m.namespace)
+			result, err := m.k8sClient.DescribeIngresses(ctx, m.namespace, m.deployment)
 			if err != nil {
 				return CommandResultMsg{err: err}
 			}
-			var result strings.Builder
-			result.WriteString(fmt.Sprintf("Ingresses in %s:\n\n", m.namespace))
-			for _, ing := range ingresses {
-				result.WriteString(fmt.Sprintf("  %s:\n", ing.Name))
-				for _, rule := range ing.Spec.Rules {
-					host := rule.Host
-					if host == "" {
-						host = "*"
-					}
-					result.WriteString(fmt.Sprintf("    Host: %s\n", host))
-					if rule.HTTP != nil {
-						for _, path := range rule.HTTP.Paths {
-							result.WriteString(fmt.Sprintf("      %s -> %s:%d\n",
-								path.Path,
-								path.Backend.Service.Name,
-								path.Backend.Service.Port.Number))
-						}
-					}
-				}
+			return CommandResultMsg{result: result}
+		}
+
+	case "gateway":
+		return m, func() tea.Msg {
+			result, err := m.k8sClient.DescribeGatewayRoutes(ctx, m.namespace, m.deployment)
+			if err != nil {
+				return CommandResultMsg{err: err}
 			}
-			return CommandResultMsg{result: result.String()}
+			return CommandResultMsg{result: result}
+		}
+
+	case "map":
+		return m, func() tea.Msg {
+			result, err := m.k8sClient.DescribeTopology(ctx, m.namespace, m.deployment)
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: result}
 		}
 
+	case "metrics":
+		return m, m.runMetrics(strings.TrimSpace(m.inputValue))
+
+	case "deps":
+		return m, func() tea.Msg {
+			result, err := m.k8sClient.DescribeDependencies(ctx, m.namespace, m.deployment)
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: result}
+		}
+
+	case "yaml", "pod-yaml":
+		return m, m.loadManifest()
+
+	case "compare-pods":
+		pods := m.comparePods
+		return m, func() tea.Msg {
+			result, err := m.k8sClient.ComparePods(ctx, m.namespace, pods[0], pods[1])
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: result}
+		}
+
+	case "rbac":
+		return m, func() tea.Msg {
+			result, err := m.k8sClient.DescribeRBAC(ctx, m.namespace, m.deployment)
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: result}
+		}
+
+	case "sa-token":
+		serviceAccount, duration, err := parseTokenInput(m.inputValue)
+		if err != nil {
+			return m, func() tea.Msg {
+				return CommandResultMsg{err: err}
+			}
+		}
+		return m, func() tea.Msg {
+			result, err := m.mintToken(ctx, serviceAccount, duration)
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: result}
+		}
+
+	case "events":
+		filter, err := k8s.ParseEventFilter(m.inputValue)
+		if err != nil {
+			return m, func() tea.Msg {
+				return CommandResultMsg{err: err}
+			}
+		}
+		// Only the colors of the log prefix settings apply to events
+		settings := m.config.GetLogPrefix()
+		m.logPrefixer, _ = NewLogPrefixer(config.LogPrefix{Colors: settings.Colors, NoColor: settings.NoColor})
+		m.eventFilter = filter
+		m.logViewer = m.newLogViewer(m.namespace + "-events")
+		m.logViewer.SetLogs("")
+		m.state = StateViewLogs
+		return m.startFollowing(time.Time{})
+
+	case "resources":
+		m.resources = NewResourcesModel(m.k8sClient, m.namespace)
+		m.resources.embedded = true
+		m.resources.SetReadOnly(m.config.IsReadOnly())
+		m.resources, _ = m.resources.update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		m.state = StateBrowseResources
+		return m, m.resources.Init()
+
 	case "describe":
 		return m, func() tea.Msg {
-			deployment, err := m.k8sClient.GetDeployment(ctx, m.namespace, m.deployment)
+			result, err := m.k8sClient.DescribeDeployment(ctx, m.namespace, m.deployment)
 			if err != nil {
 				return CommandResultMsg{err: err}
 			}
-			var result strings.Builder
-			result.WriteString(fmt.Sprintf("Deployment: %s\n", deployment.Name))
-			result.WriteString(fmt.Sprintf("Namespace: %s\n", deployment.Namespace))
-			result.WriteString(fmt.Sprintf("Replicas: %d/%d\n", deployment.Status.ReadyReplicas, *deployment.Spec.Replicas))
-			result.WriteString(fmt.Sprintf("Strategy: %s\n", deployment.Spec.Strategy.Type))
-			result.WriteString("\nContainers:\n")
-			for _, container := range deployment.Spec.Template.Spec.Containers {
-				result.WriteString(fmt.Sprintf("  %s:\n", container.Name))
-				result.WriteString(fmt.Sprintf("    Image: %s\n", container.Image))
-				if len(container.Ports) > 0 {
-					result.WriteString("    Ports: ")
-					for i, port := range container.Ports {
-						if i > 0 {
-							result.WriteString(", ")
-						}
-						result.WriteString(fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol))
-					}
-					result.WriteString("\n")
-				}
+			return CommandResultMsg{result: result}
+		}
+
+	case "whoami":
+		return m, func() tea.Msg {
+			identity, err := m.k8sClient.WhoAmI(ctx)
+			caps, _ := m.k8sClient.Capabilities(ctx)
+			return CommandResultMsg{result: FormatIdentity(identity, caps, err, m.namespace)}
+		}
+
+	case "describe-pod":
+		return m, func() tea.Msg {
+			result, err := m.k8sClient.DescribePod(ctx, m.namespace, m.pod)
+			if err != nil {
+				return CommandResultMsg{err: err}
 			}
-			return CommandResultMsg{result: result.String()}
+			return CommandResultMsg{result: result}
 		}
 	}
 
 	return m, nil
 }
 
+// View renders the current screen. Accessible mode renders it in ASCII,
+// with the announced state on the first line.
 func (m Model) View() string {
+	if !accessible {
+		return m.view()
+	}
+	return accessibleText(m.announcement() + "\n" + m.view())
+}
+
+func (m Model) view() string {
 	var b strings.Builder
 
 	// Header
-	b.WriteString(RenderHeader(m.kubeconfig, m.namespace, m.deployment))
+	b.WriteString(m.header())
 	b.WriteString("\n")
+	if forwards := m.forwardsLine(); forwards != "" {
+		b.WriteString(forwards)
+		b.WriteString("\n")
+	}
+
+	if m.warning != "" && (m.state == StateSelectNamespace || m.state == StateSelectDeployment || m.state == StateSelectContainer || m.state == StateSelectConfig) {
+		b.WriteString(WarningStyle.Render("⚠ " + m.warning))
+		b.WriteString("\n\n")
+	}
+	if warning := capabilityWarning(m.capabilities); warning != "" && (m.state == StateSelectNamespace || m.state == StateSelectCommand) {
+		b.WriteString(WarningStyle.Render(warning))
+		b.WriteString("\n\n")
+	}
+	if note := m.retryNote(); note != "" {
+		b.WriteString(WarningStyle.Render(note))
+		b.WriteString("\n\n")
+	}
+	if notice := m.autoPickNotice(); notice != "" {
+		b.WriteString(InfoStyle.Render(notice))
+		b.WriteString("\n\n")
+	}
+
+	if m.showHelp {
+		b.WriteString(m.helpView())
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+	}
 
 	// Main content based on state
 	switch m.state {
+	case StateResumePrompt:
+		b.WriteString(m.resumeSelector.View())
+
 	case StateSelectKubeConfig:
 		if m.k8sClient == nil && m.initialClientErr != nil {
 			b.WriteString(WarningStyle.Render("No kubeconfig found or configured."))
 			b.WriteString("\n")
 			b.WriteString(InfoStyle.Render("Please select or enter a kubeconfig path:"))
 			b.WriteString("\n\n")
+		} else if m.comparingClusters {
+			b.WriteString(InfoStyle.Render(fmt.Sprintf("Compare %s with the same deployment in:", m.deployment)))
+			b.WriteString("\n\n")
 		} else if m.showKubeConfigChange {
 			b.WriteString(InfoStyle.Render("Changing kubeconfig..."))
 			b.WriteString("\n\n")
@@ -1260,13 +3017,70 @@ func (m Model) View() string {
 	case StateSelectDeployment:
 		b.WriteString(m.depSelector.View())
 
+	case StateSelectConfig:
+		b.WriteString(m.configSelectorView())
+
+	case StateSelectCleanup:
+		b.WriteString(m.cleanupSelector.View())
+		b.WriteString("\n\n")
+		b.WriteString(RenderHelp("↑↓: navigate", "Space: mark", "Ctrl+A: mark all", "Enter: delete marked", "Esc: back", "Ctrl+C: quit"))
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+
+	case StateBrowseFiles:
+		b.WriteString(m.filesView())
+		b.WriteString("\n\n")
+		b.WriteString(RenderHelp("↑↓: navigate", "Enter: open or show", "Backspace: up", "Ctrl+S: download", "Esc: back", "Ctrl+C: quit"))
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+
+	case StateQuickOpen:
+		b.WriteString(m.quickSelector.View())
+		b.WriteString("\n\n")
+		b.WriteString(RenderHelp("↑↓: navigate", "Enter: open", "Ctrl+R: reload", "Esc: back", "Ctrl+C: quit"))
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+
+	case StateNamespaceOverview:
+		b.WriteString(m.overviewView())
+		b.WriteString("\n\n")
+		b.WriteString(RenderHelp("any key: deployments", "r: refresh", "q: quit"))
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+
+	case StateShellPane:
+		b.WriteString(m.shellPaneView())
+		b.WriteString("\n")
+		b.WriteString(RenderHelp("Ctrl+]: back to commands (the shell keeps running)"))
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+
+	case StateJobs, StateJobOutput:
+		b.WriteString(m.jobsView())
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+
+	case StateEditImages:
+		b.WriteString(m.imageTableView())
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+
 	case StateSelectCommand:
 		b.WriteString(m.cmdSelector.View())
+		if hint := m.repeatFastDeployHint(); hint != "" {
+			b.WriteString("\n")
+			b.WriteString(DimStyle.Render(hint))
+		}
 
 	case StateSelectPod:
+		if m.command != nil && m.command.ComparesPods {
+			b.WriteString(InfoStyle.Render("Mark two pods to compare (Space or Enter)"))
+			b.WriteString("\n\n")
+		}
+		if m.containerFirst {
+			b.WriteString(InfoStyle.Render(fmt.Sprintf("Pods running container %s:", m.container)))
+			b.WriteString("\n\n")
+		}
 		b.WriteString(m.podSelector.View())
 
 	case StateSelectContainer:
+		if m.containerFirst && m.pod == "" {
+			b.WriteString(InfoStyle.Render(fmt.Sprintf("Containers in any pod of %s:", m.deployment)))
+			b.WriteString("\n\n")
+		}
 		b.WriteString(m.contSelector.View())
 
 	case StateSelectAssetFolder:
@@ -1280,28 +3094,102 @@ func (m Model) View() string {
 		b.WriteString(m.localPathSelector.View())
 
 	case StateInputValue:
-		if m.command != nil && m.command.Name == "fast-deploy" {
-			b.WriteString(InfoStyle.Render(fmt.Sprintf("Target: /app/assets/%s/js", m.assetFolder)))
-			b.WriteString("\n\n")
-			b.WriteString(LabelStyle.Render("Enter local dist folder path:"))
-		} else {
-			b.WriteString(LabelStyle.Render(m.command.InputPrompt))
-		}
+		b.WriteString(LabelStyle.Render(m.command.InputPrompt))
 		b.WriteString("\n")
 		b.WriteString(FocusedInputStyle.Render(m.valueInput.View()))
+		if len(m.completions) > 0 || m.completionNote != "" {
+			b.WriteString("\n")
+			b.WriteString(m.completionsView())
+		}
+		if m.command != nil && m.command.Name == "update-image" && len(m.previousImages) > 0 {
+			b.WriteString("\n\n")
+			b.WriteString(m.previousImagesView())
+		}
 
 	case StateExecuting:
-		b.WriteString(RenderLoading("Executing command..."))
+		b.WriteString(m.executingView())
 
 	case StateShowResult:
+		if m.scheduledScale != nil {
+			b.WriteString(m.scheduledScaleView())
+			break
+		}
+		if m.pendingLint != nil {
+			b.WriteString(m.lintView())
+			break
+		}
+		if m.rolloutWait != nil {
+			b.WriteString(m.rolloutWaitView())
+			break
+		}
 		if m.err != nil {
-			b.WriteString(RenderError(m.err.Error()))
+			b.WriteString(renderErrorScreen(m.err, m.showErrorDetails, m.width))
 		} else {
 			b.WriteString(SuccessStyle.Render("Result:"))
 			b.WriteString("\n\n")
-			b.WriteString(m.result)
+			b.WriteString(m.resultViewer.View())
 		}
 		b.WriteString("\n\n")
+		if m.err == nil && m.isManifestCommand() {
+			state := "hidden"
+			if m.showManagedFields {
+				state = "shown"
+			}
+			b.WriteString(InfoStyle.Render("M: toggle managedFields (" + state + ") • "))
+		}
+		if m.err == nil && m.canShowOverlay() {
+			state := "hidden"
+			if m.kustomize.shown {
+				state = "shown"
+			}
+			b.WriteString(InfoStyle.Render("K: toggle kustomize overlay (" + state + ") • "))
+		}
+		if m.pendingUndo != nil {
+			b.WriteString(WarningStyle.Render("y: restore • any other key: cancel"))
+			break
+		}
+		if m.pendingPatch != nil {
+			b.WriteString(WarningStyle.Render("y: apply • e: edit again • ↑↓: scroll • any other key: cancel"))
+			break
+		}
+		if m.pendingRename != nil {
+			b.WriteString(WarningStyle.Render(m.renameHelp()))
+			break
+		}
+		if m.pendingConfigRestore != nil {
+			b.WriteString(WarningStyle.Render(m.configRestoreHelp()))
+			break
+		}
+		if m.pendingCleanup != nil {
+			b.WriteString(WarningStyle.Render("y: delete • any other key: back to the list"))
+			break
+		}
+		if m.pendingOnline != nil {
+			b.WriteString(WarningStyle.Render("y: run • any other key: cancel"))
+			break
+		}
+		if m.err == nil && m.canUndoScale() {
+			b.WriteString(InfoStyle.Render(fmt.Sprintf("U: undo (scale back to %d) • ", m.undoScale.replicas)))
+		}
+		if m.err == nil && m.canRemoveDebug() {
+			b.WriteString(InfoStyle.Render(m.debugHelp()))
+		}
+		if m.err == nil && m.canCreatePullSecret() {
+			b.WriteString(InfoStyle.Render("c: create the pull secret • "))
+		}
+		if m.err == nil && m.canDeleteOneOff() {
+			b.WriteString(InfoStyle.Render(m.oneOffHelp()))
+		}
+		if m.err == nil && m.canRestoreConfig() {
+			b.WriteString(InfoStyle.Render("U: put the previous data back • "))
+		}
+		if m.err != nil && hasErrorDetails(m.err) {
+			if m.showErrorDetails {
+				b.WriteString(InfoStyle.Render("d: hide details • "))
+			} else {
+				b.WriteString(InfoStyle.Render("d: show details • "))
+			}
+		}
 		b.WriteString(InfoStyle.Render("Press Enter to continue..."))
 
 	case StateViewLogs:
@@ -1309,53 +3197,194 @@ func (m Model) View() string {
 		var logView strings.Builder
 		logView.WriteString(m.logViewer.View())
 		logView.WriteString("\n")
-		help := []string{"Tab: toggle search", "↑↓: scroll (when not typing)", "PgUp/PgDn: page", "Enter: exit search", "Ctrl+L: clear", "Esc/q: back"}
+		help := []string{"Tab: toggle search", "f: follow on/off", "L: load older", "↑↓: scroll (when not typing)", "PgUp/PgDn: page", "w: wrap/scroll", "←→: pan", "m: pin", "[/]: prev/next pin", "E: export pins", "S: save", "A/B/C: context", "t: age", "o: sort by time", "p: group by pod/object", "T: time range", "c: colors", "+/-: resize", "z: hide entry", "Enter: exit search", "Ctrl+L: clear", "?: all keys", "Esc/q: back"}
 		logView.WriteString(RenderHelp(help...))
 		return lipgloss.NewStyle().Padding(1, 2).Render(logView.String())
+
+	case StateBrowseResources:
+		// The explorer renders its own help
+		b.WriteString(m.resources.content())
+		return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
 	}
 
 	// Help
 	b.WriteString("\n\n")
 	help := []string{"↑↓: navigate", "Enter: select", "Esc/Backspace: back", "Ctrl+K: kubeconfig", "Ctrl+N: namespace", "Ctrl+C: quit"}
+	if m.inCluster {
+		help = []string{"↑↓: navigate", "Enter: select", "Esc/Backspace: back", "Ctrl+N: namespace", "Ctrl+C: quit"}
+	}
+	help = append(help, "?: all keys")
+	switch m.state {
+	case StateSelectKubeConfig, StateSelectNamespace, StateSelectDeployment, StateSelectCommand:
+		help = append(help, "Ctrl+F: favorite")
+	}
+	if m.state == StateSelectContainer {
+		help = append(help, "Ctrl+D: make default")
+	}
+	if m.state == StateSelectDeployment {
+		help = append(help, "Ctrl+O: overview")
+	}
+	if m.canQuickOpen() {
+		help = append(help, "Ctrl+T: open deployment")
+	}
+	if m.state == StateSelectCommand && m.shell != nil {
+		help = append(help, "Ctrl+]: back to shell")
+	}
+	if m.state == StateSelectCommand {
+		if n := m.runningJobs(); n > 0 {
+			help = append(help, fmt.Sprintf("%d background job(s) running, see jobs", n))
+		}
+	}
+	if m.state == StateSelectDeployment && len(m.depSelector.StaleItems()) > 0 {
+		help = append(help, "Ctrl+X: prune stale recents")
+	}
+	if status := m.statusBar(); status != "" {
+		b.WriteString(status)
+		b.WriteString("\n")
+	}
 	b.WriteString(RenderHelp(help...))
 
 	return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
 }
 
 // RunShell runs an interactive shell after exiting bubble tea
-func RunShell(k8sClient *k8s.Client, namespace, pod, container, shell string) error {
+func RunShell(k8sClient k8s.Execer, namespace, pod, container, shell string) error {
 	ctx := context.Background()
-	podName := extractPodName(pod)
-	return k8sClient.Shell(ctx, namespace, podName, container, shell)
+	return k8sClient.Shell(ctx, k8s.ShellOptions{
+		Namespace:     namespace,
+		PodName:       pod,
+		ContainerName: container,
+		Shell:         shell,
+		Stdin:         os.Stdin,
+		Stdout:        os.Stdout,
+		Stderr:        os.Stderr,
+	})
 }
 
-// RunLogs streams logs after exiting bubble tea
-func RunLogs(k8sClient *k8s.Client, namespace, pod, container string, follow bool) error {
+// RunAttach attaches to the main process of a container after exiting bubble
+// tea, warning first that its stdin is shared
+func RunAttach(k8sClient k8s.Execer, namespace, pod, container string) error {
 	ctx := context.Background()
-	podName := extractPodName(pod)
-	tailLines := int64(100)
-	return k8sClient.StreamLogs(ctx, k8s.LogOptions{
+	mode, err := k8sClient.GetAttachMode(ctx, namespace, pod, container)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Messages, "Attaching to the main process of %s/%s.\n", pod, container)
+	if mode.Stdin {
+		fmt.Fprintln(Messages, "⚠ Its stdin is shared: input goes to PID 1 and every other attached session sees it.")
+		fmt.Fprintln(Messages, "  Ctrl+C and Ctrl+D reach the process and may stop the container.")
+		fmt.Fprintf(Messages, "  Press %s to detach without affecting it.\n", k8s.DetachKeys)
+	} else {
+		fmt.Fprintln(Messages, "The container does not keep stdin open, only its output is shown. Press Ctrl+C to detach.")
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
+
+	err = k8sClient.Attach(ctx, k8s.AttachOptions{
 		Namespace:     namespace,
-		PodName:       podName,
+		PodName:       pod,
+		ContainerName: container,
+		Stdin:         os.Stdin,
+		Stdout:        os.Stdout,
+		Stderr:        os.Stderr,
+	})
+	if ctx.Err() != nil {
+		err = nil
+	}
+	if err == nil {
+		fmt.Fprintln(Messages, "\nDetached.")
+	}
+	return err
+}
+
+// Messages receives the human-readable progress of the commands run outside
+// the TUI, data goes to stdout. It is io.Discard with --quiet.
+var Messages io.Writer = os.Stderr
+
+// RunLogs streams logs after exiting bubble tea, starting with the last
+// tailLines lines
+func RunLogs(k8sClient k8s.Logs, namespace, pod, container string, follow bool, tailLines int64, pipeCommand string) error {
+	ctx := context.Background()
+	opts := k8s.LogOptions{
+		Namespace:     namespace,
+		PodName:       pod,
 		ContainerName: container,
 		Follow:        follow,
 		TailLines:     tailLines,
-	}, os.Stdout)
+	}
+	var out io.Writer = os.Stdout
+	var pipe *LogPipe
+	if pipeCommand != "" {
+		// The command's output goes to stderr, so the logs can still be
+		// redirected on their own
+		var err error
+		if pipe, err = StartLogPipe(pipeCommand, os.Stderr); err != nil {
+			return err
+		}
+		out = io.MultiWriter(os.Stdout, pipe)
+	}
+
+	var err error
+	if follow {
+		err = k8sClient.FollowLogs(ctx, opts, out)
+	} else {
+		err = k8sClient.StreamLogs(ctx, opts, out)
+	}
+	if pipe != nil {
+		pipe.Close()
+		<-pipe.Done()
+		if pipeErr := pipe.Err(); pipeErr != nil && err == nil {
+			err = pipeErr
+		}
+	}
+	return err
 }
 
 // RunPortForward runs port forwarding after exiting bubble tea
-func RunPortForward(k8sClient *k8s.Client, namespace, pod string, localPort, remotePort int) error {
-	ctx := context.Background()
-	podName := extractPodName(pod)
-	return k8sClient.PortForward(ctx, k8s.PortForwardOptions{
-		Namespace:  namespace,
-		PodName:    podName,
-		LocalPort:  localPort,
-		RemotePort: remotePort,
+func RunPortForward(k8sClient k8s.Forwarder, namespace, pod string, ports []k8s.PortPair) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ready := make(chan struct{})
+	go func() {
+		select {
+		case <-ready:
+			fmt.Fprintf(Messages, "Port forwarding is ready. Forwarding %s\n", formatForwardedPorts(ports))
+			fmt.Fprintln(Messages, "Press Ctrl+C to stop...")
+		case <-ctx.Done():
+		}
+	}()
+
+	err := k8sClient.PortForward(ctx, k8s.PortForwardOptions{
+		Namespace: namespace,
+		PodName:   pod,
+		Ports:     ports,
+		Out:       Messages,
+		ErrOut:    os.Stderr,
+		ReadyChan: ready,
 	})
+	if ctx.Err() != nil {
+		fmt.Fprintln(Messages, "\nStopping port forward...")
+	}
+	return err
+}
+
+// formatForwardedPorts lists forwarded ports, e.g. "8080 -> 80, 9090 -> 9090"
+func formatForwardedPorts(ports []k8s.PortPair) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = fmt.Sprintf("%d -> %d", p.Local, p.Remote)
+	}
+	return strings.Join(parts, ", ")
 }
 
 // Getter methods for accessing model state after TUI exits
+func (m Model) GetClient() k8s.ClientInterface {
+	return m.k8sClient
+}
+
 func (m Model) GetNamespace() string {
 	return m.namespace
 }
@@ -1379,3 +3408,9 @@ func (m Model) GetContainer() string {
 func (m Model) GetInputValue() string {
 	return m.inputValue
 }
+
+// RunsAfterExit reports whether the TUI quit to run the selected command in
+// the terminal, rather than being quit by the user
+func (m Model) RunsAfterExit() bool {
+	return m.runAfterExit
+}
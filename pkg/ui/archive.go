@@ -0,0 +1,240 @@
+package ui
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+)
+
+// ArchiveEntry is a log file saved below the export dir
+type ArchiveEntry struct {
+	Path       string
+	Cluster    string
+	Namespace  string
+	Deployment string
+	ModTime    time.Time
+}
+
+// Label returns the line shown for the entry in the archive list
+func (e ArchiveEntry) Label() string {
+	return fmt.Sprintf("%s  %s/%s/%s  %s",
+		e.ModTime.Format("2006-01-02 15:04"), e.Cluster, e.Namespace, e.Deployment, filepath.Base(e.Path))
+}
+
+// archiveCluster names the cluster a kubeconfig points to in the archive
+func archiveCluster(kubeconfig string) string {
+	switch kubeconfig {
+	case "":
+		return "default"
+	case k8s.InClusterKubeConfig:
+		return "in-cluster"
+	}
+	name := filepath.Base(kubeconfig)
+	if ext := filepath.Ext(name); ext != "" && ext != name {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return sanitizeFileName(name)
+}
+
+// ListLogArchive returns the saved log files, newest first. Files exported
+// before logs were grouped by deployment are listed with "-" placeholders.
+func ListLogArchive() ([]ArchiveEntry, error) {
+	root, err := config.GetExportDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ArchiveEntry
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".log" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		entry := ArchiveEntry{Path: path, Cluster: "-", Namespace: "-", Deployment: "-", ModTime: info.ModTime()}
+		if rel, err := filepath.Rel(filepath.Join(root, "logs"), filepath.Dir(path)); err == nil {
+			if parts := strings.Split(filepath.ToSlash(rel), "/"); len(parts) == 3 {
+				entry.Cluster, entry.Namespace, entry.Deployment = parts[0], parts[1], parts[2]
+			}
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log archive: %w", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime.After(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// archiveLoadedMsg carries the archive listing
+type archiveLoadedMsg struct {
+	entries []ArchiveEntry
+	err     error
+}
+
+// archiveFileMsg carries the content of an opened archive file
+type archiveFileMsg struct {
+	entry   ArchiveEntry
+	content string
+	err     error
+}
+
+// ArchiveModel browses saved log files and opens them in the log viewer
+type ArchiveModel struct {
+	list      FuzzyList
+	entries   map[string]ArchiveEntry // label -> entry
+	logViewer LogViewer
+	viewing   bool
+	err       error
+	width     int
+	height    int
+}
+
+// NewArchiveModel creates the logs archive browser
+func NewArchiveModel() ArchiveModel {
+	return ArchiveModel{
+		list:      NewFuzzyList("📚 Saved Logs"),
+		entries:   make(map[string]ArchiveEntry),
+		logViewer: NewLogViewer(),
+	}
+}
+
+func (m ArchiveModel) Init() tea.Cmd {
+	return loadArchive
+}
+
+func loadArchive() tea.Msg {
+	entries, err := ListLogArchive()
+	return archiveLoadedMsg{entries: entries, err: err}
+}
+
+func openArchiveFile(entry ArchiveEntry) tea.Cmd {
+	return func() tea.Msg {
+		data, err := os.ReadFile(entry.Path)
+		return archiveFileMsg{entry: entry, content: strings.TrimSuffix(string(data), "\n"), err: err}
+	}
+}
+
+func (m ArchiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.logViewer.SetSize(msg.Width, msg.Height)
+		return m, nil
+
+	case archiveLoadedMsg:
+		if msg.err != nil {
+			m.list.SetError(msg.err)
+			return m, nil
+		}
+		m.entries = make(map[string]ArchiveEntry, len(msg.entries))
+		labels := make([]string, 0, len(msg.entries))
+		for _, e := range msg.entries {
+			label := e.Label()
+			m.entries[label] = e
+			labels = append(labels, label)
+		}
+		m.list.SetItems(labels)
+		return m, nil
+
+	case fuzzyMatchMsg:
+		return m, m.list.Matched(msg)
+
+	case archiveFileMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.logViewer = NewLogViewer()
+		m.logViewer.SetSize(m.width, m.height)
+		m.logViewer.SetSource(strings.TrimSuffix(filepath.Base(msg.entry.Path), ".log"))
+		m.logViewer.SetExportDir(filepath.Dir(msg.entry.Path))
+		m.logViewer.SetLogs(msg.content)
+		m.logViewer.Focus()
+		m.viewing = true
+		return m, nil
+
+	case tea.KeyMsg:
+		m.err = nil
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		if m.viewing {
+			if msg.String() == "esc" || (msg.String() == "q" && !m.logViewer.IsFocused()) {
+				// Back to the list, which may have new saves
+				m.viewing = false
+				return m, loadArchive
+			}
+			m.logViewer, cmd = m.logViewer.Update(msg)
+			return m, cmd
+		}
+
+		switch msg.String() {
+		case "esc":
+			return m, tea.Quit
+		case "enter":
+			if entry, ok := m.entries[m.list.GetSelected()]; ok {
+				return m, openArchiveFile(entry)
+			}
+			return m, nil
+		}
+	}
+
+	if m.viewing {
+		m.logViewer, cmd = m.logViewer.Update(msg)
+		return m, cmd
+	}
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m ArchiveModel) View() string {
+	if m.viewing {
+		var b strings.Builder
+		b.WriteString(m.logViewer.View())
+		b.WriteString("\n")
+		b.WriteString(RenderHelp("Tab: toggle search", "↑↓: scroll", "m: pin", "E: export pins", "t: age", "T: time range", "Esc/q: back to list"))
+		return accessibleView(lipgloss.NewStyle().Padding(1, 2).Render(b.String()))
+	}
+
+	var b strings.Builder
+	if accessible {
+		b.WriteString(accessibleText("Status: " + m.list.Summary()))
+		b.WriteString("\n")
+	}
+	b.WriteString(m.list.View())
+	if m.err != nil {
+		b.WriteString("\n")
+		b.WriteString(RenderError(m.err.Error()))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(RenderHelp("↑↓: navigate", "Enter: open", "Type: filter by date, cluster, deployment or name", "Esc: quit"))
+	return accessibleView(lipgloss.NewStyle().Padding(1, 2).Render(b.String()))
+}
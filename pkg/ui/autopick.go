@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// autoPick is what was picked without asking because it was the only choice
+type autoPick struct {
+	pod       string
+	container string
+}
+
+// canAutoPick reports whether a list of n items in the current state is
+// skipped: it has one item, the state still waits for it and the config
+// does not turn it off
+func (m Model) canAutoPick(state AppState, n int) bool {
+	return n == 1 && m.state == state && m.command != nil && !m.command.ComparesPods && !m.config.NoAutoSelect
+}
+
+// autoPickPod continues with the only pod as if it was selected
+func (m Model) autoPickPod(pod string) (tea.Model, tea.Cmd) {
+	m.pod = pod
+	m.autoPicked.pod = pod
+	m.saveSession()
+	if m.containerFirst {
+		return m.proceedAfterContainer()
+	}
+	return m.proceedAfterPod()
+}
+
+// autoPickContainer continues with the only container as if it was selected
+func (m Model) autoPickContainer(container string) (tea.Model, tea.Cmd) {
+	m.container = container
+	m.autoPicked.container = container
+	m.saveSession()
+	return m.afterContainerSelected()
+}
+
+// autoPickNotice tells what was picked without asking, on the screens
+// between the command selection and its result
+func (m Model) autoPickNotice() string {
+	switch m.state {
+	case StateSelectContainer, StateSelectAssetFolder, StateSelectLocalPath, StateInputValue, StateExecuting, StateShowResult:
+	default:
+		return ""
+	}
+	var picked []string
+	if m.autoPicked.pod != "" {
+		picked = append(picked, "pod "+m.autoPicked.pod)
+	}
+	if m.autoPicked.container != "" {
+		picked = append(picked, "container "+m.autoPicked.container)
+	}
+	if len(picked) == 0 {
+		return ""
+	}
+	return "Auto-selected the only " + strings.Join(picked, " and the only ")
+}
+
+// backToPod goes back to the pod list, or to the commands if the command
+// needs no pod or the only pod was picked without asking
+func (m Model) backToPod() (tea.Model, tea.Cmd) {
+	if m.command.NeedsPod && m.autoPicked.pod == "" {
+		m.state = StateSelectPod
+		m.podSelector.Reset()
+		return m, m.loadPods()
+	}
+	m.state = StateSelectCommand
+	m.cmdSelector.Reset()
+	return m, nil
+}
+
+// backToContainer goes back to the container list, or further back if the
+// command needs no container or the only container was picked without asking
+func (m Model) backToContainer() (tea.Model, tea.Cmd) {
+	if m.command.NeedsContainer && m.autoPicked.container == "" {
+		m.state = StateSelectContainer
+		m.contSelector.Reset()
+		return m, m.loadContainers()
+	}
+	return m.backToPod()
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// capabilitiesMsg carries the server version and the APIs it serves
+type capabilitiesMsg struct {
+	kubeconfig   string
+	capabilities *k8s.Capabilities
+}
+
+// loadCapabilities reads the server version shown in the header and checks
+// the APIs khelper's features rely on. Failures leave both out.
+func (m *Model) loadCapabilities() tea.Cmd {
+	client := m.k8sClient
+	return func() tea.Msg {
+		caps, _ := client.Capabilities(context.Background())
+		return capabilitiesMsg{kubeconfig: client.GetKubeConfigPath(), capabilities: caps}
+	}
+}
+
+// handleCapabilities keeps the capabilities if the kubeconfig is still the
+// current one
+func (m Model) handleCapabilities(msg capabilitiesMsg) (tea.Model, tea.Cmd) {
+	if msg.kubeconfig == m.kubeconfig {
+		m.capabilities = msg.capabilities
+	}
+	return m, nil
+}
+
+// capabilityWarning warns about the features the server lacks the APIs
+// for, empty if there are none
+func capabilityWarning(caps *k8s.Capabilities) string {
+	if caps == nil {
+		return ""
+	}
+	warnings := caps.Warnings()
+	if len(warnings) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("⚠ Kubernetes %s lacks APIs khelper relies on:\n  %s", caps.Version, strings.Join(warnings, "\n  "))
+}
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+)
+
+// formatCertificates renders the certs view: a section per TLS secret with
+// its certificate's expiry, subject, issuer and SANs, expiring ones flagged
+func formatCertificates(namespace string, certs []k8s.SecretCertificate, now time.Time) string {
+	if len(certs) == 0 {
+		return fmt.Sprintf("No TLS secrets in %s", namespace)
+	}
+	expiring := 0
+	for _, c := range certs {
+		if c.Cert != nil && c.Cert.DaysLeft(now) < k8s.CertWarnDays {
+			expiring++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "TLS secrets in %s: %d", namespace, len(certs))
+	if expiring > 0 {
+		fmt.Fprintf(&b, ", %d expired or expiring within %d days", expiring, k8s.CertWarnDays)
+	}
+	b.WriteString("\n")
+	for _, c := range certs {
+		fmt.Fprintf(&b, "\n%s\n", c.Secret)
+		if c.Err != nil {
+			fmt.Fprintf(&b, "    ✗ %v\n", c.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "    Expires:    %s\n", c.Cert.Expiry(now))
+		fmt.Fprintf(&b, "    Subject:    %s\n", c.Cert.Subject)
+		fmt.Fprintf(&b, "    Issuer:     %s\n", c.Cert.Issuer)
+		fmt.Fprintf(&b, "    SANs:       %s\n", strings.Join(c.Cert.SANs, ", "))
+		fmt.Fprintf(&b, "    Valid from: %s\n", c.Cert.NotBefore.Local().Format("2006-01-02 15:04"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
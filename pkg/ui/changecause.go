@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"os"
+	"os/user"
+	"strings"
+	"text/template"
+
+	"khelper/pkg/config"
+)
+
+// defaultChangeCauseTemplate describes a change like kubectl --record did,
+// naming khelper and who ran it
+const defaultChangeCauseTemplate = "khelper {{.Command}} {{.Change}} by {{.User}}"
+
+// changeCauseData is what the change-cause template is executed with
+type changeCauseData struct {
+	Command    string // update-image, set-env, rollback, patch, undo, debug-sidecar or config-rollout
+	Change     string // e.g. app=nginx:1.25, set LOG_LEVEL, revision 4 or the fields patched
+	User       string
+	Namespace  string
+	Deployment string
+	Container  string
+}
+
+// changeCauseTemplate renders the change-cause annotation, nil when it is
+// switched off
+var changeCauseTemplate = template.Must(template.New("change_cause").Parse(defaultChangeCauseTemplate))
+
+// SetChangeCauseTemplate sets the template of the kubernetes.io/change-cause
+// annotation written on updates: the default if empty, none if "off"
+func SetChangeCauseTemplate(text string) error {
+	switch text {
+	case "off":
+		changeCauseTemplate = nil
+		return nil
+	case "":
+		text = defaultChangeCauseTemplate
+	}
+	tmpl, err := template.New("change_cause").Parse(text)
+	if err != nil {
+		return err
+	}
+	changeCauseTemplate = tmpl
+	return nil
+}
+
+// changeCause describes the changes of an update for the rollout history,
+// empty for changes that roll out no new revision and when the annotation is
+// switched off. Env values are left out, they may be secrets.
+func changeCause(changes []config.Change) string {
+	if changeCauseTemplate == nil || len(changes) == 0 {
+		return ""
+	}
+	first := changes[0]
+	data := changeCauseData{
+		Command:    first.Operation,
+		User:       currentUser(),
+		Namespace:  first.Namespace,
+		Deployment: first.Deployment,
+		Container:  first.Container,
+	}
+	if first.Reverts != "" {
+		data.Command = "undo " + first.Operation
+	}
+	var details []string
+	for _, change := range changes {
+		detail := changeDetail(change)
+		if detail == "" {
+			return ""
+		}
+		details = append(details, detail)
+	}
+	data.Change = strings.Join(details, ", ")
+	return renderChangeCause(data)
+}
+
+// renderChangeCause executes the change-cause template, empty when it is
+// switched off or fails
+func renderChangeCause(data changeCauseData) string {
+	if changeCauseTemplate == nil {
+		return ""
+	}
+	var b strings.Builder
+	if err := changeCauseTemplate.Execute(&b, data); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// changeDetail describes what a change sets, empty if it rolls out no new
+// revision
+func changeDetail(change config.Change) string {
+	switch change.Operation {
+	case config.OpUpdateImage:
+		return change.Container + "=" + change.After
+	case config.OpSetEnv:
+		if change.Reverts != "" && change.After == config.FormatState(nil) {
+			return "unset " + change.Key
+		}
+		return "set " + change.Key
+	case config.OpRollback:
+		return "to revision " + change.After
+	case config.OpPatch, config.OpRename:
+		return change.After
+	}
+	return ""
+}
+
+// currentUser is the name of the local user running khelper
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cleanupLoadedMsg carries the pods the cleanup command offers to delete
+type cleanupLoadedMsg struct {
+	pods []k8s.CleanupPod
+	err  error
+}
+
+// showCleanupPods lists the namespace's evicted, completed, failed and
+// crash looping pods to mark for deletion. The input is the restart count
+// from which crash looping pods are listed.
+func (m Model) showCleanupPods() (tea.Model, tea.Cmd) {
+	restarts := int32(k8s.DefaultCleanupRestarts)
+	if input := strings.TrimSpace(m.inputValue); input != "" {
+		n, err := strconv.ParseInt(input, 10, 32)
+		if err != nil || n < 0 {
+			m.state = StateShowResult
+			m.err = fmt.Errorf("invalid restart count %q, use a number such as 5", input)
+			return m, nil
+		}
+		restarts = int32(n)
+	}
+	m.state = StateSelectCleanup
+	m.cleanupRestarts = restarts
+	m.cleanupNames = nil
+	m.cleanupSelector.Reset()
+	m.cleanupSelector.SetMultiSelect(true)
+	m.cleanupSelector.SetLoading(true)
+	namespace := m.namespace
+	return m, func() tea.Msg {
+		pods, err := m.k8sClient.ListCleanupPods(context.Background(), namespace, restarts)
+		return cleanupLoadedMsg{pods: pods, err: err}
+	}
+}
+
+func (m Model) handleCleanupLoaded(msg cleanupLoadedMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateSelectCleanup {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.cleanupSelector.SetError(msg.err)
+		return m, nil
+	}
+	if len(msg.pods) == 0 {
+		m.state = StateShowResult
+		m.err = nil
+		m.result = fmt.Sprintf("Nothing to clean up: no evicted, completed or failed pods in %s, and none crash looping with %d or more restarts.",
+			m.namespace, m.cleanupRestarts)
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(m.result)
+		return m, nil
+	}
+	labels, names := cleanupLabels(msg.pods, time.Now())
+	m.cleanupNames = names
+	m.cleanupSelector.SetItems(labels)
+	return m, nil
+}
+
+// cleanupLabels renders the pods as aligned "name  reason  age  detail"
+// rows and maps each row back to its pod
+func cleanupLabels(pods []k8s.CleanupPod, now time.Time) ([]string, map[string]string) {
+	nameWidth, reasonWidth := 0, 0
+	for _, pod := range pods {
+		nameWidth = max(nameWidth, len(pod.Name))
+		reasonWidth = max(reasonWidth, len(pod.Reason))
+	}
+	labels := make([]string, len(pods))
+	names := make(map[string]string, len(pods))
+	for i, pod := range pods {
+		label := fmt.Sprintf("%-*s  %-*s  %4s", nameWidth, pod.Name, reasonWidth, pod.Reason, formatAge(now.Sub(pod.Since)))
+		if detail := strings.Join(strings.Fields(pod.Detail), " "); detail != "" {
+			label += "  " + detail
+		}
+		labels[i] = label
+		names[label] = pod.Name
+	}
+	return labels, names
+}
+
+// confirmCleanup asks to delete the marked pods, or the one under the
+// cursor if none are marked
+func (m Model) confirmCleanup() (tea.Model, tea.Cmd) {
+	labels := m.cleanupSelector.GetMarked()
+	if len(labels) == 0 {
+		if selected := m.cleanupSelector.GetSelected(); selected != "" {
+			labels = []string{selected}
+		}
+	}
+	if len(labels) == 0 {
+		return m, nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Delete %d pod(s) in %s?\n\n", len(labels), m.namespace)
+	m.pendingCleanup = nil
+	for _, label := range labels {
+		m.pendingCleanup = append(m.pendingCleanup, m.cleanupNames[label])
+		b.WriteString("  " + label + "\n")
+	}
+	m.state = StateShowResult
+	m.err = nil
+	m.result = b.String()
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// deleteCleanupPods deletes the confirmed pods
+func (m Model) deleteCleanupPods(names []string) tea.Cmd {
+	ctx := m.exec.context()
+	namespace := m.namespace
+	return func() tea.Msg {
+		deleted, err := m.k8sClient.DeletePods(ctx, namespace, names)
+		if len(deleted) == 0 {
+			return CommandResultMsg{err: err}
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Deleted %d of %d pod(s) in %s:\n\n", len(deleted), len(names), namespace)
+		for _, name := range deleted {
+			b.WriteString("  " + name + "\n")
+		}
+		if err != nil {
+			fmt.Fprintf(&b, "\nFailed:\n\n%s\n", err)
+		}
+		return CommandResultMsg{result: b.String()}
+	}
+}
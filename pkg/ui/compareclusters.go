@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pickCompareKubeConfig shows the kubeconfig list to pick the cluster the
+// deployment is compared with; Esc returns to the commands
+func (m Model) pickCompareKubeConfig() (tea.Model, tea.Cmd) {
+	m.comparingClusters = true
+	m.showKubeConfigChange = true
+	m.prevStates = append(m.prevStates, StateSelectCommand)
+	m.state = StateSelectKubeConfig
+	m.kcSelector.Reset()
+	return m, m.loadKubeConfigs()
+}
+
+// compareClusters compares the deployment with the one of the same name and
+// namespace in the cluster of another kubeconfig
+func (m Model) compareClusters(path string) (tea.Model, tea.Cmd) {
+	m.comparingClusters = false
+	m.showKubeConfigChange = false
+	// Entering a path replaced the command with set-kubeconfig
+	for i := range AvailableCommands {
+		if AvailableCommands[i].Name == "compare-clusters" {
+			m.command = &AvailableCommands[i]
+		}
+	}
+	if len(m.prevStates) > 0 {
+		m.prevStates = m.prevStates[:len(m.prevStates)-1]
+	}
+	m.startExecution()
+	ctx := m.exec.context()
+	namespace, deployment, current := m.namespace, m.deployment, m.k8sClient.GetKubeConfigPath()
+	return m, m.whileExecuting(func() tea.Msg {
+		other, err := k8s.NewClientWithOptions(m.clientOptions(path))
+		if err != nil {
+			return CommandResultMsg{err: fmt.Errorf("failed to load kubeconfig %s: %w", path, err)}
+		}
+		mine, err := m.k8sClient.GetDeployment(ctx, namespace, deployment)
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		theirs, err := other.GetDeployment(ctx, namespace, deployment)
+		if err != nil {
+			return CommandResultMsg{err: fmt.Errorf("%s/%s in %s: %w", namespace, deployment, path, err)}
+		}
+		nameA, nameB := kubeConfigLabel(current, path)
+		return CommandResultMsg{result: fmt.Sprintf("%s/%s in two clusters\n\n%s", namespace, deployment,
+			k8s.CompareDeployments(mine, theirs, nameA, nameB)), kustomize: newKustomizeResult(namespace, "compare-clusters", mine, path)}
+	})
+}
+
+// kubeConfigLabel names two kubeconfigs as column headers: their file
+// names, or the full paths if those are the same
+func kubeConfigLabel(a, b string) (string, string) {
+	if filepath.Base(a) == filepath.Base(b) {
+		return a, b
+	}
+	return filepath.Base(a), filepath.Base(b)
+}
@@ -0,0 +1,242 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"khelper/pkg/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxCompletions is how many candidates are listed under the value input
+const maxCompletions = 12
+
+// completionMsg carries the candidates for the word at the end of the value
+// input when Tab was pressed
+type completionMsg struct {
+	input      string   // the value the candidates are for
+	prefix     string   // the value before the completed word
+	candidates []string // replacements of the word starting with it, sorted
+	err        error
+}
+
+// completer finds the candidates for the word at the end of the value
+// input; they are filtered by the word afterwards
+type completer func(ctx context.Context) ([]string, error)
+
+// complete starts completing the word at the end of the value input, on
+// Tab. Inputs without completion take Tab as Enter, as before.
+func (m Model) complete() (tea.Model, tea.Cmd) {
+	input := m.valueInput.Value()
+	prefix, word, find := m.completerFor(input)
+	if find == nil {
+		return m.handleEnter()
+	}
+	return m, func() tea.Msg {
+		found, err := find(context.Background())
+		seen := make(map[string]bool)
+		var candidates []string
+		for _, c := range found {
+			if strings.HasPrefix(c, word) && !seen[c] {
+				seen[c] = true
+				candidates = append(candidates, c)
+			}
+		}
+		sort.Strings(candidates)
+		return completionMsg{input: input, prefix: prefix, candidates: candidates, err: err}
+	}
+}
+
+// completerFor returns what completes an input of the current command: the
+// input before the word at its end, the word and how to find candidates.
+// The completer is nil if the command's input has no completion.
+func (m Model) completerFor(input string) (string, string, completer) {
+	if m.command == nil {
+		return "", "", nil
+	}
+	namespace, deployment, pod, container := m.namespace, m.deployment, m.pod, m.container
+	client := m.k8sClient
+
+	switch m.command.Name {
+	case "set-env":
+		// Only the key before the = is completed
+		if strings.Contains(input, "=") {
+			return "", "", nil
+		}
+		return "", input, func(ctx context.Context) ([]string, error) {
+			vars, err := client.GetEnvVars(ctx, namespace, deployment, container)
+			keys := make([]string, 0, len(vars))
+			for _, v := range vars {
+				keys = append(keys, v.Name+"=")
+			}
+			return keys, err
+		}
+
+	case "update-image":
+		var previous []string
+		for _, image := range m.previousImages {
+			previous = append(previous, image.Image)
+		}
+		return "", input, func(ctx context.Context) ([]string, error) {
+			d, err := client.GetDeployment(ctx, namespace, deployment)
+			if err != nil {
+				return previous, err
+			}
+			candidates := previous
+			for _, c := range d.Spec.Template.Spec.Containers {
+				if c.Name == container {
+					candidates = append(candidates, imageRepository(c.Image)+":")
+				}
+			}
+			return candidates, nil
+		}
+
+	case "metrics":
+		queries := m.config.GetPrometheusQueries()
+		return "", input, func(context.Context) ([]string, error) {
+			names := make([]string, 0, len(queries))
+			for _, q := range queries {
+				names = append(names, q.Name)
+			}
+			return names, nil
+		}
+
+	case "drain-impact":
+		return "", input, func(ctx context.Context) ([]string, error) {
+			return client.ListNodeNames(ctx)
+		}
+
+	case "set-kubeconfig":
+		return "", input, func(context.Context) ([]string, error) {
+			return localPathCandidates(input)
+		}
+
+	case "run-job":
+		// Absolute paths inside the container, at the end of the command
+		prefix, word := splitLastWord(input)
+		if !strings.HasPrefix(word, "/") {
+			return "", "", nil
+		}
+		return prefix, word, func(ctx context.Context) ([]string, error) {
+			dir, _ := path.Split(word)
+			names, err := client.ListDirectories(ctx, namespace, pod, container, dir)
+			candidates := make([]string, 0, len(names))
+			for _, name := range names {
+				candidates = append(candidates, dir+name+"/")
+			}
+			return candidates, err
+		}
+	}
+	return "", "", nil
+}
+
+// handleCompletion fills in the candidate if there is one, else the prefix
+// all candidates share, and lists them under the input
+func (m Model) handleCompletion(msg completionMsg) (tea.Model, tea.Cmd) {
+	// The input changed while the candidates were looked up
+	if m.state != StateInputValue || m.valueInput.Value() != msg.input {
+		return m, nil
+	}
+	m.completions, m.completionNote = nil, ""
+	switch {
+	case msg.err != nil && len(msg.candidates) == 0:
+		m.completionNote = fmt.Sprintf("No completions: %v", msg.err)
+		return m, nil
+	case len(msg.candidates) == 0:
+		m.completionNote = "No completions"
+		return m, nil
+	case len(msg.candidates) == 1:
+		m.valueInput.SetValue(msg.prefix + msg.candidates[0])
+	default:
+		m.valueInput.SetValue(msg.prefix + commonPrefix(msg.candidates))
+		m.completions = msg.candidates
+	}
+	m.valueInput.CursorEnd()
+	return m, nil
+}
+
+// completionsView lists the candidates of the last Tab under the input
+func (m Model) completionsView() string {
+	if m.completionNote != "" {
+		return DimStyle.Render(m.completionNote)
+	}
+	shown := m.completions
+	if len(shown) > maxCompletions {
+		shown = shown[:maxCompletions]
+	}
+	lines := make([]string, 0, len(shown)+1)
+	for _, c := range shown {
+		lines = append(lines, ListItemStyle.Render("  "+c))
+	}
+	if more := len(m.completions) - len(shown); more > 0 {
+		lines = append(lines, DimStyle.Render(fmt.Sprintf("  … %d more, type to narrow down", more)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// localPathCandidates lists the entries of the directory a local path is
+// in, directories with a trailing slash. Hidden entries are only listed
+// once the name starts with a dot.
+func localPathCandidates(input string) ([]string, error) {
+	dir, base := path.Split(input)
+	readDir := config.ExpandPath(dir)
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(path.Join(readDir, name)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if isDir {
+			name += "/"
+		}
+		candidates = append(candidates, dir+name)
+	}
+	return candidates, nil
+}
+
+// imageRepository returns an image reference without its tag or digest
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	// A colon after the last slash starts the tag, one before is a port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// splitLastWord splits an input before the word at its end
+func splitLastWord(input string) (string, string) {
+	i := strings.LastIndex(input, " ") + 1
+	return input[:i], input[i:]
+}
+
+// commonPrefix returns the longest prefix all strings share
+func commonPrefix(items []string) string {
+	prefix := items[0]
+	for _, s := range items[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,383 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"khelper/pkg/config"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// configSourcesMsg carries the ConfigMaps and Secrets the deployment uses
+type configSourcesMsg struct {
+	sources []string // "ConfigMap/name" or "Secret/name"
+	err     error
+}
+
+// configDataMsg carries the data of the ConfigMap or Secret to edit
+type configDataMsg struct {
+	source string
+	data   map[string]string
+	err    error
+}
+
+// configEditedMsg reports that the editor closed the file with the data
+type configEditedMsg struct {
+	source string
+	before map[string]string
+	file   string
+	err    error
+}
+
+// configChangedMsg reports an edit or restore and the backup made of the
+// replaced data
+type configChangedMsg struct {
+	backup *config.ConfigBackup
+	result string
+	err    error
+}
+
+// showConfigSources lists the deployment's ConfigMaps and Secrets for
+// edit-config and restore-config
+func (m Model) showConfigSources() (tea.Model, tea.Cmd) {
+	m.state = StateSelectConfig
+	m.configSource = ""
+	m.configBackups = nil
+	m.configSelector.Reset()
+	m.configSelector.SetLoading(true)
+	namespace, deployment := m.namespace, m.deployment
+	return m, func() tea.Msg {
+		sources, err := m.k8sClient.ListConfigSources(context.Background(), namespace, deployment)
+		return configSourcesMsg{sources: sources, err: err}
+	}
+}
+
+func (m Model) handleConfigSources(msg configSourcesMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateSelectConfig || m.configSource != "" {
+		return m, nil
+	}
+	switch {
+	case msg.err != nil:
+		m.configSelector.SetError(msg.err)
+	case len(msg.sources) == 0:
+		m.configSelector.SetError(fmt.Errorf("%s uses no ConfigMaps or Secrets", m.deployment))
+	default:
+		m.configSelector.SetItems(msg.sources)
+	}
+	return m, nil
+}
+
+// chooseConfig continues with the selected ConfigMap or Secret: edit-config
+// opens it in the editor, restore-config lists its backups; a selected
+// backup is restored
+func (m Model) chooseConfig(selected string) (tea.Model, tea.Cmd) {
+	if m.configSource != "" {
+		for i, label := range m.configSelector.items {
+			if label == selected {
+				backup := m.configBackups[i]
+				m.startExecution()
+				return m, m.whileExecuting(m.restoreConfig(m.configSource, backup))
+			}
+		}
+		return m, nil
+	}
+
+	if m.command.Name == "restore-config" {
+		kind, name, _ := strings.Cut(selected, "/")
+		backups, err := config.ListConfigBackups(archiveCluster(m.k8sClient.GetKubeConfigPath()), m.namespace, kind, name)
+		if err != nil {
+			m.state = StateShowResult
+			m.err = err
+			return m, nil
+		}
+		if len(backups) == 0 {
+			m.warning = fmt.Sprintf("No backups of %s yet, they are made by edit-config", selected)
+			return m, nil
+		}
+		m.warning = ""
+		m.configSource = selected
+		m.configBackups = backups
+		labels := make([]string, len(backups))
+		for i, backup := range backups {
+			labels[i] = fmt.Sprintf("%s  %d key(s)", backup.Time.Format("2006-01-02 15:04:05"), len(backup.Data))
+			if backup.User != "" {
+				labels[i] += "  by " + backup.User
+			}
+		}
+		m.configSelector.Reset()
+		m.configSelector.SetItems(labels)
+		return m, nil
+	}
+
+	m.startExecution()
+	ctx := m.exec.context()
+	namespace := m.namespace
+	return m, m.whileExecuting(func() tea.Msg {
+		kind, name, _ := strings.Cut(selected, "/")
+		data, err := m.k8sClient.GetConfigData(ctx, namespace, kind, name)
+		return configDataMsg{source: selected, data: data, err: err}
+	})
+}
+
+// editConfig writes the data to a temporary YAML file and opens it in the
+// user's editor
+func (m Model) editConfig(msg configDataMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.state = StateShowResult
+		m.err = msg.err
+		return m, nil
+	}
+	file, err := writeConfigFile(msg.source, m.namespace, msg.data)
+	if err != nil {
+		m.state = StateShowResult
+		m.err = err
+		return m, nil
+	}
+	editor := editorCommand(file)
+	return m, tea.ExecProcess(editor, func(err error) tea.Msg {
+		if err != nil {
+			err = fmt.Errorf("failed to run %s: %w", editor.Path, err)
+		}
+		return configEditedMsg{source: msg.source, before: msg.data, file: file, err: err}
+	})
+}
+
+// handleConfigEdited applies the edited data, after saving a backup of the
+// data it replaces
+func (m Model) handleConfigEdited(msg configEditedMsg) (tea.Model, tea.Cmd) {
+	defer os.RemoveAll(filepath.Dir(msg.file))
+	m.state = StateShowResult
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+	after, err := readConfigFile(msg.file)
+	if err != nil {
+		m.err = fmt.Errorf("%w, %s was not changed", err, msg.source)
+		return m, nil
+	}
+	if changes := dataChanges(msg.before, after); changes == "" {
+		m.err = nil
+		m.result = fmt.Sprintf("No changes, %s was left as it is.", msg.source)
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(m.result)
+		return m, nil
+	}
+	m.startExecution()
+	backup := m.configBackup(msg.source, msg.before)
+	ctx := m.exec.context()
+	return m, m.whileExecuting(func() tea.Msg {
+		path, err := config.SaveConfigBackup(backup)
+		if err != nil {
+			return configChangedMsg{err: fmt.Errorf("failed to back up %s, it was not changed: %w", msg.source, err)}
+		}
+		backup.Path = path
+		if err := m.k8sClient.UpdateConfigData(ctx, backup.Namespace, backup.Kind, backup.Name, after); err != nil {
+			return configChangedMsg{err: err}
+		}
+		return configChangedMsg{backup: &backup, result: fmt.Sprintf("Updated %s: %s.\n\nThe previous data is backed up in %s.\n\n%s",
+			msg.source, dataChanges(msg.before, after), path, configChangeHint)}
+	})
+}
+
+// restoreConfig puts a backup's data back, backing up the data it replaces
+func (m Model) restoreConfig(source string, backup config.ConfigBackup) tea.Cmd {
+	ctx := m.exec.context()
+	namespace := m.namespace
+	return func() tea.Msg {
+		kind, name, _ := strings.Cut(source, "/")
+		current, err := m.k8sClient.GetConfigData(ctx, namespace, kind, name)
+		if err != nil {
+			return configChangedMsg{err: err}
+		}
+		changes := dataChanges(current, backup.Data)
+		if changes == "" {
+			return configChangedMsg{result: fmt.Sprintf("%s already holds the data of the backup of %s.", source, backup.Time.Format("2006-01-02 15:04:05"))}
+		}
+		replaced := m.configBackup(source, current)
+		path, err := config.SaveConfigBackup(replaced)
+		if err != nil {
+			return configChangedMsg{err: fmt.Errorf("failed to back up %s, it was not restored: %w", source, err)}
+		}
+		replaced.Path = path
+		if err := m.k8sClient.UpdateConfigData(ctx, namespace, kind, name, backup.Data); err != nil {
+			return configChangedMsg{err: err}
+		}
+		return configChangedMsg{backup: &replaced, result: fmt.Sprintf("Restored %s to the backup of %s: %s.\n\nThe replaced data is backed up in %s.\n\n%s",
+			source, backup.Time.Format("2006-01-02 15:04:05"), changes, path, configChangeHint)}
+	}
+}
+
+// configChangeHint tells how the pods get changed config
+const configChangeHint = "Mounted files follow within a minute or two. Env vars and subPath files only\nchange when the pods restart: run config-rollout."
+
+// configBackup is a backup of data about to be replaced in the source
+func (m Model) configBackup(source string, data map[string]string) config.ConfigBackup {
+	kind, name, _ := strings.Cut(source, "/")
+	return config.ConfigBackup{
+		Time:      time.Now(),
+		Cluster:   archiveCluster(m.k8sClient.GetKubeConfigPath()),
+		Namespace: m.namespace,
+		Kind:      kind,
+		Name:      name,
+		User:      currentUser(),
+		Data:      data,
+	}
+}
+
+func (m Model) handleConfigChanged(msg configChangedMsg) (tea.Model, tea.Cmd) {
+	m.state = StateShowResult
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+	m.err = nil
+	m.configRestore = msg.backup
+	m.result = msg.result
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// canRestoreConfig reports whether the result offers to put back the data
+// an edit or restore replaced
+func (m Model) canRestoreConfig() bool {
+	return m.configRestore != nil && m.command != nil &&
+		(m.command.Name == "edit-config" || m.command.Name == "restore-config") &&
+		m.configRestore.Namespace == m.namespace
+}
+
+// restorePrevious asks to put back the data the last edit or restore
+// replaced
+func (m Model) restorePrevious() (tea.Model, tea.Cmd) {
+	m.pendingConfigRestore = m.configRestore
+	return m, nil
+}
+
+// handleConfigRestoreKey puts the data back on y; any other key keeps the
+// result, where U asks again
+func (m Model) handleConfigRestoreKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	backup := *m.pendingConfigRestore
+	m.pendingConfigRestore = nil
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Confirm):
+		m.configRestore = nil
+		m.startExecution()
+		return m, m.whileExecuting(m.restoreConfig(backup.Kind+"/"+backup.Name, backup))
+	}
+	return m, nil
+}
+
+// configRestoreHelp asks to confirm putting the replaced data back
+func (m Model) configRestoreHelp() string {
+	backup := m.pendingConfigRestore
+	return fmt.Sprintf("y: put back the data of %s/%s from %s • any other key: cancel",
+		backup.Kind, backup.Name, backup.Time.Format("2006-01-02 15:04:05"))
+}
+
+// writeConfigFile writes the data as YAML to a file only the user can read,
+// in a new temporary directory
+func writeConfigFile(source, namespace string, data map[string]string) (string, error) {
+	dir, err := os.MkdirTemp("", "khelper-edit-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	body := []byte("{}\n")
+	if len(data) > 0 {
+		if body, err = yaml.Marshal(data); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	header := fmt.Sprintf("# %s in %s: one key per entry, use | for multi-line values.\n"+
+		"# Save and quit to apply; the previous data is backed up first.\n", source, namespace)
+	kind, name, _ := strings.Cut(source, "/")
+	file := filepath.Join(dir, strings.ToLower(kind)+"-"+name+".yaml")
+	if err := os.WriteFile(file, append([]byte(header), body...), 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return file, nil
+}
+
+// readConfigFile reads the edited data back
+func readConfigFile(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	return data, nil
+}
+
+// editorCommand opens file in $VISUAL or $EDITOR, else vi (notepad on
+// Windows). The variables may hold arguments, e.g. "code --wait".
+func editorCommand(file string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			args = []string{"notepad"}
+		}
+	}
+	return exec.Command(args[0], append(args[1:], file)...)
+}
+
+// dataChanges describes how data changed by key, e.g. "changed a; added b",
+// empty if it did not
+func dataChanges(before, after map[string]string) string {
+	var changed, added, removed []string
+	for key, value := range after {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case old != value:
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	var parts []string
+	for _, group := range []struct {
+		verb string
+		keys []string
+	}{{"changed", changed}, {"added", added}, {"removed", removed}} {
+		if len(group.keys) > 0 {
+			sort.Strings(group.keys)
+			parts = append(parts, group.verb+" "+strings.Join(group.keys, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// configSelectorView renders the ConfigMap and Secret list, or the backups
+// of the one selected
+func (m Model) configSelectorView() string {
+	if m.configSource != "" {
+		return InfoStyle.Render(fmt.Sprintf("Backups of %s, newest first:", m.configSource)) + "\n\n" + m.configSelector.View()
+	}
+	return m.configSelector.View()
+}
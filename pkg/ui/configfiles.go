@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxDiffLines bounds the files diffed line by line, larger ones are only
+// reported as different
+const maxDiffLines = 2000
+
+// showMountedFiles reads the ConfigMap and Secret files mounted in the
+// selected container
+func (m Model) showMountedFiles() tea.Cmd {
+	ctx := m.exec.context()
+	namespace, pod, container := m.namespace, m.pod, m.container
+	return func() tea.Msg {
+		files, err := m.k8sClient.GetMountedFiles(ctx, namespace, pod, container)
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: formatMountedFiles(pod, container, files)}
+	}
+}
+
+// formatMountedFiles renders the mounted files with their source and state,
+// then each file's content, or for stale files how it differs from the
+// current data. Secret contents are not shown.
+func formatMountedFiles(pod, container string, files []k8s.MountedFile) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ConfigMap and Secret files in %s/%s:\n\n", pod, container)
+	if len(files) == 0 {
+		b.WriteString("No ConfigMap or Secret volumes are mounted in the container.")
+		return b.String()
+	}
+
+	rows := [][]string{{"PATH", "SOURCE", "KEY", "STATE"}}
+	stale, subPath := 0, false
+	for _, f := range files {
+		key := f.Key
+		if key == "" {
+			key = "-"
+		}
+		rows = append(rows, []string{f.Path, strings.ToLower(f.Kind) + "/" + f.Source, key, mountedFileState(f)})
+		if f.Stale() {
+			stale++
+			subPath = subPath || f.SubPath
+		}
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	for _, row := range rows {
+		fmt.Fprintf(&b, "  %-*s  %-*s  %-*s  %s\n", widths[0], row[0], widths[1], row[1], widths[2], row[2], row[3])
+	}
+	b.WriteString("\n")
+	switch {
+	case stale == 0:
+		b.WriteString("All readable files match their ConfigMap or Secret.\n")
+	case subPath:
+		fmt.Fprintf(&b, "%d file(s) differ from their source. Files mounted with subPath are only updated\nwhen the pod restarts; the others follow within a minute or two.\n", stale)
+	default:
+		fmt.Fprintf(&b, "%d file(s) differ from their source. The kubelet updates them within a minute or two.\n", stale)
+	}
+
+	for _, f := range files {
+		if f.Key == "" || f.ReadErr != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\n── %s (%s/%s, key %s) ──\n", f.Path, strings.ToLower(f.Kind), f.Source, f.Key)
+		switch {
+		case f.Secret():
+			fmt.Fprintf(&b, "(secret, %d bytes, not shown)\n", len(f.Mounted))
+		case !utf8.Valid(f.Mounted):
+			fmt.Fprintf(&b, "(binary, %d bytes)\n", len(f.Mounted))
+		case f.Stale():
+			b.WriteString("Mounted (-) against the current ConfigMap (+):\n")
+			b.WriteString(lineDiff(string(f.Mounted), string(f.Data)))
+		default:
+			b.WriteString(strings.TrimRight(string(f.Mounted), "\n"))
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// mountedFileState says whether a mounted file is current and why not
+func mountedFileState(f k8s.MountedFile) string {
+	switch {
+	case f.Missing:
+		return "source missing"
+	case f.SourceErr != nil:
+		return "source unreadable: " + f.SourceErr.Error()
+	case f.Data == nil:
+		return "key missing"
+	case f.ReadErr != nil:
+		return "unreadable: " + f.ReadErr.Error()
+	case f.Stale() && f.SubPath:
+		return "stale, needs a restart (subPath)"
+	case f.Stale():
+		return "stale, awaiting kubelet sync"
+	}
+	return "current"
+}
+
+// lineDiff lists the lines removed from and added to a text, in order
+func lineDiff(a, b string) string {
+	return contextDiff(a, b, 0)
+}
+
+// contextDiff is lineDiff with up to n unchanged lines around each change,
+// "  ..." standing for the unchanged lines left out between changes
+func contextDiff(a, b string, n int) string {
+	x := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	y := strings.Split(strings.TrimRight(b, "\n"), "\n")
+	if len(x) > maxDiffLines || len(y) > maxDiffLines {
+		return "(too long to compare line by line)\n"
+	}
+
+	// lcs[i][j] is the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Every line of both texts, marked "  " if unchanged
+	var lines []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, "  "+x[i])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+x[i])
+			i++
+		default:
+			lines = append(lines, "+ "+y[j])
+			j++
+		}
+	}
+
+	// Keep the changes and the unchanged lines within n of one
+	keep := make([]bool, len(lines))
+	for k, line := range lines {
+		if !strings.HasPrefix(line, "  ") {
+			for c := max(0, k-n); c <= min(len(lines)-1, k+n); c++ {
+				keep[c] = true
+			}
+		}
+	}
+	var out strings.Builder
+	skipped := false
+	for k, line := range lines {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped && n > 0 && out.Len() > 0 {
+			out.WriteString("  ...\n")
+		}
+		skipped = false
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+	return out.String()
+}
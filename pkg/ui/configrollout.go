@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configRolledMsg reports the config rollout started, or that the config
+// did not change
+type configRolledMsg struct {
+	rollout *k8s.ConfigRollout
+	err     error
+}
+
+// podConfigsMsg reports which pods run with the config of the wait started
+// at started
+type podConfigsMsg struct {
+	started time.Time
+	pods    []k8s.PodConfig
+	err     error
+}
+
+// rollConfig rolls the deployment if its ConfigMaps or Secrets changed
+func (m Model) rollConfig() tea.Cmd {
+	namespace, deployment := m.namespace, m.deployment
+	ctx := k8s.WithChangeCause(m.exec.context(), renderChangeCause(changeCauseData{
+		Command:    "config-rollout",
+		Change:     "for changed ConfigMaps/Secrets",
+		User:       currentUser(),
+		Namespace:  namespace,
+		Deployment: deployment,
+	}))
+	return func() tea.Msg {
+		rollout, err := m.k8sClient.RollConfig(ctx, namespace, deployment)
+		return configRolledMsg{rollout: rollout, err: err}
+	}
+}
+
+// handleConfigRolled waits for the rollout and then for the pods to see the
+// new config. An unchanged config goes straight to checking the pods.
+func (m Model) handleConfigRolled(msg configRolledMsg) (tea.Model, tea.Cmd) {
+	m.state = StateShowResult
+	unchanged := errors.Is(msg.err, k8s.ErrConfigUnchanged)
+	if msg.err != nil && !unchanged {
+		m.err = msg.err
+		return m, nil
+	}
+	m.err = nil
+	now := time.Now()
+	m.rolloutWait = &rolloutWait{started: now, deadline: now.Add(DefaultWaitTimeout), config: msg.rollout, configUnchanged: unchanged}
+	if unchanged {
+		return m, m.checkPodConfigs(now, 0)
+	}
+	return m, m.pollRollout(now, 0)
+}
+
+// checkPodConfigs checks the pods' config after delay
+func (m *Model) checkPodConfigs(started time.Time, delay time.Duration) tea.Cmd {
+	namespace, deployment, checksum := m.namespace, m.deployment, m.rolloutWait.config.Checksum
+	check := func() tea.Msg {
+		pods, err := m.k8sClient.GetPodConfigs(context.Background(), namespace, deployment, checksum)
+		return podConfigsMsg{started: started, pods: pods, err: err}
+	}
+	if delay == 0 {
+		return check
+	}
+	return tea.Tick(delay, func(time.Time) tea.Msg { return check() })
+}
+
+// handlePodConfigs finishes the config rollout once every pod runs with the
+// new config, or reports the pods still serving old data at the deadline
+func (m Model) handlePodConfigs(msg podConfigsMsg) (tea.Model, tea.Cmd) {
+	w := m.rolloutWait
+	if w == nil || !w.started.Equal(msg.started) {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.rolloutWait = nil
+		m.err = msg.err
+		return m, nil
+	}
+	w.pods = msg.pods
+	old := oldConfigPods(msg.pods)
+	elapsed := time.Since(w.started).Round(time.Second)
+	var head string
+	switch {
+	case old == 0 && w.configUnchanged:
+		head = fmt.Sprintf("The ConfigMaps and Secrets did not change since the last config rollout,\nall pods of %s serve them (checksum %s).", m.deployment, w.config.Checksum)
+	case old == 0:
+		head = fmt.Sprintf("%s rolled out with the new config after %s, all pods serve it (checksum %s).", m.deployment, elapsed, w.config.Checksum)
+	case time.Now().After(w.deadline):
+		head = fmt.Sprintf("Timed out after %s: %d pod(s) of %s still serve old config.", elapsed, old, m.deployment)
+	default:
+		return m, m.checkPodConfigs(w.started, rolloutPollInterval)
+	}
+	m.rolloutWait = nil
+	m.result = fmt.Sprintf("%s\n\nSources: %s\n\n%s", head, strings.Join(w.config.Sources, ", "), formatPodConfigs(msg.pods))
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// oldConfigPods counts the pods not yet serving the new config
+func oldConfigPods(pods []k8s.PodConfig) int {
+	n := 0
+	for _, pod := range pods {
+		if !pod.Current || len(pod.StaleFiles) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// formatPodConfigs lists the pods with whether they serve the new config
+func formatPodConfigs(pods []k8s.PodConfig) string {
+	if len(pods) == 0 {
+		return "No running pods."
+	}
+	width := len("POD")
+	for _, pod := range pods {
+		width = max(width, len(pod.Pod))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %-*s  %s\n", width, "POD", "CONFIG")
+	for _, pod := range pods {
+		state := "✓ current"
+		switch {
+		case !pod.Current:
+			state = "✗ old revision"
+		case len(pod.StaleFiles) > 0:
+			state = "✗ stale files: " + strings.Join(pod.StaleFiles, ", ")
+		}
+		if pod.Unreadable > 0 {
+			state += fmt.Sprintf(" (%d file(s) unreadable)", pod.Unreadable)
+		}
+		fmt.Fprintf(&b, "  %-*s  %s\n", width, pod.Pod, state)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
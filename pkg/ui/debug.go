@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// debugImagePrompt asks debug-sidecar and debug-copy for the sidecar image
+const debugImagePrompt = "Enter sidecar image (default: debug_image from the config or nicolaka/netshoot):"
+
+// debugSidecar is the debug sidecar one key removes: added to the deployment,
+// or running in a pod copy if pod is set
+type debugSidecar struct {
+	namespace  string
+	deployment string
+	pod        string
+}
+
+// debugSidecarMsg reports an added debug sidecar
+type debugSidecarMsg struct {
+	target *debugSidecar
+	result string
+	err    error
+}
+
+// debugImage is the sidecar image entered, else the configured one
+func (m Model) debugImage() string {
+	if image := strings.TrimSpace(m.inputValue); image != "" {
+		return image
+	}
+	if m.config.DebugImage != "" {
+		return m.config.DebugImage
+	}
+	return k8s.DefaultDebugImage
+}
+
+// debugCause is the change-cause of adding or removing the debug sidecar
+func (m Model) debugCause(change string) string {
+	return renderChangeCause(changeCauseData{
+		Command:    "debug-sidecar",
+		Change:     change,
+		User:       currentUser(),
+		Namespace:  m.namespace,
+		Deployment: m.deployment,
+		Container:  k8s.DebugContainerName,
+	})
+}
+
+// addDebugSidecar adds the debug sidecar to the deployment's pod template
+func (m Model) addDebugSidecar() tea.Cmd {
+	namespace, deployment, image := m.namespace, m.deployment, m.debugImage()
+	ctx := k8s.WithChangeCause(m.exec.context(), m.debugCause("add "+image))
+	target := &debugSidecar{namespace: namespace, deployment: deployment}
+	return func() tea.Msg {
+		err := m.k8sClient.AddDebugSidecar(ctx, namespace, deployment, image)
+		if errors.Is(err, k8s.ErrDebugSidecarExists) {
+			return debugSidecarMsg{target: target, result: fmt.Sprintf("%s already has the debug sidecar.\n\nOpen a shell in the %s container of one of its pods to use it.",
+				deployment, k8s.DebugContainerName)}
+		}
+		if err != nil {
+			return debugSidecarMsg{err: err}
+		}
+		return debugSidecarMsg{target: target, result: fmt.Sprintf("Added the debug sidecar %s to %s, its pods are rolling.\n\n"+
+			"Open a shell in the %s container of a new pod to use tcpdump,\ncurl or dig in the pod's network namespace. Press x to remove it when done.",
+			image, deployment, k8s.DebugContainerName)}
+	}
+}
+
+// createDebugCopy starts a copy of the selected pod with the debug sidecar
+func (m Model) createDebugCopy() tea.Cmd {
+	namespace, deployment, pod, image := m.namespace, m.deployment, m.pod, m.debugImage()
+	ctx := m.exec.context()
+	return func() tea.Msg {
+		name, err := m.k8sClient.CreateDebugCopy(ctx, namespace, pod, image)
+		if err != nil {
+			if name != "" {
+				err = fmt.Errorf("%w (delete the copy %s with the resources command)", err, name)
+			}
+			return debugSidecarMsg{err: err}
+		}
+		return debugSidecarMsg{target: &debugSidecar{namespace: namespace, deployment: deployment, pod: name},
+			result: fmt.Sprintf("Started %s, a copy of %s with the debug sidecar %s.\n\n"+
+				"It has none of the pod's labels and gets no service traffic. Press s for a\nshell in its %s container, x to delete the copy when done.",
+				name, pod, image, k8s.DebugContainerName)}
+	}
+}
+
+// canRemoveDebug reports whether the result offers to remove a debug sidecar
+func (m Model) canRemoveDebug() bool {
+	return m.debugSidecar != nil && m.command != nil &&
+		(m.command.Name == "debug-sidecar" || m.command.Name == "debug-copy") &&
+		m.debugSidecar.namespace == m.namespace && m.debugSidecar.deployment == m.deployment
+}
+
+// removeDebug removes the debug sidecar from the deployment or deletes the
+// pod copy
+func (m Model) removeDebug(target debugSidecar) tea.Cmd {
+	ctx := m.exec.context()
+	if target.pod != "" {
+		return func() tea.Msg {
+			if err := m.k8sClient.DeleteDebugCopy(ctx, target.namespace, target.pod); err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: fmt.Sprintf("Deleted the debug copy %s", target.pod)}
+		}
+	}
+	ctx = k8s.WithChangeCause(ctx, m.debugCause("remove"))
+	return func() tea.Msg {
+		if err := m.k8sClient.RemoveDebugSidecar(ctx, target.namespace, target.deployment); err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: fmt.Sprintf("Removed the debug sidecar from %s, its pods are rolling", target.deployment)}
+	}
+}
+
+// debugKey handles the keys a debug sidecar result offers: x removes it, s
+// opens a shell in a pod copy's sidecar
+func (m Model) debugKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, keys.RemoveDebug):
+		target := *m.debugSidecar
+		m.debugSidecar = nil
+		m.startExecution()
+		return m, m.whileExecuting(m.removeDebug(target)), true
+	case key.Matches(msg, keys.DebugShell):
+		if m.debugSidecar.pod == "" {
+			return m, nil, false
+		}
+		return m, m.openShell(m.debugSidecar.pod, k8s.DebugContainerName), true
+	}
+	return m, nil, false
+}
+
+// debugHelp is the help of a debug sidecar result
+func (m Model) debugHelp() string {
+	if m.debugSidecar.pod != "" {
+		return "s: shell in the debug container • x: delete the copy • "
+	}
+	return "x: remove the debug sidecar • "
+}
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// drainImpactPrompt asks drain-impact for the node
+const drainImpactPrompt = "Enter the node to drain (default: each node running the pods):"
+
+// checkDrainImpact previews draining the node entered, or each node running
+// the workload's pods
+func (m Model) checkDrainImpact(node string) tea.Cmd {
+	ctx := m.exec.context()
+	namespace, deployment := m.namespace, m.deployment
+	return func() tea.Msg {
+		result, _, err := DrainImpact(ctx, m.k8sClient, namespace, deployment, node)
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: result}
+	}
+}
+
+// DrainClient is what previewing a drain needs of a client
+type DrainClient interface {
+	k8s.Pods
+	k8s.Describer
+}
+
+// DrainImpact previews draining a node for a workload, or each node running
+// its pods when node is empty, and reports whether it survives all of them
+func DrainImpact(ctx context.Context, client DrainClient, namespace, deployment, node string) (string, bool, error) {
+	nodes := []string{node}
+	if node == "" {
+		pods, err := client.ListPods(ctx, namespace, deployment)
+		if err != nil {
+			return "", false, err
+		}
+		seen := map[string]bool{}
+		nodes = nil
+		for _, pod := range pods {
+			if name := pod.Spec.NodeName; name != "" && !seen[name] {
+				seen[name] = true
+				nodes = append(nodes, name)
+			}
+		}
+		if len(nodes) == 0 {
+			return fmt.Sprintf("No pod of %s runs on a node, a drain does not touch it.", deployment), true, nil
+		}
+		sort.Strings(nodes)
+	}
+
+	var sections []string
+	survives := true
+	for _, name := range nodes {
+		impact, err := client.CheckDrainImpact(ctx, namespace, deployment, name)
+		if err != nil {
+			return "", false, err
+		}
+		survives = survives && len(impact.Problems()) == 0
+		sections = append(sections, formatDrainImpact(impact))
+	}
+	return strings.Join(sections, "\n\n"), survives, nil
+}
+
+// formatDrainImpact renders a drain preview: the verdict, the pods evicted,
+// the PDBs pacing them and where their replacements start
+func formatDrainImpact(impact *k8s.DrainImpact) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Draining %s\n", impact.Node)
+	if len(impact.Evicted) == 0 {
+		fmt.Fprintf(&b, "\n  ✓ No pod of %s runs there, the drain does not touch it", impact.Workload)
+		return b.String()
+	}
+	problems := impact.Problems()
+	if len(problems) == 0 {
+		fmt.Fprintf(&b, "\n  ✓ %s keeps serving: %d ready pods elsewhere", impact.Workload, impact.Remaining)
+		if impact.CapacityErr == nil {
+			b.WriteString(" and room for the replacements")
+		}
+		b.WriteString("\n")
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(&b, "\n  ✗ %s\n", problem)
+	}
+
+	fmt.Fprintf(&b, "\nEvicted: %d of %d replicas\n", len(impact.Evicted), impact.Replicas)
+	for i, pod := range impact.Evicted {
+		state := "ready"
+		if !pod.Ready {
+			state = "not ready"
+		}
+		line := fmt.Sprintf("  %s (%s)", pod.Name, state)
+		if i < len(impact.Placements) {
+			if p := impact.Placements[i]; p.Node != "" {
+				line += " → " + p.Node
+			} else {
+				line += " → nowhere: " + p.Reason
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	if impact.CapacityErr != nil {
+		fmt.Fprintf(&b, "  ⚠ Where the replacements fit was not checked: %v\n", impact.CapacityErr)
+	} else if impact.Others > 0 {
+		fmt.Fprintf(&b, "  Placed after the %d other pods the drain evicts from %s\n", impact.Others, impact.Node)
+	}
+
+	b.WriteString("\nPDBs\n")
+	if len(impact.PDBs) == 0 {
+		b.WriteString("  None, the drain evicts all pods at once\n")
+	}
+	for _, pdb := range impact.PDBs {
+		fmt.Fprintf(&b, "  %s  %s  evictions allowed now: %d\n", pdb.Name, pdb.Budget, pdb.Allowed)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errorDetailsMaxLines is how much of a raw error the details show
+const errorDetailsMaxLines = 30
+
+// renderErrorScreen shows a known failure as a short summary with a hint,
+// and the raw error when details is set or the failure is not known
+func renderErrorScreen(err error, details bool, width int) string {
+	explained, ok := k8s.ExplainError(err)
+	if !ok {
+		return RenderError(err.Error())
+	}
+
+	var b strings.Builder
+	b.WriteString(RenderError(explained.Summary))
+	b.WriteString("\n\n")
+	wrap := lipgloss.NewStyle()
+	if width > 8 {
+		wrap = wrap.Width(width - 6)
+	}
+	b.WriteString(InfoStyle.Render(wrap.Render("→ " + explained.Hint)))
+	if details {
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) > errorDetailsMaxLines {
+			lines = append(lines[:errorDetailsMaxLines], "…")
+		}
+		b.WriteString("\n\n")
+		b.WriteString(LabelStyle.Render("Details:"))
+		b.WriteString("\n")
+		b.WriteString(DimStyle.Render(wrap.Render(strings.Join(lines, "\n"))))
+	}
+	return b.String()
+}
+
+// hasErrorDetails reports whether the error screen hides a raw error
+func hasErrorDetails(err error) bool {
+	_, ok := k8s.ExplainError(err)
+	return ok
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+
+	"khelper/pkg/k8s"
+)
+
+// eventStreamMsg carries an event and the state of the events stream
+type eventStreamMsg struct {
+	gen    int
+	event  *corev1.Event
+	events <-chan *corev1.Event
+	errs   <-chan error
+}
+
+// streamEvents tails the namespace's events into the log viewer, starting
+// with those already recorded (or those seen since a time when resuming)
+func (m *Model) streamEvents(ctx context.Context, since time.Time) tea.Cmd {
+	gen := m.streamGen
+	namespace := m.namespace
+
+	return func() tea.Msg {
+		events := make(chan *corev1.Event, 100)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- m.k8sClient.WatchEvents(ctx, namespace, since, func(e *corev1.Event) {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+				}
+			})
+			close(events)
+		}()
+
+		return readEvent(gen, events, errs)
+	}
+}
+
+// readNextEvent returns a command that reads the next event
+func readNextEvent(gen int, events <-chan *corev1.Event, errs <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		return readEvent(gen, events, errs)
+	}
+}
+
+func readEvent(gen int, events <-chan *corev1.Event, errs <-chan error) tea.Msg {
+	e, ok := <-events
+	if !ok {
+		return LogStreamEndMsg{gen: gen, err: <-errs}
+	}
+	return eventStreamMsg{gen: gen, event: e, events: events, errs: errs}
+}
+
+// formatEvent splits an event into the object it is about, used as the line
+// prefix so events group by kind, and a timestamped text
+func formatEvent(e *corev1.Event) (string, string) {
+	prefix := fmt.Sprintf("%s/%s ", e.InvolvedObject.Kind, e.InvolvedObject.Name)
+	text := fmt.Sprintf("%s %-7s %s: %s", k8s.EventTime(e).Local().Format(time.RFC3339), e.Type, e.Reason, e.Message)
+	if e.Count > 1 {
+		text += fmt.Sprintf(" (x%d)", e.Count)
+	}
+	return prefix, text
+}
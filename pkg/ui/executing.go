@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// execution is the command currently executing. It is shared by the model's
+// copies so that commands built before it started, such as a change waiting
+// for lint confirmation, use its context.
+type execution struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	gen     int // identifies the execution, results of older ones are dropped
+	started time.Time
+	output  strings.Builder // progress shown if it is cancelled
+	waiting bool            // prepared offline, waiting for the cluster
+	op      int             // the execution's operation, 0 until it runs
+}
+
+func newExecution() *execution {
+	return &execution{ctx: context.Background(), cancel: func() {}}
+}
+
+// begin starts a new execution with a fresh context
+func (e *execution) begin() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	e.gen++
+	e.started = time.Now()
+	e.output.Reset()
+	e.waiting = false
+	e.op = 0
+	return e.gen
+}
+
+// context returns the context of the current execution
+func (e *execution) context() context.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ctx
+}
+
+// stop cancels the current execution, returning how long it ran and the
+// output it produced
+func (e *execution) stop() (time.Duration, string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cancel()
+	e.gen++
+	return time.Since(e.started).Round(time.Second), e.output.String()
+}
+
+// opID returns the execution's operation
+func (e *execution) opID() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.op
+}
+
+func (e *execution) current(gen int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.gen == gen
+}
+
+func (e *execution) elapsed() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return time.Since(e.started).Round(time.Second)
+}
+
+// setWaiting records whether the execution waits for the cluster
+func (e *execution) setWaiting(waiting bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.waiting = waiting
+}
+
+func (e *execution) isWaiting() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.waiting
+}
+
+// write records progress output of the execution
+func (e *execution) write(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.output.WriteString(s)
+}
+
+// written returns the progress output so far
+func (e *execution) written() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.output.String()
+}
+
+// execResultMsg carries the result of the execution gen
+type execResultMsg struct {
+	gen int
+	op  int
+	msg tea.Msg
+}
+
+// startExecution shows the spinner until the result of the execution built
+// next arrives; Esc cancels it
+func (m *Model) startExecution() {
+	m.state = StateExecuting
+	m.exec.begin()
+}
+
+// whileExecuting runs cmd as the current execution, tagging its result so
+// the result of a cancelled execution is dropped. The execution is tracked
+// as one operation, also when its result runs a further step.
+func (m *Model) whileExecuting(cmd tea.Cmd) tea.Cmd {
+	name := "command"
+	if m.command != nil {
+		name = m.command.Name
+	}
+	e := m.exec
+	e.mu.Lock()
+	gen := e.gen
+	if e.op == 0 {
+		e.op = m.ops.start(name, true)
+		m.ops.run(e.op)
+	}
+	op := e.op
+	e.mu.Unlock()
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		return execResultMsg{gen: gen, op: op, msg: cmd()}
+	})
+}
+
+// handleExecResult hands the result of the current execution on
+func (m Model) handleExecResult(msg execResultMsg) (tea.Model, tea.Cmd) {
+	if !m.exec.current(msg.gen) || m.state != StateExecuting {
+		// Cancelled, a shell started meanwhile is closed again
+		result := msg.msg
+		if online, ok := result.(onlineMsg); ok {
+			result = online.msg
+		}
+		if opened, ok := result.(shellOpenedMsg); ok && opened.session != nil {
+			opened.session.cancel()
+		}
+		return m, nil
+	}
+	model, cmd := m.Update(msg.msg)
+	return finishCommandOp(model, cmd, msg.op)
+}
+
+// cancelExecution stops the executing command and shows what it produced
+func (m Model) cancelExecution() (tea.Model, tea.Cmd) {
+	m.ops.finish(m.exec.opID(), errCancelled)
+	m.ops.dropKeys()
+	took, output := m.exec.stop()
+	m.state = StateShowResult
+	name := "command"
+	if m.command != nil {
+		name = m.command.Name
+	}
+	note := ""
+	if m.command != nil && m.command.Mutating {
+		note = " Changes sent before cancelling may still have been applied."
+	}
+	if output == "" {
+		m.err = fmt.Errorf("%s cancelled after %s.%s", name, took, note)
+		return m, nil
+	}
+	m.result = fmt.Sprintf("%s cancelled after %s.%s Output so far:\n\n%s", name, took, note, output)
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// updateSpinner animates the spinner while a command executes
+func (m Model) updateSpinner(msg spinner.TickMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateExecuting {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return m, cmd
+}
+
+// executingView renders the spinner with the elapsed time
+func (m Model) executingView() string {
+	name := "command"
+	if m.command != nil {
+		name = m.command.Name
+	}
+	var b strings.Builder
+	b.WriteString(m.spinner.View())
+	if m.exec.isWaiting() {
+		b.WriteString(InfoStyle.Render(fmt.Sprintf(" Waiting for the cluster to run %s... %s", name, m.exec.elapsed())))
+		b.WriteString("\n\n")
+		b.WriteString(InfoStyle.Render(fmt.Sprintf("The cluster is unreachable, %s runs as soon as it answers again.", name)))
+	} else {
+		b.WriteString(InfoStyle.Render(fmt.Sprintf(" Executing %s... %s", name, m.exec.elapsed())))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(InfoStyle.Render("Esc: cancel"))
+	return b.String()
+}
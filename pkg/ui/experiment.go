@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// experimentPrompt asks the experiment command for the changes to try
+const experimentPrompt = "Enter changes: [image=IMAGE] [cpu=LIMIT] [memory=LIMIT] [KEY=VALUE ...] (other keys set env vars):"
+
+// parseExperimentInput reads the changes of an experiment:
+// "image=web:fix cpu=500m memory=1Gi LOG_LEVEL=debug". image, cpu and memory
+// are the only lowercase keys, every other key is an env var.
+func parseExperimentInput(input, container string) (k8s.Experiment, error) {
+	exp := k8s.Experiment{Container: container}
+	for _, field := range strings.Fields(input) {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			return exp, fmt.Errorf("invalid change %q, expected KEY=VALUE", field)
+		}
+		switch name {
+		case "image":
+			exp.Image = value
+		case "cpu":
+			exp.CPU = value
+		case "memory":
+			exp.Memory = value
+		default:
+			exp.Env = append(exp.Env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+	return exp, nil
+}
+
+// startExperiment creates the experiment pod from the deployment's template
+func (m Model) startExperiment() tea.Cmd {
+	exp, err := parseExperimentInput(m.inputValue, m.container)
+	if err != nil {
+		return func() tea.Msg {
+			return CommandResultMsg{err: err}
+		}
+	}
+	namespace, deployment := m.namespace, m.deployment
+	ctx := m.exec.context()
+	return func() tea.Msg {
+		name, err := m.k8sClient.CreateExperimentPod(ctx, namespace, deployment, exp)
+		if err != nil {
+			if name != "" {
+				err = fmt.Errorf("%w (delete the pod %s with the resources command)", err, name)
+			}
+			return oneOffStartedMsg{err: err}
+		}
+		return oneOffStartedMsg{target: &oneOffPod{namespace: namespace, deployment: deployment, pod: name, container: exp.Container}}
+	}
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"khelper/pkg/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fastDeployPresetPrefix marks the presets on top of the asset folders
+const fastDeployPresetPrefix = "⚡ "
+
+// fastDeployPresetItem is the asset folder list entry of a preset
+func fastDeployPresetItem(preset config.FastDeployPreset) string {
+	item := fmt.Sprintf("%s%s: %s → %s", fastDeployPresetPrefix, preset.Name, preset.LocalPath, preset.AssetFolder)
+	if len(preset.Excludes) > 0 {
+		item += " (without " + strings.Join(preset.Excludes, ", ") + ")"
+	}
+	return item
+}
+
+// fastDeployPresets returns the deployment's presets for the selected
+// container, the last used first
+func (m Model) fastDeployPresets() []config.FastDeployPreset {
+	var presets []config.FastDeployPreset
+	for _, preset := range m.config.GetFastDeployPresets(m.namespace, m.deployment) {
+		if preset.Container == "" || preset.Container == m.container {
+			presets = append(presets, preset)
+		}
+	}
+	return presets
+}
+
+// selectedFastDeployPreset returns the preset an asset folder list entry
+// stands for
+func (m Model) selectedFastDeployPreset(selected string) (config.FastDeployPreset, bool) {
+	if !strings.HasPrefix(selected, fastDeployPresetPrefix) {
+		return config.FastDeployPreset{}, false
+	}
+	for _, preset := range m.fastDeployPresets() {
+		if fastDeployPresetItem(preset) == selected {
+			return preset, true
+		}
+	}
+	return config.FastDeployPreset{}, false
+}
+
+// lastFastDeploy returns the deployment's last fast-deploy, if fast-deploy
+// is available at all
+func (m Model) lastFastDeploy() (config.FastDeployPreset, bool) {
+	presets := m.config.GetFastDeployPresets(m.namespace, m.deployment)
+	if len(presets) == 0 || m.inCluster || m.config.IsReadOnly() {
+		return config.FastDeployPreset{}, false
+	}
+	return presets[0], true
+}
+
+// runFastDeployPreset deploys a preset to the selected pod, remembering it
+// as the last one
+func (m Model) runFastDeployPreset(preset config.FastDeployPreset) (tea.Model, tea.Cmd) {
+	m.assetFolder = preset.AssetFolder
+	m.inputValue = preset.LocalPath
+	m.fastDeployExcludes = preset.Excludes
+	m.config.AddRecentAssetFolder(preset.AssetFolder)
+	m.config.AddRecentLocalPath(preset.LocalPath)
+	m.config.SaveFastDeployPreset(m.namespace, m.deployment, preset)
+	m.startExecution()
+	return m, m.whileExecuting(m.executeFastDeploy())
+}
+
+// repeatFastDeploy runs the deployment's last fast-deploy from the command
+// list, to the first pod running its container, without asking for
+// anything
+func (m Model) repeatFastDeploy() (tea.Model, tea.Cmd) {
+	preset, ok := m.lastFastDeploy()
+	if !ok {
+		return m, nil
+	}
+	for i := range AvailableCommands {
+		if AvailableCommands[i].Name == "fast-deploy" {
+			m.command = &AvailableCommands[i]
+		}
+	}
+	m.container = preset.Container
+	m.assetFolder = preset.AssetFolder
+	m.inputValue = preset.LocalPath
+	m.fastDeployExcludes = preset.Excludes
+	m.config.SaveFastDeployPreset(m.namespace, m.deployment, preset)
+	m.startExecution()
+	return m, m.whileExecuting(func() tea.Msg {
+		pods, err := m.k8sClient.ListPodEntriesWithContainer(context.Background(), m.namespace, m.deployment, m.container)
+		if err == nil && len(pods) == 0 {
+			err = fmt.Errorf("no pod of %s runs a container named %s", m.deployment, m.container)
+		}
+		if err != nil {
+			return FastDeployCompleteMsg{err: err}
+		}
+		m.pod = pods[0].Name
+		return m.executeFastDeploy()()
+	})
+}
+
+// repeatFastDeployHint tells what the repeat key deploys, empty if there is
+// nothing to repeat
+func (m Model) repeatFastDeployHint() string {
+	preset, ok := m.lastFastDeploy()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s: fast-deploy %s to %s again", keys.RepeatDeploy.Help().Key, preset.LocalPath, preset.AssetFolder)
+}
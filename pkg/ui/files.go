@@ -0,0 +1,304 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fileParent is the file list entry that goes up one directory
+const fileParent = "../"
+
+// filesLoadedMsg carries a directory of the files command, and the image
+// snapshot it was listed from if the container cannot exec
+type filesLoadedMsg struct {
+	dir      string
+	entries  []k8s.FileEntry
+	snapshot *k8s.ImageSnapshot
+	err      error
+}
+
+// fileReadMsg carries a file to show, or to download
+type fileReadMsg struct {
+	path     string
+	data     []byte
+	snapshot *k8s.ImageSnapshot
+	download bool
+	err      error
+}
+
+// browseFiles starts the files command at a directory of the container
+func (m Model) browseFiles(dir string) (tea.Model, tea.Cmd) {
+	m.state = StateBrowseFiles
+	m.imageSnapshot = nil
+	m.viewedFile, m.fileStatus = "", ""
+	m.fileSelector.Reset()
+	return m, m.loadFiles(dir)
+}
+
+// loadFiles lists a directory with ls in the container, or in the image
+// snapshot once the container turned out to have no ls
+func (m *Model) loadFiles(dir string) tea.Cmd {
+	m.fileSelector.SetError(nil)
+	m.fileSelector.SetLoading(true)
+	if m.imageSnapshot == nil {
+		m.fileSelector.title = "Files  " + dir
+	}
+	namespace, pod, container, snapshot := m.namespace, m.pod, m.container, m.imageSnapshot
+	client := m.k8sClient
+	return func() tea.Msg {
+		ctx := context.Background()
+		if snapshot == nil {
+			entries, err := client.ListFiles(ctx, namespace, pod, container, dir)
+			if !errors.Is(err, k8s.ErrNoExec) {
+				return filesLoadedMsg{dir: dir, entries: entries, err: err}
+			}
+			if snapshot, err = pullImageSnapshot(ctx, client, namespace, pod, container); err != nil {
+				return filesLoadedMsg{dir: dir, err: err}
+			}
+		}
+		entries, err := snapshot.List(dir)
+		return filesLoadedMsg{dir: dir, entries: entries, snapshot: snapshot, err: err}
+	}
+}
+
+// readFile reads a file with cat in the container, or from the image
+// snapshot once the container turned out to have no cat
+func (m Model) readFile(file string, download bool) tea.Cmd {
+	namespace, pod, container, snapshot := m.namespace, m.pod, m.container, m.imageSnapshot
+	client := m.k8sClient
+	return func() tea.Msg {
+		ctx := context.Background()
+		if snapshot == nil {
+			data, err := client.ReadContainerFile(ctx, namespace, pod, container, file)
+			if !errors.Is(err, k8s.ErrNoExec) {
+				return fileReadMsg{path: file, data: data, download: download, err: err}
+			}
+			if snapshot, err = pullImageSnapshot(ctx, client, namespace, pod, container); err != nil {
+				return fileReadMsg{path: file, download: download, err: err}
+			}
+		}
+		data, err := snapshot.ReadFile(file)
+		return fileReadMsg{path: file, data: data, snapshot: snapshot, download: download, err: err}
+	}
+}
+
+// pullImageSnapshot pulls the image of a container that cannot exec, with
+// the registry logins stored as credentials. The layer cache is pruned
+// first, so that the layers of this image are not.
+func pullImageSnapshot(ctx context.Context, client k8s.Files, namespace, pod, container string) (*k8s.ImageSnapshot, error) {
+	cacheDir, err := config.GetImageCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	// A cache that cannot be pruned still serves the pull
+	_ = config.PruneImageCache()
+	var auth k8s.RegistryAuth
+	if creds, err := config.OpenCredentials(); err == nil {
+		auth = creds.RegistryLogin
+	}
+	snapshot, err := client.SnapshotImage(ctx, namespace, pod, container, cacheDir, auth)
+	if err != nil {
+		return nil, fmt.Errorf("the container cannot run ls or cat (distroless?) and its image could not be pulled: %w", err)
+	}
+	return snapshot, nil
+}
+
+// handleFilesLoaded shows a listed directory
+func (m Model) handleFilesLoaded(msg filesLoadedMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateBrowseFiles {
+		return m, nil
+	}
+	if msg.snapshot != nil {
+		m.imageSnapshot = msg.snapshot
+	}
+	// Kept on errors too, so that Backspace leaves the directory
+	m.fileDir = msg.dir
+	m.fileSelector.title = "Files  " + msg.dir
+	if m.imageSnapshot != nil {
+		m.fileSelector.title = "Image files  " + msg.dir
+	}
+	if msg.err != nil {
+		m.fileSelector.SetError(msg.err)
+		return m, nil
+	}
+
+	var items []string
+	if msg.dir != "/" {
+		items = append(items, fileParent)
+	}
+	m.fileEntries = make(map[string]k8s.FileEntry, len(msg.entries))
+	for _, entry := range msg.entries {
+		label := fileLabel(entry)
+		m.fileEntries[label] = entry
+		items = append(items, label)
+	}
+	m.fileSelector.SetItems(items)
+	m.fileSelector.Reset()
+	return m, nil
+}
+
+// fileLabel renders a file list entry: directories with a slash, symlinks
+// with their target and files with their size if known
+func fileLabel(entry k8s.FileEntry) string {
+	label := entry.Name
+	if entry.Dir {
+		label += "/"
+	}
+	switch {
+	case entry.Link != "":
+		label += " → " + entry.Link
+	case !entry.Dir && entry.Size >= 0:
+		label += "  " + formatBytes(entry.Size)
+	}
+	return label
+}
+
+// formatBytes renders a size such as 512 B, 3.2 KB, 14.0 MB or 1.5 GB
+func formatBytes(size int64) string {
+	switch {
+	case size < 1<<10:
+		return fmt.Sprintf("%d B", size)
+	case size < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	case size < 1<<30:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f GB", float64(size)/(1<<30))
+}
+
+// openFile handles Enter in the file list: directories are opened, files
+// shown
+func (m Model) openFile() (tea.Model, tea.Cmd) {
+	selected := m.fileSelector.GetSelected()
+	if selected == fileParent {
+		return m.fileParentDir()
+	}
+	entry, ok := m.fileEntries[selected]
+	if !ok {
+		return m, nil
+	}
+	m.fileStatus = ""
+	file := path.Join(m.fileDir, entry.Name)
+	if entry.Dir {
+		m.fileSelector.Reset()
+		return m, m.loadFiles(file)
+	}
+	m.fileSelector.SetLoading(true)
+	return m, m.readFile(file, false)
+}
+
+// fileParentDir goes up a directory
+func (m Model) fileParentDir() (tea.Model, tea.Cmd) {
+	if m.fileDir == "/" {
+		return m, nil
+	}
+	m.fileStatus = ""
+	m.fileSelector.Reset()
+	return m, m.loadFiles(path.Dir(m.fileDir))
+}
+
+// downloadFile saves the selected file below the export dir
+func (m Model) downloadFile() (tea.Model, tea.Cmd) {
+	entry, ok := m.fileEntries[m.fileSelector.GetSelected()]
+	if !ok || entry.Dir {
+		return m, nil
+	}
+	m.fileStatus = "Downloading " + entry.Name + "…"
+	return m, m.readFile(path.Join(m.fileDir, entry.Name), true)
+}
+
+// handleFileRead shows a file as the result, or writes a download
+func (m Model) handleFileRead(msg fileReadMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateBrowseFiles {
+		return m, nil
+	}
+	m.fileSelector.SetLoading(false)
+	if msg.snapshot != nil {
+		m.imageSnapshot = msg.snapshot
+	}
+	if msg.err != nil {
+		m.fileStatus = "✗ " + msg.err.Error()
+		return m, nil
+	}
+	if msg.download {
+		return m, func() tea.Msg {
+			dir, err := config.GetExportDir()
+			if err != nil {
+				return exportedMsg{err: err}
+			}
+			base := path.Base(msg.path)
+			ext := filepath.Ext(base)
+			return writeExport(filepath.Join(dir, "files"), m.pod, strings.TrimSuffix(base, ext), ext, string(msg.data))
+		}
+	}
+
+	var b strings.Builder
+	if m.imageSnapshot != nil {
+		fmt.Fprintf(&b, "📦 %s from the image %s, not the live filesystem\n\n", msg.path, m.imageSnapshot.Image)
+	}
+	if utf8.Valid(msg.data) {
+		b.WriteString(strings.TrimRight(string(msg.data), "\n"))
+	} else {
+		fmt.Fprintf(&b, "(binary, %s; Ctrl+S in the file list downloads it)", formatBytes(int64(len(msg.data))))
+	}
+	m.viewedFile = msg.path
+	m.state = StateShowResult
+	m.err = nil
+	m.result = b.String()
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// handleFileExported reports where a download was saved
+func (m Model) handleFileExported(msg exportedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.fileStatus = "✗ Download failed: " + msg.err.Error()
+	} else {
+		m.fileStatus = "Saved to " + msg.path
+	}
+	return m, nil
+}
+
+// viewingFile reports whether the result is a file of the files command
+func (m Model) viewingFile() bool {
+	return m.viewedFile != "" && m.command != nil && m.command.Name == "files"
+}
+
+// closeFile goes back from a shown file to its directory
+func (m Model) closeFile() (tea.Model, tea.Cmd) {
+	m.viewedFile = ""
+	m.result = ""
+	m.state = StateBrowseFiles
+	return m, nil
+}
+
+// filesView renders the file list, labeled when it shows the image rather
+// than the container
+func (m Model) filesView() string {
+	var b strings.Builder
+	if m.imageSnapshot != nil {
+		b.WriteString(WarningStyle.Render(fmt.Sprintf("📦 Image content of %s, not the live filesystem: the container cannot run ls or cat", m.imageSnapshot.Image)))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(m.fileSelector.View())
+	if m.fileStatus != "" {
+		b.WriteString("\n")
+		if strings.HasPrefix(m.fileStatus, "✗") {
+			b.WriteString(ErrorStyle.Render(m.fileStatus))
+		} else {
+			b.WriteString(DimStyle.Render(m.fileStatus))
+		}
+	}
+	return b.String()
+}
@@ -1,15 +1,59 @@
 package ui
 
 import (
+	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/sahilm/fuzzy"
 )
 
+const (
+	// asyncMatchItems is the number of items above which typing matches
+	// them in the background instead of on every keystroke
+	asyncMatchItems = 1000
+	// matchDebounce is how long typing pauses before matching starts
+	matchDebounce = 80 * time.Millisecond
+	// matchChunk is the number of items matched per streamed result
+	matchChunk = 2000
+)
+
+// nextFuzzyListID tells lists apart in the background matching results
+var nextFuzzyListID int
+
+// fuzzyMatchMsg carries matches of a list's items found in the background,
+// or starts matching when matches is nil after the debounce
+type fuzzyMatchMsg struct {
+	list    int
+	seq     int // the query the matches are for
+	offset  int // the first item matched, -1 for the debounce
+	matches []fuzzy.Match
+}
+
+// ListItem is a list entry: the ID it is matched, selected and remembered
+// by, and a detail shown after it that may change, such as a pod's phase
+type ListItem struct {
+	ID     string
+	Detail string
+}
+
+// listItems turns plain names into list items
+func listItems(ids []string) []ListItem {
+	items := make([]ListItem, len(ids))
+	for i, id := range ids {
+		items[i] = ListItem{ID: id}
+	}
+	return items
+}
+
 // FuzzyList is an interactive fuzzy-searchable list component
 type FuzzyList struct {
+	id              int
 	textInput       textinput.Model
 	items           []string
 	recentItems     []string
@@ -22,6 +66,29 @@ type FuzzyList struct {
 	loading         bool
 	err             error
 	inRecentSection bool
+	multiSelect     bool
+	marked          []string  // marked items in the order they were marked
+	markStale       bool      // recent items missing from the items are marked gone
+	favorites       []string  // pinned items, starred in the recent section
+	cachedAt        time.Time // the items come from the offline cache saved then
+	updatedAt       time.Time // the items were last refreshed in place then
+	width           int       // cells available per row, items are cut to fit; 0 does not cut
+	itemSet         map[string]bool
+	details         map[string]string // shown dimmed after the items, not matched
+	badges          map[string]string // rendered before the items, all as wide as badgeWidth
+	badgeWidth      int
+	candidates      []string   // the items that are not among the recent items
+	seq             int        // bumped by each query, so older background matches are dropped
+	matching        bool       // the filtered items are still being matched for the query
+	nameSep         string     // items are names followed by this and a description, see SetNameSeparator
+	kept            *listPlace // where the cursor was before a refresh still being matched
+}
+
+// listPlace is the item under the cursor and where the cursor and the
+// scrolling were, to stay there when the items are refreshed
+type listPlace struct {
+	selected       string
+	cursor, offset int
 }
 
 // NewFuzzyList creates a new fuzzy list component
@@ -35,7 +102,9 @@ func NewFuzzyList(title string) FuzzyList {
 	ti.TextStyle = BaseStyle
 	ti.Cursor.Style = CursorStyle
 
+	nextFuzzyListID++
 	return FuzzyList{
+		id:              nextFuzzyListID,
 		textInput:       ti,
 		items:           []string{},
 		recentItems:     []string{},
@@ -51,30 +120,204 @@ func NewFuzzyList(title string) FuzzyList {
 
 // SetItems sets the list items
 func (f *FuzzyList) SetItems(items []string) {
-	f.items = items
+	f.storeItems(listItems(items))
+	f.filterItems()
+}
+
+// SetListItems sets the list items with their details
+func (f *FuzzyList) SetListItems(items []ListItem) {
+	f.storeItems(items)
+	f.filterItems()
+}
+
+// storeItems replaces the items and their details without matching them
+func (f *FuzzyList) storeItems(items []ListItem) {
+	f.items = make([]string, len(items))
+	f.itemSet = make(map[string]bool, len(items))
+	f.details = make(map[string]string)
+	for i, item := range items {
+		f.items[i] = item.ID
+		f.itemSet[item.ID] = true
+		if item.Detail != "" {
+			f.details[item.ID] = item.Detail
+		}
+	}
+	f.cachedAt = time.Time{}
+	f.updatedAt = time.Time{}
 	f.loading = false
+	f.setCandidates()
+}
+
+// RefreshItems replaces the items with a newer list of them, keeping the
+// query, the marks and the cursor on the same item while it is listed. A
+// long list with a query is matched in the background like typing does;
+// the current matches stay shown until the new ones arrive.
+func (f *FuzzyList) RefreshItems(items []ListItem) tea.Cmd {
+	place := listPlace{selected: f.current(), cursor: f.cursor, offset: f.scrollOffset}
+	marked := f.marked
+	f.storeItems(items)
+	f.marked = slices.DeleteFunc(marked, func(item string) bool { return !f.itemSet[item] })
+	f.updatedAt = time.Now()
+
+	if len(f.candidates) > asyncMatchItems && f.textInput.Value() != "" {
+		cmd := f.filterLater()
+		f.kept = &place
+		f.keepPlace(place)
+		return cmd
+	}
 	f.filterItems()
+	f.keepPlace(place)
+	return nil
+}
+
+// keepPlace moves the cursor back to the item it was on, or to its place if
+// the item is gone, and scrolls as before where possible
+func (f *FuzzyList) keepPlace(place listPlace) {
+	f.cursor = min(place.cursor, max(f.totalItems()-1, 0))
+	for i := 0; place.selected != "" && i < f.totalItems(); i++ {
+		if match, _ := f.itemAt(i); match.Str == place.selected {
+			f.cursor = i
+			break
+		}
+	}
+	f.inRecentSection = f.cursor < len(f.filteredRecent)
+	f.scrollOffset = min(place.offset, max(f.totalItems()-f.maxVisible, 0))
+	if f.cursor < f.scrollOffset {
+		f.scrollOffset = f.cursor
+	} else if f.cursor >= f.scrollOffset+f.maxVisible {
+		f.scrollOffset = f.cursor - f.maxVisible + 1
+	}
 }
 
 // SetRecentItems sets the recent items list
 func (f *FuzzyList) SetRecentItems(items []string) {
 	f.recentItems = items
+	f.setCandidates()
+	f.filterItems()
+}
+
+// SetNameSeparator makes the list rank items that are a name, sep and a
+// description by their name: exact and prefix matches of the name first,
+// then fuzzy matches of the name, and matches in the description last.
+func (f *FuzzyList) SetNameSeparator(sep string) {
+	f.nameSep = sep
 	f.filterItems()
 }
 
+// nameTierScore separates the scores of findByName's ranks, it is above
+// any fuzzy score of a name
+const nameTierScore = 1 << 20
+
+// findItems matches the query against items, ranked by name if sep is set
+func findItems(query string, items []string, sep string) []fuzzy.Match {
+	if sep == "" {
+		return fuzzy.Find(query, items)
+	}
+	return findByName(query, items, sep)
+}
+
+// findByName matches the query against items made of a name, sep and a
+// description. Matches of the name rank before those that need the
+// description, exact and prefix matches of the name first; their highlights
+// are on the name. The rank is part of the score, so chunks matched in the
+// background merge in the same order.
+func findByName(query string, items []string, sep string) []fuzzy.Match {
+	lower := strings.ToLower(query)
+	matches := fuzzy.Find(query, items)
+	for i, m := range matches {
+		name, _, _ := strings.Cut(m.Str, sep)
+		byName := fuzzy.Find(query, []string{name})
+		if len(byName) == 0 {
+			continue
+		}
+		tier := 1
+		switch lowerName := strings.ToLower(name); {
+		case lowerName == lower:
+			tier = 3
+		case strings.HasPrefix(lowerName, lower):
+			tier = 2
+		}
+		matches[i].Score = byName[0].Score + tier*nameTierScore
+		matches[i].MatchedIndexes = byName[0].MatchedIndexes
+	}
+	slices.SortStableFunc(matches, func(a, b fuzzy.Match) int {
+		return b.Score - a.Score
+	})
+	return matches
+}
+
+// setCandidates collects the items shown below the recent items
+func (f *FuzzyList) setCandidates() {
+	recentSet := make(map[string]bool, len(f.recentItems))
+	for _, r := range f.recentItems {
+		recentSet[r] = true
+	}
+	f.candidates = make([]string, 0, len(f.items))
+	for _, item := range f.items {
+		if !recentSet[item] {
+			f.candidates = append(f.candidates, item)
+		}
+	}
+}
+
+// SetFavorites sets the items starred as favorites. They are expected to be
+// among the recent items, which are shown at the top.
+func (f *FuzzyList) SetFavorites(items []string) {
+	f.favorites = items
+}
+
+func (f *FuzzyList) isFavorite(item string) bool {
+	for _, fav := range f.favorites {
+		if fav == item {
+			return true
+		}
+	}
+	return false
+}
+
+// SetBadges sets a column rendered before the items, e.g. their health.
+// Items without a badge get an empty column; nil removes the column.
+func (f *FuzzyList) SetBadges(badges map[string]string) {
+	f.badges = badges
+	f.badgeWidth = 0
+	for _, badge := range badges {
+		f.badgeWidth = max(f.badgeWidth, lipgloss.Width(badge))
+	}
+}
+
 // SetError sets an error message
 func (f *FuzzyList) SetError(err error) {
 	f.err = err
 	f.loading = false
 }
 
+// SetCached marks the items as the offline cache saved at the given time,
+// the zero time marks them as live
+func (f *FuzzyList) SetCached(at time.Time) {
+	f.cachedAt = at
+}
+
+// SetWidth sets the cells available per row
+func (f *FuzzyList) SetWidth(width int) {
+	f.width = width
+}
+
 // SetLoading sets the loading state
 func (f *FuzzyList) SetLoading(loading bool) {
 	f.loading = loading
 }
 
-// GetSelected returns the currently selected item
+// GetSelected returns the currently selected item. Matching still running
+// in the background is finished first, so the item is one of the query's.
 func (f *FuzzyList) GetSelected() string {
+	if f.matching {
+		f.filterItems()
+	}
+	return f.current()
+}
+
+// current returns the item under the cursor among the matches shown
+func (f *FuzzyList) current() string {
 	if f.inRecentSection && len(f.filteredRecent) > 0 {
 		if f.cursor < len(f.filteredRecent) {
 			return f.filteredRecent[f.cursor].Str
@@ -94,13 +337,106 @@ func (f *FuzzyList) GetSelected() string {
 	return ""
 }
 
+// SelectItem moves the cursor to an item and reports whether it was found
+func (f *FuzzyList) SelectItem(item string) bool {
+	for i := 0; i < f.totalItems(); i++ {
+		if match, _ := f.itemAt(i); match.Str != item {
+			continue
+		}
+		f.cursor = i
+		f.inRecentSection = i < len(f.filteredRecent)
+		if f.cursor >= f.scrollOffset+f.maxVisible {
+			f.scrollOffset = f.cursor - f.maxVisible + 1
+		}
+		return true
+	}
+	return false
+}
+
 // GetInput returns the current input value
 func (f *FuzzyList) GetInput() string {
 	return f.textInput.Value()
 }
 
+// SetMultiSelect enables marking several items with space
+func (f *FuzzyList) SetMultiSelect(enabled bool) {
+	f.multiSelect = enabled
+	if !enabled {
+		f.marked = nil
+	}
+}
+
+// ToggleMarked marks or unmarks the item under the cursor
+func (f *FuzzyList) ToggleMarked() {
+	selected := f.GetSelected()
+	if selected == "" {
+		return
+	}
+	for i, item := range f.marked {
+		if item == selected {
+			f.marked = append(f.marked[:i], f.marked[i+1:]...)
+			return
+		}
+	}
+	f.marked = append(f.marked, selected)
+}
+
+// MarkAll marks all items, or unmarks them if all are marked already
+func (f *FuzzyList) MarkAll() {
+	if len(f.marked) == len(f.items) {
+		f.marked = nil
+		return
+	}
+	f.marked = append([]string(nil), f.items...)
+}
+
+// GetMarked returns the marked items in the order they were marked
+func (f *FuzzyList) GetMarked() []string {
+	return f.marked
+}
+
+func (f *FuzzyList) isMarked(item string) bool {
+	for _, m := range f.marked {
+		if m == item {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMarkStale enables marking recent items that are no longer among the
+// items, e.g. deleted deployments
+func (f *FuzzyList) SetMarkStale(enabled bool) {
+	f.markStale = enabled
+}
+
+// IsStale reports whether a recent item is no longer among the loaded items
+func (f *FuzzyList) IsStale(item string) bool {
+	if !f.markStale || f.loading || f.err != nil || !f.cachedAt.IsZero() || f.itemSet[item] {
+		return false
+	}
+	for _, recent := range f.recentItems {
+		if recent == item {
+			return true
+		}
+	}
+	return false
+}
+
+// StaleItems returns the recent items that are no longer among the items
+func (f *FuzzyList) StaleItems() []string {
+	var stale []string
+	for _, item := range f.recentItems {
+		if f.IsStale(item) {
+			stale = append(stale, item)
+		}
+	}
+	return stale
+}
+
 // Reset clears the input and resets the list
 func (f *FuzzyList) Reset() {
+	f.marked = nil
 	f.textInput.SetValue("")
 	f.cursor = 0
 	f.scrollOffset = 0
@@ -123,10 +459,60 @@ func (f *FuzzyList) totalItems() int {
 	return len(f.filteredRecent) + len(f.filtered)
 }
 
+// Summary says in one line what the list shows: its title, the selected
+// item and where it is, or why there is none
+func (f *FuzzyList) Summary() string {
+	switch {
+	case f.loading:
+		return f.title + ": loading"
+	case f.err != nil:
+		reason, _, _ := strings.Cut(f.err.Error(), "\n")
+		return f.title + ": error: " + reason
+	}
+	selected := f.GetSelected()
+	if selected == "" {
+		if f.textInput.Value() != "" {
+			return f.title + ": no matches for " + f.textInput.Value()
+		}
+		return f.title + ": empty"
+	}
+	return fmt.Sprintf("%s: %s, %d of %d", f.title, selected, f.cursor+1, f.totalItems())
+}
+
+// itemAt returns the visible item at index i and whether it is a recent one
+func (f *FuzzyList) itemAt(i int) (fuzzy.Match, bool) {
+	if i < len(f.filteredRecent) {
+		return f.filteredRecent[i], true
+	}
+	return f.filtered[i-len(f.filteredRecent)], false
+}
+
+// filterItems matches all items against the query right away
 func (f *FuzzyList) filterItems() {
+	f.seq++
+	f.matching = false
+	f.kept = nil
+	f.filterRecent()
+
 	query := f.textInput.Value()
+	if query == "" {
+		f.filtered = make([]fuzzy.Match, len(f.candidates))
+		for i, item := range f.candidates {
+			f.filtered[i] = fuzzy.Match{
+				Str:   item,
+				Index: i,
+			}
+		}
+	} else {
+		f.filtered = findItems(query, f.candidates, f.nameSep)
+	}
+
+	f.resetCursor()
+}
 
-	// Filter recent items
+// filterRecent matches the recent items against the query
+func (f *FuzzyList) filterRecent() {
+	query := f.textInput.Value()
 	if len(f.recentItems) > 0 {
 		if query == "" {
 			f.filteredRecent = make([]fuzzy.Match, len(f.recentItems))
@@ -137,36 +523,16 @@ func (f *FuzzyList) filterItems() {
 				}
 			}
 		} else {
-			f.filteredRecent = fuzzy.Find(query, f.recentItems)
+			f.filteredRecent = findItems(query, f.recentItems, f.nameSep)
 		}
 	} else {
 		f.filteredRecent = []fuzzy.Match{}
 	}
+}
 
-	// Filter main items (excluding recent items from main list)
-	itemsWithoutRecent := make([]string, 0, len(f.items))
-	recentSet := make(map[string]bool)
-	for _, r := range f.recentItems {
-		recentSet[r] = true
-	}
-	for _, item := range f.items {
-		if !recentSet[item] {
-			itemsWithoutRecent = append(itemsWithoutRecent, item)
-		}
-	}
-
-	if query == "" {
-		f.filtered = make([]fuzzy.Match, len(itemsWithoutRecent))
-		for i, item := range itemsWithoutRecent {
-			f.filtered[i] = fuzzy.Match{
-				Str:   item,
-				Index: i,
-			}
-		}
-	} else {
-		f.filtered = fuzzy.Find(query, itemsWithoutRecent)
-	}
-
+// resetCursor moves the cursor back into the filtered items and scrolls to
+// the top
+func (f *FuzzyList) resetCursor() {
 	// Reset cursor if out of bounds
 	total := f.totalItems()
 	if f.cursor >= total {
@@ -178,15 +544,104 @@ func (f *FuzzyList) filterItems() {
 	f.scrollOffset = 0
 }
 
+// filterLater matches the recent items right away and the other items in
+// the background once typing pauses. The previous query's items stay shown
+// until the first matches arrive.
+func (f *FuzzyList) filterLater() tea.Cmd {
+	f.seq++
+	f.matching = true
+	f.kept = nil
+	f.filterRecent()
+	f.resetCursor()
+	id, seq := f.id, f.seq
+	return tea.Tick(matchDebounce, func(time.Time) tea.Msg {
+		return fuzzyMatchMsg{list: id, seq: seq, offset: -1}
+	})
+}
+
+// matchChunk matches the items from offset on in the background, up to
+// matchChunk of them
+func (f *FuzzyList) matchChunk(offset int) tea.Cmd {
+	id, seq, query, items, sep := f.id, f.seq, f.textInput.Value(), f.candidates, f.nameSep
+	return func() tea.Msg {
+		end := min(offset+matchChunk, len(items))
+		matches := findItems(query, items[offset:end], sep)
+		for i := range matches {
+			matches[i].Index += offset
+		}
+		return fuzzyMatchMsg{list: id, seq: seq, offset: offset, matches: matches}
+	}
+}
+
+// Matched takes matches found in the background for the list's current
+// query and returns the command matching the next items, if any. Results
+// for other lists and older queries are ignored.
+func (f *FuzzyList) Matched(msg fuzzyMatchMsg) tea.Cmd {
+	if msg.list != f.id || msg.seq != f.seq || !f.matching {
+		return nil
+	}
+	if msg.offset < 0 {
+		return f.matchChunk(0)
+	}
+
+	if msg.offset == 0 {
+		f.filtered = msg.matches
+		f.resetCursor()
+	} else {
+		f.filtered = mergeMatches(f.filtered, msg.matches)
+	}
+	// A refresh stays on the item the cursor was on
+	if f.kept != nil {
+		f.keepPlace(*f.kept)
+	}
+
+	if next := msg.offset + matchChunk; next < len(f.candidates) {
+		return f.matchChunk(next)
+	}
+	f.matching = false
+	f.kept = nil
+	if f.cursor >= f.totalItems() {
+		f.resetCursor()
+	}
+	return nil
+}
+
+// mergeMatches merges two lists of matches sorted by score, keeping the
+// earlier list first among equal scores
+func mergeMatches(a, b []fuzzy.Match) []fuzzy.Match {
+	merged := make([]fuzzy.Match, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0].Score >= b[0].Score {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
 // Update handles messages
 func (f *FuzzyList) Update(msg tea.Msg) (FuzzyList, tea.Cmd) {
 	var cmd tea.Cmd
 	total := f.totalItems()
 
 	switch msg := msg.(type) {
+	case fuzzyMatchMsg:
+		return *f, f.Matched(msg)
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "up", "ctrl+p":
+		// Moving the cursor leaves the place kept for a refresh
+		if key.Matches(msg, keys.Up, keys.Down, keys.PageUp, keys.PageDown) {
+			f.kept = nil
+		}
+		if f.multiSelect && key.Matches(msg, keys.Mark) {
+			f.ToggleMarked()
+			return *f, nil
+		}
+
+		switch {
+		case key.Matches(msg, keys.Up):
 			if f.cursor > 0 {
 				f.cursor--
 				f.inRecentSection = f.cursor < len(f.filteredRecent)
@@ -196,7 +651,7 @@ func (f *FuzzyList) Update(msg tea.Msg) (FuzzyList, tea.Cmd) {
 			}
 			return *f, nil
 
-		case "down", "ctrl+n":
+		case key.Matches(msg, keys.Down):
 			if f.cursor < total-1 {
 				f.cursor++
 				f.inRecentSection = f.cursor < len(f.filteredRecent)
@@ -206,7 +661,7 @@ func (f *FuzzyList) Update(msg tea.Msg) (FuzzyList, tea.Cmd) {
 			}
 			return *f, nil
 
-		case "pgup":
+		case key.Matches(msg, keys.PageUp):
 			f.cursor -= f.maxVisible
 			if f.cursor < 0 {
 				f.cursor = 0
@@ -215,7 +670,7 @@ func (f *FuzzyList) Update(msg tea.Msg) (FuzzyList, tea.Cmd) {
 			f.scrollOffset = f.cursor
 			return *f, nil
 
-		case "pgdown":
+		case key.Matches(msg, keys.PageDown):
 			f.cursor += f.maxVisible
 			if f.cursor >= total {
 				f.cursor = total - 1
@@ -235,9 +690,13 @@ func (f *FuzzyList) Update(msg tea.Msg) (FuzzyList, tea.Cmd) {
 	prevValue := f.textInput.Value()
 	f.textInput, cmd = f.textInput.Update(msg)
 
-	// If input changed, re-filter
+	// If input changed, re-filter, in the background for long lists
 	if f.textInput.Value() != prevValue {
-		f.filterItems()
+		if len(f.candidates) > asyncMatchItems && f.textInput.Value() != "" {
+			cmd = tea.Batch(cmd, f.filterLater())
+		} else {
+			f.filterItems()
+		}
 	}
 
 	return *f, cmd
@@ -271,11 +730,22 @@ func (f *FuzzyList) View() string {
 		return b.String()
 	}
 
+	if !f.cachedAt.IsZero() {
+		b.WriteString(WarningStyle.Render(fmt.Sprintf("  ⚠ Cluster unreachable, cached %s ago (%s)",
+			formatAge(time.Since(f.cachedAt)), f.cachedAt.Local().Format("Jan 2 15:04"))))
+		b.WriteString("\n")
+	} else if !f.updatedAt.IsZero() {
+		b.WriteString(DimStyle.Render("  ↻ List updated " + f.updatedAt.Local().Format("15:04:05")))
+		b.WriteString("\n")
+	}
+
 	total := f.totalItems()
 
 	// No results
 	if total == 0 {
-		if len(f.items) == 0 && len(f.recentItems) == 0 {
+		if f.matching {
+			b.WriteString(RenderLoading("Matching..."))
+		} else if len(f.items) == 0 && len(f.recentItems) == 0 {
 			b.WriteString(InfoStyle.Render("  No items available"))
 		} else {
 			b.WriteString(InfoStyle.Render("  No matches found"))
@@ -283,25 +753,10 @@ func (f *FuzzyList) View() string {
 		return b.String()
 	}
 
-	// Build combined list for rendering
-	type listItem struct {
-		match    fuzzy.Match
-		isRecent bool
-		index    int // index in combined list
-	}
-
-	allItems := make([]listItem, 0, total)
-	for i, match := range f.filteredRecent {
-		allItems = append(allItems, listItem{match: match, isRecent: true, index: i})
-	}
-	for i, match := range f.filtered {
-		allItems = append(allItems, listItem{match: match, isRecent: false, index: len(f.filteredRecent) + i})
-	}
-
-	// Render visible items
+	// Render only the visible items
 	end := f.scrollOffset + f.maxVisible
-	if end > len(allItems) {
-		end = len(allItems)
+	if end > total {
+		end = total
 	}
 
 	// Track if we need section headers
@@ -310,14 +765,18 @@ func (f *FuzzyList) View() string {
 
 	inRecentSection := true
 	for i := f.scrollOffset; i < end; i++ {
-		item := allItems[i]
+		match, isRecent := f.itemAt(i)
 
 		// Section headers
-		if showRecentHeader && i == f.scrollOffset && item.isRecent {
-			b.WriteString(InfoStyle.Render("  ⏱ Recent"))
+		if showRecentHeader && i == f.scrollOffset && isRecent {
+			header := "  ⏱ Recent"
+			if len(f.favorites) > 0 {
+				header = "  ★ Favorites & ⏱ Recent"
+			}
+			b.WriteString(InfoStyle.Render(header))
 			b.WriteString("\n")
 		}
-		if showAllHeader && !item.isRecent && inRecentSection {
+		if showAllHeader && !isRecent && inRecentSection {
 			inRecentSection = false
 			b.WriteString(InfoStyle.Render("  📋 All"))
 			b.WriteString("\n")
@@ -325,12 +784,59 @@ func (f *FuzzyList) View() string {
 
 		isSelected := i == f.cursor
 
-		// Build the display string with highlighted matches
+		// Build the display string with highlighted matches, cut to the
+		// row without the gutter and markers
+		favorite := isRecent && f.isFavorite(match.Str)
+		stale := isRecent && f.IsStale(match.Str)
+		detail := f.details[match.Str]
+		str := match.Str
+		if f.width > 0 {
+			room := f.width - 4
+			if f.multiSelect {
+				room -= 4
+			}
+			if favorite {
+				room -= 2
+			}
+			if stale {
+				room -= 7
+			}
+			if detail != "" {
+				room -= lipgloss.Width(detail) + 3
+			}
+			if f.badgeWidth > 0 {
+				room -= f.badgeWidth + 1
+			}
+			str = truncateWidth(str, max(room, 10), "...")
+		}
 		var display string
-		if len(item.match.MatchedIndexes) > 0 && f.textInput.Value() != "" {
-			display = f.highlightMatches(item.match.Str, item.match.MatchedIndexes)
+		if len(match.MatchedIndexes) > 0 && f.textInput.Value() != "" {
+			display = f.highlightMatches(str, match.MatchedIndexes)
 		} else {
-			display = item.match.Str
+			display = str
+		}
+
+		if favorite {
+			display = "★ " + display
+		}
+		if detail != "" {
+			display += DimStyle.Render(" (" + detail + ")")
+		}
+		if stale {
+			display += InfoStyle.Render(" (gone)")
+		}
+
+		if f.badgeWidth > 0 {
+			badge := f.badges[match.Str]
+			display = badge + strings.Repeat(" ", f.badgeWidth-lipgloss.Width(badge)+1) + display
+		}
+
+		if f.multiSelect {
+			if f.isMarked(match.Str) {
+				display = "[x] " + display
+			} else {
+				display = "[ ] " + display
+			}
 		}
 
 		if isSelected {
@@ -342,7 +848,9 @@ func (f *FuzzyList) View() string {
 	}
 
 	// Scroll indicator
-	if total > f.maxVisible {
+	if f.matching {
+		b.WriteString(InfoStyle.Render("  [" + itoa(f.cursor+1) + "/" + itoa(total) + ", matching...]"))
+	} else if total > f.maxVisible {
 		current := f.cursor + 1
 		b.WriteString(InfoStyle.Render("  [" + itoa(current) + "/" + itoa(total) + "]"))
 	}
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// deploymentHealthMsg carries the readiness of a namespace's deployments
+type deploymentHealthMsg struct {
+	namespace string
+	health    map[string]k8s.DeploymentHealth
+	err       error
+}
+
+// healthStyle colors the health dot by level
+func healthStyle(level k8s.HealthLevel) lipgloss.Style {
+	switch level {
+	case k8s.HealthOK:
+		return SuccessStyle
+	case k8s.HealthDegraded:
+		return WarningStyle
+	case k8s.HealthDown:
+		return ErrorStyle
+	}
+	return DimStyle
+}
+
+// loadDeploymentHealth fetches the readiness shown in the deployment list
+func (m *Model) loadDeploymentHealth() tea.Cmd {
+	namespace := m.namespace
+	return func() tea.Msg {
+		health, err := m.k8sClient.ListDeploymentHealth(context.Background(), namespace)
+		return deploymentHealthMsg{namespace: namespace, health: health, err: err}
+	}
+}
+
+// handleDeploymentHealth shows the readiness in the deployment list. It is
+// left out if it could not be fetched, the names are enough to go on.
+func (m Model) handleDeploymentHealth(msg deploymentHealthMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil || msg.namespace != m.namespace {
+		return m, nil
+	}
+	m.depSelector.SetBadges(healthBadges(msg.health))
+	return m, nil
+}
+
+// healthWord names a health level where a color is not enough
+func healthWord(level k8s.HealthLevel) string {
+	switch level {
+	case k8s.HealthOK:
+		return "ok"
+	case k8s.HealthDegraded:
+		return "degraded"
+	case k8s.HealthDown:
+		return "down"
+	}
+	return "idle"
+}
+
+// healthBadges renders a colored dot and ready/desired replicas per
+// deployment, with the counts aligned. Accessible mode names the health
+// instead of the dot.
+func healthBadges(health map[string]k8s.DeploymentHealth) map[string]string {
+	width := 0
+	for _, h := range health {
+		width = max(width, len(readiness(h)))
+	}
+	badges := make(map[string]string, len(health))
+	for name, h := range health {
+		mark := "●"
+		if accessible {
+			mark = fmt.Sprintf("%-8s", healthWord(h.Level))
+		}
+		badges[name] = healthStyle(h.Level).Render(fmt.Sprintf("%s %*s", mark, width, readiness(h)))
+	}
+	return badges
+}
+
+// readiness formats ready/desired replicas
+func readiness(h k8s.DeploymentHealth) string {
+	return fmt.Sprintf("%d/%d", h.Ready, h.Desired)
+}
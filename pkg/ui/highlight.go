@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	yamlKeyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#A78BFA"))
+	yamlStringStyle = lipgloss.NewStyle().Foreground(SecondaryColor)
+	yamlNumberStyle = lipgloss.NewStyle().Foreground(AccentColor)
+	yamlBoolStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#60A5FA"))
+
+	// yamlKeyPattern matches indentation and list dashes, then a key
+	yamlKeyPattern    = regexp.MustCompile(`^(\s*(?:- )*)([^\s:#"'][^:#]*?|"[^"]*"|'[^']*'):(\s|$)`)
+	yamlDashPattern   = regexp.MustCompile(`^(\s*(?:- )+)`)
+	yamlNumberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+	// resultKeyPattern matches an indented "Key: value" label, not URLs or
+	// times; resultEnvPattern a KEY=value pair; resultHeaderPattern a table
+	// header of upper-case columns
+	resultKeyPattern    = regexp.MustCompile(`^(\s*(?:[-•] )?)([A-Za-z][\w .,/()'-]{0,60}?):(\s|$)`)
+	resultEnvPattern    = regexp.MustCompile(`^(\s*)([A-Za-z_][A-Za-z0-9_.-]*)=`)
+	resultHeaderPattern = regexp.MustCompile(`^\s*[A-Z][A-Z0-9/%_.-]*(?: {1,2}[A-Z][A-Z0-9/%_.-]*)*(?: {2,}[A-Z][A-Z0-9/%_.-]*(?: [A-Z][A-Z0-9/%_.-]*)*)+\s*$`)
+)
+
+// highlightKeyValues colors the keys of a plain result line, such as
+// "Replicas: 3" of describe or "LOG_LEVEL=debug" of list-env, and table
+// headers, leaving the values as they are. Findings flagged with ✗ or ⚠,
+// such as an expiring certificate, are colored from the flag on.
+func highlightKeyValues(line string) string {
+	if i := strings.Index(line, "✗ "); i >= 0 {
+		return highlightKeyValues(line[:i]) + ErrorStyle.Render(line[i:])
+	}
+	if i := strings.Index(line, "⚠ "); i >= 0 {
+		return highlightKeyValues(line[:i]) + WarningStyle.Render(line[i:])
+	}
+	if resultHeaderPattern.MatchString(line) {
+		return LabelStyle.Render(line)
+	}
+	if m := resultKeyPattern.FindStringSubmatchIndex(line); m != nil {
+		return line[:m[3]] + LabelStyle.Render(line[m[4]:m[5]]) + line[m[5]:]
+	}
+	if m := resultEnvPattern.FindStringSubmatchIndex(line); m != nil {
+		return line[:m[3]] + LabelStyle.Render(line[m[4]:m[5]]) + line[m[5]:]
+	}
+	return line
+}
+
+// highlightYAML colors one line of YAML: keys, scalar values and comments
+func highlightYAML(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return DimStyle.Render(line)
+	}
+
+	var b strings.Builder
+	rest := line
+	if m := yamlKeyPattern.FindStringSubmatchIndex(line); m != nil {
+		b.WriteString(line[:m[3]])
+		b.WriteString(yamlKeyStyle.Render(line[m[4]:m[5]]))
+		b.WriteString(":")
+		rest = line[m[5]+1:]
+	} else if m := yamlDashPattern.FindStringIndex(line); m != nil {
+		b.WriteString(line[:m[1]])
+		rest = line[m[1]:]
+	}
+	b.WriteString(highlightYAMLValue(rest))
+	return b.String()
+}
+
+// highlightDiff colors a line of a diff: added lines green, removed ones
+// red and the unchanged ones around them as YAML
+func highlightDiff(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+ "):
+		return SuccessStyle.Render(line)
+	case strings.HasPrefix(line, "- "):
+		return ErrorStyle.Render(line)
+	case strings.HasPrefix(line, "  "):
+		return "  " + highlightYAML(line[2:])
+	}
+	return line
+}
+
+// highlightYAMLValue colors a scalar by its type, keeping surrounding spaces
+func highlightYAMLValue(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return value
+	}
+	lead := value[:len(value)-len(strings.TrimLeft(value, " "))]
+
+	switch {
+	case trimmed == "|" || trimmed == ">" || trimmed == "|-" || trimmed == ">-" || trimmed == "{}" || trimmed == "[]":
+		return value
+	case trimmed == "true" || trimmed == "false" || trimmed == "null":
+		return lead + yamlBoolStyle.Render(trimmed)
+	case yamlNumberPattern.MatchString(trimmed):
+		return lead + yamlNumberStyle.Render(trimmed)
+	}
+	return lead + yamlStringStyle.Render(trimmed)
+}
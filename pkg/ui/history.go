@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// formatRolloutHistory renders a deployment's conditions and a timeline of
+// its revisions, newest first, showing what each one changed
+func formatRolloutHistory(h *k8s.RolloutHistory) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rollout history of %s (%d revisions kept)\n\n", h.Deployment, len(h.Revisions))
+
+	if len(h.Conditions) > 0 {
+		b.WriteString("Conditions:\n")
+		for _, c := range h.Conditions {
+			mark := "✓"
+			if c.Status != corev1.ConditionTrue {
+				mark = "✗"
+			}
+			fmt.Fprintf(&b, "  %s %-14s %s, %s ago", mark, c.Type, c.Reason, formatAge(time.Since(c.LastUpdateTime.Time)))
+			if c.Message != "" {
+				fmt.Fprintf(&b, ": %s", c.Message)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(h.Revisions) == 0 {
+		b.WriteString("No revisions found.\n")
+		return b.String()
+	}
+	for i := len(h.Revisions) - 1; i >= 0; i-- {
+		rev := h.Revisions[i]
+		mark := "○"
+		if rev.Current {
+			mark = "●"
+		}
+		fmt.Fprintf(&b, "%s Revision %d  %s  %s (%s ago)\n", mark, rev.Number, revisionState(rev),
+			rev.Created.Local().Format("2006-01-02 15:04"), formatAge(time.Since(rev.Created)))
+
+		line := func(label, value string) {
+			fmt.Fprintf(&b, "│   %-8s %s\n", label, value)
+		}
+		cause := rev.ChangeCause
+		if cause == "" {
+			cause = "(none recorded)"
+		}
+		line("Cause:", cause)
+		line("Images:", strings.Join(rev.Images, ", "))
+		switch {
+		case i == 0:
+			line("Changes:", "(oldest revision kept)")
+		case len(rev.Changes) == 0:
+			line("Changes:", "none in the pod template")
+		default:
+			line("Changes:", rev.Changes[0])
+			for _, change := range rev.Changes[1:] {
+				line("", change)
+			}
+		}
+		if len(rev.Earlier) > 0 {
+			line("Earlier:", "rolled out before as revision "+strings.Join(rev.Earlier, ", "))
+		}
+		line("RS:", rev.ReplicaSet)
+		if i > 0 {
+			b.WriteString("│\n")
+		}
+	}
+	b.WriteString("\nRoll back with the rollback command and a revision number.\n")
+	return b.String()
+}
+
+// revisionState describes what became of a revision
+func revisionState(rev k8s.Revision) string {
+	switch {
+	case rev.Current && rev.Ready == rev.Replicas:
+		return fmt.Sprintf("current, %d/%d ready", rev.Ready, rev.Replicas)
+	case rev.Current:
+		return fmt.Sprintf("current, rolling out (%d/%d ready)", rev.Ready, rev.Replicas)
+	case rev.Replicas > 0:
+		return fmt.Sprintf("superseded, %d replicas still running", rev.Replicas)
+	}
+	return "superseded, scaled down"
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxPreviousImages is how many previously deployed images update-image
+// offers
+const maxPreviousImages = 9
+
+// loadPreviousImages looks up the images khelper deployed to the selected
+// container before, offered when entering update-image's new image
+func (m *Model) loadPreviousImages() {
+	m.previousImages, m.imagePick = nil, -1
+	history, err := config.LoadHistory()
+	if err != nil {
+		return
+	}
+	images := config.ImageHistory(history, m.kubeconfig, m.namespace, m.deployment, m.container)
+	if len(images) > maxPreviousImages {
+		images = images[:maxPreviousImages]
+	}
+	m.previousImages = images
+}
+
+// previousImageKey lets ↑↓ fill in a previously deployed image and Alt+1-9
+// redeploy one right away. It reports false for keys that go to the input.
+func (m Model) previousImageKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch key := msg.String(); key {
+	case "up":
+		m.imagePick = min(m.imagePick+1, len(m.previousImages)-1)
+	case "down":
+		m.imagePick = max(m.imagePick-1, -1)
+	default:
+		if !strings.HasPrefix(key, "alt+") || len(key) != 5 || key[4] < '1' || key[4] > '9' {
+			return m, nil, false
+		}
+		i := int(key[4] - '1')
+		if i >= len(m.previousImages) {
+			return m, nil, true
+		}
+		m.inputValue = m.previousImages[i].Image
+		m.valueInput.SetValue(m.inputValue)
+		model, cmd := m.executeCommand()
+		return model, cmd, true
+	}
+	if m.imagePick < 0 {
+		m.valueInput.SetValue("")
+	} else {
+		m.valueInput.SetValue(m.previousImages[m.imagePick].Image)
+		m.valueInput.CursorEnd()
+	}
+	return m, nil, true
+}
+
+// previousImagesView lists the previously deployed images under the input
+func (m Model) previousImagesView() string {
+	var b strings.Builder
+	b.WriteString(LabelStyle.Render("Previously deployed (↑↓: fill in, Alt+1-9: redeploy now):"))
+	b.WriteString("\n")
+	for i, image := range m.previousImages {
+		line := fmt.Sprintf("%d  %s  %s ago", i+1, image.Image, formatAge(time.Since(image.Time)))
+		if image.User != "" {
+			line += " by " + image.User
+		}
+		if i == m.imagePick {
+			b.WriteString(SelectedItemStyle.Render("▸ " + line))
+		} else {
+			b.WriteString(ListItemStyle.Render("  " + line))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// imageRow is a container of the images table with its new image input
+type imageRow struct {
+	container string
+	current   string
+	input     textinput.Model
+}
+
+// imagesLoadedMsg carries the containers and images of the deployment
+type imagesLoadedMsg struct {
+	rows []imageRow
+	err  error
+}
+
+// loadImageTable reads the images of the deployment's containers
+func (m Model) loadImageTable() tea.Cmd {
+	namespace, deployment := m.namespace, m.deployment
+	return func() tea.Msg {
+		d, err := m.k8sClient.GetDeployment(context.Background(), namespace, deployment)
+		if err != nil {
+			return imagesLoadedMsg{err: err}
+		}
+		var rows []imageRow
+		for _, c := range d.Spec.Template.Spec.Containers {
+			input := textinput.New()
+			input.Placeholder = "unchanged"
+			input.CharLimit = 200
+			input.Width = 40
+			input.PromptStyle = PromptStyle
+			input.TextStyle = BaseStyle
+			rows = append(rows, imageRow{container: c.Name, current: c.Image, input: input})
+		}
+		return imagesLoadedMsg{rows: rows}
+	}
+}
+
+// showImageTable shows the loaded images for editing
+func (m Model) showImageTable(msg imagesLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.state = StateShowResult
+		m.err = msg.err
+		return m, nil
+	}
+	m.state = StateEditImages
+	m.imageRows = msg.rows
+	m.imageCursor = 0
+	m.focusImageRow()
+	return m, textinput.Blink
+}
+
+// focusImageRow focuses the input of the row under the cursor
+func (m *Model) focusImageRow() {
+	for i := range m.imageRows {
+		if i == m.imageCursor {
+			m.imageRows[i].input.Focus()
+		} else {
+			m.imageRows[i].input.Blur()
+		}
+	}
+}
+
+// changedImages returns the new images entered, keyed by container
+func (m Model) changedImages() map[string]string {
+	images := make(map[string]string)
+	for _, row := range m.imageRows {
+		image := strings.TrimSpace(row.input.Value())
+		if image != "" && image != row.current {
+			images[row.container] = image
+		}
+	}
+	return images
+}
+
+// imageTableKey edits the images table: ↑↓ move between containers, Enter
+// applies every change in a single update
+func (m Model) imageTableKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Back):
+		m.state = StateSelectCommand
+		m.imageRows = nil
+		m.cmdSelector.Reset()
+		return m, nil
+	case key.Matches(msg, keys.PrevRow):
+		m.imageCursor = (m.imageCursor + len(m.imageRows) - 1) % len(m.imageRows)
+		m.focusImageRow()
+		return m, nil
+	case key.Matches(msg, keys.NextRow):
+		m.imageCursor = (m.imageCursor + 1) % len(m.imageRows)
+		m.focusImageRow()
+		return m, nil
+	case key.Matches(msg, keys.ResetRow):
+		m.imageRows[m.imageCursor].input.SetValue(m.imageRows[m.imageCursor].current)
+		m.imageRows[m.imageCursor].input.CursorEnd()
+		return m, nil
+	case key.Matches(msg, keys.ApplyImages):
+		return m.applyImageTable()
+	}
+	var cmd tea.Cmd
+	m.imageRows[m.imageCursor].input, cmd = m.imageRows[m.imageCursor].input.Update(msg)
+	return m, cmd
+}
+
+// applyImageTable lints and applies the changed images as one rollout
+func (m Model) applyImageTable() (tea.Model, tea.Cmd) {
+	images := m.changedImages()
+	if len(images) == 0 {
+		return m, nil
+	}
+	containers := make([]string, 0, len(images))
+	for name := range images {
+		containers = append(containers, name)
+	}
+	sort.Strings(containers)
+
+	namespace, deployment := m.namespace, m.deployment
+	changes := make([]config.Change, 0, len(containers))
+	var summary []string
+	for _, name := range containers {
+		changes = append(changes, config.Change{Namespace: namespace, Deployment: deployment, Operation: config.OpUpdateImage, Container: name, After: images[name]})
+		summary = append(summary, fmt.Sprintf("  %s → %s", name, images[name]))
+	}
+
+	m.startExecution()
+	ctx := m.exec.context()
+	return m, m.whileExecuting(m.lintBefore(k8s.EditImages(images), func() tea.Msg {
+		note, err := ApplyChanges(ctx, m.k8sClient, changes, func(ctx context.Context) error {
+			return m.k8sClient.UpdateImages(ctx, namespace, deployment, images)
+		})
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: withNote(fmt.Sprintf("Updated %d images of %s in one rollout:\n%s", len(images), deployment, strings.Join(summary, "\n")), note)}
+	}))
+}
+
+// imageTableView renders the containers with their current and new images
+func (m Model) imageTableView() string {
+	var b strings.Builder
+	b.WriteString(LabelStyle.Render(fmt.Sprintf("Images of %s", m.deployment)))
+	b.WriteString("\n\n")
+
+	width := len("CONTAINER")
+	currentWidth := len("CURRENT")
+	for _, row := range m.imageRows {
+		width = max(width, len(row.container))
+		currentWidth = max(currentWidth, len(row.current))
+	}
+	b.WriteString(InfoStyle.Render(fmt.Sprintf("  %-*s  %-*s  NEW", width, "CONTAINER", currentWidth, "CURRENT")))
+	b.WriteString("\n")
+	changed := m.changedImages()
+	for i, row := range m.imageRows {
+		cursor := "  "
+		if i == m.imageCursor {
+			cursor = "▸ "
+		}
+		line := fmt.Sprintf("%s%-*s  %-*s  ", cursor, width, row.container, currentWidth, row.current)
+		if i == m.imageCursor {
+			b.WriteString(SelectedItemStyle.Render(line))
+		} else {
+			b.WriteString(ListItemStyle.Render(line))
+		}
+		b.WriteString(row.input.View())
+		if _, ok := changed[row.container]; ok {
+			b.WriteString(WarningStyle.Render(" ●"))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	if len(changed) > 0 {
+		b.WriteString(InfoStyle.Render(fmt.Sprintf("%d of %d containers change, applied together in one update", len(changed), len(m.imageRows))))
+	} else {
+		b.WriteString(InfoStyle.Render("Type a new image for the containers to change"))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(RenderHelp("↑↓/Tab: container", "Ctrl+R: start from current", "Enter: apply", "Esc: back"))
+	return b.String()
+}
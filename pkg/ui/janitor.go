@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+)
+
+// stuckTitles name the janitor's sections by kind
+var stuckTitles = map[string]string{
+	k8s.StuckPod:        fmt.Sprintf("Pods pending for more than %s", formatAge(k8s.StuckPendingAge)),
+	k8s.StuckPVC:        "Unbound PersistentVolumeClaims",
+	k8s.StuckJob:        "Failed Jobs",
+	k8s.StuckReplicaSet: "ReplicaSets with 0 replicas that are not garbage collected",
+}
+
+// formatStuckResources renders the janitor view: a section per kind with
+// the stuck resources' names, ages and reasons
+func formatStuckResources(namespace string, s *k8s.StuckResources, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Stuck resources in %s\n", namespace)
+	for _, kind := range k8s.StuckKinds {
+		var rows [][]string
+		for _, item := range s.Items {
+			if item.Kind == kind {
+				rows = append(rows, []string{item.Name, formatAge(now.Sub(item.Since)), item.Reason})
+			}
+		}
+		fmt.Fprintf(&b, "\n%s: ", stuckTitles[kind])
+		if err, ok := s.Skipped[kind]; ok {
+			fmt.Fprintf(&b, "not checked, %v\n", err)
+			continue
+		}
+		if len(rows) == 0 {
+			b.WriteString("none\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%d\n\n", len(rows))
+		rows = append([][]string{{"NAME", "AGE", "REASON"}}, rows...)
+		widths := make([]int, 2)
+		for _, row := range rows {
+			for i := range widths {
+				widths[i] = max(widths[i], len([]rune(row[i])))
+			}
+		}
+		for _, row := range rows {
+			fmt.Fprintf(&b, "  %-*s  %-*s  %s\n", widths[0], row[0], widths[1], row[1], row[2])
+		}
+	}
+	if len(s.Items) == 0 && len(s.Skipped) == 0 {
+		b.WriteString("\nNothing is stuck.")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,334 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// jobOutputLimit is how much of a job's output is kept, older output is
+// dropped
+const jobOutputLimit = 1 << 20
+
+// jobRefreshInterval is how often the jobs screens show new output
+const jobRefreshInterval = 500 * time.Millisecond
+
+// execJob is a command running in a container in the background. Its output
+// is buffered while other screens are shown.
+type execJob struct {
+	id        int
+	pod       string
+	container string
+	command   string
+	started   time.Time
+	cancel    context.CancelFunc
+
+	mu        sync.Mutex
+	output    []byte
+	truncated bool // the start of the output was dropped
+	ended     time.Time
+	err       error
+	cancelled bool
+}
+
+// jobTickMsg refreshes the jobs screens
+type jobTickMsg struct{}
+
+// startJob runs command with sh -c in the container until it exits or is
+// cancelled
+func startJob(client k8s.Execer, id int, namespace, pod, container, command string) *execJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &execJob{
+		id:        id,
+		pod:       pod,
+		container: container,
+		command:   command,
+		started:   time.Now(),
+		cancel:    cancel,
+	}
+	go func() {
+		err := client.Exec(ctx, k8s.ExecOptions{
+			Namespace:     namespace,
+			PodName:       pod,
+			ContainerName: container,
+			Command:       []string{"sh", "-c", command},
+			Stdout:        job,
+			Stderr:        job,
+		})
+		job.mu.Lock()
+		job.ended = time.Now()
+		if !job.cancelled {
+			job.err = err
+		}
+		job.mu.Unlock()
+	}()
+	return job
+}
+
+// Write buffers the job's output
+func (j *execJob) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.output = append(j.output, p...)
+	if over := len(j.output) - jobOutputLimit; over > 0 {
+		j.output = append(j.output[:0], j.output[over:]...)
+		j.truncated = true
+	}
+	return len(p), nil
+}
+
+// stop cancels a running job
+func (j *execJob) stop() {
+	j.mu.Lock()
+	if j.ended.IsZero() {
+		j.cancelled = true
+	}
+	j.mu.Unlock()
+	j.cancel()
+}
+
+func (j *execJob) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.ended.IsZero()
+}
+
+// status describes the job's state and how long it ran
+func (j *execJob) status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.ended.IsZero() {
+		return fmt.Sprintf("running %s", time.Since(j.started).Round(time.Second))
+	}
+	took := j.ended.Sub(j.started).Round(time.Second)
+	switch {
+	case j.cancelled:
+		return fmt.Sprintf("cancelled after %s", took)
+	case j.err != nil:
+		return fmt.Sprintf("failed after %s: %v", took, j.err)
+	}
+	return fmt.Sprintf("done in %s", took)
+}
+
+// text returns the buffered output
+func (j *execJob) text() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.truncated {
+		return "… (earlier output dropped)\n" + string(j.output)
+	}
+	return string(j.output)
+}
+
+// item is the job's entry in the jobs list
+func (j *execJob) item() string {
+	return fmt.Sprintf("#%d %s/%s: %s (%s)", j.id, j.pod, j.container, j.command, j.status())
+}
+
+// runJob starts the input as a background job and shows its output
+func (m Model) runJob() (tea.Model, tea.Cmd) {
+	command := strings.TrimSpace(m.inputValue)
+	if command == "" {
+		m.state = StateShowResult
+		m.err = fmt.Errorf("enter a command to run")
+		return m, nil
+	}
+	m.nextJobID++
+	job := startJob(m.k8sClient, m.nextJobID, m.namespace, m.pod, m.container, command)
+	m.jobs = append(m.jobs, job)
+	return m.showJob(job)
+}
+
+// showJobs lists the background jobs
+func (m Model) showJobs() (tea.Model, tea.Cmd) {
+	m.state = StateJobs
+	m.viewedJob = nil
+	m.jobSelector.Reset()
+	m.refreshJobs()
+	return m, m.tickJobs()
+}
+
+// showJob shows a job's output, following it while it runs
+func (m Model) showJob(job *execJob) (tea.Model, tea.Cmd) {
+	m.state = StateJobOutput
+	m.viewedJob = job
+	m.jobView = viewport.New(80, 10)
+	m.jobView.KeyMap = keys.Scroll
+	m.resizeJobView()
+	m.jobView.SetContent(job.text())
+	m.jobView.GotoBottom()
+	return m, m.tickJobs()
+}
+
+// tickJobs starts refreshing the jobs screens unless it already runs
+func (m *Model) tickJobs() tea.Cmd {
+	if m.jobTicking {
+		return nil
+	}
+	m.jobTicking = true
+	return tea.Tick(jobRefreshInterval, func(time.Time) tea.Msg { return jobTickMsg{} })
+}
+
+// handleJobTick shows new output and job states, stopping once the jobs
+// screens are left
+func (m Model) handleJobTick() (tea.Model, tea.Cmd) {
+	m.jobTicking = false
+	switch m.state {
+	case StateJobs:
+		m.refreshJobs()
+	case StateJobOutput:
+		following := m.jobView.AtBottom()
+		m.jobView.SetContent(m.viewedJob.text())
+		if following {
+			m.jobView.GotoBottom()
+		}
+	default:
+		return m, nil
+	}
+	return m, m.tickJobs()
+}
+
+// refreshJobs updates the jobs list, newest first, keeping the selection
+func (m *Model) refreshJobs() {
+	selected := m.jobSelector.GetSelected()
+	items := make([]string, len(m.jobs))
+	for i, job := range m.jobs {
+		items[len(m.jobs)-1-i] = job.item()
+	}
+	m.jobSelector.SetItems(items)
+	if id := jobID(selected); id != 0 {
+		for _, item := range items {
+			if jobID(item) == id {
+				m.jobSelector.SelectItem(item)
+				break
+			}
+		}
+	}
+}
+
+// selectedJob returns the job selected in the jobs list
+func (m Model) selectedJob() *execJob {
+	id := jobID(m.jobSelector.GetSelected())
+	for _, job := range m.jobs {
+		if job.id == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// jobID parses the id of a jobs list entry, 0 if there is none
+func jobID(item string) int {
+	if !strings.HasPrefix(item, "#") {
+		return 0
+	}
+	end := strings.IndexByte(item, ' ')
+	if end < 0 {
+		return 0
+	}
+	id, _ := strconv.Atoi(item[1:end])
+	return id
+}
+
+// runningJobs counts the jobs that have not ended
+func (m Model) runningJobs() int {
+	n := 0
+	for _, job := range m.jobs {
+		if job.running() {
+			n++
+		}
+	}
+	return n
+}
+
+// jobKey handles the keys of the jobs list and the job output
+func (m Model) jobKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.StopJob):
+		job := m.viewedJob
+		if m.state == StateJobs {
+			job = m.selectedJob()
+		}
+		if job != nil {
+			job.stop()
+		}
+		return m, nil
+	case key.Matches(msg, keys.Back):
+		if m.state == StateJobOutput {
+			return m.showJobs()
+		}
+		m.state = StateSelectCommand
+		m.cmdSelector.Reset()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	if m.state == StateJobOutput {
+		m.jobView, cmd = m.jobView.Update(msg)
+		return m, cmd
+	}
+	if key.Matches(msg, keys.Select) {
+		if job := m.selectedJob(); job != nil {
+			return m.showJob(job)
+		}
+		return m, nil
+	}
+	m.jobSelector, cmd = m.jobSelector.Update(msg)
+	return m, cmd
+}
+
+// resizeJobView fits the job output below the header and title
+func (m *Model) resizeJobView() {
+	if m.width == 0 || m.height == 0 {
+		return
+	}
+	header := lipgloss.Height(m.header())
+	m.jobView.Width = max(m.width-4, 20)
+	m.jobView.Height = max(m.height-header-8, 5)
+}
+
+// jobsView renders the jobs list or the viewed job's output
+func (m Model) jobsView() string {
+	var b strings.Builder
+	if m.state == StateJobs {
+		if len(m.jobs) == 0 {
+			b.WriteString(InfoStyle.Render("No background jobs. Start one with the run-job command."))
+		} else {
+			b.WriteString(m.jobSelector.View())
+		}
+		b.WriteString("\n\n")
+		b.WriteString(RenderHelp("Enter: view output", "Ctrl+X: cancel", "Esc: back", "jobs stop when khelper quits"))
+		return b.String()
+	}
+
+	job := m.viewedJob
+	b.WriteString(LabelStyle.Render(fmt.Sprintf("Job #%d: ", job.id)))
+	b.WriteString(ValueStyle.Render(fmt.Sprintf("%s in %s/%s", job.command, job.pod, job.container)))
+	b.WriteString("\n")
+	status := job.status()
+	switch {
+	case job.running():
+		b.WriteString(RenderLoading(status))
+	case strings.HasPrefix(status, "done"):
+		b.WriteString(SuccessStyle.Render("✓ " + status))
+	default:
+		b.WriteString(ErrorStyle.Render("✗ " + status))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(m.jobView.View())
+	b.WriteString("\n\n")
+	b.WriteString(RenderHelp("↑↓/PgUp/PgDn: scroll", "Ctrl+X: cancel", "Esc: jobs (keeps running)"))
+	return b.String()
+}
@@ -0,0 +1,462 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// KeyMap holds the key bindings of the TUI. The handlers match keys against
+// it and the ? overlay lists it, so the help follows the bindings.
+type KeyMap struct {
+	// Everywhere
+	Quit       key.Binding
+	ForceQuit  key.Binding // where q is taken
+	Help       key.Binding
+	Back       key.Binding
+	BackEmpty  key.Binding // backspace, only on an empty filter
+	Select     key.Binding
+	KubeConfig key.Binding
+	Namespace  key.Binding
+	QuickOpen  key.Binding
+
+	// Lists
+	Up               key.Binding
+	Down             key.Binding
+	PageUp           key.Binding
+	PageDown         key.Binding
+	Mark             key.Binding
+	MarkAll          key.Binding
+	Favorite         key.Binding
+	DefaultContainer key.Binding
+	Overview         key.Binding
+	PruneStale       key.Binding
+	ShellPane        key.Binding
+	RepeatDeploy     key.Binding
+	Reload           key.Binding
+	PreviousImage    key.Binding
+	Complete         key.Binding
+	ShowHidden       key.Binding
+	ParentDir        key.Binding
+	Download         key.Binding
+
+	// Scrolling the result and job output viewports
+	Scroll viewport.KeyMap
+
+	// Running commands and their results
+	Cancel           key.Binding
+	Refresh          key.Binding
+	StopWaiting      key.Binding
+	Confirm          key.Binding
+	Details          key.Binding
+	Search           key.Binding
+	NextMatch        key.Binding
+	PrevMatch        key.Binding
+	Top              key.Binding
+	Bottom           key.Binding
+	Wrap             key.Binding
+	Copy             key.Binding
+	Save             key.Binding
+	ManagedFields    key.Binding
+	UndoResult       key.Binding
+	RemoveDebug      key.Binding
+	DebugShell       key.Binding
+	DeleteOneOff     key.Binding
+	OneOffLogs       key.Binding
+	EditPatch        key.Binding
+	Overlay          key.Binding
+	CreatePullSecret key.Binding
+	RollbackRename   key.Binding
+
+	// Resources explorer
+	DeleteResource key.Binding
+	ManifestBack   key.Binding
+
+	// Log viewer
+	LogUp            key.Binding
+	LogDown          key.Binding
+	LogPageUp        key.Binding
+	LogPageDown      key.Binding
+	LogTop           key.Binding
+	LogBottom        key.Binding
+	LogSearch        key.Binding
+	LogToggleSearch  key.Binding
+	LogEndSearch     key.Binding
+	LogClearSearch   key.Binding
+	LogFollow        key.Binding
+	LogOlder         key.Binding
+	LogWrap          key.Binding
+	LogLeft          key.Binding
+	LogRight         key.Binding
+	LogGrowList      key.Binding
+	LogShrinkList    key.Binding
+	LogToggleDetail  key.Binding
+	LogColors        key.Binding
+	LogPin           key.Binding
+	LogNextPin       key.Binding
+	LogPrevPin       key.Binding
+	LogExport        key.Binding
+	LogSave          key.Binding
+	LogContextAfter  key.Binding
+	LogContextBefore key.Binding
+	LogContext       key.Binding
+	LogAge           key.Binding
+	LogOrder         key.Binding
+	LogGroup         key.Binding
+	LogTimeFilter    key.Binding
+	LogTrace         key.Binding
+	LogPipe          key.Binding
+	LogBack          key.Binding
+
+	// Background jobs and the images table
+	StopJob     key.Binding
+	NextRow     key.Binding
+	PrevRow     key.Binding
+	ResetRow    key.Binding
+	ApplyImages key.Binding
+}
+
+// DefaultKeyMap returns the default key bindings
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Quit:       key.NewBinding(key.WithKeys("ctrl+c", "q"), key.WithHelp("Ctrl+C/q", "quit")),
+		ForceQuit:  key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("Ctrl+C", "quit")),
+		Help:       key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "show or hide this help")),
+		Back:       key.NewBinding(key.WithKeys("esc"), key.WithHelp("Esc", "go back")),
+		BackEmpty:  key.NewBinding(key.WithKeys("backspace"), key.WithHelp("Backspace", "go back when the filter is empty")),
+		Select:     key.NewBinding(key.WithKeys("enter", "tab"), key.WithHelp("Enter/Tab", "select")),
+		KubeConfig: key.NewBinding(key.WithKeys("ctrl+k"), key.WithHelp("Ctrl+K", "change kubeconfig")),
+		Namespace:  key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("Ctrl+N", "change namespace")),
+		QuickOpen:  key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("Ctrl+T", "open a deployment in any namespace")),
+
+		Up:               key.NewBinding(key.WithKeys("up", "ctrl+p"), key.WithHelp("↑/Ctrl+P", "move up")),
+		Down:             key.NewBinding(key.WithKeys("down", "ctrl+n"), key.WithHelp("↓", "move down")),
+		PageUp:           key.NewBinding(key.WithKeys("pgup"), key.WithHelp("PgUp", "page up")),
+		PageDown:         key.NewBinding(key.WithKeys("pgdown"), key.WithHelp("PgDn", "page down")),
+		Mark:             key.NewBinding(key.WithKeys(" "), key.WithHelp("Space", "mark or unmark")),
+		MarkAll:          key.NewBinding(key.WithKeys("ctrl+a"), key.WithHelp("Ctrl+A", "mark or unmark all")),
+		Favorite:         key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("Ctrl+F", "star or unstar")),
+		DefaultContainer: key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("Ctrl+D", "make the container the default")),
+		Overview:         key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("Ctrl+O", "namespace overview")),
+		PruneStale:       key.NewBinding(key.WithKeys("ctrl+x"), key.WithHelp("Ctrl+X", "prune stale recents")),
+		ShellPane:        key.NewBinding(key.WithKeys("ctrl+]"), key.WithHelp("Ctrl+]", "switch between the shell pane and the commands")),
+		RepeatDeploy:     key.NewBinding(key.WithKeys("ctrl+g"), key.WithHelp("Ctrl+G", "repeat the last fast-deploy")),
+		Reload:           key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("Ctrl+R", "reload")),
+		PreviousImage:    key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "fill in a previous image, Alt+1-9 deploys one")),
+		Complete:         key.NewBinding(key.WithKeys("tab"), key.WithHelp("Tab", "complete env keys, images and paths")),
+		ShowHidden:       key.NewBinding(key.WithKeys("ctrl+a"), key.WithHelp("Ctrl+A", "show or hide hidden directories")),
+		ParentDir:        key.NewBinding(key.WithKeys("backspace"), key.WithHelp("Backspace", "go up a directory when the filter is empty")),
+		Download:         key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("Ctrl+S", "download the file to the export dir")),
+
+		Scroll: viewport.KeyMap{
+			Up:           key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "scroll up")),
+			Down:         key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "scroll down")),
+			PageUp:       key.NewBinding(key.WithKeys("pgup", "b"), key.WithHelp("PgUp/b", "page up")),
+			PageDown:     key.NewBinding(key.WithKeys("pgdown", " ", "f"), key.WithHelp("PgDn/f/Space", "page down")),
+			HalfPageUp:   key.NewBinding(key.WithKeys("u", "ctrl+u"), key.WithHelp("Ctrl+U/u", "half a page up")),
+			HalfPageDown: key.NewBinding(key.WithKeys("d", "ctrl+d"), key.WithHelp("Ctrl+D/d", "half a page down")),
+			Left:         key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/h", "scroll left")),
+			Right:        key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→/l", "scroll right")),
+		},
+
+		Cancel:           key.NewBinding(key.WithKeys("esc"), key.WithHelp("Esc", "cancel the command")),
+		Refresh:          key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+		StopWaiting:      key.NewBinding(key.WithKeys("esc", "c"), key.WithHelp("Esc/c", "stop waiting")),
+		Confirm:          key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm, any other key cancels")),
+		Details:          key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "show or hide the error details")),
+		Search:           key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+		NextMatch:        key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "next match")),
+		PrevMatch:        key.NewBinding(key.WithKeys("N"), key.WithHelp("N", "previous match")),
+		Top:              key.NewBinding(key.WithKeys("home", "g"), key.WithHelp("Home/g", "go to the top")),
+		Bottom:           key.NewBinding(key.WithKeys("end", "G"), key.WithHelp("End/G", "go to the bottom")),
+		Wrap:             key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "wrap or cut long lines")),
+		Copy:             key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy to the clipboard")),
+		Save:             key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "save to a file")),
+		ManagedFields:    key.NewBinding(key.WithKeys("M"), key.WithHelp("M", "show or hide managedFields")),
+		UndoResult:       key.NewBinding(key.WithKeys("U"), key.WithHelp("U", "undo the change")),
+		RemoveDebug:      key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "remove the debug sidecar or copy")),
+		DebugShell:       key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "open a shell in the debug copy")),
+		DeleteOneOff:     key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete the experiment pod or Job")),
+		OneOffLogs:       key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "follow the experiment pod's or Job's logs again")),
+		EditPatch:        key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit the patch again")),
+		Overlay:          key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "show or hide the kustomize overlay")),
+		RollbackRename:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "roll back the steps of the rename done so far")),
+		CreatePullSecret: key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "create the pull secret from the stored registry logins")),
+
+		DeleteResource: key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "delete the resource, y confirms")),
+		ManifestBack:   key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("Esc/q", "back to the list")),
+
+		LogUp:            key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "previous line")),
+		LogDown:          key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "next line")),
+		LogPageUp:        key.NewBinding(key.WithKeys("pgup", "ctrl+u"), key.WithHelp("PgUp/Ctrl+U", "half a page up")),
+		LogPageDown:      key.NewBinding(key.WithKeys("pgdown", "ctrl+d"), key.WithHelp("PgDn/Ctrl+D", "half a page down")),
+		LogTop:           key.NewBinding(key.WithKeys("home", "g"), key.WithHelp("Home/g", "first line")),
+		LogBottom:        key.NewBinding(key.WithKeys("end", "G"), key.WithHelp("End/G", "last line")),
+		LogSearch:        key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+		LogToggleSearch:  key.NewBinding(key.WithKeys("tab"), key.WithHelp("Tab", "toggle search mode")),
+		LogEndSearch:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("Enter", "leave the search input")),
+		LogClearSearch:   key.NewBinding(key.WithKeys("ctrl+l"), key.WithHelp("Ctrl+L", "clear the search")),
+		LogFollow:        key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "follow or stop following")),
+		LogOlder:         key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "load older lines")),
+		LogWrap:          key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "cycle long lines: truncate, wrap, scroll")),
+		LogLeft:          key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/h", "scroll left")),
+		LogRight:         key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→/l", "scroll right")),
+		LogGrowList:      key.NewBinding(key.WithKeys("+", "="), key.WithHelp("+", "more room for the list")),
+		LogShrinkList:    key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "more room for the full entry")),
+		LogToggleDetail:  key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "hide or show the full entry")),
+		LogColors:        key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "show or strip the lines' own colors")),
+		LogPin:           key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "pin or unpin the line")),
+		LogNextPin:       key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "next pinned line")),
+		LogPrevPin:       key.NewBinding(key.WithKeys("["), key.WithHelp("[", "previous pinned line")),
+		LogExport:        key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "export pinned lines with context")),
+		LogSave:          key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "save all loaded lines")),
+		LogContextAfter:  key.NewBinding(key.WithKeys("A"), key.WithHelp("A", "context lines after matches")),
+		LogContextBefore: key.NewBinding(key.WithKeys("B"), key.WithHelp("B", "context lines before matches")),
+		LogContext:       key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "context lines around matches")),
+		LogAge:           key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "show or hide the age of lines")),
+		LogOrder:         key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "order by timestamp")),
+		LogGroup:         key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "group by pod or object")),
+		LogTimeFilter:    key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "filter by time of day")),
+		LogTrace:         key.NewBinding(key.WithKeys("O"), key.WithHelp("O", "open the line's trace")),
+		LogPipe:          key.NewBinding(key.WithKeys("|"), key.WithHelp("|", "pipe lines to a command, again to stop")),
+		LogBack:          key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("Esc/q", "back to the commands")),
+
+		StopJob:     key.NewBinding(key.WithKeys("ctrl+x"), key.WithHelp("Ctrl+X", "cancel the job")),
+		NextRow:     key.NewBinding(key.WithKeys("down", "tab"), key.WithHelp("↓/Tab", "next container")),
+		PrevRow:     key.NewBinding(key.WithKeys("up", "shift+tab"), key.WithHelp("↑/Shift+Tab", "previous container")),
+		ResetRow:    key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("Ctrl+R", "reset the image")),
+		ApplyImages: key.NewBinding(key.WithKeys("enter"), key.WithHelp("Enter", "apply the changed images")),
+	}
+}
+
+// keys are the bindings in use
+var keys = DefaultKeyMap()
+
+// helpSection is a titled group of bindings in the ? overlay
+type helpSection struct {
+	title    string
+	bindings []key.Binding
+}
+
+// helpSections returns the bindings available in the current state, those
+// of the screen first and the global ones last
+func (m Model) helpSections() []helpSection {
+	var sections []helpSection
+	add := func(title string, bindings ...key.Binding) {
+		sections = append(sections, helpSection{title: title, bindings: bindings})
+	}
+	list := []key.Binding{keys.Up, keys.Down, keys.PageUp, keys.PageDown, keys.Select, keys.Back, keys.BackEmpty}
+
+	switch m.state {
+	case StateSelectKubeConfig, StateSelectNamespace, StateSelectDeployment, StateSelectCommand:
+		screen := append(list, keys.Favorite)
+		switch m.state {
+		case StateSelectNamespace:
+			screen = append(screen, keys.PruneStale)
+		case StateSelectDeployment:
+			screen = append(screen, keys.Overview, keys.PruneStale)
+		case StateSelectCommand:
+			if m.shell != nil {
+				screen = append(screen, keys.ShellPane)
+			}
+			if _, ok := m.lastFastDeploy(); ok {
+				screen = append(screen, keys.RepeatDeploy)
+			}
+		}
+		add("List", screen...)
+	case StateSelectContainer:
+		add("List", append(list, keys.DefaultContainer)...)
+	case StateSelectPod:
+		if m.command != nil && m.command.ComparesPods {
+			list = append(list, keys.Mark)
+		}
+		add("List", list...)
+	case StateSelectCleanup:
+		add("List", append(list, keys.Mark, keys.MarkAll)...)
+	case StateQuickOpen:
+		add("Quick-open", append(list, keys.Reload)...)
+	case StateResumePrompt, StateSelectConfig, StateSelectAssetFolder:
+		add("List", list...)
+	case StateBrowseFiles:
+		add("Files", keys.Up, keys.Down, keys.PageUp, keys.PageDown, keys.Select, keys.Back, keys.ParentDir, keys.Download)
+	case StateSelectLocalPath:
+		add("Directories", keys.Up, keys.Down, keys.PageUp, keys.PageDown, keys.Select, keys.Back, keys.ParentDir, keys.ShowHidden)
+	case StateInputValue:
+		input := []key.Binding{keys.Select, keys.Complete, keys.Back, keys.BackEmpty}
+		if m.command != nil && m.command.Name == "update-image" && len(m.previousImages) > 0 {
+			input = append(input, keys.PreviousImage)
+		}
+		add("Input", input...)
+	case StateExecuting:
+		add("Running", keys.Cancel)
+	case StateNamespaceOverview:
+		add("Overview", keys.Refresh)
+	case StateShowResult:
+		add("Result", m.resultBindings()...)
+	case StateViewLogs:
+		add("Moving", keys.LogUp, keys.LogDown, keys.LogPageUp, keys.LogPageDown, keys.LogTop, keys.LogBottom,
+			keys.LogWrap, keys.LogLeft, keys.LogRight, keys.LogGrowList, keys.LogShrinkList, keys.LogToggleDetail)
+		add("Searching", keys.LogSearch, keys.LogToggleSearch, keys.LogEndSearch, keys.LogClearSearch,
+			keys.LogContextAfter, keys.LogContextBefore, keys.LogContext, keys.LogTimeFilter)
+		add("Logs", keys.LogFollow, keys.LogOlder, keys.LogPin, keys.LogNextPin, keys.LogPrevPin, keys.LogExport,
+			keys.LogSave, keys.LogColors, keys.LogAge, keys.LogOrder, keys.LogGroup, keys.LogTrace, keys.LogPipe, keys.LogBack)
+	case StateJobs:
+		add("Jobs", keys.Up, keys.Down, keys.Select, keys.StopJob, keys.Back)
+	case StateJobOutput:
+		add("Job output", keys.Scroll.Up, keys.Scroll.Down, keys.Scroll.PageUp, keys.Scroll.PageDown, keys.StopJob, keys.Back)
+	case StateEditImages:
+		add("Images", keys.NextRow, keys.PrevRow, keys.ResetRow, keys.ApplyImages, keys.Back)
+	case StateBrowseResources:
+		add("Resources", m.resources.bindings()...)
+	}
+
+	global := []key.Binding{keys.Help}
+	switch {
+	case m.state == StateNamespaceOverview:
+		global = append(global, keys.Quit)
+	case m.ownsKeys():
+		global = append(global, keys.ForceQuit)
+	default:
+		if !m.inCluster {
+			global = append(global, keys.KubeConfig)
+		}
+		global = append(global, keys.Namespace)
+		if m.canQuickOpen() {
+			global = append(global, keys.QuickOpen)
+		}
+		global = append(global, keys.Quit)
+	}
+	add("Everywhere", global...)
+	return sections
+}
+
+// ownsKeys reports whether the screen takes every key but Ctrl+C, so the
+// global shortcuts do not apply
+func (m Model) ownsKeys() bool {
+	switch m.state {
+	case StateViewLogs, StateJobs, StateJobOutput, StateEditImages, StateBrowseResources:
+		return true
+	case StateShowResult:
+		return m.pendingUndo != nil || m.pendingCleanup != nil || m.pendingLint != nil || m.pendingPatch != nil || m.pendingRename != nil || m.pendingConfigRestore != nil || m.pendingOnline != nil || m.rolloutWait != nil || m.scheduledScale != nil
+	}
+	return false
+}
+
+// resultBindings are the keys of the result screen: a confirmation, a
+// rollout wait, an error or the result viewer with the extras of the command
+func (m Model) resultBindings() []key.Binding {
+	switch {
+	case m.pendingUndo != nil || m.pendingCleanup != nil || m.pendingLint != nil || m.pendingConfigRestore != nil || m.pendingOnline != nil:
+		return []key.Binding{keys.Confirm}
+	case m.pendingPatch != nil:
+		return []key.Binding{keys.Confirm, keys.EditPatch, keys.Scroll.Up, keys.Scroll.Down, keys.Scroll.PageUp, keys.Scroll.PageDown}
+	case m.pendingRename != nil:
+		return []key.Binding{keys.Confirm, keys.RollbackRename, keys.Scroll.Up, keys.Scroll.Down, keys.Scroll.PageUp, keys.Scroll.PageDown}
+	case m.rolloutWait != nil || m.scheduledScale != nil:
+		return []key.Binding{keys.StopWaiting}
+	case m.err != nil:
+		bindings := []key.Binding{keys.Back}
+		if hasErrorDetails(m.err) {
+			bindings = append(bindings, keys.Details)
+		}
+		return bindings
+	}
+	bindings := []key.Binding{keys.Scroll.Up, keys.Scroll.Down, keys.Scroll.PageUp, keys.Scroll.PageDown, keys.Top, keys.Bottom}
+	if m.resultViewer.noWrap {
+		bindings = append(bindings, keys.Scroll.Left, keys.Scroll.Right)
+	}
+	bindings = append(bindings, keys.Search, keys.NextMatch, keys.PrevMatch, keys.Wrap, keys.Copy, keys.Save)
+	if m.isManifestCommand() {
+		bindings = append(bindings, keys.ManagedFields)
+	}
+	if m.canShowOverlay() {
+		bindings = append(bindings, keys.Overlay)
+	}
+	if m.canRestoreConfig() || m.canUndoScale() {
+		bindings = append(bindings, keys.UndoResult)
+	}
+	if m.canRemoveDebug() {
+		bindings = append(bindings, keys.RemoveDebug)
+		if m.debugSidecar.pod != "" {
+			bindings = append(bindings, keys.DebugShell)
+		}
+	}
+	if m.canDeleteOneOff() {
+		bindings = append(bindings, keys.DeleteOneOff, keys.OneOffLogs)
+	}
+	if m.canCreatePullSecret() {
+		bindings = append(bindings, keys.CreatePullSecret)
+	}
+	return append(bindings, keys.Back)
+}
+
+// canShowHelp reports whether ? opens the help instead of being typed:
+// not into a filter or input holding text, a search or the shell pane
+func (m Model) canShowHelp() bool {
+	switch m.state {
+	case StateShellPane, StateEditImages:
+		return false
+	case StateBrowseResources:
+		return m.resources.canShowHelp()
+	case StateViewLogs:
+		return !m.logViewer.IsFocused()
+	case StateShowResult:
+		return !m.resultViewer.IsSearching()
+	case StateInputValue:
+		return m.valueInput.Value() == ""
+	}
+	return m.inputEmpty()
+}
+
+// helpKey closes the help on any key but quit
+func (m Model) helpKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	m.showHelp = false
+	return m, nil
+}
+
+// helpView renders the help overlay: the bindings of the current state in
+// aligned columns under their section titles
+func (m Model) helpView() string {
+	sections := m.helpSections()
+	width := 0
+	for _, section := range sections {
+		for _, b := range section.bindings {
+			if b.Enabled() {
+				width = max(width, len([]rune(b.Help().Key)))
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(TitleStyle.Render("Keyboard shortcuts"))
+	b.WriteString("\n")
+	for _, section := range sections {
+		b.WriteString("\n")
+		b.WriteString(LabelStyle.Render(section.title))
+		b.WriteString("\n")
+		for _, binding := range section.bindings {
+			if !binding.Enabled() {
+				continue
+			}
+			help := binding.Help()
+			b.WriteString(fmt.Sprintf("  %s  %s\n", CommandStyle.Render(padRight(help.Key, width)), help.Desc))
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(InfoStyle.Render("Press any key to close"))
+	return b.String()
+}
+
+// padRight pads s with spaces to width runes
+func padRight(s string, width int) string {
+	if n := width - len([]rune(s)); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// kustomizeResult is the result of a deployment built by kustomize, which K
+// shows as a kustomize overlay instead
+type kustomizeResult struct {
+	namespace, deployment, command string
+	origin                         k8s.KustomizeOrigin
+	// other is the kubeconfig of the cluster compared with, which gets an
+	// overlay too
+	other string
+	// shown is set while the overlay replaces result
+	shown  bool
+	result string
+}
+
+// overlayLoadedMsg carries the overlays of a kustomize result
+type overlayLoadedMsg struct {
+	overlay string
+	err     error
+}
+
+// canShowOverlay reports whether the result offers its kustomize overlay
+func (m Model) canShowOverlay() bool {
+	return m.kustomize != nil && m.command != nil && m.command.Name == m.kustomize.command &&
+		m.kustomize.namespace == m.namespace && m.kustomize.deployment == m.deployment
+}
+
+// newKustomizeResult keeps the kustomize origin of a deployment shown by a
+// command, nil if kustomize did not build it
+func newKustomizeResult(namespace, command string, deployment *appsv1.Deployment, other string) *kustomizeResult {
+	origin := k8s.DetectKustomize(deployment.ObjectMeta)
+	if origin == nil {
+		return nil
+	}
+	return &kustomizeResult{namespace: namespace, deployment: deployment.Name, command: command, origin: *origin, other: other}
+}
+
+// toggleOverlay shows the result as kustomize overlays, or the result again
+func (m Model) toggleOverlay() (tea.Model, tea.Cmd) {
+	target := *m.kustomize
+	if !target.shown {
+		m.startExecution()
+		return m, m.whileExecuting(m.loadOverlay(target))
+	}
+	target.shown = false
+	m.kustomize = &target
+	m.result = target.result
+	m.resultViewer.SetHighlighter(nil)
+	if m.isManifestCommand() {
+		m.resultViewer.SetHighlighter(highlightYAML)
+	}
+	m.resultViewer.SetSource(m.resultSource())
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// loadOverlay writes the deployment as an overlay on its base in the
+// repository checkout, and the one of the compared cluster if any
+func (m *Model) loadOverlay(target kustomizeResult) tea.Cmd {
+	ctx := m.exec.context()
+	repo := m.config.GetKustomizeRepo()
+	current := m.k8sClient.GetKubeConfigPath()
+	return func() tea.Msg {
+		deployment, err := m.k8sClient.GetDeployment(ctx, target.namespace, target.deployment)
+		if err != nil {
+			return overlayLoadedMsg{err: err}
+		}
+		base, err := KustomizeBase(repo, deployment)
+		if err != nil {
+			return overlayLoadedMsg{err: err}
+		}
+		resources := filepath.ToSlash(filepath.Dir(target.origin.Path))
+		overlay, err := DeploymentOverlay(ctx, m.k8sClient, base, deployment, resources)
+		if err != nil {
+			return overlayLoadedMsg{err: err}
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "# %s as a kustomize overlay on %s\n", target.deployment, base)
+		fmt.Fprintf(&b, "# Origin: %s\n", target.origin)
+		b.WriteString("# The resources path is relative to the repository root, khelper overlay -o writes it\n# relative to the overlay.\n")
+		if target.other == "" {
+			b.WriteString(overlay.String())
+			return overlayLoadedMsg{overlay: b.String()}
+		}
+
+		other, err := k8s.NewClientWithOptions(m.clientOptions(target.other))
+		if err != nil {
+			return overlayLoadedMsg{err: fmt.Errorf("failed to load kubeconfig %s: %w", target.other, err)}
+		}
+		theirs, err := other.GetDeployment(ctx, target.namespace, target.deployment)
+		if err != nil {
+			return overlayLoadedMsg{err: fmt.Errorf("%s/%s in %s: %w", target.namespace, target.deployment, target.other, err)}
+		}
+		otherOverlay, err := DeploymentOverlay(ctx, other, base, theirs, resources)
+		if err != nil {
+			return overlayLoadedMsg{err: err}
+		}
+		nameA, nameB := kubeConfigLabel(current, target.other)
+		fmt.Fprintf(&b, "\n# Overlay for %s\n%s---\n# Overlay for %s\n%s", nameA, overlay, nameB, otherOverlay)
+		return overlayLoadedMsg{overlay: b.String()}
+	}
+}
+
+func (m Model) handleOverlayLoaded(msg overlayLoadedMsg) (tea.Model, tea.Cmd) {
+	m.state = StateShowResult
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+	target := *m.kustomize
+	target.shown, target.result = true, m.result
+	m.kustomize = &target
+	m.result = msg.overlay
+	m.resultViewer.SetHighlighter(highlightYAML)
+	m.resultViewer.SetSource("overlay-"+m.deployment, ".yaml")
+	m.resultViewer.SetContent(msg.overlay)
+	return m, nil
+}
+
+// KustomizeBase finds the file kustomize built a deployment from in the
+// checkout of the GitOps repository, from its origin annotation
+func KustomizeBase(repo string, deployment *appsv1.Deployment) (string, error) {
+	origin := k8s.DetectKustomize(deployment.ObjectMeta)
+	switch {
+	case origin == nil:
+		return "", fmt.Errorf("%s has no kustomize labels or annotations", deployment.Name)
+	case origin.Path == "":
+		return "", fmt.Errorf("%s does not tell its base file: build it with buildMetadata: [originAnnotations] in the kustomization, or give the base with khelper overlay --base", deployment.Name)
+	case repo == "":
+		return "", fmt.Errorf("the base of %s is %s: set kustomize.repo_dir in the config to the checkout of the GitOps repository", deployment.Name, origin)
+	}
+	base := filepath.Join(repo, filepath.FromSlash(origin.Path))
+	if _, err := os.Stat(base); err != nil {
+		return "", fmt.Errorf("the base of %s is not in %s: %w", deployment.Name, repo, err)
+	}
+	return base, nil
+}
+
+// DeploymentOverlay writes a deployment as a kustomize overlay on the
+// deployment of the same name in a base file. resources is the path of the
+// base the kustomization lists.
+func DeploymentOverlay(ctx context.Context, client k8s.Workloads, baseFile string, deployment *appsv1.Deployment, resources string) (k8s.Overlay, error) {
+	base, err := k8s.ReadBaseDeployment(baseFile, deployment.Name)
+	if err != nil {
+		return k8s.Overlay{}, err
+	}
+	base = client.DefaultDeployment(ctx, deployment.Namespace, base)
+	return k8s.KustomizeOverlay(base, deployment, resources)
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// lintedMsg carries the lint findings of a change waiting to be applied
+type lintedMsg struct {
+	findings []k8s.LintFinding
+	blocked  bool
+	apply    tea.Cmd
+	err      error
+}
+
+// LintGate keeps the findings at or above the confirm threshold and reports
+// whether one reaches the block threshold
+func LintGate(findings []k8s.LintFinding, t config.LintThresholds) ([]k8s.LintFinding, bool, error) {
+	confirm, err := k8s.ParseSeverity(t.Confirm, k8s.SeverityWarning)
+	if err != nil {
+		return nil, false, fmt.Errorf("lint confirm: %w", err)
+	}
+	block, err := k8s.ParseSeverity(t.Block, k8s.SeverityOff)
+	if err != nil {
+		return nil, false, fmt.Errorf("lint block: %w", err)
+	}
+
+	var shown []k8s.LintFinding
+	blocked := false
+	for _, f := range findings {
+		if f.Severity >= block {
+			blocked = true
+		}
+		if f.Severity >= confirm || f.Severity >= block {
+			shown = append(shown, f)
+		}
+	}
+	return shown, blocked, nil
+}
+
+// FormatLintFindings renders findings one per line
+func FormatLintFindings(findings []k8s.LintFinding) string {
+	lines := make([]string, len(findings))
+	for i, f := range findings {
+		lines[i] = "  " + f.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lintBefore lints the workload's template as edit leaves it, then runs apply
+// unless findings need confirmation or refuse the change
+func (m *Model) lintBefore(edit func(*corev1.PodTemplateSpec) error, apply tea.Cmd) tea.Cmd {
+	namespace, deployment := m.namespace, m.deployment
+	thresholds := m.config.GetLintThresholds(m.k8sClient.GetKubeConfigPath())
+	return func() tea.Msg {
+		findings, err := m.k8sClient.LintWorkload(m.exec.context(), namespace, deployment, edit)
+		if err != nil {
+			return lintedMsg{err: err}
+		}
+		shown, blocked, err := LintGate(findings, thresholds)
+		return lintedMsg{findings: shown, blocked: blocked, apply: apply, err: err}
+	}
+}
+
+// lintView renders the findings of a change waiting for confirmation
+func (m Model) lintView() string {
+	var b strings.Builder
+	b.WriteString(WarningStyle.Render(fmt.Sprintf("⚠ %s on %s: the template has lint findings", m.command.Name, m.deployment)))
+	b.WriteString("\n\n")
+	b.WriteString(FormatLintFindings(m.pendingLint.findings))
+	b.WriteString("\n\n")
+	b.WriteString(WarningStyle.Render("y: apply anyway • any other key: cancel"))
+	return b.String()
+}
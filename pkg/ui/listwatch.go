@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"context"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// listRefreshDelay is how long changes are collected before the shown list
+// is reloaded, so that a rollout reloads it a few times rather than for
+// every pod update
+const listRefreshDelay = 500 * time.Millisecond
+
+// listWatch watches what the shown pod or deployment list is made of
+type listWatch struct {
+	state   AppState
+	key     string // the namespace, and the deployment of pods
+	cancel  context.CancelFunc
+	ctx     context.Context
+	changes chan struct{}
+	errs    chan error
+}
+
+// listChangedMsg reports changes to what a watched list shows
+type listChangedMsg struct {
+	watch *listWatch
+}
+
+// listRefreshedMsg carries the reloaded items of a watched list
+type listRefreshedMsg struct {
+	watch *listWatch
+	items []string       // the deployments
+	pods  []k8s.PodEntry // or the pods
+	err   error
+}
+
+// listWatchKey identifies the list shown in a state
+func (m Model) listWatchKey(state AppState) string {
+	if state == StateSelectPod {
+		return m.namespace + "/" + m.deployment
+	}
+	return m.namespace
+}
+
+// watchList starts watching the shown pod or deployment list, unless it is
+// watched already
+func (m *Model) watchList() tea.Cmd {
+	if m.state != StateSelectPod && m.state != StateSelectDeployment {
+		return nil
+	}
+	key := m.listWatchKey(m.state)
+	if w := m.listWatch; w != nil {
+		if w.state == m.state && w.key == key {
+			return nil
+		}
+		w.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &listWatch{
+		state:   m.state,
+		key:     key,
+		cancel:  cancel,
+		ctx:     ctx,
+		changes: make(chan struct{}, 1),
+		errs:    make(chan error, 1),
+	}
+	m.listWatch = w
+	changed := func() {
+		select {
+		case w.changes <- struct{}{}:
+		default:
+		}
+	}
+	client, namespace, deployment := m.k8sClient, m.namespace, m.deployment
+	go func() {
+		if w.state == StateSelectPod {
+			w.errs <- client.WatchPods(ctx, namespace, deployment, changed)
+		} else {
+			w.errs <- client.WatchDeployments(ctx, namespace, changed)
+		}
+	}()
+	return waitListChange(w)
+}
+
+// stopListWatch stops watching the list once it is no longer shown
+func (m *Model) stopListWatch() {
+	w := m.listWatch
+	if w == nil || (w.state == m.state && w.key == m.listWatchKey(m.state)) {
+		return
+	}
+	w.cancel()
+	m.listWatch = nil
+}
+
+// waitListChange waits for changes to a watched list, collecting those that
+// follow within listRefreshDelay
+func waitListChange(w *listWatch) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-w.changes:
+		case err := <-w.errs:
+			// Without the watch, the list is simply no longer refreshed
+			if err != nil {
+				w.cancel()
+			}
+			return nil
+		case <-w.ctx.Done():
+			return nil
+		}
+		select {
+		case <-time.After(listRefreshDelay):
+		case <-w.ctx.Done():
+			return nil
+		}
+		select {
+		case <-w.changes:
+		default:
+		}
+		return listChangedMsg{watch: w}
+	}
+}
+
+// refreshList reloads the items of a watched list
+func (m *Model) refreshList(w *listWatch) tea.Cmd {
+	client, namespace, deployment := m.k8sClient, m.namespace, m.deployment
+	container, containerFirst := m.container, m.containerFirst
+	return func() tea.Msg {
+		msg := listRefreshedMsg{watch: w}
+		switch {
+		case w.state == StateSelectDeployment:
+			msg.items, msg.err = client.ListWorkloads(w.ctx, namespace)
+		case containerFirst && container != "":
+			msg.pods, msg.err = client.ListPodEntriesWithContainer(w.ctx, namespace, deployment, container)
+		default:
+			msg.pods, msg.err = client.ListPodEntries(w.ctx, namespace, deployment)
+		}
+		return msg
+	}
+}
+
+// handleListChanged reloads the watched list that changed
+func (m Model) handleListChanged(msg listChangedMsg) (tea.Model, tea.Cmd) {
+	if msg.watch != m.listWatch {
+		return m, nil
+	}
+	return m, m.refreshList(msg.watch)
+}
+
+// handleListRefreshed shows the reloaded items in place, keeping the query
+// and the cursor on the same item, and waits for the next changes
+func (m Model) handleListRefreshed(msg listRefreshedMsg) (tea.Model, tea.Cmd) {
+	if msg.watch != m.listWatch {
+		return m, nil
+	}
+	wait := waitListChange(msg.watch)
+	if msg.err != nil {
+		// The list stays as it is until the next change
+		return m, wait
+	}
+
+	if msg.watch.state == StateSelectDeployment {
+		match := m.depSelector.RefreshItems(listItems(msg.items))
+		return m, tea.Batch(wait, match, m.loadDeploymentHealth())
+	}
+	match := m.podSelector.RefreshItems(m.podListItems(msg.pods))
+	return m, tea.Batch(wait, match)
+}
@@ -0,0 +1,252 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logHistoryMsg carries a page of logs-history lines
+type logHistoryMsg struct {
+	query k8s.LogRangeQuery
+	lines []k8s.TimedLogLine
+	more  bool
+	older bool // the page goes above the loaded lines
+	err   error
+}
+
+// logHistoryData is what log backend queries are executed with
+type logHistoryData struct {
+	Namespace  string
+	Deployment string
+	Container  string
+	PodRegex   string // matches the names of the deployment's pods, deleted ones too
+}
+
+// runLogHistory loads the container's lines of all pods in the input's time
+// range into the log viewer, from the log backend if one is configured
+func (m Model) runLogHistory() (tea.Model, tea.Cmd) {
+	settings := m.config.GetLogBackend(m.k8sClient.GetKubeConfigPath())
+	start, end, err := parseLogHistoryRange(m.inputValue, time.Now(), settings.Range)
+	if err != nil {
+		return m, func() tea.Msg { return CommandResultMsg{err: err} }
+	}
+	prefixer, err := NewLogPrefixer(m.config.GetLogPrefix())
+	if err != nil {
+		return m, func() tea.Msg {
+			return CommandResultMsg{err: fmt.Errorf("invalid log prefix template: %w", err)}
+		}
+	}
+	m.logPrefixer = prefixer
+
+	namespace, deployment, container := m.namespace, m.deployment, m.container
+	client := m.k8sClient
+	return m, func() tea.Msg {
+		q, err := logHistoryQuery(context.Background(), client, settings, namespace, deployment, container)
+		if err != nil {
+			return logHistoryMsg{err: err}
+		}
+		q.Start, q.End = start, end
+		return queryLogHistory(client, q, false)
+	}
+}
+
+// logHistoryQuery prepares the query of a deployment's logs: the pods to
+// read without a log backend, else the backend's query and login
+func logHistoryQuery(ctx context.Context, client k8s.Pods, settings config.LogBackendSettings, namespace, deployment, container string) (k8s.LogRangeQuery, error) {
+	q := k8s.LogRangeQuery{
+		Backend:       settings.Type,
+		URL:           settings.URL,
+		Index:         settings.Index,
+		MessageField:  settings.MessageField,
+		PodField:      settings.PodField,
+		Namespace:     namespace,
+		ContainerName: container,
+		Limit:         settings.Limit,
+	}
+	if settings.Type == "" {
+		pods, err := client.ListPods(ctx, namespace, deployment)
+		if err != nil {
+			return q, err
+		}
+		for _, pod := range pods {
+			// Pending pods have no log yet
+			if pod.Status.Phase != corev1.PodPending && pod.Status.Phase != corev1.PodUnknown {
+				q.PodNames = append(q.PodNames, pod.Name)
+			}
+		}
+		if len(q.PodNames) == 0 {
+			return q, fmt.Errorf("no pods of %s with logs; configure log_backend in config.yml to read the logs of deleted pods", deployment)
+		}
+		return q, nil
+	}
+	if settings.URL == "" {
+		return q, fmt.Errorf("no %s URL: set log_backend.url in config.yml", settings.Type)
+	}
+
+	data := logHistoryData{Namespace: namespace, Deployment: deployment, Container: container}
+	if k8s.IsCustomWorkload(deployment) {
+		_, data.Deployment, _ = strings.Cut(deployment, "/")
+	}
+	// Pods are named after their workload with one or two generated parts,
+	// e.g. api-5d8f9c7b6-x7k2p
+	data.PodRegex = regexp.QuoteMeta(data.Deployment) + "-[a-z0-9]+(-[a-z0-9]+)?"
+	tmpl, err := template.New("log_backend").Option("missingkey=error").Parse(settings.Query)
+	if err != nil {
+		return q, fmt.Errorf("invalid log_backend.query: %w", err)
+	}
+	var query strings.Builder
+	if err := tmpl.Execute(&query, data); err != nil {
+		return q, fmt.Errorf("invalid log_backend.query: %w", err)
+	}
+	q.Query = query.String()
+
+	if !strings.HasPrefix(settings.URL, k8s.ServicePrefix) {
+		if creds, err := config.OpenCredentials(); err == nil {
+			q.Username, q.Password, _ = creds.Login(config.CredentialLogs, k8s.APIHost(settings.URL))
+		}
+	}
+	return q, nil
+}
+
+// queryLogHistory fetches a page of the query's range
+func queryLogHistory(client k8s.Telemetry, q k8s.LogRangeQuery, older bool) tea.Msg {
+	r, err := client.QueryLogRange(context.Background(), q)
+	if err != nil {
+		return logHistoryMsg{query: q, older: older, err: err}
+	}
+	return logHistoryMsg{query: q, lines: r.Lines, more: r.More, older: older}
+}
+
+// loadOlderHistory fetches the page of logs-history before the loaded
+// lines: the rest of the range if the limit cut it, else the range as long
+// again before it
+func (m *Model) loadOlderHistory() tea.Cmd {
+	q := m.logHistory
+	if !m.historyMore {
+		q.Start = m.logsOldest.Add(-q.End.Sub(q.Start))
+	}
+	q.End = m.logsOldest
+	client := m.k8sClient
+	return func() tea.Msg {
+		return queryLogHistory(client, q, true)
+	}
+}
+
+// handleLogHistory shows a page of logs-history, the first in a new log
+// viewer and older ones above the loaded lines
+func (m Model) handleLogHistory(msg logHistoryMsg) (tea.Model, tea.Cmd) {
+	if msg.older {
+		m.loadingOlder = false
+		if m.state != StateViewLogs {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.logViewer.SetStatus("Loading older lines failed: " + msg.err.Error())
+			return m, nil
+		}
+	} else if msg.err != nil {
+		m.err = msg.err
+		m.state = StateShowResult
+		return m, nil
+	}
+
+	lines := make([]PrefixedLine, len(msg.lines))
+	for i, line := range msg.lines {
+		prefix, text := m.logPrefixer.Format(line.LogLine)
+		lines[i] = PrefixedLine{Prefix: prefix, Text: text, Color: m.logPrefixer.Color(line.PodName)}
+	}
+	if !msg.older {
+		m.logViewer = m.newLogViewer(m.deployment + "-" + m.container)
+		m.logViewer.SetLogs("")
+		m.logViewer.Focus()
+		// Following continues where the range ends
+		m.logsUntil = msg.query.End
+		m.state = StateViewLogs
+	}
+	m.logViewer.PrependPrefixedLogs(lines)
+	m.logHistory = msg.query
+	m.historyMore = msg.more
+	m.logsOldest = msg.query.Start
+	if msg.more && len(msg.lines) > 0 {
+		m.logsOldest = msg.lines[0].Time
+	}
+	// A backend keeps logs longer than pods live, so it may have more before
+	// an empty range
+	m.logViewer.SetHasOlder(msg.more || msg.query.Backend != "")
+	if len(msg.lines) == 0 {
+		m.logViewer.SetStatus(fmt.Sprintf("No lines from %s to %s", formatHistoryTime(msg.query.Start), formatHistoryTime(msg.query.End)))
+	}
+	return m, nil
+}
+
+// formatHistoryTime renders a range boundary, with the date unless it is
+// today
+func formatHistoryTime(t time.Time) string {
+	t = t.Local()
+	if t.Format(time.DateOnly) == time.Now().Format(time.DateOnly) {
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// parseLogHistoryRange parses the range of logs-history: empty for the last
+// def, a duration such as 6h or 2d back from now, or a time-of-day window
+// such as 14:00-15:00, optionally after a date like 2024-05-01. A window
+// without a date is the latest one that has begun.
+func parseLogHistoryRange(input string, now time.Time, def time.Duration) (time.Time, time.Time, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return now.Add(-def), now, nil
+	}
+	if days, ok := strings.CutSuffix(input, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), now, nil
+		}
+	}
+	if d, err := time.ParseDuration(input); err == nil && d > 0 {
+		return now.Add(-d), now, nil
+	}
+
+	day, dated := now.Local(), false
+	if date, rest, ok := strings.Cut(input, " "); ok {
+		t, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", date)
+		}
+		day, dated, input = t, true, rest
+	}
+	r, err := parseTimeRange(input)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range %q, use e.g. 6h, 2d, 14:00-15:00 or 2024-05-01 14:00-15:00", input)
+	}
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	start, end := midnight.Add(r.from), midnight.Add(r.to+time.Second)
+	switch {
+	case !r.hasFrom:
+		start = end.Add(-def)
+	case !r.hasTo:
+		end = midnight.AddDate(0, 0, 1)
+	case r.from > r.to:
+		// Wraps past midnight into the day
+		start = start.AddDate(0, 0, -1)
+	}
+	if !dated && start.After(now) {
+		start, end = start.AddDate(0, 0, -1), end.AddDate(0, 0, -1)
+	}
+	if end.After(now) || (!r.hasTo && !dated) {
+		end = now
+	}
+	return start, end, nil
+}
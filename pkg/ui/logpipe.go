@@ -0,0 +1,211 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pipeBacklog is how many lines wait for a slow pipe command before lines
+// are dropped, so that the log stream never waits for it
+const pipeBacklog = 10000
+
+// pipeStopTimeout is how long a pipe command may take to finish once its
+// input is closed before it is killed
+const pipeStopTimeout = 5 * time.Second
+
+// LogPipe tees log lines into the standard input of a shell command such as
+// jq or grep. Lines are sent from one goroutine; a command that stops
+// reading never blocks the sender.
+type LogPipe struct {
+	Command string
+
+	cmd      *exec.Cmd
+	lines    chan string
+	done     chan struct{}
+	err      error // how the command ended, set when done is closed
+	killed   atomic.Bool
+	closed   bool
+	partial  []byte // the unfinished line of Write
+	sent     atomic.Int64
+	dropped  atomic.Int64
+	lastLine lastLineWriter
+}
+
+// pipeEndedMsg reports that a log pipe's command has ended
+type pipeEndedMsg struct {
+	pipe *LogPipe
+}
+
+// lastLineWriter passes output on and remembers its last non-empty line,
+// which usually says why a command failed
+type lastLineWriter struct {
+	w    io.Writer
+	last string
+}
+
+func (w *lastLineWriter) Write(b []byte) (int, error) {
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.last = line
+		}
+	}
+	return w.w.Write(b)
+}
+
+// StartLogPipe starts a command with sh -c (cmd /C on Windows) that reads
+// log lines, writing its output to out
+func StartLogPipe(command string, out io.Writer) (*LogPipe, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	p := &LogPipe{
+		Command:  command,
+		cmd:      cmd,
+		lines:    make(chan string, pipeBacklog),
+		done:     make(chan struct{}),
+		lastLine: lastLineWriter{w: out},
+	}
+	// One writer for both, so exec writes to it from one goroutine only
+	cmd.Stdout, cmd.Stderr = &p.lastLine, &p.lastLine
+	// Children of a killed shell may hold its output open
+	cmd.WaitDelay = time.Second
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %q: %w", command, err)
+	}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+	go p.feed(stdin)
+	return p, nil
+}
+
+// feed writes the queued lines to the command until the pipe is closed.
+// Once the command stops reading, the rest are counted as dropped.
+func (p *LogPipe) feed(stdin io.WriteCloser) {
+	w := bufio.NewWriter(stdin)
+	var err error
+	for line := range p.lines {
+		if err != nil {
+			p.dropped.Add(1)
+			continue
+		}
+		if _, err = w.WriteString(line + "\n"); err == nil && len(p.lines) == 0 {
+			err = w.Flush()
+		}
+		if err == nil {
+			p.sent.Add(1)
+		} else {
+			p.dropped.Add(1)
+		}
+	}
+	if err == nil {
+		w.Flush()
+	}
+	stdin.Close()
+}
+
+// Send queues a line for the command, dropping it if the command is too
+// far behind
+func (p *LogPipe) Send(line string) {
+	if p.closed {
+		return
+	}
+	select {
+	case p.lines <- line:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// Write sends the lines of b, keeping an unfinished last line for the next
+// write, so the pipe can be the target of a log stream
+func (p *LogPipe) Write(b []byte) (int, error) {
+	p.partial = append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		p.Send(strings.TrimSuffix(string(p.partial[:i]), "\r"))
+		p.partial = p.partial[i+1:]
+	}
+	return len(b), nil
+}
+
+// Close ends the command's input, so that it finishes; it is killed if it
+// is still running after pipeStopTimeout. Done is closed once it ended.
+func (p *LogPipe) Close() {
+	if p.closed {
+		return
+	}
+	if len(p.partial) > 0 {
+		p.Send(string(p.partial))
+		p.partial = nil
+	}
+	p.closed = true
+	close(p.lines)
+	go func() {
+		select {
+		case <-p.done:
+		case <-time.After(pipeStopTimeout):
+			p.killed.Store(true)
+			p.cmd.Process.Kill()
+		}
+	}()
+}
+
+// Done is closed when the command has ended
+func (p *LogPipe) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns why the command failed once it ended, with the last line it
+// printed
+func (p *LogPipe) Err() error {
+	if p.err == nil {
+		return nil
+	}
+	err := p.err
+	if p.killed.Load() {
+		err = fmt.Errorf("still running %s after its input ended, killed", pipeStopTimeout)
+	} else if exit := (*exec.ExitError)(nil); errors.As(err, &exit) && p.lastLine.last != "" {
+		err = fmt.Errorf("%w: %s", err, p.lastLine.last)
+	}
+	return fmt.Errorf("pipe %q failed: %w", p.Command, err)
+}
+
+// Summary tells how many lines went to the command
+func (p *LogPipe) Summary() string {
+	s := fmt.Sprintf("%d lines piped to %q", p.sent.Load(), p.Command)
+	if dropped := p.dropped.Load(); dropped > 0 {
+		s += fmt.Sprintf(", %d dropped as it did not keep up or stopped reading", dropped)
+	}
+	return s
+}
+
+// waitPipe reports when a pipe's command ends, closing its output
+func waitPipe(p *LogPipe, out io.Closer) tea.Cmd {
+	return func() tea.Msg {
+		<-p.Done()
+		out.Close()
+		return pipeEndedMsg{pipe: p}
+	}
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"hash/fnv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+)
+
+// defaultLogPrefixTemplate matches stern's pod and container prefix
+const defaultLogPrefixTemplate = "{{.ShortPod}} {{.Container}} "
+
+// defaultPodColors is the palette pods are assigned colors from, the
+// terminal's standard and bright colors like stern
+var defaultPodColors = []string{"1", "2", "3", "4", "5", "6", "9", "10", "11", "12", "13", "14"}
+
+// logPrefixData is what the prefix template is executed with
+type logPrefixData struct {
+	Pod       string
+	Container string
+	ShortPod  string
+	Timestamp string
+	Time      time.Time
+}
+
+// LogPrefixer renders the prefix of lines in multi-pod log streams
+type LogPrefixer struct {
+	tmpl       *template.Template
+	timestamps bool
+	colors     []lipgloss.Color
+}
+
+// NewLogPrefixer creates a prefixer from the config settings
+func NewLogPrefixer(settings config.LogPrefix) (*LogPrefixer, error) {
+	text := settings.Template
+	if text == "" {
+		text = defaultLogPrefixTemplate
+	}
+	tmpl, err := template.New("log_prefix").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &LogPrefixer{
+		tmpl: tmpl,
+		// Only ask the API for timestamps when the template shows them
+		timestamps: strings.Contains(text, ".Time"),
+	}
+	if !settings.NoColor {
+		palette := settings.Colors
+		if len(palette) == 0 {
+			palette = defaultPodColors
+		}
+		for _, c := range palette {
+			p.colors = append(p.colors, lipgloss.Color(c))
+		}
+	}
+	return p, nil
+}
+
+// Timestamps returns whether lines should be requested with timestamps
+func (p *LogPrefixer) Timestamps() bool {
+	return p.timestamps
+}
+
+// Format splits a streamed line into its rendered prefix and text, removing
+// the API timestamp when the template uses it
+func (p *LogPrefixer) Format(line k8s.LogLine) (string, string) {
+	data := logPrefixData{
+		Pod:       line.PodName,
+		Container: line.ContainerName,
+		ShortPod:  shortPodName(line.PodName),
+	}
+	text := line.Text
+	if p.timestamps {
+		if stamp, rest, ok := strings.Cut(text, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+				data.Time = t.Local()
+				data.Timestamp = data.Time.Format("15:04:05")
+				text = rest
+			}
+		}
+	}
+
+	var prefix strings.Builder
+	if err := p.tmpl.Execute(&prefix, data); err != nil {
+		return "", text
+	}
+	return prefix.String(), text
+}
+
+// Color returns the pod's color, the same for a pod name on every run, or
+// nil when colors are disabled
+func (p *LogPrefixer) Color(podName string) lipgloss.TerminalColor {
+	if len(p.colors) == 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(podName))
+	return p.colors[h.Sum32()%uint32(len(p.colors))]
+}
+
+// shortPodName returns the random suffix that tells a deployment's pods
+// apart, e.g. "x7k2p" for "api-5d8f9c7b6-x7k2p"
+func shortPodName(podName string) string {
+	if i := strings.LastIndex(podName, "-"); i >= 0 && i < len(podName)-1 {
+		return podName[i+1:]
+	}
+	return podName
+}
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampSearchLen limits how far into a line we look for a timestamp
+const timestampSearchLen = 80
+
+var (
+	// 2024-01-02T15:04:05.123Z, 2024-01-02 15:04:05,123 +0100, ...
+	isoTimestampRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	// klog: I0102 15:04:05.123456
+	klogTimestampRe = regexp.MustCompile(`^[IWEF](\d{4}) (\d{2}:\d{2}:\d{2}(?:\.\d+)?)`)
+	// Common log format: 02/Jan/2006:15:04:05 -0700
+	clfTimestampRe = regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`)
+	// Syslog: Jan  2 15:04:05
+	syslogTimestampRe = regexp.MustCompile(`^[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}`)
+)
+
+// parseLogTimestamp detects a timestamp near the start of a log line.
+// Timestamps without a zone are taken as local time.
+func parseLogTimestamp(line string) (time.Time, bool) {
+	head := line
+	if len(head) > timestampSearchLen {
+		head = head[:timestampSearchLen]
+	}
+
+	if match := isoTimestampRe.FindString(head); match != "" {
+		if t, ok := parseISOTimestamp(match); ok {
+			return t, true
+		}
+	}
+
+	if m := klogTimestampRe.FindStringSubmatch(head); m != nil {
+		// klog omits the year
+		t, err := time.ParseInLocation("0102 15:04:05.999999", m[1]+" "+m[2], time.Local)
+		if err == nil {
+			return t.AddDate(time.Now().Year(), 0, 0), true
+		}
+	}
+
+	if match := clfTimestampRe.FindString(head); match != "" {
+		if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", match); err == nil {
+			return t, true
+		}
+	}
+
+	if match := syslogTimestampRe.FindString(head); match != "" {
+		if t, err := time.ParseInLocation(time.Stamp, match, time.Local); err == nil {
+			return t.AddDate(time.Now().Year(), 0, 0), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// parseISOTimestamp parses the RFC3339-like variants matched by isoTimestampRe
+func parseISOTimestamp(s string) (time.Time, bool) {
+	s = strings.Replace(s, ",", ".", 1)
+	s = strings.Replace(s, " ", "T", 1)
+
+	layouts := []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999999999Z0700",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	// No zone: local time
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", s, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// timeRange is a time-of-day window such as "14:02-14:07". Either end may
+// be open, and windows may wrap past midnight.
+type timeRange struct {
+	from, to       time.Duration // offset since midnight
+	hasFrom, hasTo bool
+	text           string
+}
+
+// parseTimeRange parses "HH:MM[:SS]-HH:MM[:SS]"; either side may be empty
+func parseTimeRange(s string) (timeRange, error) {
+	var r timeRange
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return r, fmt.Errorf("expected a range like 14:02-14:07")
+	}
+
+	var err error
+	if from = strings.TrimSpace(from); from != "" {
+		if r.from, err = parseTimeOfDay(from); err != nil {
+			return r, err
+		}
+		r.hasFrom = true
+	}
+	if to = strings.TrimSpace(to); to != "" {
+		if r.to, err = parseTimeOfDay(to); err != nil {
+			return r, err
+		}
+		// An end given without seconds includes that whole minute
+		if strings.Count(to, ":") == 1 {
+			r.to += 59 * time.Second
+		}
+		r.hasTo = true
+	}
+	if !r.hasFrom && !r.hasTo {
+		return r, fmt.Errorf("expected a range like 14:02-14:07")
+	}
+	r.text = strings.TrimSpace(s)
+	return r, nil
+}
+
+// parseTimeOfDay parses HH:MM or HH:MM:SS into an offset since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM or HH:MM:SS", s)
+	}
+	limits := []int{24, 60, 60}
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n >= limits[i] {
+			return 0, fmt.Errorf("invalid time %q, use HH:MM or HH:MM:SS", s)
+		}
+		d += time.Duration(n) * units[i]
+	}
+	return d, nil
+}
+
+// Contains reports whether t's local time of day falls within the range
+func (r timeRange) Contains(t time.Time) bool {
+	t = t.Local()
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+
+	afterFrom := !r.hasFrom || tod >= r.from
+	beforeTo := !r.hasTo || tod <= r.to
+	if r.hasFrom && r.hasTo && r.from > r.to {
+		// Wraps past midnight
+		return afterFrom || beforeTo
+	}
+	return afterFrom && beforeTo
+}
+
+// formatAge renders a duration as a compact age such as 42s, 5m, 3h or 2d
+func formatAge(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "0s"
+	case d < time.Minute:
+		return strconv.Itoa(int(d.Seconds())) + "s"
+	case d < time.Hour:
+		return strconv.Itoa(int(d.Minutes())) + "m"
+	case d < 48*time.Hour:
+		return strconv.Itoa(int(d.Hours())) + "h"
+	default:
+		return strconv.Itoa(int(d.Hours()/24)) + "d"
+	}
+}
@@ -1,14 +1,88 @@
 package ui
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+// WrapMode controls how long lines are shown in the log list
+type WrapMode int
+
+const (
+	// WrapTruncate cuts long lines at the pane width
+	WrapTruncate WrapMode = iota
+	// WrapSoft wraps long lines onto continuation rows
+	WrapSoft
+	// WrapScroll shows a horizontally scrollable window of each line
+	WrapScroll
 )
 
+// String returns the mode name shown in the status line
+func (w WrapMode) String() string {
+	switch w {
+	case WrapSoft:
+		return "wrap"
+	case WrapScroll:
+		return "scroll"
+	default:
+		return "truncate"
+	}
+}
+
+// hScrollStep is the number of columns moved per left/right key press
+const hScrollStep = 10
+
+// pinContextLines is the number of lines exported around each pinned line
+const pinContextLines = 3
+
+// contextSteps are the values the context line keys cycle through
+var contextSteps = []int{0, 1, 2, 3, 5, 10}
+
+// nextContextStep returns the context step after n, wrapping to 0
+func nextContextStep(n int) int {
+	for _, step := range contextSteps {
+		if step > n {
+			return step
+		}
+	}
+	return 0
+}
+
+// Bounds and step of the list's share of the log viewer's height, in percent
+const (
+	minLogSplit  = 20
+	maxLogSplit  = 90
+	logSplitStep = 10
+)
+
+// logSplitMsg reports a layout chosen in the log viewer, to remember it
+type logSplitMsg struct {
+	split config.LogSplit
+}
+
+// exportedMsg reports the result of writing an export file
+type exportedMsg struct {
+	path string
+	err  error
+}
+
 // LogViewer is an interactive log viewer with search and selection capability
 type LogViewer struct {
 	viewport       viewport.Model
@@ -16,6 +90,11 @@ type LogViewer struct {
 	searchInput    textinput.Model
 	allLines       []string
 	filteredLines  []string
+	filteredIdx    []int  // index in allLines of each filtered line
+	isContext      []bool // filtered line is shown as context, not a match
+	contextBefore  int
+	contextAfter   int
+	pins           map[int]bool
 	recentSearches []string
 	searchQuery    string
 	selectedIndex  int
@@ -25,6 +104,33 @@ type LogViewer struct {
 	height         int
 	streaming      bool
 	autoScroll     bool
+	wrapMode       WrapMode
+	hOffset        int
+	rowOffsets     []int // first viewport row of each filtered line, plus the total
+	source         string
+	exportDir      string // where exports are written, the export dir if empty
+	status         string
+	lineTimes      []time.Time // timestamp of each line, carried forward to continuation lines
+	ownTime        []bool      // the line has its own timestamp
+	prefixes       []linePrefix
+	hasOlder       bool // older lines can be loaded above the first one
+	showAge        bool
+	sortByTime     bool
+	groupByPrefix  bool // prefixed lines are grouped by prefix (pod, object)
+	timeInput      textinput.Model
+	timeFilter     *timeRange
+	pipeInput      textinput.Model
+	pipe           *LogPipe // tees the matching lines to a command while set
+	pipeOutput     string   // the file the pipe command writes to
+	split          config.LogSplit
+	colored        map[int]string // lines received with escape codes, by index in allLines
+	showColors     bool           // show the colored lines' own colors instead of stripping them
+}
+
+// linePrefix is the colored pod prefix of a line from a multi-pod stream
+type linePrefix struct {
+	length int
+	color  lipgloss.TerminalColor
 }
 
 // NewLogViewer creates a new log viewer component
@@ -39,14 +145,39 @@ func NewLogViewer() LogViewer {
 	ti.PlaceholderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
 	ti.Cursor.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#10B981"))
 
+	timeInput := textinput.New()
+	timeInput.Placeholder = "14:02-14:07"
+	timeInput.Prompt = "> "
+	timeInput.CharLimit = 20
+	timeInput.Width = 20
+	timeInput.PromptStyle = PromptStyle
+	timeInput.TextStyle = ti.TextStyle
+	timeInput.PlaceholderStyle = ti.PlaceholderStyle
+	timeInput.Cursor.Style = ti.Cursor.Style
+
+	pipeInput := textinput.New()
+	pipeInput.Placeholder = "jq -c 'select(.level == \"error\")'"
+	pipeInput.Prompt = "> "
+	pipeInput.CharLimit = 500
+	pipeInput.Width = 60
+	pipeInput.PromptStyle = PromptStyle
+	pipeInput.TextStyle = ti.TextStyle
+	pipeInput.PlaceholderStyle = ti.PlaceholderStyle
+	pipeInput.Cursor.Style = ti.Cursor.Style
+
 	return LogViewer{
 		searchInput:    ti,
+		timeInput:      timeInput,
+		pipeInput:      pipeInput,
 		allLines:       []string{},
 		filteredLines:  []string{},
+		pins:           make(map[int]bool),
+		colored:        make(map[int]string),
 		recentSearches: []string{},
 		showSearch:     true,
 		selectedIndex:  0,
 		autoScroll:     true,
+		split:          config.LogSplit{List: config.DefaultLogSplit},
 	}
 }
 
@@ -55,9 +186,13 @@ func (l *LogViewer) SetSize(width, height int) {
 	l.width = width
 	l.height = height
 
-	// Split: list takes 60%, detail takes 40% (minus headers)
-	listHeight := (height - 10) * 6 / 10
+	// Split between the list and the full entry (minus headers)
+	listHeight := (height - 10) * l.split.List / 100
 	detailHeight := (height - 10) - listHeight
+	if l.split.HideDetail {
+		// The list also takes the entry's title and border
+		listHeight = height - 10 + 3
+	}
 
 	if listHeight < 5 {
 		listHeight = 5
@@ -83,6 +218,37 @@ func (l *LogViewer) SetSize(width, height int) {
 	l.updateContent()
 }
 
+// SetSplit sets the share of the height the list takes and whether the full
+// entry is shown below it
+func (l *LogViewer) SetSplit(split config.LogSplit) {
+	split.List = min(max(split.List, minLogSplit), maxLogSplit)
+	l.split = split
+	if l.ready {
+		l.SetSize(l.width, l.height)
+	}
+}
+
+// resizeSplit grows the list by delta percent, showing the full entry
+// again if it was hidden
+func (l *LogViewer) resizeSplit(delta int) tea.Cmd {
+	l.SetSplit(config.LogSplit{List: l.split.List + delta})
+	l.status = fmt.Sprintf("List %d%% • entry %d%%", l.split.List, 100-l.split.List)
+	return l.splitChanged()
+}
+
+// toggleDetail hides or shows the full entry below the list
+func (l *LogViewer) toggleDetail() tea.Cmd {
+	l.SetSplit(config.LogSplit{List: l.split.List, HideDetail: !l.split.HideDetail})
+	return l.splitChanged()
+}
+
+func (l *LogViewer) splitChanged() tea.Cmd {
+	split := l.split
+	return func() tea.Msg {
+		return logSplitMsg{split: split}
+	}
+}
+
 // SetLogs sets the log content
 func (l *LogViewer) SetLogs(logs string) {
 	if logs == "" {
@@ -90,13 +256,64 @@ func (l *LogViewer) SetLogs(logs string) {
 	} else {
 		l.allLines = strings.Split(logs, "\n")
 	}
+	l.colored = make(map[int]string)
+	for i, line := range l.allLines {
+		l.allLines[i] = l.stripLine(i, line)
+	}
+	l.pins = make(map[int]bool)
+	l.prefixes = make([]linePrefix, len(l.allLines))
+	l.indexTimes(0)
 	l.filterLogs()
 }
 
+// indexTimes parses timestamps for lines from index start onwards. Lines
+// without a timestamp (e.g. stack traces) inherit the previous one.
+func (l *LogViewer) indexTimes(start int) {
+	l.lineTimes = l.lineTimes[:start]
+	l.ownTime = l.ownTime[:start]
+	var last time.Time
+	if start > 0 {
+		last = l.lineTimes[start-1]
+	}
+	for i, line := range l.allLines[start:] {
+		t, ok := parseLogTimestamp(line[l.prefixes[start+i].length:])
+		if ok {
+			last = t
+		}
+		l.lineTimes = append(l.lineTimes, last)
+		l.ownTime = append(l.ownTime, ok)
+	}
+}
+
+// SetSource sets a label for where the logs came from, used in export names
+func (l *LogViewer) SetSource(source string) {
+	l.source = source
+}
+
+// SetExportDir sets the directory pinned lines and saved logs are written to
+func (l *LogViewer) SetExportDir(dir string) {
+	l.exportDir = dir
+}
+
 // AppendLog appends a log line
 func (l *LogViewer) AppendLog(line string) {
-	l.allLines = append(l.allLines, line)
+	l.appendLine(line, linePrefix{})
+}
+
+// AppendPrefixedLog appends a line from a multi-pod stream, showing its
+// prefix in the given color (nil for none)
+func (l *LogViewer) AppendPrefixedLog(prefix, text string, color lipgloss.TerminalColor) {
+	l.appendLine(prefix+text, linePrefix{length: len(prefix), color: color})
+}
+
+func (l *LogViewer) appendLine(line string, prefix linePrefix) {
+	l.allLines = append(l.allLines, l.stripLine(len(l.allLines), line))
+	l.prefixes = append(l.prefixes, prefix)
+	l.indexTimes(len(l.allLines) - 1)
 	l.filterLogs()
+	if i := len(l.allLines) - 1; l.pipe != nil && l.matches(i, l.allLines[i], strings.ToLower(l.searchQuery)) {
+		l.pipe.Send(l.allLines[i][prefix.length:])
+	}
 
 	// Auto-scroll to bottom if enabled and at/near bottom
 	if l.autoScroll && l.streaming {
@@ -106,6 +323,133 @@ func (l *LogViewer) AppendLog(line string) {
 	}
 }
 
+// PrefixedLine is a line of a multi-pod log with its prefix
+type PrefixedLine struct {
+	Prefix string
+	Text   string
+	Color  lipgloss.TerminalColor // nil for none
+}
+
+// PrependLogs inserts older lines before the current ones, keeping pins and
+// the selection on the same lines
+func (l *LogViewer) PrependLogs(lines []string) {
+	l.prependLines(lines, make([]linePrefix, len(lines)))
+}
+
+// PrependPrefixedLogs inserts older lines of a multi-pod log, like
+// PrependLogs
+func (l *LogViewer) PrependPrefixedLogs(lines []PrefixedLine) {
+	texts := make([]string, len(lines))
+	prefixes := make([]linePrefix, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Prefix + line.Text
+		prefixes[i] = linePrefix{length: len(line.Prefix), color: line.Color}
+	}
+	l.prependLines(texts, prefixes)
+}
+
+func (l *LogViewer) prependLines(lines []string, prefixes []linePrefix) {
+	n := len(lines)
+	if n == 0 {
+		return
+	}
+
+	selected := -1
+	if l.selectedIndex < len(l.filteredIdx) {
+		selected = l.filteredIdx[l.selectedIndex] + n
+	}
+
+	l.allLines = append(append(make([]string, 0, len(l.allLines)+n), lines...), l.allLines...)
+	l.prefixes = append(append(make([]linePrefix, 0, len(l.prefixes)+n), prefixes...), l.prefixes...)
+	pins := make(map[int]bool, len(l.pins))
+	for i := range l.pins {
+		pins[i+n] = true
+	}
+	l.pins = pins
+	colored := make(map[int]string, len(l.colored))
+	for i, line := range l.colored {
+		colored[i+n] = line
+	}
+	l.colored = colored
+	for i := range lines {
+		l.allLines[i] = l.stripLine(i, l.allLines[i])
+	}
+	l.indexTimes(0)
+
+	l.filterLogs()
+	for i, idx := range l.filteredIdx {
+		if idx == selected {
+			l.selectedIndex = i
+			break
+		}
+	}
+	l.updateContent()
+}
+
+// stripLine returns line i without escape codes, keeping the line as
+// received if it had any
+func (l *LogViewer) stripLine(i int, line string) string {
+	if !strings.Contains(line, "\x1b") {
+		return line
+	}
+	l.colored[i] = line
+	return stripANSI(line)
+}
+
+// SetColors sets whether lines are shown in their own ANSI colors
+func (l *LogViewer) SetColors(show bool) {
+	l.showColors = show
+}
+
+// coloredLine returns filtered line i as received if it is shown in its
+// own colors
+func (l *LogViewer) coloredLine(i int) (string, bool) {
+	if !l.showColors || l.isContext[i] {
+		return "", false
+	}
+	line, ok := l.colored[l.filteredIdx[i]]
+	return line, ok
+}
+
+// SetHasOlder sets whether older lines can be loaded
+func (l *LogViewer) SetHasOlder(hasOlder bool) {
+	l.hasOlder = hasOlder
+	l.updateContent()
+}
+
+// HasOlder returns whether older lines can be loaded
+func (l *LogViewer) HasOlder() bool {
+	return l.hasOlder
+}
+
+// LineCount returns the number of lines held, before filtering
+func (l *LogViewer) LineCount() int {
+	return len(l.allLines)
+}
+
+// SelectedLine returns the selected line, empty if there is none
+func (l *LogViewer) SelectedLine() string {
+	if l.selectedIndex < len(l.filteredLines) {
+		return l.filteredLines[l.selectedIndex]
+	}
+	return ""
+}
+
+// hasPrefixes reports whether any line came with a prefix
+func (l *LogViewer) hasPrefixes() bool {
+	for _, p := range l.prefixes {
+		if p.length > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStatus shows a message in the stats line until the next key press
+func (l *LogViewer) SetStatus(status string) {
+	l.status = status
+}
+
 // SetStreaming sets streaming mode
 func (l *LogViewer) SetStreaming(streaming bool) {
 	l.streaming = streaming
@@ -131,17 +475,45 @@ func (l *LogViewer) filterLogs() {
 	query := strings.ToLower(l.searchInput.Value())
 	l.searchQuery = l.searchInput.Value()
 
-	if query == "" {
-		l.filteredLines = l.allLines
-	} else {
-		l.filteredLines = make([]string, 0)
-		for _, line := range l.allLines {
-			if strings.Contains(strings.ToLower(line), query) {
-				l.filteredLines = append(l.filteredLines, line)
+	l.filteredLines = make([]string, 0, len(l.allLines))
+	l.filteredIdx = make([]int, 0, len(l.allLines))
+	l.isContext = make([]bool, 0, len(l.allLines))
+	add := func(i int, context bool) {
+		l.filteredLines = append(l.filteredLines, l.allLines[i])
+		l.filteredIdx = append(l.filteredIdx, i)
+		l.isContext = append(l.isContext, context)
+	}
+
+	// Like grep -B/-A: keep lines around each match as dimmed context
+	lastAdded, afterUntil := -1, -1
+	for i, line := range l.allLines {
+		if l.matches(i, line, query) {
+			start := i - l.contextBefore
+			if start <= lastAdded {
+				start = lastAdded + 1
+			}
+			if start < 0 {
+				start = 0
 			}
+			for j := start; j < i; j++ {
+				add(j, true)
+			}
+			add(i, false)
+			lastAdded = i
+			afterUntil = i + l.contextAfter
+		} else if i <= afterUntil {
+			add(i, true)
+			lastAdded = i
 		}
 	}
 
+	if l.sortByTime {
+		l.sortFilteredByTime()
+	}
+	if l.groupByPrefix {
+		l.groupFilteredByPrefix()
+	}
+
 	// Reset selection if out of bounds
 	if l.selectedIndex >= len(l.filteredLines) {
 		l.selectedIndex = 0
@@ -150,6 +522,58 @@ func (l *LogViewer) filterLogs() {
 	l.updateContent()
 }
 
+// matches reports whether line i passes the search and time filters
+func (l *LogViewer) matches(i int, line, query string) bool {
+	if query != "" && !strings.Contains(strings.ToLower(line), query) {
+		return false
+	}
+	if l.timeFilter != nil {
+		t := l.lineTimes[i]
+		if t.IsZero() || !l.timeFilter.Contains(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortFilteredByTime reorders the filtered lines by timestamp, keeping the
+// original order for equal times so continuation lines stay together
+func (l *LogViewer) sortFilteredByTime() {
+	l.reorderFiltered(func(a, b int) bool {
+		return l.lineTimes[a].Before(l.lineTimes[b])
+	})
+}
+
+// groupFilteredByPrefix reorders the filtered lines so lines with the same
+// prefix are together, keeping their order within each group
+func (l *LogViewer) groupFilteredByPrefix() {
+	l.reorderFiltered(func(a, b int) bool {
+		return l.allLines[a][:l.prefixes[a].length] < l.allLines[b][:l.prefixes[b].length]
+	})
+}
+
+// reorderFiltered stable-sorts the filtered lines, comparing them by their
+// index in allLines
+func (l *LogViewer) reorderFiltered(less func(a, b int) bool) {
+	order := make([]int, len(l.filteredIdx))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return less(l.filteredIdx[order[a]], l.filteredIdx[order[b]])
+	})
+
+	lines := make([]string, len(order))
+	idx := make([]int, len(order))
+	context := make([]bool, len(order))
+	for i, o := range order {
+		lines[i] = l.filteredLines[o]
+		idx[i] = l.filteredIdx[o]
+		context[i] = l.isContext[o]
+	}
+	l.filteredLines, l.filteredIdx, l.isContext = lines, idx, context
+}
+
 func (l *LogViewer) updateContent() {
 	if !l.ready {
 		return
@@ -157,35 +581,81 @@ func (l *LogViewer) updateContent() {
 
 	var content strings.Builder
 	query := strings.ToLower(l.searchInput.Value())
+	maxLen := l.width - 11
+	if l.showAge {
+		maxLen -= 6
+	}
+	l.rowOffsets = l.rowOffsets[:0]
+	row := 0
+	now := time.Now()
+
+	// The load action sits above the first line
+	if l.hasOlder {
+		content.WriteString(DimStyle.Render("   ↑ L: load older lines") + "\n")
+		row++
+	}
 
 	for i, line := range l.filteredLines {
-		// Truncate long lines for the list view
-		displayLine := line
-		maxLen := l.width - 10
-		if maxLen > 0 && len(displayLine) > maxLen {
-			displayLine = displayLine[:maxLen] + "..."
+		l.rowOffsets = append(l.rowOffsets, row)
+
+		// Fit long lines to the list view according to the wrap mode
+		raw, colored := l.coloredLine(i)
+		var rows []string
+		if colored {
+			rows = l.displayRowsANSI(highlightANSI(raw, query), maxLen)
+		} else {
+			rows = l.displayRows(line, maxLen)
 		}
 
-		// Apply selection style
-		if i == l.selectedIndex {
-			// Selected line - highlight background
-			if query != "" {
-				highlighted := l.highlightMatches(displayLine, query)
-				content.WriteString(SelectedItemStyle.Render("▶ " + highlighted))
-			} else {
-				content.WriteString(SelectedItemStyle.Render("▶ " + displayLine))
+		for j, displayLine := range rows {
+			head := ""
+			if j == 0 {
+				head, displayLine = l.splitPrefix(l.filteredIdx[i], line, displayLine)
 			}
-		} else {
-			// Normal line
-			if query != "" {
-				highlighted := l.highlightMatches(displayLine, query)
-				content.WriteString("  " + highlighted)
+
+			if colored {
+				// The line's own colors end with the row
+				displayLine += "\x1b[0m"
+			} else if l.isContext[i] {
+				displayLine = DimStyle.Render(displayLine)
+			} else if strings.HasPrefix(line[l.prefixes[l.filteredIdx[i]].length:], k8s.RestartMarker) {
+				displayLine = WarningStyle.Render(displayLine)
+			} else if query != "" {
+				displayLine = l.highlightMatches(displayLine, query)
+			}
+			displayLine = head + displayLine
+
+			// Gutter: selection marker, then pin marker
+			prefix := "   "
+			if j == 0 {
+				marker, pin := " ", " "
+				if i == l.selectedIndex {
+					marker = "▶"
+				}
+				if l.pins[l.filteredIdx[i]] {
+					pin = "◆"
+				}
+				prefix = marker + pin + " "
+			}
+			if l.showAge {
+				age := "      "
+				if j == 0 && l.ownTime[l.filteredIdx[i]] {
+					age = fmt.Sprintf("%5s ", formatAge(now.Sub(l.lineTimes[l.filteredIdx[i]])))
+				}
+				prefix += DimStyle.Render(age)
+			}
+
+			// Apply selection style
+			if i == l.selectedIndex {
+				content.WriteString(SelectedItemStyle.Render(prefix + displayLine))
 			} else {
-				content.WriteString("  " + displayLine)
+				content.WriteString(prefix + displayLine)
 			}
+			content.WriteString("\n")
 		}
-		content.WriteString("\n")
+		row += len(rows)
 	}
+	l.rowOffsets = append(l.rowOffsets, row)
 
 	l.viewport.SetContent(content.String())
 
@@ -196,6 +666,66 @@ func (l *LogViewer) updateContent() {
 	l.ensureSelectedVisible()
 }
 
+// splitPrefix separates a line's pod prefix from the rest of its first
+// display row, rendering the prefix in the pod's color
+func (l *LogViewer) splitPrefix(idx int, line, row string) (string, string) {
+	p := l.prefixes[idx]
+	if p.length == 0 || p.color == nil || !strings.HasPrefix(row, line[:p.length]) {
+		return "", row
+	}
+	head := lipgloss.NewStyle().Foreground(p.color).Render(line[:p.length])
+	return head, row[p.length:]
+}
+
+// displayRows returns the rows a log line occupies in the list view
+func (l *LogViewer) displayRows(line string, maxLen int) []string {
+	if maxLen <= 0 {
+		return []string{line}
+	}
+
+	switch l.wrapMode {
+	case WrapSoft:
+		return strings.Split(wrapWidth(line, maxLen), "\n")
+
+	case WrapScroll:
+		if l.hOffset == 0 {
+			return []string{truncateWidth(line, maxLen, "...")}
+		}
+		if l.hOffset >= runewidth.StringWidth(line) {
+			return []string{"«"}
+		}
+		return []string{"«" + truncateWidth(skipColumns(line, l.hOffset), maxLen-1, "...")}
+
+	default:
+		return []string{truncateWidth(line, maxLen, "...")}
+	}
+}
+
+// displayRowsANSI is displayRows for a line with escape codes, which are
+// kept intact and take no room
+func (l *LogViewer) displayRowsANSI(line string, maxLen int) []string {
+	if maxLen <= 0 {
+		return []string{line}
+	}
+
+	switch l.wrapMode {
+	case WrapSoft:
+		return strings.Split(ansi.WrapWc(line, maxLen, ",;:"), "\n")
+
+	case WrapScroll:
+		if l.hOffset == 0 {
+			return []string{ansi.TruncateWc(line, maxLen, "...")}
+		}
+		if l.hOffset >= ansi.StringWidthWc(line) {
+			return []string{"«"}
+		}
+		return []string{"«" + ansi.TruncateWc(ansi.TruncateLeftWc(line, l.hOffset, ""), maxLen-1, "...")}
+
+	default:
+		return []string{ansi.TruncateWc(line, maxLen, "...")}
+	}
+}
+
 func (l *LogViewer) updateDetailView() {
 	if !l.ready || len(l.filteredLines) == 0 {
 		l.detailViewport.SetContent(InfoStyle.Render("No log entry selected"))
@@ -207,9 +737,11 @@ func (l *LogViewer) updateDetailView() {
 		query := strings.ToLower(l.searchInput.Value())
 
 		// Word wrap the full line
-		wrapped := l.wordWrap(fullLine, l.width-6)
+		wrapped := wrapWidth(fullLine, l.width-6)
 
-		if query != "" {
+		if raw, ok := l.coloredLine(l.selectedIndex); ok {
+			wrapped = ansi.WrapWc(highlightANSI(raw, query), l.width-6, ",;:")
+		} else if query != "" {
 			wrapped = l.highlightMatches(wrapped, query)
 		}
 
@@ -217,47 +749,42 @@ func (l *LogViewer) updateDetailView() {
 	}
 }
 
-func (l *LogViewer) wordWrap(text string, width int) string {
-	if width <= 0 {
-		return text
-	}
-
-	var result strings.Builder
-	for len(text) > width {
-		// Find a good break point
-		breakAt := width
-		for i := width; i > width/2; i-- {
-			if text[i] == ' ' || text[i] == ',' || text[i] == ';' || text[i] == ':' {
-				breakAt = i + 1
-				break
-			}
-		}
-		result.WriteString(text[:breakAt])
-		result.WriteString("\n")
-		text = text[breakAt:]
-	}
-	result.WriteString(text)
-	return result.String()
-}
-
 func (l *LogViewer) ensureSelectedVisible() {
-	if len(l.filteredLines) == 0 {
+	if len(l.filteredLines) == 0 || l.selectedIndex+1 >= len(l.rowOffsets) {
 		return
 	}
 
-	// Each line is approximately 1 row
+	// Wrapped lines span several rows
+	startRow := l.rowOffsets[l.selectedIndex]
+	endRow := l.rowOffsets[l.selectedIndex+1] - 1
+	if l.selectedIndex == 0 {
+		// Keep the load older lines row in view
+		startRow = 0
+	}
+
 	visibleStart := l.viewport.YOffset
 	visibleEnd := visibleStart + l.viewport.Height
 
-	if l.selectedIndex < visibleStart {
-		l.viewport.SetYOffset(l.selectedIndex)
-	} else if l.selectedIndex >= visibleEnd {
-		l.viewport.SetYOffset(l.selectedIndex - l.viewport.Height + 1)
+	if startRow < visibleStart {
+		l.viewport.SetYOffset(startRow)
+	} else if endRow >= visibleEnd {
+		l.viewport.SetYOffset(endRow - l.viewport.Height + 1)
 	}
 }
 
+// lowerInPlace lowercases s where that keeps each character's byte length,
+// so that positions found in the result are valid in s
+func lowerInPlace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if lower := unicode.ToLower(r); utf8.RuneLen(lower) == utf8.RuneLen(r) {
+			return lower
+		}
+		return r
+	}, s)
+}
+
 func (l *LogViewer) highlightMatches(line, query string) string {
-	lower := strings.ToLower(line)
+	lower := lowerInPlace(line)
 	var result strings.Builder
 	lastEnd := 0
 
@@ -286,22 +813,73 @@ func (l *LogViewer) Update(msg tea.Msg) (LogViewer, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case exportedMsg:
+		if msg.err != nil {
+			l.status = "Export failed: " + msg.err.Error()
+		} else {
+			l.status = "Exported to " + msg.path
+		}
+		return *l, nil
+
+	case pipeEndedMsg:
+		if msg.pipe != l.pipe {
+			return *l, nil
+		}
+		// Also ends the input of a command that stopped on its own
+		msg.pipe.Close()
+		l.pipe = nil
+		if err := msg.pipe.Err(); err != nil {
+			l.status = "✗ " + err.Error() + ", output in " + l.pipeOutput
+		} else {
+			l.status = msg.pipe.Summary() + ", output in " + l.pipeOutput
+		}
+		return *l, nil
+
 	case tea.KeyMsg:
-		switch msg.String() {
+		l.status = ""
+
+		// The pipe command input takes all keys while focused
+		if l.pipeInput.Focused() {
+			switch msg.String() {
+			case "enter":
+				return *l, l.startPipe()
+			case "esc":
+				l.pipeInput.Blur()
+				return *l, nil
+			}
+			l.pipeInput, cmd = l.pipeInput.Update(msg)
+			return *l, cmd
+		}
+
+		// Time range input takes all keys while focused
+		if l.timeInput.Focused() {
+			switch msg.String() {
+			case "enter":
+				l.applyTimeFilter()
+				return *l, nil
+			case "tab":
+				l.timeInput.Blur()
+				return *l, nil
+			}
+			l.timeInput, cmd = l.timeInput.Update(msg)
+			return *l, cmd
+		}
+
+		switch {
 		// Navigation - works even when search is focused
-		case "up", "k":
+		case key.Matches(msg, keys.LogUp):
 			if l.selectedIndex > 0 {
 				l.selectedIndex--
 				l.updateContent()
 			}
 			return *l, nil
-		case "down", "j":
+		case key.Matches(msg, keys.LogDown):
 			if l.selectedIndex < len(l.filteredLines)-1 {
 				l.selectedIndex++
 				l.updateContent()
 			}
 			return *l, nil
-		case "pgup", "ctrl+u":
+		case key.Matches(msg, keys.LogPageUp):
 			// Move selection up by half page
 			l.selectedIndex -= l.viewport.Height / 2
 			if l.selectedIndex < 0 {
@@ -309,7 +887,7 @@ func (l *LogViewer) Update(msg tea.Msg) (LogViewer, tea.Cmd) {
 			}
 			l.updateContent()
 			return *l, nil
-		case "pgdown", "ctrl+d":
+		case key.Matches(msg, keys.LogPageDown):
 			// Move selection down by half page
 			l.selectedIndex += l.viewport.Height / 2
 			if l.selectedIndex >= len(l.filteredLines) {
@@ -320,13 +898,13 @@ func (l *LogViewer) Update(msg tea.Msg) (LogViewer, tea.Cmd) {
 			}
 			l.updateContent()
 			return *l, nil
-		case "home", "g":
+		case key.Matches(msg, keys.LogTop):
 			if !l.searchInput.Focused() {
 				l.selectedIndex = 0
 				l.updateContent()
 				return *l, nil
 			}
-		case "end", "G":
+		case key.Matches(msg, keys.LogBottom):
 			if !l.searchInput.Focused() {
 				if len(l.filteredLines) > 0 {
 					l.selectedIndex = len(l.filteredLines) - 1
@@ -334,18 +912,18 @@ func (l *LogViewer) Update(msg tea.Msg) (LogViewer, tea.Cmd) {
 				l.updateContent()
 				return *l, nil
 			}
-		case "/":
+		case key.Matches(msg, keys.LogSearch):
 			// Focus search if not already focused
 			if !l.searchInput.Focused() {
 				l.searchInput.Focus()
 				return *l, nil
 			}
-		case "enter":
+		case key.Matches(msg, keys.LogEndSearch):
 			if l.searchInput.Focused() {
 				l.searchInput.Blur()
 				return *l, nil
 			}
-		case "tab":
+		case key.Matches(msg, keys.LogToggleSearch):
 			// Toggle focus between search and viewport
 			if l.searchInput.Focused() {
 				l.searchInput.Blur()
@@ -353,11 +931,155 @@ func (l *LogViewer) Update(msg tea.Msg) (LogViewer, tea.Cmd) {
 				l.searchInput.Focus()
 			}
 			return *l, nil
-		case "ctrl+l":
+		case key.Matches(msg, keys.LogClearSearch):
 			// Clear search
 			l.searchInput.SetValue("")
 			l.filterLogs()
 			return *l, nil
+		case key.Matches(msg, keys.LogPin):
+			// Pin/unpin the selected line
+			if !l.searchInput.Focused() && l.selectedIndex < len(l.filteredIdx) {
+				idx := l.filteredIdx[l.selectedIndex]
+				if l.pins[idx] {
+					delete(l.pins, idx)
+				} else {
+					l.pins[idx] = true
+				}
+				l.updateContent()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogNextPin):
+			if !l.searchInput.Focused() {
+				l.jumpToPin(1)
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogPrevPin):
+			if !l.searchInput.Focused() {
+				l.jumpToPin(-1)
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogExport):
+			if !l.searchInput.Focused() {
+				if len(l.pins) == 0 {
+					l.status = "No pinned lines to export"
+					return *l, nil
+				}
+				return *l, l.exportPins()
+			}
+		case key.Matches(msg, keys.LogSave):
+			if !l.searchInput.Focused() {
+				if len(l.allLines) == 0 {
+					l.status = "No lines to save"
+					return *l, nil
+				}
+				return *l, l.saveLogs()
+			}
+		case key.Matches(msg, keys.LogContextAfter):
+			// Context lines after each match
+			if !l.searchInput.Focused() {
+				l.contextAfter = nextContextStep(l.contextAfter)
+				l.filterLogs()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogContextBefore):
+			// Context lines before each match
+			if !l.searchInput.Focused() {
+				l.contextBefore = nextContextStep(l.contextBefore)
+				l.filterLogs()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogContext):
+			// Context lines on both sides
+			if !l.searchInput.Focused() {
+				next := nextContextStep(l.contextBefore)
+				l.contextBefore, l.contextAfter = next, next
+				l.filterLogs()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogAge):
+			// Toggle relative age column
+			if !l.searchInput.Focused() {
+				l.showAge = !l.showAge
+				l.updateContent()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogOrder):
+			// Toggle ordering by timestamp
+			if !l.searchInput.Focused() {
+				l.sortByTime = !l.sortByTime
+				l.filterLogs()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogGroup):
+			// Toggle grouping lines by their pod or object prefix
+			if !l.searchInput.Focused() && l.hasPrefixes() {
+				l.groupByPrefix = !l.groupByPrefix
+				l.filterLogs()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogTimeFilter):
+			// Edit the time range filter
+			if !l.searchInput.Focused() {
+				l.timeInput.Focus()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogPipe):
+			// Start piping to a command, or stop the running one
+			if !l.searchInput.Focused() {
+				if l.pipe != nil {
+					l.StopPipe()
+					l.status = "Stopping the pipe to " + l.pipe.Command + "…"
+					return *l, nil
+				}
+				l.pipeInput.Focus()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogWrap):
+			// Cycle truncate -> wrap -> horizontal scroll
+			if !l.searchInput.Focused() {
+				l.wrapMode = (l.wrapMode + 1) % 3
+				l.hOffset = 0
+				l.updateContent()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogGrowList):
+			if !l.searchInput.Focused() {
+				return *l, l.resizeSplit(logSplitStep)
+			}
+		case key.Matches(msg, keys.LogShrinkList):
+			if !l.searchInput.Focused() {
+				return *l, l.resizeSplit(-logSplitStep)
+			}
+		case key.Matches(msg, keys.LogToggleDetail):
+			if !l.searchInput.Focused() {
+				return *l, l.toggleDetail()
+			}
+		case key.Matches(msg, keys.LogColors):
+			if !l.searchInput.Focused() {
+				l.showColors = !l.showColors
+				if l.showColors {
+					l.status = "Showing the lines' own colors"
+				} else {
+					l.status = "Stripping the lines' own colors"
+				}
+				l.updateContent()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogLeft):
+			if !l.searchInput.Focused() && l.wrapMode == WrapScroll {
+				l.hOffset -= hScrollStep
+				if l.hOffset < 0 {
+					l.hOffset = 0
+				}
+				l.updateContent()
+				return *l, nil
+			}
+		case key.Matches(msg, keys.LogRight):
+			if !l.searchInput.Focused() && l.wrapMode == WrapScroll {
+				l.hOffset += hScrollStep
+				l.updateContent()
+				return *l, nil
+			}
 		}
 	}
 
@@ -397,9 +1119,50 @@ func (l *LogViewer) View() string {
 	if l.selectedIndex < len(l.filteredLines) {
 		stats += InfoStyle.Render(" • Selected: " + itoa(l.selectedIndex+1))
 	}
+	stats += InfoStyle.Render(" • Lines: " + l.wrapMode.String())
+	if len(l.pins) > 0 {
+		stats += InfoStyle.Render(" • Pinned: " + itoa(len(l.pins)))
+	}
+	if l.timeFilter != nil {
+		stats += InfoStyle.Render(" • Time: " + l.timeFilter.text)
+	}
+	if l.pipe != nil {
+		stats += InfoStyle.Render(" • | " + l.pipe.Command)
+	}
+	if l.sortByTime {
+		stats += InfoStyle.Render(" • Sorted by time")
+	}
+	if l.groupByPrefix {
+		stats += InfoStyle.Render(" • Grouped")
+	}
+	if l.searchQuery != "" && (l.contextBefore > 0 || l.contextAfter > 0) {
+		stats += InfoStyle.Render(" • Context: -" + itoa(l.contextBefore) + "/+" + itoa(l.contextAfter))
+	}
+	if l.wrapMode == WrapScroll && l.hOffset > 0 {
+		stats += InfoStyle.Render(" +" + itoa(l.hOffset))
+	}
 	b.WriteString(stats)
+	if l.status != "" {
+		b.WriteString("  " + WarningStyle.Render(l.status))
+	}
 	b.WriteString("\n")
 
+	// Time range input
+	if l.timeInput.Focused() {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#10B981")).Bold(true).Render("⏱ Time range: "))
+		b.WriteString(l.timeInput.View())
+		b.WriteString(InfoStyle.Render("  Enter: apply (empty clears)"))
+		b.WriteString("\n")
+	}
+
+	// Pipe command input
+	if l.pipeInput.Focused() {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#10B981")).Bold(true).Render("| Pipe to: "))
+		b.WriteString(l.pipeInput.View())
+		b.WriteString(InfoStyle.Render("  Enter: send the shown lines and new matches • Esc: cancel"))
+		b.WriteString("\n")
+	}
+
 	// Log list header
 	b.WriteString(LabelStyle.Render("─── Matching Logs ───"))
 	b.WriteString("\n")
@@ -409,6 +1172,10 @@ func (l *LogViewer) View() string {
 		b.WriteString(l.viewport.View())
 	}
 
+	if l.split.HideDetail {
+		return b.String()
+	}
+
 	// Detail header
 	b.WriteString("\n")
 	b.WriteString(LabelStyle.Render("─── Full Log Entry ───"))
@@ -440,3 +1207,201 @@ func (l *LogViewer) Blur() {
 func (l *LogViewer) IsFocused() bool {
 	return l.searchInput.Focused()
 }
+
+// IsPromptFocused returns whether the time range or pipe input takes the
+// keys
+func (l *LogViewer) IsPromptFocused() bool {
+	return l.timeInput.Focused() || l.pipeInput.Focused()
+}
+
+// startPipe starts the command of the pipe input, sending it the shown
+// lines and then the new matching ones. Its output goes to a file next to
+// the exports.
+func (l *LogViewer) startPipe() tea.Cmd {
+	l.pipeInput.Blur()
+	command := strings.TrimSpace(l.pipeInput.Value())
+	if command == "" {
+		return nil
+	}
+	path, err := exportPath(l.exportDir, l.source, "pipe", ".log")
+	if err != nil {
+		l.status = "✗ " + err.Error()
+		return nil
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		l.status = "✗ " + err.Error()
+		return nil
+	}
+	pipe, err := StartLogPipe(command, out)
+	if err != nil {
+		out.Close()
+		os.Remove(path)
+		l.status = "✗ " + err.Error()
+		return nil
+	}
+	l.pipe, l.pipeOutput = pipe, path
+	for _, i := range l.filteredIdx {
+		pipe.Send(l.allLines[i][l.prefixes[i].length:])
+	}
+	l.status = "Piping to " + command + ", output in " + path
+	return waitPipe(pipe, out)
+}
+
+// StopPipe ends the input of the pipe command, if one runs
+func (l *LogViewer) StopPipe() {
+	if l.pipe != nil {
+		l.pipe.Close()
+	}
+}
+
+// applyTimeFilter parses the time range input; an empty input clears it
+func (l *LogViewer) applyTimeFilter() {
+	l.timeInput.Blur()
+	value := strings.TrimSpace(l.timeInput.Value())
+	if value == "" {
+		l.timeFilter = nil
+		l.filterLogs()
+		return
+	}
+	r, err := parseTimeRange(value)
+	if err != nil {
+		l.status = err.Error()
+		return
+	}
+	l.timeFilter = &r
+	l.filterLogs()
+}
+
+// jumpToPin moves the selection to the next (dir > 0) or previous pinned
+// line among the filtered lines
+func (l *LogViewer) jumpToPin(dir int) {
+	for i := l.selectedIndex + dir; i >= 0 && i < len(l.filteredIdx); i += dir {
+		if l.pins[l.filteredIdx[i]] {
+			l.selectedIndex = i
+			l.autoScroll = false
+			l.updateContent()
+			return
+		}
+	}
+	l.status = "No more pinned lines"
+}
+
+// exportPins writes the pinned lines with surrounding context to a file in
+// the export directory
+func (l *LogViewer) exportPins() tea.Cmd {
+	pinned := make([]int, 0, len(l.pins))
+	for idx := range l.pins {
+		pinned = append(pinned, idx)
+	}
+	sort.Ints(pinned)
+	lines := l.allLines
+	source := l.source
+	exportDir := l.exportDir
+
+	return func() tea.Msg {
+		var b strings.Builder
+		if source != "" {
+			b.WriteString(fmt.Sprintf("# Pinned log lines from %s\n", source))
+		}
+		b.WriteString(fmt.Sprintf("# Exported %s, %d lines of context\n", time.Now().Format(time.RFC3339), pinContextLines))
+
+		pinSet := make(map[int]bool, len(pinned))
+		for _, idx := range pinned {
+			pinSet[idx] = true
+		}
+
+		// Merge overlapping context ranges
+		last := -1
+		for _, idx := range pinned {
+			start := idx - pinContextLines
+			if start <= last {
+				start = last + 1
+			} else if last >= 0 {
+				b.WriteString("--\n")
+			}
+			if start < 0 {
+				start = 0
+			}
+			end := idx + pinContextLines
+			if end >= len(lines) {
+				end = len(lines) - 1
+			}
+			for i := start; i <= end; i++ {
+				marker := "  "
+				if pinSet[i] {
+					marker = "> "
+				}
+				b.WriteString(fmt.Sprintf("%s%d: %s\n", marker, i+1, lines[i]))
+			}
+			if end > last {
+				last = end
+			}
+		}
+
+		return writeExport(exportDir, source, "pins", ".log", b.String())
+	}
+}
+
+// saveLogs writes all loaded lines to the export dir, e.g. to compare with a
+// later incident in the logs archive
+func (l *LogViewer) saveLogs() tea.Cmd {
+	lines := l.allLines
+	source := l.source
+	exportDir := l.exportDir
+
+	return func() tea.Msg {
+		var b strings.Builder
+		if source != "" {
+			b.WriteString(fmt.Sprintf("# Logs from %s\n", source))
+		}
+		b.WriteString(fmt.Sprintf("# Saved %s, %d lines\n", time.Now().Format(time.RFC3339), len(lines)))
+		for _, line := range lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		return writeExport(exportDir, source, "logs", ".log", b.String())
+	}
+}
+
+// writeExport writes an export file named after the source, kind and time
+func writeExport(dir, source, kind, ext, content string) tea.Msg {
+	path, err := exportPath(dir, source, kind, ext)
+	if err != nil {
+		return exportedMsg{err: err}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return exportedMsg{err: err}
+	}
+	return exportedMsg{path: path}
+}
+
+// exportPath returns the path of a new export file named after the source,
+// kind and time, creating its directory
+func exportPath(dir, source, kind, ext string) (string, error) {
+	if dir == "" {
+		var err error
+		if dir, err = config.GetExportDir(); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := kind + "-" + time.Now().Format("20060102-150405") + ext
+	if source != "" {
+		name = sanitizeFileName(source) + "-" + name
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// sanitizeFileName replaces characters that are awkward in file names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', ' ', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,233 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// metricsSteps is how many points a series of the metrics command has, one
+// per sparkline character
+const metricsSteps = 40
+
+// maxMetricSeries bounds the series shown per query
+const maxMetricSeries = 20
+
+// sparkBars are the sparkline characters from the lowest value up
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// metricsTemplateData is what PromQL templates are executed with
+type metricsTemplateData struct {
+	Namespace  string
+	Deployment string
+	PodRegex   string // matches the names of the deployment's pods
+}
+
+// runMetrics runs the Prometheus queries the input names, all predefined
+// ones if it is empty, or the input itself as a PromQL template
+func (m Model) runMetrics(input string) tea.Cmd {
+	settings := m.config.GetPrometheus(m.k8sClient.GetKubeConfigPath())
+	queries := m.config.GetPrometheusQueries()
+	namespace, deployment := m.namespace, m.deployment
+	client := m.k8sClient
+	return func() tea.Msg {
+		if settings.URL == "" {
+			return CommandResultMsg{err: errors.New("no Prometheus configured: set prometheus.url in config.yml, or per kubeconfig under prometheus.profiles")}
+		}
+		selected := queries
+		if input != "" {
+			selected = []config.PrometheusQuery{{Name: "custom", Query: input}}
+			for _, q := range queries {
+				if strings.EqualFold(q.Name, input) {
+					selected = []config.PrometheusQuery{q}
+				}
+			}
+		}
+
+		ctx := context.Background()
+		data := metricsTemplateData{Namespace: namespace, Deployment: deployment}
+		if k8s.IsCustomWorkload(deployment) {
+			_, data.Deployment, _ = strings.Cut(deployment, "/")
+		}
+		pods, err := client.ListPods(ctx, namespace, deployment)
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		var names []string
+		for _, pod := range pods {
+			names = append(names, regexp.QuoteMeta(pod.Name))
+		}
+		data.PodRegex = strings.Join(names, "|")
+		if data.PodRegex == "" {
+			data.PodRegex = regexp.QuoteMeta(data.Deployment) + "-.*"
+		}
+
+		query := k8s.MetricsQuery{URL: settings.URL, End: time.Now()}
+		query.Step = (settings.Range / metricsSteps).Truncate(time.Second)
+		if query.Step < time.Second {
+			query.Step = time.Second
+		}
+		query.Start = query.End.Add(-query.Step * (metricsSteps - 1))
+		if !strings.HasPrefix(settings.URL, k8s.ServicePrefix) {
+			if creds, err := config.OpenCredentials(); err == nil {
+				query.Username, query.Password, _ = creds.Login(config.CredentialPrometheus, k8s.APIHost(settings.URL))
+			}
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "Metrics of %s from %s, last %s every %s\n", data.Deployment, settings.URL, formatAge(settings.Range), query.Step)
+		for _, q := range selected {
+			b.WriteString("\n")
+			series, err := runMetricsQuery(ctx, client, query, q, data)
+			b.WriteString(formatMetricSeries(q, series, err))
+		}
+		return CommandResultMsg{result: strings.TrimRight(b.String(), "\n")}
+	}
+}
+
+// runMetricsQuery executes a query's template and runs it
+func runMetricsQuery(ctx context.Context, client k8s.Telemetry, query k8s.MetricsQuery, q config.PrometheusQuery, data metricsTemplateData) ([]k8s.MetricSeries, error) {
+	tmpl, err := template.New(q.Name).Option("missingkey=error").Parse(q.Query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query template: %w", err)
+	}
+	var promQL strings.Builder
+	if err := tmpl.Execute(&promQL, data); err != nil {
+		return nil, fmt.Errorf("invalid query template: %w", err)
+	}
+	query.Query = promQL.String()
+	return client.QueryPrometheus(ctx, query)
+}
+
+// formatMetricSeries renders a query's series as a table with a sparkline
+// and the last, lowest and highest value of each
+func formatMetricSeries(q config.PrometheusQuery, series []k8s.MetricSeries, err error) string {
+	var b strings.Builder
+	b.WriteString(q.Name + "\n")
+	if err != nil {
+		return b.String() + "  ✗ " + err.Error() + "\n"
+	}
+	// Series without a sample in range, e.g. all NaN, are left out
+	series = slices.DeleteFunc(series, func(s k8s.MetricSeries) bool {
+		return !slices.ContainsFunc(s.Values, func(v float64) bool { return !math.IsNaN(v) })
+	})
+	if len(series) == 0 {
+		return b.String() + "  no data\n"
+	}
+
+	sort.Slice(series, func(i, j int) bool { return metricLabel(series[i].Labels) < metricLabel(series[j].Labels) })
+	rows := [][]string{{"SERIES", "TREND", "LAST", "MIN", "MAX"}}
+	for i, s := range series {
+		if i == maxMetricSeries {
+			break
+		}
+		last, low, high := math.NaN(), math.NaN(), math.NaN()
+		for _, v := range s.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			last = v
+			if math.IsNaN(low) || v < low {
+				low = v
+			}
+			if math.IsNaN(high) || v > high {
+				high = v
+			}
+		}
+		rows = append(rows, []string{
+			metricLabel(s.Labels),
+			sparkline(s.Values, low, high),
+			formatMetricValue(last, q.Unit),
+			formatMetricValue(low, q.Unit),
+			formatMetricValue(high, q.Unit),
+		})
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	for _, row := range rows {
+		b.WriteString("  ")
+		for i, cell := range row {
+			if i == len(row)-1 {
+				b.WriteString(cell)
+			} else {
+				fmt.Fprintf(&b, "%-*s  ", widths[i], cell)
+			}
+		}
+		b.WriteString("\n")
+	}
+	if len(series) > maxMetricSeries {
+		fmt.Fprintf(&b, "  … %d more series\n", len(series)-maxMetricSeries)
+	}
+	return b.String()
+}
+
+// metricLabel names a series by its labels, a pod by its name alone
+func metricLabel(labels map[string]string) string {
+	var pairs []string
+	for key, value := range labels {
+		if key != "__name__" {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	switch {
+	case len(pairs) == 0:
+		return "total"
+	case len(pairs) == 1 && labels["pod"] != "":
+		return labels["pod"]
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// sparkline draws values between low and high, gaps and infinite values as
+// spaces
+func sparkline(values []float64, low, high float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v) || math.IsInf(v, 0):
+			b.WriteRune(' ')
+		case high <= low:
+			b.WriteRune(sparkBars[0])
+		default:
+			b.WriteRune(sparkBars[int((v-low)/(high-low)*float64(len(sparkBars)-1)+0.5)])
+		}
+	}
+	return b.String()
+}
+
+// formatMetricValue renders a value in its query's unit
+func formatMetricValue(v float64, unit string) string {
+	switch {
+	case math.IsNaN(v):
+		return "-"
+	case unit == config.UnitBytes:
+		return formatBytes(int64(v))
+	case unit == config.UnitSeconds && v < 1:
+		return fmt.Sprintf("%.0fms", v*1000)
+	case unit == config.UnitSeconds:
+		return fmt.Sprintf("%.2fs", v)
+	case unit == config.UnitRatio:
+		return fmt.Sprintf("%.2f%%", v*100)
+	case unit == config.UnitCores:
+		return fmt.Sprintf("%.0fm", v*1000)
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runNetTest runs the network checks entered, or the default ones, from
+// inside the selected container
+func (m Model) runNetTest() tea.Cmd {
+	checks := k8s.DefaultNetChecks
+	if input := strings.TrimSpace(m.inputValue); input != "" {
+		parsed, err := k8s.ParseNetChecks(input)
+		if err != nil {
+			return func() tea.Msg { return CommandResultMsg{err: err} }
+		}
+		checks = parsed
+	}
+	ctx := m.exec.context()
+	namespace, pod, container := m.namespace, m.pod, m.container
+	return func() tea.Msg {
+		results, err := m.k8sClient.RunNetChecks(ctx, namespace, pod, container, checks)
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: formatNetResults(pod, container, results)}
+	}
+}
+
+// formatNetResults renders the checks' results as a table with a summary
+func formatNetResults(pod, container string, results []k8s.NetResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Network checks from %s/%s:\n\n", pod, container)
+
+	width := len("TARGET")
+	for _, r := range results {
+		width = max(width, len(r.Check.Target))
+	}
+	fmt.Fprintf(&b, "  %-5s %-*s  %-6s %-9s %-7s %s\n", "CHECK", width, "TARGET", "RESULT", "TOOL", "TIME", "DETAIL")
+	passed := 0
+	for _, r := range results {
+		mark := "✗ fail"
+		if r.OK {
+			mark = "✓ ok"
+			passed++
+		}
+		tool := r.Tool
+		if tool == "" {
+			tool = "-"
+		}
+		fmt.Fprintf(&b, "  %-5s %-*s  %-6s %-9s %-7s %s\n", r.Check.Kind, width, r.Check.Target, mark, tool,
+			r.Duration.Round(time.Millisecond), r.Detail)
+	}
+	fmt.Fprintf(&b, "\n%d of %d checks passed.", passed, len(results))
+	return b.String()
+}
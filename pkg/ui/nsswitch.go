@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// deploymentKeptMsg reports whether the deployment selected before a
+// namespace switch exists in the new namespace
+type deploymentKeptMsg struct {
+	namespace  string
+	deployment string
+	exists     bool
+	err        error
+}
+
+// keepDeployment checks for the previous deployment in the namespace just
+// selected, while its deployment list loads
+func (m Model) keepDeployment(deployment string) tea.Cmd {
+	namespace := m.namespace
+	return func() tea.Msg {
+		exists, err := m.k8sClient.WorkloadExists(context.Background(), namespace, deployment)
+		return deploymentKeptMsg{namespace: namespace, deployment: deployment, exists: exists, err: err}
+	}
+}
+
+// handleDeploymentKept goes on to the commands of the same-named deployment,
+// or stays in the deployment list with a note that it does not exist here
+func (m Model) handleDeploymentKept(msg deploymentKeptMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateSelectDeployment || m.namespace != msg.namespace || m.deployment != "" {
+		return m, nil
+	}
+	if msg.err != nil {
+		// Unknown, e.g. while offline: the list is shown as usual
+		return m, nil
+	}
+	if !msg.exists {
+		m.warning = fmt.Sprintf("%s does not exist in %s, select a deployment", msg.deployment, msg.namespace)
+		if m.config.NamespaceOverview {
+			return m.showOverview()
+		}
+		return m, nil
+	}
+	return m.chooseDeployment(msg.deployment)
+}
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reconnectInterval is how often a command prepared offline checks whether
+// the cluster can be reached again
+const reconnectInterval = 5 * time.Second
+
+// onlineMsg carries the result of a command that waited for the cluster, or
+// the command itself if it changes the cluster and is asked about again
+type onlineMsg struct {
+	msg     tea.Msg
+	pending tea.Cmd
+}
+
+// cachedList loads a list from the cluster and saves it for offline use. If
+// the cluster is unreachable it returns the cached version and when it was
+// saved instead; the time is zero for a live list.
+func (m *Model) cachedList(kind, scope string, load func() ([]string, error)) ([]string, time.Time, error) {
+	list, err := m.cachedDetails(kind, scope, func() (config.CachedList, error) {
+		items, err := load()
+		return config.CachedList{Items: items}, err
+	})
+	return list.Items, list.Time, err
+}
+
+// cachedPods is cachedList for pods, cached with their phases
+func (m *Model) cachedPods(scope string, load func() ([]k8s.PodEntry, error)) ([]k8s.PodEntry, time.Time, error) {
+	list, err := m.cachedDetails(config.CachePods, scope, func() (config.CachedList, error) {
+		pods, err := load()
+		list := config.CachedList{Details: make(map[string]string, len(pods))}
+		for _, pod := range pods {
+			list.Items = append(list.Items, pod.Name)
+			list.Details[pod.Name] = string(pod.Phase)
+		}
+		return list, err
+	})
+	pods := make([]k8s.PodEntry, len(list.Items))
+	for i, name := range list.Items {
+		pods[i] = k8s.PodEntry{Name: name, Phase: corev1.PodPhase(list.Details[name])}
+	}
+	return pods, list.Time, err
+}
+
+// cachedDetails loads a list with the details of its items, see cachedList
+func (m *Model) cachedDetails(kind, scope string, load func() (config.CachedList, error)) (config.CachedList, error) {
+	list, err := load()
+	if err == nil {
+		// The cache is a convenience, failing to write it is not an error
+		_ = config.SaveCachedList(m.kubeconfig, kind, scope, list)
+		return list, nil
+	}
+	if k8s.IsUnreachable(err) {
+		if cached, ok := config.LoadCachedList(m.kubeconfig, kind, scope); ok {
+			return cached, nil
+		}
+	}
+	return config.CachedList{}, err
+}
+
+// whenOnline runs cmd once the cluster can be reached again, or hands it
+// back to be confirmed first if ask is set. Esc cancels the wait like any
+// execution.
+func (m *Model) whenOnline(cmd tea.Cmd, ask bool) tea.Cmd {
+	e, client := m.exec, m.k8sClient
+	return func() tea.Msg {
+		ctx := e.context()
+		e.setWaiting(true)
+		for {
+			_, err := client.ListNamespaces(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !k8s.IsUnreachable(err) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(reconnectInterval):
+			}
+		}
+		e.setWaiting(false)
+		if ask {
+			return onlineMsg{pending: cmd}
+		}
+		return onlineMsg{msg: cmd()}
+	}
+}
+
+// handleOnline leaves offline mode and hands the command's result on. A
+// command that changes the cluster was picked from lists cached up to 30
+// days ago, so it is shown for confirmation instead.
+func (m Model) handleOnline(msg onlineMsg) (tea.Model, tea.Cmd) {
+	m.offline = false
+	if msg.pending == nil {
+		return m.Update(msg.msg)
+	}
+	target := m.namespace + "/" + m.deployment
+	if m.pod != "" {
+		target = m.namespace + "/" + m.pod
+	}
+	m.pendingOnline = msg.pending
+	m.state = StateShowResult
+	m.err = nil
+	m.result = fmt.Sprintf("The cluster can be reached again.\n\nRun %s on %s now? It was picked from lists cached offline, check that the target is still the one you mean.\n", m.command.Name, target)
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// handleOnlineKey runs the command prepared offline once confirmed with y
+func (m Model) handleOnlineKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	run := m.pendingOnline
+	m.pendingOnline = nil
+	switch {
+	case key.Matches(msg, keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Confirm):
+		m.startExecution()
+		return m, m.whileExecuting(run)
+	}
+	m.state = StateSelectCommand
+	m.cmdSelector.Reset()
+	return m, nil
+}
+
+// refuseOffline ends a command that does not end in a result, such as a
+// shell, a log stream or one opening $EDITOR, instead of starting it once
+// the cluster answers again
+func (m Model) refuseOffline() (tea.Model, tea.Cmd) {
+	m.state = StateShowResult
+	m.err = fmt.Errorf("%s cannot wait for the cluster while offline, run it once the cluster can be reached again", m.command.Name)
+	return m, nil
+}
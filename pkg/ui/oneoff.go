@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// oneOffPod is a pod that runs once outside the rollout, an experiment pod
+// or the pod of a Job created from the template, whose logs are followed
+// and which one key deletes
+type oneOffPod struct {
+	namespace  string
+	deployment string
+	pod        string
+	container  string
+	job        string // the Job running the pod, if any
+}
+
+// oneOffStartedMsg reports a started one-off pod
+type oneOffStartedMsg struct {
+	target *oneOffPod
+	err    error
+}
+
+// oneOffJobStatusMsg tells how a one-off pod's Job ended
+type oneOffJobStatusMsg struct {
+	job    string
+	status string
+	err    error
+}
+
+// kind names what the one-off pod is in the UI
+func (p *oneOffPod) kind() string {
+	if p.job != "" {
+		return "Job"
+	}
+	return "experiment pod"
+}
+
+// name is the one-off pod, or its Job
+func (p *oneOffPod) name() string {
+	if p.job != "" {
+		return p.job
+	}
+	return p.pod
+}
+
+// handleOneOffStarted follows the one-off pod's logs
+func (m Model) handleOneOffStarted(msg oneOffStartedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.state = StateShowResult
+		m.err = msg.err
+		return m, nil
+	}
+	m.oneOff = msg.target
+	m.pod = msg.target.pod
+	m.container = msg.target.container
+	m.logViewer = m.newLogViewer(msg.target.pod + "-" + msg.target.container)
+	m.logViewer.SetLogs("")
+	m.logViewer.SetStatus(fmt.Sprintf("%s %s • Esc to delete or keep it", msg.target.kind(), msg.target.name()))
+	m.state = StateViewLogs
+	return m.startFollowing(time.Time{})
+}
+
+// oneOffJobStatus fetches how the Job whose logs ended did
+func (m Model) oneOffJobStatus() tea.Cmd {
+	target := *m.oneOff
+	return func() tea.Msg {
+		status, err := m.k8sClient.TemplateJobStatus(context.Background(), target.namespace, target.job, target.pod)
+		return oneOffJobStatusMsg{job: target.job, status: status, err: err}
+	}
+}
+
+// handleOneOffJobStatus shows how the Job ended in its logs
+func (m Model) handleOneOffJobStatus(msg oneOffJobStatusMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateViewLogs || m.oneOff == nil || m.oneOff.job != msg.job {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.logViewer.SetStatus(fmt.Sprintf("Job %s: %v", msg.job, msg.err))
+		return m, nil
+	}
+	m.logViewer.SetStatus(fmt.Sprintf("Job %s %s • Esc to delete or keep it", msg.job, msg.status))
+	return m, nil
+}
+
+// canDeleteOneOff reports whether the result offers to delete a one-off pod
+func (m Model) canDeleteOneOff() bool {
+	return m.oneOff != nil && m.command != nil && (m.command.Name == "experiment" || m.command.Name == "template-job") &&
+		m.oneOff.namespace == m.namespace && m.oneOff.deployment == m.deployment
+}
+
+// leaveOneOffLogs shows what became of the one-off pod after its logs: an
+// experiment pod keeps running, a Job reports how it ended
+func (m Model) leaveOneOffLogs() (tea.Model, tea.Cmd) {
+	target := *m.oneOff
+	if target.job == "" {
+		m.state = StateShowResult
+		m.err = nil
+		m.result = fmt.Sprintf("The experiment pod %s runs on its own, outside %s's rollout.\n\n"+
+			"Press x to delete it or L to follow its logs again. A kept pod runs until it\n"+
+			"ends; the resources command finds it later.",
+			target.pod, m.deployment)
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(m.result)
+		return m, nil
+	}
+
+	m.startExecution()
+	ctx := m.exec.context()
+	return m, m.whileExecuting(func() tea.Msg {
+		status, err := m.k8sClient.TemplateJobStatus(ctx, target.namespace, target.job, target.pod)
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: fmt.Sprintf("Job %s %s.\n\n"+
+			"Press x to delete the Job and its pod or L to follow its logs again. A kept\n"+
+			"Job stays until deleted; the resources command finds it later.", target.job, status)}
+	})
+}
+
+// oneOffKey handles the keys a one-off pod result offers: x deletes the pod
+// or Job, L follows its logs again
+func (m Model) oneOffKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, keys.DeleteOneOff):
+		target := *m.oneOff
+		m.oneOff = nil
+		m.startExecution()
+		ctx := m.exec.context()
+		return m, m.whileExecuting(func() tea.Msg {
+			var err error
+			if target.job != "" {
+				err = m.k8sClient.DeleteTemplateJob(ctx, target.namespace, target.job)
+			} else {
+				err = m.k8sClient.DeleteExperimentPod(ctx, target.namespace, target.pod)
+			}
+			if err != nil {
+				return CommandResultMsg{err: err}
+			}
+			return CommandResultMsg{result: fmt.Sprintf("Deleted the %s %s", target.kind(), target.name())}
+		}), true
+	case key.Matches(msg, keys.OneOffLogs):
+		model, cmd := m.handleOneOffStarted(oneOffStartedMsg{target: m.oneOff})
+		return model, cmd, true
+	}
+	return m, nil, false
+}
+
+// oneOffHelp is the help of a one-off pod result
+func (m Model) oneOffHelp() string {
+	return fmt.Sprintf("x: delete the %s • L: follow its logs • ", m.oneOff.kind())
+}
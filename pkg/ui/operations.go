@@ -0,0 +1,314 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxQueuedKeys bounds the keys kept while khelper is busy
+const maxQueuedKeys = 64
+
+// errCancelled marks an operation stopped with Esc
+var errCancelled = errors.New("cancelled")
+
+// opState is where an operation is in its life
+type opState int
+
+const (
+	opPending opState = iota // waiting to start, e.g. for the cluster
+	opRunning
+	opDone
+	opFailed
+)
+
+// operation is a cluster call khelper makes for the user: a command or the
+// first load of a list
+type operation struct {
+	id      int
+	name    string
+	command bool // a command shown with the spinner, not a list
+	state   opState
+	started time.Time
+	err     error
+}
+
+// operations tracks the running operations and the keys pressed while they
+// keep khelper busy, so they are replayed instead of lost. It is shared by
+// the model's copies, as the commands update it from their goroutines.
+type operations struct {
+	mu   sync.Mutex
+	next int
+	ops  []*operation // unfinished ones, then the last finished command
+	keys []tea.KeyMsg
+}
+
+func newOperations() *operations {
+	return &operations{}
+}
+
+// start adds a pending operation and returns its ID
+func (o *operations) start(name string, command bool) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.next++
+	o.ops = append(o.ops, &operation{id: o.next, name: name, command: command, started: time.Now()})
+	return o.next
+}
+
+// run marks an operation as running
+func (o *operations) run(id int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, op := range o.ops {
+		if op.id == id && op.state == opPending {
+			op.state = opRunning
+			op.started = time.Now()
+		}
+	}
+}
+
+// finish marks an operation as done, or failed with err. Of the finished
+// ones only the last command, or list that failed, is kept to be shown.
+func (o *operations) finish(id int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var finished *operation
+	for _, op := range o.ops {
+		if op.id == id && op.state < opDone {
+			finished = op
+		}
+	}
+	if finished == nil {
+		return
+	}
+	finished.state, finished.err = opDone, err
+	if err != nil {
+		finished.state = opFailed
+	}
+	kept := o.ops[:0]
+	for _, op := range o.ops {
+		if op.state < opDone || (op == finished && (op.command || err != nil)) {
+			kept = append(kept, op)
+		}
+	}
+	o.ops = kept
+}
+
+// loading reports whether a list's operation is unfinished
+func (o *operations) loading(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, op := range o.ops {
+		if !op.command && op.name == name && op.state < opDone {
+			return true
+		}
+	}
+	return false
+}
+
+// queue keeps a key pressed while busy, dropping the oldest beyond the limit
+func (o *operations) queue(msg tea.KeyMsg) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.keys = append(o.keys, msg)
+	if len(o.keys) > maxQueuedKeys {
+		o.keys = o.keys[len(o.keys)-maxQueuedKeys:]
+	}
+}
+
+// dequeue returns the oldest queued key
+func (o *operations) dequeue() (tea.KeyMsg, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.keys) == 0 {
+		return tea.KeyMsg{}, false
+	}
+	msg := o.keys[0]
+	o.keys = o.keys[1:]
+	return msg, true
+}
+
+// dropKeys forgets the queued keys, e.g. when the user cancels
+func (o *operations) dropKeys() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.keys = nil
+}
+
+// snapshot copies the operations and queued keys to render them
+func (o *operations) snapshot() ([]operation, []tea.KeyMsg) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ops := make([]operation, len(o.ops))
+	for i, op := range o.ops {
+		ops[i] = *op
+	}
+	return ops, append([]tea.KeyMsg(nil), o.keys...)
+}
+
+// opDoneMsg carries the result of a tracked list load
+type opDoneMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// trackList runs a list load as an operation; keys pressed on the list
+// before it arrives are queued
+func (m *Model) trackList(name string, cmd tea.Cmd) tea.Cmd {
+	ops := m.ops
+	id := ops.start(name, false)
+	return func() tea.Msg {
+		ops.run(id)
+		return opDoneMsg{id: id, msg: cmd()}
+	}
+}
+
+// loadErr returns the error of a list's result
+func loadErr(msg tea.Msg) error {
+	switch msg := msg.(type) {
+	case NamespacesLoadedMsg:
+		return msg.err
+	case DeploymentsLoadedMsg:
+		return msg.err
+	case PodsLoadedMsg:
+		return msg.err
+	case ContainersLoadedMsg:
+		return msg.err
+	}
+	return nil
+}
+
+// handleOpDone finishes a list load, hands its result on and replays the
+// keys pressed meanwhile
+func (m Model) handleOpDone(msg opDoneMsg) (tea.Model, tea.Cmd) {
+	m.ops.finish(msg.id, loadErr(msg.msg))
+	model, cmd := m.Update(msg.msg)
+	return replayKeys(model, cmd)
+}
+
+// finishCommandOp finishes a command's operation with the error shown for
+// it, if any, and replays the keys pressed meanwhile. A command still
+// executing, e.g. applying a change after linting it, keeps running.
+func finishCommandOp(model tea.Model, cmd tea.Cmd, op int) (tea.Model, tea.Cmd) {
+	next, ok := model.(Model)
+	if !ok || next.state == StateExecuting {
+		return model, cmd
+	}
+	next.ops.finish(op, next.err)
+	return replayKeys(next, cmd)
+}
+
+// replayKeys sends the keys queued while busy until one makes khelper
+// busy again. A confirmation shown meanwhile drops them, as a queued key
+// would answer it before the user has read it.
+func replayKeys(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	cmds := []tea.Cmd{cmd}
+	for {
+		next, ok := model.(Model)
+		if !ok || next.busy() {
+			break
+		}
+		if next.confirming() {
+			next.ops.dropKeys()
+			break
+		}
+		msg, ok := next.ops.dequeue()
+		if !ok {
+			break
+		}
+		var c tea.Cmd
+		model, c = next.Update(msg)
+		cmds = append(cmds, c)
+	}
+	return model, tea.Batch(cmds...)
+}
+
+// busyLists maps the list states to the operation loading their list
+var busyLists = map[AppState]string{
+	StateSelectNamespace:  "namespaces",
+	StateSelectDeployment: "deployments",
+	StateSelectPod:        "pods",
+	StateSelectContainer:  "containers",
+}
+
+// busy reports whether keys would be lost: a command runs, or the list
+// shown is loading
+func (m Model) busy() bool {
+	if m.state == StateExecuting {
+		return true
+	}
+	name, ok := busyLists[m.state]
+	return ok && m.ops.loading(name)
+}
+
+// confirming reports whether a change waits for the user's y
+func (m Model) confirming() bool {
+	return m.pendingUndo != nil || m.pendingLint != nil || m.pendingPatch != nil ||
+		m.pendingRename != nil || m.pendingConfigRestore != nil || m.pendingCleanup != nil || m.pendingOnline != nil
+}
+
+// queueKey keeps a key pressed while busy. Keys that cancel, quit or
+// switch the kubeconfig or namespace are handled at once and drop the
+// queue instead. A y that would answer a confirmation is dropped, never
+// replayed into a prompt the user has not seen; elsewhere, e.g. in a
+// filter, it is queued like any key.
+func (m Model) queueKey(msg tea.KeyMsg) bool {
+	if !m.busy() || m.showHelp {
+		return false
+	}
+	if key.Matches(msg, keys.Confirm) && m.confirming() {
+		return true
+	}
+	if key.Matches(msg, keys.Cancel, keys.Back, keys.ForceQuit, keys.KubeConfig, keys.Namespace, keys.QuickOpen, keys.Help) {
+		m.ops.dropKeys()
+		return false
+	}
+	m.ops.queue(msg)
+	return true
+}
+
+// statusBar shows the running operations, the keys queued meanwhile and
+// how the last command ended. Accessible mode announces them instead.
+func (m Model) statusBar() string {
+	parts := m.statusParts()
+	if len(parts) == 0 || accessible {
+		return ""
+	}
+	return StatusBarStyle.Render(strings.Join(parts, " • "))
+}
+
+// statusParts describes each running operation, the queued keys and the
+// last command
+func (m Model) statusParts() []string {
+	ops, queued := m.ops.snapshot()
+	var parts []string
+	for _, op := range ops {
+		switch {
+		case op.state == opPending || (op.command && op.state == opRunning && m.exec.isWaiting()):
+			parts = append(parts, "○ "+op.name+" waiting for the cluster")
+		case op.state == opRunning && op.command:
+			parts = append(parts, fmt.Sprintf("● %s %s", op.name, time.Since(op.started).Round(time.Second)))
+		case op.state == opRunning:
+			parts = append(parts, "● loading "+op.name)
+		case op.state == opDone:
+			parts = append(parts, "✓ "+op.name)
+		case op.state == opFailed:
+			reason, _, _ := strings.Cut(op.err.Error(), "\n")
+			parts = append(parts, "✗ "+op.name+": "+truncateWidth(reason, 40, "…"))
+		}
+	}
+	if len(queued) > 0 {
+		names := make([]string, len(queued))
+		for i, k := range queued {
+			names[i] = k.String()
+		}
+		parts = append(parts, "⌨ queued: "+truncateWidth(strings.Join(names, " "), 30, "…"))
+	}
+	return parts
+}
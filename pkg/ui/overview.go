@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// overviewMaxItems is how many entries each overview section lists
+const overviewMaxItems = 5
+
+// overviewLoadedMsg carries the overview of the namespace it was loaded for
+type overviewLoadedMsg struct {
+	namespace string
+	overview  *k8s.NamespaceOverview
+	err       error
+}
+
+// showOverview switches to the namespace overview and loads it
+func (m Model) showOverview() (tea.Model, tea.Cmd) {
+	m.state = StateNamespaceOverview
+	m.overview = nil
+	m.overviewErr = nil
+	return m, m.loadOverview()
+}
+
+func (m *Model) loadOverview() tea.Cmd {
+	namespace := m.namespace
+	return func() tea.Msg {
+		overview, err := m.k8sClient.GetNamespaceOverview(context.Background(), namespace)
+		return overviewLoadedMsg{namespace: namespace, overview: overview, err: err}
+	}
+}
+
+// leaveOverview continues from the overview to the deployment list
+func (m Model) leaveOverview() (tea.Model, tea.Cmd) {
+	m.state = StateSelectDeployment
+	m.depSelector.Reset()
+	return m, m.loadDeployments()
+}
+
+// overviewView renders the namespace overview on one screen
+func (m Model) overviewView() string {
+	if m.overviewErr != nil {
+		return RenderError(m.overviewErr.Error())
+	}
+	o := m.overview
+	if o == nil {
+		return RenderLoading(fmt.Sprintf("Loading overview of %s...", m.namespace))
+	}
+
+	var b strings.Builder
+	healthy := o.Deployments - len(o.Unhealthy)
+	b.WriteString(LabelStyle.Render("Deployments: "))
+	b.WriteString(ValueStyle.Render(fmt.Sprintf("%d", o.Deployments)))
+	if len(o.Unhealthy) > 0 {
+		b.WriteString(ValueStyle.Render(fmt.Sprintf(" (%d healthy, ", healthy)))
+		b.WriteString(ErrorStyle.Render(fmt.Sprintf("%d unhealthy", len(o.Unhealthy))))
+		b.WriteString(ValueStyle.Render(")"))
+	}
+	b.WriteString("\n")
+	writeOverviewSection(&b, m.width, "", o.Unhealthy, ErrorStyle.Render)
+
+	writeOverviewSection(&b, m.width, "Pending pods", o.PendingPods, WarningStyle.Render)
+
+	warnings := make([]string, len(o.Warnings))
+	for i, e := range o.Warnings {
+		age := formatAge(time.Since(k8s.EventTime(e)))
+		warnings[i] = fmt.Sprintf("%s ago %s %s/%s: %s", age, e.Reason, strings.ToLower(e.InvolvedObject.Kind), e.InvolvedObject.Name, e.Message)
+	}
+	writeOverviewSection(&b, m.width, "Warning events (last hour)", warnings, WarningStyle.Render)
+
+	writeOverviewSection(&b, m.width, "Failed jobs", o.FailedJobs, ErrorStyle.Render)
+
+	if len(o.Unhealthy)+len(o.PendingPods)+len(o.Warnings)+len(o.FailedJobs) == 0 {
+		b.WriteString("\n")
+		b.WriteString(SuccessStyle.Render("✓ Nothing needs attention"))
+	}
+	return b.String()
+}
+
+// writeOverviewSection writes a titled list of at most overviewMaxItems
+// entries cut to the screen width, or nothing for an empty list
+func writeOverviewSection(b *strings.Builder, width int, title string, items []string, render func(...string) string) {
+	if len(items) == 0 {
+		return
+	}
+	if title != "" {
+		b.WriteString("\n")
+		b.WriteString(LabelStyle.Render(fmt.Sprintf("%s: %d", title, len(items))))
+		b.WriteString("\n")
+	}
+	for i, item := range items {
+		if i == overviewMaxItems {
+			b.WriteString(DimStyle.Render(fmt.Sprintf("  … and %d more", len(items)-overviewMaxItems)))
+			b.WriteString("\n")
+			break
+		}
+		line := []rune("  • " + item)
+		if max := width - 6; max > 0 && len(line) > max {
+			line = append(line[:max-1], '…')
+		}
+		b.WriteString(render(string(line)))
+		b.WriteString("\n")
+	}
+}
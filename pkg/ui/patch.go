@@ -0,0 +1,281 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// patchDiffContext is how many unchanged lines the patch preview shows
+// around each change
+const patchDiffContext = 3
+
+// patchRetryHint follows the errors of a patch that editing it can fix
+const patchRetryHint = "\n\nRun patch again to fix it, the text is kept."
+
+// patchTypePattern matches the comment choosing the patch type
+var patchTypePattern = regexp.MustCompile(`^#\s*type:\s*(\S+)\s*$`)
+
+// patchEditedMsg reports that the editor closed the patch file
+type patchEditedMsg struct {
+	file string
+	err  error
+}
+
+// patchPreviewMsg carries a patch checked with a server-side dry run and
+// the diff it makes
+type patchPreviewMsg struct {
+	patch   k8s.Patch
+	preview string
+	changed bool
+	err     error
+}
+
+// patchHeader explains the patch file; its lines are not kept
+const patchHeader = `## Patch for deployment %s in %s. Save and quit to preview the change; it is
+## checked with a server-side dry run and only applied once confirmed. Leave
+## no patch to cancel.
+##
+## A mapping is a strategic merge patch, containers merge by name:
+##
+##   spec:
+##     template:
+##       spec:
+##         containers:
+##         - name: app
+##           resources:
+##             limits: {memory: 512Mi}
+##
+## A list is a JSON patch (RFC 6902):
+##
+##   - op: replace
+##     path: /spec/revisionHistoryLimit
+##     value: 5
+##
+## A "# type: merge" line makes a mapping a JSON merge patch (RFC 7386),
+## where lists are replaced.
+
+`
+
+// editPatch opens the patch file in the user's editor, holding the last
+// patch typed so a refused one can be fixed
+func (m Model) editPatch() (tea.Model, tea.Cmd) {
+	dir, err := os.MkdirTemp("", "khelper-patch-")
+	if err != nil {
+		m.state = StateShowResult
+		m.err = fmt.Errorf("failed to create temporary directory: %w", err)
+		return m, nil
+	}
+	file := filepath.Join(dir, "patch-"+m.deployment+".yaml")
+	content := fmt.Sprintf(patchHeader, m.deployment, m.namespace) + m.patchText
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		m.state = StateShowResult
+		m.err = fmt.Errorf("failed to write %s: %w", file, err)
+		return m, nil
+	}
+	editor := editorCommand(file)
+	return m, tea.ExecProcess(editor, func(err error) tea.Msg {
+		if err != nil {
+			err = fmt.Errorf("failed to run %s: %w", editor.Path, err)
+		}
+		return patchEditedMsg{file: file, err: err}
+	})
+}
+
+// handlePatchEdited checks the patch and previews it with a dry run
+func (m Model) handlePatchEdited(msg patchEditedMsg) (tea.Model, tea.Cmd) {
+	defer os.RemoveAll(filepath.Dir(msg.file))
+	m.state = StateShowResult
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+	content, err := os.ReadFile(msg.file)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	text, patchType := readPatchText(string(content))
+	m.patchText = text
+	if strings.TrimSpace(stripComments(text)) == "" {
+		m.err = nil
+		m.result = fmt.Sprintf("No patch, %s was left as it is.", m.deployment)
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(m.result)
+		return m, nil
+	}
+	patch, err := k8s.ParsePatch(patchType, text)
+	if err != nil {
+		m.err = fmt.Errorf("%w"+patchRetryHint, err)
+		return m, nil
+	}
+	m.startExecution()
+	return m, m.whileExecuting(m.previewPatch(patch))
+}
+
+// readPatchText drops the header from the patch file and reads the type
+// comment, if any
+func readPatchText(content string) (text, patchType string) {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "##") {
+			continue
+		}
+		if match := patchTypePattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			patchType = match[1]
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimLeft(strings.Join(lines, "\n"), "\n"), patchType
+}
+
+// stripComments drops the comment lines of YAML
+func stripComments(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// patchChange is the audit history entry of a patch
+func (m Model) patchChange(patch k8s.Patch) config.Change {
+	return config.Change{Namespace: m.namespace, Deployment: m.deployment, Operation: config.OpPatch, After: patch.Summary()}
+}
+
+// previewPatch applies the patch in a dry run and describes the diff it
+// makes and the lint findings of the resulting template
+func (m *Model) previewPatch(patch k8s.Patch) tea.Cmd {
+	ctx := m.exec.context()
+	namespace, deployment := m.namespace, m.deployment
+	thresholds := m.config.GetLintThresholds(m.k8sClient.GetKubeConfigPath())
+	cause := changeCause([]config.Change{m.patchChange(patch)})
+	return func() tea.Msg {
+		before, after, err := m.k8sClient.PatchDeployment(k8s.WithChangeCause(ctx, cause), namespace, deployment, patch, true)
+		if err != nil {
+			return patchPreviewMsg{err: err}
+		}
+		diff, err := PatchDiff(before, after)
+		if err != nil {
+			return patchPreviewMsg{err: err}
+		}
+		// Applying writes the previewed version or fails
+		patch.ResourceVersion = before.ResourceVersion
+		if diff == "" {
+			return patchPreviewMsg{patch: patch, preview: fmt.Sprintf("The patch changes nothing in %s.", deployment)}
+		}
+		shown, blocked, err := LintGate(k8s.LintPodTemplate(&after.Spec.Template), thresholds)
+		if err != nil {
+			return patchPreviewMsg{err: err}
+		}
+		if blocked {
+			return patchPreviewMsg{err: fmt.Errorf("patch refused, the template has lint findings:\n%s", FormatLintFindings(shown))}
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s patch of %s in %s, checked with a dry run:\n\n", patch.Type, deployment, namespace)
+		b.WriteString(diff)
+		if len(shown) > 0 {
+			b.WriteString("\n⚠ Lint findings in the template:\n")
+			b.WriteString(FormatLintFindings(shown))
+		}
+		return patchPreviewMsg{patch: patch, preview: strings.TrimRight(b.String(), "\n"), changed: true}
+	}
+}
+
+// PatchDiff compares the manifests of a deployment before and after a patch,
+// with the unchanged lines around each change
+func PatchDiff(before, after *appsv1.Deployment) (string, error) {
+	a, err := k8s.PatchManifest(before)
+	if err != nil {
+		return "", err
+	}
+	b, err := k8s.PatchManifest(after)
+	if err != nil {
+		return "", err
+	}
+	if a == b {
+		return "", nil
+	}
+	return contextDiff(a, b, patchDiffContext), nil
+}
+
+func (m Model) handlePatchPreview(msg patchPreviewMsg) (tea.Model, tea.Cmd) {
+	m.state = StateShowResult
+	if msg.err != nil {
+		m.err = fmt.Errorf("%w"+patchRetryHint, msg.err)
+		return m, nil
+	}
+	m.err = nil
+	if msg.changed {
+		m.pendingPatch = &msg.patch
+	}
+	m.result = msg.preview
+	m.resultViewer.SetHighlighter(highlightDiff)
+	m.resultViewer.SetContent(msg.preview)
+	return m, nil
+}
+
+// handlePatchKey applies the previewed patch once confirmed, edits it
+// again or scrolls the preview; any other key cancels
+func (m Model) handlePatchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	patch := *m.pendingPatch
+	switch {
+	case key.Matches(msg, keys.Scroll.Up, keys.Scroll.Down, keys.Scroll.PageUp, keys.Scroll.PageDown, keys.Top, keys.Bottom):
+		var cmd tea.Cmd
+		m.resultViewer, cmd = m.resultViewer.Update(msg)
+		return m, cmd
+	case key.Matches(msg, keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.EditPatch):
+		m.pendingPatch = nil
+		return m.editPatch()
+	case key.Matches(msg, keys.Confirm):
+		m.pendingPatch = nil
+		m.startExecution()
+		return m, m.whileExecuting(m.applyPatch(patch))
+	}
+	m.pendingPatch = nil
+	m.state = StateSelectCommand
+	m.cmdSelector.Reset()
+	return m, nil
+}
+
+// applyPatch applies a confirmed patch and records it in the audit history
+func (m *Model) applyPatch(patch k8s.Patch) tea.Cmd {
+	ctx := m.exec.context()
+	namespace, deployment := m.namespace, m.deployment
+	change := m.patchChange(patch)
+	return func() tea.Msg {
+		var diff string
+		note, err := ApplyChange(ctx, m.k8sClient, change, func(ctx context.Context) error {
+			before, after, err := m.k8sClient.PatchDeployment(ctx, namespace, deployment, patch, false)
+			if err != nil {
+				return err
+			}
+			diff, err = PatchDiff(before, after)
+			return err
+		})
+		if errors.Is(err, k8s.ErrChangedSincePreview) {
+			return CommandResultMsg{err: fmt.Errorf("%w"+patchRetryHint, err)}
+		}
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: withNote(fmt.Sprintf("Patched %s: %s\n\n%s", deployment, change.After, strings.TrimRight(diff, "\n")), note)}
+	}
+}
@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"khelper/pkg/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pathPickerParent is the list entry that goes up one directory
+const pathPickerParent = "../"
+
+// PathPicker browses the local filesystem for a directory: Enter opens the
+// highlighted directory or picks the one being browsed, which is highlighted
+// first. Backspace on an empty filter goes up. Recent paths are listed on
+// top and picked directly, and a typed path starting with / or ~ is opened
+// with Enter.
+type PathPicker struct {
+	list       FuzzyList
+	title      string
+	dir        string // absolute directory being browsed
+	showHidden bool
+	recents    map[string]bool
+	err        error // why the directory could not be read
+}
+
+// NewPathPicker creates a directory picker
+func NewPathPicker(title string) PathPicker {
+	return PathPicker{list: NewFuzzyList(title), title: title}
+}
+
+// Open starts browsing at start, or its closest existing parent, with the
+// given recent paths on top. Without a start the working directory is used.
+func (p *PathPicker) Open(start string, recents []string) {
+	p.recents = make(map[string]bool, len(recents))
+	for _, r := range recents {
+		p.recents[r] = true
+	}
+	p.list.SetRecentItems(recents)
+
+	dir := config.ExpandPath(start)
+	if start == "" {
+		dir, _ = os.Getwd()
+	}
+	dir, _ = filepath.Abs(dir)
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	p.cd(dir)
+}
+
+// cd browses a directory
+func (p *PathPicker) cd(dir string) {
+	p.dir = dir
+	p.err = nil
+	items := []string{p.useItem()}
+	if filepath.Dir(dir) != dir {
+		items = append(items, pathPickerParent)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		p.err = err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !p.showHidden {
+			continue
+		}
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if isDir {
+			dirs = append(dirs, name+"/")
+		}
+	}
+	sort.Strings(dirs)
+	p.list.title = p.title + "  " + displayPath(dir)
+	p.list.SetItems(append(items, dirs...))
+	p.list.Reset()
+	p.list.SelectItem(p.useItem())
+}
+
+// useItem is the list entry that picks the directory being browsed
+func (p *PathPicker) useItem() string {
+	return "✓ use " + displayPath(p.dir)
+}
+
+// Select handles Enter: it returns the picked path, or false if it opened a
+// directory instead
+func (p *PathPicker) Select() (string, bool) {
+	input := p.list.GetInput()
+	if strings.HasPrefix(input, "/") || strings.HasPrefix(input, "~") {
+		if info, err := os.Stat(config.ExpandPath(input)); err == nil && info.IsDir() {
+			p.cd(config.ExpandPath(input))
+			return "", false
+		}
+	}
+
+	selected := p.list.GetSelected()
+	switch {
+	case selected == "":
+		return "", false
+	case p.recents[selected]:
+		return selected, true
+	case selected == p.useItem():
+		return displayPath(p.dir), true
+	case selected == pathPickerParent:
+		p.Up()
+		return "", false
+	}
+	p.cd(filepath.Join(p.dir, strings.TrimSuffix(selected, "/")))
+	return "", false
+}
+
+// Up goes to the parent directory
+func (p *PathPicker) Up() {
+	if parent := filepath.Dir(p.dir); parent != p.dir {
+		p.cd(parent)
+	}
+}
+
+// ToggleHidden shows or hides the directories starting with a dot
+func (p *PathPicker) ToggleHidden() {
+	p.showHidden = !p.showHidden
+	p.cd(p.dir)
+}
+
+// InputEmpty reports whether the filter is empty
+func (p *PathPicker) InputEmpty() bool {
+	return p.list.GetInput() == ""
+}
+
+func (p PathPicker) Update(msg tea.Msg) (PathPicker, tea.Cmd) {
+	var cmd tea.Cmd
+	p.list, cmd = p.list.Update(msg)
+	return p, cmd
+}
+
+// View renders the directory list
+func (p *PathPicker) View() string {
+	view := p.list.View()
+	if p.err != nil {
+		view += "\n" + ErrorStyle.Render(p.err.Error())
+	}
+	return view
+}
+
+// displayPath shows a path under the home directory with ~
+func displayPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rel, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+)
+
+// forwardRetryDelay is how long a saved port-forward waits before it
+// reconnects, e.g. to the new pod after a rollout
+const forwardRetryDelay = 2 * time.Second
+
+// ForwardClient is what a saved port-forward needs of a client
+type ForwardClient interface {
+	k8s.Connection
+	k8s.Forwarder
+}
+
+// SavedForward is a saved port-forward with the client of its cluster
+type SavedForward struct {
+	Name string
+	config.PortForward
+	Client ForwardClient
+}
+
+// forwardSaved forwards a saved port-forward's port until ctx is cancelled,
+// reconnecting when the connection is lost. report is called with the pod
+// once forwarding is ready and with the error when it is not. With
+// failFast, failing before the first connection is returned instead.
+func forwardSaved(ctx context.Context, f SavedForward, failFast bool, report func(pod string, err error)) error {
+	ports, err := k8s.ParsePortPairs(f.Ports)
+	if err != nil {
+		if !failFast {
+			report("", err)
+		}
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	var connected atomic.Bool
+	for {
+		pod, podPorts, err := f.Client.ResolvePortForward(ctx, f.Namespace, f.Target, ports)
+		if err == nil {
+			ready := make(chan struct{})
+			go func() {
+				select {
+				case <-ready:
+					connected.Store(true)
+					report(pod, nil)
+				case <-ctx.Done():
+				}
+			}()
+			err = f.Client.PortForward(ctx, k8s.PortForwardOptions{
+				Namespace: f.Namespace,
+				PodName:   pod,
+				Ports:     podPorts,
+				ReadyChan: ready,
+			})
+			if err == nil {
+				err = fmt.Errorf("connection to %s closed", pod)
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if failFast && !connected.Load() {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		report("", err)
+		select {
+		case <-time.After(forwardRetryDelay):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// RunSavedForwards runs saved port-forwards until interrupted, printing
+// when each is ready or lost. It fails if one cannot be started.
+func RunSavedForwards(forwards []SavedForward) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var failed error
+	for _, f := range forwards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := ""
+			err := forwardSaved(ctx, f, true, func(pod string, err error) {
+				msg := fmt.Sprintf("%s: forwarding %s -> %s via %s", f.Name, f.Ports, f.Target, pod)
+				if err != nil {
+					msg = fmt.Sprintf("%s: %v, reconnecting", f.Name, err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				// A forward that keeps failing the same way is reported once
+				if msg != last {
+					fmt.Fprintln(Messages, msg)
+					last = msg
+				}
+			})
+			if err != nil {
+				mu.Lock()
+				failed = err
+				mu.Unlock()
+				cancel()
+			}
+		}()
+	}
+	fmt.Fprintln(Messages, "Press Ctrl+C to stop...")
+	wg.Wait()
+	if failed != nil {
+		return failed
+	}
+	fmt.Fprintln(Messages, "\nStopping port forwards...")
+	return nil
+}
+
+// RunReverseTunnel sends a service's traffic to a local address until
+// interrupted
+func RunReverseTunnel(k8sClient k8s.Forwarder, opts k8s.ReverseTunnelOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts.Out = Messages
+	fmt.Fprintln(Messages, "Press Ctrl+C to stop and give the service back to its pods...")
+	return k8sClient.ReverseTunnel(ctx, opts)
+}
+
+// runningForward is a saved port-forward started with the TUI
+type runningForward struct {
+	SavedForward
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	pod string // the pod forwarded to, empty while not connected
+	err error
+}
+
+// forwardChangedMsg reports that a background port-forward connected or
+// failed
+type forwardChangedMsg struct {
+	changes chan struct{}
+}
+
+// autostartForwardsMsg starts the saved port-forwards of the kubeconfig the
+// TUI opened with
+type autostartForwardsMsg struct{}
+
+// autostartForwards is the command of Init sending autostartForwardsMsg
+func autostartForwards() tea.Msg {
+	return autostartForwardsMsg{}
+}
+
+// startForwards starts the saved port-forwards set to autostart for the
+// current kubeconfig, stopping those of the previous one
+func (m *Model) startForwards() tea.Cmd {
+	m.stopForwards()
+	names := m.config.AutostartPortForwards(m.k8sClient.GetKubeConfigPath())
+	if len(names) == 0 {
+		return nil
+	}
+	changes := make(chan struct{}, 1)
+	m.forwardChanges = changes
+	for _, name := range names {
+		ctx, cancel := context.WithCancel(context.Background())
+		f := &runningForward{
+			SavedForward: SavedForward{Name: name, PortForward: m.config.PortForwards[name], Client: m.k8sClient},
+			cancel:       cancel,
+		}
+		m.forwards = append(m.forwards, f)
+		go forwardSaved(ctx, f.SavedForward, false, func(pod string, err error) {
+			f.mu.Lock()
+			f.pod, f.err = pod, err
+			f.mu.Unlock()
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		})
+	}
+	return waitForwardChange(changes)
+}
+
+// stopForwards stops the background port-forwards
+func (m *Model) stopForwards() {
+	for _, f := range m.forwards {
+		f.cancel()
+	}
+	m.forwards = nil
+	m.forwardChanges = nil
+}
+
+// waitForwardChange waits for a background port-forward to connect or fail
+func waitForwardChange(changes chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		<-changes
+		return forwardChangedMsg{changes: changes}
+	}
+}
+
+// handleForwardChanged redraws the port-forwards line and waits for the
+// next change of the current forwards
+func (m Model) handleForwardChanged(msg forwardChangedMsg) (tea.Model, tea.Cmd) {
+	if msg.changes != m.forwardChanges {
+		return m, nil
+	}
+	return m, waitForwardChange(msg.changes)
+}
+
+// forwardsLine shows the background port-forwards and whether they are up
+func (m Model) forwardsLine() string {
+	if len(m.forwards) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(m.forwards))
+	for _, f := range m.forwards {
+		f.mu.Lock()
+		part := fmt.Sprintf("%s %s ", f.Name, f.Ports)
+		switch {
+		case f.pod != "":
+			part += SuccessStyle.Render("✓")
+		case f.err != nil:
+			part += ErrorStyle.Render("✗ " + f.err.Error())
+		default:
+			part += "connecting"
+		}
+		f.mu.Unlock()
+		parts = append(parts, part)
+	}
+	return InfoStyle.Render("⇄ ") + strings.Join(parts, InfoStyle.Render(" · "))
+}
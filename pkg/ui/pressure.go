@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// pressureWarnPercent is the usage of a limit from which a container is
+// reported as close to it
+const pressureWarnPercent = 90
+
+// formatResourcePressure renders the containers of all pods with their
+// restarts, last termination and usage against limits, followed by what
+// that suggests per container
+func formatResourcePressure(deployment string, p *k8s.ResourcePressure) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Resource pressure of %s\n\n", deployment)
+	if len(p.Containers) == 0 {
+		b.WriteString("No pods found.\n")
+		return b.String()
+	}
+
+	header := []string{"POD", "CONTAINER", "RESTARTS", "LAST TERMINATION", "MEMORY USE/LIMIT", "CPU USE/LIMIT"}
+	rows := [][]string{header}
+	for _, c := range p.Containers {
+		rows = append(rows, []string{
+			c.Pod,
+			c.Container,
+			fmt.Sprint(c.Restarts),
+			lastTermination(c),
+			usageOfLimit(c.MemoryUsage, c.MemoryLimit, c.MemoryPercent(), formatMemory),
+			usageOfLimit(c.CPUUsage, c.CPULimit, c.CPUPercent(), formatCPU),
+		})
+	}
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(row)-1 {
+				b.WriteString(cell)
+			} else {
+				fmt.Fprintf(&b, "%-*s  ", widths[i], cell)
+			}
+		}
+		b.WriteString("\n")
+	}
+	if p.MetricsErr != nil {
+		fmt.Fprintf(&b, "\nUsage unknown: %v\n", p.MetricsErr)
+	}
+
+	findings := pressureFindings(p.Containers)
+	b.WriteString("\n")
+	if len(findings) == 0 {
+		b.WriteString("No OOM kills, restarts or containers close to their limits.")
+		return b.String()
+	}
+	b.WriteString("Findings:\n")
+	for _, finding := range findings {
+		b.WriteString("  • " + finding + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// pressureFindings sums up the containers of the same name across pods: OOM
+// kills against the limit and peak usage, other kills, and usage close to or
+// without limits
+func pressureFindings(containers []k8s.ContainerPressure) []string {
+	byName := map[string][]k8s.ContainerPressure{}
+	var names []string
+	for _, c := range containers {
+		if _, ok := byName[c.Container]; !ok {
+			names = append(names, c.Container)
+		}
+		byName[c.Container] = append(byName[c.Container], c)
+	}
+	sort.Strings(names)
+
+	var findings []string
+	for _, name := range names {
+		pods := byName[name]
+		var oomKilled, killed []k8s.ContainerPressure
+		var peak *resource.Quantity
+		nearMemory, nearCPU, noMemoryLimit := 0, 0, 0
+		for _, c := range pods {
+			switch {
+			case c.OOMKilled():
+				oomKilled = append(oomKilled, c)
+			case c.LastReason != "" && c.LastExitCode != 0:
+				killed = append(killed, c)
+			}
+			if c.MemoryUsage != nil && (peak == nil || c.MemoryUsage.Cmp(*peak) > 0) {
+				peak = c.MemoryUsage
+			}
+			if c.MemoryPercent() >= pressureWarnPercent {
+				nearMemory++
+			}
+			if c.CPUPercent() >= pressureWarnPercent {
+				nearCPU++
+			}
+			if c.MemoryLimit == nil {
+				noMemoryLimit++
+			}
+		}
+
+		if len(oomKilled) > 0 {
+			latest := oomKilled[0]
+			for _, c := range oomKilled {
+				if c.LastFinishedAt.After(latest.LastFinishedAt) {
+					latest = c
+				}
+			}
+			finding := fmt.Sprintf("%s was OOMKilled in %d of %d pods, last %s ago", name, len(oomKilled), len(pods),
+				formatAge(time.Since(latest.LastFinishedAt)))
+			if latest.MemoryLimit != nil {
+				finding += " at a " + formatMemory(latest.MemoryLimit) + " limit"
+			}
+			advice := ". Raise the memory limit, or look for a leak if usage keeps growing."
+			if peak != nil {
+				finding += ", peak usage now " + formatMemory(peak)
+				advice = ". Raise the memory limit above the peak, or look for a leak if usage keeps growing."
+			}
+			findings = append(findings, finding+advice)
+		}
+		for _, c := range killed {
+			findings = append(findings, fmt.Sprintf("%s in %s last ended with %s (exit %d, %s) %s ago", name, c.Pod, c.LastReason,
+				c.LastExitCode, exitCodeMeaning(c.LastExitCode), formatAge(time.Since(c.LastFinishedAt))))
+		}
+		if nearMemory > 0 {
+			findings = append(findings, fmt.Sprintf("%s uses %d%%+ of its memory limit in %d of %d pods and may be OOMKilled next",
+				name, pressureWarnPercent, nearMemory, len(pods)))
+		}
+		if nearCPU > 0 {
+			findings = append(findings, fmt.Sprintf("%s uses %d%%+ of its CPU limit in %d of %d pods and is likely throttled",
+				name, pressureWarnPercent, nearCPU, len(pods)))
+		}
+		if noMemoryLimit > 0 && len(oomKilled) == 0 {
+			findings = append(findings, fmt.Sprintf("%s has no memory limit, it can take the node's memory and is evicted first under memory pressure", name))
+		}
+	}
+	return findings
+}
+
+// lastTermination describes how a container last ended
+func lastTermination(c k8s.ContainerPressure) string {
+	if c.LastReason == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%d) %s ago", c.LastReason, c.LastExitCode, formatAge(time.Since(c.LastFinishedAt)))
+}
+
+// exitCodeMeaning explains the common exit codes of killed containers
+func exitCodeMeaning(code int32) string {
+	switch code {
+	case 1:
+		return "application error"
+	case 126:
+		return "command not executable"
+	case 127:
+		return "command not found"
+	case 137:
+		return "SIGKILL, e.g. a failed liveness probe or eviction"
+	case 139:
+		return "segmentation fault"
+	case 143:
+		return "SIGTERM, e.g. stopped by the kubelet"
+	}
+	if code > 128 {
+		return fmt.Sprintf("signal %d", code-128)
+	}
+	return "error"
+}
+
+// usageOfLimit renders usage against a limit such as "480Mi/512Mi (94%)"
+func usageOfLimit(usage, limit *resource.Quantity, percent int, format func(*resource.Quantity) string) string {
+	u, l := "?", "none"
+	if usage != nil {
+		u = format(usage)
+	}
+	if limit != nil {
+		l = format(limit)
+	}
+	if percent >= 0 {
+		return fmt.Sprintf("%s/%s (%d%%)", u, l, percent)
+	}
+	return u + "/" + l
+}
+
+func formatMemory(q *resource.Quantity) string {
+	return fmt.Sprintf("%dMi", q.Value()>>20)
+}
+
+func formatCPU(q *resource.Quantity) string {
+	return fmt.Sprintf("%dm", q.MilliValue())
+}
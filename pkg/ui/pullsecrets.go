@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pullSecretFix is the pull secret c creates from the stored registry
+// logins, for the registries that denied a pull
+type pullSecretFix struct {
+	namespace, deployment string
+	secret                k8s.PullSecret
+	// images are the images of the workload, to check the logins with
+	images []string
+	// reference is set when the pod template does not reference the secret
+	reference bool
+	// unstored are the denied registries without a stored login
+	unstored []string
+}
+
+// pullSecretsMsg carries a pull secret check and the fix c offers, if any
+type pullSecretsMsg struct {
+	report *k8s.PullSecretReport
+	fix    *pullSecretFix
+	err    error
+}
+
+// checkPullSecrets checks the pull secrets of the workload and plans the
+// secret c creates
+func (m Model) checkPullSecrets() tea.Cmd {
+	ctx := m.exec.context()
+	namespace, deployment := m.namespace, m.deployment
+	return func() tea.Msg {
+		report, err := m.k8sClient.CheckPullSecrets(ctx, namespace, deployment)
+		if err != nil {
+			return pullSecretsMsg{err: err}
+		}
+		var logins k8s.RegistryAuth
+		if creds, err := config.OpenCredentials(); err == nil {
+			logins = creds.RegistryLogin
+		}
+		return pullSecretsMsg{report: report, fix: planPullSecretFix(report, logins)}
+	}
+}
+
+// planPullSecretFix plans a secret with the stored logins of the registries
+// that denied a pull, named like the missing secret if one is referenced;
+// nil if no registry denied one
+func planPullSecretFix(report *k8s.PullSecretReport, logins k8s.RegistryAuth) *pullSecretFix {
+	denied := report.DeniedRegistries()
+	if len(denied) == 0 {
+		return nil
+	}
+	fix := &pullSecretFix{namespace: report.Namespace, deployment: report.Workload}
+	fix.secret.Name = PullSecretName(report)
+	fix.reference = !report.References(fix.secret.Name)
+	for _, image := range report.Images {
+		fix.images = append(fix.images, image.Image)
+	}
+	for _, registry := range denied {
+		var username, password string
+		ok := false
+		if logins != nil {
+			username, password, ok = logins(registry)
+		}
+		if !ok {
+			fix.unstored = append(fix.unstored, registry)
+			continue
+		}
+		fix.secret.Logins = append(fix.secret.Logins, k8s.RegistryLogin{Server: k8s.LoginServer(registry), Username: username, Password: password})
+	}
+	return fix
+}
+
+// PullSecretName is the name for a new pull secret: the first referenced one
+// that is missing, else one after the first registry that denied a pull
+func PullSecretName(report *k8s.PullSecretReport) string {
+	if missing := report.Missing(); len(missing) > 0 {
+		return missing[0]
+	}
+	registry := "registry"
+	if denied := report.DeniedRegistries(); len(denied) > 0 {
+		registry = denied[0]
+	}
+	return "pull-" + strings.NewReplacer(".", "-", ":", "-").Replace(registry)
+}
+
+func (m Model) handlePullSecrets(msg pullSecretsMsg) (tea.Model, tea.Cmd) {
+	m.state = StateShowResult
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+	m.err = nil
+	m.pullSecretFix = msg.fix
+	m.result = FormatPullSecrets(msg.report)
+	if fix := msg.fix; fix != nil {
+		m.result += "\n\n" + fix.describe(m.config.IsReadOnly())
+	}
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// FormatPullSecrets renders a pull secret check: the secrets referenced,
+// each image pulled with their logins and the pods stuck on a pull
+func FormatPullSecrets(report *k8s.PullSecretReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Image pull secrets of %s in %s\n", report.Workload, report.Namespace)
+	if len(report.Secrets) == 0 {
+		fmt.Fprintf(&b, "\n  None, neither in the pod template nor in service account %s\n", report.ServiceAccount)
+	}
+	for _, s := range report.Secrets {
+		switch {
+		case s.Missing:
+			fmt.Fprintf(&b, "\n  ✗ %s (%s): does not exist\n", s.Name, s.From)
+		case s.Err != nil:
+			fmt.Fprintf(&b, "\n  ✗ %s (%s): %v\n", s.Name, s.From, s.Err)
+		default:
+			servers := make([]string, 0, len(s.Logins))
+			for _, login := range s.Logins {
+				servers = append(servers, fmt.Sprintf("%s as %s", login.Server, login.Username))
+			}
+			fmt.Fprintf(&b, "\n  ✓ %s (%s): %s\n", s.Name, s.From, strings.Join(servers, ", "))
+		}
+	}
+
+	b.WriteString("\nImages\n")
+	for _, image := range report.Images {
+		how := "anonymously"
+		if image.Secret != "" {
+			how = "with " + image.Secret
+		}
+		if image.Err != nil {
+			fmt.Fprintf(&b, "\n  ✗ %s (%s)\n      %v\n", image.Image, image.Container, image.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n  ✓ %s (%s): pulls %s\n", image.Image, image.Container, how)
+	}
+
+	if len(report.PullErrors) > 0 {
+		b.WriteString("\nPods waiting on a pull\n\n")
+		for _, line := range report.PullErrors {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// describe tells what c does, or why it cannot
+func (f *pullSecretFix) describe(readOnly bool) string {
+	var b strings.Builder
+	if len(f.secret.Logins) > 0 && !readOnly {
+		servers := make([]string, 0, len(f.secret.Logins))
+		for _, login := range f.secret.Logins {
+			servers = append(servers, login.Server)
+		}
+		fmt.Fprintf(&b, "c creates secret %s with the stored logins to %s", f.secret.Name, strings.Join(servers, ", "))
+		if f.reference {
+			fmt.Fprintf(&b, " and adds it to the imagePullSecrets of %s", f.deployment)
+		}
+		b.WriteString(".\n")
+	}
+	for _, registry := range f.unstored {
+		fmt.Fprintf(&b, "No login to %s is stored: add one with 'khelper credentials set registry/%s',\nor create the secret with khelper pull-secrets --create.\n", registry, registry)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// canCreatePullSecret reports whether the result offers to create a pull
+// secret
+func (m Model) canCreatePullSecret() bool {
+	return m.pullSecretFix != nil && len(m.pullSecretFix.secret.Logins) > 0 && !m.config.IsReadOnly() &&
+		m.command != nil && m.command.Name == "pull-secrets" &&
+		m.pullSecretFix.namespace == m.namespace && m.pullSecretFix.deployment == m.deployment
+}
+
+// createPullSecret creates the planned pull secret
+func (m Model) createPullSecret(fix pullSecretFix) tea.Cmd {
+	ctx := m.exec.context()
+	return func() tea.Msg {
+		result, err := CreatePullSecret(ctx, m.k8sClient, fix.namespace, fix.deployment, fix.secret, fix.images, fix.reference)
+		if err != nil {
+			return CommandResultMsg{err: err}
+		}
+		return CommandResultMsg{result: result}
+	}
+}
+
+// PullSecretClient is what creating a pull secret needs of a client
+type PullSecretClient interface {
+	ChangeClient
+	k8s.Access
+}
+
+// CreatePullSecret checks the logins of a docker-registry secret against the
+// images, creates it and, if reference is set, adds it to the
+// imagePullSecrets of the deployment as a recorded patch
+func CreatePullSecret(ctx context.Context, client PullSecretClient, namespace, deployment string, secret k8s.PullSecret, images []string, reference bool) (string, error) {
+	if err := secret.CheckLogins(ctx, images); err != nil {
+		return "", err
+	}
+	if err := client.CreatePullSecret(ctx, namespace, secret); err != nil {
+		return "", err
+	}
+	result := fmt.Sprintf("Created secret %s in %s.", secret.Name, namespace)
+	switch {
+	case !reference:
+		return result + "\n\nPods retry their pull on their own, the secret is used from the next attempt.", nil
+	case k8s.IsCustomWorkload(deployment):
+		return result + fmt.Sprintf("\n\nAdd it to the imagePullSecrets of %s, khelper patches Deployments only.", deployment), nil
+	}
+	patch := k8s.PullSecretPatch(secret.Name)
+	change := config.Change{Namespace: namespace, Deployment: deployment, Operation: config.OpPatch, After: patch.Summary()}
+	note, err := ApplyChange(ctx, client, change, func(ctx context.Context) error {
+		_, _, err := client.PatchDeployment(ctx, namespace, deployment, patch, false)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("created secret %s but failed to add it to %s: %w", secret.Name, deployment, err)
+	}
+	return withNote(result+fmt.Sprintf(" Added it to the imagePullSecrets of %s, its pods are rolling.", deployment), note), nil
+}
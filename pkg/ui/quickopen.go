@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// quickOpenLoadedMsg carries the deployments of all namespaces of the
+// kubeconfig they were listed with
+type quickOpenLoadedMsg struct {
+	kubeconfig string
+	items      []string // "namespace/deployment"
+	err        error
+}
+
+// canQuickOpen reports whether Ctrl+T opens quick-open in the current state
+func (m Model) canQuickOpen() bool {
+	if m.k8sClient == nil {
+		return false
+	}
+	switch m.state {
+	case StateSelectKubeConfig, StateSelectNamespace, StateSelectDeployment, StateSelectCommand,
+		StateSelectPod, StateSelectContainer, StateShowResult:
+		return true
+	}
+	return false
+}
+
+// openQuickOpen shows the deployments of all namespaces as one list. They
+// are listed the first time and kept until the kubeconfig changes.
+func (m Model) openQuickOpen() (tea.Model, tea.Cmd) {
+	m.prevStates = append(m.prevStates, m.state)
+	m.state = StateQuickOpen
+	m.quickSelector.Reset()
+	if m.quickItems != nil && m.quickKubeConfig == m.k8sClient.GetKubeConfigPath() {
+		m.setQuickItems(m.quickItems)
+		return m, nil
+	}
+	return m, m.loadQuickOpen()
+}
+
+func (m *Model) loadQuickOpen() tea.Cmd {
+	m.quickSelector.SetError(nil)
+	m.quickSelector.SetLoading(true)
+	kubeconfig := m.k8sClient.GetKubeConfigPath()
+	return func() tea.Msg {
+		items, err := m.k8sClient.ListAllDeployments(context.Background())
+		return quickOpenLoadedMsg{kubeconfig: kubeconfig, items: items, err: err}
+	}
+}
+
+// handleQuickOpenLoaded caches the listed deployments and shows them if
+// quick-open is still up
+func (m Model) handleQuickOpenLoaded(msg quickOpenLoadedMsg) (tea.Model, tea.Cmd) {
+	if m.k8sClient == nil || msg.kubeconfig != m.k8sClient.GetKubeConfigPath() {
+		return m, nil
+	}
+	if msg.err != nil {
+		if m.state == StateQuickOpen {
+			m.quickSelector.SetError(msg.err)
+		}
+		return m, nil
+	}
+	if msg.items == nil {
+		msg.items = []string{}
+	}
+	m.quickItems, m.quickKubeConfig = msg.items, msg.kubeconfig
+	if m.state == StateQuickOpen {
+		m.setQuickItems(msg.items)
+	}
+	return m, nil
+}
+
+// setQuickItems fills the list, with the recent deployments that still
+// exist on top: the current namespace's first, then the others by namespace
+func (m *Model) setQuickItems(items []string) {
+	exists := make(map[string]bool, len(items))
+	for _, item := range items {
+		exists[item] = true
+	}
+	namespaces := make([]string, 0, len(m.config.RecentDeployments))
+	for namespace := range m.config.RecentDeployments {
+		if namespace != m.namespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	if m.namespace != "" {
+		namespaces = append([]string{m.namespace}, namespaces...)
+	}
+	var recents []string
+	for _, namespace := range namespaces {
+		for _, deployment := range m.config.GetRecentDeployments(namespace) {
+			if item := namespace + "/" + deployment; exists[item] {
+				recents = append(recents, item)
+			}
+		}
+	}
+	m.quickSelector.SetRecentItems(recents)
+	m.quickSelector.SetItems(items)
+}
+
+// quickOpenKey handles the keys quick-open adds to its list: Esc and
+// Backspace on an empty filter close it, Ctrl+R lists the deployments again
+func (m Model) quickOpenKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, keys.BackEmpty):
+		if m.quickSelector.GetInput() != "" {
+			return m, nil, false
+		}
+		fallthrough
+	case key.Matches(msg, keys.Back):
+		if len(m.prevStates) > 0 {
+			m.state = m.prevStates[len(m.prevStates)-1]
+			m.prevStates = m.prevStates[:len(m.prevStates)-1]
+		}
+		return m, nil, true
+	case key.Matches(msg, keys.Reload):
+		m.quickItems = nil
+		m.quickSelector.Reset()
+		return m, m.loadQuickOpen(), true
+	}
+	return m, nil, false
+}
+
+// chooseQuickOpen switches to the namespace and deployment picked
+func (m Model) chooseQuickOpen(selected string) (tea.Model, tea.Cmd) {
+	namespace, deployment, ok := strings.Cut(selected, "/")
+	if !ok {
+		return m, nil
+	}
+	m.prevStates = nil
+	m.showNamespaceChange = false
+	m.showKubeConfigChange = false
+	m.comparingClusters = false
+	if namespace != m.namespace {
+		m.namespace = namespace
+		m.config.SetNamespace(namespace)
+	}
+	return m.chooseDeployment(deployment)
+}
@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// renameMsg reports a rename planned, a step of it run or rolled back
+type renameMsg struct {
+	rename *k8s.Rename
+	// failed is the error of the step or rollback, shown at the checkpoint
+	failed error
+	// rollback is set for a rollback, with what it undid
+	rollback   bool
+	rolledBack []string
+	err        error
+}
+
+// planRename checks the deployment can be renamed and shows the steps
+func (m Model) planRename(to string) tea.Cmd {
+	ctx := m.exec.context()
+	namespace, deployment := m.namespace, m.deployment
+	return func() tea.Msg {
+		r, err := m.k8sClient.PlanRename(ctx, namespace, deployment, to)
+		return renameMsg{rename: r, err: err}
+	}
+}
+
+// renameChange is the audit history entry of a rename step or rollback
+func renameChange(r *k8s.Rename, after string) config.Change {
+	return config.Change{Namespace: r.Namespace, Deployment: r.From, Operation: config.OpRename, After: after}
+}
+
+// runRenameStep runs the next step of the rename, recording the ones that
+// change the cluster in the audit history. Their undo is the rollback, so
+// the note that undo cannot revert them is left out.
+func (m *Model) runRenameStep(r *k8s.Rename) tea.Cmd {
+	ctx := m.exec.context()
+	return func() tea.Msg {
+		if r.Next == k8s.RenameWait {
+			err := m.k8sClient.RunRenameStep(ctx, r)
+			return renameMsg{rename: r, failed: err}
+		}
+		title := r.StepTitle(r.Next)
+		_, err := ApplyChange(ctx, m.k8sClient, renameChange(r, strings.ToLower(title[:1])+title[1:]), func(ctx context.Context) error {
+			return m.k8sClient.RunRenameStep(ctx, r)
+		})
+		return renameMsg{rename: r, failed: err}
+	}
+}
+
+// rollbackRename undoes the steps of the rename done so far
+func (m *Model) rollbackRename(r *k8s.Rename) tea.Cmd {
+	ctx := m.exec.context()
+	return func() tea.Msg {
+		var done []string
+		_, err := ApplyChange(ctx, m.k8sClient, renameChange(r, "roll back the rename to "+r.To), func(ctx context.Context) error {
+			var err error
+			done, err = m.k8sClient.RollbackRename(ctx, r)
+			return err
+		})
+		return renameMsg{rename: r, failed: err, rollback: true, rolledBack: done}
+	}
+}
+
+func (m Model) handleRename(msg renameMsg) (tea.Model, tea.Cmd) {
+	m.state = StateShowResult
+	if msg.err != nil {
+		m.pendingRename = nil
+		m.err = msg.err
+		return m, nil
+	}
+	m.err = nil
+	r := msg.rename
+	m.pendingRename = r
+	var b strings.Builder
+	switch {
+	case msg.rollback && msg.failed == nil:
+		m.pendingRename = nil
+		fmt.Fprintf(&b, "Rolled back the rename of %s to %s:\n\n", r.From, r.To)
+		for _, line := range msg.rolledBack {
+			fmt.Fprintf(&b, "  ✓ %s\n", line)
+		}
+	case r.Next == k8s.RenameDone:
+		m.pendingRename = nil
+		fmt.Fprintf(&b, "Renamed %s to %s in %s.\n\n%s", r.From, r.To, r.Namespace, formatRename(r, nil))
+		m.deployment = r.To
+	default:
+		if msg.rollback {
+			fmt.Fprintf(&b, "The rollback stopped:\n\n")
+			for _, line := range msg.rolledBack {
+				fmt.Fprintf(&b, "  ✓ %s\n", line)
+			}
+			fmt.Fprintf(&b, "  ✗ %s\n\nr tries the rollback again.\n\n", msg.failed)
+			msg.failed = nil
+		}
+		fmt.Fprintf(&b, "Renaming %s to %s in %s, step by step:\n\n%s", r.From, r.To, r.Namespace, formatRename(r, msg.failed))
+	}
+	m.result = strings.TrimRight(b.String(), "\n")
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// formatRename lists the steps of a rename with the ones done, the next one
+// and its failure if any, then what the switch leaves alone
+func formatRename(r *k8s.Rename, failed error) string {
+	var b strings.Builder
+	for step := k8s.RenameCopy; step < k8s.RenameDone; step++ {
+		mark := "○"
+		switch {
+		case step < r.Next:
+			mark = "✓"
+		case step == r.Next && failed != nil:
+			mark = "✗"
+		case step == r.Next:
+			mark = "▸"
+		}
+		fmt.Fprintf(&b, "  %s %d. %s\n", mark, step+1, r.StepTitle(step))
+		if step == r.Next && failed != nil {
+			fmt.Fprintf(&b, "       %s\n", failed)
+		}
+	}
+	if len(r.Ingresses) > 0 {
+		fmt.Fprintf(&b, "\nIngresses follow the switched services: %s\n", strings.Join(r.Ingresses, ", "))
+	}
+	if len(r.Unswitched) > 0 {
+		fmt.Fprintf(&b, "\n⚠ Services selecting the pods of both: %s\n", strings.Join(r.Unswitched, ", "))
+	}
+	if len(r.Unmatched) > 0 && r.Next <= k8s.RenameDelete {
+		fmt.Fprintf(&b, "\n⚠ Stop matching with %s, update them before %s is deleted: %s\n", r.LabelChange(), r.From, strings.Join(r.Unmatched, ", "))
+	} else if len(r.Unmatched) > 0 {
+		fmt.Fprintf(&b, "\n⚠ No longer match with %s: %s\n", r.LabelChange(), strings.Join(r.Unmatched, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// handleRenameKey runs the next step once confirmed, rolls back or scrolls;
+// any other key stops the rename, keeping the steps done
+func (m Model) handleRenameKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.pendingRename
+	switch {
+	case key.Matches(msg, keys.Scroll.Up, keys.Scroll.Down, keys.Scroll.PageUp, keys.Scroll.PageDown, keys.Top, keys.Bottom):
+		var cmd tea.Cmd
+		m.resultViewer, cmd = m.resultViewer.Update(msg)
+		return m, cmd
+	case key.Matches(msg, keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Confirm) && !r.RollingBack:
+		m.startExecution()
+		return m, m.whileExecuting(m.runRenameStep(r))
+	case key.Matches(msg, keys.RollbackRename):
+		if r.Next == k8s.RenameCopy {
+			break
+		}
+		m.startExecution()
+		return m, m.whileExecuting(m.rollbackRename(r))
+	}
+	m.pendingRename = nil
+	m.err = nil
+	if r.Next == k8s.RenameCopy {
+		m.state = StateSelectCommand
+		m.cmdSelector.Reset()
+		return m, nil
+	}
+	if r.RollingBack {
+		var b strings.Builder
+		fmt.Fprintf(&b, "Stopped rolling back the rename of %s to %s, what was undone stays undone.", r.From, r.To)
+		for _, svc := range r.Services {
+			target := r.From
+			if svc.Switched {
+				target = r.To
+			}
+			fmt.Fprintf(&b, "\n  service %s selects the pods of %s", svc.Name, target)
+		}
+		m.result = b.String()
+	} else {
+		m.result = fmt.Sprintf("Stopped renaming %s to %s before step %d, the steps done are kept:\n\n%s",
+			r.From, r.To, r.Next+1, formatRename(r, nil))
+	}
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// renameHelp is the help line of a rename checkpoint
+func (m Model) renameHelp() string {
+	if m.pendingRename.RollingBack {
+		return "r: roll back again • ↑↓: scroll • any other key: stop here"
+	}
+	next := fmt.Sprintf("y: run step %d", m.pendingRename.Next+1)
+	if m.pendingRename.Next == k8s.RenameCopy {
+		return next + " • ↑↓: scroll • any other key: cancel"
+	}
+	return next + " • r: roll back • ↑↓: scroll • any other key: stop here"
+}
@@ -0,0 +1,427 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"khelper/pkg/k8s"
+)
+
+// resourcesLevel is how deep the resources explorer is
+type resourcesLevel int
+
+const (
+	levelKinds resourcesLevel = iota
+	levelInstances
+	levelManifest
+)
+
+// apiResourcesLoadedMsg carries the resource kinds found through discovery
+type apiResourcesLoadedMsg struct {
+	resources []k8s.APIResource
+	err       error
+}
+
+// resourceInstancesMsg carries the instances of a resource kind
+type resourceInstancesMsg struct {
+	names []string
+	err   error
+}
+
+// resourceManifestMsg carries the YAML of an instance
+type resourceManifestMsg struct {
+	manifest string
+	err      error
+}
+
+// resourceDeletedMsg reports the result of a delete
+type resourceDeletedMsg struct {
+	name string
+	err  error
+}
+
+// resourcesClosedMsg is sent when the embedded explorer is left
+type resourcesClosedMsg struct{}
+
+// ResourcesClient is what the resources explorer needs of a client
+type ResourcesClient interface {
+	k8s.Connection
+	k8s.ClusterInfo
+	k8s.Resources
+}
+
+// ResourcesModel explores any resource kind the cluster serves: pick a kind,
+// then an instance, and view or delete it
+type ResourcesModel struct {
+	client            ResourcesClient
+	namespace         string
+	embedded          bool // Esc on the kind list closes instead of quitting
+	readOnly          bool // deleting is disabled
+	level             resourcesLevel
+	kinds             FuzzyList
+	instances         FuzzyList
+	viewer            ResultViewer
+	resources         map[string]k8s.APIResource // label -> resource
+	resource          k8s.APIResource
+	name              string
+	showManagedFields bool
+	confirmDelete     bool
+	identity          *k8s.Identity // shown in the header
+	status            string
+	err               error
+	width             int
+	height            int
+}
+
+// NewResourcesModel creates the resources explorer for a namespace
+func NewResourcesModel(client ResourcesClient, namespace string) ResourcesModel {
+	kinds := NewFuzzyList("🧭 Select Resource Kind")
+	kinds.SetLoading(true)
+	return ResourcesModel{
+		client:    client,
+		namespace: namespace,
+		kinds:     kinds,
+		instances: NewFuzzyList("Select Resource"),
+		viewer:    NewResultViewer(),
+		resources: make(map[string]k8s.APIResource),
+	}
+}
+
+// SetReadOnly disables deleting instances
+func (m *ResourcesModel) SetReadOnly(readOnly bool) {
+	m.readOnly = readOnly
+}
+
+// Focus opens the explorer on an instance's YAML, as khelper get does; Esc
+// leads back to the other instances of its kind
+func (m *ResourcesModel) Focus(res k8s.APIResource, name string) {
+	m.resource = res
+	m.name = name
+	m.level = levelManifest
+	m.instances = NewFuzzyList(fmt.Sprintf("Select %s", res.Kind))
+	m.instances.SetLoading(true)
+	m.viewer.SetHighlighter(highlightYAML)
+	m.viewer.SetSource(strings.ToLower(res.Kind)+"-"+name, ".yaml")
+	m.viewer.SetContent("Loading...")
+}
+
+func (m ResourcesModel) Init() tea.Cmd {
+	cmds := []tea.Cmd{m.loadKinds()}
+	if m.level == levelManifest {
+		cmds = append(cmds, m.loadInstances(), m.loadManifest())
+	}
+	if !m.embedded {
+		// Standalone the explorer shows its own header
+		client := m.client
+		cmds = append(cmds, func() tea.Msg {
+			identity, _ := client.WhoAmI(context.Background())
+			return identityMsg{kubeconfig: client.GetKubeConfigPath(), identity: identity}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// resourceLabel is the line shown for a resource kind in the list
+func resourceLabel(r k8s.APIResource) string {
+	label := fmt.Sprintf("%s (%s)", r.String(), r.Kind)
+	if !r.Namespaced {
+		label += " [cluster]"
+	}
+	return label
+}
+
+func (m *ResourcesModel) loadKinds() tea.Cmd {
+	return func() tea.Msg {
+		resources, err := m.client.ListAPIResources(context.Background())
+		return apiResourcesLoadedMsg{resources: resources, err: err}
+	}
+}
+
+func (m *ResourcesModel) loadInstances() tea.Cmd {
+	res := m.resource
+	return func() tea.Msg {
+		names, err := m.client.ListResources(context.Background(), m.namespace, res)
+		return resourceInstancesMsg{names: names, err: err}
+	}
+}
+
+func (m *ResourcesModel) loadManifest() tea.Cmd {
+	res, name, keep := m.resource, m.name, m.showManagedFields
+	return func() tea.Msg {
+		manifest, err := m.client.GetResourceManifest(context.Background(), m.namespace, res, name, keep)
+		return resourceManifestMsg{manifest: manifest, err: err}
+	}
+}
+
+func (m *ResourcesModel) deleteInstance() tea.Cmd {
+	res, name := m.resource, m.name
+	return func() tea.Msg {
+		err := m.client.DeleteResource(context.Background(), m.namespace, res, name)
+		return resourceDeletedMsg{name: name, err: err}
+	}
+}
+
+// close leaves the explorer: back to the main UI when embedded, else quit
+func (m ResourcesModel) close() (ResourcesModel, tea.Cmd) {
+	if m.embedded {
+		return m, func() tea.Msg { return resourcesClosedMsg{} }
+	}
+	return m, tea.Quit
+}
+
+func (m ResourcesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	return m.update(msg)
+}
+
+func (m ResourcesModel) update(msg tea.Msg) (ResourcesModel, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.viewer.SetSize(msg.Width, msg.Height)
+		return m, nil
+
+	case identityMsg:
+		m.identity = msg.identity
+		return m, nil
+
+	case fuzzyMatchMsg:
+		// Either list may still be matching while the other is shown
+		if cmd = m.kinds.Matched(msg); cmd == nil {
+			cmd = m.instances.Matched(msg)
+		}
+		return m, cmd
+
+	case apiResourcesLoadedMsg:
+		if msg.err != nil {
+			m.kinds.SetError(msg.err)
+			return m, nil
+		}
+		m.resources = make(map[string]k8s.APIResource, len(msg.resources))
+		labels := make([]string, 0, len(msg.resources))
+		for _, r := range msg.resources {
+			label := resourceLabel(r)
+			m.resources[label] = r
+			labels = append(labels, label)
+		}
+		m.kinds.SetItems(labels)
+		return m, nil
+
+	case resourceInstancesMsg:
+		if msg.err != nil {
+			m.instances.SetError(msg.err)
+			return m, nil
+		}
+		m.instances.SetItems(msg.names)
+		return m, nil
+
+	case resourceManifestMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.viewer.SetHighlighter(highlightYAML)
+		m.viewer.SetContent(strings.TrimSuffix(msg.manifest, "\n"))
+		return m, nil
+
+	case resourceDeletedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.status = fmt.Sprintf("Deleted %s %s", m.resource.Kind, msg.name)
+		m.level = levelInstances
+		m.instances.Reset()
+		m.instances.SetLoading(true)
+		return m, m.loadInstances()
+
+	case tea.KeyMsg:
+		if key.Matches(msg, keys.ForceQuit) {
+			return m, tea.Quit
+		}
+
+		if m.level == levelManifest {
+			if m.viewer.IsSearching() {
+				m.viewer, cmd = m.viewer.Update(msg)
+				return m, cmd
+			}
+			if m.confirmDelete {
+				m.confirmDelete = false
+				if key.Matches(msg, keys.Confirm) {
+					return m, m.deleteInstance()
+				}
+				m.status = "Delete cancelled"
+				return m, nil
+			}
+			m.err = nil
+			m.status = ""
+			switch {
+			case key.Matches(msg, keys.ManifestBack):
+				m.level = levelInstances
+				return m, nil
+			case key.Matches(msg, keys.ManagedFields):
+				m.showManagedFields = !m.showManagedFields
+				return m, m.loadManifest()
+			case key.Matches(msg, keys.Refresh):
+				return m, m.loadManifest()
+			case key.Matches(msg, keys.DeleteResource):
+				if m.readOnly {
+					m.err = fmt.Errorf("deleting is disabled in read-only mode")
+					return m, nil
+				}
+				if !m.resource.Can("delete") {
+					m.err = fmt.Errorf("%s cannot be deleted", m.resource)
+					return m, nil
+				}
+				m.confirmDelete = true
+				return m, nil
+			}
+			m.viewer, cmd = m.viewer.Update(msg)
+			return m, cmd
+		}
+
+		m.err = nil
+		switch {
+		case key.Matches(msg, keys.Back):
+			if m.level == levelInstances {
+				m.level = levelKinds
+				m.status = ""
+				return m, nil
+			}
+			return m.close()
+		case key.Matches(msg, keys.Select):
+			if m.level == levelKinds {
+				res, ok := m.resources[m.kinds.GetSelected()]
+				if !ok {
+					return m, nil
+				}
+				m.resource = res
+				m.level = levelInstances
+				m.instances = NewFuzzyList(fmt.Sprintf("Select %s", res.Kind))
+				m.instances.SetLoading(true)
+				return m, m.loadInstances()
+			}
+			name := m.instances.GetSelected()
+			if name == "" {
+				return m, nil
+			}
+			m.name = name
+			m.level = levelManifest
+			m.status = ""
+			m.viewer.SetHighlighter(highlightYAML)
+			m.viewer.SetSource(strings.ToLower(m.resource.Kind)+"-"+name, ".yaml")
+			m.viewer.SetContent("Loading...")
+			return m, m.loadManifest()
+		}
+	}
+
+	switch m.level {
+	case levelKinds:
+		m.kinds, cmd = m.kinds.Update(msg)
+	case levelInstances:
+		m.instances, cmd = m.instances.Update(msg)
+	case levelManifest:
+		m.viewer, cmd = m.viewer.Update(msg)
+	}
+	return m, cmd
+}
+
+// canShowHelp reports whether ? opens the help: not into a filter holding
+// text, a search or a delete confirmation
+func (m ResourcesModel) canShowHelp() bool {
+	switch m.level {
+	case levelKinds:
+		return m.kinds.GetInput() == ""
+	case levelInstances:
+		return m.instances.GetInput() == ""
+	}
+	return !m.viewer.IsSearching() && !m.confirmDelete
+}
+
+// bindings are the keys of the explorer's current level for the ? overlay
+func (m ResourcesModel) bindings() []key.Binding {
+	if m.level != levelManifest {
+		return []key.Binding{keys.Up, keys.Down, keys.PageUp, keys.PageDown, keys.Select, keys.Back}
+	}
+	if m.confirmDelete {
+		return []key.Binding{keys.Confirm}
+	}
+	bindings := []key.Binding{keys.Scroll.Up, keys.Scroll.Down, keys.Scroll.PageUp, keys.Scroll.PageDown, keys.Top, keys.Bottom,
+		keys.Search, keys.NextMatch, keys.PrevMatch, keys.Wrap, keys.Copy, keys.Save, keys.ManagedFields, keys.Refresh}
+	if !m.readOnly && m.resource.Can("delete") {
+		bindings = append(bindings, keys.DeleteResource)
+	}
+	return append(bindings, keys.ManifestBack)
+}
+
+// content renders the explorer without padding, for embedding
+func (m ResourcesModel) content() string {
+	var b strings.Builder
+	switch m.level {
+	case levelKinds:
+		b.WriteString(m.kinds.View())
+	case levelInstances:
+		b.WriteString(m.instances.View())
+	case levelManifest:
+		b.WriteString(SuccessStyle.Render(fmt.Sprintf("%s %s", m.resource.Kind, m.name)))
+		b.WriteString("\n\n")
+		b.WriteString(m.viewer.View())
+	}
+
+	if m.err != nil {
+		b.WriteString("\n")
+		b.WriteString(RenderError(m.err.Error()))
+	}
+	if m.confirmDelete {
+		b.WriteString("\n")
+		b.WriteString(WarningStyle.Render(fmt.Sprintf("Delete %s %s? y: confirm, any other key: cancel", m.resource.Kind, m.name)))
+	}
+	if m.status != "" {
+		b.WriteString("\n")
+		b.WriteString(InfoStyle.Render(m.status))
+	}
+
+	b.WriteString("\n\n")
+	var help []string
+	switch m.level {
+	case levelKinds:
+		back := "Esc: quit"
+		if m.embedded {
+			back = "Esc: back"
+		}
+		help = []string{"↑↓: navigate", "Enter: list instances", "Type: filter kinds", back}
+	case levelInstances:
+		help = []string{"↑↓: navigate", "Enter: show YAML", "Type: filter", "Esc: back to kinds"}
+	case levelManifest:
+		state := "hidden"
+		if m.showManagedFields {
+			state = "shown"
+		}
+		help = []string{"↑↓: scroll", "M: managedFields (" + state + ")", "r: reload"}
+		if !m.readOnly {
+			help = append(help, "D: delete")
+		}
+		help = append(help, "Esc/q: back")
+	}
+	// Only the embedded explorer has the ? overlay
+	if m.embedded {
+		help = append(help, "?: all keys")
+	}
+	b.WriteString(RenderHelp(help...))
+	return b.String()
+}
+
+func (m ResourcesModel) View() string {
+	var b strings.Builder
+	b.WriteString(RenderHeader(m.client.GetKubeConfigPath(), identitySummary(m.identity), m.namespace, ""))
+	b.WriteString("\n")
+	b.WriteString(m.content())
+	return accessibleView(lipgloss.NewStyle().Padding(1, 2).Render(b.String()))
+}
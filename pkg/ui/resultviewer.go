@@ -0,0 +1,350 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"khelper/pkg/config"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+// resultChrome is the number of rows around the result: header, title and help
+const resultChrome = 18
+
+// ResultViewer is a scrollable view of a command's result with search, line
+// wrapping, highlighting, copy to clipboard and save to a file
+type ResultViewer struct {
+	viewport    viewport.Model
+	searchInput textinput.Model
+	content     string
+	lines       []string
+	highlight   func(string) string // syntax highlighting, nil for keys and values
+	noWrap      bool                // long lines are cut and scroll sideways
+	rows        []int               // the first row of each line once wrapped
+	query       string
+	matches     []int // lines containing the query
+	current     int   // index into matches
+	status      string
+	source      string // names saved files, e.g. describe-web
+	ext         string // extension of saved files, e.g. .yaml
+	width       int
+	height      int
+}
+
+// NewResultViewer creates a new result viewer
+func NewResultViewer() ResultViewer {
+	ti := textinput.New()
+	ti.Placeholder = "search"
+	ti.Prompt = "/"
+	ti.CharLimit = 200
+	ti.PromptStyle = PromptStyle
+	ti.Cursor.Style = CursorStyle
+
+	vp := viewport.New(80, 10)
+	vp.KeyMap = keys.Scroll
+	vp.SetHorizontalStep(8)
+	return ResultViewer{
+		viewport:    vp,
+		searchInput: ti,
+	}
+}
+
+// SetSize sets the space available to the viewer
+func (r *ResultViewer) SetSize(width, height int) {
+	r.width = width
+	r.height = height
+	r.resize()
+}
+
+// SetContent replaces the result and scrolls to the top
+func (r *ResultViewer) SetContent(content string) {
+	r.content = content
+	r.lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	r.status = ""
+	r.findMatches()
+	r.resize()
+	r.viewport.GotoTop()
+	r.viewport.SetXOffset(0)
+}
+
+// SetHighlighter sets the syntax highlighting applied to each line, nil for
+// highlightKeyValues
+func (r *ResultViewer) SetHighlighter(highlight func(string) string) {
+	r.highlight = highlight
+	r.render()
+}
+
+// SetSource sets what the result is of and the extension of the files it
+// is saved to, e.g. "describe-web" and ".txt"
+func (r *ResultViewer) SetSource(source, ext string) {
+	r.source = source
+	r.ext = ext
+}
+
+// IsSearching returns whether the search input has focus
+func (r *ResultViewer) IsSearching() bool {
+	return r.searchInput.Focused()
+}
+
+// resize fits the viewport to the window, shrinking it for short results.
+// Wrapped lines depend on the width, so the content is rendered again.
+func (r *ResultViewer) resize() {
+	if r.width > 4 {
+		r.viewport.Width = r.width - 4
+	}
+	r.render()
+	height := r.height - resultChrome
+	if height < 5 {
+		height = 5
+	}
+	if rows := r.viewport.TotalLineCount(); rows < height {
+		height = rows
+	}
+	r.viewport.Height = height
+}
+
+// toggleWrap switches between wrapping long lines and cutting them
+func (r *ResultViewer) toggleWrap() {
+	r.noWrap = !r.noWrap
+	r.viewport.SetXOffset(0)
+	r.resize()
+	if r.noWrap {
+		r.status = "Long lines are cut, ←/→ scroll sideways"
+	} else {
+		r.status = "Long lines are wrapped"
+	}
+}
+
+// findMatches collects the lines containing the search query
+func (r *ResultViewer) findMatches() {
+	r.matches = r.matches[:0]
+	r.current = 0
+	if r.query == "" {
+		return
+	}
+	query := strings.ToLower(r.query)
+	for i, line := range r.lines {
+		if strings.Contains(strings.ToLower(line), query) {
+			r.matches = append(r.matches, i)
+		}
+	}
+}
+
+// render builds the viewport content. Lines with a match show the match
+// highlighted instead of the syntax highlighting. Long lines are wrapped
+// before they are highlighted, leaving room for the match marker.
+func (r *ResultViewer) render() {
+	query := strings.ToLower(r.query)
+	currentLine := -1
+	if len(r.matches) > 0 {
+		currentLine = r.matches[r.current]
+	}
+	highlight := r.highlight
+	if highlight == nil {
+		highlight = highlightKeyValues
+	}
+
+	var out []string
+	r.rows = r.rows[:0]
+	for i, line := range r.lines {
+		r.rows = append(r.rows, len(out))
+		parts := []string{line}
+		if width := r.viewport.Width - 2; !r.noWrap && width > 10 && ansi.StringWidth(line) > width {
+			parts = strings.Split(ansi.Wrap(line, width, ""), "\n")
+		}
+		for j, part := range parts {
+			if query != "" && strings.Contains(strings.ToLower(line), query) {
+				part = highlightQuery(part, query)
+			} else if j == 0 {
+				part = highlight(part)
+			}
+			if i == currentLine && j == 0 {
+				part = "▶ " + part
+			}
+			out = append(out, part)
+		}
+	}
+	r.viewport.SetContent(strings.Join(out, "\n"))
+}
+
+// highlightQuery marks every case-insensitive occurrence of query in line
+func highlightQuery(line, query string) string {
+	lower := strings.ToLower(line)
+	var b strings.Builder
+	last := 0
+	for {
+		idx := strings.Index(lower[last:], query)
+		if idx == -1 {
+			b.WriteString(line[last:])
+			break
+		}
+		start := last + idx
+		end := start + len(query)
+		b.WriteString(line[last:start])
+		b.WriteString(MatchStyle.Render(line[start:end]))
+		last = end
+	}
+	return b.String()
+}
+
+// jumpToMatch moves to the next (dir 1) or previous (dir -1) match
+func (r *ResultViewer) jumpToMatch(dir int) {
+	if len(r.matches) == 0 {
+		if r.query != "" {
+			r.status = "No matches for " + r.query
+		}
+		return
+	}
+	r.current = (r.current + dir + len(r.matches)) % len(r.matches)
+	r.render()
+	r.scrollToLine(r.matches[r.current])
+}
+
+// scrollToLine brings a line into view, a few rows from the top
+func (r *ResultViewer) scrollToLine(line int) {
+	if line < len(r.rows) {
+		line = r.rows[line]
+	}
+	offset := line - 3
+	if offset < 0 {
+		offset = 0
+	}
+	r.viewport.SetYOffset(offset)
+}
+
+// copyContent copies the result to the clipboard, falling back to the
+// terminal's OSC 52 clipboard when there is no system clipboard (e.g. SSH)
+func (r *ResultViewer) copyContent() {
+	if err := clipboard.WriteAll(r.content); err != nil {
+		termenv.Copy(r.content)
+		r.status = "Copied via terminal clipboard"
+		return
+	}
+	r.status = "Copied to clipboard"
+}
+
+// saveContent writes the result to the results directory of the exports
+func (r *ResultViewer) saveContent() tea.Cmd {
+	content, source, ext := r.content, r.source, r.ext
+	if ext == "" {
+		ext = ".txt"
+	}
+	return func() tea.Msg {
+		dir, err := config.GetExportDir()
+		if err != nil {
+			return exportedMsg{err: err}
+		}
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return writeExport(filepath.Join(dir, "results"), source, "result", ext, content)
+	}
+}
+
+// Update handles scrolling, search, copy and save keys
+func (r *ResultViewer) Update(msg tea.Msg) (ResultViewer, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(exportedMsg); ok {
+		if msg.err != nil {
+			r.status = "Save failed: " + msg.err.Error()
+		} else {
+			r.status = "Saved to " + msg.path
+		}
+		return *r, nil
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		r.status = ""
+
+		if r.searchInput.Focused() {
+			switch msg.String() {
+			case "enter":
+				r.searchInput.Blur()
+				r.query = r.searchInput.Value()
+				r.findMatches()
+				r.current = len(r.matches) - 1
+				r.jumpToMatch(1)
+				r.render()
+				return *r, nil
+			case "esc":
+				r.searchInput.Blur()
+				r.searchInput.SetValue(r.query)
+				return *r, nil
+			}
+			r.searchInput, cmd = r.searchInput.Update(msg)
+			return *r, cmd
+		}
+
+		switch {
+		case key.Matches(msg, keys.Search):
+			r.searchInput.SetValue(r.query)
+			r.searchInput.CursorEnd()
+			r.searchInput.Focus()
+			return *r, textinput.Blink
+		case key.Matches(msg, keys.NextMatch):
+			r.jumpToMatch(1)
+			return *r, nil
+		case key.Matches(msg, keys.PrevMatch):
+			r.jumpToMatch(-1)
+			return *r, nil
+		case key.Matches(msg, keys.Copy):
+			r.copyContent()
+			return *r, nil
+		case key.Matches(msg, keys.Save):
+			return *r, r.saveContent()
+		case key.Matches(msg, keys.Wrap):
+			r.toggleWrap()
+			return *r, nil
+		case key.Matches(msg, keys.Top):
+			r.viewport.GotoTop()
+			return *r, nil
+		case key.Matches(msg, keys.Bottom):
+			r.viewport.GotoBottom()
+			return *r, nil
+		}
+	}
+
+	r.viewport, cmd = r.viewport.Update(msg)
+	return *r, cmd
+}
+
+// View renders the result with a status line for scrolling and search
+func (r *ResultViewer) View() string {
+	var b strings.Builder
+	b.WriteString(r.viewport.View())
+
+	var footer []string
+	if r.viewport.TotalLineCount() > r.viewport.Height {
+		footer = append(footer, fmt.Sprintf("%3.f%%", r.viewport.ScrollPercent()*100))
+	}
+	if r.query != "" {
+		if len(r.matches) > 0 {
+			footer = append(footer, fmt.Sprintf("match %d/%d for %q", r.current+1, len(r.matches), r.query))
+		} else {
+			footer = append(footer, fmt.Sprintf("no matches for %q", r.query))
+		}
+	}
+	footer = append(footer, "/: search", "n/N: next/prev", "w: wrap", "y: copy", "S: save")
+	b.WriteString("\n")
+	b.WriteString(InfoStyle.Render(strings.Join(footer, " • ")))
+
+	if r.searchInput.Focused() {
+		b.WriteString("\n")
+		b.WriteString(r.searchInput.View())
+	}
+	if r.status != "" {
+		b.WriteString("\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(SecondaryColor).Render(r.status))
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"fmt"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// retryMsg carries a retry event of the client that owns events
+type retryMsg struct {
+	events <-chan k8s.RetryEvent
+	event  k8s.RetryEvent
+}
+
+// waitRetry waits for the next retry event, nil if the client never retries
+func waitRetry(client k8s.Connection) tea.Cmd {
+	if client == nil {
+		return nil
+	}
+	events := client.RetryEvents()
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return retryMsg{events: events, event: <-events}
+	}
+}
+
+// handleRetry shows a read being retried until it succeeds or gives up.
+// Events of a replaced client are dropped and stop being waited for.
+func (m Model) handleRetry(msg retryMsg) (tea.Model, tea.Cmd) {
+	if m.k8sClient == nil || msg.events != m.k8sClient.RetryEvents() {
+		return m, nil
+	}
+	if msg.event.Done {
+		m.retry = nil
+	} else {
+		m.retry = &msg.event
+	}
+	return m, waitRetry(m.k8sClient)
+}
+
+// retryNote describes the retry in progress, empty if there is none
+func (m Model) retryNote() string {
+	if m.retry == nil {
+		return ""
+	}
+	note := fmt.Sprintf("⟳ retrying (%d/%d)…", m.retry.Attempt, m.retry.Max)
+	if m.retry.Err != nil {
+		note += " " + m.retry.Err.Error()
+	}
+	return note
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+)
+
+// ResultTarget is what a command runs against outside the TUI
+type ResultTarget struct {
+	Namespace  string
+	Deployment string
+	Pod        string
+	Container  string
+	Input      string // the answer to the command's input prompt
+}
+
+// RunResultCommand runs a command that shows a result in the TUI, such as
+// describe, list-env or list-revisions, and returns the result as text.
+// Commands that change the cluster or need the TUI are refused.
+func RunResultCommand(ctx context.Context, cfg *config.Config, client k8s.ClientInterface, name string, target ResultTarget) (string, error) {
+	var command *Command
+	for i := range AvailableCommands {
+		if AvailableCommands[i].Name == name {
+			command = &AvailableCommands[i]
+		}
+	}
+	interactive := fmt.Errorf("%s is interactive, run it in the TUI", name)
+	switch {
+	case command == nil:
+		return "", fmt.Errorf("unknown command %s", name)
+	case command.Mutating:
+		return "", fmt.Errorf("%s changes the cluster or runs commands in it, run it in the TUI", name)
+	case command.ComparesPods || command.NeedsLocalFS:
+		return "", interactive
+	case command.NeedsPod && target.Pod == "":
+		return "", fmt.Errorf("%s needs a pod", name)
+	case command.NeedsContainer && target.Container == "":
+		return "", fmt.Errorf("%s needs a container", name)
+	case command.NeedsInput && !command.OptionalInput && target.Input == "":
+		return "", fmt.Errorf("%s needs input: %s", name, command.InputPrompt)
+	}
+
+	m := NewModel(cfg, client, nil)
+	m.namespace = target.Namespace
+	m.deployment = target.Deployment
+	m.pod = target.Pod
+	m.container = target.Container
+	m.inputValue = target.Input
+	m.command = command
+	m.exec = &execution{ctx: ctx, cancel: func() {}}
+	m.state = StateExecuting
+
+	// Result commands stay executing until their message arrives, others
+	// switch to their own screen
+	model, cmd := m.runCommand()
+	if next, ok := model.(Model); !ok || next.state != StateExecuting || cmd == nil {
+		return "", interactive
+	}
+	switch msg := cmd().(type) {
+	case CommandResultMsg:
+		return msg.result, msg.err
+	case ManifestLoadedMsg:
+		return msg.manifest, msg.err
+	}
+	return "", interactive
+}
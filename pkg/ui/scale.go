@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// scheduledScale is a scale waiting for its time, with a countdown shown
+type scheduledScale struct {
+	target string // replica count or preset, resolved when it is applied
+	at     time.Time
+}
+
+// scaleUndo is the scale that one key reverts
+type scaleUndo struct {
+	namespace  string
+	deployment string
+	replicas   int32
+}
+
+// scaleTickMsg updates the countdown of a scheduled scale
+type scaleTickMsg struct {
+	at time.Time
+}
+
+// scaledMsg reports a finished scale and the replica count before it
+type scaledMsg struct {
+	namespace  string
+	deployment string
+	previous   int32
+	result     string
+	err        error
+}
+
+// ParseScaleInput reads "<count|prev|min|max> [at HH:MM | in DURATION]". A
+// zero time means now.
+func ParseScaleInput(input string, now time.Time) (string, time.Time, error) {
+	fields := strings.Fields(input)
+	switch {
+	case len(fields) == 1:
+		return fields[0], time.Time{}, nil
+	case len(fields) == 3 && (fields[1] == "at" || fields[1] == "in"):
+		at, err := ParseScaleTime(fields[2], now)
+		return fields[0], at, err
+	}
+	return "", time.Time{}, fmt.Errorf("invalid input %q, use <count|prev|min|max> [at HH:MM | in 30m]", input)
+}
+
+// ParseScaleTime reads a clock time "HH:MM", today or tomorrow if it has
+// passed, or a duration from now such as "30m"
+func ParseScaleTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("scale delay must be positive")
+		}
+		return now.Add(d), nil
+	}
+	clock, err := time.ParseInLocation("15:04", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use HH:MM or a duration such as 30m", s)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
+
+func scaleTick(at time.Time) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return scaleTickMsg{at: at}
+	})
+}
+
+// scaleNow resolves a replica count or preset and scales the deployment
+func (m *Model) scaleNow(target string) tea.Cmd {
+	namespace, deployment := m.namespace, m.deployment
+	previous := m.config.GetPreviousReplicas(namespace, deployment)
+	return func() tea.Msg {
+		ctx := m.exec.context()
+		info, err := m.k8sClient.GetScaleInfo(ctx, namespace, deployment)
+		if err != nil {
+			return scaledMsg{err: err}
+		}
+		info.Previous = previous
+		replicas, err := info.ResolveReplicas(target)
+		if err != nil {
+			return scaledMsg{err: err}
+		}
+		change := config.Change{Namespace: namespace, Deployment: deployment, Operation: config.OpScale, After: fmt.Sprint(replicas)}
+		note, err := ApplyChange(ctx, m.k8sClient, change, func(ctx context.Context) error {
+			return m.k8sClient.ScaleDeployment(ctx, namespace, deployment, replicas)
+		})
+		if err != nil {
+			return scaledMsg{err: err}
+		}
+
+		result := withNote(fmt.Sprintf("Scaled %s from %d to %d replicas", deployment, info.Replicas, replicas), note)
+		if note := info.HPANote(); note != "" {
+			result += "\n\nNote: " + note
+		}
+		return scaledMsg{namespace: namespace, deployment: deployment, previous: info.Replicas, result: result}
+	}
+}
+
+// canUndoScale reports whether the result shown is a scale that can be undone
+func (m Model) canUndoScale() bool {
+	return m.undoScale != nil && m.command != nil && m.command.Name == "scale" &&
+		m.undoScale.namespace == m.namespace && m.undoScale.deployment == m.deployment
+}
+
+// scheduleScale starts the countdown of a scale applied later
+func (m Model) scheduleScale(target string, at time.Time) (tea.Model, tea.Cmd) {
+	m.scheduledScale = &scheduledScale{target: target, at: at}
+	m.state = StateShowResult
+	m.err = nil
+	return m, scaleTick(at)
+}
+
+// scheduledScaleView renders the countdown of a scheduled scale
+func (m Model) scheduledScaleView() string {
+	s := m.scheduledScale
+	remaining := time.Until(s.at).Round(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	var b strings.Builder
+	b.WriteString(WarningStyle.Render(fmt.Sprintf("⏰ Scaling %s to %s at %s", m.deployment, s.target, s.at.Format("15:04:05"))))
+	b.WriteString("\n\n")
+	b.WriteString(LabelStyle.Render("Starts in: "))
+	b.WriteString(ValueStyle.Render(remaining.String()))
+	b.WriteString("\n\n")
+	b.WriteString(InfoStyle.Render("Keep khelper open until then • Esc/c: cancel"))
+	return b.String()
+}
@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+)
+
+// ScalingClient is what scheduled actions need of a client
+type ScalingClient interface {
+	ChangeClient
+	k8s.Browser
+}
+
+// ScheduleClient creates the client of a scheduled action's kubeconfig, the
+// default one if empty
+type ScheduleClient func(kubeconfig string) (ScalingClient, error)
+
+// RunSchedules performs the configured scheduled actions at their times
+// until ctx is done, logging every run. The config is read again every
+// minute, so edited schedules apply without a restart. Runs missed while the
+// machine slept are made up once when it wakes.
+func RunSchedules(ctx context.Context, cfg *config.Config, newClient ScheduleClient, logf func(format string, args ...interface{})) error {
+	if err := cfg.ValidateSchedules(); err != nil {
+		return err
+	}
+	if len(cfg.Schedules) == 0 {
+		return fmt.Errorf("no schedules configured, add them under schedules in the config file")
+	}
+
+	clients := make(map[string]ScalingClient)
+	next := make(map[string]time.Time) // name -> next run
+	crons := make(map[string]string)   // name -> cron spec of the next run
+	reloadErr := ""
+	for {
+		now := time.Now()
+		wake := now.Add(time.Minute)
+		for _, s := range cfg.Schedules {
+			cron, _ := config.ParseCron(s.Cron)
+			if crons[s.Name] != s.Cron {
+				crons[s.Name] = s.Cron
+				next[s.Name] = cron.Next(now)
+				logf("%s: next run %s", s.Name, formatRunTime(next[s.Name]))
+			}
+			at := next[s.Name]
+			if at.IsZero() {
+				continue
+			}
+			if !now.Before(at) {
+				if late := now.Sub(at); late > time.Minute {
+					logf("%s: running %s late", s.Name, late.Round(time.Second))
+				}
+				runSchedule(ctx, cfg, clients, newClient, s, logf)
+				now = time.Now()
+				at = cron.Next(now)
+				next[s.Name] = at
+				logf("%s: next run %s", s.Name, formatRunTime(at))
+			}
+			if !at.IsZero() && at.Before(wake) {
+				wake = at
+			}
+		}
+
+		select {
+		case <-time.After(time.Until(wake)):
+		case <-ctx.Done():
+			return nil
+		}
+
+		fresh, err := config.Load()
+		if err == nil {
+			err = fresh.ValidateSchedules()
+		}
+		if err != nil {
+			if err.Error() != reloadErr {
+				logf("keeping the previous schedules, the config is invalid: %v", err)
+				reloadErr = err.Error()
+			}
+			continue
+		}
+		reloadErr = ""
+		fresh.Override(cfg.GetOverrides())
+		cfg = fresh
+	}
+}
+
+// runSchedule performs a scheduled action once with the client of its
+// kubeconfig, logging failures
+func runSchedule(ctx context.Context, cfg *config.Config, clients map[string]ScalingClient, newClient ScheduleClient, s config.Schedule, logf func(format string, args ...interface{})) {
+	if cfg.IsReadOnly() {
+		logf("%s: skipped, khelper is in read-only mode", s.Name)
+		return
+	}
+	kubeconfig := s.KubeConfig
+	if kubeconfig != "" {
+		kubeconfig = config.ExpandPath(kubeconfig)
+	}
+	client, ok := clients[kubeconfig]
+	if !ok {
+		var err error
+		if client, err = newClient(kubeconfig); err != nil {
+			logf("%s: failed: %v", s.Name, err)
+			return
+		}
+		clients[kubeconfig] = client
+	}
+	if err := RunSchedule(ctx, cfg, client, s, logf); err != nil {
+		logf("%s: failed: %v", s.Name, err)
+	}
+}
+
+// RunSchedule performs a scheduled action once, recording its changes in the
+// audit history with the schedule's name. A deployment that fails is logged
+// and the others are still changed.
+func RunSchedule(ctx context.Context, cfg *config.Config, client ScalingClient, s config.Schedule, logf func(format string, args ...interface{})) error {
+	deployments := s.Deployments
+	if len(deployments) == 0 {
+		var err error
+		if deployments, err = client.ListDeployments(ctx, s.Namespace); err != nil {
+			return fmt.Errorf("failed to list deployments: %w", err)
+		}
+	}
+
+	failed := 0
+	for _, deployment := range deployments {
+		if err := scaleScheduled(ctx, cfg, client, s, deployment, logf); err != nil {
+			logf("%s: %s/%s: %v", s.Name, s.Namespace, deployment, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deployments failed", failed, len(deployments))
+	}
+	return nil
+}
+
+// scaleScheduled scales a deployment of a scheduled action. A deployment
+// that already has the replica count is left alone, so that the count to go
+// back to with prev is kept.
+func scaleScheduled(ctx context.Context, cfg *config.Config, client ScalingClient, s config.Schedule, deployment string, logf func(format string, args ...interface{})) error {
+	info, err := client.GetScaleInfo(ctx, s.Namespace, deployment)
+	if err != nil {
+		return err
+	}
+	info.Previous = cfg.GetPreviousReplicas(s.Namespace, deployment)
+	replicas, err := info.ResolveReplicas(s.Replicas)
+	if err != nil {
+		return err
+	}
+	if replicas == info.Replicas {
+		logf("%s: %s/%s already has %d replicas", s.Name, s.Namespace, deployment, replicas)
+		return nil
+	}
+
+	change := config.Change{Namespace: s.Namespace, Deployment: deployment, Operation: config.OpScale, After: fmt.Sprint(replicas), Schedule: s.Name}
+	note, err := ApplyChange(ctx, client, change, func(ctx context.Context) error {
+		return client.ScaleDeployment(ctx, s.Namespace, deployment, replicas)
+	})
+	if err != nil {
+		return err
+	}
+	if err := cfg.SetPreviousReplicas(s.Namespace, deployment, info.Replicas); err != nil {
+		return fmt.Errorf("failed to record previous replicas: %w", err)
+	}
+	logf("%s: scaled %s/%s from %d to %d replicas", s.Name, s.Namespace, deployment, info.Replicas, replicas)
+	if note != "" {
+		logf("%s: note: %s", s.Name, note)
+	}
+	return nil
+}
+
+// ScheduleList renders the configured schedules with their last change found
+// in the audit history and their next run
+func ScheduleList(cfg *config.Config, history []config.Change, now time.Time) string {
+	lastRuns := make(map[string]time.Time)
+	for _, change := range history {
+		if change.Schedule != "" && change.Time.After(lastRuns[change.Schedule]) {
+			lastRuns[change.Schedule] = change.Time
+		}
+	}
+
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCRON\tACTION\tTARGET\tLAST CHANGE\tNEXT RUN")
+	for _, s := range cfg.Schedules {
+		action := s.Action
+		if s.Action == config.OpScale {
+			action += " to " + s.Replicas
+		}
+		target := s.Namespace + "/*"
+		if len(s.Deployments) > 0 {
+			target = s.Namespace + "/" + strings.Join(s.Deployments, ",")
+		}
+		last := "-"
+		if t, ok := lastRuns[s.Name]; ok {
+			last = t.Format("2006-01-02 15:04")
+		}
+		nextRun := "invalid cron spec"
+		if cron, err := config.ParseCron(s.Cron); err == nil {
+			nextRun = formatRunTime(cron.Next(now))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Cron, action, target, last, nextRun)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// formatRunTime renders the time of a scheduled run
+func formatRunTime(at time.Time) string {
+	if at.IsZero() {
+		return "never"
+	}
+	return at.Format("Mon 2006-01-02 15:04")
+}
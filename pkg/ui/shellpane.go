@@ -0,0 +1,302 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"khelper/pkg/k8s"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// shellSession is a shell running in a pane of the TUI. It outlives the pane:
+// going back to the command list keeps it open. It is the remote TTY's
+// stdin, stdout and size queue.
+type shellSession struct {
+	pod       string
+	container string
+	shell     string
+
+	mu      sync.Mutex
+	screen  *termScreen
+	input   chan []byte
+	pending []byte
+	sizes   chan remotecommand.TerminalSize
+	output  chan struct{} // signalled when the screen changed
+	done    chan struct{}
+	err     error
+	cancel  context.CancelFunc
+}
+
+type (
+	// shellOpenedMsg carries a started shell session
+	shellOpenedMsg struct {
+		session *shellSession
+		err     error
+	}
+	// shellOutputMsg reports that the session's screen changed
+	shellOutputMsg struct{ session *shellSession }
+	// shellExitedMsg reports that the session ended
+	shellExitedMsg struct{ session *shellSession }
+)
+
+// startShellSession runs shell in the container with a TTY of the given size
+func startShellSession(client k8s.Execer, namespace, pod, container, shell string, width, height int) *shellSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &shellSession{
+		pod:       pod,
+		container: container,
+		shell:     shell,
+		screen:    newTermScreen(width, height),
+		input:     make(chan []byte, 64),
+		sizes:     make(chan remotecommand.TerminalSize, 1),
+		output:    make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		cancel:    cancel,
+	}
+	s.resize(width, height)
+	go func() {
+		s.err = client.Exec(ctx, k8s.ExecOptions{
+			Namespace:     namespace,
+			PodName:       pod,
+			ContainerName: container,
+			Command:       []string{shell},
+			Stdin:         s,
+			Stdout:        s,
+			TTY:           true,
+			SizeQueue:     s,
+		})
+		close(s.done)
+	}()
+	return s
+}
+
+// Read hands the typed keys to the remote TTY
+func (s *shellSession) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		select {
+		case s.pending = <-s.input:
+		case <-s.done:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Write feeds the remote TTY's output into the screen
+func (s *shellSession) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	s.screen.Write(p)
+	s.mu.Unlock()
+	select {
+	case s.output <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Next implements remotecommand.TerminalSizeQueue
+func (s *shellSession) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-s.sizes:
+		return &size
+	case <-s.done:
+		return nil
+	}
+}
+
+// send types keys into the shell
+func (s *shellSession) send(keys []byte) {
+	if len(keys) == 0 {
+		return
+	}
+	select {
+	case s.input <- keys:
+	case <-s.done:
+	}
+}
+
+// resize resizes the screen and the remote TTY, replacing a pending size
+func (s *shellSession) resize(width, height int) {
+	s.mu.Lock()
+	s.screen.resize(width, height)
+	s.mu.Unlock()
+	size := remotecommand.TerminalSize{Width: uint16(max(width, 1)), Height: uint16(max(height, 1))}
+	select {
+	case <-s.sizes:
+	default:
+	}
+	s.sizes <- size
+}
+
+func (s *shellSession) view() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.screen.View()
+}
+
+// waitShell waits for the session's next output or its end
+func waitShell(s *shellSession) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-s.output:
+			return shellOutputMsg{session: s}
+		case <-s.done:
+			return shellExitedMsg{session: s}
+		}
+	}
+}
+
+// openShell starts a shell in a container inside the TUI, using the
+// deployment's preferred shell or the first one found
+func (m *Model) openShell(podName, container string) tea.Cmd {
+	namespace := m.namespace
+	shell := m.config.GetPrefs(m.namespace, m.deployment).Shell
+	width, height := m.shellPaneSize()
+	return func() tea.Msg {
+		if shell == "" {
+			found, err := m.k8sClient.CheckShellAvailable(m.exec.context(), namespace, podName, container)
+			if err != nil {
+				return shellOpenedMsg{err: err}
+			}
+			shell = found
+		}
+		return shellOpenedMsg{session: startShellSession(m.k8sClient, namespace, podName, container, shell, width, height)}
+	}
+}
+
+// shellPaneSize is the terminal size that fits below the header
+func (m Model) shellPaneSize() (int, int) {
+	if m.width == 0 || m.height == 0 {
+		return 80, 24
+	}
+	header := lipgloss.Height(m.header())
+	// Padding, the title line and the help line
+	return max(m.width-4, 20), max(m.height-header-6, 5)
+}
+
+// handleShellMsg updates the model for the shell session's messages
+func (m Model) handleShellMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case shellOpenedMsg:
+		if msg.err != nil {
+			m.state = StateShowResult
+			m.err = msg.err
+			return m, nil
+		}
+		if m.shell != nil {
+			m.shell.cancel()
+		}
+		m.shell = msg.session
+		m.state = StateShellPane
+		return m, waitShell(msg.session)
+
+	case shellOutputMsg:
+		if msg.session != m.shell {
+			return m, nil
+		}
+		return m, waitShell(msg.session)
+
+	case shellExitedMsg:
+		if msg.session != m.shell {
+			return m, nil
+		}
+		m.shell = nil
+		if m.state != StateShellPane {
+			return m, nil
+		}
+		m.state = StateShowResult
+		if msg.session.err != nil {
+			m.err = fmt.Errorf("shell in %s/%s ended: %w", msg.session.pod, msg.session.container, msg.session.err)
+			return m, nil
+		}
+		m.result = fmt.Sprintf("Shell in %s/%s exited", msg.session.pod, msg.session.container)
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(m.result)
+	}
+	return m, nil
+}
+
+// shellKey types a key into the shell pane, Ctrl+] goes back to the command
+// list and keeps the session
+func (m Model) shellKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, keys.ShellPane) {
+		m.state = StateSelectCommand
+		m.cmdSelector.Reset()
+		return m, nil
+	}
+	m.shell.send(keyBytes(msg))
+	return m, nil
+}
+
+// resumeShell shows the running shell session again
+func (m Model) resumeShell() (tea.Model, tea.Cmd) {
+	m.state = StateShellPane
+	return m, nil
+}
+
+// shellPaneView renders the session's screen under a title line
+func (m Model) shellPaneView() string {
+	var b strings.Builder
+	b.WriteString(InfoStyle.Render(fmt.Sprintf("Shell %s in %s/%s", m.shell.shell, m.shell.pod, m.shell.container)))
+	b.WriteString("\n")
+	b.WriteString(m.shell.view())
+	return b.String()
+}
+
+// keyBytes encodes a key the way a terminal sends it
+func keyBytes(msg tea.KeyMsg) []byte {
+	var seq string
+	switch msg.Type {
+	case tea.KeyRunes:
+		seq = string(msg.Runes)
+		if msg.Paste {
+			seq = "\x1b[200~" + seq + "\x1b[201~"
+		}
+	case tea.KeySpace:
+		seq = " "
+	case tea.KeyUp:
+		seq = "\x1b[A"
+	case tea.KeyDown:
+		seq = "\x1b[B"
+	case tea.KeyRight:
+		seq = "\x1b[C"
+	case tea.KeyLeft:
+		seq = "\x1b[D"
+	case tea.KeyCtrlRight:
+		seq = "\x1b[1;5C"
+	case tea.KeyCtrlLeft:
+		seq = "\x1b[1;5D"
+	case tea.KeyHome:
+		seq = "\x1b[H"
+	case tea.KeyEnd:
+		seq = "\x1b[F"
+	case tea.KeyPgUp:
+		seq = "\x1b[5~"
+	case tea.KeyPgDown:
+		seq = "\x1b[6~"
+	case tea.KeyInsert:
+		seq = "\x1b[2~"
+	case tea.KeyDelete:
+		seq = "\x1b[3~"
+	case tea.KeyShiftTab:
+		seq = "\x1b[Z"
+	default:
+		if msg.Type >= 0 && msg.Type <= 127 {
+			// Control characters, Tab, Enter, Esc and Backspace are their byte
+			seq = string([]byte{byte(msg.Type)})
+		}
+	}
+	if msg.Alt && seq != "" {
+		seq = "\x1b" + seq
+	}
+	return []byte(seq)
+}
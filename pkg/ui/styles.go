@@ -1,6 +1,11 @@
 package ui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
 
 var (
 	// Colors
@@ -13,113 +18,192 @@ var (
 	TextColor      = lipgloss.Color("#F3F4F6")
 	BgColor        = lipgloss.Color("#1F2937")
 	HighlightBg    = lipgloss.Color("#374151")
+)
+
+// Styles, built from the colors by buildStyles
+var (
+	BaseStyle         lipgloss.Style
+	TitleStyle        lipgloss.Style
+	HeaderStyle       lipgloss.Style
+	InfoStyle         lipgloss.Style
+	WarningStyle      lipgloss.Style
+	LabelStyle        lipgloss.Style
+	ValueStyle        lipgloss.Style
+	InputBoxStyle     lipgloss.Style
+	FocusedInputStyle lipgloss.Style
+	ListItemStyle     lipgloss.Style
+	SelectedItemStyle lipgloss.Style
+	MatchStyle        lipgloss.Style
+	DimStyle          lipgloss.Style
+	ErrorStyle        lipgloss.Style
+	SuccessStyle      lipgloss.Style
+	HelpStyle         lipgloss.Style
+	StatusBarStyle    lipgloss.Style
+	CommandStyle      lipgloss.Style
+	CursorStyle       lipgloss.Style
+	PromptStyle       lipgloss.Style
+)
+
+// Theme is a color palette
+type Theme struct {
+	Primary, Secondary, Accent, Error, Warning, Muted, Text, Bg, Highlight lipgloss.Color
+}
+
+// Themes are the palettes selectable with the theme setting
+var Themes = map[string]Theme{
+	"dark": {
+		Primary: "#7C3AED", Secondary: "#10B981", Accent: "#F59E0B", Error: "#EF4444", Warning: "#F59E0B",
+		Muted: "#6B7280", Text: "#F3F4F6", Bg: "#1F2937", Highlight: "#374151",
+	},
+	"light": {
+		Primary: "#6D28D9", Secondary: "#047857", Accent: "#B45309", Error: "#DC2626", Warning: "#B45309",
+		Muted: "#4B5563", Text: "#111827", Bg: "#F9FAFB", Highlight: "#E5E7EB",
+	},
+	// high-contrast keeps dim text readable, accessible mode defaults to it
+	"high-contrast": {
+		Primary: "#00FFFF", Secondary: "#00FF00", Accent: "#FFFF00", Error: "#FF6B6B", Warning: "#FFFF00",
+		Muted: "#E5E7EB", Text: "#FFFFFF", Bg: "#000000", Highlight: "#1E3A8A",
+	},
+}
+
+func init() {
+	buildStyles()
+}
 
+// ApplyTheme switches the colors to a named theme: "dark" (the default),
+// "light", "high-contrast", or "mono" for no colors at all. It must be
+// called before any model is created.
+func ApplyTheme(name string) error {
+	if name == "" {
+		name = "dark"
+	}
+	if name == "mono" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+		return nil
+	}
+	theme, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q, use dark, light, high-contrast or mono", name)
+	}
+	PrimaryColor, SecondaryColor, AccentColor = theme.Primary, theme.Secondary, theme.Accent
+	ErrorColor, WarningColor, MutedColor = theme.Error, theme.Warning, theme.Muted
+	TextColor, BgColor, HighlightBg = theme.Text, theme.Bg, theme.Highlight
+	buildStyles()
+	return nil
+}
+
+// buildStyles creates the styles from the current colors
+func buildStyles() {
 	// Base styles
 	BaseStyle = lipgloss.NewStyle().
-			Foreground(TextColor)
+		Foreground(TextColor)
 
 	// Title style
 	TitleStyle = lipgloss.NewStyle().
-			Foreground(PrimaryColor).
-			Bold(true).
-			Padding(0, 1)
+		Foreground(PrimaryColor).
+		Bold(true).
+		Padding(0, 1)
 
 	// Header box style
 	HeaderStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(PrimaryColor).
-			Padding(1, 2).
-			MarginBottom(1)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(PrimaryColor).
+		Padding(1, 2).
+		MarginBottom(1)
 
 	// Info style
 	InfoStyle = lipgloss.NewStyle().
-			Foreground(MutedColor).
-			Italic(true)
+		Foreground(MutedColor).
+		Italic(true)
 
 	// Warning style
 	WarningStyle = lipgloss.NewStyle().
-			Foreground(WarningColor).
-			Bold(true)
+		Foreground(WarningColor).
+		Bold(true)
 
 	// Label style
 	LabelStyle = lipgloss.NewStyle().
-			Foreground(SecondaryColor).
-			Bold(true)
+		Foreground(SecondaryColor).
+		Bold(true)
 
 	// Value style
 	ValueStyle = lipgloss.NewStyle().
-			Foreground(TextColor)
+		Foreground(TextColor)
 
 	// Input box style
 	InputBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(PrimaryColor).
-			Padding(0, 1).
-			MarginTop(1).
-			MarginBottom(1)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(PrimaryColor).
+		Padding(0, 1).
+		MarginTop(1).
+		MarginBottom(1)
 
 	// Focused input style
 	FocusedInputStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(SecondaryColor).
-				Padding(0, 1).
-				MarginTop(1).
-				MarginBottom(1)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(SecondaryColor).
+		Padding(0, 1).
+		MarginTop(1).
+		MarginBottom(1)
 
 	// List item style
 	ListItemStyle = lipgloss.NewStyle().
-			Foreground(TextColor).
-			PaddingLeft(2)
+		Foreground(TextColor).
+		PaddingLeft(2)
 
 	// Selected list item style
 	SelectedItemStyle = lipgloss.NewStyle().
-				Foreground(PrimaryColor).
-				Bold(true).
-				PaddingLeft(2)
+		Foreground(PrimaryColor).
+		Bold(true).
+		PaddingLeft(2)
 
 	// Highlight match style
 	MatchStyle = lipgloss.NewStyle().
-			Foreground(AccentColor).
-			Bold(true)
+		Foreground(AccentColor).
+		Bold(true)
+
+	// Dim style for secondary content such as context lines
+	DimStyle = lipgloss.NewStyle().
+		Foreground(MutedColor)
 
 	// Error style
 	ErrorStyle = lipgloss.NewStyle().
-			Foreground(ErrorColor).
-			Bold(true)
+		Foreground(ErrorColor).
+		Bold(true)
 
 	// Success style
 	SuccessStyle = lipgloss.NewStyle().
-			Foreground(SecondaryColor).
-			Bold(true)
+		Foreground(SecondaryColor).
+		Bold(true)
 
 	// Help style
 	HelpStyle = lipgloss.NewStyle().
-			Foreground(MutedColor).
-			MarginTop(1)
+		Foreground(MutedColor).
+		MarginTop(1)
 
 	// Status bar style
 	StatusBarStyle = lipgloss.NewStyle().
-			Foreground(TextColor).
-			Background(HighlightBg).
-			Padding(0, 1)
+		Foreground(TextColor).
+		Background(HighlightBg).
+		Padding(0, 1)
 
 	// Command style
 	CommandStyle = lipgloss.NewStyle().
-			Foreground(AccentColor).
-			Bold(true)
+		Foreground(AccentColor).
+		Bold(true)
 
 	// Cursor style
 	CursorStyle = lipgloss.NewStyle().
-			Foreground(SecondaryColor)
+		Foreground(SecondaryColor)
 
 	// Prompt style
 	PromptStyle = lipgloss.NewStyle().
-			Foreground(PrimaryColor).
-			Bold(true)
-)
+		Foreground(PrimaryColor).
+		Bold(true)
+}
 
 // RenderHeader creates a styled header with app info
-func RenderHeader(kubeconfig, namespace, deployment string) string {
+func RenderHeader(kubeconfig, cluster, namespace, deployment string) string {
 	title := TitleStyle.Render("🚀 khelper - Kubernetes Helper")
 
 	// Kubeconfig info
@@ -129,6 +213,12 @@ func RenderHeader(kubeconfig, namespace, deployment string) string {
 		kcValue = InfoStyle.Render("(default)")
 	}
 
+	// Who and where the commands run as, left out until known
+	clusterLine := ""
+	if cluster != "" {
+		clusterLine = LabelStyle.Render("Cluster: ") + ValueStyle.Render(cluster)
+	}
+
 	nsLabel := LabelStyle.Render("Namespace: ")
 	nsValue := ValueStyle.Render(namespace)
 	if namespace == "" {
@@ -141,13 +231,11 @@ func RenderHeader(kubeconfig, namespace, deployment string) string {
 		depValue = InfoStyle.Render("(not selected)")
 	}
 
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		title,
-		"",
-		kcLabel+kcValue,
-		nsLabel+nsValue,
-		depLabel+depValue,
-	)
+	lines := []string{title, "", kcLabel + kcValue}
+	if clusterLine != "" {
+		lines = append(lines, clusterLine)
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left, append(lines, nsLabel+nsValue, depLabel+depValue)...)
 
 	return HeaderStyle.Render(content)
 }
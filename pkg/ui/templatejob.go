@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startTemplateJob creates a Job from the deployment's template running the
+// entered command in the selected container
+func (m Model) startTemplateJob() tea.Cmd {
+	command := strings.TrimSpace(m.inputValue)
+	namespace, deployment, container := m.namespace, m.deployment, m.container
+	ctx := m.exec.context()
+	return func() tea.Msg {
+		job, pod, err := m.k8sClient.CreateTemplateJob(ctx, namespace, deployment, container, command)
+		if err != nil {
+			if job != "" {
+				err = fmt.Errorf("%w (delete the Job %s with the resources command)", err, job)
+			}
+			return oneOffStartedMsg{err: err}
+		}
+		return oneOffStartedMsg{target: &oneOffPod{namespace: namespace, deployment: deployment, pod: pod, container: container, job: job}}
+	}
+}
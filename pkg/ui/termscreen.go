@@ -0,0 +1,323 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// termCursorStyle marks the cursor cell, visible on any theme
+var termCursorStyle = lipgloss.NewStyle().Reverse(true)
+
+// termScreen is a basic terminal emulator: it keeps a grid of characters and
+// a cursor, and understands the control and CSI sequences shells and line
+// editors use. Colors and attributes are dropped, there is no scrollback.
+type termScreen struct {
+	cells         [][]rune
+	width, height int
+	x, y          int
+	savedX        int
+	savedY        int
+	top, bottom   int      // scroll region, inclusive
+	mainCells     [][]rune // the main screen while the alternate one is shown
+	parser        *ansi.Parser
+}
+
+func newTermScreen(width, height int) *termScreen {
+	s := &termScreen{parser: ansi.NewParser()}
+	s.parser.SetHandler(ansi.Handler{
+		Print:     s.print,
+		Execute:   s.execute,
+		HandleCsi: s.csi,
+		HandleEsc: s.esc,
+	})
+	s.resize(width, height)
+	return s
+}
+
+// Write feeds output of the remote TTY into the screen
+func (s *termScreen) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.parser.Advance(b)
+	}
+	return len(p), nil
+}
+
+// resize changes the screen size, keeping the bottom of the content
+func (s *termScreen) resize(width, height int) {
+	width, height = max(width, 1), max(height, 1)
+	if width == s.width && height == s.height {
+		return
+	}
+	cells := make([][]rune, height)
+	shift := max(len(s.cells)-height, 0)
+	if s.y-shift < 0 {
+		shift = max(s.y, 0)
+	}
+	for y := range cells {
+		cells[y] = blankRow(width)
+		if y+shift < len(s.cells) {
+			copy(cells[y], s.cells[y+shift])
+		}
+	}
+	s.cells = cells
+	s.width, s.height = width, height
+	s.y -= shift
+	s.top, s.bottom = 0, height-1
+	s.clampCursor()
+}
+
+// View renders the screen with the cursor as a reversed cell
+func (s *termScreen) View() string {
+	lines := make([]string, s.height)
+	for y, row := range s.cells {
+		if y != s.y {
+			lines[y] = strings.TrimRight(string(row), " ")
+			continue
+		}
+		x := min(s.x, s.width-1)
+		lines[y] = string(row[:x]) + termCursorStyle.Render(string(row[x])) + strings.TrimRight(string(row[x+1:]), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func blankRow(width int) []rune {
+	row := make([]rune, width)
+	for i := range row {
+		row[i] = ' '
+	}
+	return row
+}
+
+func (s *termScreen) clampCursor() {
+	s.x = min(max(s.x, 0), s.width)
+	s.y = min(max(s.y, 0), s.height-1)
+}
+
+func (s *termScreen) print(r rune) {
+	if s.x >= s.width {
+		// Wrap at the right margin
+		s.x = 0
+		s.lineFeed()
+	}
+	s.cells[s.y][s.x] = r
+	s.x++
+}
+
+func (s *termScreen) execute(b byte) {
+	switch b {
+	case '\r':
+		s.x = 0
+	case '\n', '\v', '\f':
+		s.lineFeed()
+	case '\b':
+		if s.x > 0 {
+			s.x = min(s.x, s.width) - 1
+		}
+	case '\t':
+		s.x = min((s.x/8+1)*8, s.width-1)
+	}
+}
+
+// lineFeed moves the cursor down, scrolling at the bottom of the region
+func (s *termScreen) lineFeed() {
+	if s.y == s.bottom {
+		s.scrollUp(1)
+		return
+	}
+	s.y = min(s.y+1, s.height-1)
+}
+
+// scrollUp moves the scroll region's lines up by n, blanking the bottom
+func (s *termScreen) scrollUp(n int) {
+	for ; n > 0; n-- {
+		copy(s.cells[s.top:s.bottom], s.cells[s.top+1:s.bottom+1])
+		s.cells[s.bottom] = blankRow(s.width)
+	}
+}
+
+// scrollDown moves the scroll region's lines down by n, blanking the top
+func (s *termScreen) scrollDown(n int) {
+	for ; n > 0; n-- {
+		copy(s.cells[s.top+1:s.bottom+1], s.cells[s.top:s.bottom])
+		s.cells[s.top] = blankRow(s.width)
+	}
+}
+
+// erase blanks the cells of row y from x0 up to x1 (exclusive)
+func (s *termScreen) erase(y, x0, x1 int) {
+	x0, x1 = max(x0, 0), min(x1, s.width)
+	for x := x0; x < x1; x++ {
+		s.cells[y][x] = ' '
+	}
+}
+
+func (s *termScreen) csi(cmd ansi.Cmd, params ansi.Params) {
+	// count returns parameter i, where a missing or zero value means 1
+	count := func(i int) int {
+		n, _, _ := params.Param(i, 1)
+		return max(n, 1)
+	}
+	mode, _, _ := params.Param(0, 0)
+
+	if cmd.Prefix() == '?' {
+		switch cmd.Final() {
+		case 'h':
+			if mode == 47 || mode == 1047 || mode == 1049 {
+				s.enterAltScreen()
+			}
+		case 'l':
+			if mode == 47 || mode == 1047 || mode == 1049 {
+				s.leaveAltScreen()
+			}
+		}
+		return
+	}
+
+	switch cmd.Final() {
+	case 'A':
+		s.y -= count(0)
+	case 'B':
+		s.y += count(0)
+	case 'C':
+		s.x += count(0)
+	case 'D':
+		s.x = min(s.x, s.width-1) - count(0)
+	case 'E':
+		s.x, s.y = 0, s.y+count(0)
+	case 'F':
+		s.x, s.y = 0, s.y-count(0)
+	case 'G', '`':
+		s.x = count(0) - 1
+	case 'd':
+		s.y = count(0) - 1
+	case 'H', 'f':
+		s.y, s.x = count(0)-1, count(1)-1
+	case 'J':
+		switch mode {
+		case 0:
+			s.erase(s.y, s.x, s.width)
+			for y := s.y + 1; y < s.height; y++ {
+				s.cells[y] = blankRow(s.width)
+			}
+		case 1:
+			for y := 0; y < s.y; y++ {
+				s.cells[y] = blankRow(s.width)
+			}
+			s.erase(s.y, 0, s.x+1)
+		case 2, 3:
+			for y := range s.cells {
+				s.cells[y] = blankRow(s.width)
+			}
+		}
+	case 'K':
+		switch mode {
+		case 0:
+			s.erase(s.y, s.x, s.width)
+		case 1:
+			s.erase(s.y, 0, s.x+1)
+		case 2:
+			s.erase(s.y, 0, s.width)
+		}
+	case 'X':
+		s.erase(s.y, s.x, s.x+count(0))
+	case 'P':
+		// Delete characters, shifting the rest of the line left
+		if s.x < s.width {
+			row := s.cells[s.y]
+			n := min(count(0), s.width-s.x)
+			copy(row[s.x:], row[s.x+n:])
+			s.erase(s.y, s.width-n, s.width)
+		}
+	case '@':
+		// Insert blanks, shifting the rest of the line right
+		if s.x < s.width {
+			row := s.cells[s.y]
+			n := min(count(0), s.width-s.x)
+			copy(row[s.x+n:], row[s.x:])
+			s.erase(s.y, s.x, s.x+n)
+		}
+	case 'L', 'M':
+		if s.y >= s.top && s.y <= s.bottom {
+			top := s.top
+			s.top = s.y
+			if cmd.Final() == 'L' {
+				s.scrollDown(count(0))
+			} else {
+				s.scrollUp(count(0))
+			}
+			s.top = top
+		}
+	case 'S':
+		s.scrollUp(count(0))
+	case 'T':
+		s.scrollDown(count(0))
+	case 'r':
+		top, _, _ := params.Param(0, 1)
+		bottom, _, _ := params.Param(1, s.height)
+		top, bottom = max(top, 1)-1, min(max(bottom, 1), s.height)-1
+		if top < bottom {
+			s.top, s.bottom = top, bottom
+			s.x, s.y = 0, 0
+		}
+	case 's':
+		s.savedX, s.savedY = s.x, s.y
+	case 'u':
+		s.x, s.y = s.savedX, s.savedY
+	}
+	s.clampCursor()
+	s.x = min(s.x, s.width-1)
+}
+
+func (s *termScreen) esc(cmd ansi.Cmd) {
+	switch cmd.Final() {
+	case '7':
+		s.savedX, s.savedY = s.x, s.y
+	case '8':
+		s.x, s.y = s.savedX, s.savedY
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.x = 0
+		s.lineFeed()
+	case 'M':
+		// Reverse index: up one line, scrolling at the top of the region
+		if s.y == s.top {
+			s.scrollDown(1)
+		} else {
+			s.y--
+		}
+	case 'c':
+		width, height := s.width, s.height
+		s.cells, s.width, s.height, s.mainCells = nil, 0, 0, nil
+		s.x, s.y = 0, 0
+		s.resize(width, height)
+	}
+	s.clampCursor()
+}
+
+// enterAltScreen shows a blank screen for full-screen programs, keeping the
+// main screen to restore
+func (s *termScreen) enterAltScreen() {
+	if s.mainCells != nil {
+		return
+	}
+	s.mainCells = s.cells
+	s.cells = make([][]rune, s.height)
+	for y := range s.cells {
+		s.cells[y] = blankRow(s.width)
+	}
+	s.savedX, s.savedY = s.x, s.y
+}
+
+func (s *termScreen) leaveAltScreen() {
+	if s.mainCells == nil {
+		return
+	}
+	if len(s.mainCells) == s.height && len(s.mainCells[0]) == s.width {
+		s.cells = s.mainCells
+	}
+	s.mainCells = nil
+	s.x, s.y = s.savedX, s.savedY
+}
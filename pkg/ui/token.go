@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+)
+
+// parseTokenInput reads "[service-account] [duration]" in either order. An
+// empty account means the deployment's own.
+func parseTokenInput(input string) (string, time.Duration, error) {
+	serviceAccount := ""
+	duration := k8s.DefaultTokenDuration
+	for _, field := range strings.Fields(input) {
+		if d, err := time.ParseDuration(field); err == nil {
+			if d < 10*time.Minute {
+				return "", 0, fmt.Errorf("token duration must be at least 10m")
+			}
+			duration = d
+			continue
+		}
+		if serviceAccount != "" {
+			return "", 0, fmt.Errorf("invalid input %q, use [service-account] [duration]", input)
+		}
+		serviceAccount = field
+	}
+	return serviceAccount, duration, nil
+}
+
+// mintToken creates a token for the service account, writes a kubeconfig
+// using it to the export dir and returns a summary with the token
+func (m *Model) mintToken(ctx context.Context, serviceAccount string, duration time.Duration) (string, error) {
+	if serviceAccount == "" {
+		var err error
+		serviceAccount, err = m.k8sClient.DeploymentServiceAccount(ctx, m.namespace, m.deployment)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	token, err := m.k8sClient.CreateServiceAccountToken(ctx, m.namespace, serviceAccount, duration)
+	if err != nil {
+		return "", err
+	}
+	path, err := writeTokenKubeConfig(token)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Service Account: %s/%s\n", token.Namespace, token.ServiceAccount)
+	fmt.Fprintf(&b, "Expires:         %s (in %s)\n", token.Expires.Local().Format(time.RFC3339), time.Until(token.Expires).Round(time.Minute))
+	fmt.Fprintf(&b, "Kubeconfig:      %s\n\n", path)
+	b.WriteString(token.Token)
+	return b.String(), nil
+}
+
+// writeTokenKubeConfig writes a kubeconfig for the token, readable only by
+// the current user, and returns its path
+func writeTokenKubeConfig(token *k8s.ServiceAccountToken) (string, error) {
+	data, err := token.KubeConfig()
+	if err != nil {
+		return "", err
+	}
+	path, err := config.GetTokenKubeConfigPath(token.Namespace, sanitizeFileName(token.ServiceAccount))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create kubeconfig dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return path, nil
+}
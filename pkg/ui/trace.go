@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+var (
+	// traceIDField matches trace IDs in log fields such as trace_id=...,
+	// "traceId":"..." or trace.id: ...
+	traceIDField = regexp.MustCompile(`(?i)trace[_.-]?id["']?\s*[:=]\s*["']?([0-9a-f]{32}|[0-9a-f]{16})\b`)
+	// traceParent matches W3C traceparent headers, 00-<trace>-<span>-<flags>
+	traceParent = regexp.MustCompile(`\b00-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}\b`)
+)
+
+// traceLoadedMsg carries the summary of a log line's trace
+type traceLoadedMsg struct {
+	traceID string
+	summary string
+	err     error
+}
+
+// lineTraceID returns the trace ID a log line mentions, if any
+func lineTraceID(line string) string {
+	for _, pattern := range []*regexp.Regexp{traceIDField, traceParent} {
+		if match := pattern.FindStringSubmatch(line); match != nil {
+			return strings.ToLower(match[1])
+		}
+	}
+	return ""
+}
+
+// lookupTrace handles the trace key in the log viewer: the selected line's
+// trace is fetched and summed up if a tracing API is configured, else
+// opened in the tracing UI
+func (m Model) lookupTrace() (tea.Model, tea.Cmd) {
+	traceID := lineTraceID(m.logViewer.SelectedLine())
+	if traceID == "" {
+		m.logViewer.SetStatus("No trace ID in the selected line")
+		return m, nil
+	}
+	settings := m.config.GetTracing(m.k8sClient.GetKubeConfigPath())
+	if settings.API == "" && settings.URL == "" {
+		m.logViewer.SetStatus("No tracing configured: set tracing.url or tracing.api in config.yml")
+		return m, nil
+	}
+	traceURL, err := renderTraceURL(settings.URL, traceID)
+	if err != nil {
+		m.logViewer.SetStatus(err.Error())
+		return m, nil
+	}
+	if settings.API == "" {
+		m.logViewer.SetStatus(m.openTraceURL(traceURL))
+		return m, nil
+	}
+
+	m.logViewer.SetStatus("Fetching trace " + traceID + "…")
+	client := m.k8sClient
+	return m, func() tea.Msg {
+		q := k8s.TraceQuery{API: settings.API, TraceID: traceID}
+		if !strings.HasPrefix(settings.API, k8s.ServicePrefix) {
+			if creds, err := config.OpenCredentials(); err == nil {
+				q.Username, q.Password, _ = creds.Login(config.CredentialTracing, k8s.APIHost(settings.API))
+			}
+		}
+		summary, err := client.DescribeTrace(context.Background(), q)
+		if err == nil && traceURL != "" {
+			summary = "Open: " + traceURL + "\n\n" + summary
+		}
+		return traceLoadedMsg{traceID: traceID, summary: summary, err: err}
+	}
+}
+
+// renderTraceURL executes the tracing UI template for a trace, empty
+// without a template
+func renderTraceURL(tmpl, traceID string) (string, error) {
+	if tmpl == "" {
+		return "", nil
+	}
+	t, err := template.New("tracing").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid tracing.url: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, struct{ TraceID string }{traceID}); err != nil {
+		return "", fmt.Errorf("invalid tracing.url: %w", err)
+	}
+	return b.String(), nil
+}
+
+// openTraceURL opens a trace in the browser, or copies its URL where there
+// is none, and says which it did
+func (m Model) openTraceURL(traceURL string) string {
+	if !m.inCluster {
+		if err := openBrowser(traceURL); err == nil {
+			return "Opened the trace in the browser"
+		}
+	}
+	if err := clipboard.WriteAll(traceURL); err != nil {
+		termenv.Copy(traceURL)
+	}
+	return "Copied the trace URL: " + traceURL
+}
+
+// openBrowser opens a URL with the desktop's default browser
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the opener without waiting for it
+	go cmd.Wait()
+	return nil
+}
+
+// handleTraceLoaded shows a fetched trace as the result, Esc goes back to
+// the logs
+func (m Model) handleTraceLoaded(msg traceLoadedMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateViewLogs {
+		return m, nil
+	}
+	if msg.err != nil {
+		var status string
+		if errors.Is(msg.err, context.DeadlineExceeded) {
+			status = "Fetching trace " + msg.traceID + " timed out"
+		} else {
+			status = "Trace lookup failed: " + msg.err.Error()
+		}
+		m.logViewer.SetStatus(status)
+		return m, nil
+	}
+	m.logViewer.SetStatus("")
+	m.viewedTrace = msg.traceID
+	m.state = StateShowResult
+	m.err = nil
+	m.result = msg.summary
+	m.resultViewer.SetHighlighter(nil)
+	m.resultViewer.SetContent(m.result)
+	return m, nil
+}
+
+// closeTrace goes back from a shown trace to the logs
+func (m Model) closeTrace() (tea.Model, tea.Cmd) {
+	m.viewedTrace = ""
+	m.result = ""
+	m.state = StateViewLogs
+	return m, nil
+}
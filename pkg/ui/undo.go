@@ -0,0 +1,248 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"khelper/pkg/config"
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// revisionAnnotation holds a deployment's current rollout revision
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// undoPreviewMsg carries the change the undo command offers to revert
+type undoPreviewMsg struct {
+	change  *config.Change
+	preview string
+	err     error
+}
+
+// ChangeClient is what recording, applying and undoing changes needs of a
+// client
+type ChangeClient interface {
+	k8s.Connection
+	k8s.Workloads
+}
+
+// CaptureState returns the state a change is about to replace: the replica
+// count, the container's image, the env var's value (nil if it is not set)
+// or the deployment's revision. noUndo is set when the state exists but
+// cannot be restored.
+func CaptureState(ctx context.Context, client ChangeClient, change config.Change) (state *string, noUndo string, err error) {
+	// The deployment is gone after the last step
+	if change.Operation == config.OpRename {
+		return nil, "a rename is rolled back step by step from its flow", nil
+	}
+	deployment, err := client.GetDeployment(ctx, change.Namespace, change.Deployment)
+	if err != nil {
+		return nil, "", err
+	}
+	switch change.Operation {
+	case config.OpScale:
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		s := strconv.Itoa(int(replicas))
+		return &s, "", nil
+	case config.OpRollback:
+		s := deployment.Annotations[revisionAnnotation]
+		if s == "" {
+			return nil, "the deployment has no revision yet", nil
+		}
+		return &s, "", nil
+	case config.OpPatch:
+		return nil, "a patch can change any field, rollback restores an earlier pod template", nil
+	}
+
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name != change.Container {
+			continue
+		}
+		if change.Operation == config.OpUpdateImage {
+			s := container.Image
+			return &s, "", nil
+		}
+		for _, env := range container.Env {
+			if env.Name != change.Key {
+				continue
+			}
+			if env.ValueFrom != nil {
+				return nil, fmt.Sprintf("%s was set from a reference, not a value", change.Key), nil
+			}
+			s := env.Value
+			return &s, "", nil
+		}
+		return nil, "", nil
+	}
+	return nil, "", fmt.Errorf("container %s not found in %s", change.Container, change.Deployment)
+}
+
+// ApplyChange captures the state a change replaces, applies it with its
+// change cause in ctx and records it in the audit history. The returned note,
+// if any, says why the change cannot be undone; recording never fails a
+// change that was applied.
+func ApplyChange(ctx context.Context, client ChangeClient, change config.Change, apply func(ctx context.Context) error) (string, error) {
+	return ApplyChanges(ctx, client, []config.Change{change}, apply)
+}
+
+// ApplyChanges is ApplyChange for changes made by a single update, such as
+// the images of several containers. Each is recorded and undone on its own.
+func ApplyChanges(ctx context.Context, client ChangeClient, changes []config.Change, apply func(ctx context.Context) error) (string, error) {
+	var noUndos []string
+	for i := range changes {
+		change := &changes[i]
+		change.KubeConfig = client.GetKubeConfigPath()
+		change.User = currentUser()
+		before, noUndo, err := CaptureState(ctx, client, *change)
+		if err != nil {
+			noUndo = "the previous state could not be read: " + err.Error()
+		}
+		change.Before, change.NoUndo = before, noUndo
+		if noUndo != "" {
+			noUndos = append(noUndos, noUndo)
+		}
+	}
+
+	if err := apply(k8s.WithChangeCause(ctx, changeCause(changes))); err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		if _, err := config.RecordChange(change); err != nil {
+			return "This change cannot be undone, recording it failed: " + err.Error(), nil
+		}
+	}
+	if len(noUndos) > 0 {
+		return "This change cannot be undone: " + strings.Join(noUndos, "; "), nil
+	}
+	return "", nil
+}
+
+// LastUndoableChange returns the latest change to a deployment that can be
+// undone, or nil. An empty namespace or deployment matches all.
+func LastUndoableChange(client ChangeClient, namespace, deployment string) (*config.Change, error) {
+	history, err := config.LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	return config.LastUndoable(history, client.GetKubeConfigPath(), namespace, deployment), nil
+}
+
+// UndoPreview describes exactly what undoing a change restores, warning when
+// the state was changed again outside khelper since
+func UndoPreview(ctx context.Context, client ChangeClient, change config.Change) (string, error) {
+	if change.NoUndo != "" {
+		return "", fmt.Errorf("the last change (%s) cannot be undone: %s", change.Summary(), change.NoUndo)
+	}
+	current, _, err := CaptureState(ctx, client, change)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Undo %s in %s, made %s\n\n", change.Operation, change.Namespace, change.Time.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "  %s\n", change.Target())
+	fmt.Fprintf(&b, "    current:  %s\n", config.FormatState(current))
+	fmt.Fprintf(&b, "    restore:  %s\n", config.FormatState(change.Before))
+	// A rollback gets a new revision number, so only the other states can
+	// be compared with what khelper set
+	if change.Operation != config.OpRollback && config.FormatState(current) != change.After {
+		fmt.Fprintf(&b, "\nWarning: changed since khelper set it to %s", change.After)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// UndoChange restores the state a change replaced and records the undo
+func UndoChange(ctx context.Context, client ChangeClient, change config.Change) (string, error) {
+	if change.NoUndo != "" {
+		return "", fmt.Errorf("%s cannot be undone: %s", change.Summary(), change.NoUndo)
+	}
+	if change.Before == nil && change.Operation != config.OpSetEnv {
+		return "", fmt.Errorf("%s has no previous state recorded", change.Summary())
+	}
+
+	undo := config.Change{
+		Namespace:  change.Namespace,
+		Deployment: change.Deployment,
+		Operation:  change.Operation,
+		Container:  change.Container,
+		Key:        change.Key,
+		After:      config.FormatState(change.Before),
+		Reverts:    change.ID,
+	}
+	var apply func(ctx context.Context) error
+	switch change.Operation {
+	case config.OpScale:
+		replicas, err := strconv.ParseInt(*change.Before, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid recorded replica count %q", *change.Before)
+		}
+		apply = func(ctx context.Context) error {
+			return client.ScaleDeployment(ctx, change.Namespace, change.Deployment, int32(replicas))
+		}
+	case config.OpUpdateImage:
+		apply = func(ctx context.Context) error {
+			return client.UpdateImage(ctx, change.Namespace, change.Deployment, change.Container, *change.Before)
+		}
+	case config.OpSetEnv:
+		apply = func(ctx context.Context) error {
+			if change.Before == nil {
+				return client.UnsetEnvVar(ctx, change.Namespace, change.Deployment, change.Container, change.Key)
+			}
+			return client.SetEnvVar(ctx, change.Namespace, change.Deployment, change.Container, change.Key, *change.Before)
+		}
+	case config.OpRollback:
+		revision, err := strconv.ParseInt(*change.Before, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid recorded revision %q", *change.Before)
+		}
+		apply = func(ctx context.Context) error {
+			return client.RollbackDeployment(ctx, change.Namespace, change.Deployment, revision)
+		}
+	default:
+		return "", fmt.Errorf("cannot undo unknown operation %q", change.Operation)
+	}
+
+	note, err := ApplyChange(ctx, client, undo, apply)
+	if err != nil {
+		return "", err
+	}
+	return withNote(fmt.Sprintf("Restored %s to %s", change.Target(), undo.After), note), nil
+}
+
+// loadUndo finds the change the undo command offers to revert
+func (m *Model) loadUndo() tea.Cmd {
+	namespace, deployment := m.namespace, m.deployment
+	return func() tea.Msg {
+		change, err := LastUndoableChange(m.k8sClient, namespace, deployment)
+		if err != nil {
+			return undoPreviewMsg{err: err}
+		}
+		if change == nil {
+			return undoPreviewMsg{err: fmt.Errorf("no change to %s recorded that can be undone", deployment)}
+		}
+		preview, err := UndoPreview(m.exec.context(), m.k8sClient, *change)
+		return undoPreviewMsg{change: change, preview: preview, err: err}
+	}
+}
+
+// undoNow reverts the change confirmed in the undo preview
+func (m *Model) undoNow(change config.Change) tea.Cmd {
+	return func() tea.Msg {
+		result, err := UndoChange(m.exec.context(), m.k8sClient, change)
+		return CommandResultMsg{result: result, err: err}
+	}
+}
+
+// withNote appends the note returned by ApplyChange to a result
+func withNote(result, note string) string {
+	if note == "" {
+		return result
+	}
+	return result + "\n\nNote: " + note
+}
@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultWaitTimeout is how long the wait command waits without a timeout
+const DefaultWaitTimeout = 5 * time.Minute
+
+// rolloutPollInterval is how often the wait command checks the rollout
+const rolloutPollInterval = 2 * time.Second
+
+// rolloutWait is a rollout being waited for, with its live progress
+type rolloutWait struct {
+	started  time.Time
+	deadline time.Time
+	status   *k8s.RolloutStatus
+
+	// A config rollout then waits for the pods to serve the new config
+	config          *k8s.ConfigRollout
+	configUnchanged bool
+	pods            []k8s.PodConfig
+}
+
+// rolloutStatusMsg reports the rollout progress of the wait started at started
+type rolloutStatusMsg struct {
+	started time.Time
+	status  k8s.RolloutStatus
+	err     error
+}
+
+// startWait starts waiting for the deployment's rollout, timeout being empty
+// or a duration
+func (m Model) startWait(timeout string) (tea.Model, tea.Cmd) {
+	d := DefaultWaitTimeout
+	if timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil || parsed <= 0 {
+			m.state = StateShowResult
+			m.err = fmt.Errorf("invalid timeout %q, use a duration such as 5m", timeout)
+			return m, nil
+		}
+		d = parsed
+	}
+	now := time.Now()
+	m.rolloutWait = &rolloutWait{started: now, deadline: now.Add(d)}
+	m.state = StateShowResult
+	m.err = nil
+	return m, m.pollRollout(now, 0)
+}
+
+// pollRollout checks the rollout after delay
+func (m *Model) pollRollout(started time.Time, delay time.Duration) tea.Cmd {
+	namespace, deployment := m.namespace, m.deployment
+	poll := func() tea.Msg {
+		d, err := m.k8sClient.GetDeployment(context.Background(), namespace, deployment)
+		if err != nil {
+			return rolloutStatusMsg{started: started, err: err}
+		}
+		status, err := k8s.GetRolloutStatus(d)
+		return rolloutStatusMsg{started: started, status: status, err: err}
+	}
+	if delay == 0 {
+		return poll
+	}
+	return tea.Tick(delay, func(time.Time) tea.Msg { return poll() })
+}
+
+// handleRolloutStatus updates the progress of the wait and finishes it once
+// the rollout is done, failed or timed out
+func (m Model) handleRolloutStatus(msg rolloutStatusMsg) (tea.Model, tea.Cmd) {
+	w := m.rolloutWait
+	if w == nil || !w.started.Equal(msg.started) {
+		// Cancelled or replaced
+		return m, nil
+	}
+	elapsed := time.Since(w.started).Round(time.Second)
+	switch {
+	case msg.err != nil:
+		m.rolloutWait = nil
+		m.err = msg.err
+	case msg.status.Done && w.config != nil:
+		status := msg.status
+		w.status = &status
+		return m, m.checkPodConfigs(w.started, 0)
+	case msg.status.Done:
+		m.rolloutWait = nil
+		m.result = fmt.Sprintf("%s is rolled out and ready after %s\n\n%s", m.deployment, elapsed, msg.status)
+		m.resultViewer.SetHighlighter(nil)
+		m.resultViewer.SetContent(m.result)
+	case time.Now().After(w.deadline):
+		m.rolloutWait = nil
+		m.err = fmt.Errorf("timed out after %s waiting for %s: %s", elapsed, m.deployment, msg.status)
+	default:
+		status := msg.status
+		w.status = &status
+		return m, m.pollRollout(w.started, rolloutPollInterval)
+	}
+	return m, nil
+}
+
+// rolloutWaitView renders the live progress of the wait
+func (m Model) rolloutWaitView() string {
+	w := m.rolloutWait
+	var b strings.Builder
+	switch {
+	case w.config != nil && (w.configUnchanged || (w.status != nil && w.status.Done)):
+		b.WriteString(RenderLoading(fmt.Sprintf("Waiting for the pods of %s to serve the new config...", m.deployment)))
+	default:
+		b.WriteString(RenderLoading(fmt.Sprintf("Waiting for %s to roll out...", m.deployment)))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(LabelStyle.Render("Progress: "))
+	if w.configUnchanged {
+		b.WriteString(ValueStyle.Render("config unchanged, nothing to roll out"))
+	} else if w.status != nil {
+		b.WriteString(ValueStyle.Render(w.status.String()))
+	} else {
+		b.WriteString(ValueStyle.Render("checking"))
+	}
+	b.WriteString("\n")
+	b.WriteString(LabelStyle.Render("Elapsed:  "))
+	b.WriteString(ValueStyle.Render(fmt.Sprintf("%s of %s", time.Since(w.started).Round(time.Second), w.deadline.Sub(w.started))))
+	b.WriteString("\n")
+	if w.pods != nil {
+		b.WriteString("\n")
+		b.WriteString(ValueStyle.Render(formatPodConfigs(w.pods)))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(InfoStyle.Render("Esc/c: stop waiting"))
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"khelper/pkg/k8s"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// identityMsg carries who the client of a kubeconfig talks to the cluster as
+type identityMsg struct {
+	kubeconfig string
+	identity   *k8s.Identity
+}
+
+// loadIdentity fetches the identity shown in the header. A failed review
+// still shows the kubeconfig's context and user.
+func (m *Model) loadIdentity() tea.Cmd {
+	client := m.k8sClient
+	return func() tea.Msg {
+		identity, _ := client.WhoAmI(context.Background())
+		return identityMsg{kubeconfig: client.GetKubeConfigPath(), identity: identity}
+	}
+}
+
+// handleIdentity shows the identity in the header if the kubeconfig is still
+// the current one
+func (m Model) handleIdentity(msg identityMsg) (tea.Model, tea.Cmd) {
+	if msg.kubeconfig == m.kubeconfig {
+		m.identity = msg.identity
+	}
+	return m, nil
+}
+
+// header renders the header with the current selection
+func (m Model) header() string {
+	cluster := identitySummary(m.identity)
+	if m.capabilities != nil && m.capabilities.Version != "" {
+		if cluster != "" {
+			cluster += " • "
+		}
+		cluster += m.capabilities.Version
+	}
+	return RenderHeader(m.kubeconfig, cluster, m.namespace, m.deployment)
+}
+
+// identitySummary is the header's line on the cluster and user, e.g.
+// "prod (https://10.0.0.1:6443) as alice", empty until known
+func identitySummary(id *k8s.Identity) string {
+	if id == nil {
+		return ""
+	}
+	var parts []string
+	if id.Context != "" {
+		parts = append(parts, id.Context)
+	}
+	if id.Server != "" {
+		parts = append(parts, "("+id.Server+")")
+	}
+	if user := identityUser(id); user != "" {
+		parts = append(parts, "as "+user)
+	}
+	return strings.Join(parts, " ")
+}
+
+// identityUser is the user the API server authenticated, or the kubeconfig
+// user if it could not tell
+func identityUser(id *k8s.Identity) string {
+	if id.User != "" {
+		return id.User
+	}
+	return id.AuthInfo
+}
+
+// FormatIdentity renders the whoami result: the user and groups the API
+// server sees, the cluster with its version if known and the namespace
+// khelper uses
+func FormatIdentity(id *k8s.Identity, caps *k8s.Capabilities, err error, namespace string) string {
+	var b strings.Builder
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%-18s %s\n", label+":", value)
+		}
+	}
+	user := id.User
+	if user == "" {
+		user = "(unknown)"
+	}
+	row("User", user)
+	row("UID", id.UID)
+	row("Groups", strings.Join(id.Groups, ", "))
+	row("Kubeconfig user", id.AuthInfo)
+	row("Context", id.Context)
+	row("Cluster", id.Cluster)
+	row("Server", id.Server)
+	if caps != nil {
+		row("Server version", caps.Version)
+	}
+	if namespace == "" {
+		namespace = id.Namespace
+	}
+	row("Namespace", namespace)
+	if id.Namespace != "" && id.Namespace != namespace {
+		row("Context namespace", id.Namespace)
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\nThe API server did not say who you are: %v\n", err)
+	}
+	if warning := capabilityWarning(caps); warning != "" {
+		b.WriteString("\n" + warning + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Log lines and list items are cut and wrapped by terminal cells rather
+// than bytes, so multi-byte characters stay whole and wide ones (CJK,
+// emoji) take the two columns they are drawn in.
+
+// truncateWidth cuts s to at most width cells, ending with tail if cut
+func truncateWidth(s string, width int, tail string) string {
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+	return runewidth.Truncate(s, width, tail)
+}
+
+// skipColumns drops the first n cells of s. A wide character cut in half
+// leaves a space.
+func skipColumns(s string, n int) string {
+	return runewidth.TruncateLeft(s, n, "")
+}
+
+// wrapWidth wraps text into rows of at most width cells, breaking after a
+// space or punctuation (including the Japanese comma and full stop) in the
+// second half of a row where there is one
+func wrapWidth(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	var result strings.Builder
+	for runewidth.StringWidth(text) > width {
+		head := runewidth.Truncate(text, width, "")
+		if head == "" {
+			// A character wider than the row gets a row of its own
+			_, size := utf8.DecodeRuneInString(text)
+			head = text[:size]
+		}
+		breakAt := len(head)
+		if i := strings.LastIndexAny(head, " ,;:、。"); i >= 0 && runewidth.StringWidth(head[:i+1]) > width/2 {
+			breakAt = i + 1
+		}
+		result.WriteString(text[:breakAt])
+		result.WriteString("\n")
+		text = text[breakAt:]
+	}
+	result.WriteString(text)
+	return result.String()
+}